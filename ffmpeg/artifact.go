@@ -0,0 +1,295 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Private functions (alphabetical)
+
+// artifactPenalty maps an artifact value to a penalty between 0 (none) and 1 (at or above the limit).
+func artifactPenalty(value, limit float64) float64 {
+	return math.Max(0, math.Min(value/limit, 1))
+}
+
+// bandingContours counts the false contours along a line of n pixels starting at start and
+// advancing by stride: steps of at most artifactBandingMaxStep between runs of at least
+// artifactBandingFlatRun equal pixels. Steps within texture or noise are not counted.
+func bandingContours(pixels []byte, start, stride, n int) int {
+	contours := 0
+	run := 0
+	for i := 1; i < n; i++ {
+		current, previous := int(pixels[start+i*stride]), int(pixels[start+(i-1)*stride])
+		step := current - previous
+		if step == 0 {
+			run++
+			continue
+		}
+
+		if step >= -artifactBandingMaxStep && step <= artifactBandingMaxStep && run >= artifactBandingFlatRun &&
+			i+artifactBandingFlatRun < n {
+			flat := true
+			for j := i + 1; j <= i+artifactBandingFlatRun; j++ {
+				if int(pixels[start+j*stride]) != current {
+					flat = false
+					break
+				}
+			}
+			if flat {
+				contours++
+			}
+		}
+		run = 0
+	}
+	return contours
+}
+
+// bandingRatio estimates the banding of an 8-bit luma frame as the share of pixels, in percent,
+// lying on a false contour along the rows or the columns.
+func bandingRatio(frame []byte, width, height int) float64 {
+	if width <= 0 || height <= 0 || len(frame) < width*height {
+		return 0
+	}
+
+	contours := 0
+	for y := 0; y < height; y++ {
+		contours += bandingContours(frame, y*width, 1, width)
+	}
+	for x := 0; x < width; x++ {
+		contours += bandingContours(frame, x, width, height)
+	}
+	return float64(contours) * 100 / float64(width*height)
+}
+
+// parseArtifactMetadata reads the blockdetect and blurdetect values the metadata filter logs for
+// every frame and sends them to the channel. Log lines carry a "[Parsed_metadata_N @ 0x...]"
+// prefix, which is removed; lines that are not metadata are ignored.
+func parseArtifactMetadata(ctx context.Context, r io.Reader, metadataCh chan<- artifactMetadata) error {
+	var current *artifactMetadata
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case metadataCh <- *current:
+		}
+		current = nil
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			if _, rest, found := strings.Cut(line, "] "); found {
+				line = strings.TrimSpace(rest)
+			}
+		}
+
+		if match := metadataFrameRegex.FindStringSubmatch(line); match != nil {
+			if err := flush(); err != nil {
+				return err
+			}
+			frameNumber, _ := strconv.Atoi(match[1])
+			frameTime, _ := strconv.ParseFloat(match[2], 64)
+			current = &artifactMetadata{frameNumber: frameNumber, time: frameTime}
+			continue
+		}
+
+		key, rawValue, found := strings.Cut(line, "=")
+		if !found || current == nil {
+			continue
+		}
+		value, err := strconv.ParseFloat(rawValue, 64)
+		if err != nil {
+			continue
+		}
+
+		switch key {
+		case "lavfi.block":
+			current.blockiness = value
+		case "lavfi.blur":
+			current.blur = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading artifact metadata: %w", err)
+	}
+	return flush()
+}
+
+// scoreArtifactFrame combines the artifact values of a frame in a score from 0 to 100, where
+// every artifact type weighs the same and 100 means no measurable artifacts.
+func scoreArtifactFrame(metadata artifactMetadata, banding float64) ArtifactFrame {
+	penalty := (artifactPenalty(metadata.blockiness, artifactBlockinessLimit) +
+		artifactPenalty(metadata.blur, artifactBlurLimit) +
+		artifactPenalty(banding, artifactBandingLimit)) / 3
+	score := 100 * (1 - penalty)
+
+	return ArtifactFrame{
+		FrameNumber:  metadata.frameNumber,
+		Time:         metadata.time,
+		Blockiness:   metadata.blockiness,
+		Blur:         metadata.blur,
+		Banding:      banding,
+		Score:        score,
+		QualityLevel: qualityScoreToLevel(score),
+	}
+}
+
+// Public functions (alphabetical)
+
+// NewArtifactAnalyzer creates a new ArtifactAnalyzer with the default banding resolution.
+func NewArtifactAnalyzer(ffmpegInfo *FFmpegInfo) (*ArtifactAnalyzer, error) {
+	if ffmpegInfo == nil || !ffmpegInfo.Installed {
+		return nil, fmt.Errorf("ffmpeg not available")
+	}
+
+	return &ArtifactAnalyzer{
+		FFmpegPath: ffmpegInfo.Path,
+		MaxWidth:   artifactBandingMaxWidth,
+	}, nil
+}
+
+// SummarizeArtifacts rolls the artifact scores of the frames of a stream up.
+// It returns nil when no frame was analyzed.
+func SummarizeArtifacts(frames []ArtifactFrame) *ArtifactSummary {
+	if len(frames) == 0 {
+		return nil
+	}
+
+	summary := &ArtifactSummary{Frames: len(frames), MinScore: math.Inf(1)}
+	for _, frame := range frames {
+		summary.AverageBlockiness += frame.Blockiness
+		summary.AverageBlur += frame.Blur
+		summary.AverageBanding += frame.Banding
+		summary.AverageScore += frame.Score
+		summary.MaxBlockiness = math.Max(summary.MaxBlockiness, frame.Blockiness)
+		summary.MaxBlur = math.Max(summary.MaxBlur, frame.Blur)
+		summary.MaxBanding = math.Max(summary.MaxBanding, frame.Banding)
+		if frame.Score < summary.MinScore {
+			summary.MinScore = frame.Score
+			summary.WorstFrame = frame.FrameNumber
+		}
+	}
+
+	count := float64(len(frames))
+	summary.AverageBlockiness /= count
+	summary.AverageBlur /= count
+	summary.AverageBanding /= count
+	summary.AverageScore /= count
+	summary.QualityLevel = qualityScoreToLevel(summary.AverageScore)
+	return summary
+}
+
+// Private methods (alphabetical)
+
+// bandingSize returns the even dimensions frames are scaled to for the banding estimate.
+func (a *ArtifactAnalyzer) bandingSize(stream VideoStream) (int, int) {
+	width, height := stream.Width, stream.Height
+	if a.MaxWidth > 0 && width > a.MaxWidth {
+		height = int(math.Round(float64(height)*float64(a.MaxWidth)/float64(width)/2)) * 2
+		width = a.MaxWidth
+	}
+	return width &^ 1, height &^ 1
+}
+
+// Public methods (alphabetical)
+
+// Analyze decodes a video stream once through the blockdetect and blurdetect filters and
+// estimates the banding of every frame from its downscaled luma plane. The artifact scores
+// of every frame are sent to the provided channel as soon as the frame is decoded; the
+// channel is closed when the analysis ends.
+func (a *ArtifactAnalyzer) Analyze(ctx context.Context, filePath string, stream VideoStream, frameCh chan<- ArtifactFrame) error {
+	defer close(frameCh)
+
+	width, height := a.bandingSize(stream)
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("unknown frame size of video stream #%d", stream.Index)
+	}
+
+	// The metadata filter logs the detections on stderr while the luma frames go to stdout
+	cmd := exec.CommandContext(
+		ctx,
+		a.FFmpegPath,
+		"-hide_banner",
+		"-nostats",
+		"-v", "info",
+		"-i", filePath,
+		"-map", fmt.Sprintf("0:%d", stream.Index),
+		"-filter:v", fmt.Sprintf("blockdetect,blurdetect,metadata=mode=print,scale=%d:%d:flags=neighbor,format=gray", width, height),
+		"-fps_mode", "passthrough",
+		"-f", "rawvideo",
+		"-",
+	)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start FFmpeg: %w", err)
+	}
+
+	metadataCh := make(chan artifactMetadata, 64)
+	parseErrCh := make(chan error, 1)
+	go func() {
+		defer close(metadataCh)
+		parseErrCh <- parseArtifactMetadata(ctx, stderr, metadataCh)
+	}()
+
+	frame := make([]byte, width*height)
+	reader := bufio.NewReaderSize(stdout, len(frame))
+	var sendErr error
+	for frameNumber := 0; ; frameNumber++ {
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			break
+		}
+
+		metadata, ok := <-metadataCh
+		if !ok {
+			metadata = artifactMetadata{frameNumber: frameNumber}
+		}
+
+		select {
+		case <-ctx.Done():
+			sendErr = ctx.Err()
+		case frameCh <- scoreArtifactFrame(metadata, bandingRatio(frame, width, height)):
+		}
+		if sendErr != nil {
+			break
+		}
+	}
+
+	// Drain both outputs so FFmpeg can exit
+	go func() {
+		for range metadataCh {
+		}
+	}()
+	_, _ = io.Copy(io.Discard, reader)
+	parseErr := <-parseErrCh
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("error running artifact filters: %w", err)
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+	return parseErr
+}
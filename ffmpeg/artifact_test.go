@@ -0,0 +1,117 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the no-reference artifact analyzer.
+package ffmpeg
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// ArtifactAnalyzerTestSuite defines the test suite for ArtifactAnalyzer.
+// It uses synthetic filter logs and frames so no sample media is required.
+type ArtifactAnalyzerTestSuite struct {
+	suite.Suite
+}
+
+// TestParseMetadata verifies parsing of the prefixed metadata log lines.
+func (s *ArtifactAnalyzerTestSuite) TestParseMetadata() {
+	output := "Input #0, matroska,webm, from 'test.mkv':\n" +
+		"[Parsed_metadata_2 @ 0x5581c0] frame:0    pts:0       pts_time:0\n" +
+		"[Parsed_metadata_2 @ 0x5581c0] lavfi.block=3.5\n" +
+		"[Parsed_metadata_2 @ 0x5581c0] lavfi.blur=2.25\n" +
+		"[Parsed_metadata_2 @ 0x5581c0] frame:1    pts:40      pts_time:0.04\n" +
+		"[Parsed_metadata_2 @ 0x5581c0] lavfi.block=1\n" +
+		"[Parsed_metadata_2 @ 0x5581c0] lavfi.blur=7\n"
+
+	metadataCh := make(chan artifactMetadata, 4)
+	require.NoError(s.T(), parseArtifactMetadata(context.Background(), strings.NewReader(output), metadataCh))
+	close(metadataCh)
+
+	var frames []artifactMetadata
+	for metadata := range metadataCh {
+		frames = append(frames, metadata)
+	}
+	require.Len(s.T(), frames, 2)
+	assert.Equal(s.T(), artifactMetadata{frameNumber: 0, time: 0, blockiness: 3.5, blur: 2.25}, frames[0])
+	assert.Equal(s.T(), artifactMetadata{frameNumber: 1, time: 0.04, blockiness: 1, blur: 7}, frames[1])
+}
+
+// TestBandingRatio verifies that smooth steps count as banding while a gradient and a hard edge do not.
+func (s *ArtifactAnalyzerTestSuite) TestBandingRatio() {
+	const width, height = 64, 16
+
+	// Bands of 16 columns, one luma step apart
+	banded := make([]byte, width*height)
+	for i := range banded {
+		banded[i] = byte(100 + (i%width)/16)
+	}
+	// Every row crosses three contours surrounded by flat runs on both sides
+	assert.InDelta(s.T(), 3*float64(height)*100/(width*height), bandingRatio(banded, width, height), 1e-9)
+
+	gradient := make([]byte, width*height)
+	for i := range gradient {
+		gradient[i] = byte(i % width * 2)
+	}
+	assert.Zero(s.T(), bandingRatio(gradient, width, height))
+
+	// A hard edge between flat areas is a real edge, not a contour
+	edge := make([]byte, width*height)
+	for i := range edge {
+		if i%width >= width/2 {
+			edge[i] = 200
+		}
+	}
+	assert.Zero(s.T(), bandingRatio(edge, width, height))
+	assert.Zero(s.T(), bandingRatio(nil, width, height))
+}
+
+// TestScoreAndSummary verifies the combined score and the roll up of a stream.
+func (s *ArtifactAnalyzerTestSuite) TestScoreAndSummary() {
+	clean := scoreArtifactFrame(artifactMetadata{frameNumber: 0}, 0)
+	assert.Equal(s.T(), 100.0, clean.Score)
+	assert.Equal(s.T(), ExcellentQuality, clean.QualityLevel)
+
+	// Blockiness above the limit and half the banding limit cost half of the score
+	worst := scoreArtifactFrame(artifactMetadata{frameNumber: 1, time: 0.04, blockiness: 20, blur: 0}, artifactBandingLimit/2)
+	assert.InDelta(s.T(), 50.0, worst.Score, 1e-9)
+	assert.Equal(s.T(), BadQuality, worst.QualityLevel)
+
+	summary := SummarizeArtifacts([]ArtifactFrame{clean, worst})
+	require.NotNil(s.T(), summary)
+	assert.Equal(s.T(), 2, summary.Frames)
+	assert.InDelta(s.T(), 75.0, summary.AverageScore, 1e-9)
+	assert.InDelta(s.T(), 50.0, summary.MinScore, 1e-9)
+	assert.Equal(s.T(), 1, summary.WorstFrame)
+	assert.InDelta(s.T(), 10.0, summary.AverageBlockiness, 1e-9)
+	assert.InDelta(s.T(), 20.0, summary.MaxBlockiness, 1e-9)
+	assert.Equal(s.T(), MediumQuality, summary.QualityLevel)
+
+	assert.Nil(s.T(), SummarizeArtifacts(nil))
+}
+
+// TestBandingSize verifies the downscaled frame size for the banding estimate.
+func (s *ArtifactAnalyzerTestSuite) TestBandingSize() {
+	analyzer, err := NewArtifactAnalyzer(&FFmpegInfo{Installed: true, Path: "ffmpeg"})
+	require.NoError(s.T(), err)
+
+	width, height := analyzer.bandingSize(VideoStream{Width: 1920, Height: 800})
+	assert.Equal(s.T(), 960, width)
+	assert.Equal(s.T(), 400, height)
+
+	width, height = analyzer.bandingSize(VideoStream{Width: 719, Height: 575})
+	assert.Equal(s.T(), 718, width)
+	assert.Equal(s.T(), 574, height)
+
+	_, err = NewArtifactAnalyzer(nil)
+	assert.Error(s.T(), err)
+}
+
+// TestArtifactAnalyzerSuite runs the ArtifactAnalyzer test suite.
+func TestArtifactAnalyzerSuite(t *testing.T) {
+	suite.Run(t, new(ArtifactAnalyzerTestSuite))
+}
@@ -0,0 +1,422 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Private variables (alphabetical)
+
+// audioQCChannelKeyRegex matches the per-channel astats metadata keys.
+var audioQCChannelKeyRegex = regexp.MustCompile(`^lavfi\.astats\.(\d+)\.(\w+)$`)
+
+// metadataFrameRegex matches the frame header printed by the metadata and ametadata filters.
+var metadataFrameRegex = regexp.MustCompile(`frame:\s*(\d+)\s+pts:\s*-?\d+\s+pts_time:\s*(-?[\d.]+)`)
+
+// Private functions (alphabetical)
+
+// audioQCRuns returns the [start, end) index ranges of consecutive windows matching the predicate.
+func audioQCRuns(windows []audioQCWindow, match func(audioQCWindow) bool) [][2]int {
+	var runs [][2]int
+	start := -1
+	for i, window := range windows {
+		switch {
+		case match(window) && start < 0:
+			start = i
+		case !match(window) && start >= 0:
+			runs = append(runs, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		runs = append(runs, [2]int{start, len(windows)})
+	}
+	return runs
+}
+
+// audioQCWindowEnd returns the end time of the window at the given index.
+func audioQCWindowEnd(windows []audioQCWindow, index int) float64 {
+	return windows[index].time + audioQCWindowLength
+}
+
+// maxChannelLevel returns the highest RMS level of all channels of a window, optionally skipping one channel.
+func maxChannelLevel(window audioQCWindow, skipChannel int) float64 {
+	level := math.Inf(-1)
+	for number, channel := range window.channels {
+		if number != skipChannel && channel.rmsLevel > level {
+			level = channel.rmsLevel
+		}
+	}
+	return level
+}
+
+// parseAudioQCMetadata reads the output of the ametadata filter into analysis windows.
+// Silence start and end times reported by silencedetect are returned as pairs; a silence
+// still open at the end of the stream ends with the last window.
+func parseAudioQCMetadata(r io.Reader) ([]audioQCWindow, [][2]float64, error) {
+	var windows []audioQCWindow
+	var silences [][2]float64
+	silenceStart := -1.0
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Lines logged instead of printed to a file carry a filter prefix
+		if i := strings.Index(line, "] "); i >= 0 && strings.HasPrefix(line, "[") {
+			line = line[i+2:]
+		}
+		line = strings.TrimSpace(line)
+
+		if match := metadataFrameRegex.FindStringSubmatch(line); match != nil {
+			time, _ := strconv.ParseFloat(match[2], 64)
+			windows = append(windows, audioQCWindow{time: time, channels: make(map[int]*audioQCChannel)})
+			continue
+		}
+
+		key, rawValue, found := strings.Cut(line, "=")
+		if !found || len(windows) == 0 {
+			continue
+		}
+		value, ok := parseAudioQCValue(rawValue)
+		if !ok {
+			continue
+		}
+		window := &windows[len(windows)-1]
+
+		switch {
+		case key == "lavfi.aphasemeter.phase":
+			window.phase = value
+			window.hasPhase = true
+		case key == "lavfi.silence_start":
+			silenceStart = value
+		case key == "lavfi.silence_end" && silenceStart >= 0:
+			silences = append(silences, [2]float64{silenceStart, value})
+			silenceStart = -1
+		default:
+			match := audioQCChannelKeyRegex.FindStringSubmatch(key)
+			if match == nil {
+				continue
+			}
+			number, _ := strconv.Atoi(match[1])
+			channel := window.channels[number]
+			if channel == nil {
+				channel = &audioQCChannel{peakLevel: math.Inf(-1), rmsLevel: math.Inf(-1)}
+				window.channels[number] = channel
+			}
+			switch match[2] {
+			case "DC_offset":
+				channel.dcOffset = value
+			case "Peak_count":
+				channel.peakCount = value
+			case "Peak_level":
+				channel.peakLevel = value
+			case "RMS_level":
+				channel.rmsLevel = value
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error reading audio metadata: %w", err)
+	}
+	if silenceStart >= 0 && len(windows) > 0 {
+		silences = append(silences, [2]float64{silenceStart, audioQCWindowEnd(windows, len(windows)-1)})
+	}
+
+	return windows, silences, nil
+}
+
+// parseAudioQCValue converts a value printed by astats, aphasemeter or silencedetect. The -inf
+// level of digital silence is kept, while nan, printed when a window has no measure, is skipped.
+func parseAudioQCValue(value string) (float64, bool) {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(parsed) {
+		return 0, false
+	}
+	return parsed, true
+}
+
+// Public functions (alphabetical)
+
+// NewAudioQCAnalyzer creates a new AudioQCAnalyzer instance with the provided FFmpeg information.
+func NewAudioQCAnalyzer(ffmpegInfo *FFmpegInfo) (*AudioQCAnalyzer, error) {
+	if ffmpegInfo == nil || !ffmpegInfo.Installed {
+		return nil, fmt.Errorf("ffmpeg not available")
+	}
+
+	return &AudioQCAnalyzer{FFmpegPath: ffmpegInfo.Path}, nil
+}
+
+// Private methods (alphabetical)
+
+// detectClipping reports runs of windows with several samples at full scale on a channel.
+func (a *AudioQCAnalyzer) detectClipping(windows []audioQCWindow, channels []int) []AudioQCEvent {
+	var events []AudioQCEvent
+	for _, number := range channels {
+		clipped := func(window audioQCWindow) bool {
+			channel := window.channels[number]
+			return channel != nil && channel.peakLevel >= audioQCClipLevel && channel.peakCount >= audioQCClipMinPeakCount
+		}
+
+		for _, run := range audioQCRuns(windows, clipped) {
+			samples := 0.0
+			for _, window := range windows[run[0]:run[1]] {
+				samples += window.channels[number].peakCount
+			}
+			events = append(events, AudioQCEvent{
+				Type:        AudioQCClipping,
+				Channel:     number,
+				Start:       windows[run[0]].time,
+				End:         audioQCWindowEnd(windows, run[1]-1),
+				Value:       samples,
+				Description: fmt.Sprintf("%.0f samples at full scale", samples),
+			})
+		}
+	}
+	return events
+}
+
+// detectDCOffset reports channels whose average DC offset exceeds the limit.
+func (a *AudioQCAnalyzer) detectDCOffset(windows []audioQCWindow, channels []int) []AudioQCEvent {
+	var events []AudioQCEvent
+	for _, number := range channels {
+		sum, count := 0.0, 0
+		for _, window := range windows {
+			if channel := window.channels[number]; channel != nil {
+				sum += channel.dcOffset
+				count++
+			}
+		}
+		if count == 0 {
+			continue
+		}
+
+		if offset := sum / float64(count); math.Abs(offset) > audioQCDCOffsetLimit {
+			events = append(events, AudioQCEvent{
+				Type:        AudioQCDCOffset,
+				Channel:     number,
+				Start:       windows[0].time,
+				End:         audioQCWindowEnd(windows, len(windows)-1),
+				Value:       offset,
+				Description: fmt.Sprintf("average DC offset %+.2f%% of full scale", offset*100),
+			})
+		}
+	}
+	return events
+}
+
+// detectDropouts reports short gaps of near silence between windows where audio is present.
+func (a *AudioQCAnalyzer) detectDropouts(windows []audioQCWindow) []AudioQCEvent {
+	var events []AudioQCEvent
+	silent := func(window audioQCWindow) bool {
+		return maxChannelLevel(window, 0) < audioQCDropoutLevel
+	}
+
+	for _, run := range audioQCRuns(windows, silent) {
+		start, end := run[0], run[1]
+		duration := audioQCWindowEnd(windows, end-1) - windows[start].time
+		if start == 0 || end == len(windows) || duration > audioQCDropoutMaxDuration+1e-9 {
+			continue
+		}
+		if maxChannelLevel(windows[start-1], 0) < audioQCPresentLevel || maxChannelLevel(windows[end], 0) < audioQCPresentLevel {
+			continue
+		}
+
+		events = append(events, AudioQCEvent{
+			Type:        AudioQCDropout,
+			Start:       windows[start].time,
+			End:         audioQCWindowEnd(windows, end-1),
+			Value:       duration,
+			Description: fmt.Sprintf("%.0f ms gap in present audio", duration*1000),
+		})
+	}
+	return events
+}
+
+// detectPhase reports runs where the stereo phase correlation indicates out of phase channels.
+func (a *AudioQCAnalyzer) detectPhase(windows []audioQCWindow) []AudioQCEvent {
+	var events []AudioQCEvent
+	outOfPhase := func(window audioQCWindow) bool {
+		return window.hasPhase && window.phase < audioQCPhaseLimit && maxChannelLevel(window, 0) > audioQCPresentLevel
+	}
+
+	for _, run := range audioQCRuns(windows, outOfPhase) {
+		start, end := windows[run[0]].time, audioQCWindowEnd(windows, run[1]-1)
+		if end-start < audioQCRunDuration-1e-9 {
+			continue
+		}
+
+		phase := 0.0
+		for _, window := range windows[run[0]:run[1]] {
+			phase += window.phase
+		}
+		phase /= float64(run[1] - run[0])
+
+		events = append(events, AudioQCEvent{
+			Type:        AudioQCPhase,
+			Start:       start,
+			End:         end,
+			Value:       phase,
+			Description: fmt.Sprintf("average phase correlation %.2f", phase),
+		})
+	}
+	return events
+}
+
+// detectSilentChannels reports runs where a channel is silent while another channel carries audio.
+func (a *AudioQCAnalyzer) detectSilentChannels(windows []audioQCWindow, channels []int) []AudioQCEvent {
+	var events []AudioQCEvent
+	if len(channels) < 2 {
+		return events
+	}
+
+	for _, number := range channels {
+		silentAlone := func(window audioQCWindow) bool {
+			channel := window.channels[number]
+			return channel != nil && channel.rmsLevel < audioQCDropoutLevel && maxChannelLevel(window, number) > audioQCPresentLevel
+		}
+
+		for _, run := range audioQCRuns(windows, silentAlone) {
+			start, end := windows[run[0]].time, audioQCWindowEnd(windows, run[1]-1)
+			if end-start < audioQCRunDuration-1e-9 {
+				continue
+			}
+			events = append(events, AudioQCEvent{
+				Type:        AudioQCSilentChannel,
+				Channel:     number,
+				Start:       start,
+				End:         end,
+				Value:       end - start,
+				Description: "channel silent while other channels carry audio",
+			})
+		}
+	}
+	return events
+}
+
+// evaluate runs all detectors on the parsed windows and silences.
+func (a *AudioQCAnalyzer) evaluate(report *AudioQCReport, windows []audioQCWindow, silences [][2]float64) {
+	for _, silence := range silences {
+		report.Events = append(report.Events, AudioQCEvent{
+			Type:        AudioQCSilence,
+			Start:       silence[0],
+			End:         silence[1],
+			Value:       silence[1] - silence[0],
+			Description: fmt.Sprintf("%.1f s of silence below %s", silence[1]-silence[0], audioQCSilenceNoise),
+		})
+	}
+	if len(windows) == 0 {
+		return
+	}
+	report.Duration = audioQCWindowEnd(windows, len(windows)-1)
+
+	// Collect the channel numbers seen in the stream
+	seen := make(map[int]bool)
+	for _, window := range windows {
+		for number := range window.channels {
+			seen[number] = true
+		}
+	}
+	channels := make([]int, 0, len(seen))
+	for number := range seen {
+		channels = append(channels, number)
+	}
+	sort.Ints(channels)
+
+	report.Events = append(report.Events, a.detectClipping(windows, channels)...)
+	report.Events = append(report.Events, a.detectSilentChannels(windows, channels)...)
+	report.Events = append(report.Events, a.detectPhase(windows)...)
+	report.Events = append(report.Events, a.detectDCOffset(windows, channels)...)
+	report.Events = append(report.Events, a.detectDropouts(windows)...)
+
+	sort.SliceStable(report.Events, func(i, j int) bool {
+		return report.Events[i].Start < report.Events[j].Start
+	})
+}
+
+// filterChain builds the FFmpeg audio filter chain for a stream.
+// Audio is cut into fixed windows measured by astats; the phase meter is added for stereo streams.
+func (a *AudioQCAnalyzer) filterChain(stream AudioStream) string {
+	samplingRate := stream.SamplingRate
+	if samplingRate <= 0 {
+		samplingRate = 48000
+	}
+
+	filters := []string{
+		fmt.Sprintf("asetnsamples=n=%d:p=0", int(float64(samplingRate)*audioQCWindowLength)),
+		"astats=metadata=1:reset=1",
+	}
+	if stream.Channels == 2 {
+		filters = append(filters, "aphasemeter=video=0")
+	}
+	filters = append(filters,
+		fmt.Sprintf("silencedetect=noise=%s:d=%g", audioQCSilenceNoise, audioQCSilenceDuration),
+		"ametadata=mode=print:file=-",
+	)
+	return strings.Join(filters, ",")
+}
+
+// Public methods (alphabetical)
+
+// Analyze decodes an audio stream and reports its defects as timestamped events.
+// Detected defects are clipping runs, silences (silencedetect), channels silent while
+// others are not, out of phase stereo, DC offset and short dropouts.
+func (a *AudioQCAnalyzer) Analyze(ctx context.Context, filePath string, stream AudioStream) (*AudioQCReport, error) {
+	cmd := exec.CommandContext(
+		ctx,
+		a.FFmpegPath,
+		"-hide_banner",
+		"-nostats",
+		"-v", "error",
+		"-i", filePath,
+		"-map", fmt.Sprintf("0:%d", stream.Index),
+		"-filter:a", a.filterChain(stream),
+		"-f", "null",
+		"-",
+	)
+
+	// The ametadata filter prints the measurements on stdout
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start FFmpeg: %w", err)
+	}
+
+	windows, silences, parseErr := parseAudioQCMetadata(stdout)
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("error running audio filters: %w", err)
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	report := &AudioQCReport{StreamIndex: stream.Index}
+	a.evaluate(report, windows, silences)
+	return report, nil
+}
+
+// Count returns the number of events of the given type.
+func (r *AudioQCReport) Count(eventType string) int {
+	count := 0
+	for _, event := range r.Events {
+		if event.Type == eventType {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,217 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the audio QC analyzer.
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// audioQCTestWindow describes a stereo window used to build synthetic ametadata output.
+type audioQCTestWindow struct {
+	left, right  float64 // RMS levels in dBFS
+	peak         float64 // Peak level of the left channel in dBFS
+	peakCount    int     // Samples at the peak level on the left channel
+	phase        float64 // Phase correlation
+	dcOffset     float64 // DC offset of the right channel
+	silenceStart string  // Optional silence_start value
+	silenceEnd   string  // Optional silence_end value
+}
+
+// AudioQCAnalyzerTestSuite defines the test suite for AudioQCAnalyzer.
+// It feeds synthetic filter output so no sample media is required.
+type AudioQCAnalyzerTestSuite struct {
+	suite.Suite
+	analyzer *AudioQCAnalyzer // AudioQCAnalyzer instance under test
+}
+
+// SetupSuite creates the analyzer.
+func (s *AudioQCAnalyzerTestSuite) SetupSuite() {
+	s.analyzer = &AudioQCAnalyzer{FFmpegPath: "ffmpeg"}
+}
+
+// audioQCTestOutput renders windows in the ametadata print format.
+func audioQCTestOutput(windows []audioQCTestWindow) string {
+	var sb strings.Builder
+	for i, w := range windows {
+		fmt.Fprintf(&sb, "frame:%-4d pts:%-8d pts_time:%g\n", i, i*4800, float64(i)/10)
+		fmt.Fprintf(&sb, "lavfi.astats.1.DC_offset=0.000000\n")
+		fmt.Fprintf(&sb, "lavfi.astats.1.Peak_level=%f\n", w.peak)
+		fmt.Fprintf(&sb, "lavfi.astats.1.Peak_count=%d.000000\n", w.peakCount)
+		fmt.Fprintf(&sb, "lavfi.astats.1.RMS_level=%f\n", w.left)
+		fmt.Fprintf(&sb, "lavfi.astats.2.DC_offset=%f\n", w.dcOffset)
+		fmt.Fprintf(&sb, "lavfi.astats.2.Peak_level=-12.000000\n")
+		fmt.Fprintf(&sb, "lavfi.astats.2.Peak_count=1.000000\n")
+		if w.right < -200 {
+			fmt.Fprintf(&sb, "lavfi.astats.2.RMS_level=-inf\n")
+		} else {
+			fmt.Fprintf(&sb, "lavfi.astats.2.RMS_level=%f\n", w.right)
+		}
+		fmt.Fprintf(&sb, "lavfi.astats.Overall.Number_of_samples=4800.000000\n")
+		fmt.Fprintf(&sb, "lavfi.aphasemeter.phase=%f\n", w.phase)
+		if w.silenceStart != "" {
+			fmt.Fprintf(&sb, "lavfi.silence_start=%s\n", w.silenceStart)
+		}
+		if w.silenceEnd != "" {
+			fmt.Fprintf(&sb, "lavfi.silence_end=%s\nlavfi.silence_duration=2.5\n", w.silenceEnd)
+		}
+	}
+	return sb.String()
+}
+
+// repeatWindow returns count copies of a window.
+func repeatWindow(window audioQCTestWindow, count int) []audioQCTestWindow {
+	windows := make([]audioQCTestWindow, count)
+	for i := range windows {
+		windows[i] = window
+	}
+	return windows
+}
+
+// TestParseMetadata verifies parsing of windows, channels, phase and silences, including logged output.
+func (s *AudioQCAnalyzerTestSuite) TestParseMetadata() {
+	normal := audioQCTestWindow{left: -20, right: -20, peak: -6, phase: 0.9}
+	output := audioQCTestOutput([]audioQCTestWindow{normal, {left: -20, right: -300, peak: -6, phase: 0.9, silenceStart: "0.1"}})
+	// Logged lines carry a filter prefix
+	output = strings.ReplaceAll(output, "lavfi.aphasemeter", "[Parsed_ametadata_4 @ 0x55d] lavfi.aphasemeter")
+
+	windows, silences, err := parseAudioQCMetadata(strings.NewReader(output))
+	require.NoError(s.T(), err)
+	require.Len(s.T(), windows, 2)
+
+	assert.InDelta(s.T(), 0.1, windows[1].time, 1e-9)
+	require.Len(s.T(), windows[1].channels, 2)
+	assert.Equal(s.T(), -20.0, windows[1].channels[1].rmsLevel)
+	assert.Equal(s.T(), -6.0, windows[1].channels[1].peakLevel)
+	assert.True(s.T(), windows[1].channels[2].rmsLevel < -1000)
+	assert.True(s.T(), windows[1].hasPhase)
+	assert.Equal(s.T(), 0.9, windows[1].phase)
+
+	// The silence is still open at the end of the stream
+	assert.Equal(s.T(), [][2]float64{{0.1, 0.2}}, silences)
+}
+
+// TestParseNaN verifies that nan values are skipped so no event carries a non-finite value.
+func (s *AudioQCAnalyzerTestSuite) TestParseNaN() {
+	windows := repeatWindow(audioQCTestWindow{left: -20, right: -20, peak: -6, phase: -0.8, dcOffset: -0.05}, 30)
+	output := audioQCTestOutput(windows)
+	output = strings.Replace(output, "lavfi.aphasemeter.phase=-0.800000", "lavfi.aphasemeter.phase=nan", 1)
+	output = strings.Replace(output, "lavfi.astats.2.DC_offset=-0.050000", "lavfi.astats.2.DC_offset=nan", 1)
+
+	parsed, silences, err := parseAudioQCMetadata(strings.NewReader(output))
+	require.NoError(s.T(), err)
+	require.Len(s.T(), parsed, 30)
+	assert.False(s.T(), parsed[0].hasPhase)
+	assert.Equal(s.T(), 0.0, parsed[0].channels[2].dcOffset)
+
+	report := &AudioQCReport{}
+	s.analyzer.evaluate(report, parsed, silences)
+	assert.Equal(s.T(), 1, report.Count(AudioQCPhase))
+	assert.Equal(s.T(), 1, report.Count(AudioQCDCOffset))
+	for _, event := range report.Events {
+		assert.False(s.T(), math.IsInf(event.Value, 0) || math.IsNaN(event.Value), event.Type)
+	}
+}
+
+// TestDetectors verifies every defect type on a synthetic stereo stream.
+func (s *AudioQCAnalyzerTestSuite) TestDetectors() {
+	normal := audioQCTestWindow{left: -20, right: -20, peak: -6, phase: 0.9}
+	clipped := audioQCTestWindow{left: -8, right: -20, peak: 0, peakCount: 12, phase: 0.9}
+	singlePeak := audioQCTestWindow{left: -8, right: -20, peak: 0, peakCount: 1, phase: 0.9}
+	rightSilent := audioQCTestWindow{left: -20, right: -300, peak: -6, phase: 0}
+	inverted := audioQCTestWindow{left: -20, right: -20, peak: -6, phase: -0.95}
+	dropout := audioQCTestWindow{left: -90, right: -90, peak: -80, phase: 0}
+
+	var windows []audioQCTestWindow
+	windows = append(windows, repeatWindow(normal, 5)...)       // 0.0 - 0.5
+	windows = append(windows, repeatWindow(clipped, 3)...)      // 0.5 - 0.8
+	windows = append(windows, singlePeak)                       // 0.8 - 0.9
+	windows = append(windows, repeatWindow(rightSilent, 15)...) // 0.9 - 2.4
+	windows = append(windows, repeatWindow(normal, 2)...)       // 2.4 - 2.6
+	windows = append(windows, repeatWindow(inverted, 12)...)    // 2.6 - 3.8
+	windows = append(windows, repeatWindow(dropout, 2)...)      // 3.8 - 4.0
+	windows = append(windows, repeatWindow(normal, 3)...)       // 4.0 - 4.3
+	windows[len(windows)-1].silenceStart = "1.0"
+	windows[len(windows)-1].silenceEnd = "3.5"
+
+	parsed, silences, err := parseAudioQCMetadata(strings.NewReader(audioQCTestOutput(windows)))
+	require.NoError(s.T(), err)
+
+	report := &AudioQCReport{}
+	s.analyzer.evaluate(report, parsed, silences)
+
+	assert.InDelta(s.T(), 4.3, report.Duration, 1e-9)
+	assert.Equal(s.T(), 1, report.Count(AudioQCClipping))
+	assert.Equal(s.T(), 1, report.Count(AudioQCSilence))
+	assert.Equal(s.T(), 1, report.Count(AudioQCSilentChannel))
+	assert.Equal(s.T(), 1, report.Count(AudioQCPhase))
+	assert.Equal(s.T(), 1, report.Count(AudioQCDropout))
+	assert.Equal(s.T(), 0, report.Count(AudioQCDCOffset))
+
+	for _, event := range report.Events {
+		switch event.Type {
+		case AudioQCClipping:
+			assert.Equal(s.T(), 1, event.Channel)
+			assert.InDelta(s.T(), 0.5, event.Start, 1e-9)
+			assert.InDelta(s.T(), 0.8, event.End, 1e-9)
+			assert.Equal(s.T(), "36 samples at full scale", event.Description)
+		case AudioQCSilentChannel:
+			assert.Equal(s.T(), 2, event.Channel)
+			assert.InDelta(s.T(), 0.9, event.Start, 1e-9)
+			assert.InDelta(s.T(), 2.4, event.End, 1e-9)
+		case AudioQCPhase:
+			assert.InDelta(s.T(), 2.6, event.Start, 1e-9)
+			assert.InDelta(s.T(), -0.95, event.Value, 1e-9)
+		case AudioQCDropout:
+			assert.InDelta(s.T(), 3.8, event.Start, 1e-9)
+			assert.Equal(s.T(), "200 ms gap in present audio", event.Description)
+		case AudioQCSilence:
+			assert.InDelta(s.T(), 2.5, event.Value, 1e-9)
+		}
+	}
+
+	// Events are ordered by start time
+	for i := 1; i < len(report.Events); i++ {
+		assert.LessOrEqual(s.T(), report.Events[i-1].Start, report.Events[i].Start)
+	}
+}
+
+// TestDCOffset verifies detection of a channel with a constant DC offset.
+func (s *AudioQCAnalyzerTestSuite) TestDCOffset() {
+	windows := repeatWindow(audioQCTestWindow{left: -20, right: -20, peak: -6, phase: 0.9, dcOffset: -0.05}, 10)
+	parsed, silences, err := parseAudioQCMetadata(strings.NewReader(audioQCTestOutput(windows)))
+	require.NoError(s.T(), err)
+
+	report := &AudioQCReport{}
+	s.analyzer.evaluate(report, parsed, silences)
+
+	require.Len(s.T(), report.Events, 1)
+	assert.Equal(s.T(), AudioQCDCOffset, report.Events[0].Type)
+	assert.Equal(s.T(), 2, report.Events[0].Channel)
+	assert.Equal(s.T(), "average DC offset -5.00% of full scale", report.Events[0].Description)
+}
+
+// TestFilterChain verifies the filter chain for mono and stereo streams.
+func (s *AudioQCAnalyzerTestSuite) TestFilterChain() {
+	stereo := s.analyzer.filterChain(AudioStream{Channels: 2, SamplingRate: 44100})
+	assert.Equal(s.T(), "asetnsamples=n=4410:p=0,astats=metadata=1:reset=1,aphasemeter=video=0,"+
+		"silencedetect=noise=-60dB:d=2,ametadata=mode=print:file=-", stereo)
+
+	mono := s.analyzer.filterChain(AudioStream{Channels: 1})
+	assert.NotContains(s.T(), mono, "aphasemeter")
+	assert.Contains(s.T(), mono, "asetnsamples=n=4800:p=0")
+
+	_, err := NewAudioQCAnalyzer(nil)
+	assert.Error(s.T(), err)
+}
+
+// TestAudioQCAnalyzerSuite runs the AudioQCAnalyzer test suite.
+func TestAudioQCAnalyzerSuite(t *testing.T) {
+	suite.Run(t, new(AudioQCAnalyzerTestSuite))
+}
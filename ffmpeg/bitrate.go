@@ -8,7 +8,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"os/exec"
+	"strconv"
 	"strings"
 )
 
@@ -45,11 +47,46 @@ func NewBitrateAnalyzer(ffmpegInfo *FFmpegInfo) (*BitrateAnalyzer, error) {
 	}, nil
 }
 
+// NewBitrateStats creates empty BitrateStats for a video stream with the given declared frame rate.
+func NewBitrateStats(frameRate float64) *BitrateStats {
+	return &BitrateStats{FrameRate: frameRate}
+}
+
 // Private methods (alphabetical)
 // None currently defined
 
 // Public methods (alphabetical)
 
+// AddFrame adds the size and time of a frame measured by a BitrateAnalyzer.
+// Frames are expected in presentation order, as the analyzer sends them.
+func (s *BitrateStats) AddFrame(frame FrameBitrateInfo) {
+	if s.Frames == 0 {
+		s.first = frame.Time
+	} else {
+		interval := frame.Time - s.last
+		if s.Frames == 1 {
+			s.minInterval, s.maxInterval = interval, interval
+		}
+		s.minInterval = math.Min(s.minInterval, interval)
+		s.maxInterval = math.Max(s.maxInterval, interval)
+	}
+	s.last = frame.Time
+	s.Frames++
+	s.TotalBits += frame.Bitrate
+
+	// The peak is measured over full seconds of frames
+	windowSize := max(int(math.Round(s.FrameRate)), 1)
+	s.window = append(s.window, frame.Bitrate)
+	s.windowSum += frame.Bitrate
+	if len(s.window) > windowSize {
+		s.windowSum -= s.window[0]
+		s.window = s.window[1:]
+	}
+	if len(s.window) == windowSize && s.FrameRate > 0 {
+		s.PeakBitrate = math.Max(s.PeakBitrate, float64(s.windowSum)*s.FrameRate/float64(windowSize))
+	}
+}
+
 // Analyze processes a video file to extract frame-by-frame bitrate information.
 // It examines each video frame, calculating its bitrate and collecting metadata such as
 // frame type and timestamps. Results are streamed through the provided channel to
@@ -88,6 +125,26 @@ func (b *BitrateAnalyzer) Analyze(ctx context.Context, filePath string, resultCh
 	return b.waitForCompletion(ctx, cmd, done, errCh, cancel)
 }
 
+// Finish computes the duration, the average bitrate, the measured frame rate and the
+// frame rate mode once all frames have been added.
+func (s *BitrateStats) Finish() {
+	s.window = nil
+	if s.Frames < 2 || s.last <= s.first {
+		return
+	}
+
+	// The last frame lasts as long as the average frame
+	span := s.last - s.first
+	s.MeasuredFrameRate = float64(s.Frames-1) / span
+	s.Duration = span + 1/s.MeasuredFrameRate
+	s.AverageBitrate = float64(s.TotalBits) / s.Duration
+
+	s.FrameRateMode = FrameRateModeConstant
+	if s.maxInterval-s.minInterval > bitrateCFRTolerance {
+		s.FrameRateMode = FrameRateModeVariable
+	}
+}
+
 // setupCommand creates and starts the FFprobe command.
 func (b *BitrateAnalyzer) setupCommand(ctx context.Context, filePath string) (*exec.Cmd, io.ReadCloser, error) {
 	cmd := exec.CommandContext(
@@ -202,11 +259,29 @@ func (b *BitrateAnalyzer) processVideoFrame(ctx context.Context, frameInfo ffpro
 		return nil // Skip frames with invalid size, not a fatal error
 	}
 
-	// Extract PTS (Presentation Timestamp)
-	pts, _ := frameInfo.PktPts.Int64()
+	// Extract PTS (Presentation Timestamp), older FFprobe versions only print the packet PTS
+	pts := NoTimestamp
+	for _, rawPts := range []json.Number{frameInfo.Pts, frameInfo.PktPts} {
+		if value, err := rawPts.Int64(); err == nil {
+			pts = value
+			break
+		}
+	}
 
 	// Extract DTS (Decoding Timestamp)
-	dts, _ := frameInfo.PktDts.Int64()
+	dts, err := frameInfo.PktDts.Int64()
+	if err != nil {
+		dts = NoTimestamp
+	}
+
+	// Extract the presentation time, older FFprobe versions only print the packet time
+	var frameTime float64
+	for _, rawTime := range []string{frameInfo.PtsTime, frameInfo.BestEffortTime, frameInfo.PktPtsTime} {
+		if value, err := strconv.ParseFloat(rawTime, 64); err == nil {
+			frameTime = value
+			break
+		}
+	}
 
 	// Determine frame type from picture type
 	frameType := strings.ToUpper(frameInfo.PictType)
@@ -221,6 +296,8 @@ func (b *BitrateAnalyzer) processVideoFrame(ctx context.Context, frameInfo ffpro
 		Bitrate:     pktSize * 8, // Convert bytes to bits
 		PTS:         pts,
 		DTS:         dts,
+		Time:        frameTime,
+		KeyFrame:    frameInfo.KeyFrame == 1,
 	}
 
 	// Send to the channel
@@ -3,6 +3,7 @@ package ffmpeg
 
 import (
 	"context"
+	"math"
 	"os"
 	"strings"
 	"testing"
@@ -246,3 +247,59 @@ func (s *BitrateAnalyzerTestSuite) TestNewBitrateAnalyzer() {
 func TestBitrateAnalyzerSuite(t *testing.T) {
 	suite.Run(t, new(BitrateAnalyzerTestSuite))
 }
+
+// BitrateStatsTestSuite defines the test suite for BitrateStats.
+// It adds synthetic frames so no sample media is required.
+type BitrateStatsTestSuite struct {
+	suite.Suite
+}
+
+// addFrames adds 48 frames of 23.976 fps with millisecond timestamps, as Matroska stores them.
+// Every 24th frame is an I-frame 8 times larger than the others.
+func (s *BitrateStatsTestSuite) addFrames(stats *BitrateStats) {
+	for n := 0; n < 48; n++ {
+		bits := int64(100000)
+		if n%24 == 0 {
+			bits = 800000
+		}
+		stats.AddFrame(FrameBitrateInfo{FrameNumber: n, Bitrate: bits, Time: math.Round(float64(n)*1001/24) / 1000})
+	}
+}
+
+// TestConstantFrameRate verifies the peak, the average and the detection of a constant frame rate.
+func (s *BitrateStatsTestSuite) TestConstantFrameRate() {
+	stats := NewBitrateStats(24000.0 / 1001)
+	s.addFrames(stats)
+	stats.Finish()
+
+	assert.Equal(s.T(), 48, stats.Frames)
+	assert.Equal(s.T(), FrameRateModeConstant, stats.FrameRateMode)
+	assert.InDelta(s.T(), 23.976, stats.MeasuredFrameRate, 0.01)
+	assert.InDelta(s.T(), 2.002, stats.Duration, 0.002)
+
+	// A second holds one I-frame and 23 P-frames
+	assert.InDelta(s.T(), 3100000*24000.0/1001/24, stats.PeakBitrate, 1e-6)
+	assert.InDelta(s.T(), float64(stats.TotalBits)/stats.Duration, stats.AverageBitrate, 1e-6)
+}
+
+// TestVariableFrameRate verifies that a missing frame makes the frame rate variable.
+func (s *BitrateStatsTestSuite) TestVariableFrameRate() {
+	stats := NewBitrateStats(25)
+	for _, n := range []int{0, 1, 2, 4, 5} {
+		stats.AddFrame(FrameBitrateInfo{FrameNumber: n, Bitrate: 1000, Time: float64(n) / 25})
+	}
+	stats.Finish()
+	assert.Equal(s.T(), FrameRateModeVariable, stats.FrameRateMode)
+
+	// A single frame has no frame rate mode
+	single := NewBitrateStats(25)
+	single.AddFrame(FrameBitrateInfo{Bitrate: 1000})
+	single.Finish()
+	assert.Empty(s.T(), single.FrameRateMode)
+	assert.Zero(s.T(), single.PeakBitrate)
+}
+
+// TestBitrateStatsSuite runs the BitrateStats test suite.
+func TestBitrateStatsSuite(t *testing.T) {
+	suite.Run(t, new(BitrateStatsTestSuite))
+}
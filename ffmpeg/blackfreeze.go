@@ -0,0 +1,243 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Public functions (alphabetical)
+
+// NewBlackFreezeAnalyzer creates a new BlackFreezeAnalyzer with the default thresholds.
+func NewBlackFreezeAnalyzer(ffmpegInfo *FFmpegInfo) (*BlackFreezeAnalyzer, error) {
+	if ffmpegInfo == nil || !ffmpegInfo.Installed {
+		return nil, fmt.Errorf("ffmpeg not available")
+	}
+
+	return &BlackFreezeAnalyzer{
+		FFmpegPath:        ffmpegInfo.Path,
+		BlackMinDuration:  blackFreezeDefaultBlackDuration,
+		PictureThreshold:  blackFreezeDefaultPictureThreshold,
+		PixelThreshold:    blackFreezeDefaultPixelThreshold,
+		FreezeMinDuration: blackFreezeDefaultFreezeDuration,
+		FreezeNoise:       blackFreezeDefaultFreezeNoise,
+	}, nil
+}
+
+// Private methods (alphabetical)
+
+// filterChain builds the detection filters followed by a metadata printer.
+// The blackframe thresholds are derived from the blackdetect ones so both agree on what black is.
+func (a *BlackFreezeAnalyzer) filterChain() string {
+	return fmt.Sprintf(
+		"blackdetect=d=%g:pic_th=%g:pix_th=%g,blackframe=amount=%d:threshold=%d,freezedetect=n=%gdB:d=%g,metadata=mode=print:file=-",
+		a.BlackMinDuration, a.PictureThreshold, a.PixelThreshold,
+		int(math.Round(a.PictureThreshold*100)), int(math.Round(a.PixelThreshold*255)),
+		a.FreezeNoise, a.FreezeMinDuration,
+	)
+}
+
+// isEdge reports whether an event touches the start or the end of the stream.
+func (a *BlackFreezeAnalyzer) isEdge(event VideoEvent, streamStart, streamEnd float64) bool {
+	return event.Start <= streamStart+blackFreezeEdgeTolerance ||
+		(streamEnd > 0 && event.End >= streamEnd-blackFreezeEdgeTolerance)
+}
+
+// parseMetadata reads the metadata printed by the filter chain and sends every completed
+// interval to the channel. Intervals still open at the end of the output are closed at the
+// end of the stream. blackdetect marks every black run, so only runs of at least BlackMinDuration
+// are reported as black segments; shorter runs of blackframe frames are reported as blank frames.
+func (a *BlackFreezeAnalyzer) parseMetadata(ctx context.Context, r io.Reader, stream VideoStream, eventCh chan<- VideoEvent) error {
+	streamStart := stream.StartTime
+	streamEnd := 0.0
+	if stream.Duration > 0 {
+		streamEnd = stream.StartTime + stream.Duration
+	}
+	frameDuration := 0.0
+	if stream.FrameRate > 0 {
+		frameDuration = 1 / stream.FrameRate
+	}
+
+	emit := func(event VideoEvent, trailing bool) error {
+		// Only black is ignored at the edges, frozen video is always reported
+		if a.IgnoreEdges && event.Type != VideoEventFreeze && (trailing || a.isEdge(event, streamStart, streamEnd)) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case eventCh <- event:
+			return nil
+		}
+	}
+
+	var (
+		frameNumber, blankLast                int
+		frameTime, lastTime                   float64
+		blackStart, freezeStart               = math.NaN(), math.NaN()
+		blankOpen                             bool
+		blankStart, blankEnd, blankBlackRatio float64
+	)
+	closeBlank := func(trailing bool) error {
+		if !blankOpen {
+			return nil
+		}
+		blankOpen = false
+		if blankEnd-blankStart >= a.BlackMinDuration {
+			return nil
+		}
+		return emit(VideoEvent{Type: VideoEventBlankFrames, Start: blankStart, End: blankEnd, Value: blankBlackRatio}, trailing)
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if match := metadataFrameRegex.FindStringSubmatch(line); match != nil {
+			frameNumber, _ = strconv.Atoi(match[1])
+			frameTime, _ = strconv.ParseFloat(match[2], 64)
+			lastTime = math.Max(lastTime, frameTime+frameDuration)
+			if blankOpen && frameNumber != blankLast+1 {
+				if err := closeBlank(false); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		key, rawValue, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		value, err := strconv.ParseFloat(rawValue, 64)
+		if err != nil {
+			continue
+		}
+
+		switch key {
+		case "lavfi.black_start":
+			blackStart = value
+		case "lavfi.black_end":
+			if math.IsNaN(blackStart) {
+				blackStart = streamStart
+			}
+			if value-blackStart >= a.BlackMinDuration {
+				if err := emit(VideoEvent{Type: VideoEventBlack, Start: blackStart, End: value}, false); err != nil {
+					return err
+				}
+			}
+			blackStart = math.NaN()
+		case "lavfi.freezedetect.freeze_start":
+			freezeStart = value
+		case "lavfi.freezedetect.freeze_end":
+			if !math.IsNaN(freezeStart) {
+				if err := emit(VideoEvent{Type: VideoEventFreeze, Start: freezeStart, End: value}, false); err != nil {
+					return err
+				}
+			}
+			freezeStart = math.NaN()
+		case "lavfi.blackframe.pblack":
+			if !blankOpen {
+				blankOpen = true
+				blankStart = frameTime
+				blankBlackRatio = 0
+			}
+			blankLast = frameNumber
+			blankEnd = frameTime + frameDuration
+			blankBlackRatio = math.Max(blankBlackRatio, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading detection output: %w", err)
+	}
+
+	// Close the intervals still open at the end of the stream
+	end := math.Max(streamEnd, lastTime)
+	if err := closeBlank(blankEnd >= end-blackFreezeEdgeTolerance); err != nil {
+		return err
+	}
+	if !math.IsNaN(blackStart) && end-blackStart >= a.BlackMinDuration {
+		if err := emit(VideoEvent{Type: VideoEventBlack, Start: blackStart, End: end}, true); err != nil {
+			return err
+		}
+	}
+	if !math.IsNaN(freezeStart) {
+		if err := emit(VideoEvent{Type: VideoEventFreeze, Start: freezeStart, End: end}, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Public methods (alphabetical)
+
+// Analyze decodes a video stream and sends black segments, blank frames and frozen segments
+// to the provided channel as soon as each interval is complete. The channel is closed when
+// the analysis ends. With IgnoreEdges set, black at the start and at the end of the stream is skipped.
+func (a *BlackFreezeAnalyzer) Analyze(ctx context.Context, filePath string, stream VideoStream, eventCh chan<- VideoEvent) error {
+	defer close(eventCh)
+
+	cmd := exec.CommandContext(
+		ctx,
+		a.FFmpegPath,
+		"-hide_banner",
+		"-nostats",
+		"-v", "error",
+		"-i", filePath,
+		"-map", fmt.Sprintf("0:%d", stream.Index),
+		"-filter:v", a.filterChain(),
+		"-f", "null",
+		"-",
+	)
+
+	// The metadata filter prints the detections on stdout
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start FFmpeg: %w", err)
+	}
+
+	parseErr := a.parseMetadata(ctx, stdout, stream, eventCh)
+	if parseErr != nil {
+		// Drain the output so FFmpeg can exit
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("error running detection filters: %w", err)
+	}
+	return parseErr
+}
+
+// Count returns the number of events of the given type.
+func (r *VideoEventReport) Count(eventType string) int {
+	count := 0
+	for _, event := range r.Events {
+		if event.Type == eventType {
+			count++
+		}
+	}
+	return count
+}
+
+// TotalDuration returns the summed duration in seconds of the events of the given type.
+func (r *VideoEventReport) TotalDuration(eventType string) float64 {
+	total := 0.0
+	for _, event := range r.Events {
+		if event.Type == eventType {
+			total += event.End - event.Start
+		}
+	}
+	return total
+}
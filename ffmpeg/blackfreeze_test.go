@@ -0,0 +1,139 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the black and freeze frame analyzer.
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// blackFreezeTestOutput renders the metadata printed for a 10 second, 25 fps stream starting
+// with 1.2 s of black, containing a two frame black flash at 3 s, frozen video from 4 s to
+// 6.48 s and ending with 1 s of black. Only frames carrying metadata are printed.
+func blackFreezeTestOutput() string {
+	isBlack := func(n int) bool { return n < 30 || (n >= 75 && n < 77) || n >= 225 }
+
+	var sb strings.Builder
+	for n := 0; n < 250; n++ {
+		var lines []string
+		if isBlack(n) {
+			lines = append(lines, "lavfi.blackframe.pblack=100")
+			if n == 0 || !isBlack(n-1) {
+				lines = append(lines, fmt.Sprintf("lavfi.black_start=%g", float64(n)/25))
+			}
+		} else if n > 0 && isBlack(n-1) {
+			lines = append(lines, fmt.Sprintf("lavfi.black_end=%g", float64(n)/25))
+		}
+		switch n {
+		case 150:
+			lines = append(lines, "lavfi.freezedetect.freeze_start=4", "lavfi.freezedetect.freeze_duration=2")
+		case 162:
+			lines = append(lines, "lavfi.freezedetect.freeze_duration=2.48", "lavfi.freezedetect.freeze_end=6.48")
+		}
+
+		if len(lines) > 0 {
+			fmt.Fprintf(&sb, "frame:%-4d pts:%-8d pts_time:%g\n%s\n", n, n, float64(n)/25, strings.Join(lines, "\n"))
+		}
+	}
+	return sb.String()
+}
+
+// BlackFreezeAnalyzerTestSuite defines the test suite for BlackFreezeAnalyzer.
+// It parses synthetic metadata output so no sample media is required.
+type BlackFreezeAnalyzerTestSuite struct {
+	suite.Suite
+	analyzer *BlackFreezeAnalyzer // BlackFreezeAnalyzer instance under test
+	stream   VideoStream          // Stream matching blackFreezeTestOutput
+}
+
+// SetupTest creates an analyzer with the default thresholds.
+func (s *BlackFreezeAnalyzerTestSuite) SetupTest() {
+	analyzer, err := NewBlackFreezeAnalyzer(&FFmpegInfo{Installed: true, Path: "ffmpeg"})
+	require.NoError(s.T(), err)
+	s.analyzer = analyzer
+	s.stream = VideoStream{Index: 0, FrameRate: 25, Duration: 10}
+}
+
+// parse runs the parser and collects the events sent on the channel.
+func (s *BlackFreezeAnalyzerTestSuite) parse(output string) []VideoEvent {
+	eventCh := make(chan VideoEvent, 16)
+	require.NoError(s.T(), s.analyzer.parseMetadata(context.Background(), strings.NewReader(output), s.stream, eventCh))
+	close(eventCh)
+
+	var events []VideoEvent
+	for event := range eventCh {
+		events = append(events, event)
+	}
+	return events
+}
+
+// TestParseMetadata verifies black segments, blank flashes and frozen segments.
+func (s *BlackFreezeAnalyzerTestSuite) TestParseMetadata() {
+	events := s.parse(blackFreezeTestOutput())
+	require.Len(s.T(), events, 4)
+
+	assert.Equal(s.T(), VideoEvent{Type: VideoEventBlack, Start: 0, End: 1.2}, events[0])
+
+	// The flash is shorter than a black segment and comes from blackframe
+	assert.Equal(s.T(), VideoEventBlankFrames, events[1].Type)
+	assert.InDelta(s.T(), 3.0, events[1].Start, 1e-9)
+	assert.InDelta(s.T(), 3.08, events[1].End, 1e-9)
+	assert.Equal(s.T(), 100.0, events[1].Value)
+
+	assert.Equal(s.T(), VideoEvent{Type: VideoEventFreeze, Start: 4, End: 6.48}, events[2])
+
+	// The black segment still open at the end of the output is closed at the end of the stream
+	assert.Equal(s.T(), VideoEventBlack, events[3].Type)
+	assert.InDelta(s.T(), 9.0, events[3].Start, 1e-9)
+	assert.InDelta(s.T(), 10.0, events[3].End, 1e-9)
+
+	report := &VideoEventReport{Events: events}
+	assert.Equal(s.T(), 2, report.Count(VideoEventBlack))
+	assert.InDelta(s.T(), 2.2, report.TotalDuration(VideoEventBlack), 1e-9)
+	assert.InDelta(s.T(), 2.48, report.TotalDuration(VideoEventFreeze), 1e-9)
+}
+
+// TestIgnoreEdges verifies that leading and trailing black are skipped while frozen video is kept.
+func (s *BlackFreezeAnalyzerTestSuite) TestIgnoreEdges() {
+	s.analyzer.IgnoreEdges = true
+	events := s.parse(blackFreezeTestOutput())
+
+	require.Len(s.T(), events, 2)
+	assert.Equal(s.T(), VideoEventBlankFrames, events[0].Type)
+	assert.InDelta(s.T(), 3.0, events[0].Start, 1e-9)
+	assert.Equal(s.T(), VideoEventFreeze, events[1].Type)
+}
+
+// TestFilterChain verifies that thresholds are passed to the filters.
+func (s *BlackFreezeAnalyzerTestSuite) TestFilterChain() {
+	assert.Equal(s.T(), "blackdetect=d=1:pic_th=0.98:pix_th=0.1,blackframe=amount=98:threshold=26,"+
+		"freezedetect=n=-60dB:d=2,metadata=mode=print:file=-", s.analyzer.filterChain())
+
+	s.analyzer.BlackMinDuration = 0.5
+	s.analyzer.FreezeNoise = -50
+	assert.Contains(s.T(), s.analyzer.filterChain(), "blackdetect=d=0.5:")
+	assert.Contains(s.T(), s.analyzer.filterChain(), "freezedetect=n=-50dB:")
+
+	_, err := NewBlackFreezeAnalyzer(nil)
+	assert.Error(s.T(), err)
+}
+
+// TestCancel verifies that a cancelled context stops the parser.
+func (s *BlackFreezeAnalyzerTestSuite) TestCancel() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := s.analyzer.parseMetadata(ctx, strings.NewReader(blackFreezeTestOutput()), s.stream, make(chan VideoEvent))
+	assert.ErrorIs(s.T(), err, context.Canceled)
+}
+
+// TestBlackFreezeAnalyzerSuite runs the BlackFreezeAnalyzer test suite.
+func TestBlackFreezeAnalyzerSuite(t *testing.T) {
+	suite.Run(t, new(BlackFreezeAnalyzerTestSuite))
+}
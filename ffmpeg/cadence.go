@@ -0,0 +1,186 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+// Private variables (alphabetical)
+
+var (
+	// mpdecimateRegex matches the decision printed by the mpdecimate filter for every frame,
+	// such as "drop pts:3003 pts_time:0.0333667 drop_count:1 keep_count:-1".
+	mpdecimateRegex = regexp.MustCompile(`\b(keep|drop) pts:(\S+) pts_time:(\S+)`)
+)
+
+// Private functions (alphabetical)
+
+// frameRateOf returns the number of frames per second over a time range, 0 when the range is empty.
+func frameRateOf(frames int, start, end float64) float64 {
+	if end <= start {
+		return 0
+	}
+	return float64(frames) / (end - start)
+}
+
+// Public functions (alphabetical)
+
+// NewCadenceAnalyzer creates a new CadenceAnalyzer with the default segment duration.
+func NewCadenceAnalyzer(ffmpegInfo *FFmpegInfo) (*CadenceAnalyzer, error) {
+	if ffmpegInfo == nil || !ffmpegInfo.Installed {
+		return nil, fmt.Errorf("ffmpeg not available")
+	}
+
+	return &CadenceAnalyzer{
+		FFmpegPath:      ffmpegInfo.Path,
+		SegmentDuration: cadenceSegmentDuration,
+	}, nil
+}
+
+// Private methods (alphabetical)
+
+// parseLog reads the keep and drop decisions of mpdecimate and builds the report. A dropped
+// (duplicate) frame extends the current duplicate run; a distance between two frames larger
+// than cadenceDropFactor frame durations is counted as dropped frames.
+func (a *CadenceAnalyzer) parseLog(r io.Reader, stream VideoStream) (*CadenceReport, error) {
+	report := &CadenceReport{StreamIndex: stream.Index, FrameRate: stream.FrameRate}
+	frameDuration := 0.0
+	if stream.FrameRate > 0 {
+		frameDuration = 1 / stream.FrameRate
+	}
+
+	firstTime, previousTime := math.NaN(), math.NaN()
+	openRun := -1
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		match := mpdecimateRegex.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		frameTime, err := strconv.ParseFloat(match[3], 64)
+		if err != nil {
+			continue
+		}
+		if math.IsNaN(firstTime) {
+			firstTime = frameTime
+		}
+
+		segmentStart := firstTime + math.Floor((frameTime-firstTime)/a.SegmentDuration)*a.SegmentDuration
+		if len(report.Segments) == 0 || segmentStart > report.Segments[len(report.Segments)-1].Start {
+			report.Segments = append(report.Segments, CadenceSegment{Start: segmentStart})
+		}
+		segment := &report.Segments[len(report.Segments)-1]
+
+		if gap := frameTime - previousTime; frameDuration > 0 && gap > cadenceDropFactor*frameDuration {
+			missing := int(math.Round(gap/frameDuration)) - 1
+			report.Runs = append(report.Runs, CadenceRun{Type: CadenceDropped, Start: previousTime + frameDuration, End: frameTime, Frames: missing})
+			report.Dropped += missing
+			segment.Dropped += missing
+			openRun = -1
+		}
+
+		report.Frames++
+		segment.Frames++
+		segment.End = frameTime + frameDuration
+		previousTime = frameTime
+
+		if match[1] != "drop" {
+			openRun = -1
+			continue
+		}
+		report.Duplicates++
+		segment.Duplicates++
+		if openRun < 0 {
+			report.Runs = append(report.Runs, CadenceRun{Type: CadenceDuplicate, Start: frameTime})
+			openRun = len(report.Runs) - 1
+		}
+		report.Runs[openRun].End = frameTime + frameDuration
+		report.Runs[openRun].Frames++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading mpdecimate output: %w", err)
+	}
+
+	for i := range report.Segments {
+		segment := &report.Segments[i]
+		segment.UniqueFrameRate = frameRateOf(segment.Frames-segment.Duplicates, segment.Start, segment.End)
+	}
+	if len(report.Segments) > 0 {
+		report.UniqueFrameRate = frameRateOf(report.Frames-report.Duplicates, firstTime, report.Segments[len(report.Segments)-1].End)
+	}
+	return report, nil
+}
+
+// Public methods (alphabetical)
+
+// Analyze decodes a video stream through the mpdecimate filter, which reports every frame
+// repeating the previous one, and checks the cadence of the presentation timestamps for
+// dropped frames. The unique frame rate is computed for the whole stream and per segment.
+func (a *CadenceAnalyzer) Analyze(ctx context.Context, filePath string, stream VideoStream) (*CadenceReport, error) {
+	cmd := exec.CommandContext(
+		ctx,
+		a.FFmpegPath,
+		"-hide_banner",
+		"-nostats",
+		"-v", "debug",
+		"-i", filePath,
+		"-map", fmt.Sprintf("0:%d", stream.Index),
+		"-filter:v", "mpdecimate",
+		"-f", "null",
+		"-",
+	)
+
+	// mpdecimate logs its decision for every frame at debug level on stderr
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start FFmpeg: %w", err)
+	}
+
+	report, parseErr := a.parseLog(stderr, stream)
+	if parseErr != nil {
+		// Drain the output so FFmpeg can exit
+		_, _ = io.Copy(io.Discard, stderr)
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("error running mpdecimate filter: %w", err)
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return report, nil
+}
+
+// DuplicateRuns returns the number of runs of duplicate frames.
+func (r *CadenceReport) DuplicateRuns() int {
+	count := 0
+	for _, run := range r.Runs {
+		if run.Type == CadenceDuplicate {
+			count++
+		}
+	}
+	return count
+}
+
+// IrregularSegments returns the segments containing duplicate or dropped frames.
+func (r *CadenceReport) IrregularSegments() []CadenceSegment {
+	var segments []CadenceSegment
+	for _, segment := range r.Segments {
+		if segment.Duplicates > 0 || segment.Dropped > 0 {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
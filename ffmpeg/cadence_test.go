@@ -0,0 +1,96 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the duplicate and dropped frame analyzer.
+package ffmpeg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// cadenceTestLog renders the debug output of mpdecimate for 15 seconds of 25 fps video with
+// frames 50 to 54 repeating frame 49 and frames 300 to 302 missing, surrounded by unrelated
+// debug lines.
+func cadenceTestLog() string {
+	var sb strings.Builder
+	sb.WriteString("[h264 @ 0x5601] nal_unit_type: 5(IDR), nal_ref_idc: 3\n")
+	for n := 0; n < 375; n++ {
+		if n >= 300 && n < 303 {
+			continue
+		}
+		decision, dropCount := "keep", -1
+		if n >= 50 && n < 55 {
+			decision, dropCount = "drop", n-49
+		}
+		fmt.Fprintf(&sb, "[Parsed_mpdecimate_0 @ 0x5602] %s pts:%d pts_time:%g drop_count:%d keep_count:-1\n",
+			decision, n*512, float64(n)/25, dropCount)
+	}
+	sb.WriteString("[out#0/null @ 0x5603] Output file #0 (pipe:):\n")
+	return sb.String()
+}
+
+// CadenceAnalyzerTestSuite defines the test suite for CadenceAnalyzer.
+// It parses synthetic mpdecimate output so no sample media is required.
+type CadenceAnalyzerTestSuite struct {
+	suite.Suite
+	analyzer *CadenceAnalyzer // CadenceAnalyzer instance under test
+}
+
+// SetupTest creates an analyzer with the default segment duration.
+func (s *CadenceAnalyzerTestSuite) SetupTest() {
+	analyzer, err := NewCadenceAnalyzer(&FFmpegInfo{Installed: true, Path: "ffmpeg"})
+	require.NoError(s.T(), err)
+	s.analyzer = analyzer
+}
+
+// TestNewCadenceAnalyzer verifies that FFmpeg is required.
+func (s *CadenceAnalyzerTestSuite) TestNewCadenceAnalyzer() {
+	_, err := NewCadenceAnalyzer(nil)
+	assert.Error(s.T(), err)
+	assert.InDelta(s.T(), cadenceSegmentDuration, s.analyzer.SegmentDuration, 1e-9)
+}
+
+// TestParseLog verifies the duplicate runs, the dropped frames and the unique frame rates.
+func (s *CadenceAnalyzerTestSuite) TestParseLog() {
+	report, err := s.analyzer.parseLog(strings.NewReader(cadenceTestLog()), VideoStream{Index: 0, FrameRate: 25})
+	require.NoError(s.T(), err)
+
+	assert.Equal(s.T(), 372, report.Frames)
+	assert.Equal(s.T(), 5, report.Duplicates)
+	assert.Equal(s.T(), 3, report.Dropped)
+	assert.InDelta(s.T(), 367.0/15, report.UniqueFrameRate, 1e-9)
+
+	require.Len(s.T(), report.Runs, 2)
+	assert.Equal(s.T(), CadenceRun{Type: CadenceDuplicate, Start: 2, End: 2.2, Frames: 5}, report.Runs[0])
+	assert.Equal(s.T(), CadenceDropped, report.Runs[1].Type)
+	assert.InDelta(s.T(), 12, report.Runs[1].Start, 1e-9)
+	assert.InDelta(s.T(), 12.12, report.Runs[1].End, 1e-9)
+	assert.Equal(s.T(), 3, report.Runs[1].Frames)
+	assert.Equal(s.T(), 1, report.DuplicateRuns())
+
+	require.Len(s.T(), report.Segments, 2)
+	assert.Equal(s.T(), 250, report.Segments[0].Frames)
+	assert.InDelta(s.T(), 24.5, report.Segments[0].UniqueFrameRate, 1e-9)
+	assert.Equal(s.T(), 122, report.Segments[1].Frames)
+	assert.Equal(s.T(), 3, report.Segments[1].Dropped)
+	assert.InDelta(s.T(), 24.4, report.Segments[1].UniqueFrameRate, 1e-9)
+	assert.Len(s.T(), report.IrregularSegments(), 2)
+}
+
+// TestParseLogWithoutFrameRate verifies that the cadence check is skipped without a frame rate.
+func (s *CadenceAnalyzerTestSuite) TestParseLogWithoutFrameRate() {
+	report, err := s.analyzer.parseLog(strings.NewReader(cadenceTestLog()), VideoStream{Index: 0})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 0, report.Dropped)
+	assert.Equal(s.T(), 1, report.DuplicateRuns())
+	assert.InDelta(s.T(), 2.16, report.Runs[0].End, 1e-9)
+}
+
+// TestCadenceAnalyzerSuite runs the CadenceAnalyzer test suite.
+func TestCadenceAnalyzerSuite(t *testing.T) {
+	suite.Run(t, new(CadenceAnalyzerTestSuite))
+}
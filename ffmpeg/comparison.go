@@ -0,0 +1,390 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Private variables (alphabetical)
+
+var (
+	// comparisonMetadataFields lists the properties compared by an EncodeComparison, in report order.
+	comparisonMetadataFields = []struct {
+		name  string
+		value func(info *ContainerInfo, video VideoStream) string
+	}{
+		{"Container", func(info *ContainerInfo, _ VideoStream) string { return info.General.Format }},
+		{"Duration", func(info *ContainerInfo, _ VideoStream) string { return info.General.Duration }},
+		{"File Size", func(info *ContainerInfo, _ VideoStream) string { return info.General.Size }},
+		{"Overall Bit Rate", func(info *ContainerInfo, _ VideoStream) string { return info.General.BitRate }},
+		{"Video Codec", func(_ *ContainerInfo, video VideoStream) string { return video.Format }},
+		{"Video Profile", func(_ *ContainerInfo, video VideoStream) string { return video.FormatProfile }},
+		{"Resolution", func(_ *ContainerInfo, video VideoStream) string {
+			if video.Width == 0 || video.Height == 0 {
+				return ""
+			}
+			return fmt.Sprintf("%dx%d", video.Width, video.Height)
+		}},
+		{"Display Aspect Ratio", func(_ *ContainerInfo, video VideoStream) string {
+			return formatComparisonFloat(video.DisplayAspectRatio, 3)
+		}},
+		{"Frame Rate", func(_ *ContainerInfo, video VideoStream) string {
+			return strings.TrimSpace(formatComparisonFloat(video.FrameRate, 3) + " " + video.FrameRateMode)
+		}},
+		{"Bit Depth", func(_ *ContainerInfo, video VideoStream) string {
+			if video.BitDepth == 0 {
+				return ""
+			}
+			return strconv.Itoa(video.BitDepth)
+		}},
+		{"Color Space", func(_ *ContainerInfo, video VideoStream) string { return video.ColorSpace }},
+		{"Scan Type", func(_ *ContainerInfo, video VideoStream) string { return video.ScanType }},
+		{"B-Frames", func(_ *ContainerInfo, video VideoStream) string {
+			if video.HasBFrames {
+				return "Yes"
+			}
+			return "No"
+		}},
+		{"Video Bit Rate", func(_ *ContainerInfo, video VideoStream) string {
+			if video.BitRate <= 0 {
+				return ""
+			}
+			return fmt.Sprintf("%.2f Kbps", float64(video.BitRate)/1000)
+		}},
+		{"Encoder", func(info *ContainerInfo, video VideoStream) string {
+			for _, tags := range []map[string]string{video.Tags, info.General.Tags} {
+				for key, value := range tags {
+					if strings.EqualFold(key, "encoder") {
+						return value
+					}
+				}
+			}
+			return ""
+		}},
+		{"Audio Streams", func(info *ContainerInfo, _ VideoStream) string {
+			var streams []string
+			for _, stream := range info.AudioStreams {
+				description := fmt.Sprintf("%s %dch", stream.Format, stream.Channels)
+				if stream.Language != "" {
+					description += " " + stream.Language
+				}
+				streams = append(streams, description)
+			}
+			return strings.Join(streams, ", ")
+		}},
+		{"Subtitle Streams", func(info *ContainerInfo, _ VideoStream) string {
+			return strconv.Itoa(len(info.SubtitleStreams))
+		}},
+	}
+)
+
+// Private functions (alphabetical)
+
+// compareMetadata lists the compared properties of every file.
+func compareMetadata(infos []*ContainerInfo) []MetadataDifference {
+	differences := make([]MetadataDifference, 0, len(comparisonMetadataFields))
+	for _, field := range comparisonMetadataFields {
+		difference := MetadataDifference{Field: field.name, Values: make([]string, len(infos))}
+		for i, info := range infos {
+			if info == nil {
+				continue
+			}
+			video, _ := primaryVideoStream(info)
+			difference.Values[i] = field.value(info, video)
+			difference.Differs = difference.Differs || difference.Values[i] != difference.Values[0]
+		}
+		differences = append(differences, difference)
+	}
+	return differences
+}
+
+// formatComparisonFloat formats a property with the given number of decimals, empty when unknown.
+func formatComparisonFloat(value float64, decimals int) string {
+	if value <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(value, 'f', decimals, 64)
+}
+
+// Public functions (alphabetical)
+
+// NewEncodeComparison creates a comparison of files. The container information of every file
+// gives the metadata comparison; the measurements are added with AddFrame and AddQualityFrame.
+func NewEncodeComparison(paths []string, infos []*ContainerInfo) *EncodeComparison {
+	comparison := &EncodeComparison{
+		Files:       make([]ComparedFile, len(paths)),
+		Metadata:    compareMetadata(infos),
+		BinDuration: comparisonBinDuration,
+		Winner:      -1,
+	}
+	for i, path := range paths {
+		comparison.Files[i] = ComparedFile{Path: path, Info: infos[i], FrameTypes: make(map[string]*FrameTypeSize)}
+	}
+	return comparison
+}
+
+// Private methods (alphabetical)
+
+// alignedTime returns the time of a frame from the first frame of its file, NaN when the
+// bitrate analysis did not report the frame.
+func (f *ComparedFile) alignedTime(frameNumber int) float64 {
+	if frameNumber >= len(f.sawFrame) || !f.sawFrame[frameNumber] {
+		return math.NaN()
+	}
+	return f.frameTimes[frameNumber] - f.start
+}
+
+// finishFile computes the start, duration and averages of a file.
+func (f *ComparedFile) finishFile() {
+	f.start = math.Inf(1)
+	last := math.Inf(-1)
+	for i, seen := range f.sawFrame {
+		if seen {
+			f.start = math.Min(f.start, f.frameTimes[i])
+			last = math.Max(last, f.frameTimes[i])
+		}
+	}
+	if math.IsInf(f.start, 1) {
+		f.start = 0
+		return
+	}
+
+	f.Duration = last - f.start
+	if f.Info != nil {
+		if video, found := primaryVideoStream(f.Info); found && video.FrameRate > 0 {
+			f.Duration += 1 / video.FrameRate
+		}
+	}
+	if f.Duration > 0 {
+		f.AverageBitrate = float64(f.TotalBits) / f.Duration
+	}
+
+	f.AverageQP, f.QPFrames = 0, 0
+	for i, qp := range f.frameQP {
+		if qp > 0 && i < len(f.sawFrame) && f.sawFrame[i] {
+			f.AverageQP += qp
+			f.QPFrames++
+		}
+	}
+	if f.QPFrames > 0 {
+		f.AverageQP /= float64(f.QPFrames)
+	}
+}
+
+// sceneWinner picks the winning file of a scene, the one with the lowest average QP. Bitrate alone
+// says nothing about quality, so it returns -1 when QPs are not comparable, when a file with
+// frames in the scene has no QP in it, or when no file has frames in the scene.
+func (c *EncodeComparison) sceneWinner(scene ComparedScene, compareQP bool) int {
+	if !compareQP {
+		return -1
+	}
+
+	winner := -1
+	for i := range c.Files {
+		if scene.Bitrate[i] <= 0 {
+			continue
+		}
+		if scene.AverageQP[i] <= 0 {
+			return -1
+		}
+		if winner < 0 || scene.AverageQP[i] < scene.AverageQP[winner] {
+			winner = i
+		}
+	}
+	return winner
+}
+
+// Public methods (alphabetical)
+
+// AddFrame adds the size, type and presentation time of a frame measured by a BitrateAnalyzer
+// to a file of the comparison.
+func (c *EncodeComparison) AddFrame(file int, frame FrameBitrateInfo) {
+	f := &c.Files[file]
+	for len(f.sawFrame) <= frame.FrameNumber {
+		f.sawFrame = append(f.sawFrame, false)
+		f.frameTimes = append(f.frameTimes, 0)
+		f.frameBits = append(f.frameBits, 0)
+	}
+	f.sawFrame[frame.FrameNumber] = true
+	f.frameTimes[frame.FrameNumber] = frame.Time
+	f.frameBits[frame.FrameNumber] = frame.Bitrate
+
+	f.Frames++
+	f.TotalBits += frame.Bitrate
+	size, found := f.FrameTypes[frame.FrameType]
+	if !found {
+		size = &FrameTypeSize{}
+		f.FrameTypes[frame.FrameType] = size
+	}
+	size.Frames++
+	size.TotalBits += frame.Bitrate
+}
+
+// AddQualityFrame adds the QP of a frame measured by a FrameQualityAnalyzer to a file of the
+// comparison. Frames may be added in any order.
+func (c *EncodeComparison) AddQualityFrame(file int, frame QualityFrame) {
+	if frame.QP <= 0 || frame.FrameNumber < 0 {
+		return
+	}
+	f := &c.Files[file]
+	for len(f.frameQP) <= frame.FrameNumber {
+		f.frameQP = append(f.frameQP, 0)
+	}
+	f.frameQP[frame.FrameNumber] = frame.QP
+}
+
+// Finish aligns the files and computes the bitrate and QP series, the frame type ratios and
+// the measurements and winner of every scene. Without scenes, the files are compared over
+// segments of comparisonSegmentDuration seconds.
+func (c *EncodeComparison) Finish() {
+	if len(c.Files) == 0 {
+		return
+	}
+
+	end := 0.0
+	for i := range c.Files {
+		c.Files[i].finishFile()
+		end = math.Max(end, c.Files[i].Duration)
+	}
+
+	// Frame type sizes relative to the reference
+	reference := c.Files[0].FrameTypes
+	for i := range c.Files {
+		for frameType, size := range c.Files[i].FrameTypes {
+			size.AverageSize = float64(size.TotalBits) / float64(size.Frames)
+			if base, found := reference[frameType]; found && base.TotalBits > 0 {
+				size.Ratio = size.AverageSize / (float64(base.TotalBits) / float64(base.Frames))
+			}
+		}
+	}
+
+	// Scene boundaries as times from the first frame of the reference
+	var starts []float64
+	for _, start := range c.sceneStarts {
+		if aligned := start - c.Files[0].start; aligned > 0 && aligned < end {
+			starts = append(starts, aligned)
+		}
+	}
+	if len(c.sceneStarts) == 0 {
+		for start := comparisonSegmentDuration; start < end; start += comparisonSegmentDuration {
+			starts = append(starts, start)
+		}
+	}
+	sort.Float64s(starts)
+	starts = append([]float64{0}, starts...)
+
+	binCount := int(math.Ceil(end / c.BinDuration))
+	c.Bins = make([]ComparisonBin, binCount)
+	for b := range c.Bins {
+		c.Bins[b] = ComparisonBin{Start: float64(b) * c.BinDuration,
+			Bitrate: make([]float64, len(c.Files)), AverageQP: make([]float64, len(c.Files))}
+	}
+	c.Scenes = make([]ComparedScene, len(starts))
+	for s := range c.Scenes {
+		sceneEnd := end
+		if s+1 < len(starts) {
+			sceneEnd = starts[s+1]
+		}
+		c.Scenes[s] = ComparedScene{Index: s, Start: starts[s], End: sceneEnd,
+			Bitrate: make([]float64, len(c.Files)), AverageQP: make([]float64, len(c.Files))}
+	}
+
+	// Sum the frames of every file into the bins and scenes, the sums become averages below
+	binQPFrames := make([][]int, binCount)
+	for b := range binQPFrames {
+		binQPFrames[b] = make([]int, len(c.Files))
+	}
+	sceneQPFrames := make([][]int, len(c.Scenes))
+	for s := range sceneQPFrames {
+		sceneQPFrames[s] = make([]int, len(c.Files))
+	}
+	for i := range c.Files {
+		f := &c.Files[i]
+		for frameNumber := range f.sawFrame {
+			t := f.alignedTime(frameNumber)
+			if math.IsNaN(t) {
+				continue
+			}
+			b := min(int(t/c.BinDuration), binCount-1)
+			s := max(sort.SearchFloat64s(starts, t+1e-9)-1, 0)
+			c.Bins[b].Bitrate[i] += float64(f.frameBits[frameNumber])
+			c.Scenes[s].Bitrate[i] += float64(f.frameBits[frameNumber])
+			if frameNumber < len(f.frameQP) && f.frameQP[frameNumber] > 0 {
+				c.Bins[b].AverageQP[i] += f.frameQP[frameNumber]
+				c.Scenes[s].AverageQP[i] += f.frameQP[frameNumber]
+				binQPFrames[b][i]++
+				sceneQPFrames[s][i]++
+			}
+		}
+	}
+	for b := range c.Bins {
+		for i := range c.Files {
+			c.Bins[b].Bitrate[i] /= c.BinDuration
+			if binQPFrames[b][i] > 0 {
+				c.Bins[b].AverageQP[i] /= float64(binQPFrames[b][i])
+			}
+		}
+	}
+
+	// QPs of different codecs cannot be compared
+	compareQP := true
+	codec := ""
+	for i, f := range c.Files {
+		if f.Info == nil {
+			compareQP = false
+			continue
+		}
+		video, _ := primaryVideoStream(f.Info)
+		if i > 0 && video.Format != codec {
+			compareQP = false
+		}
+		codec = video.Format
+	}
+
+	for _, f := range c.Files {
+		compareQP = compareQP && f.QPFrames > 0
+	}
+	c.QPComparable = compareQP
+
+	for s := range c.Scenes {
+		scene := &c.Scenes[s]
+		for i := range c.Files {
+			if duration := scene.End - scene.Start; duration > 0 {
+				scene.Bitrate[i] /= duration
+			}
+			if sceneQPFrames[s][i] > 0 {
+				scene.AverageQP[i] /= float64(sceneQPFrames[s][i])
+			}
+		}
+		scene.Winner = c.sceneWinner(*scene, compareQP)
+		if scene.Winner >= 0 {
+			c.Files[scene.Winner].ScenesWon++
+		}
+	}
+
+	// The file winning the most scenes wins, the smaller file on a tie
+	for i, f := range c.Files {
+		if f.ScenesWon == 0 {
+			continue
+		}
+		if c.Winner < 0 || f.ScenesWon > c.Files[c.Winner].ScenesWon ||
+			f.ScenesWon == c.Files[c.Winner].ScenesWon && f.TotalBits < c.Files[c.Winner].TotalBits {
+			c.Winner = i
+		}
+	}
+}
+
+// SetScenes sets the scenes of the reference file the files are compared over.
+func (c *EncodeComparison) SetScenes(scenes []Scene) {
+	c.sceneStarts = c.sceneStarts[:0]
+	for _, scene := range scenes {
+		c.sceneStarts = append(c.sceneStarts, scene.Start)
+	}
+}
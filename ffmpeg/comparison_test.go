@@ -0,0 +1,111 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the encode comparison.
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// EncodeComparisonTestSuite defines the test suite for EncodeComparison.
+// It adds synthetic frames so no sample media is required.
+type EncodeComparisonTestSuite struct {
+	suite.Suite
+	comparison *EncodeComparison // EncodeComparison under test
+}
+
+// SetupTest compares a 1080p reference starting at 1.4 seconds, as MPEG-TS files do, with a
+// 720p encode starting at 0. Both are 20 seconds of 25 fps H.264 with a keyframe every 2 seconds;
+// the encode has frames half the size, a higher QP in the first 10 seconds and a lower one after.
+func (s *EncodeComparisonTestSuite) SetupTest() {
+	infos := []*ContainerInfo{
+		{General: GeneralInfo{Format: "mpegts"}, VideoStreams: []VideoStream{{Format: "h264", Width: 1920, Height: 1080, FrameRate: 25}}},
+		{General: GeneralInfo{Format: "mpegts"}, VideoStreams: []VideoStream{{Format: "h264", Width: 1280, Height: 720, FrameRate: 25}}},
+	}
+	s.comparison = NewEncodeComparison([]string{"reference.ts", "encode.ts"}, infos)
+
+	for file, start := range []float64{1.4, 0} {
+		for n := 0; n < 500; n++ {
+			frameType, bits := "P", int64(100000)
+			if n%50 == 0 {
+				frameType, bits = "I", 400000
+			}
+			s.comparison.AddFrame(file, FrameBitrateInfo{FrameNumber: n, FrameType: frameType,
+				Bitrate: bits / int64(file+1), Time: start + float64(n)/25})
+
+			qp := 20.0
+			if file == 1 && n < 250 {
+				qp = 24
+			} else if file == 1 {
+				qp = 18
+			}
+			s.comparison.AddQualityFrame(file, QualityFrame{FrameNumber: n, QP: qp})
+		}
+	}
+}
+
+// TestMetadata verifies that differing properties are flagged.
+func (s *EncodeComparisonTestSuite) TestMetadata() {
+	fields := make(map[string]MetadataDifference)
+	for _, difference := range s.comparison.Metadata {
+		fields[difference.Field] = difference
+	}
+	assert.True(s.T(), fields["Resolution"].Differs)
+	assert.Equal(s.T(), []string{"1920x1080", "1280x720"}, fields["Resolution"].Values)
+	assert.False(s.T(), fields["Container"].Differs)
+	assert.False(s.T(), fields["Video Codec"].Differs)
+}
+
+// TestScenes verifies the aligned scenes, their winners and the verdict.
+func (s *EncodeComparisonTestSuite) TestScenes() {
+	s.comparison.SetScenes([]Scene{{Start: 1.4}, {Start: 11.4}})
+	s.comparison.Finish()
+
+	reference, encode := s.comparison.Files[0], s.comparison.Files[1]
+	assert.InDelta(s.T(), 20, reference.Duration, 1e-9)
+	assert.InDelta(s.T(), 20, encode.Duration, 1e-9)
+	assert.InDelta(s.T(), 20, reference.AverageQP, 1e-9)
+	assert.InDelta(s.T(), 21, encode.AverageQP, 1e-9)
+
+	require.Len(s.T(), s.comparison.Scenes, 2)
+	assert.InDelta(s.T(), 10, s.comparison.Scenes[1].Start, 1e-9)
+	assert.Equal(s.T(), 0, s.comparison.Scenes[0].Winner)
+	assert.Equal(s.T(), 1, s.comparison.Scenes[1].Winner)
+	assert.InDelta(s.T(), 2650000, s.comparison.Scenes[0].Bitrate[0], 1e-6)
+
+	// One scene each, the smaller file wins
+	assert.Equal(s.T(), 1, s.comparison.Winner)
+	assert.True(s.T(), s.comparison.QPComparable)
+
+	require.Len(s.T(), s.comparison.Bins, 20)
+	assert.InDelta(s.T(), 2800000, s.comparison.Bins[0].Bitrate[0], 1e-6)
+	assert.InDelta(s.T(), 1400000, s.comparison.Bins[0].Bitrate[1], 1e-6)
+	assert.InDelta(s.T(), 24, s.comparison.Bins[0].AverageQP[1], 1e-9)
+
+	assert.InDelta(s.T(), 0.5, encode.FrameTypes["P"].Ratio, 1e-9)
+	assert.InDelta(s.T(), 1, reference.FrameTypes["I"].Ratio, 1e-9)
+	assert.Equal(s.T(), 10, encode.FrameTypes["I"].Frames)
+}
+
+// TestSegments verifies the fixed segments used without scenes and that encodes with different
+// codecs have no winner.
+func (s *EncodeComparisonTestSuite) TestSegments() {
+	s.comparison.Files[1].Info.VideoStreams[0].Format = "hevc"
+	s.comparison.Finish()
+
+	require.Len(s.T(), s.comparison.Scenes, 2)
+	assert.InDelta(s.T(), 10, s.comparison.Scenes[1].Start, 1e-9)
+	assert.Equal(s.T(), -1, s.comparison.Scenes[0].Winner)
+	assert.Equal(s.T(), -1, s.comparison.Scenes[1].Winner)
+	assert.Equal(s.T(), -1, s.comparison.Winner)
+	assert.Zero(s.T(), s.comparison.Files[0].ScenesWon)
+	assert.False(s.T(), s.comparison.QPComparable)
+}
+
+// TestEncodeComparisonSuite runs the EncodeComparison test suite.
+func TestEncodeComparisonSuite(t *testing.T) {
+	suite.Run(t, new(EncodeComparisonTestSuite))
+}
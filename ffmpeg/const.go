@@ -6,11 +6,120 @@ package ffmpeg
 
 import (
 	"fmt"
+	"math"
 	"time"
 )
 
 // Private constants (alphabetical)
 const (
+	// artifactBandingFlatRun is the number of equal pixels needed on both sides of a step for it to be a false contour.
+	artifactBandingFlatRun = 8
+
+	// artifactBandingLimit is the share of false contour pixels in percent that scores as fully banded.
+	artifactBandingLimit = 2.0
+
+	// artifactBandingMaxStep is the largest 8-bit luma step between flat areas still treated as a false contour.
+	artifactBandingMaxStep = 2
+
+	// artifactBandingMaxWidth is the width frames are downscaled to for the banding estimate.
+	// Nearest neighbour scaling keeps the steps between bands intact.
+	artifactBandingMaxWidth = 960
+
+	// artifactBlockinessLimit is the blockdetect value that scores as fully blocky.
+	// Like the QP thresholds, this is an approximation and can be adjusted.
+	artifactBlockinessLimit = 10.0
+
+	// artifactBlurLimit is the blurdetect value that scores as fully blurred.
+	artifactBlurLimit = 10.0
+
+	// bitrateCFRTolerance is the largest difference in seconds between the shortest and the
+	// longest frame interval of a constant frame rate stream. Millisecond time bases round
+	// the intervals of 23.976 fps to 41 and 42 ms.
+	bitrateCFRTolerance = 0.002
+
+	// blackFreezeDefaultBlackDuration is the default minimum duration in seconds of a black segment.
+	blackFreezeDefaultBlackDuration = 1.0
+
+	// blackFreezeDefaultFreezeDuration is the default minimum duration in seconds of a frozen segment.
+	blackFreezeDefaultFreezeDuration = 2.0
+
+	// blackFreezeDefaultFreezeNoise is the default noise tolerance in dB between frames of a frozen segment.
+	blackFreezeDefaultFreezeNoise = -60.0
+
+	// blackFreezeDefaultPictureThreshold is the default ratio of black pixels a frame needs to count as black.
+	blackFreezeDefaultPictureThreshold = 0.98
+
+	// blackFreezeDefaultPixelThreshold is the default luminance (relative to full range) below which a pixel is black.
+	blackFreezeDefaultPixelThreshold = 0.10
+
+	// blackFreezeEdgeTolerance is the distance in seconds from the start or the end of the
+	// stream within which a black segment counts as leading or trailing black.
+	blackFreezeEdgeTolerance = 0.1
+
+	// audioQCClipLevel is the peak level in dBFS at or above which a window is considered clipped.
+	audioQCClipLevel = -0.1
+
+	// audioQCClipMinPeakCount is the number of samples at the peak level a window needs to be
+	// reported as clipped. Single full scale samples are legitimate peaks.
+	audioQCClipMinPeakCount = 3
+
+	// audioQCDCOffsetLimit is the average DC offset (relative to full scale) above which a channel is reported.
+	audioQCDCOffsetLimit = 0.01
+
+	// audioQCDropoutLevel is the RMS level in dBFS below which a window counts as a dropout or a silent channel.
+	audioQCDropoutLevel = -70.0
+
+	// audioQCDropoutMaxDuration is the longest gap in seconds reported as a dropout.
+	// Longer gaps are silences reported by silencedetect.
+	audioQCDropoutMaxDuration = 0.5
+
+	// audioQCPhaseLimit is the phase correlation below which stereo audio is considered out of phase.
+	audioQCPhaseLimit = -0.5
+
+	// audioQCPresentLevel is the RMS level in dBFS above which audio is considered present.
+	// Dropouts must be surrounded by present audio and silent channels are reported only
+	// while another channel is present.
+	audioQCPresentLevel = -50.0
+
+	// audioQCRunDuration is the minimum duration in seconds of silent channel and phase runs.
+	audioQCRunDuration = 1.0
+
+	// audioQCSilenceDuration is the minimum duration in seconds of a silence reported by silencedetect.
+	audioQCSilenceDuration = 2.0
+
+	// audioQCSilenceNoise is the noise floor used by silencedetect.
+	audioQCSilenceNoise = "-60dB"
+
+	// audioQCWindowLength is the length in seconds of the analysis windows.
+	audioQCWindowLength = 0.1
+
+	// cadenceDropFactor is the multiple of the expected frame duration above which the distance
+	// between two frames is counted as dropped frames.
+	cadenceDropFactor = 1.5
+
+	// cadenceSegmentDuration is the length in seconds of the segments of the cadence analysis.
+	cadenceSegmentDuration = 10.0
+
+	// comparisonBinDuration is the length in seconds of the time bins of the bitrate and QP series of an encode comparison.
+	comparisonBinDuration = 1.0
+
+	// comparisonSegmentDuration is the length in seconds of the segments compared instead of scenes
+	// when the scenes of the reference are unknown.
+	comparisonSegmentDuration = 10.0
+
+	// cropDefaultLimit is the default luminance (0-255) at or below which cropdetect treats a pixel as black.
+	cropDefaultLimit = 24
+
+	// cropDefaultRound is the default value the crop width and height are rounded to.
+	cropDefaultRound = 2
+
+	// cropMinSegmentSamples is the number of consecutive samples a crop needs to form a segment.
+	// Shorter runs, typically dark scenes, are attributed to the surrounding segment.
+	cropMinSegmentSamples = 3
+
+	// cropTolerance is the distance in pixels within which two crop rectangles are considered equal.
+	cropTolerance = 4
+
 	// defaultTimeout is the standard timeout in seconds for FFmpeg operations.
 	// Operations that exceed this timeout will be terminated.
 	defaultTimeout = 30 * time.Second
@@ -18,21 +127,368 @@ const (
 	// errorPrefix is used as a prefix for all error messages from this package.
 	// This ensures consistent error formatting across the package.
 	errorPrefix = "ffmpeg: "
+
+	// fingerprintFlatRange is the smallest difference between the brightest and the darkest pixel of
+	// a downscaled keyframe for it to be hashed. Black and other flat frames match any other flat frame.
+	fingerprintFlatRange = 12
+
+	// fingerprintHashHeight is the number of rows of the difference hash grid.
+	fingerprintHashHeight = 8
+
+	// fingerprintHashWidth is the number of columns of the difference hash grid; every row
+	// yields one bit less than it has pixels.
+	fingerprintHashWidth = 9
+
+	// fingerprintIndexVersion is the format version of fingerprint indexes.
+	fingerprintIndexVersion = 1
+
+	// fingerprintMatchDistance is the largest Hamming distance between the hashes of matching scenes.
+	fingerprintMatchDistance = 8
+
+	// fingerprintMinMatches is the number of matching scenes needed to match two files, unless
+	// one of them has fewer scenes.
+	fingerprintMinMatches = 3
+
+	// fingerprintOffsetTolerance is the largest difference in seconds between the offsets of
+	// matching scenes of the same shared content.
+	fingerprintOffsetTolerance = 1.0
+
+	// fingerprintSceneDistance is the default Hamming distance between consecutive keyframe hashes
+	// that starts a new scene.
+	fingerprintSceneDistance = 12
+
+	// frameHashDefaultAlgorithm is the default hash algorithm of the framehash muxer, as used by framemd5.
+	frameHashDefaultAlgorithm = "md5"
+
+	// integrityTruncationTolerance is the largest distance in seconds between the declared
+	// duration of a stream and the end of its decoded data before the stream is reported as truncated.
+	integrityTruncationTolerance = 0.5
+
+	// manifestAlignmentTolerance is the largest distance in seconds between two IDR frames of
+	// different renditions, or between an IDR frame and a segment start, that still counts as aligned.
+	manifestAlignmentTolerance = 0.005
+
+	// manifestBandwidthTolerance is the relative difference allowed between a declared and a
+	// measured bandwidth before it is reported.
+	manifestBandwidthTolerance = 0.1
+
+	// mkvCueGapThreshold is the largest distance in seconds between two cue points
+	// before the gap is reported as hurting seek accuracy.
+	mkvCueGapThreshold = 10.0
+
+	// mkvDefaultTimestampScale is the Matroska timestamp scale (nanoseconds per tick)
+	// used when the segment does not declare one.
+	mkvDefaultTimestampScale = 1000000
+
+	// mkvMaxValueSize is the largest non-binary element value the EBML reader loads into memory.
+	mkvMaxValueSize = 1 << 20
+
+	// mkvStatisticsTolerance is the relative difference allowed between statistics
+	// tags and measured values before the tags are reported as stale.
+	mkvStatisticsTolerance = 0.01
+
+	// mp4AudioPrimingSamples is the longest codec priming (AAC encoder delay) in samples that an
+	// audio edit list may skip without moving the A/V start.
+	mp4AudioPrimingSamples = 2112
+
+	// mp4EditShiftThreshold is the smallest edit list shift in seconds reported as moving the
+	// A/V start, one frame at 24 fps.
+	mp4EditShiftThreshold = 0.042
+
+	// mp4MaxBoxDepth limits how deep the ISO-BMFF walker descends into nested boxes.
+	// It protects against malformed files that declare recursive container boxes.
+	mp4MaxBoxDepth = 16
+
+	// mp4MaxPayloadSize is the largest box payload the ISO-BMFF walker reads into memory.
+	// Boxes it parses (ftyp, tkhd, elst, ...) are tiny in valid files.
+	mp4MaxPayloadSize = 1 << 20
+
+	// sceneDefaultThreshold is the default scdet score (0-100) at which a frame starts a new scene.
+	sceneDefaultThreshold = 10.0
+
+	// sceneStarvedRatio is the share of the average bitrate of the stream below which a scene is starved of bits.
+	sceneStarvedRatio = 0.5
+
+	// scanTypeInterlacedRatio is the share of interlaced frames above which a segment is interlaced.
+	scanTypeInterlacedRatio = 0.8
+
+	// scanTypeMinDetermined is the share of frames idet must classify before a segment gets a scan type.
+	scanTypeMinDetermined = 0.1
+
+	// scanTypeProgressiveRatio is the share of interlaced frames below which a segment is progressive.
+	scanTypeProgressiveRatio = 0.2
+
+	// scanTypeSegmentDuration is the length in seconds of the segments classified by the scan type analyzer.
+	scanTypeSegmentDuration = 10.0
+
+	// scanTypeTelecineExpectedRatio is the share of frames with a repeated field in 3:2 pulldown.
+	scanTypeTelecineExpectedRatio = 0.4
+
+	// scanTypeTelecineRepeatRatio is the share of frames with a repeated field above which a
+	// segment that is not fully interlaced is considered telecined.
+	scanTypeTelecineRepeatRatio = 0.15
+
+	// specRelativeTolerance is the relative difference below which numbers compared by a spec rule
+	// without a tolerance are equal, so 23.976 matches 24000/1001 fps but not 24 fps.
+	specRelativeTolerance = 1e-4
+
+	// storeLockTimeout is how long opening a result store waits for another process to close it.
+	storeLockTimeout = 5 * time.Second
+
+	// subtitleBitmapClearPacketSize is the largest bitmap subtitle packet treated as a
+	// clear event. PGS display sets that only remove the current picture are tiny.
+	subtitleBitmapClearPacketSize = 64
+
+	// subtitleDefaultMaxCharsPerSecond is the default reading speed limit for text subtitles.
+	subtitleDefaultMaxCharsPerSecond = 20.0
+
+	// subtitleTimingTolerance is the slack in seconds allowed before cues are reported
+	// as overlapping or extending beyond the video.
+	subtitleTimingTolerance = 0.001
+
+	// syncContentClusterWidth is the width in seconds of the offset cluster the content check votes for.
+	syncContentClusterWidth = 0.02
+
+	// syncContentMinMatches is the number of onsets that must agree before a content offset is reported.
+	syncContentMinMatches = 3
+
+	// syncContentOnsetNoise is the noise floor used by silencedetect to find audio onsets.
+	syncContentOnsetNoise = "-40dB"
+
+	// syncContentOnsetSilence is the minimum silence in seconds before a sound counts as an onset.
+	syncContentOnsetSilence = 0.2
+
+	// syncContentSceneThreshold is the scene change score above which a frame counts as a cut.
+	syncContentSceneThreshold = 0.4
+
+	// syncContentWindow is the largest distance in seconds between an onset and the cut it is matched to.
+	syncContentWindow = 0.5
+
+	// timestampGapFactor is the multiple of the expected frame duration above which the distance
+	// between two frames is reported as a gap.
+	timestampGapFactor = 1.5
+
+	// timestampJumpThreshold is the distance in seconds between two frames above which the
+	// timestamps are reported as jumping rather than as a gap.
+	timestampJumpThreshold = 1.0
+
+	// timestampWrapThreshold is the smallest backward step in ticks treated as a wraparound of
+	// 33-bit MPEG timestamps rather than as timestamps going back, half of the 2^33 range.
+	timestampWrapThreshold = int64(1) << 32
+
+	// watchQueueVersion is the format version of watch queues.
+	watchQueueVersion = 1
+)
+
+// Matroska element IDs (alphabetical)
+const (
+	mkvIDAttachedFile       = 0x61A7
+	mkvIDAttachments        = 0x1941A469
+	mkvIDChapters           = 0x1043A770
+	mkvIDCluster            = 0x1F43B675
+	mkvIDCodecID            = 0x86
+	mkvIDContentCompAlgo    = 0x4254
+	mkvIDContentCompression = 0x5034
+	mkvIDContentEncoding    = 0x6240
+	mkvIDContentEncodings   = 0x6D80
+	mkvIDCueClusterPosition = 0xF1
+	mkvIDCuePoint           = 0xBB
+	mkvIDCues               = 0x1C53BB6B
+	mkvIDCueTime            = 0xB3
+	mkvIDCueTrackPositions  = 0xB7
+	mkvIDDocType            = 0x4282
+	mkvIDDocTypeVersion     = 0x4287
+	mkvIDDuration           = 0x4489
+	mkvIDEBML               = 0x1A45DFA3
+	mkvIDFileData           = 0x465C
+	mkvIDFileDescription    = 0x467E
+	mkvIDFileMimeType       = 0x4660
+	mkvIDFileName           = 0x466E
+	mkvIDInfo               = 0x1549A966
+	mkvIDLanguage           = 0x22B59C
+	mkvIDMuxingApp          = 0x4D80
+	mkvIDName               = 0x536E
+	mkvIDSeekHead           = 0x114D9B74
+	mkvIDSegment            = 0x18538067
+	mkvIDSimpleTag          = 0x67C8
+	mkvIDTag                = 0x7373
+	mkvIDTagName            = 0x45A3
+	mkvIDTags               = 0x1254C367
+	mkvIDTagString          = 0x4487
+	mkvIDTagTrackUID        = 0x63C5
+	mkvIDTargets            = 0x63C0
+	mkvIDTimestampScale     = 0x2AD7B1
+	mkvIDTitle              = 0x7BA9
+	mkvIDTrackEntry         = 0xAE
+	mkvIDTrackNumber        = 0xD7
+	mkvIDTracks             = 0x1654AE6B
+	mkvIDTrackType          = 0x83
+	mkvIDTrackUID           = 0x73C5
+	mkvIDWritingApp         = 0x5741
 )
 
 // Public constants (alphabetical)
 const (
+	// AudioQCClipping marks a run of windows with samples at full scale.
+	AudioQCClipping = "clipping"
+
+	// AudioQCDCOffset marks a channel with a DC offset.
+	AudioQCDCOffset = "dc_offset"
+
+	// AudioQCDropout marks a short gap in otherwise present audio.
+	AudioQCDropout = "dropout"
+
+	// AudioQCPhase marks a run of out of phase stereo audio.
+	AudioQCPhase = "phase"
+
+	// AudioQCSilence marks a silence detected by the silencedetect filter.
+	AudioQCSilence = "silence"
+
+	// AudioQCSilentChannel marks a channel that is silent while other channels are not.
+	AudioQCSilentChannel = "silent_channel"
+
+	// CadenceDropped marks frames missing from the cadence of the presentation timestamps.
+	CadenceDropped = "dropped"
+
+	// CadenceDuplicate marks a run of frames repeating the previous frame.
+	CadenceDuplicate = "duplicate"
+
 	// DefaultBitrate specifies the standard bitrate used when no bitrate is specified.
 	// The value "1M" represents 1 megabit per second.
 	DefaultBitrate = "1M"
 
+	// DefaultSyncThreshold is the largest offset in seconds between a stream and the video
+	// accepted by the A/V sync check. It is about one frame at 25 fps.
+	DefaultSyncThreshold = 0.04
+
 	// DefaultFrameRate defines the standard frame rate used when no frame rate is specified.
 	// This value is commonly used in video production workflows.
 	DefaultFrameRate = 24.0
 
+	// IntegrityConcealment marks a decoder concealing damaged macroblocks or slices.
+	IntegrityConcealment = "concealment"
+
+	// IntegrityContainerError marks an error reported by the demuxer rather than by a decoder.
+	IntegrityContainerError = "container_error"
+
+	// IntegrityDecodeError marks any other error reported by a decoder.
+	IntegrityDecodeError = "decode_error"
+
+	// IntegrityMissingReference marks a frame referencing a picture that is not available.
+	IntegrityMissingReference = "missing_reference"
+
+	// IntegrityTruncated marks a stream whose data ends before its declared duration.
+	IntegrityTruncated = "truncated"
+
+	// ManifestDASH marks an MPEG-DASH manifest (.mpd).
+	ManifestDASH = "DASH"
+
+	// ManifestHLS marks an HLS playlist (.m3u8).
+	ManifestHLS = "HLS"
+
 	// MaxConcurrentOperations defines the maximum number of concurrent FFmpeg operations
 	// allowed to prevent system resource exhaustion.
 	MaxConcurrentOperations = 4
+
+	// BoxingLetterbox marks an active image with black bars above and below.
+	BoxingLetterbox = "letterbox"
+
+	// BoxingNone marks an active image filling the coded frame.
+	BoxingNone = "none"
+
+	// BoxingPillarbox marks an active image with black bars on the left and the right.
+	BoxingPillarbox = "pillarbox"
+
+	// BoxingWindowbox marks an active image with black bars on all sides.
+	BoxingWindowbox = "windowbox"
+
+	// FrameRateModeConstant marks a stream whose frames are evenly spaced.
+	FrameRateModeConstant = "CFR"
+
+	// FrameRateModeVariable marks a stream whose frame intervals vary.
+	FrameRateModeVariable = "VFR"
+
+	// RenditionAudio marks an audio only rendition of a manifest.
+	RenditionAudio = "audio"
+
+	// RenditionVideo marks a rendition of a manifest carrying video.
+	RenditionVideo = "video"
+
+	// ScanTypeInterlacedBFF marks interlaced content with the bottom field first.
+	ScanTypeInterlacedBFF = "interlaced (BFF)"
+
+	// ScanTypeInterlacedTFF marks interlaced content with the top field first.
+	ScanTypeInterlacedTFF = "interlaced (TFF)"
+
+	// ScanTypeMixed marks content mixing progressive and interlaced frames.
+	ScanTypeMixed = "mixed"
+
+	// ScanTypeProgressive marks progressive content.
+	ScanTypeProgressive = "progressive"
+
+	// ScanTypeTelecined marks progressive film content with repeated fields (hard telecine).
+	ScanTypeTelecined = "telecined"
+
+	// ScanTypeUndetermined marks content idet could not classify, such as static or black video.
+	ScanTypeUndetermined = "undetermined"
+
+	// NoTimestamp marks a timestamp that is not known, like AV_NOPTS_VALUE in FFmpeg.
+	NoTimestamp int64 = math.MinInt64
+
+	// SeverityError marks a finding that breaks playback, seeking or remuxing.
+	SeverityError = "error"
+
+	// SeverityWarning marks a finding that players usually cope with.
+	SeverityWarning = "warning"
+
+	// SpecAnalysisBitrate marks spec fields measured from the frames, such as the peak bitrate.
+	SpecAnalysisBitrate = "bitrate"
+
+	// SpecAnalysisLoudness marks spec fields measured by the loudness analysis.
+	SpecAnalysisLoudness = "loudness"
+
+	// TimestampDuplicatePTS marks a frame with the same presentation timestamp as the previous one.
+	TimestampDuplicatePTS = "duplicate_pts"
+
+	// TimestampGap marks a distance between two frames larger than the expected frame duration.
+	TimestampGap = "gap"
+
+	// TimestampJump marks presentation timestamps going back or jumping ahead by more than a second.
+	TimestampJump = "jump"
+
+	// TimestampMissingPTS marks a frame without a presentation timestamp.
+	TimestampMissingPTS = "missing_pts"
+
+	// TimestampNonMonotonicDTS marks a decoding timestamp not greater than the previous one.
+	TimestampNonMonotonicDTS = "non_monotonic_dts"
+
+	// TimestampPTSBeforeDTS marks a frame presented before it is decoded.
+	TimestampPTSBeforeDTS = "pts_before_dts"
+
+	// TimestampWraparound marks 33-bit MPEG timestamps wrapping around to zero.
+	TimestampWraparound = "wraparound"
+
+	// VideoEventBlack marks a black segment detected by the blackdetect filter.
+	VideoEventBlack = "black"
+
+	// VideoEventBlankFrames marks black frames detected by the blackframe filter that are too
+	// short to form a black segment, such as single black flashes.
+	VideoEventBlankFrames = "blank"
+
+	// VideoEventFreeze marks a frozen segment detected by the freezedetect filter.
+	VideoEventFreeze = "freeze"
+
+	// WatchStatusFailed marks a watched file that could not be handled, kept until it changes.
+	WatchStatusFailed = "failed"
+
+	// WatchStatusPending marks a watched file that may still be written.
+	WatchStatusPending = "pending"
+
+	// WatchStatusQueued marks a watched file ready for analysis.
+	WatchStatusQueued = "queued"
+
+	// WatchStatusRunning marks a watched file being analyzed.
+	WatchStatusRunning = "running"
 )
 
 // Public functions (alphabetical)
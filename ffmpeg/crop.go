@@ -0,0 +1,280 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Private functions (alphabetical)
+
+// cropAspectRatio returns the display aspect ratio of a rectangle of the stream, taking
+// non-square pixels into account. It returns 0 for an empty rectangle.
+func cropAspectRatio(rect CropRect, stream VideoStream) float64 {
+	if rect.Width <= 0 || rect.Height <= 0 {
+		return 0
+	}
+
+	pixelAspectRatio := stream.PixelAspectRatio
+	if pixelAspectRatio <= 0 && stream.DisplayAspectRatio > 0 && stream.Width > 0 && stream.Height > 0 {
+		pixelAspectRatio = stream.DisplayAspectRatio * float64(stream.Height) / float64(stream.Width)
+	}
+	if pixelAspectRatio <= 0 {
+		pixelAspectRatio = 1
+	}
+	return float64(rect.Width) * pixelAspectRatio / float64(rect.Height)
+}
+
+// cropBoxing classifies the black bars left around a crop rectangle in the coded frame.
+func cropBoxing(rect CropRect, codedWidth, codedHeight int) string {
+	letterbox := codedHeight-rect.Height > cropTolerance
+	pillarbox := codedWidth-rect.Width > cropTolerance
+
+	switch {
+	case letterbox && pillarbox:
+		return BoxingWindowbox
+	case letterbox:
+		return BoxingLetterbox
+	case pillarbox:
+		return BoxingPillarbox
+	default:
+		return BoxingNone
+	}
+}
+
+// cropSimilar reports whether two crop rectangles differ by no more than cropTolerance on every edge.
+func cropSimilar(a, b CropRect) bool {
+	within := func(x, y int) bool { return x-y <= cropTolerance && y-x <= cropTolerance }
+	return within(a.X, b.X) && within(a.Y, b.Y) &&
+		within(a.X+a.Width, b.X+b.Width) && within(a.Y+a.Height, b.Y+b.Height)
+}
+
+// cropUnion returns the smallest rectangle enclosing both rectangles. An empty rectangle is ignored.
+func cropUnion(a, b CropRect) CropRect {
+	if a.Width <= 0 || a.Height <= 0 {
+		return b
+	}
+	if b.Width <= 0 || b.Height <= 0 {
+		return a
+	}
+
+	left, top := min(a.X, b.X), min(a.Y, b.Y)
+	right, bottom := max(a.X+a.Width, b.X+b.Width), max(a.Y+a.Height, b.Y+b.Height)
+	return CropRect{X: left, Y: top, Width: right - left, Height: bottom - top}
+}
+
+// newCropReport builds the report of a stream from its crop samples.
+// A stream without samples, such as a fully black one, reports the coded frame as active.
+func newCropReport(samples []cropSample, stream VideoStream) *CropReport {
+	report := &CropReport{
+		StreamIndex: stream.Index,
+		CodedWidth:  stream.Width,
+		CodedHeight: stream.Height,
+		Samples:     len(samples),
+	}
+
+	end := 0.0
+	if stream.Duration > 0 {
+		end = stream.StartTime + stream.Duration
+	}
+	if len(samples) > 0 {
+		report.Segments = segmentCropSamples(samples, end)
+	}
+	for i := range report.Segments {
+		report.Segments[i].AspectRatio = cropAspectRatio(report.Segments[i].Crop, stream)
+		report.Crop = cropUnion(report.Crop, report.Segments[i].Crop)
+	}
+	if report.Crop.Width <= 0 || report.Crop.Height <= 0 {
+		report.Crop = CropRect{Width: stream.Width, Height: stream.Height}
+	}
+
+	report.AspectRatio = cropAspectRatio(report.Crop, stream)
+	report.Boxing = cropBoxing(report.Crop, stream.Width, stream.Height)
+	return report
+}
+
+// parseCropMetadata reads the crop rectangles printed by the metadata filter for every sampled frame.
+// Frames without a usable rectangle, such as fully black frames, are skipped.
+func parseCropMetadata(r io.Reader) ([]cropSample, error) {
+	var samples []cropSample
+	var current *cropSample
+
+	flush := func() {
+		if current != nil && current.rect.Width > 0 && current.rect.Height > 0 {
+			samples = append(samples, *current)
+		}
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if match := metadataFrameRegex.FindStringSubmatch(line); match != nil {
+			flush()
+			frameTime, _ := strconv.ParseFloat(match[2], 64)
+			current = &cropSample{time: frameTime}
+			continue
+		}
+
+		key, rawValue, found := strings.Cut(line, "=")
+		if !found || current == nil {
+			continue
+		}
+		value, err := strconv.Atoi(rawValue)
+		if err != nil {
+			continue
+		}
+
+		switch key {
+		case "lavfi.cropdetect.x":
+			current.rect.X = value
+		case "lavfi.cropdetect.y":
+			current.rect.Y = value
+		case "lavfi.cropdetect.w":
+			current.rect.Width = value
+		case "lavfi.cropdetect.h":
+			current.rect.Height = value
+		}
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading cropdetect output: %w", err)
+	}
+	return samples, nil
+}
+
+// segmentCropSamples groups consecutive samples with a similar crop in segments ending at end.
+// Runs shorter than cropMinSegmentSamples, typically dark scenes where part of the picture
+// is detected as black, are attributed to the previous segment, or the next one at the start.
+func segmentCropSamples(samples []cropSample, end float64) []CropSegment {
+	var runs []CropSegment
+	for _, sample := range samples {
+		if last := len(runs) - 1; last >= 0 && cropSimilar(runs[last].Crop, sample.rect) {
+			runs[last].Crop = cropUnion(runs[last].Crop, sample.rect)
+			runs[last].Samples++
+			continue
+		}
+		runs = append(runs, CropSegment{Start: sample.time, Crop: sample.rect, Samples: 1})
+	}
+
+	// Fold short runs into their neighbours, then join neighbours that became similar
+	var segments []CropSegment
+	pending := 0
+	pendingStart := 0.0
+	for _, run := range runs {
+		if run.Samples < cropMinSegmentSamples {
+			if last := len(segments) - 1; last >= 0 {
+				segments[last].Samples += run.Samples
+			} else {
+				if pending == 0 {
+					pendingStart = run.Start
+				}
+				pending += run.Samples
+			}
+			continue
+		}
+
+		if pending > 0 {
+			run.Start = pendingStart
+			run.Samples += pending
+			pending = 0
+		}
+		if last := len(segments) - 1; last >= 0 && cropSimilar(segments[last].Crop, run.Crop) {
+			segments[last].Crop = cropUnion(segments[last].Crop, run.Crop)
+			segments[last].Samples += run.Samples
+			continue
+		}
+		segments = append(segments, run)
+	}
+
+	// Without any stable run, the whole stream is a single segment enclosing every sample
+	if len(segments) == 0 && len(runs) > 0 {
+		segment := CropSegment{Start: runs[0].Start}
+		for _, run := range runs {
+			segment.Crop = cropUnion(segment.Crop, run.Crop)
+			segment.Samples += run.Samples
+		}
+		segments = append(segments, segment)
+	}
+
+	for i := range segments {
+		if i+1 < len(segments) {
+			segments[i].End = segments[i+1].Start
+		} else {
+			segments[i].End = math.Max(end, samples[len(samples)-1].time)
+		}
+	}
+	return segments
+}
+
+// Public functions (alphabetical)
+
+// NewCropAnalyzer creates a new CropAnalyzer with the default black limit and rounding.
+func NewCropAnalyzer(ffmpegInfo *FFmpegInfo) (*CropAnalyzer, error) {
+	if ffmpegInfo == nil || !ffmpegInfo.Installed {
+		return nil, fmt.Errorf("ffmpeg not available")
+	}
+
+	return &CropAnalyzer{
+		FFmpegPath: ffmpegInfo.Path,
+		Limit:      cropDefaultLimit,
+		Round:      cropDefaultRound,
+	}, nil
+}
+
+// Public methods (alphabetical)
+
+// Analyze decodes the keyframes of a video stream through the cropdetect filter and reports
+// the active picture area, overall and for every part of the stream where it changes, such as
+// a letterboxed feature between full frame titles. The recommended crop encloses every segment
+// so no active picture is lost.
+func (a *CropAnalyzer) Analyze(ctx context.Context, filePath string, stream VideoStream) (*CropReport, error) {
+	cmd := exec.CommandContext(
+		ctx,
+		a.FFmpegPath,
+		"-hide_banner",
+		"-nostats",
+		"-v", "error",
+		"-skip_frame", "nokey",
+		"-i", filePath,
+		"-map", fmt.Sprintf("0:%d", stream.Index),
+		"-filter:v", fmt.Sprintf("cropdetect=limit=%d:round=%d:reset=1,metadata=mode=print:file=-", a.Limit, a.Round),
+		"-f", "null",
+		"-",
+	)
+
+	// The metadata filter prints the crop proposed for every keyframe on stdout
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start FFmpeg: %w", err)
+	}
+
+	samples, parseErr := parseCropMetadata(stdout)
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("error running cropdetect filter: %w", err)
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	return newCropReport(samples, stream), nil
+}
+
+// Filter returns the FFmpeg crop filter applying the rectangle.
+func (c CropRect) Filter() string {
+	return fmt.Sprintf("crop=%d:%d:%d:%d", c.Width, c.Height, c.X, c.Y)
+}
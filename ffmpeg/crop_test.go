@@ -0,0 +1,118 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the crop analyzer.
+package ffmpeg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// CropAnalyzerTestSuite defines the test suite for CropAnalyzer.
+// It parses synthetic cropdetect output so no sample media is required.
+type CropAnalyzerTestSuite struct {
+	suite.Suite
+}
+
+// cropTestOutput renders the metadata printed for one keyframe per second, using crop to
+// pick the rectangle of every keyframe. A zero width renders a frame without a usable crop.
+func cropTestOutput(keyframes int, crop func(n int) CropRect) string {
+	var sb strings.Builder
+	for n := 0; n < keyframes; n++ {
+		rect := crop(n)
+		fmt.Fprintf(&sb, "frame:%-4d pts:%-8d pts_time:%d\n", n, n*1000, n)
+		fmt.Fprintf(&sb, "lavfi.cropdetect.x1=%d\nlavfi.cropdetect.x2=%d\n", rect.X, rect.X+rect.Width-1)
+		fmt.Fprintf(&sb, "lavfi.cropdetect.y1=%d\nlavfi.cropdetect.y2=%d\n", rect.Y, rect.Y+rect.Height-1)
+		fmt.Fprintf(&sb, "lavfi.cropdetect.w=%d\nlavfi.cropdetect.h=%d\n", rect.Width, rect.Height)
+		fmt.Fprintf(&sb, "lavfi.cropdetect.x=%d\nlavfi.cropdetect.y=%d\n", rect.X, rect.Y)
+	}
+	return sb.String()
+}
+
+// TestLetterboxChange verifies full frame titles followed by a letterboxed feature with a dark scene.
+func (s *CropAnalyzerTestSuite) TestLetterboxChange() {
+	full := CropRect{Width: 1920, Height: 1080}
+	scope := CropRect{Y: 140, Width: 1920, Height: 800}
+	output := cropTestOutput(60, func(n int) CropRect {
+		switch {
+		case n < 10:
+			return full
+		case n == 30 || n == 31:
+			// A dark scene looks like a smaller picture
+			return CropRect{X: 300, Y: 200, Width: 1320, Height: 680}
+		case n == 45:
+			return CropRect{}
+		case n == 50:
+			// Noise on the edge stays within the tolerance
+			return CropRect{Y: 138, Width: 1920, Height: 804}
+		}
+		return scope
+	})
+
+	samples, err := parseCropMetadata(strings.NewReader(output))
+	require.NoError(s.T(), err)
+	require.Len(s.T(), samples, 59)
+	assert.Equal(s.T(), scope, samples[10].rect)
+	assert.InDelta(s.T(), 10.0, samples[10].time, 1e-9)
+
+	stream := VideoStream{Index: 0, Width: 1920, Height: 1080, PixelAspectRatio: 1, Duration: 60}
+	report := newCropReport(samples, stream)
+
+	require.Len(s.T(), report.Segments, 2)
+	assert.Equal(s.T(), full, report.Segments[0].Crop)
+	assert.InDelta(s.T(), 10.0, report.Segments[0].End, 1e-9)
+	assert.Equal(s.T(), CropRect{Y: 138, Width: 1920, Height: 804}, report.Segments[1].Crop)
+	assert.Equal(s.T(), 49, report.Segments[1].Samples)
+	assert.InDelta(s.T(), 60.0, report.Segments[1].End, 1e-9)
+	assert.InDelta(s.T(), 2.388, report.Segments[1].AspectRatio, 1e-3)
+
+	// The recommended crop keeps the full frame titles
+	assert.Equal(s.T(), full, report.Crop)
+	assert.Equal(s.T(), BoxingNone, report.Boxing)
+	assert.Equal(s.T(), 59, report.Samples)
+}
+
+// TestPillarbox verifies a 4:3 picture in an anamorphic 16:9 frame.
+func (s *CropAnalyzerTestSuite) TestPillarbox() {
+	rect := CropRect{X: 90, Width: 540, Height: 576}
+	samples, err := parseCropMetadata(strings.NewReader(cropTestOutput(5, func(int) CropRect { return rect })))
+	require.NoError(s.T(), err)
+
+	stream := VideoStream{Width: 720, Height: 576, DisplayAspectRatio: 16.0 / 9.0, Duration: 5}
+	report := newCropReport(samples, stream)
+
+	require.Len(s.T(), report.Segments, 1)
+	assert.Equal(s.T(), rect, report.Crop)
+	assert.Equal(s.T(), BoxingPillarbox, report.Boxing)
+	assert.InDelta(s.T(), 4.0/3.0, report.AspectRatio, 1e-9)
+	assert.Equal(s.T(), "crop=540:576:90:0", report.Crop.Filter())
+}
+
+// TestBoxing verifies the classification of the black bars.
+func (s *CropAnalyzerTestSuite) TestBoxing() {
+	assert.Equal(s.T(), BoxingLetterbox, cropBoxing(CropRect{Y: 140, Width: 1918, Height: 800}, 1920, 1080))
+	assert.Equal(s.T(), BoxingWindowbox, cropBoxing(CropRect{X: 240, Y: 140, Width: 1440, Height: 800}, 1920, 1080))
+	assert.Equal(s.T(), BoxingNone, cropBoxing(CropRect{Width: 1920, Height: 1076}, 1920, 1080))
+}
+
+// TestNoSamples verifies that a stream without usable crops reports the coded frame.
+func (s *CropAnalyzerTestSuite) TestNoSamples() {
+	report := newCropReport(nil, VideoStream{Width: 1280, Height: 720})
+
+	assert.Empty(s.T(), report.Segments)
+	assert.Equal(s.T(), CropRect{Width: 1280, Height: 720}, report.Crop)
+	assert.Equal(s.T(), BoxingNone, report.Boxing)
+	assert.InDelta(s.T(), 16.0/9.0, report.AspectRatio, 1e-9)
+
+	_, err := NewCropAnalyzer(nil)
+	assert.Error(s.T(), err)
+}
+
+// TestCropAnalyzerSuite runs the CropAnalyzer test suite.
+func TestCropAnalyzerSuite(t *testing.T) {
+	suite.Run(t, new(CropAnalyzerTestSuite))
+}
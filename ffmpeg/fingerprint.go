@@ -0,0 +1,410 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Private variables (alphabetical)
+
+var (
+	// showinfoFrameRegex matches the frame number and presentation time logged by the showinfo
+	// filter, such as "n:   3 pts:  12288 pts_time:8".
+	showinfoFrameRegex = regexp.MustCompile(`\bn:\s*(\d+)\s+pts:\s*-?\d+\s+pts_time:\s*(\S+)`)
+)
+
+// Private functions (alphabetical)
+
+// bestCopy picks the best copy of the files of a cluster: the lowest average QP when every file
+// has one and all use the same codec, as QPs of different codecs cannot be compared, otherwise
+// the highest bit rate.
+func bestCopy(files []*Fingerprint) *Fingerprint {
+	compareQP := true
+	for _, file := range files {
+		if file.AverageQP <= 0 || file.Format != files[0].Format {
+			compareQP = false
+			break
+		}
+	}
+
+	best := files[0]
+	for _, file := range files[1:] {
+		if compareQP && file.AverageQP < best.AverageQP || !compareQP && file.BitRate > best.BitRate {
+			best = file
+		}
+	}
+	return best
+}
+
+// differenceHash computes the 64-bit difference hash of a downscaled luma frame of
+// fingerprintHashWidth by fingerprintHashHeight pixels: every bit tells whether a pixel is
+// brighter than its right neighbour. It also reports whether the frame is too flat to be hashed.
+func differenceHash(pixels []byte) (uint64, bool) {
+	var hash uint64
+	darkest, brightest := byte(math.MaxUint8), byte(0)
+	for y := 0; y < fingerprintHashHeight; y++ {
+		row := pixels[y*fingerprintHashWidth : (y+1)*fingerprintHashWidth]
+		for x := 0; x < fingerprintHashWidth; x++ {
+			darkest, brightest = min(darkest, row[x]), max(brightest, row[x])
+			if x > 0 {
+				hash <<= 1
+				if row[x-1] > row[x] {
+					hash |= 1
+				}
+			}
+		}
+	}
+	return hash, int(brightest)-int(darkest) < fingerprintFlatRange
+}
+
+// hammingDistance returns the number of bits that differ between two hashes.
+func hammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// parseShowinfoTimes reads the presentation time of every frame logged by the showinfo filter.
+func parseShowinfoTimes(r io.Reader) ([]float64, error) {
+	var times []float64
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		match := showinfoFrameRegex.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		frameTime, err := strconv.ParseFloat(match[2], 64)
+		if err != nil {
+			frameTime = math.NaN()
+		}
+		times = append(times, frameTime)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading showinfo output: %w", err)
+	}
+	return times, nil
+}
+
+// Public functions (alphabetical)
+
+// ClusterFingerprints groups files whose content matches, directly or through other files of
+// the group. Only groups of at least two files are returned, sorted by the path of their first file.
+func ClusterFingerprints(fingerprints []*Fingerprint) []FingerprintCluster {
+	sorted := append([]*Fingerprint(nil), fingerprints...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+
+	// Union-find over the files, joined by every matching pair
+	parent := make([]int, len(sorted))
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+
+	var matches []FingerprintMatch
+	var matchRoots []int
+	for i := range sorted {
+		for j := i + 1; j < len(sorted); j++ {
+			if match := MatchFingerprints(sorted[i], sorted[j]); match != nil {
+				parent[root(j)] = root(i)
+				matches = append(matches, *match)
+				matchRoots = append(matchRoots, i)
+			}
+		}
+	}
+
+	clusterOf := make(map[int]int)
+	var clusters []FingerprintCluster
+	for i, fingerprint := range sorted {
+		r := root(i)
+		index, found := clusterOf[r]
+		if !found {
+			index = len(clusters)
+			clusterOf[r] = index
+			clusters = append(clusters, FingerprintCluster{})
+		}
+		clusters[index].Files = append(clusters[index].Files, fingerprint)
+	}
+	for k, match := range matches {
+		index := clusterOf[root(matchRoots[k])]
+		clusters[index].Matches = append(clusters[index].Matches, match)
+	}
+
+	var result []FingerprintCluster
+	for _, cluster := range clusters {
+		if len(cluster.Files) > 1 {
+			cluster.Best = bestCopy(cluster.Files)
+			result = append(result, cluster)
+		}
+	}
+	return result
+}
+
+// LoadFingerprintIndex reads a fingerprint index. A missing index, or an index written in an
+// older format, yields an empty index.
+func LoadFingerprintIndex(path string) (*FingerprintIndex, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewFingerprintIndex(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading fingerprint index: %w", err)
+	}
+
+	index := NewFingerprintIndex()
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("error parsing fingerprint index: %w", err)
+	}
+	if index.Version != fingerprintIndexVersion || index.Files == nil {
+		return NewFingerprintIndex(), nil
+	}
+	return index, nil
+}
+
+// MatchFingerprints looks for content shared by two files. Every pair of scenes with close
+// hashes votes for the offset between the files; the offset most pairs agree on, within
+// fingerprintOffsetTolerance, gives the shared content, so trimmed copies still match.
+// It returns nil when fewer than fingerprintMinMatches scenes match, or fewer than all the
+// scenes of a file with less scenes.
+func MatchFingerprints(a, b *Fingerprint) *FingerprintMatch {
+	type scenePair struct {
+		a, b   int
+		offset float64
+	}
+
+	var pairs []scenePair
+	for i, sceneA := range a.Scenes {
+		for j, sceneB := range b.Scenes {
+			if hammingDistance(sceneA.Hash, sceneB.Hash) <= fingerprintMatchDistance {
+				pairs = append(pairs, scenePair{a: i, b: j, offset: sceneB.Start - sceneA.Start})
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].offset < pairs[j].offset })
+
+	// Slide a window of fingerprintOffsetTolerance over the sorted offsets
+	bestStart, bestCount := 0, 0
+	end := 0
+	for start := range pairs {
+		for end < len(pairs) && pairs[end].offset-pairs[start].offset <= fingerprintOffsetTolerance {
+			end++
+		}
+		if end-start > bestCount {
+			bestStart, bestCount = start, end-start
+		}
+	}
+	if bestCount == 0 || bestCount < min(fingerprintMinMatches, len(a.Scenes), len(b.Scenes)) {
+		return nil
+	}
+
+	match := &FingerprintMatch{PathA: a.Path, PathB: b.Path, Scenes: bestCount,
+		StartA: math.Inf(1), StartB: math.Inf(1)}
+	for _, pair := range pairs[bestStart : bestStart+bestCount] {
+		sceneA, sceneB := a.Scenes[pair.a], b.Scenes[pair.b]
+		match.Offset += pair.offset
+		match.StartA, match.EndA = math.Min(match.StartA, sceneA.Start), math.Max(match.EndA, sceneA.End)
+		match.StartB, match.EndB = math.Min(match.StartB, sceneB.Start), math.Max(match.EndB, sceneB.End)
+	}
+	match.Offset /= float64(bestCount)
+	return match
+}
+
+// NewFingerprintAnalyzer creates a new FingerprintAnalyzer with the default scene distance.
+func NewFingerprintAnalyzer(ffmpegInfo *FFmpegInfo) (*FingerprintAnalyzer, error) {
+	if ffmpegInfo == nil || !ffmpegInfo.Installed {
+		return nil, fmt.Errorf("ffmpeg not available")
+	}
+
+	return &FingerprintAnalyzer{
+		FFmpegPath:    ffmpegInfo.Path,
+		SceneDistance: fingerprintSceneDistance,
+	}, nil
+}
+
+// NewFingerprintIndex creates an empty fingerprint index.
+func NewFingerprintIndex() *FingerprintIndex {
+	return &FingerprintIndex{
+		Version: fingerprintIndexVersion,
+		Files:   make(map[string]*Fingerprint),
+	}
+}
+
+// Private methods (alphabetical)
+
+// buildScenes groups consecutive keyframes with close hashes into scenes. A flat keyframe,
+// such as the black frame of a fade, ends the current scene without starting a new one.
+// The last scene ends at the end of the stream.
+func (a *FingerprintAnalyzer) buildScenes(keyframes []fingerprintKeyframe, duration float64) []FingerprintScene {
+	var scenes []FingerprintScene
+	open := false
+	var previous uint64
+	for _, keyframe := range keyframes {
+		if math.IsNaN(keyframe.time) {
+			continue
+		}
+		if open && (keyframe.flat || hammingDistance(keyframe.hash, previous) > a.SceneDistance) {
+			scenes[len(scenes)-1].End = keyframe.time
+			open = false
+		}
+		if keyframe.flat {
+			continue
+		}
+		if !open {
+			scenes = append(scenes, FingerprintScene{Start: keyframe.time, Hash: keyframe.hash})
+			open = true
+		}
+		previous = keyframe.hash
+	}
+	if open {
+		scenes[len(scenes)-1].End = math.Max(duration, scenes[len(scenes)-1].Start)
+	}
+	return scenes
+}
+
+// Public methods (alphabetical)
+
+// Analyze decodes the keyframes of the first video stream of a file, downscales their luma to
+// a 9x8 grid and hashes it. Consecutive keyframes with close hashes form a scene, identified
+// by the hash of its first keyframe. As the hash only depends on the coarse structure of the
+// picture, copies of the same content with other codecs or resolutions get close hashes.
+func (a *FingerprintAnalyzer) Analyze(ctx context.Context, filePath string, info *ContainerInfo) (*Fingerprint, error) {
+	var stream *VideoStream
+	for i := range info.VideoStreams {
+		if !info.VideoStreams[i].Disposition.AttachedPic {
+			stream = &info.VideoStreams[i]
+			break
+		}
+	}
+	if stream == nil {
+		return nil, fmt.Errorf("no video stream to fingerprint")
+	}
+
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file information: %w", err)
+	}
+
+	fingerprint := &Fingerprint{
+		Path:     filePath,
+		Size:     fileInfo.Size(),
+		ModTime:  fileInfo.ModTime(),
+		Duration: stream.Duration,
+		Format:   stream.Format,
+		Width:    stream.Width,
+		Height:   stream.Height,
+		BitRate:  stream.BitRate,
+	}
+	if fingerprint.Duration <= 0 {
+		fingerprint.Duration = info.General.DurationF
+	}
+	if fingerprint.BitRate <= 0 && fingerprint.Duration > 0 {
+		// Without a declared video bit rate, fall back to the overall bit rate of the file
+		fingerprint.BitRate = int64(float64(fingerprint.Size) * 8 / fingerprint.Duration)
+	}
+
+	cmd := exec.CommandContext(
+		ctx,
+		a.FFmpegPath,
+		"-hide_banner",
+		"-nostats",
+		"-v", "info",
+		"-skip_frame", "nokey",
+		"-i", filePath,
+		"-map", fmt.Sprintf("0:%d", stream.Index),
+		"-filter:v", fmt.Sprintf("showinfo,scale=%d:%d:flags=area,format=gray", fingerprintHashWidth, fingerprintHashHeight),
+		"-fps_mode", "passthrough",
+		"-f", "rawvideo",
+		"-",
+	)
+
+	// The showinfo filter logs the time of every keyframe on stderr while the tiny luma frames
+	// go to stdout
+	var pixels bytes.Buffer
+	cmd.Stdout = &pixels
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start FFmpeg: %w", err)
+	}
+
+	times, parseErr := parseShowinfoTimes(stderr)
+	if parseErr != nil {
+		// Drain the output so FFmpeg can exit
+		_, _ = io.Copy(io.Discard, stderr)
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("error fingerprinting keyframes: %w", err)
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	frameSize := fingerprintHashWidth * fingerprintHashHeight
+	keyframes := make([]fingerprintKeyframe, 0, len(times))
+	for i, frameTime := range times {
+		if (i+1)*frameSize > pixels.Len() {
+			break
+		}
+		hash, flat := differenceHash(pixels.Bytes()[i*frameSize : (i+1)*frameSize])
+		keyframes = append(keyframes, fingerprintKeyframe{time: frameTime, hash: hash, flat: flat})
+	}
+
+	fingerprint.Scenes = a.buildScenes(keyframes, fingerprint.Duration)
+	return fingerprint, nil
+}
+
+// Lookup returns the fingerprint of a file when the index holds one taken since the file last
+// changed, nil otherwise.
+func (i *FingerprintIndex) Lookup(path string, fileInfo os.FileInfo) *Fingerprint {
+	fingerprint, found := i.Files[path]
+	if !found || fingerprint.Size != fileInfo.Size() || !fingerprint.ModTime.Equal(fileInfo.ModTime()) {
+		return nil
+	}
+	return fingerprint
+}
+
+// Save writes the index as JSON. The index is written to a temporary file first so an
+// interrupted save does not lose the previous index.
+func (i *FingerprintIndex) Save(path string) error {
+	data, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding fingerprint index: %w", err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("error writing fingerprint index: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("error replacing fingerprint index: %w", err)
+	}
+	return nil
+}
+
+// Set stores the fingerprint of a file in the index, replacing any previous one.
+func (i *FingerprintIndex) Set(fingerprint *Fingerprint) {
+	i.Files[fingerprint.Path] = fingerprint
+}
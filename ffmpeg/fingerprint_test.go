@@ -0,0 +1,184 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the perceptual fingerprints.
+package ffmpeg
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// fingerprintTestScenes returns scenes of 10 seconds with pseudo-random hashes, starting at
+// the given time. The same seed yields the same hashes.
+func fingerprintTestScenes(seed int64, count int, start float64) []FingerprintScene {
+	random := rand.New(rand.NewSource(seed))
+	scenes := make([]FingerprintScene, count)
+	for i := range scenes {
+		scenes[i] = FingerprintScene{Start: start + float64(i)*10, End: start + float64(i+1)*10, Hash: random.Uint64()}
+	}
+	return scenes
+}
+
+// FingerprintAnalyzerTestSuite defines the test suite for FingerprintAnalyzer.
+// It uses synthetic frames and scenes so no sample media is required.
+type FingerprintAnalyzerTestSuite struct {
+	suite.Suite
+	analyzer *FingerprintAnalyzer // FingerprintAnalyzer instance under test
+	tempDir  string               // Temporary directory for test files
+}
+
+// SetupTest creates an analyzer and a temporary directory.
+func (s *FingerprintAnalyzerTestSuite) SetupTest() {
+	analyzer, err := NewFingerprintAnalyzer(&FFmpegInfo{Installed: true, Path: "ffmpeg"})
+	require.NoError(s.T(), err)
+	s.analyzer = analyzer
+
+	tempDir, err := os.MkdirTemp("", "fingerprint-test")
+	require.NoError(s.T(), err)
+	s.tempDir = tempDir
+}
+
+// TearDownTest removes the temporary directory.
+func (s *FingerprintAnalyzerTestSuite) TearDownTest() {
+	os.RemoveAll(s.tempDir)
+}
+
+// TestDifferenceHash verifies the hash bits and the detection of flat frames.
+func (s *FingerprintAnalyzerTestSuite) TestDifferenceHash() {
+	// Every row darkens from left to right, so every pixel is brighter than its right neighbour
+	pixels := make([]byte, fingerprintHashWidth*fingerprintHashHeight)
+	for i := range pixels {
+		pixels[i] = byte(200 - (i%fingerprintHashWidth)*20)
+	}
+	hash, flat := differenceHash(pixels)
+	assert.Equal(s.T(), uint64(math.MaxUint64), hash)
+	assert.False(s.T(), flat)
+
+	for i := range pixels {
+		pixels[i] = byte(16 + i%3)
+	}
+	_, flat = differenceHash(pixels)
+	assert.True(s.T(), flat)
+}
+
+// TestParseShowinfoTimes verifies the keyframe times read from the showinfo log.
+func (s *FingerprintAnalyzerTestSuite) TestParseShowinfoTimes() {
+	log := "[Parsed_showinfo_0 @ 0x5601] config in time_base: 1/1000, frame_rate: 25/1\n" +
+		"[Parsed_showinfo_0 @ 0x5601] n:   0 pts:      0 pts_time:0       duration:     40 fmt:yuv420p\n" +
+		"[Parsed_showinfo_0 @ 0x5601]   color_range:tv color_space:bt709\n" +
+		"[Parsed_showinfo_0 @ 0x5601] n:   1 pts:   2000 pts_time:2       duration:     40 fmt:yuv420p\n"
+	times, err := parseShowinfoTimes(strings.NewReader(log))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []float64{0, 2}, times)
+}
+
+// TestBuildScenes verifies that close keyframes are merged and flat keyframes split scenes.
+func (s *FingerprintAnalyzerTestSuite) TestBuildScenes() {
+	keyframes := []fingerprintKeyframe{
+		{time: 0, hash: 0xFF00FF00FF00FF00},
+		{time: 2, hash: 0xFF00FF00FF00FF01},
+		{time: 4, hash: 0x00FF00FF00FF00FF},
+		{time: 6, flat: true},
+		{time: 8, hash: 0x00FF00FF00FF00FF},
+		{time: math.NaN(), hash: 0x0F0F0F0F0F0F0F0F},
+	}
+	scenes := s.analyzer.buildScenes(keyframes, 12)
+	assert.Equal(s.T(), []FingerprintScene{
+		{Start: 0, End: 4, Hash: 0xFF00FF00FF00FF00},
+		{Start: 4, End: 6, Hash: 0x00FF00FF00FF00FF},
+		{Start: 8, End: 12, Hash: 0x00FF00FF00FF00FF},
+	}, scenes)
+}
+
+// TestMatchFingerprints verifies the match of a trimmed re-encode and the rejection of other content.
+func (s *FingerprintAnalyzerTestSuite) TestMatchFingerprints() {
+	original := &Fingerprint{Path: "a.mkv", Scenes: fingerprintTestScenes(1, 30, 0)}
+
+	// The re-encode lost its first 5 scenes and starts 0.2 seconds later, with slightly different hashes
+	trimmed := &Fingerprint{Path: "b.mp4", Scenes: fingerprintTestScenes(1, 30, 0.2)[5:]}
+	for i := range trimmed.Scenes {
+		trimmed.Scenes[i].Start -= 50
+		trimmed.Scenes[i].End -= 50
+		trimmed.Scenes[i].Hash ^= 1 << (i % 64)
+	}
+
+	match := MatchFingerprints(original, trimmed)
+	require.NotNil(s.T(), match)
+	assert.Equal(s.T(), 25, match.Scenes)
+	assert.InDelta(s.T(), -49.8, match.Offset, 1e-9)
+	assert.InDelta(s.T(), 50, match.StartA, 1e-9)
+	assert.InDelta(s.T(), 300, match.EndA, 1e-9)
+	assert.InDelta(s.T(), 0.2, match.StartB, 1e-9)
+
+	other := &Fingerprint{Path: "c.mkv", Scenes: fingerprintTestScenes(2, 30, 0)}
+	assert.Nil(s.T(), MatchFingerprints(original, other))
+}
+
+// TestClusterFingerprints verifies the clusters and the choice of the best copy.
+func (s *FingerprintAnalyzerTestSuite) TestClusterFingerprints() {
+	files := []*Fingerprint{
+		{Path: "c.mkv", Format: "hevc", BitRate: 3000000, AverageQP: 24, Scenes: fingerprintTestScenes(1, 20, 0)},
+		{Path: "a.mkv", Format: "h264", BitRate: 8000000, AverageQP: 20, Scenes: fingerprintTestScenes(1, 20, 0)},
+		{Path: "b.mp4", Format: "h264", BitRate: 4000000, Scenes: fingerprintTestScenes(1, 20, 3)},
+		{Path: "d.mkv", Format: "h264", BitRate: 4000000, Scenes: fingerprintTestScenes(2, 20, 0)},
+	}
+
+	clusters := ClusterFingerprints(files)
+	require.Len(s.T(), clusters, 1)
+	cluster := clusters[0]
+	require.Len(s.T(), cluster.Files, 3)
+	assert.Equal(s.T(), "a.mkv", cluster.Files[0].Path)
+	assert.Len(s.T(), cluster.Matches, 3)
+	assert.Equal(s.T(), "a.mkv", cluster.Best.Path)
+
+	// With comparable QPs, the lowest QP wins over the highest bit rate
+	files[2].AverageQP = 18
+	files[0].Format = "h264"
+	clusters = ClusterFingerprints(files)
+	assert.Equal(s.T(), "b.mp4", clusters[0].Best.Path)
+}
+
+// TestFingerprintIndex verifies saving, loading and looking up fingerprints.
+func (s *FingerprintAnalyzerTestSuite) TestFingerprintIndex() {
+	mediaPath := filepath.Join(s.tempDir, "video.mkv")
+	require.NoError(s.T(), os.WriteFile(mediaPath, []byte("video"), 0644))
+	fileInfo, err := os.Stat(mediaPath)
+	require.NoError(s.T(), err)
+
+	indexPath := filepath.Join(s.tempDir, "index.json")
+	index, err := LoadFingerprintIndex(indexPath)
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), index.Files)
+
+	index.Set(&Fingerprint{Path: mediaPath, Size: fileInfo.Size(), ModTime: fileInfo.ModTime(),
+		Scenes: fingerprintTestScenes(1, 2, 0)})
+	require.NoError(s.T(), index.Save(indexPath))
+
+	loaded, err := LoadFingerprintIndex(indexPath)
+	require.NoError(s.T(), err)
+	fingerprint := loaded.Lookup(mediaPath, fileInfo)
+	require.NotNil(s.T(), fingerprint)
+	assert.Equal(s.T(), fingerprintTestScenes(1, 2, 0), fingerprint.Scenes)
+
+	// A changed file is fingerprinted again
+	require.NoError(s.T(), os.WriteFile(mediaPath, []byte("another video"), 0644))
+	fileInfo, err = os.Stat(mediaPath)
+	require.NoError(s.T(), err)
+	assert.Nil(s.T(), loaded.Lookup(mediaPath, fileInfo))
+
+	require.NoError(s.T(), os.WriteFile(indexPath, []byte("{"), 0644))
+	_, err = LoadFingerprintIndex(indexPath)
+	assert.Error(s.T(), err)
+}
+
+// TestFingerprintAnalyzerSuite runs the FingerprintAnalyzer test suite.
+func TestFingerprintAnalyzerSuite(t *testing.T) {
+	suite.Run(t, new(FingerprintAnalyzerTestSuite))
+}
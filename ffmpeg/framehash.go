@@ -0,0 +1,267 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Private variables (alphabetical)
+
+var (
+	// frameHashTimeBaseRegex matches the time base header of an output stream of the framehash
+	// muxer, such as "#tb 0: 1/25".
+	frameHashTimeBaseRegex = regexp.MustCompile(`^#tb (\d+): (\d+)/(\d+)`)
+)
+
+// Private functions (alphabetical)
+
+// compareStreamFrames finds the first frame that differs between two streams.
+func compareStreamFrames(comparison *StreamHashComparison, framesA, framesB []FrameHash) {
+	comparison.FirstDifference = -1
+	for i := 0; i < max(len(framesA), len(framesB)); i++ {
+		if i < len(framesA) && i < len(framesB) && framesA[i].Hash == framesB[i].Hash {
+			continue
+		}
+		comparison.FirstDifference = i
+		if i < len(framesA) {
+			comparison.TimeA, comparison.HashA = framesA[i].Time, framesA[i].Hash
+		}
+		if i < len(framesB) {
+			comparison.TimeB, comparison.HashB = framesB[i].Time, framesB[i].Hash
+		}
+		return
+	}
+}
+
+// groupFrameHashes groups frame checksums by stream index, keeping their order.
+func groupFrameHashes(frames []FrameHash) map[int][]FrameHash {
+	groups := make(map[int][]FrameHash)
+	for _, frame := range frames {
+		groups[frame.StreamIndex] = append(groups[frame.StreamIndex], frame)
+	}
+	return groups
+}
+
+// hashableStreams lists the streams of the container that are hashed: every video stream
+// except cover art, then every audio stream.
+func hashableStreams(info *ContainerInfo) []StreamHash {
+	var streams []StreamHash
+	for _, stream := range info.VideoStreams {
+		if !stream.Disposition.AttachedPic {
+			streams = append(streams, StreamHash{StreamIndex: stream.Index, Type: "video"})
+		}
+	}
+	for _, stream := range info.AudioStreams {
+		streams = append(streams, StreamHash{StreamIndex: stream.Index, Type: "audio"})
+	}
+	return streams
+}
+
+// Public functions (alphabetical)
+
+// CompareFrameHashes compares the decoded frames of two files. Streams are paired by type in
+// the order they appear, so the first video stream of a file is compared with the first video
+// stream of the other. The frames are the checksums sent by FrameHashAnalyzer.Analyze.
+func CompareFrameHashes(a, b *FileHash, framesA, framesB []FrameHash) *FrameHashComparison {
+	groupsA, groupsB := groupFrameHashes(framesA), groupFrameHashes(framesB)
+	comparison := &FrameHashComparison{Identical: true}
+
+	for _, streamType := range []string{"video", "audio"} {
+		var streamsA, streamsB []StreamHash
+		for _, stream := range a.Streams {
+			if stream.Type == streamType {
+				streamsA = append(streamsA, stream)
+			}
+		}
+		for _, stream := range b.Streams {
+			if stream.Type == streamType {
+				streamsB = append(streamsB, stream)
+			}
+		}
+
+		for i := 0; i < max(len(streamsA), len(streamsB)); i++ {
+			pair := StreamHashComparison{Type: streamType, StreamIndexA: -1, StreamIndexB: -1}
+			var pairFramesA, pairFramesB []FrameHash
+			if i < len(streamsA) {
+				pair.StreamIndexA, pair.FramesA = streamsA[i].StreamIndex, streamsA[i].Frames
+				pairFramesA = groupsA[streamsA[i].StreamIndex]
+			}
+			if i < len(streamsB) {
+				pair.StreamIndexB, pair.FramesB = streamsB[i].StreamIndex, streamsB[i].Frames
+				pairFramesB = groupsB[streamsB[i].StreamIndex]
+			}
+
+			pair.Identical = i < len(streamsA) && i < len(streamsB) &&
+				streamsA[i].ContentHash == streamsB[i].ContentHash && pair.FramesA == pair.FramesB
+			if pair.Identical {
+				pair.FirstDifference = -1
+			} else {
+				compareStreamFrames(&pair, pairFramesA, pairFramesB)
+				comparison.Identical = false
+			}
+			comparison.Streams = append(comparison.Streams, pair)
+		}
+	}
+	return comparison
+}
+
+// FileSHA256 returns the SHA-256 checksum of a file as a hexadecimal string.
+func FileSHA256(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("error opening file: %w", err)
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("error reading file: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// NewFrameHashAnalyzer creates a new FrameHashAnalyzer using MD5, like the framemd5 muxer.
+func NewFrameHashAnalyzer(ffmpegInfo *FFmpegInfo) (*FrameHashAnalyzer, error) {
+	if ffmpegInfo == nil || !ffmpegInfo.Installed {
+		return nil, fmt.Errorf("ffmpeg not available")
+	}
+
+	return &FrameHashAnalyzer{
+		FFmpegPath: ffmpegInfo.Path,
+		Algorithm:  frameHashDefaultAlgorithm,
+	}, nil
+}
+
+// Private methods (alphabetical)
+
+// parseOutput reads the framehash output, sends every frame checksum to the channel and fills
+// in the frame count and the content hash of the streams. Output stream k is result.Streams[k],
+// as every stream is mapped in that order.
+func (a *FrameHashAnalyzer) parseOutput(ctx context.Context, r io.Reader, result *FileHash, frameCh chan<- FrameHash) error {
+	hashers := make([]hash.Hash, len(result.Streams))
+	for i := range hashers {
+		hashers[i] = sha256.New()
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if match := frameHashTimeBaseRegex.FindStringSubmatch(line); match != nil {
+			output, _ := strconv.Atoi(match[1])
+			num, _ := strconv.ParseFloat(match[2], 64)
+			den, _ := strconv.ParseFloat(match[3], 64)
+			if output < len(result.Streams) && den > 0 {
+				result.Streams[output].TimeBase = num / den
+			}
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// stream#, dts, pts, duration, size, hash
+		fields := strings.Split(line, ",")
+		if len(fields) < 6 {
+			continue
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		output, err := strconv.Atoi(fields[0])
+		if err != nil || output >= len(result.Streams) {
+			continue
+		}
+		stream := &result.Streams[output]
+
+		frame := FrameHash{StreamIndex: stream.StreamIndex, Frame: stream.Frames, Hash: fields[5]}
+		frame.PTS, _ = strconv.ParseInt(fields[2], 10, 64)
+		frame.Duration, _ = strconv.ParseInt(fields[3], 10, 64)
+		frame.Size, _ = strconv.Atoi(fields[4])
+		frame.Time = float64(frame.PTS) * stream.TimeBase
+
+		stream.Frames++
+		hashers[output].Write([]byte(frame.Hash + "\n"))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case frameCh <- frame:
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading framehash output: %w", err)
+	}
+
+	// The content hash of the file combines the stream hashes in stream order
+	fileHasher := sha256.New()
+	for i := range result.Streams {
+		result.Streams[i].ContentHash = hex.EncodeToString(hashers[i].Sum(nil))
+		fileHasher.Write([]byte(result.Streams[i].Type + ":" + result.Streams[i].ContentHash + "\n"))
+	}
+	result.ContentHash = hex.EncodeToString(fileHasher.Sum(nil))
+	return nil
+}
+
+// Public methods (alphabetical)
+
+// Analyze decodes every video and audio stream of a file and sends the checksum of every
+// frame to the provided channel. The channel is closed when the analysis ends. The returned
+// FileHash holds the SHA-256 of the file and content hashes that only depend on the decoded
+// frames, so the same streams in different containers get the same content hashes.
+func (a *FrameHashAnalyzer) Analyze(ctx context.Context, filePath string, info *ContainerInfo, frameCh chan<- FrameHash) (*FileHash, error) {
+	defer close(frameCh)
+
+	result := &FileHash{Path: filePath, Streams: hashableStreams(info)}
+	if len(result.Streams) == 0 {
+		return nil, fmt.Errorf("no video or audio streams to hash")
+	}
+
+	fileHash, err := FileSHA256(filePath)
+	if err != nil {
+		return nil, err
+	}
+	result.SHA256 = fileHash
+
+	args := []string{"-hide_banner", "-nostats", "-v", "error", "-i", filePath}
+	for _, stream := range result.Streams {
+		args = append(args, "-map", fmt.Sprintf("0:%d", stream.StreamIndex))
+	}
+	args = append(args, "-f", "framehash", "-hash", a.Algorithm, "-")
+	cmd := exec.CommandContext(ctx, a.FFmpegPath, args...)
+
+	// The framehash muxer writes one line per frame on stdout
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start FFmpeg: %w", err)
+	}
+
+	parseErr := a.parseOutput(ctx, stdout, result, frameCh)
+	if parseErr != nil {
+		// Drain the output so FFmpeg can exit
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("error running framehash: %w", err)
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return result, nil
+}
@@ -0,0 +1,152 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the frame checksums.
+package ffmpeg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// frameHashTestOutput is the framehash output of three video frames and two audio frames.
+const frameHashTestOutput = `#format: frame checksums
+#version: 2
+#hash: MD5
+#tb 0: 1/25
+#media_type 0: video
+#codec_id 0: rawvideo
+#dimensions 0: 320x240
+#sar 0: 1/1
+#tb 1: 1/48000
+#media_type 1: audio
+#codec_id 1: pcm_s16le
+#sample_rate 1: 48000
+#channel_layout_name 1: stereo
+#stream#, dts,        pts, duration,     size, hash
+0,          0,          0,        1,   115200, 5b8c3f0a2e7e31d0ff1d4e8d5d2a3c11
+1,          0,          0,     1024,     4096, 0f343b0931126a20f133d67c2b018a3b
+0,          1,          1,        1,   115200, 9e107d9d372bb6826bd81d3542a419d6
+1,       1024,       1024,     1024,     4096, 0f343b0931126a20f133d67c2b018a3b
+0,          2,          2,        1,   115200, e4d909c290d0fb1ca068ffaddf22cbd0
+`
+
+// FrameHashAnalyzerTestSuite defines the test suite for FrameHashAnalyzer.
+// It parses synthetic framehash output so no sample media is required.
+type FrameHashAnalyzerTestSuite struct {
+	suite.Suite
+	analyzer *FrameHashAnalyzer // FrameHashAnalyzer instance under test
+	tempDir  string             // Temporary directory for test files
+}
+
+// SetupTest creates an analyzer and a temporary directory.
+func (s *FrameHashAnalyzerTestSuite) SetupTest() {
+	analyzer, err := NewFrameHashAnalyzer(&FFmpegInfo{Installed: true, Path: "ffmpeg"})
+	require.NoError(s.T(), err)
+	s.analyzer = analyzer
+
+	tempDir, err := os.MkdirTemp("", "framehash-test")
+	require.NoError(s.T(), err)
+	s.tempDir = tempDir
+}
+
+// TearDownTest removes the temporary directory.
+func (s *FrameHashAnalyzerTestSuite) TearDownTest() {
+	os.RemoveAll(s.tempDir)
+}
+
+// parse runs the parser on the test output for a file with a video stream 0 and an audio stream 2.
+func (s *FrameHashAnalyzerTestSuite) parse(output string) (*FileHash, []FrameHash) {
+	result := &FileHash{Streams: hashableStreams(&ContainerInfo{
+		VideoStreams: []VideoStream{{Index: 0}, {Index: 1, Disposition: StreamDisposition{AttachedPic: true}}},
+		AudioStreams: []AudioStream{{Index: 2}},
+	})}
+	frameCh := make(chan FrameHash, 16)
+	require.NoError(s.T(), s.analyzer.parseOutput(context.Background(), strings.NewReader(output), result, frameCh))
+	close(frameCh)
+
+	var frames []FrameHash
+	for frame := range frameCh {
+		frames = append(frames, frame)
+	}
+	return result, frames
+}
+
+// TestParseOutput verifies the frame checksums, time bases and content hashes.
+func (s *FrameHashAnalyzerTestSuite) TestParseOutput() {
+	assert.Equal(s.T(), "md5", s.analyzer.Algorithm)
+
+	result, frames := s.parse(frameHashTestOutput)
+	require.Len(s.T(), result.Streams, 2)
+	require.Len(s.T(), frames, 5)
+
+	assert.Equal(s.T(), 3, result.Streams[0].Frames)
+	assert.Equal(s.T(), 2, result.Streams[1].Frames)
+	assert.Equal(s.T(), 2, result.Streams[1].StreamIndex)
+	assert.InDelta(s.T(), 1.0/48000, result.Streams[1].TimeBase, 1e-12)
+
+	assert.Equal(s.T(), FrameHash{StreamIndex: 0, Frame: 1, PTS: 1, Time: 0.04, Duration: 1, Size: 115200,
+		Hash: "9e107d9d372bb6826bd81d3542a419d6"}, frames[2])
+	assert.Equal(s.T(), 2, frames[3].StreamIndex)
+	assert.Equal(s.T(), 1, frames[3].Frame)
+
+	assert.Len(s.T(), result.ContentHash, 64)
+	assert.NotEqual(s.T(), result.Streams[0].ContentHash, result.Streams[1].ContentHash)
+
+	// Different timestamps in another container do not change the content hashes
+	shifted := strings.ReplaceAll(frameHashTestOutput, "0,          1,          1,", "0,         11,         11,")
+	other, _ := s.parse(shifted)
+	assert.Equal(s.T(), result.ContentHash, other.ContentHash)
+}
+
+// TestCompareFrameHashes verifies identical files, the first differing frame and missing streams.
+func (s *FrameHashAnalyzerTestSuite) TestCompareFrameHashes() {
+	a, framesA := s.parse(frameHashTestOutput)
+	b, framesB := s.parse(frameHashTestOutput)
+	comparison := CompareFrameHashes(a, b, framesA, framesB)
+	assert.True(s.T(), comparison.Identical)
+	require.Len(s.T(), comparison.Streams, 2)
+	assert.Equal(s.T(), -1, comparison.Streams[0].FirstDifference)
+
+	changed := strings.Replace(frameHashTestOutput, "e4d909c290d0fb1ca068ffaddf22cbd0", "00000000000000000000000000000000", 1)
+	c, framesC := s.parse(changed)
+	comparison = CompareFrameHashes(a, c, framesA, framesC)
+	assert.False(s.T(), comparison.Identical)
+	video := comparison.Streams[0]
+	assert.False(s.T(), video.Identical)
+	assert.Equal(s.T(), 2, video.FirstDifference)
+	assert.InDelta(s.T(), 0.08, video.TimeB, 1e-9)
+	assert.Equal(s.T(), "00000000000000000000000000000000", video.HashB)
+	assert.True(s.T(), comparison.Streams[1].Identical)
+
+	// A file without audio leaves the audio stream unmatched
+	videoOnly := &FileHash{Streams: a.Streams[:1]}
+	comparison = CompareFrameHashes(a, videoOnly, framesA, framesA[:0])
+	assert.False(s.T(), comparison.Identical)
+	require.Len(s.T(), comparison.Streams, 2)
+	assert.Equal(s.T(), -1, comparison.Streams[1].StreamIndexB)
+	assert.Equal(s.T(), 0, comparison.Streams[1].FirstDifference)
+}
+
+// TestFileSHA256 verifies the checksum of a file.
+func (s *FrameHashAnalyzerTestSuite) TestFileSHA256() {
+	path := filepath.Join(s.tempDir, "abc.bin")
+	require.NoError(s.T(), os.WriteFile(path, []byte("abc"), 0644))
+
+	checksum, err := FileSHA256(path)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", checksum)
+
+	_, err = FileSHA256(filepath.Join(s.tempDir, "missing.bin"))
+	assert.Error(s.T(), err)
+}
+
+// TestFrameHashAnalyzerSuite runs the FrameHashAnalyzer test suite.
+func TestFrameHashAnalyzerSuite(t *testing.T) {
+	suite.Run(t, new(FrameHashAnalyzerTestSuite))
+}
@@ -0,0 +1,273 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Private variables (alphabetical)
+
+var (
+	// integrityConcealmentRegex matches decoder messages about concealed damage.
+	integrityConcealmentRegex = regexp.MustCompile(`(?i)conceal`)
+
+	// integrityLogRegex matches an FFmpeg log line with its component prefix, such as
+	// "[h264 @ 0x5581c0] error while decoding MB 12 7".
+	integrityLogRegex = regexp.MustCompile(`^\[([^\]]+?) @ (?:0x)?[0-9a-fA-F]+\]\s*(.*)$`)
+
+	// integrityMissingReferenceRegex matches decoder messages about unavailable reference pictures.
+	integrityMissingReferenceRegex = regexp.MustCompile(`(?i)missing reference|reference picture missing|no reference|co located POCs unavailable|could not find ref|reference \d+ >= \d+|reference frames? .*(unavailable|missing)`)
+
+	// integrityStreamRegex matches the stream reference of FFmpeg's own decoding error message,
+	// "Error while decoding stream #0:1: ...".
+	integrityStreamRegex = regexp.MustCompile(`(?i)error while decoding stream #\d+:\d+`)
+
+	// integrityTruncationRegex matches messages about data ending early.
+	integrityTruncationRegex = regexp.MustCompile(`(?i)partial file|truncat|unexpected end|premature end|end of file`)
+)
+
+// Private functions (alphabetical)
+
+// integrityEventType classifies an error message. Messages reported by the decoder of the
+// stream, whose name contains the codec name, belong to the stream; all other messages are
+// reported by the demuxer and belong to the container.
+func integrityEventType(source, message, format string) (string, bool) {
+	fromDecoder := (format != "" && strings.Contains(source, format)) ||
+		(source == "" && integrityStreamRegex.MatchString(message))
+
+	switch {
+	case integrityConcealmentRegex.MatchString(message):
+		return IntegrityConcealment, fromDecoder
+	case integrityMissingReferenceRegex.MatchString(message):
+		return IntegrityMissingReference, fromDecoder
+	case integrityTruncationRegex.MatchString(message):
+		return IntegrityTruncated, fromDecoder
+	case fromDecoder:
+		return IntegrityDecodeError, true
+	default:
+		return IntegrityContainerError, false
+	}
+}
+
+// Public functions (alphabetical)
+
+// NewIntegrityAnalyzer creates a new IntegrityAnalyzer with the default truncation tolerance.
+func NewIntegrityAnalyzer(ffmpegInfo *FFmpegInfo) (*IntegrityAnalyzer, error) {
+	if ffmpegInfo == nil || !ffmpegInfo.Installed {
+		return nil, fmt.Errorf("ffmpeg not available")
+	}
+
+	return &IntegrityAnalyzer{
+		FFmpegPath:          ffmpegInfo.Path,
+		TruncationTolerance: integrityTruncationTolerance,
+	}, nil
+}
+
+// NewIntegrityReport creates a report listing the streams of the container to check:
+// every audio stream and every video stream except cover art.
+func NewIntegrityReport(info *ContainerInfo) *IntegrityReport {
+	report := &IntegrityReport{}
+	for _, stream := range info.VideoStreams {
+		if stream.Disposition.AttachedPic {
+			continue
+		}
+		report.Streams = append(report.Streams, IntegrityStream{
+			StreamIndex:      stream.Index,
+			Type:             "video",
+			Format:           stream.Format,
+			DeclaredDuration: stream.Duration,
+		})
+	}
+	for _, stream := range info.AudioStreams {
+		report.Streams = append(report.Streams, IntegrityStream{
+			StreamIndex:      stream.Index,
+			Type:             "audio",
+			Format:           stream.Format,
+			DeclaredDuration: stream.Duration,
+		})
+	}
+	return report
+}
+
+// Private methods (alphabetical)
+
+// parseLog reads the errors printed by FFmpeg, stamps them with the current decoding
+// position and sends them to the channel. The stream error count is updated in result.
+func (a *IntegrityAnalyzer) parseLog(ctx context.Context, r io.Reader, result *IntegrityStream, position func() float64, eventCh chan<- IntegrityEvent) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		source, message := "", line
+		if match := integrityLogRegex.FindStringSubmatch(line); match != nil {
+			source, message = match[1], match[2]
+		}
+
+		eventType, fromDecoder := integrityEventType(source, message, result.Format)
+		event := IntegrityEvent{StreamIndex: -1, Time: position(), Type: eventType, Source: source, Message: message}
+		if fromDecoder {
+			event.StreamIndex = result.StreamIndex
+			result.Errors++
+		}
+		if eventType == IntegrityTruncated {
+			result.Truncated = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case eventCh <- event:
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading decoder output: %w", err)
+	}
+	return nil
+}
+
+// readProgress follows the progress output of FFmpeg and stores the latest output time in
+// microseconds. Both out_time_us and the misnamed out_time_ms are microseconds.
+func (a *IntegrityAnalyzer) readProgress(r io.Reader, position *atomic.Int64) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, rawValue, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !found || (key != "out_time_us" && key != "out_time_ms") {
+			continue
+		}
+		if value, err := strconv.ParseInt(rawValue, 10, 64); err == nil && value > position.Load() {
+			position.Store(value)
+		}
+	}
+}
+
+// Public methods (alphabetical)
+
+// Add records an event and reports whether it is new. Container errors are reported again by
+// the decoding pass of every stream, so a container error already recorded is skipped.
+func (r *IntegrityReport) Add(event IntegrityEvent) bool {
+	if event.StreamIndex < 0 {
+		for _, recorded := range r.Events {
+			if recorded.StreamIndex < 0 && recorded.Source == event.Source && recorded.Message == event.Message {
+				return false
+			}
+		}
+	}
+	r.Events = append(r.Events, event)
+	return true
+}
+
+// Analyze decodes a stream in full and sends every error reported by FFmpeg to the provided
+// channel as soon as it is printed. The channel is closed when the analysis ends. The returned
+// result is the given stream with the decoded duration, the error count and the truncation and
+// failure flags filled in. FFmpeg stopping on a fatal error is a finding, not an error.
+func (a *IntegrityAnalyzer) Analyze(ctx context.Context, filePath string, stream IntegrityStream, eventCh chan<- IntegrityEvent) (IntegrityStream, error) {
+	defer close(eventCh)
+
+	cmd := exec.CommandContext(
+		ctx,
+		a.FFmpegPath,
+		"-hide_banner",
+		"-nostats",
+		"-v", "error",
+		"-err_detect", "crccheck+bitstream+buffer",
+		"-i", filePath,
+		"-map", fmt.Sprintf("0:%d", stream.StreamIndex),
+		"-progress", "pipe:1",
+		"-f", "null",
+		"-",
+	)
+
+	// The progress report is printed on stdout and the errors on stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return stream, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return stream, fmt.Errorf("failed to get stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return stream, fmt.Errorf("failed to start FFmpeg: %w", err)
+	}
+
+	var position atomic.Int64
+	progressDone := make(chan struct{})
+	go func() {
+		defer close(progressDone)
+		a.readProgress(stdout, &position)
+	}()
+	currentTime := func() float64 { return float64(position.Load()) / 1e6 }
+
+	result := stream
+	parseErr := a.parseLog(ctx, stderr, &result, currentTime, eventCh)
+	if parseErr != nil {
+		// Drain the output so FFmpeg can exit
+		_, _ = io.Copy(io.Discard, stderr)
+	}
+	<-progressDone
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		result.Failed = true
+	}
+	if parseErr != nil {
+		return result, parseErr
+	}
+
+	result.DecodedDuration = currentTime()
+	if result.DeclaredDuration > 0 && result.DecodedDuration < result.DeclaredDuration-a.TruncationTolerance {
+		result.Truncated = true
+		event := IntegrityEvent{
+			StreamIndex: result.StreamIndex,
+			Time:        result.DecodedDuration,
+			Type:        IntegrityTruncated,
+			Message:     fmt.Sprintf("data ends at %.3f s of a declared duration of %.3f s", result.DecodedDuration, result.DeclaredDuration),
+		}
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case eventCh <- event:
+		}
+	}
+	return result, nil
+}
+
+// Count returns the number of events of the given type.
+func (r *IntegrityReport) Count(eventType string) int {
+	count := 0
+	for _, event := range r.Events {
+		if event.Type == eventType {
+			count++
+		}
+	}
+	return count
+}
+
+// Healthy reports whether every stream decoded to its end without errors.
+func (r *IntegrityReport) Healthy() bool {
+	if len(r.Events) > 0 {
+		return false
+	}
+	for _, stream := range r.Streams {
+		if stream.Truncated || stream.Failed {
+			return false
+		}
+	}
+	return true
+}
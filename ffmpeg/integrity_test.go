@@ -0,0 +1,125 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the decode integrity check.
+package ffmpeg
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// integrityTestLog is the error output of a damaged H.264 stream in an MP4 file.
+const integrityTestLog = `[h264 @ 0x55d1c2a4e3c0] error while decoding MB 41 22, bytestream -7
+[h264 @ 0x55d1c2a4e3c0] concealing 812 DC, 812 AC, 812 MV errors in P frame
+[h264 @ 0x55d1c2a4e3c0] Missing reference picture, default is 65530
+
+[mov,mp4,m4a,3gp,3g2,mj2 @ 0x55d1c2a41f00] stream 0, offset 0x2f7a1: partial file
+Error while decoding stream #0:0: Invalid data found when processing input
+`
+
+// IntegrityAnalyzerTestSuite defines the test suite for IntegrityAnalyzer.
+// It parses synthetic FFmpeg output so no sample media is required.
+type IntegrityAnalyzerTestSuite struct {
+	suite.Suite
+	analyzer *IntegrityAnalyzer // IntegrityAnalyzer instance under test
+}
+
+// SetupTest creates an analyzer with the default tolerance.
+func (s *IntegrityAnalyzerTestSuite) SetupTest() {
+	analyzer, err := NewIntegrityAnalyzer(&FFmpegInfo{Installed: true, Path: "ffmpeg"})
+	require.NoError(s.T(), err)
+	s.analyzer = analyzer
+}
+
+// TestNewIntegrityAnalyzer verifies that FFmpeg is required.
+func (s *IntegrityAnalyzerTestSuite) TestNewIntegrityAnalyzer() {
+	_, err := NewIntegrityAnalyzer(nil)
+	assert.Error(s.T(), err)
+	_, err = NewIntegrityAnalyzer(&FFmpegInfo{Installed: false})
+	assert.Error(s.T(), err)
+	assert.InDelta(s.T(), integrityTruncationTolerance, s.analyzer.TruncationTolerance, 1e-9)
+}
+
+// TestParseLog verifies the classification and the time stamps of decoder and container errors.
+func (s *IntegrityAnalyzerTestSuite) TestParseLog() {
+	result := IntegrityStream{StreamIndex: 0, Type: "video", Format: "h264", DeclaredDuration: 60}
+	eventCh := make(chan IntegrityEvent, 16)
+	require.NoError(s.T(), s.analyzer.parseLog(context.Background(), strings.NewReader(integrityTestLog), &result,
+		func() float64 { return 12.5 }, eventCh))
+	close(eventCh)
+
+	var events []IntegrityEvent
+	for event := range eventCh {
+		events = append(events, event)
+	}
+	require.Len(s.T(), events, 5)
+
+	assert.Equal(s.T(), IntegrityDecodeError, events[0].Type)
+	assert.Equal(s.T(), "h264", events[0].Source)
+	assert.Equal(s.T(), "error while decoding MB 41 22, bytestream -7", events[0].Message)
+	assert.InDelta(s.T(), 12.5, events[0].Time, 1e-9)
+	assert.Equal(s.T(), IntegrityConcealment, events[1].Type)
+	assert.Equal(s.T(), IntegrityMissingReference, events[2].Type)
+
+	assert.Equal(s.T(), IntegrityTruncated, events[3].Type)
+	assert.Equal(s.T(), -1, events[3].StreamIndex)
+	assert.Equal(s.T(), "mov,mp4,m4a,3gp,3g2,mj2", events[3].Source)
+
+	assert.Equal(s.T(), IntegrityDecodeError, events[4].Type)
+	assert.Equal(s.T(), 0, events[4].StreamIndex)
+	assert.Empty(s.T(), events[4].Source)
+
+	assert.Equal(s.T(), 4, result.Errors)
+	assert.True(s.T(), result.Truncated)
+}
+
+// TestReadProgress verifies that the latest output time is kept.
+func (s *IntegrityAnalyzerTestSuite) TestReadProgress() {
+	progress := "frame=25\nout_time_us=1000000\nout_time_ms=1000000\nprogress=continue\n" +
+		"out_time_us=N/A\nout_time_us=2040000\nprogress=end\n"
+
+	var position atomic.Int64
+	s.analyzer.readProgress(strings.NewReader(progress), &position)
+	assert.Equal(s.T(), int64(2040000), position.Load())
+}
+
+// TestReport verifies the stream selection, the deduplication of container errors and the verdict.
+func (s *IntegrityAnalyzerTestSuite) TestReport() {
+	report := NewIntegrityReport(&ContainerInfo{
+		VideoStreams: []VideoStream{
+			{Index: 0, Format: "h264", Duration: 60},
+			{Index: 3, Format: "mjpeg", Disposition: StreamDisposition{AttachedPic: true}},
+		},
+		AudioStreams: []AudioStream{{Index: 1, Format: "aac", Duration: 59.9}},
+	})
+	require.Len(s.T(), report.Streams, 2)
+	assert.Equal(s.T(), "video", report.Streams[0].Type)
+	assert.Equal(s.T(), "audio", report.Streams[1].Type)
+	assert.InDelta(s.T(), 59.9, report.Streams[1].DeclaredDuration, 1e-9)
+	assert.True(s.T(), report.Healthy())
+
+	report.Streams[1].Truncated = true
+	assert.False(s.T(), report.Healthy())
+	report.Streams[1].Truncated = false
+
+	container := IntegrityEvent{StreamIndex: -1, Type: IntegrityContainerError, Source: "matroska,webm", Message: "Read error"}
+	assert.True(s.T(), report.Add(container))
+	assert.True(s.T(), report.Add(IntegrityEvent{StreamIndex: 0, Type: IntegrityConcealment}))
+	assert.True(s.T(), report.Add(IntegrityEvent{StreamIndex: 0, Type: IntegrityConcealment}))
+	container.Time = 30
+	assert.False(s.T(), report.Add(container))
+
+	assert.Len(s.T(), report.Events, 3)
+	assert.Equal(s.T(), 2, report.Count(IntegrityConcealment))
+	assert.False(s.T(), report.Healthy())
+}
+
+// TestIntegrityAnalyzerSuite runs the IntegrityAnalyzer test suite.
+func TestIntegrityAnalyzerSuite(t *testing.T) {
+	suite.Run(t, new(IntegrityAnalyzerTestSuite))
+}
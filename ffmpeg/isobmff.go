@@ -0,0 +1,475 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// Private variables (alphabetical)
+
+// mp4ContainerBoxes lists the box types whose payload is a sequence of child boxes.
+// Only these boxes are descended into while walking the hierarchy.
+var mp4ContainerBoxes = map[string]bool{
+	"dinf": true,
+	"edts": true,
+	"mdia": true,
+	"mfra": true,
+	"minf": true,
+	"moof": true,
+	"moov": true,
+	"mvex": true,
+	"stbl": true,
+	"traf": true,
+	"trak": true,
+}
+
+// mp4WebBrands lists the ftyp brands that web players recognize as MP4 compatible.
+var mp4WebBrands = map[string]bool{
+	"avc1": true,
+	"dash": true,
+	"iso2": true,
+	"iso4": true,
+	"iso5": true,
+	"iso6": true,
+	"isom": true,
+	"mp41": true,
+	"mp42": true,
+}
+
+// Private functions (alphabetical)
+
+// mp4ReadBoxHeader reads the box header starting at offset and returns the box type,
+// the total box size and the header size. The end parameter bounds the enclosing box.
+func mp4ReadBoxHeader(r io.ReaderAt, offset, end int64) (string, int64, int64, error) {
+	if end-offset < 8 {
+		return "", 0, 0, fmt.Errorf("truncated box header at offset %d", offset)
+	}
+
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, offset); err != nil {
+		return "", 0, 0, fmt.Errorf("error reading box header at offset %d: %w", offset, err)
+	}
+
+	size := int64(binary.BigEndian.Uint32(header[0:4]))
+	boxType := string(header[4:8])
+	headerSize := int64(8)
+
+	switch size {
+	case 0:
+		// A size of zero means the box extends to the end of the enclosing space
+		size = end - offset
+	case 1:
+		// A size of one means a 64-bit size follows the box type
+		if end-offset < 16 {
+			return "", 0, 0, fmt.Errorf("truncated large box header at offset %d", offset)
+		}
+		largeSize := make([]byte, 8)
+		if _, err := r.ReadAt(largeSize, offset+8); err != nil {
+			return "", 0, 0, fmt.Errorf("error reading large box size at offset %d: %w", offset, err)
+		}
+		largeValue := binary.BigEndian.Uint64(largeSize)
+		if largeValue > math.MaxInt64 {
+			return "", 0, 0, fmt.Errorf("invalid box size at offset %d", offset)
+		}
+		size = int64(largeValue)
+		headerSize = 16
+	}
+
+	if boxType == "uuid" {
+		headerSize += 16
+	}
+
+	if size < headerSize || offset+size > end {
+		return "", 0, 0, fmt.Errorf("invalid size %d for box '%s' at offset %d", size, boxType, offset)
+	}
+
+	return boxType, size, headerSize, nil
+}
+
+// mp4ReadPayload reads the payload of a box into memory, refusing oversized payloads.
+func mp4ReadPayload(r io.ReaderAt, offset, size int64) ([]byte, error) {
+	if size > mp4MaxPayloadSize {
+		return nil, fmt.Errorf("box payload too large (%d bytes)", size)
+	}
+
+	payload := make([]byte, size)
+	if _, err := r.ReadAt(payload, offset); err != nil {
+		return nil, fmt.Errorf("error reading box payload at offset %d: %w", offset, err)
+	}
+
+	return payload, nil
+}
+
+// Public functions (alphabetical)
+
+// NewMP4Inspector creates a new MP4Inspector.
+// The inspector is pure Go and does not depend on an FFmpeg installation.
+func NewMP4Inspector() *MP4Inspector {
+	return &MP4Inspector{}
+}
+
+// Private methods (alphabetical)
+
+// buildEditList converts the raw entries of a track edit list into a presentation shift.
+func (m *MP4Inspector) buildEditList(entries []mp4EditEntry, movieTimescale uint32, mediaTimescale uint32) *MP4EditList {
+	editList := &MP4EditList{EntryCount: len(entries)}
+
+	for _, entry := range entries {
+		if entry.mediaTime == -1 {
+			// Empty edits delay the presentation, expressed in movie timescale
+			if movieTimescale > 0 {
+				editList.EmptyEdit += float64(entry.segmentDuration) / float64(movieTimescale)
+			}
+			continue
+		}
+
+		// The first non-empty edit decides where media playback starts
+		if mediaTimescale > 0 {
+			editList.MediaStart = float64(entry.mediaTime) / float64(mediaTimescale)
+		}
+		break
+	}
+	editList.StartShift = editList.EmptyEdit - editList.MediaStart
+
+	return editList
+}
+
+// evaluate runs the delivery checks on a walked structure and records the findings.
+func (m *MP4Inspector) evaluate(structure *MP4Structure) {
+	// Faststart: moov must come before the first mdat for progressive playback
+	switch {
+	case structure.MoovOffset < 0:
+		structure.Issues = append(structure.Issues, "moov box is missing")
+	case structure.MdatOffset >= 0 && structure.MoovOffset > structure.MdatOffset:
+		structure.Issues = append(structure.Issues, "moov box is located after mdat (not faststart)")
+	}
+
+	// Brand compatibility
+	m.evaluateBrands(structure)
+
+	// Fragmented layout
+	if structure.Fragmented {
+		sidxState := "missing"
+		if structure.HasSegmentIndex {
+			sidxState = "present"
+		}
+		structure.Issues = append(structure.Issues, fmt.Sprintf(
+			"fragmented layout (%d moof boxes, sidx %s)", structure.FragmentCount, sidxState))
+	}
+
+	// Multiple sample descriptions and edit list shifts. Edit lists also carry the encoder delay:
+	// the video media start compensates the composition offset of B-frames and the audio media
+	// start skips the codec priming, neither moves the A/V start.
+	var videoShift float64
+	for _, track := range structure.Tracks {
+		if track.HandlerType == "vide" && track.EditList != nil {
+			videoShift = track.EditList.EmptyEdit
+			break
+		}
+	}
+
+	for _, track := range structure.Tracks {
+		if track.SampleDescriptions > 1 {
+			structure.Issues = append(structure.Issues, fmt.Sprintf(
+				"track %d (%s) has %d sample descriptions", track.TrackID, track.HandlerType, track.SampleDescriptions))
+		}
+
+		if track.EditList == nil {
+			continue
+		}
+
+		// Without video any shift of the other tracks is reported
+		if track.HandlerType == "vide" {
+			if track.EditList.EmptyEdit >= mp4EditShiftThreshold {
+				structure.Issues = append(structure.Issues, fmt.Sprintf(
+					"track %d (vide) edit list shifts start by %+.3f s", track.TrackID, track.EditList.EmptyEdit))
+			}
+			continue
+		}
+		if delta := m.presentationShift(track) - videoShift; math.Abs(delta) >= mp4EditShiftThreshold {
+			structure.Issues = append(structure.Issues, fmt.Sprintf(
+				"track %d (%s) edit list shifts start by %+.3f s relative to video",
+				track.TrackID, track.HandlerType, delta))
+		}
+	}
+}
+
+// evaluateBrands checks the ftyp brands for web compatibility.
+func (m *MP4Inspector) evaluateBrands(structure *MP4Structure) {
+	if structure.MajorBrand == "" {
+		structure.Issues = append(structure.Issues, "ftyp box is missing")
+		return
+	}
+
+	if structure.MajorBrand == "qt  " {
+		structure.Issues = append(structure.Issues, "major brand is 'qt  ' (QuickTime), not an ISO brand")
+	}
+
+	compatible := mp4WebBrands[structure.MajorBrand]
+	for _, brand := range structure.CompatibleBrands {
+		if mp4WebBrands[brand] {
+			compatible = true
+			break
+		}
+	}
+
+	if !compatible {
+		structure.Issues = append(structure.Issues, "no web compatible brand (isom, mp41, mp42, avc1, ...) declared")
+	}
+}
+
+// parseBox interprets the payload of the leaf boxes that feed the delivery checks.
+func (m *MP4Inspector) parseBox(state *mp4WalkState, box MP4Box, headerSize int64, track *MP4Track) error {
+	switch box.Type {
+	case "ftyp", "mvhd", "tkhd", "mdhd", "hdlr", "elst", "stsd":
+	default:
+		return nil
+	}
+
+	payload, err := mp4ReadPayload(state.file, box.Offset+headerSize, box.Size-headerSize)
+	if err != nil {
+		return err
+	}
+
+	switch box.Type {
+	case "ftyp":
+		m.parseFtyp(state.structure, payload)
+	case "mvhd":
+		state.movieTimescale = m.parseFieldAfterTimes(payload)
+	case "tkhd":
+		if track != nil {
+			track.TrackID = m.parseFieldAfterTimes(payload)
+		}
+	case "mdhd":
+		if track != nil {
+			track.Timescale = m.parseFieldAfterTimes(payload)
+		}
+	case "hdlr":
+		if track != nil && len(payload) >= 12 {
+			track.HandlerType = string(payload[8:12])
+		}
+	case "elst":
+		if track != nil {
+			state.rawEdits[track] = m.parseElst(payload)
+		}
+	case "stsd":
+		if track != nil && len(payload) >= 8 {
+			track.SampleDescriptions = int(binary.BigEndian.Uint32(payload[4:8]))
+		}
+	}
+
+	return nil
+}
+
+// parseElst decodes the entries of an elst box for both version 0 and version 1 layouts.
+func (m *MP4Inspector) parseElst(payload []byte) []mp4EditEntry {
+	if len(payload) < 8 {
+		return nil
+	}
+
+	version := payload[0]
+	count := int(binary.BigEndian.Uint32(payload[4:8]))
+	entrySize := 12
+	if version == 1 {
+		entrySize = 20
+	}
+
+	var entries []mp4EditEntry
+	data := payload[8:]
+	for i := 0; i < count && len(data) >= entrySize; i++ {
+		var entry mp4EditEntry
+		if version == 1 {
+			entry.segmentDuration = binary.BigEndian.Uint64(data[0:8])
+			entry.mediaTime = int64(binary.BigEndian.Uint64(data[8:16]))
+		} else {
+			entry.segmentDuration = uint64(binary.BigEndian.Uint32(data[0:4]))
+			entry.mediaTime = int64(int32(binary.BigEndian.Uint32(data[4:8])))
+		}
+		entries = append(entries, entry)
+		data = data[entrySize:]
+	}
+
+	return entries
+}
+
+// parseFieldAfterTimes extracts the 32-bit field that follows the creation and
+// modification times in mvhd, mdhd (timescale) and tkhd (track ID) boxes.
+func (m *MP4Inspector) parseFieldAfterTimes(payload []byte) uint32 {
+	if len(payload) < 4 {
+		return 0
+	}
+
+	// Version 1 uses 64-bit creation and modification times
+	offset := 12
+	if payload[0] == 1 {
+		offset = 20
+	}
+	if len(payload) < offset+4 {
+		return 0
+	}
+
+	return binary.BigEndian.Uint32(payload[offset : offset+4])
+}
+
+// parseFtyp decodes the brands declared in an ftyp box.
+func (m *MP4Inspector) parseFtyp(structure *MP4Structure, payload []byte) {
+	if len(payload) < 8 {
+		return
+	}
+
+	structure.MajorBrand = string(payload[0:4])
+	structure.MinorVersion = binary.BigEndian.Uint32(payload[4:8])
+	for i := 8; i+4 <= len(payload); i += 4 {
+		structure.CompatibleBrands = append(structure.CompatibleBrands, string(payload[i:i+4]))
+	}
+}
+
+// presentationShift returns how far the edit list of a non-video track moves its start on the
+// presentation timeline. Media skipped by an audio track up to the codec priming is not counted.
+func (m *MP4Inspector) presentationShift(track MP4Track) float64 {
+	skipped := track.EditList.MediaStart
+	if track.HandlerType == "soun" && track.Timescale > 0 {
+		skipped = math.Max(skipped-float64(mp4AudioPrimingSamples)/float64(track.Timescale), 0)
+	}
+	return track.EditList.EmptyEdit - skipped
+}
+
+// recordTopLevel updates the layout information tracked for top-level boxes.
+func (m *MP4Inspector) recordTopLevel(structure *MP4Structure, box MP4Box, depth int) {
+	switch box.Type {
+	case "moov":
+		if depth == 0 && structure.MoovOffset < 0 {
+			structure.MoovOffset = box.Offset
+		}
+	case "mdat":
+		if depth == 0 && structure.MdatOffset < 0 {
+			structure.MdatOffset = box.Offset
+		}
+	case "moof":
+		structure.Fragmented = true
+		structure.FragmentCount++
+	case "mvex":
+		structure.Fragmented = true
+	case "sidx":
+		structure.HasSegmentIndex = true
+	}
+}
+
+// walk reads the boxes between offset and end, descending into container boxes.
+// Boxes found inside a trak box are attributed to the given track.
+func (m *MP4Inspector) walk(state *mp4WalkState, offset, end int64, depth int, track *MP4Track) ([]MP4Box, error) {
+	var boxes []MP4Box
+
+	for offset < end {
+		boxType, size, headerSize, err := mp4ReadBoxHeader(state.file, offset, end)
+		if err != nil {
+			return boxes, err
+		}
+
+		box := MP4Box{Type: boxType, Offset: offset, Size: size}
+		m.recordTopLevel(state.structure, box, depth)
+
+		if err := m.parseBox(state, box, headerSize, track); err != nil {
+			return boxes, err
+		}
+
+		if mp4ContainerBoxes[boxType] && depth < mp4MaxBoxDepth {
+			childTrack := track
+			if boxType == "trak" {
+				childTrack = &MP4Track{}
+			}
+
+			children, err := m.walk(state, offset+headerSize, offset+size, depth+1, childTrack)
+			box.Children = children
+			if boxType == "trak" {
+				// The edit list can only be resolved once mdhd provided the media timescale
+				if entries, ok := state.rawEdits[childTrack]; ok {
+					childTrack.EditList = m.buildEditList(entries, state.movieTimescale, childTrack.Timescale)
+					delete(state.rawEdits, childTrack)
+				}
+				state.structure.Tracks = append(state.structure.Tracks, *childTrack)
+			}
+			if err != nil {
+				boxes = append(boxes, box)
+				return boxes, err
+			}
+		}
+
+		boxes = append(boxes, box)
+		offset += size
+	}
+
+	return boxes, nil
+}
+
+// Public methods (alphabetical)
+
+// Inspect walks the box hierarchy of an MP4 or MOV file and evaluates its layout.
+// It flags files that are not faststart, edit lists that shift the A/V start,
+// tracks with multiple sample descriptions and fragmented layouts. A file that is
+// truncated part way through is still reported, with the problem listed in Issues.
+func (m *MP4Inspector) Inspect(filePath string) (*MP4Structure, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("error reading file information: %w", err)
+	}
+
+	structure := &MP4Structure{
+		MoovOffset: -1,
+		MdatOffset: -1,
+	}
+	state := &mp4WalkState{
+		file:      file,
+		structure: structure,
+		rawEdits:  make(map[*MP4Track][]mp4EditEntry),
+	}
+
+	boxes, walkErr := m.walk(state, 0, stat.Size(), 0, nil)
+	if len(boxes) == 0 {
+		if walkErr == nil {
+			walkErr = fmt.Errorf("file is empty")
+		}
+		return nil, fmt.Errorf("not an ISO-BMFF file: %w", walkErr)
+	}
+	structure.Boxes = boxes
+
+	structure.FastStart = structure.MoovOffset >= 0 &&
+		(structure.MdatOffset < 0 || structure.MoovOffset < structure.MdatOffset)
+	m.evaluate(structure)
+
+	if walkErr != nil {
+		structure.Issues = append(structure.Issues, "box walk stopped early: "+walkErr.Error())
+	}
+
+	return structure, nil
+}
+
+// String renders the box hierarchy as an indented tree, one box per line.
+func (s *MP4Structure) String() string {
+	var sb strings.Builder
+
+	var render func(boxes []MP4Box, depth int)
+	render = func(boxes []MP4Box, depth int) {
+		for _, box := range boxes {
+			fmt.Fprintf(&sb, "%s%s [offset=%d size=%d]\n", strings.Repeat("  ", depth), box.Type, box.Offset, box.Size)
+			render(box.Children, depth+1)
+		}
+	}
+	render(s.Boxes, 0)
+
+	return sb.String()
+}
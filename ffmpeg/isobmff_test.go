@@ -0,0 +1,232 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the ISO-BMFF box walker.
+package ffmpeg
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// MP4InspectorTestSuite defines the test suite for MP4Inspector.
+// It builds synthetic MP4 files in memory so no sample media is required.
+type MP4InspectorTestSuite struct {
+	suite.Suite
+	tempDir   string        // Temporary directory for generated files
+	inspector *MP4Inspector // MP4Inspector instance under test
+}
+
+// SetupSuite creates the temporary directory and the inspector.
+func (s *MP4InspectorTestSuite) SetupSuite() {
+	tempDir, err := os.MkdirTemp("", "isobmff-test")
+	require.NoError(s.T(), err)
+	s.tempDir = tempDir
+	s.inspector = NewMP4Inspector()
+}
+
+// TearDownSuite removes the temporary directory.
+func (s *MP4InspectorTestSuite) TearDownSuite() {
+	os.RemoveAll(s.tempDir)
+}
+
+// mp4TestBox serializes a box with a 32-bit size header.
+func mp4TestBox(boxType string, payloads ...[]byte) []byte {
+	size := 8
+	for _, p := range payloads {
+		size += len(p)
+	}
+
+	data := make([]byte, 8, size)
+	binary.BigEndian.PutUint32(data[0:4], uint32(size))
+	copy(data[4:8], boxType)
+	for _, p := range payloads {
+		data = append(data, p...)
+	}
+	return data
+}
+
+// mp4TestU32 serializes a sequence of 32-bit big endian values.
+func mp4TestU32(values ...uint32) []byte {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(data[i*4:], v)
+	}
+	return data
+}
+
+// mp4TestTrack builds a trak box with the given handler, timescale, edit list and sample description count.
+func mp4TestTrack(trackID uint32, handler string, timescale uint32, edits [][2]uint32, sampleDescriptions uint32) []byte {
+	tkhd := mp4TestBox("tkhd", mp4TestU32(0, 0, 0, trackID, 0))
+	mdhd := mp4TestBox("mdhd", mp4TestU32(0, 0, 0, timescale, 0))
+	hdlr := mp4TestBox("hdlr", mp4TestU32(0, 0), []byte(handler), mp4TestU32(0, 0, 0))
+	stsd := mp4TestBox("stsd", mp4TestU32(0, sampleDescriptions))
+	mdia := mp4TestBox("mdia", mdhd, hdlr, mp4TestBox("minf", mp4TestBox("stbl", stsd)))
+
+	if edits == nil {
+		return mp4TestBox("trak", tkhd, mdia)
+	}
+
+	elstPayload := mp4TestU32(0, uint32(len(edits)))
+	for _, edit := range edits {
+		elstPayload = append(elstPayload, mp4TestU32(edit[0], edit[1], 0x00010000)...)
+	}
+	return mp4TestBox("trak", tkhd, mp4TestBox("edts", mp4TestBox("elst", elstPayload)), mdia)
+}
+
+// writeFile writes the concatenated boxes to a file and returns its path.
+func (s *MP4InspectorTestSuite) writeFile(name string, boxes ...[]byte) string {
+	var data []byte
+	for _, b := range boxes {
+		data = append(data, b...)
+	}
+
+	path := filepath.Join(s.tempDir, name)
+	require.NoError(s.T(), os.WriteFile(path, data, 0644))
+	return path
+}
+
+// TestFastStartFile verifies that a clean faststart file passes all checks.
+func (s *MP4InspectorTestSuite) TestFastStartFile() {
+	ftyp := mp4TestBox("ftyp", []byte("isom"), mp4TestU32(512), []byte("isomiso2avc1mp41"))
+	moov := mp4TestBox("moov",
+		mp4TestBox("mvhd", mp4TestU32(0, 0, 0, 1000, 0)),
+		mp4TestTrack(1, "vide", 24000, nil, 1),
+		mp4TestTrack(2, "soun", 48000, nil, 1),
+	)
+	path := s.writeFile("faststart.mp4", ftyp, moov, mp4TestBox("mdat", make([]byte, 32)))
+
+	structure, err := s.inspector.Inspect(path)
+	require.NoError(s.T(), err)
+
+	assert.Equal(s.T(), "isom", structure.MajorBrand)
+	assert.Equal(s.T(), uint32(512), structure.MinorVersion)
+	assert.Equal(s.T(), []string{"isom", "iso2", "avc1", "mp41"}, structure.CompatibleBrands)
+	assert.True(s.T(), structure.FastStart)
+	assert.False(s.T(), structure.Fragmented)
+	assert.Empty(s.T(), structure.Issues)
+
+	require.Len(s.T(), structure.Tracks, 2)
+	assert.Equal(s.T(), uint32(1), structure.Tracks[0].TrackID)
+	assert.Equal(s.T(), "vide", structure.Tracks[0].HandlerType)
+	assert.Equal(s.T(), uint32(24000), structure.Tracks[0].Timescale)
+	assert.Equal(s.T(), "soun", structure.Tracks[1].HandlerType)
+
+	require.Len(s.T(), structure.Boxes, 3)
+	assert.Equal(s.T(), "moov", structure.Boxes[1].Type)
+	assert.Contains(s.T(), structure.String(), "  trak [offset=")
+}
+
+// TestNonFastStartFile verifies that moov after mdat is flagged.
+func (s *MP4InspectorTestSuite) TestNonFastStartFile() {
+	ftyp := mp4TestBox("ftyp", []byte("mp42"), mp4TestU32(0), []byte("mp42isom"))
+	moov := mp4TestBox("moov", mp4TestBox("mvhd", mp4TestU32(0, 0, 0, 1000, 0)), mp4TestTrack(1, "vide", 25, nil, 1))
+	path := s.writeFile("tail.mp4", ftyp, mp4TestBox("mdat", make([]byte, 16)), moov)
+
+	structure, err := s.inspector.Inspect(path)
+	require.NoError(s.T(), err)
+
+	assert.False(s.T(), structure.FastStart)
+	assert.Contains(s.T(), structure.Issues, "moov box is located after mdat (not faststart)")
+}
+
+// TestEditListsAndSampleDescriptions verifies edit list shifts and multiple stsd entries.
+func (s *MP4InspectorTestSuite) TestEditListsAndSampleDescriptions() {
+	ftyp := mp4TestBox("ftyp", []byte("isom"), mp4TestU32(0), []byte("isom"))
+	// Video starts at media time 0, audio has a 200 ms empty edit followed by 1024 samples of priming
+	videoEdits := [][2]uint32{{10000, 0}}
+	audioEdits := [][2]uint32{{200, 0xFFFFFFFF}, {9800, 1024}}
+	moov := mp4TestBox("moov",
+		mp4TestBox("mvhd", mp4TestU32(0, 0, 0, 1000, 0)),
+		mp4TestTrack(1, "vide", 24000, videoEdits, 2),
+		mp4TestTrack(2, "soun", 48000, audioEdits, 1),
+	)
+	path := s.writeFile("edits.mp4", ftyp, moov, mp4TestBox("mdat"))
+
+	structure, err := s.inspector.Inspect(path)
+	require.NoError(s.T(), err)
+
+	require.Len(s.T(), structure.Tracks, 2)
+	require.NotNil(s.T(), structure.Tracks[1].EditList)
+	assert.Equal(s.T(), 2, structure.Tracks[1].EditList.EntryCount)
+	assert.InDelta(s.T(), 0.2, structure.Tracks[1].EditList.EmptyEdit, 1e-9)
+	assert.InDelta(s.T(), 1024.0/48000.0, structure.Tracks[1].EditList.MediaStart, 1e-9)
+	assert.InDelta(s.T(), 0.2-1024.0/48000.0, structure.Tracks[1].EditList.StartShift, 1e-9)
+
+	assert.Contains(s.T(), structure.Issues, "track 1 (vide) has 2 sample descriptions")
+	assert.Contains(s.T(), structure.Issues, "track 2 (soun) edit list shifts start by +0.200 s relative to video")
+}
+
+// TestEncoderDelayEditLists verifies that the edit lists of a B-frame video and AAC audio are not reported.
+func (s *MP4InspectorTestSuite) TestEncoderDelayEditLists() {
+	ftyp := mp4TestBox("ftyp", []byte("isom"), mp4TestU32(0), []byte("isomiso2avc1mp41"))
+	// Video skips 2 frames of composition offset at 23.976 fps, audio skips 1024 samples of AAC priming
+	videoEdits := [][2]uint32{{10000, 2002}}
+	audioEdits := [][2]uint32{{10000, 1024}}
+	moov := mp4TestBox("moov",
+		mp4TestBox("mvhd", mp4TestU32(0, 0, 0, 1000, 0)),
+		mp4TestTrack(1, "vide", 24000, videoEdits, 1),
+		mp4TestTrack(2, "soun", 48000, audioEdits, 1),
+	)
+	path := s.writeFile("encoder-delay.mp4", ftyp, moov, mp4TestBox("mdat"))
+
+	structure, err := s.inspector.Inspect(path)
+	require.NoError(s.T(), err)
+
+	require.Len(s.T(), structure.Tracks, 2)
+	assert.InDelta(s.T(), 2002.0/24000.0, structure.Tracks[0].EditList.MediaStart, 1e-9)
+	assert.Empty(s.T(), structure.Issues)
+}
+
+// TestFragmentedFile verifies detection of moof and sidx boxes.
+func (s *MP4InspectorTestSuite) TestFragmentedFile() {
+	ftyp := mp4TestBox("ftyp", []byte("iso5"), mp4TestU32(0), []byte("iso5dash"))
+	moov := mp4TestBox("moov", mp4TestBox("mvhd", mp4TestU32(0, 0, 0, 1000, 0)), mp4TestBox("mvex", mp4TestBox("trex", mp4TestU32(0, 1, 1, 0, 0, 0))))
+	fragment := mp4TestBox("moof", mp4TestBox("mfhd", mp4TestU32(0, 1)), mp4TestBox("traf", mp4TestBox("tfhd", mp4TestU32(0, 1))))
+	path := s.writeFile("fragmented.mp4", ftyp, moov, mp4TestBox("sidx", mp4TestU32(0)), fragment, mp4TestBox("mdat"), fragment, mp4TestBox("mdat"))
+
+	structure, err := s.inspector.Inspect(path)
+	require.NoError(s.T(), err)
+
+	assert.True(s.T(), structure.Fragmented)
+	assert.True(s.T(), structure.HasSegmentIndex)
+	assert.Equal(s.T(), 2, structure.FragmentCount)
+	assert.True(s.T(), structure.FastStart)
+	assert.Contains(s.T(), structure.Issues, "fragmented layout (2 moof boxes, sidx present)")
+}
+
+// TestQuickTimeBrandAndTruncation verifies brand checks and reporting of truncated files.
+func (s *MP4InspectorTestSuite) TestQuickTimeBrandAndTruncation() {
+	ftyp := mp4TestBox("ftyp", []byte("qt  "), mp4TestU32(0), []byte("qt  "))
+	moov := mp4TestBox("moov", mp4TestBox("mvhd", mp4TestU32(0, 0, 0, 600, 0)))
+	// The mdat header claims more data than the file contains
+	mdat := mp4TestU32(4096)
+	mdat = append(mdat, []byte("mdat")...)
+	path := s.writeFile("truncated.mov", ftyp, moov, mdat)
+
+	structure, err := s.inspector.Inspect(path)
+	require.NoError(s.T(), err)
+
+	assert.Contains(s.T(), structure.Issues, "major brand is 'qt  ' (QuickTime), not an ISO brand")
+	assert.Contains(s.T(), structure.Issues, "no web compatible brand (isom, mp41, mp42, avc1, ...) declared")
+	assert.Contains(s.T(), structure.Issues[len(structure.Issues)-1], "box walk stopped early")
+}
+
+// TestInvalidFiles verifies errors for missing and non ISO-BMFF files.
+func (s *MP4InspectorTestSuite) TestInvalidFiles() {
+	_, err := s.inspector.Inspect(filepath.Join(s.tempDir, "missing.mp4"))
+	assert.Error(s.T(), err)
+
+	path := s.writeFile("garbage.mp4", []byte("not an mp4"))
+	_, err = s.inspector.Inspect(path)
+	assert.Error(s.T(), err)
+}
+
+// TestMP4InspectorSuite runs the MP4Inspector test suite.
+func TestMP4InspectorSuite(t *testing.T) {
+	suite.Run(t, new(MP4InspectorTestSuite))
+}
@@ -0,0 +1,146 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"math"
+	"sort"
+)
+
+// Private functions (alphabetical)
+
+// countMisaligned returns the number of markers that do not land on a keyframe.
+func countMisaligned(alignments []KeyframeAlignment) int {
+	count := 0
+	for _, alignment := range alignments {
+		if !alignment.Aligned {
+			count++
+		}
+	}
+	return count
+}
+
+// Public functions (alphabetical)
+
+// NewKeyframeReport creates an empty KeyframeReport for a stream with the given frame rate.
+func NewKeyframeReport(frameRate float64) *KeyframeReport {
+	return &KeyframeReport{FrameRate: frameRate}
+}
+
+// Private methods (alphabetical)
+
+// align measures the distance from a marker to the nearest keyframe. The frame of a chapter
+// start is not known and is derived from the keyframe with the frame rate, so frame is -1.
+func (r *KeyframeReport) align(index int, title string, markerTime float64, frame int) KeyframeAlignment {
+	alignment := KeyframeAlignment{
+		Index:         index,
+		Title:         title,
+		Time:          markerTime,
+		Frame:         frame,
+		KeyframeFrame: -1,
+	}
+
+	keyframe, found := r.nearestKeyframe(markerTime)
+	if !found {
+		return alignment
+	}
+
+	offset := keyframe.time - markerTime
+	alignment.KeyframeFrame = keyframe.frame
+	alignment.KeyframeTime = keyframe.time
+	alignment.IDR = keyframe.idr
+	alignment.OffsetMs = offset * 1000
+	switch {
+	case frame >= 0:
+		alignment.OffsetFrames = keyframe.frame - frame
+		alignment.Aligned = alignment.OffsetFrames == 0
+	case r.FrameRate > 0:
+		alignment.OffsetFrames = int(math.Round(offset * r.FrameRate))
+		alignment.Frame = keyframe.frame - alignment.OffsetFrames
+		alignment.Aligned = alignment.OffsetFrames == 0
+	default:
+		// Without a frame rate only a marker within a millisecond of the keyframe is aligned
+		alignment.Frame = keyframe.frame
+		alignment.Aligned = math.Abs(offset) < 0.001
+	}
+	return alignment
+}
+
+// nearestKeyframe returns the keyframe closest in time, preferring the earlier one on a tie.
+func (r *KeyframeReport) nearestKeyframe(markerTime float64) (keyframePosition, bool) {
+	if len(r.keyframes) == 0 {
+		return keyframePosition{}, false
+	}
+
+	next := sort.Search(len(r.keyframes), func(i int) bool { return r.keyframes[i].time >= markerTime })
+	if next == len(r.keyframes) {
+		return r.keyframes[next-1], true
+	}
+	if next > 0 && markerTime-r.keyframes[next-1].time <= r.keyframes[next].time-markerTime {
+		return r.keyframes[next-1], true
+	}
+	return r.keyframes[next], true
+}
+
+// Public methods (alphabetical)
+
+// AddFrame records the frame if decoding can start from it.
+// Frames are expected in presentation order, as the BitrateAnalyzer sends them.
+func (r *KeyframeReport) AddFrame(frame FrameBitrateInfo) {
+	if frame.FrameType != "I" && !frame.KeyFrame {
+		return
+	}
+	r.keyframes = append(r.keyframes, keyframePosition{frame: frame.FrameNumber, time: frame.Time, idr: frame.KeyFrame})
+}
+
+// Check aligns every chapter start and every scene cut with the nearest keyframe once all
+// frames have been added. Scenes may be nil.
+func (r *KeyframeReport) Check(chapters []ChapterStream, scenes *SceneReport) {
+	r.Chapters = nil
+	for _, chapter := range chapters {
+		r.Chapters = append(r.Chapters, r.align(int(chapter.ID), chapter.Title, chapter.StartTime, -1))
+	}
+
+	r.SceneCuts = nil
+	if scenes == nil {
+		return
+	}
+	// The first scene starts with the stream and is not a cut
+	for _, scene := range scenes.Scenes[min(1, len(scenes.Scenes)):] {
+		r.SceneCuts = append(r.SceneCuts, r.align(scene.Index, "", scene.Start, scene.StartFrame))
+	}
+}
+
+// Keyframes returns the number of I/IDR frames added to the report.
+func (r *KeyframeReport) Keyframes() int {
+	return len(r.keyframes)
+}
+
+// MaxSceneCutOffset returns the scene cut farthest from its keyframe, or false when every
+// scene cut is aligned.
+func (r *KeyframeReport) MaxSceneCutOffset() (KeyframeAlignment, bool) {
+	var worst KeyframeAlignment
+	found := false
+	for _, cut := range r.SceneCuts {
+		if cut.Aligned || cut.KeyframeFrame < 0 {
+			continue
+		}
+		if !found || math.Abs(cut.OffsetMs) > math.Abs(worst.OffsetMs) {
+			worst = cut
+			found = true
+		}
+	}
+	return worst, found
+}
+
+// MisalignedChapters returns the number of chapter starts that do not land on a keyframe.
+func (r *KeyframeReport) MisalignedChapters() int {
+	return countMisaligned(r.Chapters)
+}
+
+// MisalignedSceneCuts returns the number of scene cuts that do not land on a keyframe.
+func (r *KeyframeReport) MisalignedSceneCuts() int {
+	return countMisaligned(r.SceneCuts)
+}
@@ -0,0 +1,107 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the keyframe alignment check.
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// KeyframeReportTestSuite defines the test suite for KeyframeReport.
+// It uses synthetic frames so no sample media is required.
+type KeyframeReportTestSuite struct {
+	suite.Suite
+}
+
+// keyframeTestReport returns a report of 200 frames at 25 fps with an IDR frame every
+// 50 frames and an additional non-IDR I-frame on frame 130.
+func keyframeTestReport() *KeyframeReport {
+	report := NewKeyframeReport(25)
+	for n := 0; n < 200; n++ {
+		frame := FrameBitrateInfo{FrameNumber: n, FrameType: "P", Time: float64(n) / 25}
+		switch {
+		case n%50 == 0:
+			frame.FrameType, frame.KeyFrame = "I", true
+		case n == 130:
+			frame.FrameType = "I"
+		}
+		report.AddFrame(frame)
+	}
+	return report
+}
+
+// TestChapters verifies the alignment of chapter starts derived from their times.
+func (s *KeyframeReportTestSuite) TestChapters() {
+	report := keyframeTestReport()
+	assert.Equal(s.T(), 5, report.Keyframes())
+
+	report.Check([]ChapterStream{
+		{ID: 0, StartTime: 0, Title: "Opening"},
+		{ID: 1, StartTime: 2.2, Title: "Middle"},
+		{ID: 2, StartTime: 5.1},
+	}, nil)
+	require.Len(s.T(), report.Chapters, 3)
+	assert.Empty(s.T(), report.SceneCuts)
+
+	assert.True(s.T(), report.Chapters[0].Aligned)
+	assert.True(s.T(), report.Chapters[0].IDR)
+
+	// 2.2 s is frame 55, five frames after the IDR frame at 2 s
+	middle := report.Chapters[1]
+	assert.False(s.T(), middle.Aligned)
+	assert.Equal(s.T(), "Middle", middle.Title)
+	assert.Equal(s.T(), 50, middle.KeyframeFrame)
+	assert.Equal(s.T(), 55, middle.Frame)
+	assert.Equal(s.T(), -5, middle.OffsetFrames)
+	assert.InDelta(s.T(), -200.0, middle.OffsetMs, 1e-6)
+
+	// 5.1 s is closer to the I-frame at 5.2 s than to the IDR frame at 6 s
+	last := report.Chapters[2]
+	assert.Equal(s.T(), 130, last.KeyframeFrame)
+	assert.False(s.T(), last.IDR)
+	assert.Equal(s.T(), 3, last.OffsetFrames)
+	assert.Equal(s.T(), 2, report.MisalignedChapters())
+}
+
+// TestSceneCuts verifies the alignment of scene cuts and the worst offset.
+func (s *KeyframeReportTestSuite) TestSceneCuts() {
+	report := keyframeTestReport()
+	scenes := newSceneReport(VideoStream{FrameRate: 25, Duration: 8},
+		sceneDefaultThreshold, []sceneCut{{frame: 50, time: 2}, {frame: 98, time: 3.92}, {frame: 140, time: 5.6}})
+
+	report.Check(nil, scenes)
+	require.Len(s.T(), report.SceneCuts, 3)
+	assert.Empty(s.T(), report.Chapters)
+
+	assert.True(s.T(), report.SceneCuts[0].Aligned)
+	assert.Equal(s.T(), 2, report.SceneCuts[1].OffsetFrames)
+	assert.Equal(s.T(), -10, report.SceneCuts[2].OffsetFrames)
+	assert.Equal(s.T(), 2, report.MisalignedSceneCuts())
+
+	worst, found := report.MaxSceneCutOffset()
+	require.True(s.T(), found)
+	assert.Equal(s.T(), 3, worst.Index)
+	assert.InDelta(s.T(), -400.0, worst.OffsetMs, 1e-6)
+}
+
+// TestNoKeyframes verifies that markers are reported as misaligned without keyframes.
+func (s *KeyframeReportTestSuite) TestNoKeyframes() {
+	report := NewKeyframeReport(0)
+	report.AddFrame(FrameBitrateInfo{FrameNumber: 0, FrameType: "P"})
+	report.Check([]ChapterStream{{ID: 1, StartTime: 10}}, nil)
+
+	require.Len(s.T(), report.Chapters, 1)
+	assert.Equal(s.T(), -1, report.Chapters[0].KeyframeFrame)
+	assert.False(s.T(), report.Chapters[0].Aligned)
+
+	_, found := report.MaxSceneCutOffset()
+	assert.False(s.T(), found)
+}
+
+// TestKeyframeReportSuite runs the KeyframeReport test suite.
+func TestKeyframeReportSuite(t *testing.T) {
+	suite.Run(t, new(KeyframeReportTestSuite))
+}
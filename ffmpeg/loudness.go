@@ -0,0 +1,282 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Private variables (alphabetical)
+
+// loudnessFrameRegex matches the per-frame measurement lines of the ebur128 filter.
+var loudnessFrameRegex = regexp.MustCompile(`t:\s*(-?[\d.]+)\s+TARGET:.*?M:\s*(-?[\d.]+|-?inf|nan)\s+S:\s*(-?[\d.]+|-?inf|nan)`)
+
+// loudnessSummaryRegex matches the value lines of the ebur128 filter summary.
+var loudnessSummaryRegex = regexp.MustCompile(`^\s*(I|Threshold|LRA|LRA low|LRA high|Peak):\s*(-?[\d.]+|-?inf|nan)`)
+
+// loudnessTargets lists the built-in delivery targets by name.
+var loudnessTargets = map[string]LoudnessTarget{
+	"atsc-a85":     {Name: "ATSC A/85", IntegratedLoudness: -24, Tolerance: 2, MaxTruePeak: -2},
+	"ebu-r128":     {Name: "EBU R128", IntegratedLoudness: -23, Tolerance: 0.5, MaxTruePeak: -1},
+	"streaming-14": {Name: "Streaming -14 LUFS", IntegratedLoudness: -14, Tolerance: 1, MaxTruePeak: -1},
+	"streaming-16": {Name: "Streaming -16 LUFS", IntegratedLoudness: -16, Tolerance: 1, MaxTruePeak: -1},
+}
+
+// Private functions (alphabetical)
+
+// decodeLoudnessJSON converts a JSON loudness value back, mapping null to negative infinity.
+func decodeLoudnessJSON(value *float64) float64 {
+	if value == nil {
+		return math.Inf(-1)
+	}
+	return *value
+}
+
+// encodeLoudnessJSON returns a loudness value for JSON, nil when it is not finite as JSON has no infinity.
+func encodeLoudnessJSON(value float64) *float64 {
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return nil
+	}
+	return &value
+}
+
+// parseLoudnessValue converts a value printed by the ebur128 filter, mapping -inf and nan to negative infinity.
+func parseLoudnessValue(value string) float64 {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(parsed) {
+		return math.Inf(-1)
+	}
+	return parsed
+}
+
+// parseEBUR128Output reads the log of the ebur128 filter into the report.
+// It collects the momentary and short-term series and the final summary values.
+func parseEBUR128Output(r io.Reader, report *LoudnessReport) error {
+	section := ""
+	inSummary := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if !inSummary {
+			if match := loudnessFrameRegex.FindStringSubmatch(line); match != nil {
+				report.Samples = append(report.Samples, LoudnessSample{
+					Time:      parseLoudnessValue(match[1]),
+					Momentary: parseLoudnessValue(match[2]),
+					ShortTerm: parseLoudnessValue(match[3]),
+				})
+				continue
+			}
+			inSummary = strings.Contains(line, "Summary:")
+			continue
+		}
+
+		// The summary lists integrated loudness, loudness range and true peak sections
+		trimmed := strings.TrimSpace(line)
+		switch trimmed {
+		case "Integrated loudness:", "Loudness range:", "True peak:", "Sample peak:":
+			section = trimmed
+			continue
+		}
+
+		match := loudnessSummaryRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		value := parseLoudnessValue(match[2])
+
+		switch {
+		case section == "Integrated loudness:" && match[1] == "I":
+			report.IntegratedLoudness = value
+		case section == "Integrated loudness:" && match[1] == "Threshold":
+			report.IntegratedThreshold = value
+		case section == "Loudness range:" && match[1] == "LRA":
+			report.LoudnessRange = value
+		case section == "Loudness range:" && match[1] == "LRA low":
+			report.LoudnessRangeLow = value
+		case section == "Loudness range:" && match[1] == "LRA high":
+			report.LoudnessRangeHigh = value
+		case section == "True peak:" && match[1] == "Peak":
+			report.TruePeak = value
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading ebur128 output: %w", err)
+	}
+	if !inSummary {
+		return fmt.Errorf("ebur128 summary not found in FFmpeg output")
+	}
+
+	return nil
+}
+
+// Public functions (alphabetical)
+
+// LoudnessTargetNames returns the names of the built-in loudness targets in alphabetical order.
+func LoudnessTargetNames() []string {
+	names := make([]string, 0, len(loudnessTargets))
+	for name := range loudnessTargets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupLoudnessTarget returns the built-in loudness target with the given name.
+// Known names are ebu-r128, atsc-a85, streaming-14 and streaming-16.
+func LookupLoudnessTarget(name string) (LoudnessTarget, error) {
+	target, ok := loudnessTargets[strings.ToLower(name)]
+	if !ok {
+		return LoudnessTarget{}, fmt.Errorf("unknown loudness target %q (available: %s)",
+			name, strings.Join(LoudnessTargetNames(), ", "))
+	}
+	return target, nil
+}
+
+// NewLoudnessAnalyzer creates a new LoudnessAnalyzer that checks audio against the given target.
+func NewLoudnessAnalyzer(ffmpegInfo *FFmpegInfo, target LoudnessTarget) (*LoudnessAnalyzer, error) {
+	if ffmpegInfo == nil || !ffmpegInfo.Installed {
+		return nil, fmt.Errorf("ffmpeg not available")
+	}
+
+	return &LoudnessAnalyzer{
+		FFmpegPath: ffmpegInfo.Path,
+		Target:     target,
+	}, nil
+}
+
+// Private methods (alphabetical)
+
+// evaluate compares the measured loudness with the target.
+func (a *LoudnessAnalyzer) evaluate(report *LoudnessReport) {
+	report.Target = a.Target
+	report.IntegratedPass = math.Abs(report.IntegratedLoudness-a.Target.IntegratedLoudness) <= a.Target.Tolerance
+	report.TruePeakPass = report.TruePeak <= a.Target.MaxTruePeak
+	report.Passed = report.IntegratedPass && report.TruePeakPass
+}
+
+// Public methods (alphabetical)
+
+// Analyze measures the loudness of an audio stream with the FFmpeg ebur128 filter.
+// It reports integrated loudness, loudness range, true peak and the momentary and
+// short-term series (10 values per second), and checks them against the analyzer target.
+func (a *LoudnessAnalyzer) Analyze(ctx context.Context, filePath string, stream AudioStream) (*LoudnessReport, error) {
+	cmd := exec.CommandContext(
+		ctx,
+		a.FFmpegPath,
+		"-hide_banner",
+		"-nostats",
+		"-i", filePath,
+		"-map", fmt.Sprintf("0:%d", stream.Index),
+		"-filter:a", "ebur128=peak=true:framelog=info",
+		"-f", "null",
+		"-",
+	)
+
+	// The filter logs its measurements on stderr
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start FFmpeg: %w", err)
+	}
+
+	report := &LoudnessReport{StreamIndex: stream.Index}
+	parseErr := parseEBUR128Output(stderr, report)
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("error running ebur128 filter: %w", err)
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	a.evaluate(report)
+	return report, nil
+}
+
+// MarshalJSON encodes the report with the non-finite values of silent audio as null.
+func (r LoudnessReport) MarshalJSON() ([]byte, error) {
+	type report LoudnessReport
+	return json.Marshal(struct {
+		report
+		IntegratedLoudness  *float64
+		IntegratedThreshold *float64
+		LoudnessRange       *float64
+		LoudnessRangeLow    *float64
+		LoudnessRangeHigh   *float64
+		TruePeak            *float64
+	}{
+		report:              report(r),
+		IntegratedLoudness:  encodeLoudnessJSON(r.IntegratedLoudness),
+		IntegratedThreshold: encodeLoudnessJSON(r.IntegratedThreshold),
+		LoudnessRange:       encodeLoudnessJSON(r.LoudnessRange),
+		LoudnessRangeLow:    encodeLoudnessJSON(r.LoudnessRangeLow),
+		LoudnessRangeHigh:   encodeLoudnessJSON(r.LoudnessRangeHigh),
+		TruePeak:            encodeLoudnessJSON(r.TruePeak),
+	})
+}
+
+// MarshalJSON encodes the sample with the non-finite values of silent audio as null.
+func (s LoudnessSample) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Time      float64
+		Momentary *float64
+		ShortTerm *float64
+	}{s.Time, encodeLoudnessJSON(s.Momentary), encodeLoudnessJSON(s.ShortTerm)})
+}
+
+// UnmarshalJSON decodes a report encoded by MarshalJSON, restoring null values as negative infinity.
+func (r *LoudnessReport) UnmarshalJSON(data []byte) error {
+	type report LoudnessReport
+	var decoded struct {
+		report
+		IntegratedLoudness  *float64
+		IntegratedThreshold *float64
+		LoudnessRange       *float64
+		LoudnessRangeLow    *float64
+		LoudnessRangeHigh   *float64
+		TruePeak            *float64
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	*r = LoudnessReport(decoded.report)
+	r.IntegratedLoudness = decodeLoudnessJSON(decoded.IntegratedLoudness)
+	r.IntegratedThreshold = decodeLoudnessJSON(decoded.IntegratedThreshold)
+	r.LoudnessRange = decodeLoudnessJSON(decoded.LoudnessRange)
+	r.LoudnessRangeLow = decodeLoudnessJSON(decoded.LoudnessRangeLow)
+	r.LoudnessRangeHigh = decodeLoudnessJSON(decoded.LoudnessRangeHigh)
+	r.TruePeak = decodeLoudnessJSON(decoded.TruePeak)
+	return nil
+}
+
+// UnmarshalJSON decodes a sample encoded by MarshalJSON, restoring null values as negative infinity.
+func (s *LoudnessSample) UnmarshalJSON(data []byte) error {
+	var decoded struct {
+		Time      float64
+		Momentary *float64
+		ShortTerm *float64
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	*s = LoudnessSample{Time: decoded.Time, Momentary: decodeLoudnessJSON(decoded.Momentary), ShortTerm: decodeLoudnessJSON(decoded.ShortTerm)}
+	return nil
+}
@@ -0,0 +1,114 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the loudness analyzer.
+package ffmpeg
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// loudnessTestOutput is a shortened log of the ebur128 filter.
+const loudnessTestOutput = `Input #0, matroska,webm, from 'sample.mkv':
+[Parsed_ebur128_0 @ 0x5583] t: 0.1       TARGET:-23 LUFS    M: -120.7 S: -120.7     I: -70.0 LUFS       LRA:   0.0 LU  FTPK: -inf dBFS  TPK: -inf dBFS
+[Parsed_ebur128_0 @ 0x5583] t: 0.2       TARGET:-23 LUFS    M: -24.3 S: -30.1     I: -24.3 LUFS       LRA:   0.0 LU  FTPK: -3.2 dBFS  TPK: -3.2 dBFS
+[Parsed_ebur128_0 @ 0x5583] t: 0.3       TARGET:-23 LUFS    M: nan S: -inf     I: -24.3 LUFS       LRA:   0.0 LU  FTPK: -3.2 dBFS  TPK: -3.2 dBFS
+[Parsed_ebur128_0 @ 0x5583] Summary:
+
+  Integrated loudness:
+    I:         -22.8 LUFS
+    Threshold: -33.4 LUFS
+
+  Loudness range:
+    LRA:         5.3 LU
+    Threshold: -43.4 LUFS
+    LRA low:   -26.6 LUFS
+    LRA high:  -21.3 LUFS
+
+  True peak:
+    Peak:       -1.6 dBFS
+`
+
+// LoudnessAnalyzerTestSuite defines the test suite for LoudnessAnalyzer.
+// It parses synthetic ebur128 output so no sample media is required.
+type LoudnessAnalyzerTestSuite struct {
+	suite.Suite
+}
+
+// TestParseOutput verifies parsing of the series and the summary.
+func (s *LoudnessAnalyzerTestSuite) TestParseOutput() {
+	report := &LoudnessReport{}
+	require.NoError(s.T(), parseEBUR128Output(strings.NewReader(loudnessTestOutput), report))
+
+	require.Len(s.T(), report.Samples, 3)
+	assert.Equal(s.T(), LoudnessSample{Time: 0.2, Momentary: -24.3, ShortTerm: -30.1}, report.Samples[1])
+	assert.True(s.T(), math.IsInf(report.Samples[2].Momentary, -1))
+	assert.True(s.T(), math.IsInf(report.Samples[2].ShortTerm, -1))
+
+	assert.Equal(s.T(), -22.8, report.IntegratedLoudness)
+	assert.Equal(s.T(), -33.4, report.IntegratedThreshold)
+	assert.Equal(s.T(), 5.3, report.LoudnessRange)
+	assert.Equal(s.T(), -26.6, report.LoudnessRangeLow)
+	assert.Equal(s.T(), -21.3, report.LoudnessRangeHigh)
+	assert.Equal(s.T(), -1.6, report.TruePeak)
+}
+
+// TestParseOutputWithoutSummary verifies that a truncated log is rejected.
+func (s *LoudnessAnalyzerTestSuite) TestParseOutputWithoutSummary() {
+	err := parseEBUR128Output(strings.NewReader("Stream mapping:\n"), &LoudnessReport{})
+	assert.Error(s.T(), err)
+}
+
+// TestEvaluate verifies the pass/fail decision for each built-in target.
+func (s *LoudnessAnalyzerTestSuite) TestEvaluate() {
+	measured := LoudnessReport{IntegratedLoudness: -22.8, TruePeak: -1.6}
+
+	testCases := []struct {
+		target         string
+		integratedPass bool
+		truePeakPass   bool
+	}{
+		{"ebu-r128", true, true},
+		{"atsc-a85", true, false},
+		{"streaming-14", false, true},
+		{"streaming-16", false, true},
+	}
+
+	for _, tc := range testCases {
+		target, err := LookupLoudnessTarget(tc.target)
+		require.NoError(s.T(), err)
+
+		report := measured
+		analyzer := &LoudnessAnalyzer{Target: target}
+		analyzer.evaluate(&report)
+
+		assert.Equal(s.T(), target, report.Target, tc.target)
+		assert.Equal(s.T(), tc.integratedPass, report.IntegratedPass, tc.target)
+		assert.Equal(s.T(), tc.truePeakPass, report.TruePeakPass, tc.target)
+		assert.Equal(s.T(), tc.integratedPass && tc.truePeakPass, report.Passed, tc.target)
+	}
+}
+
+// TestTargets verifies target lookup and listing.
+func (s *LoudnessAnalyzerTestSuite) TestTargets() {
+	assert.Equal(s.T(), []string{"atsc-a85", "ebu-r128", "streaming-14", "streaming-16"}, LoudnessTargetNames())
+
+	target, err := LookupLoudnessTarget("EBU-R128")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), -23.0, target.IntegratedLoudness)
+
+	_, err = LookupLoudnessTarget("cinema")
+	assert.ErrorContains(s.T(), err, "available: atsc-a85, ebu-r128")
+
+	_, err = NewLoudnessAnalyzer(nil, target)
+	assert.Error(s.T(), err)
+}
+
+// TestLoudnessAnalyzerSuite runs the LoudnessAnalyzer test suite.
+func TestLoudnessAnalyzerSuite(t *testing.T) {
+	suite.Run(t, new(LoudnessAnalyzerTestSuite))
+}
@@ -0,0 +1,713 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Private variables (alphabetical)
+
+// dashTemplateRegex matches the identifiers of a DASH segment template, with an optional width.
+var dashTemplateRegex = regexp.MustCompile(`\$(RepresentationID|Number|Time|Bandwidth)(?:%0(\d+)d)?\$`)
+
+// hlsAttributeRegex matches an attribute of an HLS attribute list, quoted or not.
+var hlsAttributeRegex = regexp.MustCompile(`([A-Z0-9-]+)=("[^"]*"|[^,]*)`)
+
+// iso8601DurationRegex matches the xs:duration values used by DASH manifests.
+var iso8601DurationRegex = regexp.MustCompile(
+	`^P(?:([\d.]+)D)?(?:T(?:([\d.]+)H)?(?:([\d.]+)M)?(?:([\d.]+)S)?)?$`)
+
+// Private functions (alphabetical)
+
+// atoiOrZero converts a decimal string, returning 0 when it is not a number.
+func atoiOrZero(value string) int {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return number
+}
+
+// copySegment copies the bytes of a segment to w.
+func copySegment(w io.Writer, segment ManifestSegment) (int64, error) {
+	file, err := os.Open(segment.Path)
+	if err != nil {
+		return 0, fmt.Errorf("error opening segment: %w", err)
+	}
+	defer file.Close()
+
+	n, err := io.Copy(w, io.NewSectionReader(file, segment.Offset, segment.Size))
+	if err != nil {
+		return n, fmt.Errorf("error copying segment %s: %w", segment.URI, err)
+	}
+	return n, nil
+}
+
+// dashBaseURL combines the BaseURL of a manifest element with the one inherited from its parents.
+func dashBaseURL(parent, baseURL string) string {
+	baseURL = strings.TrimSpace(baseURL)
+	if baseURL == "" {
+		return parent
+	}
+	if strings.Contains(baseURL, "://") || strings.HasPrefix(baseURL, "/") {
+		return baseURL
+	}
+	return parent + baseURL
+}
+
+// expandDASHTemplate replaces the identifiers of a DASH segment template.
+func expandDASHTemplate(template, representationID string, bandwidth, number, time int64) string {
+	expanded := dashTemplateRegex.ReplaceAllStringFunc(template, func(identifier string) string {
+		match := dashTemplateRegex.FindStringSubmatch(identifier)
+		var value int64
+		switch match[1] {
+		case "RepresentationID":
+			return representationID
+		case "Number":
+			value = number
+		case "Time":
+			value = time
+		case "Bandwidth":
+			value = bandwidth
+		}
+		if match[2] != "" {
+			return fmt.Sprintf("%0*d", atoiOrZero(match[2]), value)
+		}
+		return strconv.FormatInt(value, 10)
+	})
+	return strings.ReplaceAll(expanded, "$$", "$")
+}
+
+// hasIDRNear reports whether one of the sorted IDR times is within the alignment tolerance of t.
+func hasIDRNear(idrTimes []float64, t float64) bool {
+	next := sort.SearchFloat64s(idrTimes, t-manifestAlignmentTolerance)
+	return next < len(idrTimes) && idrTimes[next] <= t+manifestAlignmentTolerance
+}
+
+// hlsAttributes parses an HLS attribute list, removing the quotes of quoted values.
+func hlsAttributes(list string) map[string]string {
+	attributes := make(map[string]string)
+	for _, match := range hlsAttributeRegex.FindAllStringSubmatch(list, -1) {
+		attributes[match[1]] = strings.Trim(match[2], `"`)
+	}
+	return attributes
+}
+
+// hlsRenditionType guesses the rendition type from the CODECS attribute of a variant.
+// Variants without a CODECS attribute are assumed to carry video.
+func hlsRenditionType(codecs string) string {
+	if codecs == "" {
+		return RenditionVideo
+	}
+	for _, codec := range strings.Split(codecs, ",") {
+		switch strings.SplitN(strings.TrimSpace(codec), ".", 2)[0] {
+		case "mp4a", "ac-3", "ec-3", "opus", "fLaC", "mp3":
+			continue
+		}
+		return RenditionVideo
+	}
+	return RenditionAudio
+}
+
+// parseISO8601Duration converts an xs:duration such as PT1H2M3.5S to seconds.
+// It returns 0 for values it cannot parse.
+func parseISO8601Duration(value string) float64 {
+	match := iso8601DurationRegex.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0
+	}
+	seconds := 0.0
+	for i, unit := range []float64{86400, 3600, 60, 1} {
+		if part, err := strconv.ParseFloat(match[i+1], 64); err == nil {
+			seconds += part * unit
+		}
+	}
+	return seconds
+}
+
+// readPlaylistLines returns the trimmed, non-empty lines of an HLS playlist.
+func readPlaylistLines(playlistPath string) ([]string, error) {
+	file, err := os.Open(playlistPath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening playlist: %w", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading playlist: %w", err)
+	}
+	if len(lines) == 0 || lines[0] != "#EXTM3U" {
+		return nil, fmt.Errorf("not an HLS playlist: missing #EXTM3U header")
+	}
+	return lines, nil
+}
+
+// resolveManifestURI resolves a URI of a manifest against the directory of the manifest.
+// Remote URIs cannot be analyzed locally and resolve to an empty path.
+func resolveManifestURI(baseDir, uri string) string {
+	if strings.Contains(uri, "://") {
+		return ""
+	}
+	uri, _, _ = strings.Cut(uri, "?")
+	if unescaped, err := url.PathUnescape(uri); err == nil {
+		uri = unescaped
+	}
+	if filepath.IsAbs(uri) {
+		return filepath.Clean(uri)
+	}
+	return filepath.Join(baseDir, filepath.FromSlash(uri))
+}
+
+// segmentDurationStats computes the statistics of the declared segment durations.
+func segmentDurationStats(segments []ManifestSegment) SegmentDurationStats {
+	stats := SegmentDurationStats{Count: len(segments)}
+	if len(segments) == 0 {
+		return stats
+	}
+
+	stats.Min = math.Inf(1)
+	for _, segment := range segments {
+		stats.Min = math.Min(stats.Min, segment.Duration)
+		stats.Max = math.Max(stats.Max, segment.Duration)
+		stats.Mean += segment.Duration
+	}
+	stats.Mean /= float64(len(segments))
+
+	variance := 0.0
+	for _, segment := range segments {
+		variance += (segment.Duration - stats.Mean) * (segment.Duration - stats.Mean)
+	}
+	stats.StdDev = math.Sqrt(variance / float64(len(segments)))
+	return stats
+}
+
+// Public functions (alphabetical)
+
+// IsManifest reports whether a path names an HLS playlist or a DASH manifest.
+func IsManifest(filePath string) bool {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".m3u8", ".m3u", ".mpd":
+		return true
+	}
+	return false
+}
+
+// NewManifestInspector creates a new ManifestInspector.
+// The inspector is pure Go and does not depend on an FFmpeg installation.
+func NewManifestInspector() *ManifestInspector {
+	return &ManifestInspector{}
+}
+
+// Private methods (alphabetical)
+
+// dashSegments appends the segments of a DASH representation within a period of the given
+// duration. Template, list and base URL are the elements in effect for the representation.
+func (m *ManifestInspector) dashSegments(rendition *Rendition, baseDir, baseURL string, representation mpdRepresentation,
+	template *mpdSegmentTemplate, list *mpdSegmentList, periodDuration float64) error {
+	newSegment := func(uri string, duration float64) ManifestSegment {
+		uri = dashBaseURL(baseURL, uri)
+		return ManifestSegment{URI: uri, Path: resolveManifestURI(baseDir, uri), Duration: duration, Length: -1}
+	}
+
+	switch {
+	case template != nil:
+		timescale := max(template.Timescale, 1)
+		number := int64(1)
+		if template.StartNumber != nil {
+			number = *template.StartNumber
+		}
+		if template.Initialization != "" && rendition.InitSegment == nil {
+			init := newSegment(expandDASHTemplate(template.Initialization, representation.ID, representation.Bandwidth, 0, 0), 0)
+			rendition.InitSegment = &init
+		}
+
+		if len(template.Timeline) > 0 {
+			var t int64
+			periodEnd := int64(periodDuration * float64(timescale))
+			for _, entry := range template.Timeline {
+				if entry.T != nil {
+					t = *entry.T
+				}
+				if entry.D <= 0 {
+					return fmt.Errorf("segment timeline entry without duration")
+				}
+				repeat := entry.R
+				if repeat < 0 {
+					// A negative repeat count lasts until the end of the period
+					repeat = (periodEnd-t+entry.D-1)/entry.D - 1
+				}
+				for i := int64(0); i <= repeat; i++ {
+					uri := expandDASHTemplate(template.Media, representation.ID, representation.Bandwidth, number, t)
+					rendition.Segments = append(rendition.Segments, newSegment(uri, float64(entry.D)/float64(timescale)))
+					number++
+					t += entry.D
+				}
+			}
+			return nil
+		}
+
+		if template.Duration <= 0 || periodDuration <= 0 {
+			return fmt.Errorf("segment template without timeline, duration or period duration")
+		}
+		nominal := float64(template.Duration) / float64(timescale)
+		count := int64(math.Ceil(periodDuration/nominal - 1e-9))
+		for i := int64(0); i < count; i++ {
+			duration := math.Min(nominal, periodDuration-float64(i)*nominal)
+			uri := expandDASHTemplate(template.Media, representation.ID, representation.Bandwidth, number+i, i*template.Duration)
+			rendition.Segments = append(rendition.Segments, newSegment(uri, duration))
+		}
+	case list != nil:
+		timescale := max(list.Timescale, 1)
+		if list.Initialization != nil && rendition.InitSegment == nil {
+			init := newSegment(list.Initialization.SourceURL, 0)
+			rendition.InitSegment = &init
+		}
+		for _, segmentURL := range list.SegmentURLs {
+			rendition.Segments = append(rendition.Segments, newSegment(segmentURL.Media, float64(list.Duration)/float64(timescale)))
+		}
+	default:
+		// On-demand profile: the whole representation is a single file
+		if representation.BaseURL == "" {
+			return fmt.Errorf("no segment template, segment list or base URL")
+		}
+		rendition.Segments = append(rendition.Segments, ManifestSegment{
+			URI:      baseURL,
+			Path:     resolveManifestURI(baseDir, baseURL),
+			Duration: periodDuration,
+			Length:   -1,
+		})
+	}
+	return nil
+}
+
+// evaluate checks the measured bandwidths, segment durations and missing segments of every rendition.
+func (m *ManifestInspector) evaluate(manifest *Manifest) {
+	for _, rendition := range manifest.Renditions {
+		if len(rendition.Segments) == 0 {
+			manifest.Issues = append(manifest.Issues, fmt.Sprintf("rendition %s: no segments", rendition.ID))
+			continue
+		}
+		if rendition.InitSegment != nil && rendition.InitSegment.Size < 0 {
+			manifest.Issues = append(manifest.Issues, fmt.Sprintf("rendition %s: initialization segment %s missing",
+				rendition.ID, rendition.InitSegment.URI))
+		}
+		if rendition.MissingSegments > 0 {
+			manifest.Issues = append(manifest.Issues, fmt.Sprintf("rendition %s: %d of %d segments missing",
+				rendition.ID, rendition.MissingSegments, len(rendition.Segments)))
+		}
+
+		if rendition.Bandwidth > 0 && rendition.MeasuredPeakBandwidth > float64(rendition.Bandwidth)*(1+manifestBandwidthTolerance) {
+			manifest.Issues = append(manifest.Issues, fmt.Sprintf(
+				"rendition %s: measured peak bandwidth %.0f kbps exceeds the declared %d kbps",
+				rendition.ID, rendition.MeasuredPeakBandwidth/1000, rendition.Bandwidth/1000))
+		}
+		if rendition.AverageBandwidth > 0 &&
+			math.Abs(rendition.MeasuredAverageBandwidth-float64(rendition.AverageBandwidth)) > float64(rendition.AverageBandwidth)*manifestBandwidthTolerance {
+			manifest.Issues = append(manifest.Issues, fmt.Sprintf(
+				"rendition %s: measured average bandwidth %.0f kbps differs from the declared %d kbps by more than %.0f%%",
+				rendition.ID, rendition.MeasuredAverageBandwidth/1000, rendition.AverageBandwidth/1000, manifestBandwidthTolerance*100))
+		}
+
+		// HLS requires every segment duration, rounded to an integer, to stay within the target duration
+		if manifest.Format == ManifestHLS && manifest.TargetDuration > 0 {
+			longer := 0
+			for _, segment := range rendition.Segments {
+				if math.Round(segment.Duration) > manifest.TargetDuration {
+					longer++
+				}
+			}
+			if longer > 0 {
+				manifest.Issues = append(manifest.Issues, fmt.Sprintf("rendition %s: %d segments longer than the target duration of %g s",
+					rendition.ID, longer, manifest.TargetDuration))
+			}
+		}
+	}
+}
+
+// inspectDASH reads the representations of a DASH manifest and lists their segments.
+func (m *ManifestInspector) inspectDASH(manifestPath string) (*Manifest, error) {
+	file, err := os.Open(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening manifest: %w", err)
+	}
+	defer file.Close()
+
+	var document mpdDocument
+	if err := xml.NewDecoder(file).Decode(&document); err != nil {
+		return nil, fmt.Errorf("error parsing DASH manifest: %w", err)
+	}
+
+	manifest := &Manifest{Path: manifestPath, Format: ManifestDASH}
+	baseDir := filepath.Dir(manifestPath)
+	totalDuration := parseISO8601Duration(document.MediaPresentationDuration)
+	renditionIndex := make(map[string]int)
+
+	for _, period := range document.Periods {
+		periodDuration := parseISO8601Duration(period.Duration)
+		if periodDuration == 0 && len(document.Periods) == 1 {
+			periodDuration = totalDuration
+		}
+		periodBaseURL := dashBaseURL(dashBaseURL("", document.BaseURL), period.BaseURL)
+
+		for _, set := range period.AdaptationSets {
+			for _, representation := range set.Representations {
+				contentType := set.ContentType
+				if contentType == "" {
+					mimeType := representation.MimeType
+					if mimeType == "" {
+						mimeType = set.MimeType
+					}
+					contentType, _, _ = strings.Cut(mimeType, "/")
+				}
+				if contentType != RenditionVideo && contentType != RenditionAudio {
+					continue
+				}
+
+				index, found := renditionIndex[representation.ID]
+				if !found {
+					codecs := representation.Codecs
+					if codecs == "" {
+						codecs = set.Codecs
+					}
+					manifest.Renditions = append(manifest.Renditions, Rendition{
+						ID:        representation.ID,
+						Type:      contentType,
+						Bandwidth: representation.Bandwidth,
+						Width:     representation.Width,
+						Height:    representation.Height,
+						Codecs:    codecs,
+					})
+					index = len(manifest.Renditions) - 1
+					renditionIndex[representation.ID] = index
+				}
+
+				template := representation.SegmentTemplate
+				if template == nil {
+					template = set.SegmentTemplate
+				}
+				list := representation.SegmentList
+				if list == nil {
+					list = set.SegmentList
+				}
+				if template != nil && template.Duration > 0 && len(template.Timeline) == 0 {
+					manifest.TargetDuration = math.Max(manifest.TargetDuration, float64(template.Duration)/float64(max(template.Timescale, 1)))
+				}
+
+				baseURL := dashBaseURL(dashBaseURL(periodBaseURL, set.BaseURL), representation.BaseURL)
+				if err := m.dashSegments(&manifest.Renditions[index], baseDir, baseURL, representation, template, list, periodDuration); err != nil {
+					manifest.Issues = append(manifest.Issues, fmt.Sprintf("rendition %s: %v", representation.ID, err))
+				}
+			}
+		}
+	}
+	return manifest, nil
+}
+
+// inspectHLS reads the variants and audio renditions of an HLS master playlist, or the
+// single rendition of a media playlist, and lists their segments.
+func (m *ManifestInspector) inspectHLS(playlistPath string) (*Manifest, error) {
+	lines, err := readPlaylistLines(playlistPath)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{Path: playlistPath, Format: ManifestHLS}
+	baseDir := filepath.Dir(playlistPath)
+
+	isMaster := false
+	for _, line := range lines {
+		if strings.HasPrefix(line, "#EXT-X-STREAM-INF:") {
+			isMaster = true
+			break
+		}
+	}
+	if !isMaster {
+		manifest.Renditions = []Rendition{{ID: filepath.Base(playlistPath), Type: RenditionVideo}}
+		targetDuration, err := m.parseHLSMediaPlaylist(playlistPath, &manifest.Renditions[0])
+		if err != nil {
+			return nil, err
+		}
+		manifest.TargetDuration = targetDuration
+		return manifest, nil
+	}
+
+	seen := make(map[string]bool)
+	var pending map[string]string
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			pending = hlsAttributes(strings.TrimPrefix(line, "#EXT-X-STREAM-INF:"))
+		case strings.HasPrefix(line, "#EXT-X-MEDIA:"):
+			attributes := hlsAttributes(strings.TrimPrefix(line, "#EXT-X-MEDIA:"))
+			if attributes["TYPE"] == "AUDIO" && attributes["URI"] != "" && !seen[attributes["URI"]] {
+				seen[attributes["URI"]] = true
+				manifest.Renditions = append(manifest.Renditions, Rendition{ID: attributes["URI"], Type: RenditionAudio})
+			}
+		case strings.HasPrefix(line, "#"):
+			continue
+		case pending != nil:
+			// A variant repeated for several audio groups shares its playlist
+			if !seen[line] {
+				seen[line] = true
+				rendition := Rendition{ID: line, Type: hlsRenditionType(pending["CODECS"]), Codecs: pending["CODECS"]}
+				rendition.Bandwidth, _ = strconv.ParseInt(pending["BANDWIDTH"], 10, 64)
+				rendition.AverageBandwidth, _ = strconv.ParseInt(pending["AVERAGE-BANDWIDTH"], 10, 64)
+				if width, height, found := strings.Cut(pending["RESOLUTION"], "x"); found {
+					rendition.Width, rendition.Height = atoiOrZero(width), atoiOrZero(height)
+				}
+				manifest.Renditions = append(manifest.Renditions, rendition)
+			}
+			pending = nil
+		}
+	}
+
+	for i := range manifest.Renditions {
+		rendition := &manifest.Renditions[i]
+		mediaPath := resolveManifestURI(baseDir, rendition.ID)
+		if mediaPath == "" {
+			manifest.Issues = append(manifest.Issues, fmt.Sprintf("rendition %s: remote playlist cannot be analyzed", rendition.ID))
+			continue
+		}
+		targetDuration, err := m.parseHLSMediaPlaylist(mediaPath, rendition)
+		if err != nil {
+			manifest.Issues = append(manifest.Issues, fmt.Sprintf("rendition %s: %v", rendition.ID, err))
+			continue
+		}
+		manifest.TargetDuration = math.Max(manifest.TargetDuration, targetDuration)
+	}
+	return manifest, nil
+}
+
+// measure resolves the segment start times and sizes of a rendition and derives its bandwidth
+// and segment duration statistics.
+func (m *ManifestInspector) measure(rendition *Rendition) {
+	stat := func(segment *ManifestSegment) bool {
+		segment.Size = -1
+		if segment.Path == "" {
+			return false
+		}
+		info, err := os.Stat(segment.Path)
+		if err != nil || info.IsDir() {
+			return false
+		}
+		segment.Size = info.Size() - segment.Offset
+		if segment.Length >= 0 {
+			segment.Size = min(segment.Length, segment.Size)
+		}
+		return segment.Size >= 0
+	}
+
+	if rendition.InitSegment != nil {
+		stat(rendition.InitSegment)
+	}
+
+	var totalBits, totalDuration, start float64
+	for i := range rendition.Segments {
+		segment := &rendition.Segments[i]
+		segment.Start = start
+		start += segment.Duration
+
+		if !stat(segment) {
+			rendition.MissingSegments++
+			continue
+		}
+		if segment.Duration > 0 {
+			bits := float64(segment.Size * 8)
+			totalBits += bits
+			totalDuration += segment.Duration
+			rendition.MeasuredPeakBandwidth = math.Max(rendition.MeasuredPeakBandwidth, bits/segment.Duration)
+		}
+	}
+	if totalDuration > 0 {
+		rendition.MeasuredAverageBandwidth = totalBits / totalDuration
+	}
+	rendition.SegmentDurations = segmentDurationStats(rendition.Segments)
+}
+
+// parseHLSMediaPlaylist lists the segments of an HLS media playlist and returns its target duration.
+func (m *ManifestInspector) parseHLSMediaPlaylist(playlistPath string, rendition *Rendition) (float64, error) {
+	lines, err := readPlaylistLines(playlistPath)
+	if err != nil {
+		return 0, err
+	}
+
+	baseDir := filepath.Dir(playlistPath)
+	targetDuration := 0.0
+	duration := 0.0
+	var rangeLength int64 = -1
+	var rangeOffset int64 = -1
+	rangeEnds := make(map[string]int64)
+
+	// parseRange reads an HLS byte range "length[@offset]"
+	parseRange := func(value string) (int64, int64) {
+		rawLength, rawOffset, hasOffset := strings.Cut(value, "@")
+		length, _ := strconv.ParseInt(rawLength, 10, 64)
+		offset := int64(-1)
+		if hasOffset {
+			offset, _ = strconv.ParseInt(rawOffset, 10, 64)
+		}
+		return length, offset
+	}
+
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-TARGETDURATION:"):
+			targetDuration, _ = strconv.ParseFloat(strings.TrimPrefix(line, "#EXT-X-TARGETDURATION:"), 64)
+		case strings.HasPrefix(line, "#EXTINF:"):
+			rawDuration, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			duration, _ = strconv.ParseFloat(strings.TrimSpace(rawDuration), 64)
+		case strings.HasPrefix(line, "#EXT-X-BYTERANGE:"):
+			rangeLength, rangeOffset = parseRange(strings.TrimPrefix(line, "#EXT-X-BYTERANGE:"))
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			attributes := hlsAttributes(strings.TrimPrefix(line, "#EXT-X-MAP:"))
+			init := ManifestSegment{URI: attributes["URI"], Path: resolveManifestURI(baseDir, attributes["URI"]), Length: -1}
+			if value, found := attributes["BYTERANGE"]; found {
+				init.Length, init.Offset = parseRange(value)
+				init.Offset = max(init.Offset, 0)
+			}
+			rendition.InitSegment = &init
+		case strings.HasPrefix(line, "#"):
+			continue
+		default:
+			segment := ManifestSegment{URI: line, Path: resolveManifestURI(baseDir, line), Duration: duration, Length: -1}
+			if rangeLength >= 0 {
+				// Without an offset the range continues from the previous range of the same file
+				segment.Length = rangeLength
+				segment.Offset = rangeOffset
+				if segment.Offset < 0 {
+					segment.Offset = rangeEnds[line]
+				}
+				rangeEnds[line] = segment.Offset + segment.Length
+			}
+			rendition.Segments = append(rendition.Segments, segment)
+			duration, rangeLength, rangeOffset = 0, -1, -1
+		}
+	}
+	return targetDuration, nil
+}
+
+// Public methods (alphabetical)
+
+// AddFrame records the time of a frame measured by a BitrateAnalyzer if it is an IDR frame.
+// Frames of the concatenated segments are expected in presentation order.
+func (r *Rendition) AddFrame(frame FrameBitrateInfo) {
+	if r.frames == 0 {
+		r.firstTime = frame.Time
+	}
+	r.frames++
+	if frame.KeyFrame {
+		r.IDRTimes = append(r.IDRTimes, frame.Time-r.firstTime)
+	}
+}
+
+// CheckAlignment checks, once the frames of the renditions have been added, that every
+// segment of the video renditions starts with an IDR frame and that the IDR frames starting
+// the segments of the first video rendition are present in the others, so players can switch
+// renditions at segment boundaries. Renditions with missing segments are not checked, as
+// their frames no longer line up with the segment start times of the manifest.
+func (m *Manifest) CheckAlignment() {
+	var reference *Rendition
+	for i := range m.Renditions {
+		rendition := &m.Renditions[i]
+		if rendition.Type != RenditionVideo || len(rendition.IDRTimes) == 0 || rendition.MissingSegments > 0 {
+			continue
+		}
+		sort.Float64s(rendition.IDRTimes)
+
+		rendition.SegmentsWithoutIDR = 0
+		for j := range rendition.Segments {
+			segment := &rendition.Segments[j]
+			segment.StartsWithIDR = hasIDRNear(rendition.IDRTimes, segment.Start)
+			if !segment.StartsWithIDR && segment.Size >= 0 {
+				rendition.SegmentsWithoutIDR++
+			}
+		}
+		if rendition.SegmentsWithoutIDR > 0 {
+			m.Issues = append(m.Issues, fmt.Sprintf("rendition %s: %d segments do not start with an IDR frame",
+				rendition.ID, rendition.SegmentsWithoutIDR))
+		}
+
+		if reference == nil {
+			reference = rendition
+			continue
+		}
+		rendition.MisalignedIDRs = 0
+		for _, segment := range reference.Segments {
+			if segment.StartsWithIDR && !hasIDRNear(rendition.IDRTimes, segment.Start) {
+				rendition.MisalignedIDRs++
+			}
+		}
+		if rendition.MisalignedIDRs > 0 {
+			m.Issues = append(m.Issues, fmt.Sprintf("rendition %s: %d IDR frames at segment starts of rendition %s are missing",
+				rendition.ID, rendition.MisalignedIDRs, reference.ID))
+		}
+	}
+}
+
+// Inspect reads a local HLS playlist (.m3u8) or DASH manifest (.mpd), resolves the segments
+// of every rendition on disk and checks their measured bandwidth and durations against the
+// manifest. Missing segments are counted and reported in Issues.
+func (m *ManifestInspector) Inspect(manifestPath string) (*Manifest, error) {
+	var manifest *Manifest
+	var err error
+	switch strings.ToLower(filepath.Ext(manifestPath)) {
+	case ".m3u8", ".m3u":
+		manifest, err = m.inspectHLS(manifestPath)
+	case ".mpd":
+		manifest, err = m.inspectDASH(manifestPath)
+	default:
+		return nil, fmt.Errorf("unsupported manifest: %s", filepath.Base(manifestPath))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range manifest.Renditions {
+		m.measure(&manifest.Renditions[i])
+	}
+	m.evaluate(manifest)
+	return manifest, nil
+}
+
+// WriteTo writes the initialization segment and the media segments of the rendition found on
+// disk to w, producing a single stream FFprobe can analyze. It implements io.WriterTo.
+func (r *Rendition) WriteTo(w io.Writer) (int64, error) {
+	segments := r.Segments
+	if r.InitSegment != nil {
+		segments = append([]ManifestSegment{*r.InitSegment}, segments...)
+	}
+
+	var written int64
+	for _, segment := range segments {
+		if segment.Size < 0 {
+			continue
+		}
+		n, err := copySegment(w, segment)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
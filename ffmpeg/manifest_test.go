@@ -0,0 +1,217 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the HLS and DASH manifest inspector.
+package ffmpeg
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// ManifestInspectorTestSuite defines the test suite for ManifestInspector.
+// It writes synthetic manifests and segments so no sample media is required.
+type ManifestInspectorTestSuite struct {
+	suite.Suite
+	tempDir   string             // Temporary directory for generated files
+	inspector *ManifestInspector // ManifestInspector instance under test
+}
+
+// SetupTest creates a fresh temporary directory and the inspector.
+func (s *ManifestInspectorTestSuite) SetupTest() {
+	tempDir, err := os.MkdirTemp("", "manifest-test")
+	require.NoError(s.T(), err)
+	s.tempDir = tempDir
+	s.inspector = NewManifestInspector()
+}
+
+// TearDownTest removes the temporary directory.
+func (s *ManifestInspectorTestSuite) TearDownTest() {
+	os.RemoveAll(s.tempDir)
+}
+
+// writeFile writes a file below the temporary directory, creating its directory.
+func (s *ManifestInspectorTestSuite) writeFile(name string, content []byte) {
+	path := filepath.Join(s.tempDir, filepath.FromSlash(name))
+	require.NoError(s.T(), os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(s.T(), os.WriteFile(path, content, 0644))
+}
+
+// TestHLS verifies variant resolution, declared vs measured bandwidth and duration checks.
+func (s *ManifestInspectorTestSuite) TestHLS() {
+	s.writeFile("master.m3u8", []byte("#EXTM3U\n"+
+		"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"aac\",NAME=\"English\",URI=\"audio/index.m3u8\"\n"+
+		"#EXT-X-STREAM-INF:BANDWIDTH=100000,AVERAGE-BANDWIDTH=60000,RESOLUTION=1280x720,CODECS=\"avc1.64001f,mp4a.40.2\",AUDIO=\"aac\"\n"+
+		"720p/index.m3u8\n"+
+		"#EXT-X-STREAM-INF:BANDWIDTH=50000,RESOLUTION=640x360,CODECS=\"avc1.64001e,mp4a.40.2\"\n"+
+		"360p/index.m3u8\n"))
+
+	// 4 s segments of 40000 bytes (80 kbps) and a short last segment
+	s.writeFile("720p/index.m3u8", []byte("#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-MAP:URI=\"init.mp4\"\n"+
+		"#EXTINF:4.0,\nseg0.m4s\n#EXTINF:4.0,\nseg1.m4s\n#EXTINF:2.0,\nseg2.m4s\n#EXT-X-ENDLIST\n"))
+	s.writeFile("720p/init.mp4", make([]byte, 100))
+	s.writeFile("720p/seg0.m4s", make([]byte, 40000))
+	s.writeFile("720p/seg1.m4s", make([]byte, 40000))
+	s.writeFile("720p/seg2.m4s", make([]byte, 20000))
+
+	// Byte ranges within a single file, the second segment is too long for the target duration
+	s.writeFile("360p/index.m3u8", []byte("#EXTM3U\n#EXT-X-TARGETDURATION:4\n"+
+		"#EXTINF:4.0,\n#EXT-X-BYTERANGE:10000@0\nall.ts\n#EXTINF:5.0,\n#EXT-X-BYTERANGE:10000\nall.ts\n"))
+	s.writeFile("360p/all.ts", make([]byte, 20000))
+
+	s.writeFile("audio/index.m3u8", []byte("#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXTINF:4.0,\na0.aac\n#EXTINF:4.0,\na1.aac\n"))
+	s.writeFile("audio/a0.aac", make([]byte, 1000))
+
+	manifest, err := s.inspector.Inspect(filepath.Join(s.tempDir, "master.m3u8"))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), ManifestHLS, manifest.Format)
+	assert.Equal(s.T(), 4.0, manifest.TargetDuration)
+	require.Len(s.T(), manifest.Renditions, 3)
+
+	audio := manifest.Renditions[0]
+	assert.Equal(s.T(), RenditionAudio, audio.Type)
+	assert.Equal(s.T(), 1, audio.MissingSegments)
+
+	hd := manifest.Renditions[1]
+	assert.Equal(s.T(), "720p/index.m3u8", hd.ID)
+	assert.Equal(s.T(), RenditionVideo, hd.Type)
+	assert.Equal(s.T(), 1280, hd.Width)
+	assert.Equal(s.T(), int64(60000), hd.AverageBandwidth)
+	require.NotNil(s.T(), hd.InitSegment)
+	assert.Equal(s.T(), int64(100), hd.InitSegment.Size)
+	assert.InDelta(s.T(), 80000.0, hd.MeasuredPeakBandwidth, 1e-6)
+	assert.InDelta(s.T(), 80000.0, hd.MeasuredAverageBandwidth, 1e-6)
+	assert.InDelta(s.T(), 8.0, hd.Segments[2].Start, 1e-9)
+	assert.Equal(s.T(), SegmentDurationStats{Count: 3, Min: 2, Max: 4, Mean: 10.0 / 3, StdDev: hd.SegmentDurations.StdDev},
+		hd.SegmentDurations)
+	assert.InDelta(s.T(), 0.943, hd.SegmentDurations.StdDev, 1e-3)
+
+	sd := manifest.Renditions[2]
+	assert.Equal(s.T(), int64(10000), sd.Segments[1].Offset)
+	assert.Equal(s.T(), int64(10000), sd.Segments[1].Size)
+	assert.InDelta(s.T(), 20000.0, sd.MeasuredPeakBandwidth, 1e-6)
+
+	issues := strings.Join(manifest.Issues, "\n")
+	assert.Contains(s.T(), issues, "rendition audio/index.m3u8: 1 of 2 segments missing")
+	assert.Contains(s.T(), issues, "rendition 720p/index.m3u8: measured average bandwidth 80 kbps differs from the declared 60 kbps")
+	assert.NotContains(s.T(), issues, "720p/index.m3u8: measured peak")
+	assert.Contains(s.T(), issues, "rendition 360p/index.m3u8: 1 segments longer than the target duration of 4 s")
+
+	var buffer bytes.Buffer
+	written, err := manifest.Renditions[1].WriteTo(&buffer)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(100100), written)
+}
+
+// TestDASH verifies segment templates with and without a timeline.
+func (s *ManifestInspectorTestSuite) TestDASH() {
+	s.writeFile("stream.mpd", []byte(`<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" mediaPresentationDuration="PT10S">
+  <Period>
+    <AdaptationSet contentType="video">
+      <SegmentTemplate timescale="1000" initialization="$RepresentationID$/init.mp4" media="$RepresentationID$/seg-$Number%03d$.m4s" startNumber="1">
+        <SegmentTimeline><S t="0" d="4000" r="1"/><S d="2000"/></SegmentTimeline>
+      </SegmentTemplate>
+      <Representation id="v1" bandwidth="60000" width="1920" height="1080" codecs="avc1.640028"/>
+    </AdaptationSet>
+    <AdaptationSet mimeType="audio/mp4">
+      <SegmentTemplate timescale="48000" duration="192000" media="a/$Time$.m4s"/>
+      <Representation id="a1" bandwidth="128000"/>
+    </AdaptationSet>
+    <AdaptationSet contentType="text"><Representation id="t1"/></AdaptationSet>
+  </Period>
+</MPD>`))
+	s.writeFile("v1/init.mp4", make([]byte, 50))
+	for i, size := range []int{40000, 40000, 20000} {
+		s.writeFile(fmt.Sprintf("v1/seg-%03d.m4s", i+1), make([]byte, size))
+	}
+
+	manifest, err := s.inspector.Inspect(filepath.Join(s.tempDir, "stream.mpd"))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), ManifestDASH, manifest.Format)
+	assert.Equal(s.T(), 4.0, manifest.TargetDuration)
+	require.Len(s.T(), manifest.Renditions, 2)
+
+	video := manifest.Renditions[0]
+	require.Len(s.T(), video.Segments, 3)
+	assert.Equal(s.T(), "v1/seg-003.m4s", video.Segments[2].URI)
+	assert.Zero(s.T(), video.MissingSegments)
+	assert.InDelta(s.T(), 80000.0, video.MeasuredPeakBandwidth, 1e-6)
+
+	audio := manifest.Renditions[1]
+	assert.Equal(s.T(), RenditionAudio, audio.Type)
+	require.Len(s.T(), audio.Segments, 3)
+	assert.Equal(s.T(), "a/384000.m4s", audio.Segments[2].URI)
+	assert.InDelta(s.T(), 2.0, audio.Segments[2].Duration, 1e-9)
+	assert.Equal(s.T(), 3, audio.MissingSegments)
+
+	assert.Contains(s.T(), strings.Join(manifest.Issues, "\n"), "rendition v1: measured peak bandwidth 80 kbps exceeds the declared 60 kbps")
+}
+
+// TestCheckAlignment verifies the IDR checks of segment starts and across renditions.
+func (s *ManifestInspectorTestSuite) TestCheckAlignment() {
+	segments := func() []ManifestSegment {
+		return []ManifestSegment{{Start: 0, Duration: 2}, {Start: 2, Duration: 2}, {Start: 4, Duration: 2}}
+	}
+	manifest := &Manifest{Renditions: []Rendition{
+		{ID: "audio", Type: RenditionAudio, Segments: segments()},
+		{ID: "high", Type: RenditionVideo, Segments: segments()},
+		{ID: "low", Type: RenditionVideo, Segments: segments()},
+	}}
+
+	// The high rendition has IDR frames every 2 s; the low one misses the one at 4 s
+	for n := 0; n < 150; n++ {
+		frame := FrameBitrateInfo{FrameNumber: n, Time: 1.4 + float64(n)/25}
+		frame.KeyFrame = n%50 == 0
+		manifest.Renditions[1].AddFrame(frame)
+		frame.KeyFrame = n == 0 || n == 50 || n == 110
+		manifest.Renditions[2].AddFrame(frame)
+	}
+	manifest.CheckAlignment()
+
+	assert.Equal(s.T(), []float64{0, 2, 4}, manifest.Renditions[1].IDRTimes)
+	assert.Zero(s.T(), manifest.Renditions[1].SegmentsWithoutIDR)
+	assert.Equal(s.T(), 1, manifest.Renditions[2].SegmentsWithoutIDR)
+	assert.False(s.T(), manifest.Renditions[2].Segments[2].StartsWithIDR)
+	assert.Equal(s.T(), 1, manifest.Renditions[2].MisalignedIDRs)
+	assert.Contains(s.T(), manifest.Issues, "rendition low: 1 IDR frames at segment starts of rendition high are missing")
+
+	// A missing segment shifts the frames of the segments after it, so the rendition is not checked
+	manifest.Issues = nil
+	manifest.Renditions[2].Segments[1].Size = -1
+	manifest.Renditions[2].MissingSegments = 1
+	manifest.Renditions[2].SegmentsWithoutIDR, manifest.Renditions[2].MisalignedIDRs = 0, 0
+	manifest.CheckAlignment()
+	assert.Zero(s.T(), manifest.Renditions[2].SegmentsWithoutIDR)
+	assert.Zero(s.T(), manifest.Renditions[2].MisalignedIDRs)
+	assert.Empty(s.T(), manifest.Issues)
+}
+
+// TestHelpers verifies the manifest helpers.
+func (s *ManifestInspectorTestSuite) TestHelpers() {
+	assert.InDelta(s.T(), 3723.5, parseISO8601Duration("PT1H2M3.5S"), 1e-9)
+	assert.InDelta(s.T(), 86400.0, parseISO8601Duration("P1D"), 1e-9)
+	assert.Zero(s.T(), parseISO8601Duration("10 seconds"))
+
+	assert.Equal(s.T(), "v1/00042-$.m4s", expandDASHTemplate("$RepresentationID$/$Number%05d$-$$.m4s", "v1", 0, 42, 0))
+	assert.Equal(s.T(), RenditionAudio, hlsRenditionType("mp4a.40.2"))
+	assert.Equal(s.T(), RenditionVideo, hlsRenditionType("hvc1.2.4.L123.B0,ec-3"))
+	assert.Equal(s.T(), "", resolveManifestURI("/tmp", "https://cdn.example.com/seg.ts"))
+	assert.Equal(s.T(), filepath.Join("/tmp", "a b", "seg.ts"), resolveManifestURI("/tmp", "a%20b/seg.ts?token=1"))
+
+	assert.True(s.T(), IsManifest("stream.M3U8"))
+	assert.False(s.T(), IsManifest("movie.mkv"))
+	_, err := s.inspector.Inspect(filepath.Join(s.tempDir, "missing.m3u8"))
+	assert.Error(s.T(), err)
+}
+
+// TestManifestInspectorSuite runs the ManifestInspector test suite.
+func TestManifestInspectorSuite(t *testing.T) {
+	suite.Run(t, new(ManifestInspectorTestSuite))
+}
@@ -0,0 +1,681 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Private variables (alphabetical)
+
+// mkvCompressionAlgorithms maps ContentCompAlgo values to readable names.
+var mkvCompressionAlgorithms = map[uint64]string{
+	0: "zlib",
+	1: "bzlib",
+	2: "lzo1x",
+	3: "header stripping",
+}
+
+// mkvSegmentChildren lists the level 1 element IDs that can follow a cluster.
+// They mark the end of clusters written with an unknown size.
+var mkvSegmentChildren = map[uint32]bool{
+	mkvIDAttachments: true,
+	mkvIDChapters:    true,
+	mkvIDCluster:     true,
+	mkvIDCues:        true,
+	mkvIDInfo:        true,
+	mkvIDSeekHead:    true,
+	mkvIDTags:        true,
+	mkvIDTracks:      true,
+}
+
+// mkvStatisticsTagNames lists the tags written by muxers such as mkvmerge to describe track statistics.
+var mkvStatisticsTagNames = map[string]bool{
+	"BPS":                          true,
+	"DURATION":                     true,
+	"NUMBER_OF_BYTES":              true,
+	"NUMBER_OF_FRAMES":             true,
+	"_STATISTICS_TAGS":             true,
+	"_STATISTICS_WRITING_APP":      true,
+	"_STATISTICS_WRITING_DATE_UTC": true,
+}
+
+// mkvTrackTypes maps TrackType values to readable names.
+var mkvTrackTypes = map[uint64]string{
+	1:    "video",
+	2:    "audio",
+	3:    "complex",
+	0x10: "logo",
+	0x11: "subtitle",
+	0x12: "buttons",
+	0x20: "control",
+	0x21: "metadata",
+}
+
+// Private functions (alphabetical)
+
+// mkvParseDuration parses the HH:MM:SS.nnnnnnnnn format used by the DURATION tag.
+func mkvParseDuration(value string) (float64, bool) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+
+	hours, errHours := strconv.ParseFloat(parts[0], 64)
+	minutes, errMinutes := strconv.ParseFloat(parts[1], 64)
+	seconds, errSeconds := strconv.ParseFloat(parts[2], 64)
+	if errHours != nil || errMinutes != nil || errSeconds != nil {
+		return 0, false
+	}
+
+	return hours*3600 + minutes*60 + seconds, true
+}
+
+// mkvReadElementHeader reads the EBML element ID and size starting at offset.
+// It returns the element together with the header length. The end parameter bounds
+// the enclosing element so that corrupt sizes are detected.
+func mkvReadElementHeader(r io.ReaderAt, offset, end int64) (mkvElement, error) {
+	available := end - offset
+	if available < 2 {
+		return mkvElement{}, fmt.Errorf("truncated element header at offset %d", offset)
+	}
+	if available > 12 {
+		available = 12
+	}
+
+	header := make([]byte, available)
+	if _, err := r.ReadAt(header, offset); err != nil && err != io.EOF {
+		return mkvElement{}, fmt.Errorf("error reading element header at offset %d: %w", offset, err)
+	}
+
+	// Element IDs keep their length marker and are at most 4 bytes long
+	idLength := mkvVintLength(header[0])
+	if idLength == 0 || idLength > 4 || idLength >= len(header) {
+		return mkvElement{}, fmt.Errorf("invalid element ID at offset %d", offset)
+	}
+	var id uint32
+	for _, b := range header[:idLength] {
+		id = id<<8 | uint32(b)
+	}
+
+	// Sizes drop their length marker; all value bits set means unknown size
+	sizeLength := mkvVintLength(header[idLength])
+	if sizeLength == 0 || idLength+sizeLength > len(header) {
+		return mkvElement{}, fmt.Errorf("invalid element size at offset %d", offset)
+	}
+	size := uint64(header[idLength] & (0xFF >> sizeLength))
+	allOnes := size == uint64(0xFF>>sizeLength)
+	for _, b := range header[idLength+1 : idLength+sizeLength] {
+		size = size<<8 | uint64(b)
+		allOnes = allOnes && b == 0xFF
+	}
+
+	element := mkvElement{
+		id:         id,
+		dataOffset: offset + int64(idLength+sizeLength),
+		dataSize:   -1,
+	}
+	if !allOnes {
+		if size > math.MaxInt64 || element.dataOffset+int64(size) > end {
+			return mkvElement{}, fmt.Errorf("element 0x%X at offset %d exceeds its parent", id, offset)
+		}
+		element.dataSize = int64(size)
+	}
+
+	return element, nil
+}
+
+// mkvVintLength returns the length of an EBML variable size integer from its first byte.
+func mkvVintLength(first byte) int {
+	for i := 0; i < 8; i++ {
+		if first&(0x80>>i) != 0 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Public functions (alphabetical)
+
+// NewMatroskaInspector creates a new MatroskaInspector.
+// The inspector is pure Go and does not depend on an FFmpeg installation.
+func NewMatroskaInspector() *MatroskaInspector {
+	return &MatroskaInspector{}
+}
+
+// Private methods (alphabetical)
+
+// children calls fn for every child element stored between start and end.
+// Children with an unknown size are rejected, as only clusters may use them.
+func (m *MatroskaInspector) children(r io.ReaderAt, start, end int64, fn func(mkvElement) error) error {
+	for offset := start; offset < end; {
+		element, err := mkvReadElementHeader(r, offset, end)
+		if err != nil {
+			return err
+		}
+		if element.dataSize < 0 {
+			return fmt.Errorf("element 0x%X at offset %d has an unknown size", element.id, offset)
+		}
+
+		if err := fn(element); err != nil {
+			return err
+		}
+		offset = element.dataOffset + element.dataSize
+	}
+
+	return nil
+}
+
+// clusterEnd finds where a cluster written with an unknown size ends by scanning
+// its children until the next level 1 element.
+func (m *MatroskaInspector) clusterEnd(r io.ReaderAt, cluster mkvElement, end int64) (int64, error) {
+	offset := cluster.dataOffset
+	for offset < end {
+		element, err := mkvReadElementHeader(r, offset, end)
+		if err != nil {
+			return offset, err
+		}
+		if mkvSegmentChildren[element.id] {
+			return offset, nil
+		}
+		if element.dataSize < 0 {
+			return offset, fmt.Errorf("nested element with unknown size at offset %d", offset)
+		}
+		offset = element.dataOffset + element.dataSize
+	}
+
+	return end, nil
+}
+
+// evaluate runs the structure checks on a parsed segment and records the findings.
+func (m *MatroskaInspector) evaluate(structure *MatroskaStructure, cueTimes []float64) {
+	// Cue coverage
+	if structure.CuePointCount == 0 {
+		structure.Issues = append(structure.Issues, "no cue points (seeking requires scanning the file)")
+	} else {
+		m.evaluateCueGaps(structure, cueTimes)
+	}
+
+	for _, track := range structure.Tracks {
+		// Header compression is not supported by many hardware players
+		if track.Compression == "header stripping" {
+			structure.Issues = append(structure.Issues, fmt.Sprintf(
+				"track %d (%s) uses header stripping compression", track.Number, track.Type))
+		}
+
+		// A track cannot last longer than its segment, so such a DURATION tag is stale
+		if value, ok := track.StatisticsTags["DURATION"]; ok && structure.Duration > 0 {
+			if duration, ok := mkvParseDuration(value); ok && duration > structure.Duration+1 {
+				structure.Issues = append(structure.Issues, fmt.Sprintf(
+					"track %d DURATION tag (%.3f s) exceeds segment duration (%.3f s)",
+					track.Number, duration, structure.Duration))
+				m.markStale(structure, track.Number)
+			}
+		}
+	}
+}
+
+// evaluateCueGaps reports stretches of the timeline that lack cue points.
+func (m *MatroskaInspector) evaluateCueGaps(structure *MatroskaStructure, cueTimes []float64) {
+	sort.Float64s(cueTimes)
+	structure.FirstCueTime = cueTimes[0]
+	structure.LastCueTime = cueTimes[len(cueTimes)-1]
+
+	// Include the segment start and end so missing cues at the edges are reported
+	points := append([]float64{0}, cueTimes...)
+	if structure.Duration > structure.LastCueTime {
+		points = append(points, structure.Duration)
+	}
+
+	for i := 1; i < len(points); i++ {
+		if points[i]-points[i-1] > mkvCueGapThreshold {
+			structure.CueGaps = append(structure.CueGaps, MatroskaCueGap{Start: points[i-1], End: points[i]})
+		}
+	}
+
+	if len(structure.CueGaps) > 0 {
+		structure.Issues = append(structure.Issues, fmt.Sprintf(
+			"%d gaps longer than %.0f s without cue points", len(structure.CueGaps), mkvCueGapThreshold))
+	}
+}
+
+// markStale flags the statistics tags of a track as stale.
+func (m *MatroskaInspector) markStale(structure *MatroskaStructure, trackNumber uint64) {
+	for i := range structure.Tracks {
+		if structure.Tracks[i].Number == trackNumber {
+			structure.Tracks[i].StaleStatistics = true
+		}
+	}
+}
+
+// parseAttachments decodes the Attachments element and checksums the attached data.
+func (m *MatroskaInspector) parseAttachments(r io.ReaderAt, element mkvElement, structure *MatroskaStructure) error {
+	return m.children(r, element.dataOffset, element.dataOffset+element.dataSize, func(file mkvElement) error {
+		if file.id != mkvIDAttachedFile {
+			return nil
+		}
+
+		var attachment MatroskaAttachment
+		err := m.children(r, file.dataOffset, file.dataOffset+file.dataSize, func(child mkvElement) error {
+			switch child.id {
+			case mkvIDFileName:
+				attachment.FileName = m.readString(r, child)
+			case mkvIDFileMimeType:
+				attachment.MimeType = m.readString(r, child)
+			case mkvIDFileDescription:
+				attachment.Description = m.readString(r, child)
+			case mkvIDFileData:
+				hash := sha256.New()
+				if _, err := io.Copy(hash, io.NewSectionReader(r, child.dataOffset, child.dataSize)); err != nil {
+					return fmt.Errorf("error reading attachment data: %w", err)
+				}
+				attachment.Size = child.dataSize
+				attachment.SHA256 = hex.EncodeToString(hash.Sum(nil))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		structure.Attachments = append(structure.Attachments, attachment)
+		return nil
+	})
+}
+
+// parseContentEncodings finds the compression algorithm declared by a track.
+func (m *MatroskaInspector) parseContentEncodings(r io.ReaderAt, element mkvElement, track *MatroskaTrack) error {
+	return m.children(r, element.dataOffset, element.dataOffset+element.dataSize, func(encoding mkvElement) error {
+		if encoding.id != mkvIDContentEncoding {
+			return nil
+		}
+		return m.children(r, encoding.dataOffset, encoding.dataOffset+encoding.dataSize, func(child mkvElement) error {
+			if child.id != mkvIDContentCompression {
+				return nil
+			}
+
+			// ContentCompAlgo defaults to zlib when omitted
+			algorithm := uint64(0)
+			err := m.children(r, child.dataOffset, child.dataOffset+child.dataSize, func(setting mkvElement) error {
+				if setting.id == mkvIDContentCompAlgo {
+					algorithm = m.readUint(r, setting)
+				}
+				return nil
+			})
+			track.Compression = mkvCompressionAlgorithms[algorithm]
+			if track.Compression == "" {
+				track.Compression = fmt.Sprintf("unknown (%d)", algorithm)
+			}
+			return err
+		})
+	})
+}
+
+// parseCues decodes the Cues element, collecting cue times and indexed cluster positions.
+func (m *MatroskaInspector) parseCues(r io.ReaderAt, element mkvElement, cueTicks *[]uint64, indexed map[int64]bool) error {
+	return m.children(r, element.dataOffset, element.dataOffset+element.dataSize, func(point mkvElement) error {
+		if point.id != mkvIDCuePoint {
+			return nil
+		}
+
+		return m.children(r, point.dataOffset, point.dataOffset+point.dataSize, func(child mkvElement) error {
+			switch child.id {
+			case mkvIDCueTime:
+				*cueTicks = append(*cueTicks, m.readUint(r, child))
+			case mkvIDCueTrackPositions:
+				return m.children(r, child.dataOffset, child.dataOffset+child.dataSize, func(position mkvElement) error {
+					if position.id == mkvIDCueClusterPosition {
+						indexed[int64(m.readUint(r, position))] = true
+					}
+					return nil
+				})
+			}
+			return nil
+		})
+	})
+}
+
+// parseInfo decodes the segment Info element.
+func (m *MatroskaInspector) parseInfo(r io.ReaderAt, element mkvElement, structure *MatroskaStructure) (float64, error) {
+	var durationTicks float64
+	err := m.children(r, element.dataOffset, element.dataOffset+element.dataSize, func(child mkvElement) error {
+		switch child.id {
+		case mkvIDTimestampScale:
+			structure.TimestampScale = m.readUint(r, child)
+		case mkvIDDuration:
+			durationTicks = m.readFloat(r, child)
+		case mkvIDMuxingApp:
+			structure.MuxingApp = m.readString(r, child)
+		case mkvIDWritingApp:
+			structure.WritingApp = m.readString(r, child)
+		case mkvIDTitle:
+			structure.Title = m.readString(r, child)
+		}
+		return nil
+	})
+
+	return durationTicks, err
+}
+
+// parseTags decodes the Tags element and returns the statistics tags keyed by track UID.
+func (m *MatroskaInspector) parseTags(r io.ReaderAt, element mkvElement, tagsByUID map[uint64]map[string]string) error {
+	return m.children(r, element.dataOffset, element.dataOffset+element.dataSize, func(tag mkvElement) error {
+		if tag.id != mkvIDTag {
+			return nil
+		}
+
+		var trackUIDs []uint64
+		values := make(map[string]string)
+		err := m.children(r, tag.dataOffset, tag.dataOffset+tag.dataSize, func(child mkvElement) error {
+			switch child.id {
+			case mkvIDTargets:
+				return m.children(r, child.dataOffset, child.dataOffset+child.dataSize, func(target mkvElement) error {
+					if target.id == mkvIDTagTrackUID {
+						trackUIDs = append(trackUIDs, m.readUint(r, target))
+					}
+					return nil
+				})
+			case mkvIDSimpleTag:
+				var name, value string
+				err := m.children(r, child.dataOffset, child.dataOffset+child.dataSize, func(simple mkvElement) error {
+					switch simple.id {
+					case mkvIDTagName:
+						name = m.readString(r, simple)
+					case mkvIDTagString:
+						value = m.readString(r, simple)
+					}
+					return nil
+				})
+				if mkvStatisticsTagNames[name] {
+					values[name] = value
+				}
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, uid := range trackUIDs {
+			if tagsByUID[uid] == nil {
+				tagsByUID[uid] = make(map[string]string)
+			}
+			for name, value := range values {
+				tagsByUID[uid][name] = value
+			}
+		}
+		return nil
+	})
+}
+
+// parseTracks decodes the Tracks element.
+func (m *MatroskaInspector) parseTracks(r io.ReaderAt, element mkvElement, structure *MatroskaStructure) error {
+	return m.children(r, element.dataOffset, element.dataOffset+element.dataSize, func(entry mkvElement) error {
+		if entry.id != mkvIDTrackEntry {
+			return nil
+		}
+
+		track := MatroskaTrack{Language: "eng", StatisticsTags: make(map[string]string)}
+		err := m.children(r, entry.dataOffset, entry.dataOffset+entry.dataSize, func(child mkvElement) error {
+			switch child.id {
+			case mkvIDTrackNumber:
+				track.Number = m.readUint(r, child)
+			case mkvIDTrackUID:
+				track.UID = m.readUint(r, child)
+			case mkvIDTrackType:
+				track.Type = mkvTrackTypes[m.readUint(r, child)]
+			case mkvIDCodecID:
+				track.CodecID = m.readString(r, child)
+			case mkvIDName:
+				track.Name = m.readString(r, child)
+			case mkvIDLanguage:
+				track.Language = m.readString(r, child)
+			case mkvIDContentEncodings:
+				return m.parseContentEncodings(r, child, &track)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		structure.Tracks = append(structure.Tracks, track)
+		return nil
+	})
+}
+
+// readFloat reads a 4 or 8 byte EBML float element.
+func (m *MatroskaInspector) readFloat(r io.ReaderAt, element mkvElement) float64 {
+	data := m.readValue(r, element)
+	switch len(data) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	default:
+		return 0
+	}
+}
+
+// readString reads an EBML string element, dropping trailing zero padding.
+func (m *MatroskaInspector) readString(r io.ReaderAt, element mkvElement) string {
+	return strings.TrimRight(string(m.readValue(r, element)), "\x00")
+}
+
+// readUint reads an EBML unsigned integer element.
+func (m *MatroskaInspector) readUint(r io.ReaderAt, element mkvElement) uint64 {
+	var value uint64
+	for _, b := range m.readValue(r, element) {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+// readValue loads the data of a small element, returning nil for oversized or unreadable data.
+func (m *MatroskaInspector) readValue(r io.ReaderAt, element mkvElement) []byte {
+	if element.dataSize <= 0 || element.dataSize > mkvMaxValueSize {
+		return nil
+	}
+
+	data := make([]byte, element.dataSize)
+	if _, err := r.ReadAt(data, element.dataOffset); err != nil {
+		return nil
+	}
+	return data
+}
+
+// walkSegment visits the level 1 elements of a segment and fills the structure.
+func (m *MatroskaInspector) walkSegment(r io.ReaderAt, segment mkvElement, end int64, structure *MatroskaStructure) error {
+	var cueTicks []uint64
+	var durationTicks float64
+	indexed := make(map[int64]bool)
+	tagsByUID := make(map[uint64]map[string]string)
+	clusterPositions := make(map[int64]bool)
+
+	var walkErr error
+	for offset := segment.dataOffset; offset < end; {
+		element, err := mkvReadElementHeader(r, offset, end)
+		if err != nil {
+			walkErr = err
+			break
+		}
+
+		elementEnd := element.dataOffset + element.dataSize
+		if element.dataSize < 0 {
+			if element.id != mkvIDCluster {
+				walkErr = fmt.Errorf("element 0x%X at offset %d has an unknown size", element.id, offset)
+				break
+			}
+			if elementEnd, err = m.clusterEnd(r, element, end); err != nil {
+				walkErr = err
+				break
+			}
+		}
+
+		switch element.id {
+		case mkvIDInfo:
+			durationTicks, err = m.parseInfo(r, element, structure)
+		case mkvIDTracks:
+			err = m.parseTracks(r, element, structure)
+		case mkvIDCues:
+			err = m.parseCues(r, element, &cueTicks, indexed)
+		case mkvIDTags:
+			err = m.parseTags(r, element, tagsByUID)
+		case mkvIDAttachments:
+			err = m.parseAttachments(r, element, structure)
+		case mkvIDCluster:
+			// Cue cluster positions are relative to the segment data start
+			structure.ClusterCount++
+			clusterPositions[offset-segment.dataOffset] = true
+		}
+		if err != nil {
+			walkErr = err
+			break
+		}
+
+		offset = elementEnd
+	}
+
+	// Timestamps are expressed in ticks of the segment timestamp scale
+	if structure.TimestampScale == 0 {
+		structure.TimestampScale = mkvDefaultTimestampScale
+	}
+	tickSeconds := float64(structure.TimestampScale) / 1e9
+	structure.Duration = durationTicks * tickSeconds
+
+	cueTimes := make([]float64, 0, len(cueTicks))
+	for _, ticks := range cueTicks {
+		cueTimes = append(cueTimes, float64(ticks)*tickSeconds)
+	}
+	structure.CuePointCount = len(cueTicks)
+
+	for position := range indexed {
+		if clusterPositions[position] {
+			structure.IndexedClusterCount++
+		}
+	}
+
+	for i := range structure.Tracks {
+		for name, value := range tagsByUID[structure.Tracks[i].UID] {
+			structure.Tracks[i].StatisticsTags[name] = value
+		}
+	}
+
+	m.evaluate(structure, cueTimes)
+	return walkErr
+}
+
+// Public methods (alphabetical)
+
+// CrossCheckStatistics compares the statistics tags of the first video track with values
+// measured by the BitrateAnalyzer. Tags left untouched by remuxing tools no longer match
+// the actual stream and are reported as stale. Duration is the measured track duration in seconds.
+func (s *MatroskaStructure) CrossCheckStatistics(frameCount int64, totalBits int64, duration float64) {
+	for i := range s.Tracks {
+		track := &s.Tracks[i]
+		if track.Type != "video" {
+			continue
+		}
+
+		var mismatches []string
+		check := func(name string, measured float64) {
+			value, ok := track.StatisticsTags[name]
+			if !ok || measured <= 0 {
+				return
+			}
+			declared, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return
+			}
+			if math.Abs(declared-measured) > measured*mkvStatisticsTolerance {
+				mismatches = append(mismatches, fmt.Sprintf("%s=%s (measured %.0f)", name, value, measured))
+			}
+		}
+
+		check("NUMBER_OF_FRAMES", float64(frameCount))
+		check("NUMBER_OF_BYTES", float64(totalBits)/8)
+		if duration > 0 {
+			check("BPS", float64(totalBits)/duration)
+		}
+
+		if len(mismatches) > 0 {
+			track.StaleStatistics = true
+			s.Issues = append(s.Issues, fmt.Sprintf(
+				"track %d statistics tags are stale: %s", track.Number, strings.Join(mismatches, ", ")))
+		}
+		return
+	}
+}
+
+// Inspect reads the EBML structure of a Matroska or WebM file.
+// It reports segment information, track compression, cue point coverage and gaps,
+// per-track statistics tags and attachment sizes with SHA-256 checksums. A file that
+// is damaged part way through is still reported, with the problem listed in Issues.
+func (m *MatroskaInspector) Inspect(filePath string) (*MatroskaStructure, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("error reading file information: %w", err)
+	}
+	fileSize := stat.Size()
+
+	// The file must start with an EBML header
+	header, err := mkvReadElementHeader(file, 0, fileSize)
+	if err != nil || header.id != mkvIDEBML || header.dataSize < 0 {
+		return nil, fmt.Errorf("not a Matroska file: missing EBML header")
+	}
+
+	structure := &MatroskaStructure{}
+	err = m.children(file, header.dataOffset, header.dataOffset+header.dataSize, func(child mkvElement) error {
+		switch child.id {
+		case mkvIDDocType:
+			structure.DocType = m.readString(file, child)
+		case mkvIDDocTypeVersion:
+			structure.DocTypeVersion = m.readUint(file, child)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading EBML header: %w", err)
+	}
+
+	// The declared segment size is not bounded by the file, so truncated files are still walked
+	segment, err := mkvReadElementHeader(file, header.dataOffset+header.dataSize, math.MaxInt64)
+	if err != nil || segment.id != mkvIDSegment || segment.dataOffset > fileSize {
+		return nil, fmt.Errorf("not a Matroska file: missing segment")
+	}
+
+	segmentEnd := fileSize
+	if segment.dataSize >= 0 {
+		segmentEnd = segment.dataOffset + segment.dataSize
+	}
+	if segmentEnd > fileSize {
+		structure.Issues = append(structure.Issues, fmt.Sprintf(
+			"file is truncated: the segment declares %d bytes but only %d are present",
+			segment.dataSize, fileSize-segment.dataOffset))
+		segmentEnd = fileSize
+	}
+
+	if err := m.walkSegment(file, segment, segmentEnd, structure); err != nil {
+		structure.Issues = append(structure.Issues, "segment walk stopped early: "+err.Error())
+	}
+
+	return structure, nil
+}
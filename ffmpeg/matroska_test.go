@@ -0,0 +1,300 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the Matroska EBML inspector.
+package ffmpeg
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// MatroskaInspectorTestSuite defines the test suite for MatroskaInspector.
+// It builds synthetic EBML files in memory so no sample media is required.
+type MatroskaInspectorTestSuite struct {
+	suite.Suite
+	tempDir   string             // Temporary directory for generated files
+	inspector *MatroskaInspector // MatroskaInspector instance under test
+}
+
+// SetupSuite creates the temporary directory and the inspector.
+func (s *MatroskaInspectorTestSuite) SetupSuite() {
+	tempDir, err := os.MkdirTemp("", "matroska-test")
+	require.NoError(s.T(), err)
+	s.tempDir = tempDir
+	s.inspector = NewMatroskaInspector()
+}
+
+// TearDownSuite removes the temporary directory.
+func (s *MatroskaInspectorTestSuite) TearDownSuite() {
+	os.RemoveAll(s.tempDir)
+}
+
+// mkvTestElement serializes an element with an 8 byte size field.
+func mkvTestElement(id uint32, payloads ...[]byte) []byte {
+	var data []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> shift); b != 0 || len(data) > 0 {
+			data = append(data, b)
+		}
+	}
+
+	size := 0
+	for _, p := range payloads {
+		size += len(p)
+	}
+	sizeField := make([]byte, 8)
+	binary.BigEndian.PutUint64(sizeField, uint64(size))
+	sizeField[0] = 0x01
+	data = append(data, sizeField...)
+
+	for _, p := range payloads {
+		data = append(data, p...)
+	}
+	return data
+}
+
+// mkvTestUnknownSize serializes the header of an element with an unknown size.
+func mkvTestUnknownSize(id uint32) []byte {
+	element := mkvTestElement(id)
+	return append(element[:len(element)-8], 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+}
+
+// mkvTestUint serializes an unsigned integer element.
+func mkvTestUint(id uint32, value uint64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, value)
+	return mkvTestElement(id, data)
+}
+
+// mkvTestFloat serializes an 8 byte float element.
+func mkvTestFloat(id uint32, value float64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, math.Float64bits(value))
+	return mkvTestElement(id, data)
+}
+
+// mkvTestString serializes a string element.
+func mkvTestString(id uint32, value string) []byte {
+	return mkvTestElement(id, []byte(value))
+}
+
+// mkvTestStatisticsTag builds a Tag element carrying statistics for a track UID.
+func mkvTestStatisticsTag(trackUID uint64, values map[string]string) []byte {
+	payloads := [][]byte{mkvTestElement(mkvIDTargets, mkvTestUint(mkvIDTagTrackUID, trackUID))}
+	for name, value := range values {
+		payloads = append(payloads, mkvTestElement(mkvIDSimpleTag,
+			mkvTestString(mkvIDTagName, name), mkvTestString(mkvIDTagString, value)))
+	}
+	return mkvTestElement(mkvIDTag, payloads...)
+}
+
+// writeFile writes an EBML header followed by a segment with the given children.
+func (s *MatroskaInspectorTestSuite) writeFile(name, docType string, segment []byte) string {
+	header := mkvTestElement(mkvIDEBML, mkvTestString(mkvIDDocType, docType), mkvTestUint(mkvIDDocTypeVersion, 4))
+	path := filepath.Join(s.tempDir, name)
+	require.NoError(s.T(), os.WriteFile(path, append(header, segment...), 0644))
+	return path
+}
+
+// buildSegment builds a segment with two tracks, statistics tags, cues and clusters.
+// Cue cluster positions are computed from the serialized layout.
+func (s *MatroskaInspectorTestSuite) buildSegment(cueTimes []uint64, duration float64, attachment []byte) []byte {
+	info := mkvTestElement(mkvIDInfo,
+		mkvTestUint(mkvIDTimestampScale, 1000000),
+		mkvTestFloat(mkvIDDuration, duration),
+		mkvTestString(mkvIDMuxingApp, "libebml v1.4.2 + libmatroska v1.6.4"),
+		mkvTestString(mkvIDWritingApp, "mkvmerge v70.0.0"),
+		mkvTestString(mkvIDTitle, "Sample"),
+	)
+	tracks := mkvTestElement(mkvIDTracks,
+		mkvTestElement(mkvIDTrackEntry,
+			mkvTestUint(mkvIDTrackNumber, 1),
+			mkvTestUint(mkvIDTrackUID, 111),
+			mkvTestUint(mkvIDTrackType, 1),
+			mkvTestString(mkvIDCodecID, "V_MPEG4/ISO/AVC"),
+		),
+		mkvTestElement(mkvIDTrackEntry,
+			mkvTestUint(mkvIDTrackNumber, 2),
+			mkvTestUint(mkvIDTrackUID, 222),
+			mkvTestUint(mkvIDTrackType, 2),
+			mkvTestString(mkvIDCodecID, "A_AC3"),
+			mkvTestString(mkvIDLanguage, "ger"),
+			mkvTestElement(mkvIDContentEncodings, mkvTestElement(mkvIDContentEncoding,
+				mkvTestElement(mkvIDContentCompression, mkvTestUint(mkvIDContentCompAlgo, 3)))),
+		),
+	)
+	tags := mkvTestElement(mkvIDTags,
+		mkvTestStatisticsTag(111, map[string]string{
+			"BPS":              "8000",
+			"NUMBER_OF_FRAMES": "250",
+			"NUMBER_OF_BYTES":  "10000",
+			"DURATION":         "00:00:10.000000000",
+		}),
+		mkvTestStatisticsTag(222, map[string]string{"DURATION": "00:10:00.000000000"}),
+	)
+	children := append(append(append([]byte{}, info...), tracks...), tags...)
+	if attachment != nil {
+		children = append(children, mkvTestElement(mkvIDAttachments, mkvTestElement(mkvIDAttachedFile,
+			mkvTestString(mkvIDFileName, "font.ttf"),
+			mkvTestString(mkvIDFileMimeType, "font/ttf"),
+			mkvTestElement(mkvIDFileData, attachment),
+		))...)
+	}
+
+	// Each cue points at the first cluster, the second cluster is left unindexed
+	cues := func(position uint64) []byte {
+		var points [][]byte
+		for _, ticks := range cueTimes {
+			points = append(points, mkvTestElement(mkvIDCuePoint,
+				mkvTestUint(mkvIDCueTime, ticks),
+				mkvTestElement(mkvIDCueTrackPositions,
+					mkvTestUint(mkvIDTrackNumber, 1),
+					mkvTestUint(mkvIDCueClusterPosition, position))))
+		}
+		if len(points) == 0 {
+			return nil
+		}
+		return mkvTestElement(mkvIDCues, points...)
+	}
+	clusterPosition := uint64(len(children) + len(cues(0)))
+	children = append(children, cues(clusterPosition)...)
+	children = append(children, mkvTestElement(mkvIDCluster, mkvTestUint(0xE7, 0))...)
+	children = append(children, mkvTestElement(mkvIDCluster, mkvTestUint(0xE7, 5000))...)
+
+	return mkvTestElement(mkvIDSegment, children)
+}
+
+// TestSegmentStructure verifies segment info, tracks, tags, cues and attachments.
+func (s *MatroskaInspectorTestSuite) TestSegmentStructure() {
+	attachment := []byte("font data")
+	path := s.writeFile("sample.mkv", "matroska", s.buildSegment([]uint64{0, 5000}, 10000, attachment))
+
+	structure, err := s.inspector.Inspect(path)
+	require.NoError(s.T(), err)
+
+	assert.Equal(s.T(), "matroska", structure.DocType)
+	assert.Equal(s.T(), uint64(4), structure.DocTypeVersion)
+	assert.Equal(s.T(), "Sample", structure.Title)
+	assert.Equal(s.T(), "mkvmerge v70.0.0", structure.WritingApp)
+	assert.Equal(s.T(), uint64(1000000), structure.TimestampScale)
+	assert.InDelta(s.T(), 10.0, structure.Duration, 1e-9)
+
+	require.Len(s.T(), structure.Tracks, 2)
+	assert.Equal(s.T(), "video", structure.Tracks[0].Type)
+	assert.Equal(s.T(), "eng", structure.Tracks[0].Language)
+	assert.Equal(s.T(), "250", structure.Tracks[0].StatisticsTags["NUMBER_OF_FRAMES"])
+	assert.Equal(s.T(), "audio", structure.Tracks[1].Type)
+	assert.Equal(s.T(), "ger", structure.Tracks[1].Language)
+	assert.Equal(s.T(), "header stripping", structure.Tracks[1].Compression)
+	assert.True(s.T(), structure.Tracks[1].StaleStatistics)
+
+	assert.Equal(s.T(), 2, structure.CuePointCount)
+	assert.Equal(s.T(), 2, structure.ClusterCount)
+	assert.Equal(s.T(), 1, structure.IndexedClusterCount)
+	assert.Empty(s.T(), structure.CueGaps)
+
+	sum := sha256.Sum256(attachment)
+	require.Len(s.T(), structure.Attachments, 1)
+	assert.Equal(s.T(), "font.ttf", structure.Attachments[0].FileName)
+	assert.Equal(s.T(), int64(len(attachment)), structure.Attachments[0].Size)
+	assert.Equal(s.T(), hex.EncodeToString(sum[:]), structure.Attachments[0].SHA256)
+
+	assert.Contains(s.T(), structure.Issues, "track 2 (audio) uses header stripping compression")
+	assert.Contains(s.T(), structure.Issues, "track 2 DURATION tag (600.000 s) exceeds segment duration (10.000 s)")
+}
+
+// TestCueGaps verifies that missing cue coverage is reported, including at the end of the segment.
+func (s *MatroskaInspectorTestSuite) TestCueGaps() {
+	path := s.writeFile("gaps.webm", "webm", s.buildSegment([]uint64{0, 2000, 20000}, 45000, nil))
+
+	structure, err := s.inspector.Inspect(path)
+	require.NoError(s.T(), err)
+
+	assert.Equal(s.T(), "webm", structure.DocType)
+	assert.InDelta(s.T(), 20.0, structure.LastCueTime, 1e-9)
+	require.Len(s.T(), structure.CueGaps, 2)
+	assert.Equal(s.T(), MatroskaCueGap{Start: 2, End: 20}, structure.CueGaps[0])
+	assert.Equal(s.T(), MatroskaCueGap{Start: 20, End: 45}, structure.CueGaps[1])
+	assert.Contains(s.T(), structure.Issues, "2 gaps longer than 10 s without cue points")
+}
+
+// TestMissingCuesAndUnknownSizeCluster verifies files written by live muxers.
+func (s *MatroskaInspectorTestSuite) TestMissingCuesAndUnknownSizeCluster() {
+	info := mkvTestElement(mkvIDInfo, mkvTestFloat(mkvIDDuration, 3000))
+	cluster := append(mkvTestUnknownSize(mkvIDCluster), mkvTestUint(0xE7, 0)...)
+	segment := append(mkvTestUnknownSize(mkvIDSegment), info...)
+	segment = append(segment, cluster...)
+	segment = append(segment, cluster...)
+	path := s.writeFile("live.mkv", "matroska", segment)
+
+	structure, err := s.inspector.Inspect(path)
+	require.NoError(s.T(), err)
+
+	assert.Equal(s.T(), uint64(mkvDefaultTimestampScale), structure.TimestampScale)
+	assert.InDelta(s.T(), 3.0, structure.Duration, 1e-9)
+	assert.Equal(s.T(), 2, structure.ClusterCount)
+	assert.Equal(s.T(), []string{"no cue points (seeking requires scanning the file)"}, structure.Issues)
+}
+
+// TestCrossCheckStatistics verifies detection of statistics tags that no longer match the stream.
+func (s *MatroskaInspectorTestSuite) TestCrossCheckStatistics() {
+	path := s.writeFile("stats.mkv", "matroska", s.buildSegment([]uint64{0}, 10000, nil))
+
+	structure, err := s.inspector.Inspect(path)
+	require.NoError(s.T(), err)
+
+	// Matching measurements leave the video track untouched
+	matching := *structure
+	matching.Tracks = append([]MatroskaTrack{}, structure.Tracks...)
+	matching.Issues = nil
+	matching.CrossCheckStatistics(250, 80000, 10)
+	assert.False(s.T(), matching.Tracks[0].StaleStatistics)
+	assert.Empty(s.T(), matching.Issues)
+
+	// A re-encoded stream with fewer frames and bytes is flagged
+	structure.CrossCheckStatistics(200, 40000, 10)
+	assert.True(s.T(), structure.Tracks[0].StaleStatistics)
+	assert.Contains(s.T(), structure.Issues[len(structure.Issues)-1],
+		"track 1 statistics tags are stale: NUMBER_OF_FRAMES=250 (measured 200)")
+}
+
+// TestTruncatedFile verifies that a file cut short is still reported, with the truncation listed in Issues.
+func (s *MatroskaInspectorTestSuite) TestTruncatedFile() {
+	segment := s.buildSegment([]uint64{0, 5000}, 10000, nil)
+	path := s.writeFile("truncated.mkv", "matroska", segment[:len(segment)-4])
+
+	structure, err := s.inspector.Inspect(path)
+	require.NoError(s.T(), err)
+
+	assert.Equal(s.T(), "Sample", structure.Title)
+	assert.Len(s.T(), structure.Tracks, 2)
+	assert.Equal(s.T(), 2, structure.CuePointCount)
+	assert.Equal(s.T(), 1, structure.ClusterCount)
+	require.NotEmpty(s.T(), structure.Issues)
+	assert.Contains(s.T(), structure.Issues[0], "file is truncated")
+	assert.Contains(s.T(), structure.Issues[len(structure.Issues)-1], "segment walk stopped early")
+}
+
+// TestInvalidFiles verifies errors for missing and non Matroska files.
+func (s *MatroskaInspectorTestSuite) TestInvalidFiles() {
+	_, err := s.inspector.Inspect(filepath.Join(s.tempDir, "missing.mkv"))
+	assert.Error(s.T(), err)
+
+	path := filepath.Join(s.tempDir, "garbage.mkv")
+	require.NoError(s.T(), os.WriteFile(path, []byte("not a matroska file"), 0644))
+	_, err = s.inspector.Inspect(path)
+	assert.Error(s.T(), err)
+}
+
+// TestMatroskaInspectorSuite runs the MatroskaInspector test suite.
+func TestMatroskaInspectorSuite(t *testing.T) {
+	suite.Run(t, new(MatroskaInspectorTestSuite))
+}
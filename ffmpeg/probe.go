@@ -21,8 +21,50 @@ var titleFieldsRegex = regexp.MustCompile(`[\s._-]+`)
 
 // Private functions (alphabetical)
 
+// codecLevel converts the level reported by ffprobe to the level of the codec specification:
+// H.264 reports ten times the level, HEVC thirty times, and AV1 the seq_level_idx. Other codecs
+// keep the reported value. It returns 0 when the level is unknown, which ffprobe reports as a
+// negative value, or as 0 for the codecs other than AV1, whose seq_level_idx 0 is level 2.0.
+func codecLevel(codec string, level int) float64 {
+	if codec == "av1" && level >= 0 {
+		return float64(2+level/4) + float64(level%4)/10
+	}
+	if level <= 0 {
+		return 0
+	}
+	switch codec {
+	case "h264":
+		return float64(level) / 10
+	case "hevc":
+		return float64(level) / 30
+	}
+	return float64(level)
+}
+
 // Public functions (alphabetical)
 
+// Flags returns the names of the disposition flags that are set, in a fixed order.
+func (d StreamDisposition) Flags() []string {
+	var flags []string
+	for _, flag := range []struct {
+		set  bool
+		name string
+	}{
+		{d.Default, "default"},
+		{d.Forced, "forced"},
+		{d.HearingImpaired, "hearing impaired"},
+		{d.VisualImpaired, "visual impaired"},
+		{d.Commentary, "commentary"},
+		{d.Original, "original"},
+		{d.AttachedPic, "attached picture"},
+	} {
+		if flag.set {
+			flags = append(flags, flag.name)
+		}
+	}
+	return flags
+}
+
 // GetContainerTitle returns a user-friendly title for the container based on its metadata.
 // It follows a hierarchical approach to find the most appropriate title representation
 // for displaying to users in interfaces and logs.
@@ -55,6 +97,9 @@ func (p *Prober) GetExtendedContainerInfo(filePath string) (*ContainerInfo, erro
 	// Process chapters
 	p.processChapters(probeOutput.Chapters, containerInfo)
 
+	// Inspect the container structure
+	p.processContainerStructure(filePath, containerInfo)
+
 	return containerInfo, nil
 }
 
@@ -147,7 +192,7 @@ func (p *Prober) processStreams(streams []ffprobeStreamOutput, containerInfo *Co
 			containerInfo.AudioStreams = append(containerInfo.AudioStreams, audioStream)
 
 		case "subtitle":
-			subtitleStream := p.processSubtitleStream(streamInfo)
+			subtitleStream := p.processSubtitleStream(stream, streamInfo)
 			containerInfo.SubtitleStreams = append(containerInfo.SubtitleStreams, subtitleStream)
 
 		case "attachment":
@@ -172,6 +217,16 @@ func (p *Prober) extractCommonStreamInfo(stream ffprobeStreamOutput) StreamInfo
 		Index:      stream.Index,
 		Format:     stream.CodecName,
 		FormatFull: stream.CodecLongName,
+		StartTime:  p.parseFloatField(stream.StartTime),
+	}
+
+	// Keep the disposition flags and all raw tags
+	info.Disposition = p.processDisposition(stream.DispositionObj)
+	if len(stream.Tags) > 0 {
+		info.Tags = make(map[string]string, len(stream.Tags))
+		for key, value := range stream.Tags {
+			info.Tags[key] = value
+		}
 	}
 
 	// Get title and language from stream tags
@@ -187,6 +242,19 @@ func (p *Prober) extractCommonStreamInfo(stream ffprobeStreamOutput) StreamInfo
 	return info
 }
 
+// processDisposition converts the ffprobe disposition object to a StreamDisposition.
+func (p *Prober) processDisposition(disposition map[string]int) StreamDisposition {
+	return StreamDisposition{
+		Default:         disposition["default"] != 0,
+		Forced:          disposition["forced"] != 0,
+		HearingImpaired: disposition["hearing_impaired"] != 0,
+		VisualImpaired:  disposition["visual_impaired"] != 0,
+		Commentary:      disposition["comment"] != 0,
+		Original:        disposition["original"] != 0,
+		AttachedPic:     disposition["attached_pic"] != 0,
+	}
+}
+
 // processVideoStream converts ffprobe data to a VideoStream.
 func (p *Prober) processVideoStream(stream ffprobeStreamOutput, info StreamInfo) VideoStream {
 	// Parse bitrate
@@ -218,6 +286,7 @@ func (p *Prober) processVideoStream(stream ffprobeStreamOutput, info StreamInfo)
 		Format:             info.Format,
 		FormatFull:         info.FormatFull,
 		FormatProfile:      stream.Profile,
+		Level:              codecLevel(info.Format, stream.Level),
 		Width:              stream.Width,
 		Height:             stream.Height,
 		DisplayAspectRatio: displayAspectRatio,
@@ -227,11 +296,15 @@ func (p *Prober) processVideoStream(stream ffprobeStreamOutput, info StreamInfo)
 		BitRate:            bitRate,
 		BitDepth:           bitDepth,
 		Duration:           duration,
+		StartTime:          info.StartTime,
 		ColorSpace:         stream.ColorSpace,
 		ScanType:           stream.FieldOrder,
+		FieldOrder:         stream.FieldOrder,
 		HasBFrames:         stream.HasBFrames > 0,
 		Language:           info.Language,
 		Title:              info.Title,
+		Disposition:        info.Disposition,
+		Tags:               info.Tags,
 	}
 }
 
@@ -261,19 +334,26 @@ func (p *Prober) processAudioStream(stream ffprobeStreamOutput, info StreamInfo)
 		SamplingRate:  samplingRate,
 		BitRate:       bitRate,
 		Duration:      duration,
+		StartTime:     info.StartTime,
 		Language:      info.Language,
 		Title:         info.Title,
+		Disposition:   info.Disposition,
+		Tags:          info.Tags,
 	}
 }
 
 // processSubtitleStream converts ffprobe data to a SubtitleStream.
-func (p *Prober) processSubtitleStream(info StreamInfo) SubtitleStream {
+func (p *Prober) processSubtitleStream(stream ffprobeStreamOutput, info StreamInfo) SubtitleStream {
 	return SubtitleStream{
-		Index:      info.Index,
-		Format:     info.Format,
-		FormatFull: info.FormatFull,
-		Language:   info.Language,
-		Title:      info.Title,
+		Index:       info.Index,
+		Format:      info.Format,
+		FormatFull:  info.FormatFull,
+		Duration:    p.parseFloatField(stream.Duration),
+		StartTime:   info.StartTime,
+		Language:    info.Language,
+		Title:       info.Title,
+		Disposition: info.Disposition,
+		Tags:        info.Tags,
 	}
 }
 
@@ -348,6 +428,43 @@ func (p *Prober) processChapter(chapter chapterOutput) ChapterStream {
 	}
 }
 
+// processContainerStructure walks the box hierarchy of ISO-BMFF containers and the
+// element tree of Matroska/WebM containers.
+// Failures are not fatal as ffprobe already provided the stream information.
+func (p *Prober) processContainerStructure(filePath string, containerInfo *ContainerInfo) {
+	formatNames := strings.Split(containerInfo.General.Format, ",")
+	for _, name := range formatNames {
+		switch name {
+		case "mov", "mp4":
+			if structure, err := NewMP4Inspector().Inspect(filePath); err == nil {
+				containerInfo.MP4Structure = structure
+			}
+			return
+		case "matroska", "webm":
+			if structure, err := NewMatroskaInspector().Inspect(filePath); err == nil {
+				containerInfo.MatroskaStructure = structure
+				p.processAttachmentChecksums(containerInfo, structure)
+			}
+			return
+		}
+	}
+}
+
+// processAttachmentChecksums copies attachment sizes and checksums from the Matroska
+// structure onto the attachment streams reported by ffprobe, matching them by file name.
+func (p *Prober) processAttachmentChecksums(containerInfo *ContainerInfo, structure *MatroskaStructure) {
+	for i := range containerInfo.AttachmentStreams {
+		stream := &containerInfo.AttachmentStreams[i]
+		for _, attachment := range structure.Attachments {
+			if attachment.FileName == stream.FileName {
+				stream.Size = attachment.Size
+				stream.SHA256 = attachment.SHA256
+				break
+			}
+		}
+	}
+}
+
 // parseIntField converts a string field to an int64.
 func (p *Prober) parseIntField(value string) int64 {
 	if value == "" {
@@ -79,3 +79,61 @@ func TestGetContainerTitle(t *testing.T) {
 		})
 	}
 }
+
+// TestProcessStreamsDisposition tests that disposition flags, start times and raw tags reach the typed streams.
+func TestProcessStreamsDisposition(t *testing.T) {
+	prober := &Prober{}
+
+	streams := []ffprobeStreamOutput{
+		{
+			Index:          0,
+			CodecName:      "h264",
+			CodecType:      "video",
+			Level:          41,
+			DispositionObj: map[string]int{"default": 1, "attached_pic": 0},
+			Tags:           map[string]string{"language": "und", "BPS": "8000"},
+		},
+		{
+			Index:          1,
+			CodecName:      "ac3",
+			CodecType:      "audio",
+			StartTime:      "0.021000",
+			DispositionObj: map[string]int{"default": 0, "comment": 1, "visual_impaired": 1},
+			Tags:           map[string]string{"language": "eng", "title": "Director's Commentary"},
+		},
+		{
+			Index:          2,
+			CodecName:      "subrip",
+			CodecType:      "subtitle",
+			DispositionObj: map[string]int{"default": 1, "forced": 1, "hearing_impaired": 1, "original": 1},
+		},
+	}
+
+	info := &ContainerInfo{}
+	prober.processStreams(streams, info)
+
+	assert.Len(t, info.VideoStreams, 1)
+	assert.Equal(t, []string{"default"}, info.VideoStreams[0].Disposition.Flags())
+	assert.Equal(t, "8000", info.VideoStreams[0].Tags["BPS"])
+	assert.InDelta(t, 4.1, info.VideoStreams[0].Level, 1e-9)
+
+	assert.Len(t, info.AudioStreams, 1)
+	assert.Equal(t, []string{"visual impaired", "commentary"}, info.AudioStreams[0].Disposition.Flags())
+	assert.Equal(t, "Director's Commentary", info.AudioStreams[0].Tags["title"])
+	assert.Equal(t, 0.021, info.AudioStreams[0].StartTime)
+
+	assert.Len(t, info.SubtitleStreams, 1)
+	assert.Equal(t, []string{"default", "forced", "hearing impaired", "original"}, info.SubtitleStreams[0].Disposition.Flags())
+	assert.Nil(t, info.SubtitleStreams[0].Tags)
+}
+
+// TestCodecLevel tests the conversion of the levels reported by ffprobe.
+func TestCodecLevel(t *testing.T) {
+	assert.InDelta(t, 4.1, codecLevel("h264", 41), 1e-9)
+	assert.InDelta(t, 5.1, codecLevel("hevc", 153), 1e-9)
+	assert.InDelta(t, 4.1, codecLevel("av1", 9), 1e-9)
+	assert.InDelta(t, 2.0, codecLevel("av1", 0), 1e-9)
+	assert.Zero(t, codecLevel("av1", -99))
+	assert.InDelta(t, 4, codecLevel("mpeg2video", 4), 1e-9)
+	assert.Zero(t, codecLevel("h264", -99))
+}
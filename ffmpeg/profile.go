@@ -0,0 +1,192 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Private variables (alphabetical)
+
+// specProfiles lists the built-in delivery profiles by name, every version in ascending order.
+// A published version never changes: changed thresholds go into a new version, so pipelines
+// pinning name@version keep their behavior.
+var specProfiles = map[string][]Spec{
+	"archive-ffv1": {{
+		Name:        "Archive FFV1",
+		Version:     "1",
+		Description: "Lossless preservation master: FFV1 version 3 in Matroska with lossless audio",
+		Rules: []SpecRule{
+			{Field: "container.format", Equals: "matroska,webm", Description: "Matroska carries FFV1 with per-frame CRCs and attachments"},
+			{Field: "video.codec", Equals: "ffv1", Description: "Mathematically lossless intra-frame codec"},
+			{Field: "video.level", Min: specLimit(3), Description: "FFV1 version 3 adds slice CRCs and multithreaded decoding"},
+			{Field: "video.frame_rate_mode", Equals: FrameRateModeConstant, Description: "Digitized tape keeps the source cadence"},
+			{Field: "audio.codec", OneOf: []any{"flac", "pcm_s16le", "pcm_s24le"}, Description: "Lossless audio only"},
+		},
+	}},
+	"bluray-h264": {{
+		Name:        "Blu-ray H.264",
+		Version:     "1",
+		Description: "Blu-ray Disc primary video in H.264 with disc audio formats",
+		Rules: []SpecRule{
+			{Field: "video.codec", Equals: "h264"},
+			{Field: "video.profile", OneOf: []any{"Main", "High"}},
+			{Field: "video.level", Max: specLimit(4.1), Description: "Players decode up to level 4.1"},
+			{Field: "video.resolution", OneOf: []any{"1920x1080", "1440x1080", "1280x720", "720x576", "720x480"},
+				Description: "Resolutions allowed for primary video"},
+			{Field: "video.frame_rate", OneOf: []any{23.976, 24, 25, 29.97, 50, 59.94}},
+			{Field: "video.frame_rate_mode", Equals: FrameRateModeConstant},
+			{Field: "video.bit_depth", Equals: 8},
+			{Field: "video.peak_bitrate", Max: specLimit(40000000), Description: "Maximum video bitrate of 40 Mbps over one second"},
+			{Field: "audio.codec", OneOf: []any{"ac3", "eac3", "dts", "truehd", "pcm_bluray"}},
+			{Field: "audio.sample_rate", OneOf: []any{48000, 96000, 192000}},
+		},
+	}},
+	"broadcast-hd": {{
+		Name:        "Generic broadcast HD",
+		Version:     "1",
+		Description: "HD broadcast delivery in H.264 High 1080 with EBU R128 loudness",
+		Rules: []SpecRule{
+			{Field: "video.codec", Equals: "h264"},
+			{Field: "video.profile", Equals: "High"},
+			{Field: "video.level", Max: specLimit(4.1)},
+			{Field: "video.resolution", Equals: "1920x1080"},
+			{Field: "video.frame_rate", OneOf: []any{23.976, 24, 25, 29.97, 50, 59.94}},
+			{Field: "video.frame_rate_mode", Equals: FrameRateModeConstant, Description: "Playout servers need a constant frame rate"},
+			{Field: "video.bit_depth", Equals: 8},
+			{Field: "video.peak_bitrate", Max: specLimit(20000000), Description: "Peak of 20 Mbps over one second"},
+			{Field: "audio.codec", OneOf: []any{"aac", "ac3", "pcm_s16le", "pcm_s24le"}},
+			{Field: "audio.sample_rate", Equals: 48000},
+			{Field: "audio.integrated_loudness", Equals: -23, Tolerance: 1, Description: "EBU R128 target of -23 LUFS"},
+			{Field: "audio.true_peak", Max: specLimit(-1), Description: "EBU R128 maximum true peak of -1 dBTP"},
+		},
+	}},
+	"web-h264": {{
+		Name:        "Web streaming H.264",
+		Version:     "1",
+		Description: "Progressive download and adaptive streaming in H.264 up to 1080p with stereo AAC",
+		Rules: []SpecRule{
+			{Field: "video.codec", Equals: "h264"},
+			{Field: "video.profile", OneOf: []any{"Constrained Baseline", "Baseline", "Main", "High"},
+				Description: "Profiles decoded by browsers and mobile devices"},
+			{Field: "video.level", Max: specLimit(4.2)},
+			{Field: "video.height", Max: specLimit(1080)},
+			{Field: "video.frame_rate", Max: specLimit(60)},
+			{Field: "video.frame_rate_mode", Equals: FrameRateModeConstant},
+			{Field: "video.bit_depth", Equals: 8, Description: "Browsers only decode 8-bit H.264"},
+			{Field: "video.peak_bitrate", Max: specLimit(10000000), Description: "Peak of 10 Mbps over one second"},
+			{Field: "audio.codec", Equals: "aac"},
+			{Field: "audio.sample_rate", OneOf: []any{44100, 48000}},
+			{Field: "audio.channels", Max: specLimit(2)},
+			{Field: "audio.integrated_loudness", Equals: -14, Tolerance: 1, Description: "Streaming platform target of -14 LUFS"},
+			{Field: "audio.true_peak", Max: specLimit(-1)},
+		},
+	}},
+	"web-hevc": {{
+		Name:        "Web streaming HEVC",
+		Version:     "1",
+		Description: "Adaptive streaming in HEVC up to 2160p with AAC audio",
+		Rules: []SpecRule{
+			{Field: "video.codec", Equals: "hevc"},
+			{Field: "video.profile", OneOf: []any{"Main", "Main 10"}},
+			{Field: "video.level", Max: specLimit(5.1)},
+			{Field: "video.height", Max: specLimit(2160)},
+			{Field: "video.frame_rate", Max: specLimit(60)},
+			{Field: "video.frame_rate_mode", Equals: FrameRateModeConstant},
+			{Field: "video.peak_bitrate", Max: specLimit(25000000), Description: "Peak of 25 Mbps over one second"},
+			{Field: "audio.codec", Equals: "aac"},
+			{Field: "audio.sample_rate", OneOf: []any{44100, 48000}},
+			{Field: "audio.integrated_loudness", Equals: -14, Tolerance: 1, Description: "Streaming platform target of -14 LUFS"},
+			{Field: "audio.true_peak", Max: specLimit(-1)},
+		},
+	}},
+}
+
+// Private functions (alphabetical)
+
+// specLimit returns a pointer to a threshold, for the Min and Max of the built-in rules.
+func specLimit(value float64) *float64 {
+	return &value
+}
+
+// Public functions (alphabetical)
+
+// LookupSpecProfile returns a built-in delivery profile by name, such as broadcast-hd. The
+// latest version is returned unless a version is pinned with name@version. The rules of the
+// returned spec have their Source set to the profile.
+func LookupSpecProfile(name string) (*Spec, error) {
+	key, version, pinned := strings.Cut(strings.ToLower(strings.TrimSpace(name)), "@")
+	versions, ok := specProfiles[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(SpecProfileNames(), ", "))
+	}
+
+	index := len(versions) - 1
+	if pinned {
+		index = slices.IndexFunc(versions, func(profile Spec) bool { return profile.Version == version })
+		if index < 0 {
+			available := make([]string, len(versions))
+			for i, profile := range versions {
+				available[i] = profile.Version
+			}
+			return nil, fmt.Errorf("unknown version %q of profile %s (available: %s)", version, key, strings.Join(available, ", "))
+		}
+	}
+
+	spec := versions[index]
+	spec.Profile = key + "@" + spec.Version
+	spec.Rules = slices.Clone(spec.Rules)
+	for i := range spec.Rules {
+		spec.Rules[i].Source = spec.Profile
+	}
+	return &spec, nil
+}
+
+// MergeSpecs overrides the rules of a profile field by field. A rule of the override replaces the
+// profile rule checking the same field, or removes it when disabled; other rules are added. The
+// name, version and description of the override replace those of the profile when set.
+func MergeSpecs(profile *Spec, override *Spec) *Spec {
+	merged := &Spec{
+		Name:        profile.Name,
+		Version:     profile.Version,
+		Description: profile.Description,
+		Profile:     profile.Profile,
+		Rules:       slices.Clone(profile.Rules),
+	}
+	if override.Name != "" {
+		merged.Name, merged.Version = override.Name, override.Version
+	}
+	if override.Description != "" {
+		merged.Description = override.Description
+	}
+
+	for _, rule := range override.Rules {
+		index := slices.IndexFunc(merged.Rules, func(existing SpecRule) bool {
+			return existing.Source != "" && strings.EqualFold(existing.Field, strings.TrimSpace(rule.Field))
+		})
+		switch {
+		case index < 0:
+			merged.Rules = append(merged.Rules, rule)
+		case rule.Disabled:
+			merged.Rules = slices.Delete(merged.Rules, index, index+1)
+		default:
+			merged.Rules[index] = rule
+		}
+	}
+	return merged
+}
+
+// SpecProfileNames returns the names of the built-in delivery profiles in alphabetical order.
+func SpecProfileNames() []string {
+	names := make([]string, 0, len(specProfiles))
+	for name := range specProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,110 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the built-in delivery profiles.
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// SpecProfileTestSuite defines the test suite for the built-in delivery profiles.
+type SpecProfileTestSuite struct {
+	suite.Suite
+	tempDir string // Temporary directory for test files
+}
+
+// SetupTest creates a temporary directory.
+func (s *SpecProfileTestSuite) SetupTest() {
+	tempDir, err := os.MkdirTemp("", "profile-test")
+	require.NoError(s.T(), err)
+	s.tempDir = tempDir
+}
+
+// TearDownTest removes the temporary directory.
+func (s *SpecProfileTestSuite) TearDownTest() {
+	os.RemoveAll(s.tempDir)
+}
+
+// TestProfilesAreValid verifies that every version of every profile passes the spec validation.
+func (s *SpecProfileTestSuite) TestProfilesAreValid() {
+	assert.Equal(s.T(), []string{"archive-ffv1", "bluray-h264", "broadcast-hd", "web-h264", "web-hevc"}, SpecProfileNames())
+	for name, versions := range specProfiles {
+		for _, version := range versions {
+			profile, err := LookupSpecProfile(name + "@" + version.Version)
+			require.NoError(s.T(), err)
+			assert.NoError(s.T(), profile.validate(), name)
+			assert.NotEmpty(s.T(), profile.Description, name)
+		}
+	}
+}
+
+// TestLookupSpecProfile verifies the latest and pinned versions and unknown names.
+func (s *SpecProfileTestSuite) TestLookupSpecProfile() {
+	profile, err := LookupSpecProfile("Broadcast-HD")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "broadcast-hd@1", profile.Profile)
+	assert.Equal(s.T(), "broadcast-hd@1", profile.Rules[0].Source)
+
+	// The returned rules are a copy
+	profile.Rules[0].Equals = "hevc"
+	again, err := LookupSpecProfile("broadcast-hd@1")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "h264", again.Rules[0].Equals)
+
+	_, err = LookupSpecProfile("broadcast-hd@9")
+	assert.ErrorContains(s.T(), err, "available: 1")
+	_, err = LookupSpecProfile("dvd")
+	assert.ErrorContains(s.T(), err, "unknown profile")
+}
+
+// TestOverrideProfile verifies a spec file replacing, removing and adding rules of a profile.
+func (s *SpecProfileTestSuite) TestOverrideProfile() {
+	path := filepath.Join(s.tempDir, "channel.yaml")
+	require.NoError(s.T(), os.WriteFile(path, []byte(`profile: broadcast-hd@1
+rules:
+  - field: video.level
+    max: 4.2
+    description: Our encoders run at level 4.2
+  - field: audio.true_peak
+    disabled: true
+  - field: audio.language
+    equals: eng
+`), 0644))
+
+	spec, err := LoadSpec(path)
+	require.NoError(s.T(), err)
+	profile, err := LookupSpecProfile("broadcast-hd")
+	require.NoError(s.T(), err)
+
+	assert.Equal(s.T(), "Generic broadcast HD", spec.Name)
+	assert.Equal(s.T(), "broadcast-hd@1", spec.Profile)
+	require.Len(s.T(), spec.Rules, len(profile.Rules))
+	assert.Equal(s.T(), 4.2, *spec.Rules[2].Max)
+	assert.Empty(s.T(), spec.Rules[2].Source)
+	assert.Equal(s.T(), "broadcast-hd@1", spec.Rules[3].Source)
+	assert.Equal(s.T(), "audio.language", spec.Rules[len(spec.Rules)-1].Field)
+	for _, rule := range spec.Rules {
+		assert.NotEqual(s.T(), "audio.true_peak", rule.Field)
+	}
+
+	// A named override keeps the profile it is based on
+	named := MergeSpecs(profile, &Spec{Name: "Channel 5", Version: "2024-03"})
+	assert.Equal(s.T(), "Channel 5", named.Name)
+	assert.Equal(s.T(), "2024-03", named.Version)
+	assert.Equal(s.T(), "broadcast-hd@1", named.Profile)
+	assert.Equal(s.T(), profile.Description, named.Description)
+
+	require.NoError(s.T(), os.WriteFile(path, []byte("profile: dvd\n"), 0644))
+	_, err = LoadSpec(path)
+	assert.ErrorContains(s.T(), err, "unknown profile")
+}
+
+// TestSpecProfileSuite runs the delivery profile test suite.
+func TestSpecProfileSuite(t *testing.T) {
+	suite.Run(t, new(SpecProfileTestSuite))
+}
@@ -7,6 +7,7 @@ package ffmpeg
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -56,11 +57,12 @@ type QualityFrame struct {
 	FrameNumber  int          // The frame number (starting from 0)
 	Quality      float64      // A numerical quality score for the frame (higher is better)
 	QualityLevel QualityLevel // A categorical quality level
+	QP           float64      // The average quantization parameter the score is derived from
 }
 
 // FrameQualityAnalyzer is an interface for analyzing video quality on a frame-by-frame basis
 type FrameQualityAnalyzer interface {
-	Analyze(filePath string, frameQualityChan chan<- QualityFrame) error
+	Analyze(ctx context.Context, filePath string, frameQualityChan chan<- QualityFrame) error
 }
 
 // BaseQualityAnalyzer contains common functionality for quality analyzers
@@ -162,14 +164,15 @@ type XvidQualityAnalyzer struct {
 }
 
 // Analyze processes a video file and sends frame quality data to the provided channel
-func (a *XvidQualityAnalyzer) Analyze(filePath string, frameQualityChan chan<- QualityFrame) error {
+func (a *XvidQualityAnalyzer) Analyze(ctx context.Context, filePath string, frameQualityChan chan<- QualityFrame) error {
 	defer close(frameQualityChan)
 
 	// For Xvid, we'll look for quantizer information in FFmpeg debug output
 	// This regexp looks for lines that contain frame number and quantizer information
 	frameQPRegex := regexp.MustCompile(`(?i)frame=\s*(\d+).*q=\s*([0-9.]+)`)
 
-	cmd := exec.Command(
+	cmd := exec.CommandContext(
+		ctx,
 		a.FFmpegPath,
 		"-i", filePath,
 		"-f", "null",
@@ -211,6 +214,7 @@ func (a *XvidQualityAnalyzer) Analyze(filePath string, frameQualityChan chan<- Q
 				FrameNumber:  frameNumber,
 				Quality:      normalizedQuality,
 				QualityLevel: qualityLevel,
+				QP:           qp,
 			}
 		}
 	}
@@ -228,13 +232,14 @@ type DivxQualityAnalyzer struct {
 }
 
 // Analyze processes a video file and sends frame quality data to the provided channel
-func (a *DivxQualityAnalyzer) Analyze(filePath string, frameQualityChan chan<- QualityFrame) error {
+func (a *DivxQualityAnalyzer) Analyze(ctx context.Context, filePath string, frameQualityChan chan<- QualityFrame) error {
 	defer close(frameQualityChan)
 
 	// For DivX, we'll look for similar quantizer information as with Xvid
 	frameQPRegex := regexp.MustCompile(`(?i)frame=\s*(\d+).*q=\s*([0-9.]+)`)
 
-	cmd := exec.Command(
+	cmd := exec.CommandContext(
+		ctx,
 		a.FFmpegPath,
 		"-i", filePath,
 		"-f", "null",
@@ -280,6 +285,7 @@ func (a *DivxQualityAnalyzer) Analyze(filePath string, frameQualityChan chan<- Q
 				FrameNumber:  frameNumber,
 				Quality:      normalizedQuality,
 				QualityLevel: qualityLevel,
+				QP:           qp,
 			}
 
 			frameCount++
@@ -306,35 +312,35 @@ type H264QualityAnalyzer struct {
 }
 
 // Analyze processes a video file and sends frame quality data to the provided channel
-func (a *H264QualityAnalyzer) Analyze(filePath string, frameQualityChan chan<- QualityFrame) error {
+func (a *H264QualityAnalyzer) Analyze(ctx context.Context, filePath string, frameQualityChan chan<- QualityFrame) error {
 	defer close(frameQualityChan)
 
 	// Try the basic method first, which is more reliable than qp-hist
 	log.Printf("H264QualityAnalyzer: Starting basic analysis for file %s", filePath)
-	err := a.basicAnalyze(filePath, frameQualityChan)
+	err := a.basicAnalyze(ctx, filePath, frameQualityChan)
 	if err != nil {
 		log.Printf("H264QualityAnalyzer: Basic analysis failed with error: %v. Trying qp-hist method.", err)
-		return a.qpHistAnalyze(filePath, frameQualityChan)
+		return a.qpHistAnalyze(ctx, filePath, frameQualityChan)
 	}
 
 	return nil
 }
 
 // basicAnalyze provides a basic analysis method using standard FFmpeg output
-func (a *H264QualityAnalyzer) basicAnalyze(filePath string, frameQualityChan chan<- QualityFrame) error {
-	probeData, err := a.runFFprobeCommand(filePath)
+func (a *H264QualityAnalyzer) basicAnalyze(ctx context.Context, filePath string, frameQualityChan chan<- QualityFrame) error {
+	probeData, err := a.runFFprobeCommand(ctx, filePath)
 	if err != nil {
 		log.Printf("H264 Error with ffprobe: %v. Trying FFmpeg method.", err)
-		return a.ffmpegTraceAnalyze(filePath, frameQualityChan)
+		return a.ffmpegTraceAnalyze(ctx, filePath, frameQualityChan)
 	}
 
 	if len(probeData.Frames) == 0 {
 		log.Printf("H264 No frames found in ffprobe output. Trying FFmpeg method.")
-		return a.ffmpegTraceAnalyze(filePath, frameQualityChan)
+		return a.ffmpegTraceAnalyze(ctx, filePath, frameQualityChan)
 	}
 
 	// Extract QP information from FFmpeg output
-	frameQPS := a.extractH264QpValues(filePath)
+	frameQPS := a.extractH264QpValues(ctx, filePath)
 
 	// Process frames with QP information
 	frameCount, missingQpCount := a.processFramesWithQp(probeData.Frames, frameQPS, frameQualityChan)
@@ -345,7 +351,7 @@ func (a *H264QualityAnalyzer) basicAnalyze(filePath string, frameQualityChan cha
 			return fmt.Errorf("no QP data found for any frames (%d frames skipped)", missingQpCount)
 		}
 		log.Printf("H264 No frames were processed. Trying FFmpeg method.")
-		return a.ffmpegTraceAnalyze(filePath, frameQualityChan)
+		return a.ffmpegTraceAnalyze(ctx, filePath, frameQualityChan)
 	}
 
 	if missingQpCount > 0 {
@@ -371,8 +377,9 @@ type ProbeFrame struct {
 }
 
 // runFFprobeCommand executes the ffprobe command and parses the output
-func (a *H264QualityAnalyzer) runFFprobeCommand(filePath string) (*ProbeData, error) {
-	cmd := exec.Command(
+func (a *H264QualityAnalyzer) runFFprobeCommand(ctx context.Context, filePath string) (*ProbeData, error) {
+	cmd := exec.CommandContext(
+		ctx,
 		a.FFprobePath,
 		"-v", "error",
 		"-select_streams", "v:0",
@@ -403,8 +410,9 @@ func (a *H264QualityAnalyzer) runFFprobeCommand(filePath string) (*ProbeData, er
 }
 
 // extractH264QpValues extracts QP values from FFmpeg output
-func (a *H264QualityAnalyzer) extractH264QpValues(filePath string) map[int]float64 {
-	ffmpegCmd := exec.Command(
+func (a *H264QualityAnalyzer) extractH264QpValues(ctx context.Context, filePath string) map[int]float64 {
+	ffmpegCmd := exec.CommandContext(
+		ctx,
 		a.FFmpegPath,
 		"-i", filePath,
 		"-c:v", "copy",
@@ -495,6 +503,7 @@ func (a *H264QualityAnalyzer) processFramesWithQp(
 			FrameNumber:  frameNum,
 			Quality:      normalizedQuality,
 			QualityLevel: qualityLevel,
+			QP:           qp,
 		}
 		frameCount++
 
@@ -506,9 +515,9 @@ func (a *H264QualityAnalyzer) processFramesWithQp(
 }
 
 // ffmpegTraceAnalyze is the old basicAnalyze method, now used as a fallback
-func (a *H264QualityAnalyzer) ffmpegTraceAnalyze(filePath string, frameQualityChan chan<- QualityFrame) error {
+func (a *H264QualityAnalyzer) ffmpegTraceAnalyze(ctx context.Context, filePath string, frameQualityChan chan<- QualityFrame) error {
 	// Execute FFmpeg with debug output to extract frame QP information without re-encoding
-	output, err := a.runFFmpegTraceCommand(filePath)
+	output, err := a.runFFmpegTraceCommand(ctx, filePath)
 	if err != nil {
 		log.Printf("H264 FFmpeg command failed, but continuing to check output: %v", err)
 	}
@@ -519,7 +528,7 @@ func (a *H264QualityAnalyzer) ffmpegTraceAnalyze(filePath string, frameQualityCh
 	// If we have no frame information at all, try a different approach with select filter
 	if len(frameQPS) == 0 {
 		log.Printf("H264 No QP information found in trace output, trying select filter...")
-		return a.selectFilterAnalyze(filePath, frameQualityChan)
+		return a.selectFilterAnalyze(ctx, filePath, frameQualityChan)
 	}
 
 	// Send frame data to channel
@@ -534,8 +543,9 @@ func (a *H264QualityAnalyzer) ffmpegTraceAnalyze(filePath string, frameQualityCh
 }
 
 // runFFmpegTraceCommand executes the FFmpeg command to get trace output
-func (a *H264QualityAnalyzer) runFFmpegTraceCommand(filePath string) (string, error) {
-	cmd := exec.Command(
+func (a *H264QualityAnalyzer) runFFmpegTraceCommand(ctx context.Context, filePath string) (string, error) {
+	cmd := exec.CommandContext(
+		ctx,
 		a.FFmpegPath,
 		"-i", filePath,
 		"-c:v", "copy",
@@ -695,6 +705,7 @@ func (a *H264QualityAnalyzer) sendH264FrameDataToChannel(
 			FrameNumber:  frameNum,
 			Quality:      normalizedQuality,
 			QualityLevel: qualityLevel,
+			QP:           qp,
 		}
 		frameCount++
 	}
@@ -702,9 +713,10 @@ func (a *H264QualityAnalyzer) sendH264FrameDataToChannel(
 }
 
 // selectFilterAnalyze uses the select filter to get frame information
-func (a *H264QualityAnalyzer) selectFilterAnalyze(filePath string, _ chan<- QualityFrame) error {
+func (a *H264QualityAnalyzer) selectFilterAnalyze(ctx context.Context, filePath string, _ chan<- QualityFrame) error {
 	// Try using the select filter to extract frame info
-	cmd := exec.Command(
+	cmd := exec.CommandContext(
+		ctx,
 		a.FFmpegPath,
 		"-i", filePath,
 		"-vf", "select=1",
@@ -737,9 +749,9 @@ func (a *H264QualityAnalyzer) selectFilterAnalyze(filePath string, _ chan<- Qual
 }
 
 // qpHistAnalyze is the original analysis method using qp-hist filter
-func (a *H264QualityAnalyzer) qpHistAnalyze(filePath string, frameQualityChan chan<- QualityFrame) error {
+func (a *H264QualityAnalyzer) qpHistAnalyze(ctx context.Context, filePath string, frameQualityChan chan<- QualityFrame) error {
 	// Set up the command and start processing
-	stderr, cmd, err := a.setupQpHistCommand(filePath)
+	stderr, cmd, err := a.setupQpHistCommand(ctx, filePath)
 	if err != nil {
 		return err
 	}
@@ -750,12 +762,13 @@ func (a *H264QualityAnalyzer) qpHistAnalyze(filePath string, frameQualityChan ch
 	log.Printf("H264 qpHistAnalyze: Processed %d frames", frameCount)
 
 	// Wait for command to finish and handle any errors
-	return a.handleQpHistCommandCompletion(cmd, filePath, frameQualityChan)
+	return a.handleQpHistCommandCompletion(ctx, cmd, filePath, frameQualityChan)
 }
 
 // setupQpHistCommand prepares and starts the FFmpeg command for qp-hist analysis
-func (a *H264QualityAnalyzer) setupQpHistCommand(filePath string) (*bufio.Scanner, *exec.Cmd, error) {
-	cmd := exec.Command(
+func (a *H264QualityAnalyzer) setupQpHistCommand(ctx context.Context, filePath string) (*bufio.Scanner, *exec.Cmd, error) {
+	cmd := exec.CommandContext(
+		ctx,
 		a.FFmpegPath,
 		"-i", filePath,
 		"-vf", "qp-hist",
@@ -837,6 +850,7 @@ func (a *H264QualityAnalyzer) processCompleteFrame(frameNumber int, qpSum int, q
 		FrameNumber:  frameNumber,
 		Quality:      normalizedQuality,
 		QualityLevel: qualityLevel,
+		QP:           avgQP,
 	}
 
 	return 1
@@ -882,14 +896,14 @@ func (a *H264QualityAnalyzer) addQpSample(qpMatches []string, qpSum int, qpCount
 }
 
 // handleQpHistCommandCompletion waits for the command to complete and handles errors
-func (a *H264QualityAnalyzer) handleQpHistCommandCompletion(cmd *exec.Cmd, filePath string, frameQualityChan chan<- QualityFrame) error {
+func (a *H264QualityAnalyzer) handleQpHistCommandCompletion(ctx context.Context, cmd *exec.Cmd, filePath string, frameQualityChan chan<- QualityFrame) error {
 	if err := cmd.Wait(); err != nil {
 		// Check if it's a filter not found error, which would indicate qp-hist isn't supported
 		log.Printf("H264 qpHistAnalyze: FFmpeg error: %v", err)
 		if strings.Contains(err.Error(), "No such filter") || strings.Contains(err.Error(), "not found") {
 			log.Printf("qp-hist filter not supported, falling back to alternative method")
 			// Fall back to a simpler approach
-			return a.fallbackAnalyze(filePath, frameQualityChan)
+			return a.fallbackAnalyze(ctx, filePath, frameQualityChan)
 		}
 		return fmt.Errorf("error during ffmpeg execution: %w", err)
 	}
@@ -897,8 +911,8 @@ func (a *H264QualityAnalyzer) handleQpHistCommandCompletion(cmd *exec.Cmd, fileP
 }
 
 // fallbackAnalyze provides an alternative method when qp-hist filter is not available
-func (a *H264QualityAnalyzer) fallbackAnalyze(filePath string, frameQualityChan chan<- QualityFrame) error {
-	return a.basicAnalyze(filePath, frameQualityChan)
+func (a *H264QualityAnalyzer) fallbackAnalyze(ctx context.Context, filePath string, frameQualityChan chan<- QualityFrame) error {
+	return a.basicAnalyze(ctx, filePath, frameQualityChan)
 }
 
 // HevcQualityAnalyzer implements FrameQualityAnalyzer for HEVC (H.265) codec
@@ -907,35 +921,35 @@ type HevcQualityAnalyzer struct {
 }
 
 // Analyze processes a video file and sends frame quality data to the provided channel
-func (a *HevcQualityAnalyzer) Analyze(filePath string, frameQualityChan chan<- QualityFrame) error {
+func (a *HevcQualityAnalyzer) Analyze(ctx context.Context, filePath string, frameQualityChan chan<- QualityFrame) error {
 	defer close(frameQualityChan)
 
 	// Try the basic method first, which is more reliable than qp-hist
 	log.Printf("HevcQualityAnalyzer: Starting basic analysis for file %s", filePath)
-	err := a.basicAnalyze(filePath, frameQualityChan)
+	err := a.basicAnalyze(ctx, filePath, frameQualityChan)
 	if err != nil {
 		log.Printf("HevcQualityAnalyzer: Basic analysis failed with error: %v. Trying qp-hist method.", err)
-		return a.qpHistAnalyze(filePath, frameQualityChan)
+		return a.qpHistAnalyze(ctx, filePath, frameQualityChan)
 	}
 
 	return nil
 }
 
 // basicAnalyze provides a basic analysis method using standard FFmpeg output
-func (a *HevcQualityAnalyzer) basicAnalyze(filePath string, frameQualityChan chan<- QualityFrame) error {
-	probeData, err := a.runFFprobeCommand(filePath)
+func (a *HevcQualityAnalyzer) basicAnalyze(ctx context.Context, filePath string, frameQualityChan chan<- QualityFrame) error {
+	probeData, err := a.runFFprobeCommand(ctx, filePath)
 	if err != nil {
 		log.Printf("HEVC Error with ffprobe: %v. Trying FFmpeg method.", err)
-		return a.ffmpegTraceAnalyze(filePath, frameQualityChan)
+		return a.ffmpegTraceAnalyze(ctx, filePath, frameQualityChan)
 	}
 
 	if len(probeData.Frames) == 0 {
 		log.Printf("HEVC No frames found in ffprobe output. Trying FFmpeg method.")
-		return a.ffmpegTraceAnalyze(filePath, frameQualityChan)
+		return a.ffmpegTraceAnalyze(ctx, filePath, frameQualityChan)
 	}
 
 	// Extract QP information from FFmpeg output
-	frameQPS := a.extractHevcQpValues(filePath)
+	frameQPS := a.extractHevcQpValues(ctx, filePath)
 
 	// Process frames with QP information
 	frameCount, missingQpCount := a.processFramesWithQp(probeData.Frames, frameQPS, frameQualityChan)
@@ -946,7 +960,7 @@ func (a *HevcQualityAnalyzer) basicAnalyze(filePath string, frameQualityChan cha
 			return fmt.Errorf("no QP data found for any frames (%d frames skipped)", missingQpCount)
 		}
 		log.Printf("HEVC No frames were processed. Trying FFmpeg method.")
-		return a.ffmpegTraceAnalyze(filePath, frameQualityChan)
+		return a.ffmpegTraceAnalyze(ctx, filePath, frameQualityChan)
 	}
 
 	if missingQpCount > 0 {
@@ -959,8 +973,9 @@ func (a *HevcQualityAnalyzer) basicAnalyze(filePath string, frameQualityChan cha
 }
 
 // runFFprobeCommand executes the ffprobe command and parses the output
-func (a *HevcQualityAnalyzer) runFFprobeCommand(filePath string) (*ProbeData, error) {
-	cmd := exec.Command(
+func (a *HevcQualityAnalyzer) runFFprobeCommand(ctx context.Context, filePath string) (*ProbeData, error) {
+	cmd := exec.CommandContext(
+		ctx,
 		a.FFprobePath,
 		"-v", "error",
 		"-select_streams", "v:0",
@@ -991,8 +1006,9 @@ func (a *HevcQualityAnalyzer) runFFprobeCommand(filePath string) (*ProbeData, er
 }
 
 // extractHevcQpValues extracts QP values from FFmpeg output
-func (a *HevcQualityAnalyzer) extractHevcQpValues(filePath string) map[int]float64 {
-	ffmpegCmd := exec.Command(
+func (a *HevcQualityAnalyzer) extractHevcQpValues(ctx context.Context, filePath string) map[int]float64 {
+	ffmpegCmd := exec.CommandContext(
+		ctx,
 		a.FFmpegPath,
 		"-i", filePath,
 		"-c:v", "copy",
@@ -1106,6 +1122,7 @@ func (a *HevcQualityAnalyzer) processFramesWithQp(
 			FrameNumber:  frameNum,
 			Quality:      normalizedQuality,
 			QualityLevel: qualityLevel,
+			QP:           qp,
 		}
 		frameCount++
 
@@ -1117,9 +1134,9 @@ func (a *HevcQualityAnalyzer) processFramesWithQp(
 }
 
 // ffmpegTraceAnalyze is the old basicAnalyze method, now used as a fallback
-func (a *HevcQualityAnalyzer) ffmpegTraceAnalyze(filePath string, frameQualityChan chan<- QualityFrame) error {
+func (a *HevcQualityAnalyzer) ffmpegTraceAnalyze(ctx context.Context, filePath string, frameQualityChan chan<- QualityFrame) error {
 	// Execute FFmpeg with debug output to extract frame QP information without re-encoding
-	output, err := a.runFFmpegTraceCommand(filePath)
+	output, err := a.runFFmpegTraceCommand(ctx, filePath)
 	if err != nil {
 		log.Printf("HEVC FFmpeg command failed, but continuing to check output: %v", err)
 	}
@@ -1130,7 +1147,7 @@ func (a *HevcQualityAnalyzer) ffmpegTraceAnalyze(filePath string, frameQualityCh
 	// If we have no frame information at all, try a different approach with select filter
 	if len(frameQPS) == 0 {
 		log.Printf("HEVC No QP information found in trace output, trying select filter...")
-		return a.selectFilterAnalyze(filePath, frameQualityChan)
+		return a.selectFilterAnalyze(ctx, filePath, frameQualityChan)
 	}
 
 	// Send frame data to channel
@@ -1145,8 +1162,9 @@ func (a *HevcQualityAnalyzer) ffmpegTraceAnalyze(filePath string, frameQualityCh
 }
 
 // runFFmpegTraceCommand executes the FFmpeg command to get trace output
-func (a *HevcQualityAnalyzer) runFFmpegTraceCommand(filePath string) (string, error) {
-	cmd := exec.Command(
+func (a *HevcQualityAnalyzer) runFFmpegTraceCommand(ctx context.Context, filePath string) (string, error) {
+	cmd := exec.CommandContext(
+		ctx,
 		a.FFmpegPath,
 		"-i", filePath,
 		"-c:v", "copy",
@@ -1287,6 +1305,7 @@ func (a *HevcQualityAnalyzer) sendFrameDataToChannel(
 			FrameNumber:  frameNum,
 			Quality:      normalizedQuality,
 			QualityLevel: qualityLevel,
+			QP:           qp,
 		}
 		frameCount++
 	}
@@ -1294,9 +1313,10 @@ func (a *HevcQualityAnalyzer) sendFrameDataToChannel(
 }
 
 // selectFilterAnalyze uses the select filter to get frame information
-func (a *HevcQualityAnalyzer) selectFilterAnalyze(filePath string, _ chan<- QualityFrame) error {
+func (a *HevcQualityAnalyzer) selectFilterAnalyze(ctx context.Context, filePath string, _ chan<- QualityFrame) error {
 	// Try using the select filter to extract frame info
-	cmd := exec.Command(
+	cmd := exec.CommandContext(
+		ctx,
 		a.FFmpegPath,
 		"-i", filePath,
 		"-vf", "select=1",
@@ -1329,9 +1349,9 @@ func (a *HevcQualityAnalyzer) selectFilterAnalyze(filePath string, _ chan<- Qual
 }
 
 // qpHistAnalyze is the original analysis method using qp-hist filter
-func (a *HevcQualityAnalyzer) qpHistAnalyze(filePath string, frameQualityChan chan<- QualityFrame) error {
+func (a *HevcQualityAnalyzer) qpHistAnalyze(ctx context.Context, filePath string, frameQualityChan chan<- QualityFrame) error {
 	// Set up the command and start processing
-	stderr, cmd, err := a.setupQpHistCommand(filePath)
+	stderr, cmd, err := a.setupQpHistCommand(ctx, filePath)
 	if err != nil {
 		return err
 	}
@@ -1342,12 +1362,13 @@ func (a *HevcQualityAnalyzer) qpHistAnalyze(filePath string, frameQualityChan ch
 	log.Printf("HEVC qpHistAnalyze: Processed %d frames", frameCount)
 
 	// Wait for command to finish and handle any errors
-	return a.handleQpHistCommandCompletion(cmd, filePath, frameQualityChan)
+	return a.handleQpHistCommandCompletion(ctx, cmd, filePath, frameQualityChan)
 }
 
 // setupQpHistCommand prepares and starts the FFmpeg command for qp-hist analysis
-func (a *HevcQualityAnalyzer) setupQpHistCommand(filePath string) (*bufio.Scanner, *exec.Cmd, error) {
-	cmd := exec.Command(
+func (a *HevcQualityAnalyzer) setupQpHistCommand(ctx context.Context, filePath string) (*bufio.Scanner, *exec.Cmd, error) {
+	cmd := exec.CommandContext(
+		ctx,
 		a.FFmpegPath,
 		"-i", filePath,
 		"-vf", "qp-hist",
@@ -1429,6 +1450,7 @@ func (a *HevcQualityAnalyzer) processCompleteFrame(frameNumber int, qpSum int, q
 		FrameNumber:  frameNumber,
 		Quality:      normalizedQuality,
 		QualityLevel: qualityLevel,
+		QP:           avgQP,
 	}
 
 	return 1
@@ -1474,14 +1496,14 @@ func (a *HevcQualityAnalyzer) addQpSample(qpMatches []string, qpSum int, qpCount
 }
 
 // handleQpHistCommandCompletion waits for the command to complete and handles errors
-func (a *HevcQualityAnalyzer) handleQpHistCommandCompletion(cmd *exec.Cmd, filePath string, frameQualityChan chan<- QualityFrame) error {
+func (a *HevcQualityAnalyzer) handleQpHistCommandCompletion(ctx context.Context, cmd *exec.Cmd, filePath string, frameQualityChan chan<- QualityFrame) error {
 	if err := cmd.Wait(); err != nil {
 		// Check if it's a filter not found error, which would indicate qp-hist isn't supported
 		log.Printf("HEVC qpHistAnalyze: FFmpeg error: %v", err)
 		if strings.Contains(err.Error(), "No such filter") || strings.Contains(err.Error(), "not found") {
 			log.Printf("qp-hist filter not supported, falling back to alternative method")
 			// Fall back to a simpler approach
-			return a.fallbackAnalyze(filePath, frameQualityChan)
+			return a.fallbackAnalyze(ctx, filePath, frameQualityChan)
 		}
 		return fmt.Errorf("error during ffmpeg execution: %w", err)
 	}
@@ -1490,9 +1512,9 @@ func (a *HevcQualityAnalyzer) handleQpHistCommandCompletion(cmd *exec.Cmd, fileP
 }
 
 // fallbackAnalyze provides an alternative method when qp-hist filter is not available
-func (a *HevcQualityAnalyzer) fallbackAnalyze(filePath string, frameQualityChan chan<- QualityFrame) error {
+func (a *HevcQualityAnalyzer) fallbackAnalyze(ctx context.Context, filePath string, frameQualityChan chan<- QualityFrame) error {
 	log.Printf("HEVC fallbackAnalyze: Using basic method as fallback")
-	return a.basicAnalyze(filePath, frameQualityChan)
+	return a.basicAnalyze(ctx, filePath, frameQualityChan)
 }
 
 // NewQualityAnalyzer is a factory function that returns the appropriate FrameQualityAnalyzer
@@ -1,6 +1,7 @@
 package ffmpeg
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -141,6 +142,25 @@ func (suite *QualityAnalyzerTestSuite) TestNewQualityAnalyzerWithUnsupportedCode
 	suite.Nil(analyzer, "Analyzer should be nil for file with no codec")
 }
 
+// TestAnalyzeCancel verifies ending the context kills the decode instead of letting it run to the end.
+func (suite *QualityAnalyzerTestSuite) TestAnalyzeCancel() {
+	// A decoder that never finishes on its own
+	decoder := filepath.Join(suite.T().TempDir(), "ffmpeg")
+	suite.Require().NoError(os.WriteFile(decoder, []byte("#!/bin/sh\nexec sleep 30\n"), 0o755))
+	analyzer := &XvidQualityAnalyzer{BaseQualityAnalyzer{FFmpegPath: decoder}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	frameChan := make(chan QualityFrame, 1)
+	start := time.Now()
+	err := analyzer.Analyze(ctx, "movie.avi", frameChan)
+
+	suite.Error(err)
+	suite.Less(time.Since(start), 10*time.Second)
+	_, open := <-frameChan
+	suite.False(open, "The frame channel should be closed")
+}
+
 // TestQualityFrameChannel tests that frames are correctly sent to the channel
 func (suite *QualityAnalyzerTestSuite) TestQualityFrameChannel() {
 	// First ensure FFmpeg is installed
@@ -241,7 +261,7 @@ func (suite *QualityAnalyzerTestSuite) startAnalysis(
 	go func() {
 		defer close(doneChan)
 
-		err := analyzer.Analyze(filePath, frameChan)
+		err := analyzer.Analyze(context.Background(), filePath, frameChan)
 		// Use t.Log instead of assertions in goroutines to avoid panic
 		if err != nil {
 			t.Logf("Error analyzing %s: %v", codecName, err)
@@ -671,7 +691,7 @@ func (suite *QualityAnalyzerTestSuite) performAnalysis(
 	// Start analysis in a goroutine
 	go func() {
 		defer close(doneChan)
-		analyzeErr = analyzer.Analyze(testFile, frameChan)
+		analyzeErr = analyzer.Analyze(context.Background(), testFile, frameChan)
 		if analyzeErr != nil {
 			t.Logf("Error analyzing video: %v", analyzeErr)
 		}
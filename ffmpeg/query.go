@@ -0,0 +1,217 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Private variables (alphabetical)
+
+var (
+	// queryConditionRegex splits a query condition into its field, operator and value.
+	queryConditionRegex = regexp.MustCompile(`^([A-Za-z_.]+)\s*(!=|>=|<=|=|>|<|~)\s*(.+)$`)
+
+	// queryFieldAliases maps the short field names of queries to spec fields.
+	queryFieldAliases = map[string]string{
+		"audio_codec":     "audio.codec",
+		"avg_bitrate":     "video.average_bitrate",
+		"bit_depth":       "video.bit_depth",
+		"bitrate":         "container.bitrate",
+		"channels":        "audio.channels",
+		"codec":           "video.codec",
+		"duration":        "container.duration",
+		"format":          "container.format",
+		"frame_rate":      "video.frame_rate",
+		"frame_rate_mode": "video.frame_rate_mode",
+		"height":          "video.height",
+		"language":        "audio.language",
+		"level":           "video.level",
+		"loudness":        "audio.integrated_loudness",
+		"peak_bitrate":    "video.peak_bitrate",
+		"profile":         "video.profile",
+		"resolution":      "video.resolution",
+		"scan_type":       "video.scan_type",
+		"size":            "container.size",
+		"width":           "video.width",
+	}
+
+	// queryNumberSuffixes are the multipliers of the SI suffixes accepted after query numbers.
+	queryNumberSuffixes = map[byte]float64{'k': 1e3, 'K': 1e3, 'm': 1e6, 'M': 1e6, 'g': 1e9, 'G': 1e9}
+)
+
+// Private functions (alphabetical)
+
+// parseQueryCondition parses a condition such as avg_bitrate>8M.
+func parseQueryCondition(text string) (queryCondition, error) {
+	match := queryConditionRegex.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		return queryCondition{}, fmt.Errorf("invalid condition %q, expected FIELD OPERATOR VALUE", text)
+	}
+
+	field := strings.ToLower(match[1])
+	if alias, ok := queryFieldAliases[field]; ok {
+		field = alias
+	}
+	if _, ok := specFields[field]; !ok {
+		return queryCondition{}, fmt.Errorf("unknown field %q (available: %s)", match[1], strings.Join(QueryFieldNames(), ", "))
+	}
+
+	condition := queryCondition{field: field, operator: match[2]}
+	value := strings.TrimSpace(match[3])
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		condition.value = unquoted
+	} else if number, ok := parseQueryNumber(value); ok {
+		condition.value = number
+	} else {
+		condition.value = value
+	}
+
+	if _, isNumber := condition.value.(float64); !isNumber && strings.ContainsAny(condition.operator, "<>") {
+		return queryCondition{}, fmt.Errorf("%s needs a number, got %q", condition.operator, value)
+	}
+	return condition, nil
+}
+
+// parseQueryNumber parses a number with an optional k, M or G suffix, so 8M reads 8000000.
+func parseQueryNumber(text string) (float64, bool) {
+	multiplier := 1.0
+	if factor, ok := queryNumberSuffixes[text[len(text)-1]]; ok && len(text) > 1 {
+		multiplier, text = factor, text[:len(text)-1]
+	}
+	number, ok := parseSpecNumber(text)
+	return number * multiplier, ok
+}
+
+// splitQuery splits a query on the and and or keywords outside quotes. The conditions and the
+// lowercased keywords alternate in the result, so a missing condition is an empty string.
+func splitQuery(text string) []string {
+	var words []string
+	var word strings.Builder
+	quoted := false
+	for _, r := range text {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if unicode.IsSpace(r) && !quoted {
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+			continue
+		}
+		word.WriteRune(r)
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+
+	parts := []string{""}
+	for _, word := range words {
+		if keyword := strings.ToLower(word); keyword == "and" || keyword == "or" {
+			parts = append(parts, keyword, "")
+			continue
+		}
+		parts[len(parts)-1] = strings.TrimSpace(parts[len(parts)-1] + " " + word)
+	}
+	return parts
+}
+
+// Public functions (alphabetical)
+
+// ParseQuery parses a query made of conditions such as codec=hevc or avg_bitrate>8M joined by
+// and and or, where and binds tighter. Fields are spec fields or their short names; the
+// operators are =, !=, <, <=, >, >= and ~ (contains). Numbers accept the k, M and G suffixes and
+// strings with spaces are quoted.
+func ParseQuery(text string) (*Query, error) {
+	query := &Query{Text: text}
+	clause := []queryCondition{}
+	parts := splitQuery(text)
+	for i := 0; i < len(parts); i += 2 {
+		if strings.TrimSpace(parts[i]) == "" {
+			return nil, fmt.Errorf("invalid query %q: missing condition", text)
+		}
+		condition, err := parseQueryCondition(parts[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid query %q: %w", text, err)
+		}
+		clause = append(clause, condition)
+		if i+1 == len(parts) || parts[i+1] == "or" {
+			query.clauses = append(query.clauses, clause)
+			clause = []queryCondition{}
+		}
+	}
+	return query, nil
+}
+
+// QueryFieldNames returns the short field names of queries in alphabetical order. Every spec
+// field can be queried too.
+func QueryFieldNames() []string {
+	names := make([]string, 0, len(queryFieldAliases))
+	for name := range queryFieldAliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Private methods (alphabetical)
+
+// matches reports whether a measured value satisfies the condition. Values that were not
+// measured never match.
+func (c queryCondition) matches(value any) bool {
+	if value == nil {
+		return false
+	}
+	switch c.operator {
+	case "=":
+		return specValuesEqual(value, c.value, 0)
+	case "!=":
+		return !specValuesEqual(value, c.value, 0)
+	case "~":
+		return strings.Contains(strings.ToLower(formatSpecValue(value)), strings.ToLower(formatSpecValue(c.value)))
+	}
+
+	number, ok := parseSpecNumber(value)
+	expected := c.value.(float64)
+	if !ok {
+		return false
+	}
+	switch c.operator {
+	case "<":
+		return number < expected
+	case "<=":
+		return number <= expected
+	case ">":
+		return number > expected
+	default:
+		return number >= expected
+	}
+}
+
+// Public methods (alphabetical)
+
+// Match reports whether the analysis of a file satisfies the query.
+func (q *Query) Match(info *ContainerInfo) bool {
+	for _, clause := range q.clauses {
+		matched := true
+		for _, condition := range clause {
+			found := false
+			for _, value := range specFields[condition.field].values(info) {
+				found = found || condition.matches(value.value)
+			}
+			matched = matched && found
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
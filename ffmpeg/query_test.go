@@ -0,0 +1,84 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the result store queries.
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// QueryTestSuite defines the test suite for Query.
+// It matches synthetic analysis results so no sample media is required.
+type QueryTestSuite struct {
+	suite.Suite
+	hevc *ContainerInfo // Analysis of a 2160p HEVC file with two audio streams
+	h264 *ContainerInfo // Analysis of a 1080p H.264 file without measured bitrate
+}
+
+// SetupTest creates the analyses of two files.
+func (s *QueryTestSuite) SetupTest() {
+	s.hevc = &ContainerInfo{
+		General:      GeneralInfo{Format: "matroska,webm", DurationF: 5400},
+		VideoStreams: []VideoStream{{Format: "hevc", FormatProfile: "Main 10", Width: 3840, Height: 2160, FrameRate: 24}},
+		AudioStreams: []AudioStream{{Format: "eac3", Language: "eng"}, {Format: "aac", Language: "ita"}},
+		Bitrate:      &BitrateStats{AverageBitrate: 12500000, PeakBitrate: 30000000},
+	}
+	s.h264 = &ContainerInfo{
+		General:      GeneralInfo{Format: "mpegts", DurationF: 60},
+		VideoStreams: []VideoStream{{Format: "h264", FormatProfile: "High", Width: 1920, Height: 1080, FrameRate: 25}},
+		AudioStreams: []AudioStream{{Format: "aac", Language: "eng"}},
+	}
+}
+
+// TestMatch verifies the operators, the number suffixes and the stream semantics.
+func (s *QueryTestSuite) TestMatch() {
+	cases := []struct {
+		query string
+		hevc  bool
+		h264  bool
+	}{
+		{"codec=hevc and avg_bitrate>8M", true, false},
+		{"codec=HEVC AND avg_bitrate>20M", false, false},
+		{"video.height>=2160 or duration<120", true, true},
+		{"audio_codec=aac", true, true},
+		{"audio_codec=aac and language=ita", true, false},
+		{"profile=\"main 10\"", true, false},
+		{"profile~main", true, false},
+		{"avg_bitrate<8M", false, false},
+		{"codec!=hevc", false, true},
+		{"format=mpegts or resolution=3840x2160 and peak_bitrate<=30M", true, true},
+		{"frame_rate=23.976", false, false},
+		{"width>1.5k", true, true},
+	}
+	for _, c := range cases {
+		query, err := ParseQuery(c.query)
+		require.NoError(s.T(), err, c.query)
+		assert.Equal(s.T(), c.hevc, query.Match(s.hevc), c.query)
+		assert.Equal(s.T(), c.h264, query.Match(s.h264), c.query)
+	}
+}
+
+// TestParseErrors verifies that invalid queries are rejected with their cause.
+func (s *QueryTestSuite) TestParseErrors() {
+	_, err := ParseQuery("colour=bt709")
+	assert.ErrorContains(s.T(), err, `unknown field "colour"`)
+	_, err = ParseQuery("codec>hevc")
+	assert.ErrorContains(s.T(), err, "> needs a number")
+	_, err = ParseQuery("codec=hevc and")
+	assert.Error(s.T(), err)
+	_, err = ParseQuery("and codec=hevc")
+	assert.ErrorContains(s.T(), err, "missing condition")
+	_, err = ParseQuery("")
+	assert.ErrorContains(s.T(), err, "missing condition")
+	_, err = ParseQuery("codec hevc")
+	assert.ErrorContains(s.T(), err, "expected FIELD OPERATOR VALUE")
+	assert.Contains(s.T(), QueryFieldNames(), "avg_bitrate")
+}
+
+// TestQuerySuite runs the Query test suite.
+func TestQuerySuite(t *testing.T) {
+	suite.Run(t, new(QueryTestSuite))
+}
@@ -0,0 +1,209 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Private functions (alphabetical)
+
+// addScanTypeCounts adds the classifications of other to counts.
+func addScanTypeCounts(counts *ScanTypeCounts, other ScanTypeCounts) {
+	counts.Frames += other.Frames
+	counts.Progressive += other.Progressive
+	counts.TFF += other.TFF
+	counts.BFF += other.BFF
+	counts.Undetermined += other.Undetermined
+	counts.RepeatedTop += other.RepeatedTop
+	counts.RepeatedBottom += other.RepeatedBottom
+}
+
+// classifyScanType derives the scan type of a part of a stream from its idet classifications.
+// Interlaced frames are compared with all determined frames; a stream that is not fully
+// interlaced but repeats fields regularly is telecined.
+func classifyScanType(counts ScanTypeCounts) (string, float64) {
+	determined := counts.Progressive + counts.TFF + counts.BFF
+	if counts.Frames == 0 || float64(determined) < float64(counts.Frames)*scanTypeMinDetermined {
+		return ScanTypeUndetermined, 0
+	}
+
+	interlaced := counts.TFF + counts.BFF
+	interlacedRatio := float64(interlaced) / float64(determined)
+	repeatedRatio := float64(counts.RepeatedTop+counts.RepeatedBottom) / float64(counts.Frames)
+
+	switch {
+	case interlacedRatio >= scanTypeInterlacedRatio:
+		if counts.BFF > counts.TFF {
+			return ScanTypeInterlacedBFF, float64(counts.BFF) / float64(determined)
+		}
+		return ScanTypeInterlacedTFF, float64(counts.TFF) / float64(determined)
+	case repeatedRatio >= scanTypeTelecineRepeatRatio:
+		return ScanTypeTelecined, math.Min(repeatedRatio/scanTypeTelecineExpectedRatio, 1)
+	case interlacedRatio <= scanTypeProgressiveRatio:
+		return ScanTypeProgressive, float64(counts.Progressive) / float64(determined)
+	default:
+		// A half and half mix is the most certain mix
+		return ScanTypeMixed, 1 - math.Abs(interlacedRatio-0.5)*2
+	}
+}
+
+// mergeScanTypeSegments classifies the segments and joins consecutive segments with the same scan type.
+func mergeScanTypeSegments(segments []ScanTypeSegment) []ScanTypeSegment {
+	var merged []ScanTypeSegment
+	for _, segment := range segments {
+		segment.ScanType, segment.Confidence = classifyScanType(segment.Counts)
+
+		if last := len(merged) - 1; last >= 0 && merged[last].ScanType == segment.ScanType {
+			merged[last].End = segment.End
+			addScanTypeCounts(&merged[last].Counts, segment.Counts)
+			_, merged[last].Confidence = classifyScanType(merged[last].Counts)
+			continue
+		}
+		merged = append(merged, segment)
+	}
+	return merged
+}
+
+// parseIdetMetadata reads the per-frame idet classifications printed by the metadata filter
+// and groups them in segments of the given duration. Segments are returned unclassified.
+func parseIdetMetadata(r io.Reader, segmentDuration float64, frameDuration float64) ([]ScanTypeSegment, error) {
+	var segments []ScanTypeSegment
+	firstTime := math.NaN()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if match := metadataFrameRegex.FindStringSubmatch(line); match != nil {
+			frameTime, _ := strconv.ParseFloat(match[2], 64)
+			if math.IsNaN(firstTime) {
+				firstTime = frameTime
+			}
+
+			segmentStart := firstTime + math.Floor((frameTime-firstTime)/segmentDuration)*segmentDuration
+			if len(segments) == 0 || segmentStart > segments[len(segments)-1].Start {
+				segments = append(segments, ScanTypeSegment{Start: segmentStart})
+			}
+			segment := &segments[len(segments)-1]
+			segment.End = frameTime + frameDuration
+			segment.Counts.Frames++
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found || len(segments) == 0 {
+			continue
+		}
+		counts := &segments[len(segments)-1].Counts
+
+		switch key {
+		case "lavfi.idet.multiple.current_frame":
+			switch value {
+			case "progressive":
+				counts.Progressive++
+			case "tff":
+				counts.TFF++
+			case "bff":
+				counts.BFF++
+			default:
+				counts.Undetermined++
+			}
+		case "lavfi.idet.repeated.current_frame":
+			switch value {
+			case "top":
+				counts.RepeatedTop++
+			case "bottom":
+				counts.RepeatedBottom++
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading idet output: %w", err)
+	}
+	return segments, nil
+}
+
+// Public functions (alphabetical)
+
+// NewScanTypeAnalyzer creates a new ScanTypeAnalyzer with the default segment duration.
+func NewScanTypeAnalyzer(ffmpegInfo *FFmpegInfo) (*ScanTypeAnalyzer, error) {
+	if ffmpegInfo == nil || !ffmpegInfo.Installed {
+		return nil, fmt.Errorf("ffmpeg not available")
+	}
+
+	return &ScanTypeAnalyzer{
+		FFmpegPath:      ffmpegInfo.Path,
+		SegmentDuration: scanTypeSegmentDuration,
+	}, nil
+}
+
+// Public methods (alphabetical)
+
+// Analyze decodes a video stream through the idet filter and classifies it as progressive,
+// interlaced (TFF or BFF), telecined or mixed, overall and per segment. Soft telecine
+// (repeat flags in the bitstream) decodes to progressive frames and is reported as progressive.
+func (a *ScanTypeAnalyzer) Analyze(ctx context.Context, filePath string, stream VideoStream) (*ScanTypeReport, error) {
+	cmd := exec.CommandContext(
+		ctx,
+		a.FFmpegPath,
+		"-hide_banner",
+		"-nostats",
+		"-v", "error",
+		"-i", filePath,
+		"-map", fmt.Sprintf("0:%d", stream.Index),
+		"-filter:v", "idet,metadata=mode=print:file=-",
+		"-f", "null",
+		"-",
+	)
+
+	// The metadata filter prints the idet classification of every frame on stdout
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start FFmpeg: %w", err)
+	}
+
+	frameDuration := 0.0
+	if stream.FrameRate > 0 {
+		frameDuration = 1 / stream.FrameRate
+	}
+	segments, parseErr := parseIdetMetadata(stdout, a.SegmentDuration, frameDuration)
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("error running idet filter: %w", err)
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	report := &ScanTypeReport{StreamIndex: stream.Index}
+	for _, segment := range segments {
+		addScanTypeCounts(&report.Counts, segment.Counts)
+	}
+	report.ScanType, report.Confidence = classifyScanType(report.Counts)
+	report.Segments = mergeScanTypeSegments(segments)
+	return report, nil
+}
+
+// Apply stores the report on the video stream and replaces the scan type declared by the
+// container with the detected one. An undetermined result keeps the declared scan type.
+func (r *ScanTypeReport) Apply(stream *VideoStream) {
+	stream.ScanAnalysis = r
+	if r.ScanType == ScanTypeUndetermined {
+		return
+	}
+	stream.ScanType = r.ScanType
+	stream.ScanTypeConfidence = r.Confidence
+}
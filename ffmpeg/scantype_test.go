@@ -0,0 +1,135 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the scan type analyzer.
+package ffmpeg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// ScanTypeAnalyzerTestSuite defines the test suite for ScanTypeAnalyzer.
+// It classifies synthetic idet output so no sample media is required.
+type ScanTypeAnalyzerTestSuite struct {
+	suite.Suite
+}
+
+// idetTestOutput renders the metadata printed for frames at 25 fps, using classify to
+// pick the multiple-frame detection and the repeated field of every frame.
+func idetTestOutput(frames int, classify func(n int) (string, string)) string {
+	var sb strings.Builder
+	for n := 0; n < frames; n++ {
+		multiple, repeated := classify(n)
+		fmt.Fprintf(&sb, "frame:%-4d pts:%-8d pts_time:%g\n", n, n, float64(n)/25)
+		fmt.Fprintf(&sb, "lavfi.idet.single.current_frame=%s\n", multiple)
+		fmt.Fprintf(&sb, "lavfi.idet.multiple.current_frame=%s\n", multiple)
+		fmt.Fprintf(&sb, "lavfi.idet.repeated.current_frame=%s\n", repeated)
+	}
+	return sb.String()
+}
+
+// TestClassify verifies the classification rules.
+func (s *ScanTypeAnalyzerTestSuite) TestClassify() {
+	testCases := []struct {
+		name       string
+		counts     ScanTypeCounts
+		scanType   string
+		confidence float64
+	}{
+		{"progressive", ScanTypeCounts{Frames: 100, Progressive: 95, TFF: 5}, ScanTypeProgressive, 0.95},
+		{"tff", ScanTypeCounts{Frames: 100, TFF: 90, Progressive: 10}, ScanTypeInterlacedTFF, 0.9},
+		{"bff", ScanTypeCounts{Frames: 100, BFF: 98, TFF: 2}, ScanTypeInterlacedBFF, 0.98},
+		{"telecined", ScanTypeCounts{Frames: 100, Progressive: 60, TFF: 40, RepeatedTop: 20, RepeatedBottom: 20}, ScanTypeTelecined, 1},
+		{"mixed", ScanTypeCounts{Frames: 100, Progressive: 50, TFF: 50}, ScanTypeMixed, 1},
+		{"undetermined", ScanTypeCounts{Frames: 100, Progressive: 5, Undetermined: 95}, ScanTypeUndetermined, 0},
+		{"empty", ScanTypeCounts{}, ScanTypeUndetermined, 0},
+	}
+
+	for _, tc := range testCases {
+		scanType, confidence := classifyScanType(tc.counts)
+		assert.Equal(s.T(), tc.scanType, scanType, tc.name)
+		assert.InDelta(s.T(), tc.confidence, confidence, 1e-9, tc.name)
+	}
+}
+
+// TestParseAndMerge verifies segmentation and the merge of segments with the same scan type.
+func (s *ScanTypeAnalyzerTestSuite) TestParseAndMerge() {
+	// 25 s progressive, then 15 s interlaced TFF
+	output := idetTestOutput(1000, func(n int) (string, string) {
+		if n < 625 {
+			return "progressive", "neither"
+		}
+		return "tff", "neither"
+	})
+
+	segments, err := parseIdetMetadata(strings.NewReader(output), 10, 0.04)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), segments, 4)
+	assert.Equal(s.T(), 250, segments[0].Counts.Frames)
+	assert.InDelta(s.T(), 20.0, segments[2].Start, 1e-9)
+	assert.Equal(s.T(), 125, segments[2].Counts.Progressive)
+	assert.Equal(s.T(), 125, segments[2].Counts.TFF)
+
+	merged := mergeScanTypeSegments(segments)
+	require.Len(s.T(), merged, 3)
+	assert.Equal(s.T(), ScanTypeProgressive, merged[0].ScanType)
+	assert.InDelta(s.T(), 20.0, merged[0].End, 1e-9)
+	assert.Equal(s.T(), 500, merged[0].Counts.Frames)
+	assert.Equal(s.T(), ScanTypeMixed, merged[1].ScanType)
+	assert.Equal(s.T(), ScanTypeInterlacedTFF, merged[2].ScanType)
+	assert.InDelta(s.T(), 40.0, merged[2].End, 1e-9)
+}
+
+// TestTelecine verifies the detection of a 3:2 pulldown pattern.
+func (s *ScanTypeAnalyzerTestSuite) TestTelecine() {
+	// Every 5 frames: 3 progressive and 2 combed frames, 2 of them repeating a field
+	output := idetTestOutput(250, func(n int) (string, string) {
+		switch n % 5 {
+		case 2:
+			return "tff", "top"
+		case 3:
+			return "tff", "neither"
+		case 4:
+			return "progressive", "bottom"
+		}
+		return "progressive", "neither"
+	})
+
+	segments, err := parseIdetMetadata(strings.NewReader(output), 10, 0.04)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), segments, 1)
+
+	scanType, confidence := classifyScanType(segments[0].Counts)
+	assert.Equal(s.T(), ScanTypeTelecined, scanType)
+	assert.InDelta(s.T(), 1.0, confidence, 1e-9)
+}
+
+// TestApply verifies that a detected scan type overrides the container value.
+func (s *ScanTypeAnalyzerTestSuite) TestApply() {
+	stream := VideoStream{ScanType: "unknown", FieldOrder: "unknown"}
+	report := &ScanTypeReport{ScanType: ScanTypeInterlacedBFF, Confidence: 0.97}
+	report.Apply(&stream)
+
+	assert.Equal(s.T(), ScanTypeInterlacedBFF, stream.ScanType)
+	assert.Equal(s.T(), 0.97, stream.ScanTypeConfidence)
+	assert.Equal(s.T(), "unknown", stream.FieldOrder)
+	assert.Same(s.T(), report, stream.ScanAnalysis)
+
+	stream = VideoStream{ScanType: "progressive"}
+	(&ScanTypeReport{ScanType: ScanTypeUndetermined}).Apply(&stream)
+	assert.Equal(s.T(), "progressive", stream.ScanType)
+	assert.Zero(s.T(), stream.ScanTypeConfidence)
+	assert.NotNil(s.T(), stream.ScanAnalysis)
+
+	_, err := NewScanTypeAnalyzer(nil)
+	assert.Error(s.T(), err)
+}
+
+// TestScanTypeAnalyzerSuite runs the ScanTypeAnalyzer test suite.
+func TestScanTypeAnalyzerSuite(t *testing.T) {
+	suite.Run(t, new(ScanTypeAnalyzerTestSuite))
+}
@@ -0,0 +1,286 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Private functions (alphabetical)
+
+// newSceneReport builds the scenes of a stream from its scene cuts. The first scene starts
+// at the beginning of the stream and the last one ends with it.
+func newSceneReport(stream VideoStream, threshold float64, cuts []sceneCut) *SceneReport {
+	report := &SceneReport{
+		StreamIndex: stream.Index,
+		FrameRate:   stream.FrameRate,
+		Threshold:   threshold,
+		current:     -1,
+	}
+
+	// A cut on the first frame only marks the start of the stream
+	if len(cuts) > 0 && cuts[0].frame == 0 {
+		cuts = cuts[1:]
+	}
+
+	report.Scenes = append(report.Scenes, Scene{Start: stream.StartTime, FrameTypes: make(map[string]int)})
+	for _, cut := range cuts {
+		report.Scenes = append(report.Scenes, Scene{
+			StartFrame: cut.frame,
+			Start:      cut.time,
+			CutScore:   cut.score,
+			FrameTypes: make(map[string]int),
+		})
+	}
+
+	for i := range report.Scenes {
+		report.Scenes[i].Index = i
+		if i+1 < len(report.Scenes) {
+			report.Scenes[i].EndFrame = report.Scenes[i+1].StartFrame
+			report.Scenes[i].End = report.Scenes[i+1].Start
+		} else if stream.Duration > 0 {
+			report.Scenes[i].End = stream.StartTime + stream.Duration
+		}
+	}
+	return report
+}
+
+// parseScdetMetadata reads the scores printed by the metadata filter after scdet and returns
+// the frames scdet marked as scene cuts.
+func parseScdetMetadata(r io.Reader) ([]sceneCut, error) {
+	var cuts []sceneCut
+	var current sceneCut
+	isCut := false
+
+	flush := func() {
+		if isCut {
+			cuts = append(cuts, current)
+		}
+		isCut = false
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if match := metadataFrameRegex.FindStringSubmatch(line); match != nil {
+			flush()
+			frameNumber, _ := strconv.Atoi(match[1])
+			frameTime, _ := strconv.ParseFloat(match[2], 64)
+			current = sceneCut{frame: frameNumber, time: frameTime}
+			continue
+		}
+
+		key, rawValue, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "lavfi.scd.score":
+			current.score, _ = strconv.ParseFloat(rawValue, 64)
+		case "lavfi.scd.time":
+			isCut = true
+		}
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading scene detection output: %w", err)
+	}
+	return cuts, nil
+}
+
+// Public functions (alphabetical)
+
+// NewSceneAnalyzer creates a new SceneAnalyzer with the default scene cut threshold.
+func NewSceneAnalyzer(ffmpegInfo *FFmpegInfo) (*SceneAnalyzer, error) {
+	if ffmpegInfo == nil || !ffmpegInfo.Installed {
+		return nil, fmt.Errorf("ffmpeg not available")
+	}
+
+	return &SceneAnalyzer{
+		FFmpegPath: ffmpegInfo.Path,
+		Threshold:  sceneDefaultThreshold,
+	}, nil
+}
+
+// Private methods (alphabetical)
+
+// sceneIndex returns the scene containing a frame, or -1 when the report has no scenes.
+func (r *SceneReport) sceneIndex(frameNumber int) int {
+	if len(r.Scenes) == 0 {
+		return -1
+	}
+	next := sort.Search(len(r.Scenes), func(i int) bool { return r.Scenes[i].StartFrame > frameNumber })
+	return max(next-1, 0)
+}
+
+// Public methods (alphabetical)
+
+// AddFrame adds the size and type of a frame measured by a BitrateAnalyzer to its scene.
+// Frames are expected in presentation order, as the analyzer sends them.
+func (r *SceneReport) AddFrame(frame FrameBitrateInfo) {
+	index := r.sceneIndex(frame.FrameNumber)
+	if index < 0 {
+		return
+	}
+	if index != r.current {
+		r.window = r.window[:0]
+		r.windowSum = 0
+		r.current = index
+	}
+
+	scene := &r.Scenes[index]
+	scene.Frames++
+	scene.TotalBits += frame.Bitrate
+	scene.FrameTypes[frame.FrameType]++
+	if frame.FrameNumber == scene.StartFrame {
+		scene.KeyframeAtCut = frame.FrameType == "I"
+	}
+
+	// The peak is measured over full seconds of frames within the scene
+	windowSize := max(int(math.Round(r.FrameRate)), 1)
+	r.window = append(r.window, frame.Bitrate)
+	r.windowSum += frame.Bitrate
+	if len(r.window) > windowSize {
+		r.windowSum -= r.window[0]
+		r.window = r.window[1:]
+	}
+	if len(r.window) == windowSize && r.FrameRate > 0 {
+		scene.PeakBitrate = math.Max(scene.PeakBitrate, float64(r.windowSum)*r.FrameRate/float64(windowSize))
+	}
+}
+
+// AddQualityFrame adds the QP of a frame measured by a FrameQualityAnalyzer to its scene.
+// Frames may be added in any order.
+func (r *SceneReport) AddQualityFrame(frame QualityFrame) {
+	index := r.sceneIndex(frame.FrameNumber)
+	if index < 0 || frame.QP <= 0 {
+		return
+	}
+
+	// AverageQP holds the sum until Finish is called
+	r.Scenes[index].AverageQP += frame.QP
+	r.Scenes[index].QPFrames++
+}
+
+// Analyze decodes a video stream through the scdet filter and returns its scenes.
+// The scene statistics are filled in by adding the frames of the bitrate and quality
+// analyzers to the report and calling Finish.
+func (a *SceneAnalyzer) Analyze(ctx context.Context, filePath string, stream VideoStream) (*SceneReport, error) {
+	cmd := exec.CommandContext(
+		ctx,
+		a.FFmpegPath,
+		"-hide_banner",
+		"-nostats",
+		"-v", "error",
+		"-i", filePath,
+		"-map", fmt.Sprintf("0:%d", stream.Index),
+		"-filter:v", fmt.Sprintf("scdet=threshold=%g,metadata=mode=print:file=-", a.Threshold),
+		"-f", "null",
+		"-",
+	)
+
+	// The metadata filter prints the scdet score of every frame on stdout
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start FFmpeg: %w", err)
+	}
+
+	cuts, parseErr := parseScdetMetadata(stdout)
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("error running scdet filter: %w", err)
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	return newSceneReport(stream, a.Threshold, cuts), nil
+}
+
+// AverageDuration returns the average duration of the scenes in seconds.
+func (r *SceneReport) AverageDuration() float64 {
+	if len(r.Scenes) == 0 {
+		return 0
+	}
+	first, last := r.Scenes[0], r.Scenes[len(r.Scenes)-1]
+	return (last.End - first.Start) / float64(len(r.Scenes))
+}
+
+// Finish computes the averages of every scene once all frames have been added and marks
+// the scenes whose bitrate is far below the average of the stream as starved.
+func (r *SceneReport) Finish() {
+	var totalBits int64
+	totalDuration := 0.0
+	for i := range r.Scenes {
+		scene := &r.Scenes[i]
+		if i == len(r.Scenes)-1 {
+			scene.EndFrame = max(scene.EndFrame, scene.StartFrame+scene.Frames)
+			if scene.End <= scene.Start && r.FrameRate > 0 {
+				scene.End = scene.Start + float64(scene.Frames)/r.FrameRate
+			}
+		}
+		if scene.QPFrames > 0 {
+			scene.AverageQP /= float64(scene.QPFrames)
+		}
+
+		duration := scene.End - scene.Start
+		if duration > 0 {
+			scene.AverageBitrate = float64(scene.TotalBits) / duration
+		}
+		// Scenes shorter than a second have no full window, their peak is their average
+		if scene.PeakBitrate == 0 {
+			scene.PeakBitrate = scene.AverageBitrate
+		}
+
+		totalBits += scene.TotalBits
+		totalDuration += math.Max(duration, 0)
+	}
+
+	if totalDuration > 0 {
+		r.AverageBitrate = float64(totalBits) / totalDuration
+	}
+	for i := range r.Scenes {
+		r.Scenes[i].Starved = r.Scenes[i].Frames > 0 && r.Scenes[i].AverageBitrate < r.AverageBitrate*sceneStarvedRatio
+	}
+}
+
+// KeyframesAtCuts returns the number of scene cuts starting on an I-frame and the number of
+// measured cuts. The start of the stream is not a cut.
+func (r *SceneReport) KeyframesAtCuts() (int, int) {
+	aligned, cuts := 0, 0
+	for _, scene := range r.Scenes[min(1, len(r.Scenes)):] {
+		if scene.Frames == 0 {
+			continue
+		}
+		cuts++
+		if scene.KeyframeAtCut {
+			aligned++
+		}
+	}
+	return aligned, cuts
+}
+
+// StarvedScenes returns the number of scenes starved of bits.
+func (r *SceneReport) StarvedScenes() int {
+	count := 0
+	for _, scene := range r.Scenes {
+		if scene.Starved {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,114 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the scene analyzer.
+package ffmpeg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// SceneAnalyzerTestSuite defines the test suite for SceneAnalyzer.
+// It uses synthetic scdet output and frames so no sample media is required.
+type SceneAnalyzerTestSuite struct {
+	suite.Suite
+}
+
+// scdetTestOutput renders the metadata printed for frames at 25 fps with cuts on the given frames.
+func scdetTestOutput(frames int, cuts ...int) string {
+	isCut := make(map[int]bool)
+	for _, cut := range cuts {
+		isCut[cut] = true
+	}
+
+	var sb strings.Builder
+	for n := 0; n < frames; n++ {
+		fmt.Fprintf(&sb, "frame:%-4d pts:%-8d pts_time:%g\n", n, n, float64(n)/25)
+		score := 1.5
+		if isCut[n] {
+			score = 42.0
+		}
+		fmt.Fprintf(&sb, "lavfi.scd.mafd=%g\nlavfi.scd.score=%g\n", score, score)
+		if isCut[n] {
+			fmt.Fprintf(&sb, "lavfi.scd.time=%g\n", float64(n)/25)
+		}
+	}
+	return sb.String()
+}
+
+// TestScenes verifies scene boundaries, bitrates, QP and frame type mix.
+func (s *SceneAnalyzerTestSuite) TestScenes() {
+	cuts, err := parseScdetMetadata(strings.NewReader(scdetTestOutput(150, 0, 50, 100)))
+	require.NoError(s.T(), err)
+	require.Len(s.T(), cuts, 3)
+	assert.Equal(s.T(), sceneCut{frame: 50, time: 2, score: 42}, cuts[1])
+
+	report := newSceneReport(VideoStream{Index: 0, FrameRate: 25, Duration: 6}, sceneDefaultThreshold, cuts)
+	require.Len(s.T(), report.Scenes, 3)
+	assert.Equal(s.T(), 50, report.Scenes[0].EndFrame)
+	assert.InDelta(s.T(), 4.0, report.Scenes[1].End, 1e-9)
+	assert.InDelta(s.T(), 6.0, report.Scenes[2].End, 1e-9)
+
+	// The second cut lands on a P-frame and the last scene gets a tenth of the bits
+	for n := 0; n < 150; n++ {
+		frameType, bits := "P", int64(40000)
+		switch {
+		case n%25 == 0 && n != 100:
+			frameType, bits = "I", 200000
+		case n%2 == 1:
+			frameType = "B"
+		}
+		if n >= 100 {
+			bits /= 10
+		}
+		report.AddFrame(FrameBitrateInfo{FrameNumber: n, FrameType: frameType, Bitrate: bits})
+		report.AddQualityFrame(QualityFrame{FrameNumber: n, QP: float64(20 + n/50)})
+	}
+	report.Finish()
+
+	first := report.Scenes[0]
+	assert.Equal(s.T(), 50, first.Frames)
+	assert.Equal(s.T(), map[string]int{"I": 2, "P": 24, "B": 24}, first.FrameTypes)
+	assert.InDelta(s.T(), float64(2*200000+48*40000)/2, first.AverageBitrate, 1e-6)
+	assert.InDelta(s.T(), float64(200000+24*40000), first.PeakBitrate, 1e-6)
+	assert.InDelta(s.T(), 20.0, first.AverageQP, 1e-9)
+
+	assert.True(s.T(), report.Scenes[1].KeyframeAtCut)
+	assert.InDelta(s.T(), 42.0, report.Scenes[1].CutScore, 1e-9)
+	assert.False(s.T(), report.Scenes[2].KeyframeAtCut)
+	assert.InDelta(s.T(), 22.0, report.Scenes[2].AverageQP, 1e-9)
+	assert.Equal(s.T(), 150, report.Scenes[2].EndFrame)
+
+	assert.True(s.T(), report.Scenes[2].Starved)
+	assert.Equal(s.T(), 1, report.StarvedScenes())
+	aligned, total := report.KeyframesAtCuts()
+	assert.Equal(s.T(), 1, aligned)
+	assert.Equal(s.T(), 2, total)
+	assert.InDelta(s.T(), 2.0, report.AverageDuration(), 1e-9)
+}
+
+// TestShortScene verifies that a scene shorter than a second reports its average as peak.
+func (s *SceneAnalyzerTestSuite) TestShortScene() {
+	report := newSceneReport(VideoStream{FrameRate: 25}, sceneDefaultThreshold, []sceneCut{{frame: 10, time: 0.4}})
+	for n := 0; n < 20; n++ {
+		report.AddFrame(FrameBitrateInfo{FrameNumber: n, FrameType: "P", Bitrate: 10000})
+	}
+	report.Finish()
+
+	require.Len(s.T(), report.Scenes, 2)
+	assert.InDelta(s.T(), 250000.0, report.Scenes[0].PeakBitrate, 1e-6)
+	assert.InDelta(s.T(), 0.8, report.Scenes[1].End, 1e-9)
+	assert.Zero(s.T(), report.Scenes[1].QPFrames)
+
+	_, err := NewSceneAnalyzer(nil)
+	assert.Error(s.T(), err)
+}
+
+// TestSceneAnalyzerSuite runs the SceneAnalyzer test suite.
+func TestSceneAnalyzerSuite(t *testing.T) {
+	suite.Run(t, new(SceneAnalyzerTestSuite))
+}
@@ -0,0 +1,404 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Private variables (alphabetical)
+
+// specFields lists the fields a delivery spec can check. Video fields check the primary video
+// stream, audio and subtitle fields every stream of their type.
+var specFields = map[string]specField{
+	"audio.bitrate":        audioSpecField("", func(s AudioStream) any { return knownSpecNumber(float64(s.BitRate)) }),
+	"audio.channel_layout": audioSpecField("", func(s AudioStream) any { return s.ChannelLayout }),
+	"audio.channels":       audioSpecField("", func(s AudioStream) any { return knownSpecNumber(float64(s.Channels)) }),
+	"audio.codec":          audioSpecField("", func(s AudioStream) any { return s.Format }),
+	"audio.integrated_loudness": audioSpecField(SpecAnalysisLoudness, func(s AudioStream) any {
+		if s.Loudness == nil {
+			return nil
+		}
+		return s.Loudness.IntegratedLoudness
+	}),
+	"audio.language": audioSpecField("", func(s AudioStream) any { return s.Language }),
+	"audio.loudness_range": audioSpecField(SpecAnalysisLoudness, func(s AudioStream) any {
+		if s.Loudness == nil {
+			return nil
+		}
+		return s.Loudness.LoudnessRange
+	}),
+	"audio.sample_rate": audioSpecField("", func(s AudioStream) any { return knownSpecNumber(float64(s.SamplingRate)) }),
+	"audio.true_peak": audioSpecField(SpecAnalysisLoudness, func(s AudioStream) any {
+		if s.Loudness == nil {
+			return nil
+		}
+		return s.Loudness.TruePeak
+	}),
+	"container.audio_streams": containerSpecField(func(info *ContainerInfo) any { return float64(len(info.AudioStreams)) }),
+	"container.bitrate": containerSpecField(func(info *ContainerInfo) any {
+		bitRate, _ := strconv.ParseFloat(info.General.BitRate, 64)
+		return knownSpecNumber(bitRate)
+	}),
+	"container.duration": containerSpecField(func(info *ContainerInfo) any { return knownSpecNumber(info.General.DurationF) }),
+	"container.format":   containerSpecField(func(info *ContainerInfo) any { return info.General.Format }),
+	"container.size": containerSpecField(func(info *ContainerInfo) any {
+		size, _ := strconv.ParseFloat(info.General.Size, 64)
+		return knownSpecNumber(size)
+	}),
+	"container.subtitle_streams": containerSpecField(func(info *ContainerInfo) any { return float64(len(info.SubtitleStreams)) }),
+	"container.video_streams":    containerSpecField(func(info *ContainerInfo) any { return float64(len(info.VideoStreams)) }),
+	"subtitle.codec":             subtitleSpecField(func(s SubtitleStream) any { return s.Format }),
+	"subtitle.language":          subtitleSpecField(func(s SubtitleStream) any { return s.Language }),
+	"video.average_bitrate": videoSpecField(SpecAnalysisBitrate, func(info *ContainerInfo, _ VideoStream) any {
+		if info.Bitrate == nil {
+			return nil
+		}
+		return knownSpecNumber(info.Bitrate.AverageBitrate)
+	}),
+	"video.bit_depth":            videoSpecField("", func(_ *ContainerInfo, s VideoStream) any { return knownSpecNumber(float64(s.BitDepth)) }),
+	"video.bitrate":              videoSpecField("", func(_ *ContainerInfo, s VideoStream) any { return knownSpecNumber(float64(s.BitRate)) }),
+	"video.codec":                videoSpecField("", func(_ *ContainerInfo, s VideoStream) any { return s.Format }),
+	"video.color_space":          videoSpecField("", func(_ *ContainerInfo, s VideoStream) any { return s.ColorSpace }),
+	"video.display_aspect_ratio": videoSpecField("", func(_ *ContainerInfo, s VideoStream) any { return knownSpecNumber(s.DisplayAspectRatio) }),
+	"video.frame_rate":           videoSpecField("", func(_ *ContainerInfo, s VideoStream) any { return knownSpecNumber(s.FrameRate) }),
+	"video.frame_rate_mode": videoSpecField(SpecAnalysisBitrate, func(info *ContainerInfo, _ VideoStream) any {
+		if info.Bitrate == nil || info.Bitrate.FrameRateMode == "" {
+			return nil
+		}
+		return info.Bitrate.FrameRateMode
+	}),
+	"video.height": videoSpecField("", func(_ *ContainerInfo, s VideoStream) any { return knownSpecNumber(float64(s.Height)) }),
+	"video.level":  videoSpecField("", func(_ *ContainerInfo, s VideoStream) any { return knownSpecNumber(s.Level) }),
+	"video.peak_bitrate": videoSpecField(SpecAnalysisBitrate, func(info *ContainerInfo, _ VideoStream) any {
+		if info.Bitrate == nil {
+			return nil
+		}
+		return knownSpecNumber(info.Bitrate.PeakBitrate)
+	}),
+	"video.profile":    videoSpecField("", func(_ *ContainerInfo, s VideoStream) any { return s.FormatProfile }),
+	"video.resolution": videoSpecField("", func(_ *ContainerInfo, s VideoStream) any { return fmt.Sprintf("%dx%d", s.Width, s.Height) }),
+	"video.scan_type":  videoSpecField("", func(_ *ContainerInfo, s VideoStream) any { return s.ScanType }),
+	"video.width":      videoSpecField("", func(_ *ContainerInfo, s VideoStream) any { return knownSpecNumber(float64(s.Width)) }),
+}
+
+// Private functions (alphabetical)
+
+// audioSpecField creates a spec field checking every audio stream.
+func audioSpecField(requires string, value func(stream AudioStream) any) specField {
+	return specField{requires: requires, values: func(info *ContainerInfo) []specValue {
+		values := make([]specValue, len(info.AudioStreams))
+		for i, stream := range info.AudioStreams {
+			values[i] = specValue{subject: fmt.Sprintf("audio stream #%d", i), value: value(stream)}
+		}
+		return values
+	}}
+}
+
+// containerSpecField creates a spec field checking the container.
+func containerSpecField(value func(info *ContainerInfo) any) specField {
+	return specField{values: func(info *ContainerInfo) []specValue {
+		return []specValue{{subject: "container", value: value(info)}}
+	}}
+}
+
+// formatSpecValue formats an expected or measured value for the reports.
+// Numbers are rounded to 3 decimals, so 24000/1001 fps reads 23.976.
+func formatSpecValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "not measured"
+	case float64:
+		return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// knownSpecNumber returns a positive number as a spec value, or nil for the 0 of unknown values.
+func knownSpecNumber(value float64) any {
+	if value <= 0 {
+		return nil
+	}
+	return value
+}
+
+// parseSpecNumber converts a spec value to a number. Spec files decode numbers as int or float64
+// and numeric strings, such as the "5.1" channel layout, are numbers too.
+func parseSpecNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return number, err == nil && !math.IsInf(number, 0) && !math.IsNaN(number)
+	default:
+		return 0, false
+	}
+}
+
+// specValuesEqual reports whether a measured value matches an expected one.
+func specValuesEqual(actual, expected any, tolerance float64) bool {
+	a, actualIsNumber := parseSpecNumber(actual)
+	e, expectedIsNumber := parseSpecNumber(expected)
+	if actualIsNumber && expectedIsNumber {
+		if tolerance > 0 {
+			return math.Abs(a-e) <= tolerance+1e-9
+		}
+		return math.Abs(a-e) <= math.Abs(e)*specRelativeTolerance
+	}
+	return strings.EqualFold(strings.TrimSpace(formatSpecValue(actual)), strings.TrimSpace(formatSpecValue(expected)))
+}
+
+// subtitleSpecField creates a spec field checking every subtitle stream.
+func subtitleSpecField(value func(stream SubtitleStream) any) specField {
+	return specField{values: func(info *ContainerInfo) []specValue {
+		values := make([]specValue, len(info.SubtitleStreams))
+		for i, stream := range info.SubtitleStreams {
+			values[i] = specValue{subject: fmt.Sprintf("subtitle stream #%d", i), value: value(stream)}
+		}
+		return values
+	}}
+}
+
+// videoSpecField creates a spec field checking the primary video stream.
+func videoSpecField(requires string, value func(info *ContainerInfo, stream VideoStream) any) specField {
+	return specField{requires: requires, values: func(info *ContainerInfo) []specValue {
+		stream, ok := primaryVideoStream(info)
+		if !ok {
+			return nil
+		}
+		return []specValue{{subject: fmt.Sprintf("video stream #%d", stream.Index), value: value(info, stream)}}
+	}}
+}
+
+// Public functions (alphabetical)
+
+// LoadSpec reads a delivery spec from a JSON file (.json) or a YAML file (any other extension).
+// Unknown keys and fields are rejected so that a typo cannot silently disable a rule. A spec
+// naming a built-in profile is merged with it, and a spec without a name is named after its file.
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading spec: %w", err)
+	}
+
+	spec := &Spec{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(spec)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(spec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing spec %s: %w", path, err)
+	}
+
+	if spec.Profile != "" {
+		profile, err := LookupSpecProfile(spec.Profile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spec %s: %w", path, err)
+		}
+		spec = MergeSpecs(profile, spec)
+	}
+
+	if err := spec.validate(); err != nil {
+		return nil, fmt.Errorf("invalid spec %s: %w", path, err)
+	}
+	if spec.Name == "" {
+		spec.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return spec, nil
+}
+
+// SpecFieldNames returns the fields a delivery spec can check in alphabetical order.
+func SpecFieldNames() []string {
+	names := make([]string, 0, len(specFields))
+	for name := range specFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Private methods (alphabetical)
+
+// check reports whether a measured value satisfies the rule.
+func (r SpecRule) check(value any) bool {
+	if value == nil {
+		return false
+	}
+	if r.Equals != nil && !specValuesEqual(value, r.Equals, r.Tolerance) {
+		return false
+	}
+	if len(r.OneOf) > 0 {
+		found := false
+		for _, expected := range r.OneOf {
+			found = found || specValuesEqual(value, expected, r.Tolerance)
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.Min != nil || r.Max != nil {
+		number, ok := parseSpecNumber(value)
+		if !ok || (r.Min != nil && number < *r.Min-1e-9) || (r.Max != nil && number > *r.Max+1e-9) {
+			return false
+		}
+	}
+	return true
+}
+
+// validate normalizes the fields of the rules and checks that every rule can be evaluated.
+func (s *Spec) validate() error {
+	if len(s.Rules) == 0 {
+		return fmt.Errorf("no rules")
+	}
+	for i := range s.Rules {
+		rule := &s.Rules[i]
+		rule.Field = strings.ToLower(strings.TrimSpace(rule.Field))
+		if _, ok := specFields[rule.Field]; !ok {
+			return fmt.Errorf("rule %d: unknown field %q (available: %s)", i+1, rule.Field, strings.Join(SpecFieldNames(), ", "))
+		}
+		if !rule.Disabled && rule.Equals == nil && len(rule.OneOf) == 0 && rule.Min == nil && rule.Max == nil {
+			return fmt.Errorf("rule %d (%s): needs equals, one_of, min or max", i+1, rule.Field)
+		}
+		if rule.Tolerance < 0 {
+			return fmt.Errorf("rule %d (%s): negative tolerance", i+1, rule.Field)
+		}
+		if rule.Min != nil && rule.Max != nil && *rule.Min > *rule.Max {
+			return fmt.Errorf("rule %d (%s): min is greater than max", i+1, rule.Field)
+		}
+	}
+	return nil
+}
+
+// Public methods (alphabetical)
+
+// Evaluate checks every enabled rule of the spec against the analysis of a file. Fields that need
+// an analysis which has not run, such as the loudness, fail as not measured.
+func (s *Spec) Evaluate(info *ContainerInfo) *SpecResult {
+	result := &SpecResult{Spec: s, Passed: true}
+	for _, rule := range s.Rules {
+		if rule.Disabled {
+			continue
+		}
+		ruleResult := SpecRuleResult{Rule: rule, Name: rule.Name, Passed: true}
+		if ruleResult.Name == "" {
+			ruleResult.Name = rule.Field
+		}
+
+		field, known := specFields[rule.Field]
+		var values []specValue
+		if known {
+			values = field.values(info)
+		}
+
+		var failures []string
+		switch {
+		case !known:
+			failures = append(failures, fmt.Sprintf("unknown field %q", rule.Field))
+		case len(values) == 0:
+			failures = append(failures, fmt.Sprintf("no %s stream", strings.SplitN(rule.Field, ".", 2)[0]))
+		}
+		for _, value := range values {
+			actual := formatSpecValue(value.value)
+			if len(values) > 1 {
+				actual = value.subject + ": " + actual
+			}
+			ruleResult.Actual = append(ruleResult.Actual, actual)
+
+			if rule.check(value.value) {
+				continue
+			}
+			if value.value == nil {
+				failures = append(failures, value.subject+": not measured")
+			} else {
+				failures = append(failures, fmt.Sprintf("%s: expected %s, got %s",
+					value.subject, rule.Expectation(), formatSpecValue(value.value)))
+			}
+		}
+
+		if len(failures) > 0 {
+			ruleResult.Passed = false
+			ruleResult.Message = strings.Join(failures, "; ")
+			result.Passed = false
+		}
+		result.Rules = append(result.Rules, ruleResult)
+	}
+	return result
+}
+
+// Expectation describes the values accepted by the rule, such as "≤ 4.1" or "-23 ± 1".
+func (r SpecRule) Expectation() string {
+	tolerance := ""
+	if r.Tolerance > 0 {
+		tolerance = " ± " + formatSpecValue(r.Tolerance)
+	}
+
+	var parts []string
+	if r.Equals != nil {
+		parts = append(parts, formatSpecValue(r.Equals)+tolerance)
+	}
+	if len(r.OneOf) > 0 {
+		values := make([]string, len(r.OneOf))
+		for i, value := range r.OneOf {
+			values[i] = formatSpecValue(value)
+		}
+		parts = append(parts, "one of "+strings.Join(values, ", ")+tolerance)
+	}
+	switch {
+	case r.Min != nil && r.Max != nil:
+		parts = append(parts, fmt.Sprintf("%s to %s", formatSpecValue(*r.Min), formatSpecValue(*r.Max)))
+	case r.Min != nil:
+		parts = append(parts, "≥ "+formatSpecValue(*r.Min))
+	case r.Max != nil:
+		parts = append(parts, "≤ "+formatSpecValue(*r.Max))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Failures returns the number of rules that failed.
+func (r *SpecResult) Failures() int {
+	failures := 0
+	for _, rule := range r.Rules {
+		if !rule.Passed {
+			failures++
+		}
+	}
+	return failures
+}
+
+// Requires reports whether a rule of the spec checks a field measured by the given analysis,
+// SpecAnalysisBitrate or SpecAnalysisLoudness.
+func (s *Spec) Requires(analysis string) bool {
+	for _, rule := range s.Rules {
+		if !rule.Disabled && specFields[rule.Field].requires == analysis {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the delivery specs.
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// specTestYAML is a broadcast delivery spec for 1080p23.976 H.264 with stereo AAC.
+const specTestYAML = `name: Broadcast HD
+description: H.264 High 1080p23.976 with EBU R128 stereo audio
+rules:
+  - field: video.codec
+    equals: h264
+  - field: video.profile
+    equals: high
+  - name: Level 4.1 or lower
+    field: video.level
+    max: 4.1
+  - field: video.resolution
+    equals: 1920x1080
+  - field: video.frame_rate
+    equals: 23.976
+  - field: video.frame_rate_mode
+    equals: CFR
+  - field: video.peak_bitrate
+    max: 20000000
+  - field: audio.codec
+    equals: aac
+  - field: audio.sample_rate
+    equals: 48000
+  - field: audio.channel_layout
+    one_of: [stereo, 2.0]
+  - field: audio.integrated_loudness
+    equals: -23
+    tolerance: 1
+`
+
+// SpecTestSuite defines the test suite for delivery specs.
+// It evaluates specs against synthetic analysis results so no sample media is required.
+type SpecTestSuite struct {
+	suite.Suite
+	info    *ContainerInfo // Analysis results of a compliant file
+	tempDir string         // Temporary directory for test files
+}
+
+// SetupTest creates the analysis of a compliant file and a temporary directory.
+func (s *SpecTestSuite) SetupTest() {
+	s.info = &ContainerInfo{
+		General: GeneralInfo{Format: "mpegts", DurationF: 60},
+		VideoStreams: []VideoStream{{Index: 0, Format: "h264", FormatProfile: "High", Level: 4.1,
+			Width: 1920, Height: 1080, FrameRate: 24000.0 / 1001}},
+		AudioStreams: []AudioStream{{Index: 1, Format: "aac", SamplingRate: 48000, ChannelLayout: "stereo",
+			Loudness: &LoudnessReport{IntegratedLoudness: -23.4}}},
+		Bitrate: &BitrateStats{PeakBitrate: 18500000, FrameRateMode: FrameRateModeConstant},
+	}
+
+	tempDir, err := os.MkdirTemp("", "spec-test")
+	require.NoError(s.T(), err)
+	s.tempDir = tempDir
+}
+
+// TearDownTest removes the temporary directory.
+func (s *SpecTestSuite) TearDownTest() {
+	os.RemoveAll(s.tempDir)
+}
+
+// load writes a spec file and loads it.
+func (s *SpecTestSuite) load(name, content string) (*Spec, error) {
+	path := filepath.Join(s.tempDir, name)
+	require.NoError(s.T(), os.WriteFile(path, []byte(content), 0644))
+	return LoadSpec(path)
+}
+
+// TestLoadSpec verifies YAML and JSON specs and the rejection of invalid rules.
+func (s *SpecTestSuite) TestLoadSpec() {
+	spec, err := s.load("broadcast.yaml", specTestYAML)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "Broadcast HD", spec.Name)
+	require.Len(s.T(), spec.Rules, 11)
+	assert.Equal(s.T(), 4.1, *spec.Rules[2].Max)
+	assert.True(s.T(), spec.Requires(SpecAnalysisBitrate))
+	assert.True(s.T(), spec.Requires(SpecAnalysisLoudness))
+
+	spec, err = s.load("web.json", `{"rules": [{"field": "Video.Codec", "one_of": ["h264", "hevc"]}]}`)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "web", spec.Name)
+	assert.Equal(s.T(), "video.codec", spec.Rules[0].Field)
+	assert.False(s.T(), spec.Requires(SpecAnalysisBitrate))
+
+	_, err = s.load("unknown.yaml", "rules:\n  - field: video.colour\n    equals: bt709\n")
+	assert.ErrorContains(s.T(), err, "unknown field")
+	_, err = s.load("empty.yaml", "rules:\n  - field: video.codec\n")
+	assert.ErrorContains(s.T(), err, "needs equals")
+	_, err = s.load("typo.yaml", "rules:\n  - field: video.level\n    maximum: 4.1\n")
+	assert.Error(s.T(), err)
+	_, err = s.load("range.json", `{"rules": [{"field": "video.level", "min": 5, "max": 4}]}`)
+	assert.ErrorContains(s.T(), err, "min is greater than max")
+}
+
+// TestEvaluate verifies a compliant file and the messages of failed rules.
+func (s *SpecTestSuite) TestEvaluate() {
+	spec, err := s.load("broadcast.yaml", specTestYAML)
+	require.NoError(s.T(), err)
+
+	result := spec.Evaluate(s.info)
+	assert.True(s.T(), result.Passed)
+	assert.Zero(s.T(), result.Failures())
+	assert.Equal(s.T(), "Level 4.1 or lower", result.Rules[2].Name)
+	assert.Equal(s.T(), []string{"23.976"}, result.Rules[4].Actual)
+
+	s.info.VideoStreams[0].FrameRate = 24
+	s.info.VideoStreams[0].Level = 4.2
+	s.info.Bitrate = nil
+	s.info.AudioStreams = append(s.info.AudioStreams, AudioStream{Index: 2, Format: "ac3", SamplingRate: 48000, ChannelLayout: "5.1(side)"})
+
+	result = spec.Evaluate(s.info)
+	assert.False(s.T(), result.Passed)
+	assert.Equal(s.T(), 7, result.Failures())
+	assert.Equal(s.T(), "video stream #0: expected ≤ 4.1, got 4.2", result.Rules[2].Message)
+	assert.Equal(s.T(), "video stream #0: expected 23.976, got 24", result.Rules[4].Message)
+	assert.Equal(s.T(), "video stream #0: not measured", result.Rules[6].Message)
+	assert.Equal(s.T(), []string{"audio stream #0: aac", "audio stream #1: ac3"}, result.Rules[7].Actual)
+	assert.Equal(s.T(), "audio stream #1: expected aac, got ac3", result.Rules[7].Message)
+	assert.True(s.T(), result.Rules[8].Passed)
+	assert.Equal(s.T(), "audio stream #1: expected one of stereo, 2, got 5.1(side)", result.Rules[9].Message)
+	assert.Equal(s.T(), "audio stream #1: not measured", result.Rules[10].Message)
+
+	s.info.AudioStreams = nil
+	result = spec.Evaluate(s.info)
+	assert.Equal(s.T(), "no audio stream", result.Rules[7].Message)
+}
+
+// TestExpectation verifies the descriptions of the accepted values.
+func (s *SpecTestSuite) TestExpectation() {
+	low, high := 1.0, 20.0
+	assert.Equal(s.T(), "-23 ± 1", SpecRule{Equals: -23, Tolerance: 1}.Expectation())
+	assert.Equal(s.T(), "1 to 20", SpecRule{Min: &low, Max: &high}.Expectation())
+	assert.Equal(s.T(), "≥ 1", SpecRule{Min: &low}.Expectation())
+	assert.Equal(s.T(), "one of aac, eac3", SpecRule{OneOf: []any{"aac", "eac3"}}.Expectation())
+}
+
+// TestSpecSuite runs the delivery spec test suite.
+func TestSpecSuite(t *testing.T) {
+	suite.Run(t, new(SpecTestSuite))
+}
@@ -0,0 +1,346 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// Private variables (alphabetical)
+
+var (
+	// storeFramesBucket holds a bucket per file hash with the gzipped per-frame series of its runs.
+	storeFramesBucket = []byte("frames")
+
+	// storePathsBucket maps the paths of analyzed files to their last known hash.
+	storePathsBucket = []byte("paths")
+
+	// storeRunsBucket holds a bucket per file hash with its runs, keyed by analysis time.
+	storeRunsBucket = []byte("runs")
+)
+
+// Private functions (alphabetical)
+
+// storeConvertFloats returns a copy of a value with every float reachable through exported fields
+// converted. Unexported fields are copied as they are, JSON does not store them.
+func storeConvertFloats(value reflect.Value, convert func(float64) float64) reflect.Value {
+	switch value.Kind() {
+	case reflect.Float32, reflect.Float64:
+		converted := reflect.New(value.Type()).Elem()
+		converted.SetFloat(convert(value.Float()))
+		return converted
+	case reflect.Pointer:
+		if value.IsNil() {
+			return value
+		}
+		converted := reflect.New(value.Type().Elem())
+		converted.Elem().Set(storeConvertFloats(value.Elem(), convert))
+		return converted
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+		converted := reflect.New(value.Type()).Elem()
+		converted.Set(storeConvertFloats(value.Elem(), convert))
+		return converted
+	case reflect.Struct:
+		converted := reflect.New(value.Type()).Elem()
+		converted.Set(value)
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).IsExported() {
+				converted.Field(i).Set(storeConvertFloats(value.Field(i), convert))
+			}
+		}
+		return converted
+	case reflect.Array:
+		converted := reflect.New(value.Type()).Elem()
+		for i := 0; i < value.Len(); i++ {
+			converted.Index(i).Set(storeConvertFloats(value.Index(i), convert))
+		}
+		return converted
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		converted := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			converted.Index(i).Set(storeConvertFloats(value.Index(i), convert))
+		}
+		return converted
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		converted := reflect.MakeMapWithSize(value.Type(), value.Len())
+		entries := value.MapRange()
+		for entries.Next() {
+			converted.SetMapIndex(entries.Key(), storeConvertFloats(entries.Value(), convert))
+		}
+		return converted
+	}
+	return value
+}
+
+// storeDecodeFloat restores the infinities stored by storeEncodeFloat.
+func storeDecodeFloat(value float64) float64 {
+	switch value {
+	case math.MaxFloat64:
+		return math.Inf(1)
+	case -math.MaxFloat64:
+		return math.Inf(-1)
+	}
+	return value
+}
+
+// storeDecodeRun decodes a stored run and restores its non-finite values.
+func storeDecodeRun(data []byte) (*StoredRun, error) {
+	run := &StoredRun{}
+	if err := json.Unmarshal(data, run); err != nil {
+		return nil, err
+	}
+	return storeConvertFloats(reflect.ValueOf(run), storeDecodeFloat).Interface().(*StoredRun), nil
+}
+
+// storeEncodeFloat maps the values JSON cannot represent to values it can: infinities to the
+// largest finite floats and NaN, which marks a missing measure, to zero.
+func storeEncodeFloat(value float64) float64 {
+	switch {
+	case math.IsInf(value, 1):
+		return math.MaxFloat64
+	case math.IsInf(value, -1):
+		return -math.MaxFloat64
+	case math.IsNaN(value):
+		return 0
+	}
+	return value
+}
+
+// storeRunKey returns the key of a run, the analysis time in nanoseconds in big endian so runs
+// sort chronologically.
+func storeRunKey(analyzed time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(analyzed.UnixNano()))
+	return key
+}
+
+// Public functions (alphabetical)
+
+// NewStoredRun creates the stored run of an analysis and computes its summary statistics.
+func NewStoredRun(path string, hash string, size int64, info *ContainerInfo, analyzed time.Time) *StoredRun {
+	run := &StoredRun{
+		Hash:     hash,
+		Path:     path,
+		Size:     size,
+		Analyzed: analyzed,
+		Info:     info,
+		Summary:  StoredSummary{Duration: info.General.DurationF},
+	}
+
+	if stream, ok := primaryVideoStream(info); ok {
+		run.Summary.VideoCodec = stream.Format
+		run.Summary.Resolution = fmt.Sprintf("%dx%d", stream.Width, stream.Height)
+		run.Summary.FrameRate = stream.FrameRate
+	}
+	if info.Bitrate != nil {
+		run.Summary.FrameCount = info.Bitrate.Frames
+		run.Summary.AverageBitrate = info.Bitrate.AverageBitrate
+		run.Summary.PeakBitrate = info.Bitrate.PeakBitrate
+	}
+	for _, stream := range info.AudioStreams {
+		run.Summary.AudioCodecs = append(run.Summary.AudioCodecs, stream.Format)
+	}
+	if len(info.AudioStreams) > 0 && info.AudioStreams[0].Loudness != nil {
+		// Silent audio has no finite loudness, which JSON cannot store
+		run.Summary.Loudness = encodeLoudnessJSON(info.AudioStreams[0].Loudness.IntegratedLoudness)
+	}
+	if info.Integrity != nil {
+		healthy := info.Integrity.Healthy()
+		run.Summary.Healthy = &healthy
+	}
+	return run
+}
+
+// OpenResultStore opens a result store, creating it and its directory when missing. It fails
+// when another process keeps the store open for longer than storeLockTimeout.
+func OpenResultStore(path string) (*ResultStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("error creating result store directory: %w", err)
+	}
+	db, err := bbolt.Open(path, 0644, &bbolt.Options{Timeout: storeLockTimeout})
+	if errors.Is(err, bbolt.ErrTimeout) {
+		return nil, fmt.Errorf("result store %s is in use by another process", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening result store: %w", err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{storeFramesBucket, storePathsBucket, storeRunsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error initializing result store: %w", err)
+	}
+	return &ResultStore{path: path, db: db}, nil
+}
+
+// Public methods (alphabetical)
+
+// Close closes the store, releasing it for other processes.
+func (s *ResultStore) Close() error {
+	return s.db.Close()
+}
+
+// Frames returns the per-frame series stored with a run, nil when none was stored.
+func (s *ResultStore) Frames(run *StoredRun) ([]FrameBitrateInfo, error) {
+	var frames []FrameBitrateInfo
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(storeFramesBucket).Bucket([]byte(run.Hash))
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get(storeRunKey(run.Analyzed))
+		if data == nil {
+			return nil
+		}
+
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		return json.NewDecoder(reader).Decode(&frames)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading stored frames: %w", err)
+	}
+	return frames, nil
+}
+
+// History returns the runs of a file, oldest first.
+func (s *ResultStore) History(hash string) ([]*StoredRun, error) {
+	var runs []*StoredRun
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(storeRunsBucket).Bucket([]byte(hash))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, data []byte) error {
+			run, err := storeDecodeRun(data)
+			if err != nil {
+				return err
+			}
+			runs = append(runs, run)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading history: %w", err)
+	}
+	return runs, nil
+}
+
+// Latest returns the last run of every stored file, sorted by path.
+func (s *ResultStore) Latest() ([]*StoredRun, error) {
+	var runs []*StoredRun
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(storeRunsBucket).ForEachBucket(func(hash []byte) error {
+			_, data := tx.Bucket(storeRunsBucket).Bucket(hash).Cursor().Last()
+			if data == nil {
+				return nil
+			}
+			run, err := storeDecodeRun(data)
+			if err != nil {
+				return err
+			}
+			runs = append(runs, run)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading stored runs: %w", err)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Path < runs[j].Path })
+	return runs, nil
+}
+
+// LookupPath returns the hash of the file last analyzed at a path, or an empty string when no
+// analysis of the path is stored.
+func (s *ResultStore) LookupPath(path string) (string, error) {
+	var hash string
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		hash = string(tx.Bucket(storePathsBucket).Get([]byte(path)))
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error reading result store: %w", err)
+	}
+	return hash, nil
+}
+
+// Save stores a run, with the per-frame series of the file when frames is not empty.
+func (s *ResultStore) Save(run *StoredRun, frames []FrameBitrateInfo) error {
+	run.Frames = len(frames)
+	// JSON has no infinity or NaN, any left in the analysis must not prevent storing it
+	data, err := json.Marshal(storeConvertFloats(reflect.ValueOf(run), storeEncodeFloat).Interface())
+	if err != nil {
+		return fmt.Errorf("error encoding stored run: %w", err)
+	}
+
+	var series bytes.Buffer
+	if len(frames) > 0 {
+		writer := gzip.NewWriter(&series)
+		if err := json.NewEncoder(writer).Encode(frames); err != nil {
+			return fmt.Errorf("error encoding stored frames: %w", err)
+		}
+		if err := writer.Close(); err != nil {
+			return fmt.Errorf("error compressing stored frames: %w", err)
+		}
+	}
+
+	key := storeRunKey(run.Analyzed)
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		runs, err := tx.Bucket(storeRunsBucket).CreateBucketIfNotExists([]byte(run.Hash))
+		if err != nil {
+			return err
+		}
+		if err := runs.Put(key, data); err != nil {
+			return err
+		}
+		if err := tx.Bucket(storePathsBucket).Put([]byte(run.Path), []byte(run.Hash)); err != nil {
+			return err
+		}
+		if series.Len() == 0 {
+			return nil
+		}
+		framesBucket, err := tx.Bucket(storeFramesBucket).CreateBucketIfNotExists([]byte(run.Hash))
+		if err != nil {
+			return err
+		}
+		return framesBucket.Put(key, series.Bytes())
+	})
+	if err != nil {
+		return fmt.Errorf("error saving to result store %s: %w", s.path, err)
+	}
+	return nil
+}
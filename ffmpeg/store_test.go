@@ -0,0 +1,150 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the result store.
+package ffmpeg
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// ResultStoreTestSuite defines the test suite for ResultStore.
+// It stores synthetic analysis results so no sample media is required.
+type ResultStoreTestSuite struct {
+	suite.Suite
+	tempDir string         // Temporary directory for test files
+	store   *ResultStore   // Store under test
+	info    *ContainerInfo // Analysis stored in the tests
+}
+
+// SetupTest opens a store in a temporary directory and creates the analysis of a file.
+func (s *ResultStoreTestSuite) SetupTest() {
+	tempDir, err := os.MkdirTemp("", "store-test")
+	require.NoError(s.T(), err)
+	s.tempDir = tempDir
+
+	s.store, err = OpenResultStore(filepath.Join(tempDir, "db", "results.db"))
+	require.NoError(s.T(), err)
+
+	s.info = &ContainerInfo{
+		General:      GeneralInfo{Format: "matroska,webm", DurationF: 60},
+		VideoStreams: []VideoStream{{Format: "hevc", Width: 3840, Height: 2160, FrameRate: 24}},
+		AudioStreams: []AudioStream{{Format: "eac3", Loudness: &LoudnessReport{IntegratedLoudness: -23.2}}},
+		Bitrate:      &BitrateStats{Frames: 1440, AverageBitrate: 12500000, PeakBitrate: 30000000},
+	}
+}
+
+// TearDownTest closes the store and removes the temporary directory.
+func (s *ResultStoreTestSuite) TearDownTest() {
+	s.store.Close()
+	os.RemoveAll(s.tempDir)
+}
+
+// TestHistory verifies the runs of a file, its per-frame series and the lookup by path.
+func (s *ResultStoreTestSuite) TestHistory() {
+	first := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	run := NewStoredRun("/media/movie.mkv", "abc", 1000, s.info, first)
+	assert.Equal(s.T(), "3840x2160", run.Summary.Resolution)
+	assert.Equal(s.T(), 1440, run.Summary.FrameCount)
+	assert.Equal(s.T(), -23.2, *run.Summary.Loudness)
+	assert.Nil(s.T(), run.Summary.Healthy)
+	frames := []FrameBitrateInfo{{FrameNumber: 0, FrameType: "I", Bitrate: 400000}, {FrameNumber: 1, FrameType: "P", Time: 0.04}}
+	require.NoError(s.T(), s.store.Save(run, frames))
+
+	// The file was moved and analyzed again, without the frames
+	second := NewStoredRun("/archive/movie.mkv", "abc", 1000, s.info, first.Add(time.Hour))
+	second.Version = "1.2.0"
+	require.NoError(s.T(), s.store.Save(second, nil))
+	other := NewStoredRun("/media/trailer.mp4", "def", 10, &ContainerInfo{}, first.Add(2*time.Hour))
+	require.NoError(s.T(), s.store.Save(other, nil))
+
+	runs, err := s.store.History("abc")
+	require.NoError(s.T(), err)
+	require.Len(s.T(), runs, 2)
+	assert.Equal(s.T(), "/media/movie.mkv", runs[0].Path)
+	assert.Equal(s.T(), 2, runs[0].Frames)
+	assert.Equal(s.T(), "1.2.0", runs[1].Version)
+	assert.Equal(s.T(), "hevc", runs[1].Info.VideoStreams[0].Format)
+	assert.InDelta(s.T(), 12500000, runs[1].Info.Bitrate.AverageBitrate, 1e-6)
+
+	stored, err := s.store.Frames(runs[0])
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), frames, stored)
+	stored, err = s.store.Frames(runs[1])
+	require.NoError(s.T(), err)
+	assert.Nil(s.T(), stored)
+
+	hash, err := s.store.LookupPath("/media/movie.mkv")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "abc", hash)
+	hash, err = s.store.LookupPath("/media/missing.mkv")
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), hash)
+
+	latest, err := s.store.Latest()
+	require.NoError(s.T(), err)
+	require.Len(s.T(), latest, 2)
+	assert.Equal(s.T(), "/archive/movie.mkv", latest[0].Path)
+	assert.Equal(s.T(), "/media/trailer.mp4", latest[1].Path)
+
+	// The runs survive reopening the store
+	path := s.store.path
+	require.NoError(s.T(), s.store.Close())
+	s.store, err = OpenResultStore(path)
+	require.NoError(s.T(), err)
+	runs, err = s.store.History("abc")
+	require.NoError(s.T(), err)
+	assert.Len(s.T(), runs, 2)
+}
+
+// TestSilentAudio verifies an analysis of silent audio, whose loudness is not finite, is stored and read back.
+func (s *ResultStoreTestSuite) TestSilentAudio() {
+	silence := math.Inf(-1)
+	s.info.AudioStreams[0].Loudness = &LoudnessReport{
+		IntegratedLoudness:  silence,
+		IntegratedThreshold: silence,
+		LoudnessRange:       silence,
+		LoudnessRangeLow:    silence,
+		LoudnessRangeHigh:   silence,
+		TruePeak:            silence,
+		Samples:             []LoudnessSample{{Time: 0.1, Momentary: silence, ShortTerm: silence}},
+	}
+	run := NewStoredRun("/media/silence.mkv", "abc", 1000, s.info, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	assert.Nil(s.T(), run.Summary.Loudness)
+	require.NoError(s.T(), s.store.Save(run, nil))
+
+	runs, err := s.store.History("abc")
+	require.NoError(s.T(), err)
+	require.Len(s.T(), runs, 1)
+	assert.Equal(s.T(), *s.info.AudioStreams[0].Loudness, *runs[0].Info.AudioStreams[0].Loudness)
+}
+
+// TestNonFiniteValues verifies non-finite values anywhere in an analysis are stored and restored,
+// NaN being stored as zero.
+func (s *ResultStoreTestSuite) TestNonFiniteValues() {
+	s.info.AudioStreams[0].QC = &AudioQCReport{Events: []AudioQCEvent{{Type: AudioQCPhase, Value: math.Inf(-1)}}}
+	s.info.VideoStreams[0].ScanTypeConfidence = math.Inf(1)
+	s.info.VideoStreams[0].DisplayAspectRatio = math.NaN()
+	run := NewStoredRun("/media/movie.mkv", "abc", 1000, s.info, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	require.NoError(s.T(), s.store.Save(run, nil))
+	assert.True(s.T(), math.IsNaN(s.info.VideoStreams[0].DisplayAspectRatio), "The saved analysis should not change")
+
+	runs, err := s.store.History("abc")
+	require.NoError(s.T(), err)
+	require.Len(s.T(), runs, 1)
+	assert.True(s.T(), math.IsInf(runs[0].Info.AudioStreams[0].QC.Events[0].Value, -1))
+	assert.True(s.T(), math.IsInf(runs[0].Info.VideoStreams[0].ScanTypeConfidence, 1))
+	assert.Equal(s.T(), 0.0, runs[0].Info.VideoStreams[0].DisplayAspectRatio)
+	assert.Equal(s.T(), -23.2, runs[0].Info.AudioStreams[0].Loudness.IntegratedLoudness)
+}
+
+// TestResultStoreSuite runs the ResultStore test suite.
+func TestResultStoreSuite(t *testing.T) {
+	suite.Run(t, new(ResultStoreTestSuite))
+}
@@ -0,0 +1,272 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Private variables (alphabetical)
+
+// subtitleBitmapCodecs lists the subtitle codecs that carry pictures instead of text.
+var subtitleBitmapCodecs = map[string]bool{
+	"dvb_subtitle":      true,
+	"dvd_subtitle":      true,
+	"hdmv_pgs_subtitle": true,
+	"xsub":              true,
+}
+
+// subtitleMarkupRegex matches HTML style tags and leftover ASS override blocks.
+var subtitleMarkupRegex = regexp.MustCompile(`<[^>]*>|\{[^}]*\}`)
+
+// subtitleTimingRegex matches an SRT timing line.
+var subtitleTimingRegex = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2})[,.](\d{3})\s*-->\s*(\d+):(\d{2}):(\d{2})[,.](\d{3})`)
+
+// Private functions (alphabetical)
+
+// parseSRTTimestamp converts the hour, minute, second and millisecond parts of an SRT timestamp to seconds.
+func parseSRTTimestamp(parts []string) float64 {
+	var values [4]float64
+	for i, part := range parts {
+		values[i], _ = strconv.ParseFloat(part, 64)
+	}
+	return values[0]*3600 + values[1]*60 + values[2] + values[3]/1000
+}
+
+// parseSubtitleBitmapPackets converts bitmap subtitle packets to events.
+// Packets without a duration are shown until the next packet; small packets
+// are clear events that only end the cue on screen.
+func parseSubtitleBitmapPackets(packets []ffprobeSubtitlePacket) []SubtitleEvent {
+	var events []SubtitleEvent
+	open := -1
+
+	for _, packet := range packets {
+		pts, err := strconv.ParseFloat(packet.PtsTime, 64)
+		if err != nil {
+			continue
+		}
+		duration, _ := strconv.ParseFloat(packet.DurationTime, 64)
+		size, _ := strconv.Atoi(packet.Size)
+
+		// Any packet ends a cue still waiting for its end time
+		if open >= 0 {
+			events[open].End = pts
+			open = -1
+		}
+		if size <= subtitleBitmapClearPacketSize {
+			continue
+		}
+
+		events = append(events, SubtitleEvent{Start: pts, End: pts + duration})
+		if duration <= 0 {
+			open = len(events) - 1
+		}
+	}
+
+	return events
+}
+
+// parseSubtitleSRT reads SRT formatted cues.
+// Cues are blocks separated by blank lines; markup is stripped from the text and
+// the visible characters are counted.
+func parseSubtitleSRT(r io.Reader) ([]SubtitleEvent, error) {
+	var events []SubtitleEvent
+	var block []string
+
+	flush := func() {
+		for i, line := range block {
+			match := subtitleTimingRegex.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+
+			event := SubtitleEvent{
+				Start: parseSRTTimestamp(match[1:5]),
+				End:   parseSRTTimestamp(match[5:9]),
+			}
+			var lines []string
+			for _, textLine := range block[i+1:] {
+				if text := strings.TrimSpace(subtitleMarkupRegex.ReplaceAllString(textLine, "")); text != "" {
+					lines = append(lines, text)
+				}
+			}
+			event.Text = strings.Join(lines, "\n")
+			for _, r := range event.Text {
+				if !unicode.IsSpace(r) {
+					event.Characters++
+				}
+			}
+			events = append(events, event)
+			break
+		}
+		block = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(strings.TrimPrefix(scanner.Text(), "\uFEFF"), "\r")
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		block = append(block, line)
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading subtitle events: %w", err)
+	}
+
+	return events, nil
+}
+
+// Public functions (alphabetical)
+
+// NewSubtitleAnalyzer creates a new SubtitleAnalyzer instance with the provided FFmpeg information.
+// The reading speed limit defaults to 20 characters per second.
+func NewSubtitleAnalyzer(ffmpegInfo *FFmpegInfo) (*SubtitleAnalyzer, error) {
+	if ffmpegInfo == nil || !ffmpegInfo.Installed {
+		return nil, fmt.Errorf("ffmpeg not available")
+	}
+
+	return &SubtitleAnalyzer{
+		FFmpegPath:        ffmpegInfo.Path,
+		FFprobePath:       strings.Replace(ffmpegInfo.Path, "ffmpeg", "ffprobe", 1),
+		MaxCharsPerSecond: subtitleDefaultMaxCharsPerSecond,
+	}, nil
+}
+
+// Private methods (alphabetical)
+
+// evaluate numbers the events and runs the timing and reading speed checks.
+func (a *SubtitleAnalyzer) evaluate(report *SubtitleReport, videoDuration float64) {
+	events := report.Events
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start < events[j].Start })
+
+	latestEnd := 0.0
+	for i := range events {
+		event := &events[i]
+		event.Number = i + 1
+
+		if i > 0 && event.Start < latestEnd-subtitleTimingTolerance {
+			event.Overlapping = true
+			report.OverlapCount++
+		}
+		if event.End > latestEnd {
+			latestEnd = event.End
+		}
+
+		if duration := event.End - event.Start; duration > 0 && event.Characters > 0 {
+			event.CharsPerSecond = float64(event.Characters) / duration
+			if a.MaxCharsPerSecond > 0 && event.CharsPerSecond > a.MaxCharsPerSecond {
+				event.TooFast = true
+				report.ReadingSpeedViolations++
+			}
+		}
+
+		if videoDuration > 0 && event.End > videoDuration+subtitleTimingTolerance {
+			event.BeyondVideoEnd = true
+			report.BeyondVideoCount++
+		}
+	}
+
+	report.EventCount = len(events)
+	if len(events) > 0 {
+		report.FirstCueTime = events[0].Start
+		report.LastCueTime = latestEnd
+	}
+}
+
+// extractBitmapEvents reads the packet timing of a bitmap subtitle stream with FFprobe.
+func (a *SubtitleAnalyzer) extractBitmapEvents(ctx context.Context, filePath string, streamIndex int) ([]SubtitleEvent, error) {
+	cmd := exec.CommandContext(
+		ctx,
+		a.FFprobePath,
+		"-v", "error",
+		"-select_streams", strconv.Itoa(streamIndex),
+		"-show_entries", "packet=pts_time,duration_time,size",
+		"-print_format", "json",
+		filePath,
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error running ffprobe: %w", err)
+	}
+
+	var packets ffprobePacketsOutput
+	if err := json.Unmarshal(output, &packets); err != nil {
+		return nil, fmt.Errorf("error parsing ffprobe output: %w", err)
+	}
+
+	return parseSubtitleBitmapPackets(packets.Packets), nil
+}
+
+// extractTextEvents converts a text subtitle stream to SRT with FFmpeg and parses the cues.
+func (a *SubtitleAnalyzer) extractTextEvents(ctx context.Context, filePath string, streamIndex int) ([]SubtitleEvent, error) {
+	cmd := exec.CommandContext(
+		ctx,
+		a.FFmpegPath,
+		"-v", "error",
+		"-i", filePath,
+		"-map", fmt.Sprintf("0:%d", streamIndex),
+		"-c:s", "srt",
+		"-f", "srt",
+		"-",
+	)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error extracting subtitles: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if !utf8.Valid(output) {
+		output = bytes.ToValidUTF8(output, []byte("\uFFFD"))
+	}
+
+	return parseSubtitleSRT(bytes.NewReader(output))
+}
+
+// Public methods (alphabetical)
+
+// Analyze extracts the events of a subtitle stream and checks them.
+// It reports the event count, the first and last cue time, overlapping cues, cues read
+// faster than MaxCharsPerSecond and cues ending after videoDuration (in seconds, 0 to skip).
+// Bitmap formats such as PGS and VobSub are analyzed by timing only.
+func (a *SubtitleAnalyzer) Analyze(ctx context.Context, filePath string, stream SubtitleStream, videoDuration float64) (*SubtitleReport, error) {
+	report := &SubtitleReport{
+		StreamIndex: stream.Index,
+		Bitmap:      subtitleBitmapCodecs[stream.Format],
+	}
+
+	var events []SubtitleEvent
+	var err error
+	if report.Bitmap {
+		events, err = a.extractBitmapEvents(ctx, filePath, stream.Index)
+	} else {
+		events, err = a.extractTextEvents(ctx, filePath, stream.Index)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report.Events = events
+	a.evaluate(report, videoDuration)
+	return report, nil
+}
@@ -0,0 +1,126 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the subtitle analyzer.
+package ffmpeg
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// SubtitleAnalyzerTestSuite defines the test suite for SubtitleAnalyzer.
+// Parsing and checks run on synthetic data; extraction runs only when FFmpeg is installed.
+type SubtitleAnalyzerTestSuite struct {
+	suite.Suite
+	analyzer *SubtitleAnalyzer // SubtitleAnalyzer instance under test
+}
+
+// SetupSuite creates an analyzer with the default reading speed limit.
+func (s *SubtitleAnalyzerTestSuite) SetupSuite() {
+	s.analyzer = &SubtitleAnalyzer{MaxCharsPerSecond: subtitleDefaultMaxCharsPerSecond}
+}
+
+// TestParseSRT verifies cue timing, markup removal and character counting.
+func (s *SubtitleAnalyzerTestSuite) TestParseSRT() {
+	srt := "\uFEFF1\r\n00:00:01,000 --> 00:00:03,500\r\n<i>Hello</i> there!\r\n\r\n" +
+		"2\n00:00:04.000 --> 00:00:05.000\n{\\an8}Two\nlines\n\n" +
+		"3\n00:01:00,250 --> 01:00:00,000\n\n"
+
+	events, err := parseSubtitleSRT(strings.NewReader(srt))
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 3)
+
+	assert.InDelta(s.T(), 1.0, events[0].Start, 1e-9)
+	assert.InDelta(s.T(), 3.5, events[0].End, 1e-9)
+	assert.Equal(s.T(), "Hello there!", events[0].Text)
+	assert.Equal(s.T(), 11, events[0].Characters)
+
+	assert.Equal(s.T(), "Two\nlines", events[1].Text)
+	assert.Equal(s.T(), 8, events[1].Characters)
+
+	assert.InDelta(s.T(), 60.25, events[2].Start, 1e-9)
+	assert.InDelta(s.T(), 3600.0, events[2].End, 1e-9)
+	assert.Empty(s.T(), events[2].Text)
+}
+
+// TestParseBitmapPackets verifies timing reconstruction from PGS style packets.
+func (s *SubtitleAnalyzerTestSuite) TestParseBitmapPackets() {
+	packets := []ffprobeSubtitlePacket{
+		{PtsTime: "1.000000", Size: "4096"},
+		{PtsTime: "3.000000", Size: "30"},
+		{PtsTime: "5.000000", Size: "8192"},
+		{PtsTime: "6.500000", Size: "2048"},
+		{PtsTime: "8.000000", Size: "30"},
+		{PtsTime: "10.000000", DurationTime: "1.500000", Size: "1024"},
+		{PtsTime: "N/A", Size: "1024"},
+	}
+
+	events := parseSubtitleBitmapPackets(packets)
+	require.Len(s.T(), events, 4)
+	assert.Equal(s.T(), SubtitleEvent{Start: 1, End: 3}, events[0])
+	assert.Equal(s.T(), SubtitleEvent{Start: 5, End: 6.5}, events[1])
+	assert.Equal(s.T(), SubtitleEvent{Start: 6.5, End: 8}, events[2])
+	assert.Equal(s.T(), SubtitleEvent{Start: 10, End: 11.5}, events[3])
+}
+
+// TestEvaluate verifies overlap, reading speed and video duration checks.
+func (s *SubtitleAnalyzerTestSuite) TestEvaluate() {
+	report := &SubtitleReport{Events: []SubtitleEvent{
+		{Start: 5, End: 7, Characters: 20},
+		{Start: 1, End: 4, Characters: 30},
+		{Start: 6.5, End: 8, Characters: 45},
+		{Start: 9, End: 12, Characters: 10},
+	}}
+
+	s.analyzer.evaluate(report, 10)
+
+	assert.Equal(s.T(), 4, report.EventCount)
+	assert.InDelta(s.T(), 1.0, report.FirstCueTime, 1e-9)
+	assert.InDelta(s.T(), 12.0, report.LastCueTime, 1e-9)
+	assert.Equal(s.T(), 1, report.OverlapCount)
+	assert.Equal(s.T(), 1, report.ReadingSpeedViolations)
+	assert.Equal(s.T(), 1, report.BeyondVideoCount)
+
+	events := report.Events
+	assert.Equal(s.T(), []int{1, 2, 3, 4}, []int{events[0].Number, events[1].Number, events[2].Number, events[3].Number})
+	assert.InDelta(s.T(), 1.0, events[0].Start, 1e-9)
+	assert.InDelta(s.T(), 10.0, events[0].CharsPerSecond, 1e-9)
+	assert.True(s.T(), events[2].Overlapping)
+	assert.True(s.T(), events[2].TooFast)
+	assert.True(s.T(), events[3].BeyondVideoEnd)
+	assert.False(s.T(), events[1].Overlapping)
+}
+
+// TestNewSubtitleAnalyzer verifies constructor validation and defaults.
+func (s *SubtitleAnalyzerTestSuite) TestNewSubtitleAnalyzer() {
+	_, err := NewSubtitleAnalyzer(nil)
+	assert.Error(s.T(), err)
+
+	analyzer, err := NewSubtitleAnalyzer(&FFmpegInfo{Installed: true, Path: "/usr/bin/ffmpeg"})
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "/usr/bin/ffprobe", analyzer.FFprobePath)
+	assert.Equal(s.T(), subtitleDefaultMaxCharsPerSecond, analyzer.MaxCharsPerSecond)
+}
+
+// TestAnalyzeMissingFile verifies that extraction failures are returned when FFmpeg is installed.
+func (s *SubtitleAnalyzerTestSuite) TestAnalyzeMissingFile() {
+	ffmpegInfo, err := FindFFmpeg()
+	if err != nil || !ffmpegInfo.Installed {
+		s.T().Skip("FFmpeg not installed, skipping test")
+	}
+
+	analyzer, err := NewSubtitleAnalyzer(ffmpegInfo)
+	require.NoError(s.T(), err)
+
+	_, err = analyzer.Analyze(context.Background(), "missing.mkv", SubtitleStream{Index: 2, Format: "subrip"}, 0)
+	assert.Error(s.T(), err)
+}
+
+// TestSubtitleAnalyzerSuite runs the SubtitleAnalyzer test suite.
+func TestSubtitleAnalyzerSuite(t *testing.T) {
+	suite.Run(t, new(SubtitleAnalyzerTestSuite))
+}
@@ -0,0 +1,279 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Private functions (alphabetical)
+
+// estimateContentOffset matches every audio onset with the nearest scene cut and returns
+// the offset most onsets agree on. Onsets further than syncContentWindow from any cut are
+// ignored; the offset is the mean of the densest cluster of onset-to-cut distances.
+// It returns zero matches when fewer than syncContentMinMatches onsets agree.
+func estimateContentOffset(cuts []float64, onsets []float64) (float64, int) {
+	if len(cuts) == 0 {
+		return 0, 0
+	}
+
+	sortedCuts := append([]float64(nil), cuts...)
+	sort.Float64s(sortedCuts)
+
+	var offsets []float64
+	for _, onset := range onsets {
+		i := sort.SearchFloat64s(sortedCuts, onset)
+		nearest := math.Inf(1)
+		for _, j := range []int{i - 1, i} {
+			if j >= 0 && j < len(sortedCuts) && math.Abs(onset-sortedCuts[j]) < math.Abs(nearest) {
+				nearest = onset - sortedCuts[j]
+			}
+		}
+		if math.Abs(nearest) <= syncContentWindow {
+			offsets = append(offsets, nearest)
+		}
+	}
+	sort.Float64s(offsets)
+
+	// Slide a window of syncContentClusterWidth over the sorted offsets
+	bestStart, bestCount := 0, 0
+	end := 0
+	for start := range offsets {
+		for end < len(offsets) && offsets[end]-offsets[start] <= syncContentClusterWidth {
+			end++
+		}
+		if end-start > bestCount {
+			bestStart, bestCount = start, end-start
+		}
+	}
+	if bestCount < syncContentMinMatches {
+		return 0, 0
+	}
+
+	sum := 0.0
+	for _, offset := range offsets[bestStart : bestStart+bestCount] {
+		sum += offset
+	}
+	return sum / float64(bestCount), bestCount
+}
+
+// parseAudioOnsets reads the silence_end values printed by the ametadata filter after silencedetect.
+// Every end of silence is the onset of a sound.
+func parseAudioOnsets(r io.Reader) ([]float64, error) {
+	var onsets []float64
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		_, value, found := strings.Cut(scanner.Text(), "lavfi.silence_end=")
+		if !found {
+			continue
+		}
+		if onset, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			onsets = append(onsets, onset)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading silencedetect output: %w", err)
+	}
+	return onsets, nil
+}
+
+// parseSceneCuts reads the frame times printed by the metadata filter after a scene select.
+// Only frames passing the select filter are printed, so every frame header is a cut.
+func parseSceneCuts(r io.Reader) ([]float64, error) {
+	var cuts []float64
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		match := metadataFrameRegex.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		if cut, err := strconv.ParseFloat(match[2], 64); err == nil {
+			cuts = append(cuts, cut)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading scene detection output: %w", err)
+	}
+	return cuts, nil
+}
+
+// primaryVideoStream returns the first video stream that is not an attached picture.
+func primaryVideoStream(info *ContainerInfo) (VideoStream, bool) {
+	for _, stream := range info.VideoStreams {
+		if !stream.Disposition.AttachedPic {
+			return stream, true
+		}
+	}
+	return VideoStream{}, false
+}
+
+// syncEditShift returns the edit list start shift of the MP4 track backing a stream.
+// The MP4 demuxer exposes tracks as streams in trak order, so the stream index selects the track.
+func syncEditShift(structure *MP4Structure, streamIndex int) float64 {
+	if structure == nil || streamIndex < 0 || streamIndex >= len(structure.Tracks) {
+		return 0
+	}
+	if editList := structure.Tracks[streamIndex].EditList; editList != nil {
+		return editList.StartShift
+	}
+	return 0
+}
+
+// Public functions (alphabetical)
+
+// NewSyncAnalyzer creates a new SyncAnalyzer that reports offsets above the given threshold in seconds.
+// A threshold of zero or less selects DefaultSyncThreshold.
+func NewSyncAnalyzer(ffmpegInfo *FFmpegInfo, threshold float64) (*SyncAnalyzer, error) {
+	if ffmpegInfo == nil || !ffmpegInfo.Installed {
+		return nil, fmt.Errorf("ffmpeg not available")
+	}
+	if threshold <= 0 {
+		threshold = DefaultSyncThreshold
+	}
+
+	return &SyncAnalyzer{
+		FFmpegPath: ffmpegInfo.Path,
+		Threshold:  threshold,
+	}, nil
+}
+
+// Private methods (alphabetical)
+
+// evaluate flags the streams whose offsets exceed the threshold and sets the overall result.
+// Subtitle streams may legitimately start with their first cue, so only their edit list is checked.
+func (a *SyncAnalyzer) evaluate(report *SyncReport) {
+	report.Threshold = a.Threshold
+	report.Passed = true
+	for i := range report.Streams {
+		stream := &report.Streams[i]
+		stream.OutOfSync = (stream.Type == "audio" && math.Abs(stream.StartDelta) > a.Threshold) ||
+			math.Abs(stream.EditListDelta) > a.Threshold ||
+			(stream.ContentMatches > 0 && math.Abs(stream.ContentOffset) > a.Threshold)
+		if stream.OutOfSync {
+			report.Passed = false
+		}
+	}
+}
+
+// runMetadataFilter runs a filter on a single stream and parses the metadata it prints on stdout.
+func (a *SyncAnalyzer) runMetadataFilter(ctx context.Context, filePath string, streamIndex int, filterOption string,
+	filter string, parse func(io.Reader) ([]float64, error)) ([]float64, error) {
+	cmd := exec.CommandContext(
+		ctx,
+		a.FFmpegPath,
+		"-hide_banner",
+		"-nostats",
+		"-v", "error",
+		"-i", filePath,
+		"-map", fmt.Sprintf("0:%d", streamIndex),
+		filterOption, filter,
+		"-f", "null",
+		"-",
+	)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start FFmpeg: %w", err)
+	}
+
+	values, parseErr := parse(stdout)
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("error running %s: %w", filter, err)
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return values, nil
+}
+
+// Public methods (alphabetical)
+
+// AnalyzeContent measures the offset of every audio stream from the content of the file.
+// Scene cuts of the video stream (or the flashes of a clapper test pattern) are matched with
+// the sound onsets of each audio stream; the offset most onsets agree on is stored in the
+// report and checked against the threshold. Streams without enough matches keep no content offset.
+func (a *SyncAnalyzer) AnalyzeContent(ctx context.Context, filePath string, report *SyncReport) error {
+	sceneFilter := fmt.Sprintf("select='gt(scene,%g)',metadata=mode=print:file=-", syncContentSceneThreshold)
+	cuts, err := a.runMetadataFilter(ctx, filePath, report.VideoStreamIndex, "-filter:v", sceneFilter, parseSceneCuts)
+	if err != nil {
+		return fmt.Errorf("error detecting scene cuts: %w", err)
+	}
+	report.SceneCuts = len(cuts)
+	report.ContentChecked = true
+
+	onsetFilter := fmt.Sprintf("silencedetect=noise=%s:d=%g,ametadata=mode=print:file=-",
+		syncContentOnsetNoise, syncContentOnsetSilence)
+	for i := range report.Streams {
+		stream := &report.Streams[i]
+		if stream.Type != "audio" {
+			continue
+		}
+
+		onsets, err := a.runMetadataFilter(ctx, filePath, stream.StreamIndex, "-filter:a", onsetFilter, parseAudioOnsets)
+		if err != nil {
+			return fmt.Errorf("error detecting audio onsets of stream %d: %w", stream.StreamIndex, err)
+		}
+		stream.ContentOffset, stream.ContentMatches = estimateContentOffset(cuts, onsets)
+	}
+
+	a.evaluate(report)
+	return nil
+}
+
+// Check compares the start time, duration and edit list of every audio and subtitle
+// stream with the primary video stream. It uses probe data only and does not decode the file.
+func (a *SyncAnalyzer) Check(info *ContainerInfo) (*SyncReport, error) {
+	video, ok := primaryVideoStream(info)
+	if !ok {
+		return nil, fmt.Errorf("no video stream to compare with")
+	}
+
+	report := &SyncReport{
+		VideoStreamIndex: video.Index,
+		VideoStartTime:   video.StartTime,
+		VideoDuration:    video.Duration,
+	}
+	videoShift := syncEditShift(info.MP4Structure, video.Index)
+
+	addStream := func(index int, streamType string, startTime, duration float64) {
+		offset := SyncStreamOffset{
+			StreamIndex:   index,
+			Type:          streamType,
+			StartTime:     startTime,
+			StartDelta:    startTime - video.StartTime,
+			Duration:      duration,
+			EditListDelta: syncEditShift(info.MP4Structure, index) - videoShift,
+		}
+		if duration > 0 && video.Duration > 0 {
+			offset.DurationDelta = duration - video.Duration
+		}
+		report.Streams = append(report.Streams, offset)
+	}
+
+	for _, stream := range info.AudioStreams {
+		addStream(stream.Index, "audio", stream.StartTime, stream.Duration)
+	}
+	for _, stream := range info.SubtitleStreams {
+		addStream(stream.Index, "subtitle", stream.StartTime, stream.Duration)
+	}
+
+	a.evaluate(report)
+	return report, nil
+}
@@ -0,0 +1,139 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the A/V sync analyzer.
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// SyncAnalyzerTestSuite defines the test suite for SyncAnalyzer.
+// The checks run on synthetic container information and filter output.
+type SyncAnalyzerTestSuite struct {
+	suite.Suite
+	analyzer *SyncAnalyzer // SyncAnalyzer instance under test
+}
+
+// SetupSuite creates an analyzer with the default threshold.
+func (s *SyncAnalyzerTestSuite) SetupSuite() {
+	s.analyzer = &SyncAnalyzer{Threshold: DefaultSyncThreshold}
+}
+
+// TestCheck verifies start, duration and edit list deltas against the primary video stream.
+func (s *SyncAnalyzerTestSuite) TestCheck() {
+	info := &ContainerInfo{
+		VideoStreams: []VideoStream{
+			{Index: 0, Disposition: StreamDisposition{AttachedPic: true}},
+			{Index: 1, StartTime: 0.042, Duration: 60},
+		},
+		AudioStreams: []AudioStream{
+			{Index: 2, StartTime: 0.042, Duration: 60.021},
+			{Index: 3, StartTime: 0.242, Duration: 59.8},
+		},
+		SubtitleStreams: []SubtitleStream{
+			{Index: 4, StartTime: 12.5},
+		},
+	}
+
+	report, err := s.analyzer.Check(info)
+	require.NoError(s.T(), err)
+
+	assert.Equal(s.T(), 1, report.VideoStreamIndex)
+	assert.Equal(s.T(), DefaultSyncThreshold, report.Threshold)
+	assert.False(s.T(), report.Passed)
+	require.Len(s.T(), report.Streams, 3)
+
+	assert.Equal(s.T(), "audio", report.Streams[0].Type)
+	assert.InDelta(s.T(), 0.0, report.Streams[0].StartDelta, 1e-9)
+	assert.InDelta(s.T(), 0.021, report.Streams[0].DurationDelta, 1e-9)
+	assert.False(s.T(), report.Streams[0].OutOfSync)
+
+	assert.InDelta(s.T(), 0.2, report.Streams[1].StartDelta, 1e-9)
+	assert.True(s.T(), report.Streams[1].OutOfSync)
+
+	// Subtitles start with their first cue and have no declared duration
+	assert.Equal(s.T(), "subtitle", report.Streams[2].Type)
+	assert.InDelta(s.T(), 12.458, report.Streams[2].StartDelta, 1e-9)
+	assert.Zero(s.T(), report.Streams[2].DurationDelta)
+	assert.False(s.T(), report.Streams[2].OutOfSync)
+
+	_, err = s.analyzer.Check(&ContainerInfo{AudioStreams: info.AudioStreams})
+	assert.Error(s.T(), err)
+}
+
+// TestCheckEditList verifies that MP4 edit list shifts are compared with the video track.
+func (s *SyncAnalyzerTestSuite) TestCheckEditList() {
+	info := &ContainerInfo{
+		VideoStreams: []VideoStream{{Index: 0}},
+		AudioStreams: []AudioStream{{Index: 1}, {Index: 2}},
+		MP4Structure: &MP4Structure{Tracks: []MP4Track{
+			{TrackID: 1, EditList: &MP4EditList{StartShift: -0.067}},
+			{TrackID: 2, EditList: &MP4EditList{StartShift: -0.046}},
+			{TrackID: 3, EditList: &MP4EditList{StartShift: 0.133}},
+		}},
+	}
+
+	report, err := s.analyzer.Check(info)
+	require.NoError(s.T(), err)
+
+	assert.InDelta(s.T(), 0.021, report.Streams[0].EditListDelta, 1e-9)
+	assert.False(s.T(), report.Streams[0].OutOfSync)
+	assert.InDelta(s.T(), 0.2, report.Streams[1].EditListDelta, 1e-9)
+	assert.True(s.T(), report.Streams[1].OutOfSync)
+}
+
+// TestParseFilterOutput verifies parsing of scene cuts and audio onsets.
+func (s *SyncAnalyzerTestSuite) TestParseFilterOutput() {
+	scenes := "frame:0    pts:0       pts_time:0\nlavfi.scene_score=1.000000\n" +
+		"frame:1    pts:12012   pts_time:12.012\nlavfi.scene_score=0.612000\n"
+	cuts, err := parseSceneCuts(strings.NewReader(scenes))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []float64{0, 12.012}, cuts)
+
+	silences := "frame:10   pts:4800    pts_time:0.1\nlavfi.silence_start=0\n" +
+		"frame:52   pts:24960   pts_time:0.52\nlavfi.silence_end=0.52\nlavfi.silence_duration=0.52\n"
+	onsets, err := parseAudioOnsets(strings.NewReader(silences))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []float64{0.52}, onsets)
+}
+
+// TestEstimateContentOffset verifies that the offset agreed by most onsets wins over stray matches.
+func (s *SyncAnalyzerTestSuite) TestEstimateContentOffset() {
+	cuts := []float64{20, 5, 10, 15}
+	onsets := []float64{5.2, 10.205, 15.195, 17.0, 20.45, 30}
+
+	offset, matches := estimateContentOffset(cuts, onsets)
+	assert.Equal(s.T(), 3, matches)
+	assert.InDelta(s.T(), 0.2, offset, 1e-9)
+
+	// Too few agreeing onsets produce no estimate
+	_, matches = estimateContentOffset(cuts, onsets[:2])
+	assert.Zero(s.T(), matches)
+	_, matches = estimateContentOffset(nil, onsets)
+	assert.Zero(s.T(), matches)
+
+	// A content offset above the threshold marks the stream as out of sync
+	report := &SyncReport{Streams: []SyncStreamOffset{{Type: "audio", ContentOffset: offset, ContentMatches: matches + 3}}}
+	s.analyzer.evaluate(report)
+	assert.True(s.T(), report.Streams[0].OutOfSync)
+	assert.False(s.T(), report.Passed)
+}
+
+// TestNewSyncAnalyzer verifies constructor validation and the default threshold.
+func (s *SyncAnalyzerTestSuite) TestNewSyncAnalyzer() {
+	_, err := NewSyncAnalyzer(nil, 0.1)
+	assert.Error(s.T(), err)
+
+	analyzer, err := NewSyncAnalyzer(&FFmpegInfo{Installed: true, Path: "/usr/bin/ffmpeg"}, 0)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), DefaultSyncThreshold, analyzer.Threshold)
+}
+
+// TestSyncAnalyzerSuite runs the SyncAnalyzer test suite.
+func TestSyncAnalyzerSuite(t *testing.T) {
+	suite.Run(t, new(SyncAnalyzerTestSuite))
+}
@@ -0,0 +1,108 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+// Public functions (alphabetical)
+
+// NewTimestampReport creates an empty TimestampReport for a stream with the given frame rate.
+// Without a frame rate the gaps between frames are not checked.
+func NewTimestampReport(frameRate float64) *TimestampReport {
+	return &TimestampReport{
+		FrameRate: frameRate,
+		previous:  FrameBitrateInfo{PTS: NoTimestamp, DTS: NoTimestamp},
+	}
+}
+
+// Private methods (alphabetical)
+
+// add records an anomaly of the frame compared with the previous one.
+func (r *TimestampReport) add(frame FrameBitrateInfo, anomalyType, severity string, delta float64) {
+	r.Anomalies = append(r.Anomalies, TimestampAnomaly{
+		FrameNumber: frame.FrameNumber,
+		Time:        frame.Time,
+		Type:        anomalyType,
+		Severity:    severity,
+		PTS:         frame.PTS,
+		DTS:         frame.DTS,
+		PreviousPTS: r.previous.PTS,
+		PreviousDTS: r.previous.DTS,
+		Delta:       delta,
+	})
+}
+
+// checkDTS compares the decoding timestamp of the frame with the previous frame.
+func (r *TimestampReport) checkDTS(frame FrameBitrateInfo) {
+	if frame.DTS == NoTimestamp || r.previous.DTS == NoTimestamp || frame.DTS > r.previous.DTS {
+		return
+	}
+	if r.previous.DTS-frame.DTS >= timestampWrapThreshold {
+		r.add(frame, TimestampWraparound, SeverityWarning, 0)
+		return
+	}
+	r.add(frame, TimestampNonMonotonicDTS, SeverityError, 0)
+}
+
+// checkPTS compares the presentation timestamp of the frame with the previous frame.
+// Frames arrive in presentation order, so their timestamps must increase by about one frame.
+func (r *TimestampReport) checkPTS(frame FrameBitrateInfo) {
+	if r.previous.PTS == NoTimestamp {
+		return
+	}
+
+	delta := frame.Time - r.previous.Time
+	switch {
+	case frame.PTS == r.previous.PTS:
+		r.add(frame, TimestampDuplicatePTS, SeverityError, 0)
+	case r.previous.PTS-frame.PTS >= timestampWrapThreshold:
+		r.add(frame, TimestampWraparound, SeverityWarning, 0)
+	case frame.PTS < r.previous.PTS || delta > timestampJumpThreshold:
+		r.add(frame, TimestampJump, SeverityError, delta)
+	case r.FrameRate > 0 && delta > timestampGapFactor/r.FrameRate:
+		r.add(frame, TimestampGap, SeverityWarning, delta)
+	}
+}
+
+// Public methods (alphabetical)
+
+// AddFrame checks the timestamps of the frame against the previous frame.
+// Frames are expected in presentation order, as the BitrateAnalyzer sends them.
+func (r *TimestampReport) AddFrame(frame FrameBitrateInfo) {
+	// While 33-bit timestamps wrap around, the PTS may already have wrapped and the DTS not yet
+	if frame.PTS != NoTimestamp && frame.DTS != NoTimestamp && frame.PTS < frame.DTS && frame.DTS-frame.PTS < timestampWrapThreshold {
+		r.add(frame, TimestampPTSBeforeDTS, SeverityError, 0)
+	}
+
+	if frame.PTS == NoTimestamp {
+		r.add(frame, TimestampMissingPTS, SeverityWarning, 0)
+	} else {
+		r.checkPTS(frame)
+	}
+	r.checkDTS(frame)
+
+	r.previous = frame
+	r.Frames++
+}
+
+// Count returns the number of anomalies of the given type.
+func (r *TimestampReport) Count(anomalyType string) int {
+	count := 0
+	for _, anomaly := range r.Anomalies {
+		if anomaly.Type == anomalyType {
+			count++
+		}
+	}
+	return count
+}
+
+// CountSeverity returns the number of anomalies of the given severity.
+func (r *TimestampReport) CountSeverity(severity string) int {
+	count := 0
+	for _, anomaly := range r.Anomalies {
+		if anomaly.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,117 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the timestamp check.
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// TimestampReportTestSuite defines the test suite for TimestampReport.
+// It uses synthetic frames so no sample media is required.
+type TimestampReportTestSuite struct {
+	suite.Suite
+}
+
+// timestampTestFrame returns frame n of a 25 fps stream with a 90 kHz time base.
+func timestampTestFrame(n int) FrameBitrateInfo {
+	return FrameBitrateInfo{FrameNumber: n, PTS: int64(n) * 3600, DTS: int64(n-1) * 3600, Time: float64(n) / 25}
+}
+
+// TestCleanStream verifies that evenly spaced timestamps are not reported.
+func (s *TimestampReportTestSuite) TestCleanStream() {
+	report := NewTimestampReport(25)
+	for n := 0; n < 100; n++ {
+		report.AddFrame(timestampTestFrame(n))
+	}
+	assert.Equal(s.T(), 100, report.Frames)
+	assert.Empty(s.T(), report.Anomalies)
+}
+
+// TestAnomalies verifies every anomaly type with its frame number and severity.
+func (s *TimestampReportTestSuite) TestAnomalies() {
+	tests := []struct {
+		name     string
+		modify   func(frame *FrameBitrateInfo)
+		expected []string
+		severity string
+	}{
+		{"duplicate PTS", func(frame *FrameBitrateInfo) { frame.PTS, frame.Time = 3600, 0.04 },
+			[]string{TimestampDuplicatePTS}, SeverityError},
+		{"PTS before DTS", func(frame *FrameBitrateInfo) { frame.DTS = 7300 },
+			[]string{TimestampPTSBeforeDTS}, SeverityError},
+		{"non-monotonic DTS", func(frame *FrameBitrateInfo) { frame.DTS = 0 },
+			[]string{TimestampNonMonotonicDTS}, SeverityError},
+		{"gap", func(frame *FrameBitrateInfo) { frame.PTS, frame.Time = 10800, 0.12 },
+			[]string{TimestampGap}, SeverityWarning},
+		{"jump", func(frame *FrameBitrateInfo) { frame.PTS, frame.Time = 180000, 2 },
+			[]string{TimestampJump}, SeverityError},
+		{"missing PTS", func(frame *FrameBitrateInfo) { frame.PTS = NoTimestamp },
+			[]string{TimestampMissingPTS}, SeverityWarning},
+	}
+
+	for _, tt := range tests {
+		// The last of three frames is modified
+		report := NewTimestampReport(25)
+		for n := 0; n < 2; n++ {
+			report.AddFrame(timestampTestFrame(n))
+		}
+		frame := timestampTestFrame(2)
+		tt.modify(&frame)
+		report.AddFrame(frame)
+
+		types := make([]string, 0, len(report.Anomalies))
+		for _, anomaly := range report.Anomalies {
+			types = append(types, anomaly.Type)
+		}
+		assert.Equal(s.T(), tt.expected, types, tt.name)
+		if len(report.Anomalies) > 0 {
+			assert.Equal(s.T(), 2, report.Anomalies[0].FrameNumber, tt.name)
+			assert.Equal(s.T(), tt.severity, report.Anomalies[0].Severity, tt.name)
+			assert.Equal(s.T(), int64(3600), report.Anomalies[0].PreviousPTS, tt.name)
+		}
+	}
+}
+
+// TestCounts verifies the counts by type and severity and the distance of a gap.
+func (s *TimestampReportTestSuite) TestCounts() {
+	report := NewTimestampReport(25)
+	for _, n := range []int{0, 1, 3, 4, 4, 6} {
+		report.AddFrame(timestampTestFrame(n))
+	}
+
+	require.Len(s.T(), report.Anomalies, 4)
+	assert.InDelta(s.T(), 0.08, report.Anomalies[0].Delta, 1e-9)
+	assert.Equal(s.T(), 2, report.Count(TimestampGap))
+	assert.Equal(s.T(), 1, report.Count(TimestampDuplicatePTS))
+	assert.Equal(s.T(), 1, report.Count(TimestampNonMonotonicDTS))
+	assert.Equal(s.T(), 2, report.CountSeverity(SeverityError))
+	assert.Equal(s.T(), 2, report.CountSeverity(SeverityWarning))
+}
+
+// TestBackwardJumpAndWraparound verifies that 33-bit wraparounds are told apart from timestamps going back.
+func (s *TimestampReportTestSuite) TestBackwardJumpAndWraparound() {
+	report := NewTimestampReport(0)
+	wrap := int64(1) << 33
+	report.AddFrame(FrameBitrateInfo{FrameNumber: 0, PTS: wrap - 3600, DTS: wrap - 7200, Time: 95443.677})
+	report.AddFrame(FrameBitrateInfo{FrameNumber: 1, PTS: 0, DTS: wrap - 3600, Time: 0})
+	report.AddFrame(FrameBitrateInfo{FrameNumber: 2, PTS: 3600, DTS: 0, Time: 0.04})
+	report.AddFrame(FrameBitrateInfo{FrameNumber: 3, PTS: 1800, DTS: 1800, Time: 0.02})
+
+	require.Len(s.T(), report.Anomalies, 3)
+	assert.Equal(s.T(), TimestampWraparound, report.Anomalies[0].Type)
+	assert.Equal(s.T(), SeverityWarning, report.Anomalies[0].Severity)
+	assert.Equal(s.T(), 1, report.Anomalies[0].FrameNumber)
+	assert.Equal(s.T(), TimestampWraparound, report.Anomalies[1].Type)
+	assert.Equal(s.T(), 2, report.Anomalies[1].FrameNumber)
+	assert.Equal(s.T(), TimestampJump, report.Anomalies[2].Type)
+	assert.InDelta(s.T(), -0.02, report.Anomalies[2].Delta, 1e-9)
+}
+
+// TestTimestampReportSuite runs the TimestampReport test suite.
+func TestTimestampReportSuite(t *testing.T) {
+	suite.Run(t, new(TimestampReportTestSuite))
+}
@@ -6,11 +6,40 @@ package ffmpeg
 
 import (
 	"encoding/json"
+	"encoding/xml"
+	"io"
 	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
 )
 
 // Private types (alphabetical)
 
+// artifactMetadata holds the blockdetect and blurdetect values printed for a frame.
+type artifactMetadata struct {
+	frameNumber int     // Frame number
+	time        float64 // Presentation time in seconds
+	blockiness  float64 // blockdetect value
+	blur        float64 // blurdetect value
+}
+
+// audioQCChannel holds the astats measurements of a channel in an analysis window.
+type audioQCChannel struct {
+	dcOffset  float64 // Average sample value relative to full scale
+	peakCount float64 // Number of samples at the peak level
+	peakLevel float64 // Peak level in dBFS
+	rmsLevel  float64 // RMS level in dBFS
+}
+
+// audioQCWindow holds the measurements of an analysis window.
+type audioQCWindow struct {
+	time     float64                 // Start of the window in seconds
+	channels map[int]*audioQCChannel // Measurements by 1-based channel number
+	phase    float64                 // Stereo phase correlation, valid when hasPhase is set
+	hasPhase bool                    // Whether the phase was measured
+}
+
 // chapterOutput represents a chapter's metadata in the ffprobe JSON output.
 type chapterOutput struct {
 	ID        int64             `json:"id"`
@@ -22,6 +51,12 @@ type chapterOutput struct {
 	Tags      map[string]string `json:"tags,omitempty"`
 }
 
+// cropSample is the crop rectangle cropdetect proposed for a sampled frame.
+type cropSample struct {
+	time float64  // Presentation time in seconds
+	rect CropRect // Proposed crop
+}
+
 // ffprobeFormatOutput represents a container's format metadata in the ffprobe JSON output.
 type ffprobeFormatOutput struct {
 	Filename         string            `json:"filename"`
@@ -45,11 +80,14 @@ type ffprobeFrameInfo struct {
 	MediaType      string      `json:"media_type"`
 	StreamIndex    int         `json:"stream_index"`
 	KeyFrame       int         `json:"key_frame"`
+	Pts            json.Number `json:"pts"`
 	PktPts         json.Number `json:"pkt_pts"`
 	PktPtsTime     string      `json:"pkt_pts_time"`
 	PktDts         json.Number `json:"pkt_dts"`
 	PktDtsTime     string      `json:"pkt_dts_time"`
 	BestEffortPts  json.Number `json:"best_effort_pts"`
+	BestEffortTime string      `json:"best_effort_timestamp_time"`
+	PtsTime        string      `json:"pts_time"`
 	PktDuration    json.Number `json:"pkt_duration"`
 	PktSize        json.Number `json:"pkt_size"`
 	Width          int         `json:"width"`
@@ -66,12 +104,18 @@ type ffprobeOutput struct {
 	Chapters []chapterOutput       `json:"chapters,omitempty"`
 }
 
+// ffprobePacketsOutput represents the packet list printed by ffprobe -show_packets.
+type ffprobePacketsOutput struct {
+	Packets []ffprobeSubtitlePacket `json:"packets"`
+}
+
 // ffprobeStreamOutput represents a stream's metadata in the ffprobe JSON output.
 type ffprobeStreamOutput struct {
 	Index              int               `json:"index"`
 	CodecName          string            `json:"codec_name"`
 	CodecLongName      string            `json:"codec_long_name"`
 	Profile            string            `json:"profile"`
+	Level              int               `json:"level,omitempty"`
 	CodecType          string            `json:"codec_type"`
 	CodecTagString     string            `json:"codec_tag_string"`
 	CodecTag           string            `json:"codec_tag"`
@@ -100,30 +144,228 @@ type ffprobeStreamOutput struct {
 	Tags               map[string]string `json:"tags,omitempty"`
 }
 
+// ffprobeSubtitlePacket represents the timing of a subtitle packet in the ffprobe JSON output.
+type ffprobeSubtitlePacket struct {
+	PtsTime      string `json:"pts_time"`
+	DurationTime string `json:"duration_time"`
+	Size         string `json:"size"`
+}
+
+// fingerprintKeyframe is the difference hash of a keyframe decoded by a FingerprintAnalyzer.
+type fingerprintKeyframe struct {
+	time float64 // Presentation time in seconds
+	hash uint64  // Difference hash of the downscaled luma
+	flat bool    // Whether the frame has too little detail to be told apart, such as a black frame
+}
+
+// keyframePosition is a frame decoding can start from, recorded by a KeyframeReport.
+type keyframePosition struct {
+	frame int     // Frame number in presentation order
+	time  float64 // Presentation time in seconds
+	idr   bool    // Whether the frame is flagged as a key frame (IDR) and not only an I-frame
+}
+
+// mkvElement is an EBML element header located while walking a Matroska file.
+type mkvElement struct {
+	id         uint32
+	dataOffset int64
+	dataSize   int64 // -1 when the element has an unknown size
+}
+
+// mp4EditEntry is a single raw entry of an elst box.
+type mp4EditEntry struct {
+	segmentDuration uint64
+	mediaTime       int64
+}
+
+// mp4WalkState carries the information collected while walking an ISO-BMFF box tree.
+type mp4WalkState struct {
+	file           io.ReaderAt
+	movieTimescale uint32
+	structure      *MP4Structure
+	rawEdits       map[*MP4Track][]mp4EditEntry
+}
+
+// mpdAdaptationSet is an AdaptationSet element of a DASH manifest.
+type mpdAdaptationSet struct {
+	ContentType     string              `xml:"contentType,attr"`
+	MimeType        string              `xml:"mimeType,attr"`
+	Codecs          string              `xml:"codecs,attr"`
+	BaseURL         string              `xml:"BaseURL"`
+	SegmentTemplate *mpdSegmentTemplate `xml:"SegmentTemplate"`
+	SegmentList     *mpdSegmentList     `xml:"SegmentList"`
+	Representations []mpdRepresentation `xml:"Representation"`
+}
+
+// mpdDocument is the root MPD element of a DASH manifest.
+type mpdDocument struct {
+	XMLName                   xml.Name    `xml:"MPD"`
+	MediaPresentationDuration string      `xml:"mediaPresentationDuration,attr"`
+	BaseURL                   string      `xml:"BaseURL"`
+	Periods                   []mpdPeriod `xml:"Period"`
+}
+
+// mpdPeriod is a Period element of a DASH manifest.
+type mpdPeriod struct {
+	Duration       string             `xml:"duration,attr"`
+	BaseURL        string             `xml:"BaseURL"`
+	AdaptationSets []mpdAdaptationSet `xml:"AdaptationSet"`
+}
+
+// mpdRepresentation is a Representation element of a DASH manifest.
+type mpdRepresentation struct {
+	ID              string              `xml:"id,attr"`
+	Bandwidth       int64               `xml:"bandwidth,attr"`
+	Width           int                 `xml:"width,attr"`
+	Height          int                 `xml:"height,attr"`
+	Codecs          string              `xml:"codecs,attr"`
+	MimeType        string              `xml:"mimeType,attr"`
+	BaseURL         string              `xml:"BaseURL"`
+	SegmentTemplate *mpdSegmentTemplate `xml:"SegmentTemplate"`
+	SegmentList     *mpdSegmentList     `xml:"SegmentList"`
+}
+
+// mpdSegmentList is a SegmentList element listing the segments of a representation.
+type mpdSegmentList struct {
+	Timescale      int64 `xml:"timescale,attr"`
+	Duration       int64 `xml:"duration,attr"`
+	Initialization *struct {
+		SourceURL string `xml:"sourceURL,attr"`
+	} `xml:"Initialization"`
+	SegmentURLs []struct {
+		Media string `xml:"media,attr"`
+	} `xml:"SegmentURL"`
+}
+
+// mpdSegmentTemplate is a SegmentTemplate element describing the segment names of a representation.
+type mpdSegmentTemplate struct {
+	Media          string `xml:"media,attr"`
+	Initialization string `xml:"initialization,attr"`
+	StartNumber    *int64 `xml:"startNumber,attr"`
+	Timescale      int64  `xml:"timescale,attr"`
+	Duration       int64  `xml:"duration,attr"`
+	Timeline       []struct {
+		T *int64 `xml:"t,attr"`
+		D int64  `xml:"d,attr"`
+		R int64  `xml:"r,attr"`
+	} `xml:"SegmentTimeline>S"`
+}
+
+// queryCondition compares a spec field with a value, for a Query.
+type queryCondition struct {
+	field    string // Spec field, such as video.codec
+	operator string // Comparison: =, !=, <, <=, >, >= or ~ (contains)
+	value    any    // String or float64 value to compare with
+}
+
+// sceneCut is a frame detected by scdet as the start of a new scene.
+type sceneCut struct {
+	frame int     // Frame number in presentation order
+	time  float64 // Presentation time in seconds
+	score float64 // scdet score (0-100)
+}
+
+// specField reads the value of a delivery spec field from the analysis of a file.
+type specField struct {
+	requires string                                // Analysis the field needs beyond probing, empty for none
+	values   func(info *ContainerInfo) []specValue // Values of the field, one per checked stream
+}
+
+// specValue is the value of a spec field for a single stream or for the container.
+type specValue struct {
+	subject string // Stream or container the value belongs to
+	value   any    // String or float64 value, nil when the value was not measured
+}
+
 // StreamInfo holds common information for different stream types.
 type StreamInfo struct {
-	Index      int
-	Format     string
-	FormatFull string
-	Title      string
-	Language   string
+	Index       int
+	Format      string
+	FormatFull  string
+	Title       string
+	Language    string
+	Disposition StreamDisposition
+	Tags        map[string]string
+	StartTime   float64
 }
 
 // Public types (alphabetical)
 
+// ArtifactAnalyzer measures visible compression artifacts without a reference.
+// It combines the FFmpeg blockdetect and blurdetect filters with a banding estimate.
+type ArtifactAnalyzer struct {
+	FFmpegPath string // Path to the FFmpeg executable
+	MaxWidth   int    // Width frames are downscaled to for the banding estimate
+}
+
+// ArtifactFrame contains the artifact scores of a single frame.
+// FrameNumber matches the one of the QualityFrame of the same frame.
+type ArtifactFrame struct {
+	FrameNumber  int          // The frame number (starting from 0)
+	Time         float64      // Presentation time in seconds
+	Blockiness   float64      // blockdetect value, higher is blockier
+	Blur         float64      // blurdetect value, higher is blurrier
+	Banding      float64      // Share of pixels on false contours in percent, higher is more banded
+	Score        float64      // Combined artifact score (0-100, higher is better)
+	QualityLevel QualityLevel // Categorical quality level of the score
+}
+
+// ArtifactSummary rolls the artifact scores of a video stream up.
+type ArtifactSummary struct {
+	Frames            int          // Number of analyzed frames
+	AverageBlockiness float64      // Average blockdetect value
+	MaxBlockiness     float64      // Highest blockdetect value
+	AverageBlur       float64      // Average blurdetect value
+	MaxBlur           float64      // Highest blurdetect value
+	AverageBanding    float64      // Average share of pixels on false contours in percent
+	MaxBanding        float64      // Highest share of pixels on false contours in percent
+	AverageScore      float64      // Average combined artifact score
+	MinScore          float64      // Lowest combined artifact score
+	WorstFrame        int          // Frame number with the lowest score
+	QualityLevel      QualityLevel // Categorical quality level of the average score
+}
+
+// AudioQCAnalyzer detects defects in audio streams using FFmpeg filters.
+// It finds clipping, silences, silent channels, out of phase stereo, DC offset and dropouts.
+type AudioQCAnalyzer struct {
+	FFmpegPath string // Path to the FFmpeg executable
+}
+
+// AudioQCEvent is a defect found in an audio stream.
+type AudioQCEvent struct {
+	Type        string  // Defect type, one of the AudioQC constants
+	Channel     int     // 1-based channel number, 0 when the defect affects all channels
+	Start       float64 // Start time in seconds
+	End         float64 // End time in seconds
+	Value       float64 // Characteristic value (peak level, RMS level, phase, DC offset or duration)
+	Description string  // Human readable description
+}
+
+// AudioQCReport contains the defects found in an audio stream.
+type AudioQCReport struct {
+	StreamIndex int            // Index of the analyzed stream
+	Duration    float64        // Analyzed duration in seconds
+	Events      []AudioQCEvent // Defects ordered by start time
+}
+
 // AudioStream encapsulates information about an audio stream found in a media file.
 // It provides access to key audio properties such as codec, channels, bitrate and duration.
 type AudioStream struct {
-	Index         int     // Stream index
-	Format        string  // Audio codec name
-	FormatFull    string  // Full codec name
-	Channels      int     // Number of audio channels
-	ChannelLayout string  // Layout of audio channels
-	SamplingRate  int     // Audio sampling rate in Hz
-	BitRate       int64   // Bit rate in bits per second
-	Duration      float64 // Duration in seconds
-	Language      string  // Language code
-	Title         string  // Stream title
+	Index         int               // Stream index
+	Format        string            // Audio codec name
+	FormatFull    string            // Full codec name
+	Channels      int               // Number of audio channels
+	ChannelLayout string            // Layout of audio channels
+	SamplingRate  int               // Audio sampling rate in Hz
+	BitRate       int64             // Bit rate in bits per second
+	Duration      float64           // Duration in seconds
+	StartTime     float64           // Start time in seconds
+	Language      string            // Language code
+	Title         string            // Stream title
+	Disposition   StreamDisposition // Disposition flags
+	Tags          map[string]string // Raw stream tags
+	Loudness      *LoudnessReport   // Loudness measurements, nil until a LoudnessAnalyzer has run
+	QC            *AudioQCReport    // Defect analysis, nil until an AudioQCAnalyzer has run
 }
 
 // AttachmentStream represents an attachment embedded within a media container.
@@ -132,6 +374,8 @@ type AttachmentStream struct {
 	Index    int    // Stream index
 	FileName string // Attached file name
 	MimeType string // MIME type
+	Size     int64  // Attached data size in bytes, 0 when unknown
+	SHA256   string // Hex SHA-256 checksum of the attached data, empty when unknown
 }
 
 // BitrateAnalyzer provides methods to analyze frame-by-frame bitrate information from video files.
@@ -143,6 +387,76 @@ type BitrateAnalyzer struct {
 	mutex sync.Mutex
 }
 
+// BitrateStats summarizes the frames of a video stream measured by a BitrateAnalyzer.
+// It measures the peak bitrate and whether the frame rate is constant, which the
+// container does not declare reliably.
+type BitrateStats struct {
+	FrameRate         float64 // Declared frames per second, used for the one second peak window
+	Frames            int     // Number of frames measured
+	TotalBits         int64   // Sum of the frame sizes in bits
+	Duration          float64 // Time from the first frame to the end of the last one in seconds, set by Finish
+	AverageBitrate    float64 // Average bitrate in bits per second, set by Finish
+	PeakBitrate       float64 // Highest bitrate over one second of frames in bits per second
+	MeasuredFrameRate float64 // Frames per second measured from the timestamps, set by Finish
+	FrameRateMode     string  // FrameRateModeConstant or FrameRateModeVariable, set by Finish
+
+	window      []int64 // Frame sizes of the last second
+	windowSum   int64   // Sum of the frame sizes in window
+	first       float64 // Presentation time of the first frame
+	last        float64 // Presentation time of the last frame
+	minInterval float64 // Shortest distance between consecutive frames
+	maxInterval float64 // Longest distance between consecutive frames
+}
+
+// BlackFreezeAnalyzer finds black segments, black flashes and frozen video in a video stream.
+// It wraps the FFmpeg blackdetect, blackframe and freezedetect filters in a single decoding pass.
+type BlackFreezeAnalyzer struct {
+	FFmpegPath        string  // Path to the FFmpeg executable
+	BlackMinDuration  float64 // Minimum duration in seconds of a black segment
+	PictureThreshold  float64 // Ratio of black pixels a frame needs to count as black
+	PixelThreshold    float64 // Luminance (relative to full range) below which a pixel is black
+	FreezeMinDuration float64 // Minimum duration in seconds of a frozen segment
+	FreezeNoise       float64 // Noise tolerance in dB between frames of a frozen segment
+	IgnoreEdges       bool    // Whether black at the start and at the end of the stream is ignored
+}
+
+// CadenceAnalyzer finds duplicate and dropped frames in a video stream.
+// It wraps the FFmpeg mpdecimate filter and checks the cadence of the frames it reports.
+type CadenceAnalyzer struct {
+	FFmpegPath      string  // Path to the FFmpeg executable
+	SegmentDuration float64 // Length in seconds of the segments with their own unique frame rate
+}
+
+// CadenceReport contains the duplicate and dropped frames of a video stream.
+type CadenceReport struct {
+	StreamIndex     int              // Index of the analyzed stream
+	FrameRate       float64          // Nominal frame rate used for the cadence check, 0 disables it
+	Frames          int              // Number of decoded frames
+	Duplicates      int              // Number of frames repeating the previous frame
+	Dropped         int              // Estimated number of frames missing from the cadence
+	UniqueFrameRate float64          // Frames per second not repeating the previous frame
+	Runs            []CadenceRun     // Duplicate runs and dropped frames in presentation order
+	Segments        []CadenceSegment // Fixed length segments of the stream
+}
+
+// CadenceRun is a run of duplicate frames or a group of dropped frames.
+type CadenceRun struct {
+	Type   string  // Run type, one of the Cadence constants
+	Start  float64 // Start time in seconds
+	End    float64 // End time in seconds
+	Frames int     // Number of duplicate or dropped frames
+}
+
+// CadenceSegment holds the cadence of a fixed length part of a video stream.
+type CadenceSegment struct {
+	Start           float64 // Start time in seconds
+	End             float64 // End time in seconds
+	Frames          int     // Number of decoded frames
+	Duplicates      int     // Number of frames repeating the previous frame
+	Dropped         int     // Estimated number of frames missing from the cadence
+	UniqueFrameRate float64 // Frames per second not repeating the previous frame
+}
+
 // ChapterStream represents a chapter marker within a media file.
 // Chapters allow navigation to specific points in the media content.
 type ChapterStream struct {
@@ -152,6 +466,43 @@ type ChapterStream struct {
 	Title     string  // Chapter title
 }
 
+// ComparedFile holds the measurements of a file of an EncodeComparison.
+type ComparedFile struct {
+	Path           string                    // Path of the file
+	Info           *ContainerInfo            // Container information
+	Frames         int                       // Number of video frames
+	TotalBits      int64                     // Sum of the video frame sizes in bits
+	Duration       float64                   // Time from the first frame to the end of the last frame in seconds
+	AverageBitrate float64                   // Average video bitrate in bits per second
+	AverageQP      float64                   // Average QP, valid when QPFrames > 0
+	QPFrames       int                       // Number of frames with a known QP
+	FrameTypes     map[string]*FrameTypeSize // Frame sizes by frame type (I, P, B, ...)
+	ScenesWon      int                       // Number of scenes where the file is the winner
+
+	start      float64   // Presentation time of the first frame, the origin of the aligned times
+	frameTimes []float64 // Presentation time of every frame by frame number
+	frameBits  []int64   // Size in bits of every frame by frame number
+	frameQP    []float64 // QP of every frame by frame number, 0 when unknown
+	sawFrame   []bool    // Whether the bitrate analysis reported the frame
+}
+
+// ComparedScene holds the measurements of every file of an EncodeComparison over a scene of the reference.
+type ComparedScene struct {
+	Index     int       // Scene number (starting from 0)
+	Start     float64   // Start time in seconds from the first frame
+	End       float64   // End time in seconds from the first frame
+	Bitrate   []float64 // Average bitrate of every file in bits per second
+	AverageQP []float64 // Average QP of every file, 0 when unknown
+	Winner    int       // Index of the winning file, -1 without comparable QPs or frames in the scene
+}
+
+// ComparisonBin holds the bitrate and QP of every file of an EncodeComparison over a time bin.
+type ComparisonBin struct {
+	Start     float64   // Start time in seconds from the first frame
+	Bitrate   []float64 // Bitrate of every file in bits per second
+	AverageQP []float64 // Average QP of every file, 0 when unknown
+}
+
 // ContainerInfo contains comprehensive information about a media container file.
 // It aggregates details about all streams and general container metadata.
 type ContainerInfo struct {
@@ -163,6 +514,52 @@ type ContainerInfo struct {
 	AttachmentStreams []AttachmentStream // Attachment streams
 	DataStreams       []DataStream       // Data streams
 	OtherStreams      []OtherStream      // Other streams
+	MP4Structure      *MP4Structure      // ISO-BMFF box structure, nil for other containers
+	MatroskaStructure *MatroskaStructure // Matroska/WebM structure, nil for other containers
+	Sync              *SyncReport        // A/V sync check, nil until a SyncAnalyzer has run
+	Scenes            *SceneReport       // Scenes of the primary video stream, nil until a SceneAnalyzer has run
+	Keyframes         *KeyframeReport    // Keyframe alignment of chapters and scene cuts, nil when not checked
+	Integrity         *IntegrityReport   // Decode integrity check, nil until an IntegrityAnalyzer has run
+	Timestamps        *TimestampReport   // Timestamp check of the primary video stream, nil when not checked
+	Checksums         *FileHash          // File and decoded content checksums, nil until a FrameHashAnalyzer has run
+	Bitrate           *BitrateStats      // Bitrate statistics of the primary video stream, nil until measured
+}
+
+// CropAnalyzer detects the active picture area of a video stream.
+// It runs the FFmpeg cropdetect filter on the keyframes of the stream.
+type CropAnalyzer struct {
+	FFmpegPath string // Path to the FFmpeg executable
+	Limit      int    // Luminance (0-255) at or below which a pixel is treated as black
+	Round      int    // Value the crop width and height are rounded to
+}
+
+// CropRect is a rectangle within the coded frame of a video stream, in pixels.
+type CropRect struct {
+	X      int // Horizontal offset of the left edge
+	Y      int // Vertical offset of the top edge
+	Width  int // Width of the rectangle
+	Height int // Height of the rectangle
+}
+
+// CropReport contains the active picture area detected in a video stream.
+type CropReport struct {
+	StreamIndex int           // Index of the analyzed stream
+	CodedWidth  int           // Width of the coded frame in pixels
+	CodedHeight int           // Height of the coded frame in pixels
+	Crop        CropRect      // Recommended crop, enclosing the active picture of every segment
+	AspectRatio float64       // Display aspect ratio of the recommended crop
+	Boxing      string        // Black bars around the active picture, one of the Boxing constants
+	Samples     int           // Number of sampled frames
+	Segments    []CropSegment // Consecutive parts of the stream with the same active picture area
+}
+
+// CropSegment is a part of a video stream with a single active picture area.
+type CropSegment struct {
+	Start       float64  // Start time in seconds
+	End         float64  // End time in seconds
+	Crop        CropRect // Active picture area
+	AspectRatio float64  // Display aspect ratio of the active picture area
+	Samples     int      // Number of sampled frames in the segment
 }
 
 // DataStream represents a data stream contained within a media file.
@@ -174,6 +571,21 @@ type DataStream struct {
 	Title      string // Stream title
 }
 
+// EncodeComparison compares several encodes of the same source. Frames are aligned on their
+// presentation time from the first frame of every file, so files with different start times
+// line up. The first file is the reference for ratios and scenes.
+type EncodeComparison struct {
+	Files        []ComparedFile       // Compared files, the reference first
+	Metadata     []MetadataDifference // Container and stream properties of every file
+	BinDuration  float64              // Length in seconds of the time bins
+	Bins         []ComparisonBin      // Bitrate and QP series, set by Finish
+	Scenes       []ComparedScene      // Scenes of the reference with the measurements of every file, set by Finish
+	Winner       int                  // Index of the file winning the most scenes, -1 without scenes, set by Finish
+	QPComparable bool                 // Whether the files use the same codec and have QPs, so scenes have winners, set by Finish
+
+	sceneStarts []float64 // Start times of the scenes of the reference, as presentation times
+}
+
 // ExecutablePaths contains the paths to FFmpeg and FFprobe executables.
 // This structure is useful for applications that need both executables.
 type ExecutablePaths struct {
@@ -200,6 +612,67 @@ type FFmpegInfo struct { //nolint:revive // Intentionally allowing stutter in ty
 	HasQPReadingInfoSupport bool
 }
 
+// FileHash holds the checksums of a media file.
+type FileHash struct {
+	Path        string       // Path of the hashed file
+	SHA256      string       // SHA-256 of the file bytes
+	ContentHash string       // SHA-256 of the content hashes of all streams, independent of the container
+	Streams     []StreamHash // Hashed streams, video streams first
+}
+
+// Fingerprint holds the perceptual hashes of the scenes of a video file, with the properties
+// needed to pick the best of several copies of the same content.
+type Fingerprint struct {
+	Path      string             `json:"path"`       // Absolute path of the file
+	Size      int64              `json:"size"`       // File size in bytes when fingerprinted
+	ModTime   time.Time          `json:"mod_time"`   // File modification time when fingerprinted
+	Duration  float64            `json:"duration"`   // Duration of the video stream in seconds
+	Format    string             `json:"format"`     // Video codec name
+	Width     int                `json:"width"`      // Frame width in pixels
+	Height    int                `json:"height"`     // Frame height in pixels
+	BitRate   int64              `json:"bit_rate"`   // Video bit rate in bits per second, 0 when unknown
+	AverageQP float64            `json:"average_qp"` // Average QP of the video, 0 when not measured
+	Scenes    []FingerprintScene `json:"scenes"`     // Scenes in presentation order
+}
+
+// FingerprintAnalyzer computes the perceptual hashes of the scenes of a video stream from its keyframes.
+type FingerprintAnalyzer struct {
+	FFmpegPath    string // Path to the FFmpeg executable
+	SceneDistance int    // Hamming distance between consecutive keyframe hashes that starts a new scene
+}
+
+// FingerprintCluster is a group of files with matching content.
+type FingerprintCluster struct {
+	Files   []*Fingerprint     // Files of the cluster, sorted by path
+	Matches []FingerprintMatch // Matching pairs of files that joined the cluster
+	Best    *Fingerprint       // Copy with the lowest QP, or the highest bit rate when the QPs cannot be compared
+}
+
+// FingerprintIndex stores the fingerprints of a directory so unchanged files are not decoded again.
+type FingerprintIndex struct {
+	Version int                     `json:"version"` // Format version of the index
+	Files   map[string]*Fingerprint `json:"files"`   // Fingerprints by absolute path
+}
+
+// FingerprintMatch describes the content shared by two files.
+type FingerprintMatch struct {
+	PathA  string  // Path of the first file
+	PathB  string  // Path of the second file
+	Scenes int     // Number of matching scenes
+	Offset float64 // Time of the shared content in the second file minus its time in the first, in seconds
+	StartA float64 // Start of the shared content in the first file, in seconds
+	EndA   float64 // End of the shared content in the first file, in seconds
+	StartB float64 // Start of the shared content in the second file, in seconds
+	EndB   float64 // End of the shared content in the second file, in seconds
+}
+
+// FingerprintScene is a scene of a fingerprinted file with the perceptual hash of its first keyframe.
+type FingerprintScene struct {
+	Start float64 `json:"start"` // Start time in seconds
+	End   float64 `json:"end"`   // End time in seconds
+	Hash  uint64  `json:"hash"`  // 64-bit difference hash (dHash) of the first keyframe
+}
+
 // FrameBitrateInfo captures bitrate information for a single video frame.
 // It provides detailed statistics about frame size, type, and timestamps.
 type FrameBitrateInfo struct {
@@ -209,10 +682,38 @@ type FrameBitrateInfo struct {
 	FrameType string `json:"frame_type"`
 	// Bitrate represents the size of the frame in bits
 	Bitrate int64 `json:"bitrate"`
-	// PTS is the presentation timestamp of the frame
+	// PTS is the presentation timestamp of the frame in stream time base units, NoTimestamp when unknown
 	PTS int64 `json:"pts"`
-	// DTS is the decoding timestamp of the frame
+	// DTS is the decoding timestamp of the frame in stream time base units, NoTimestamp when unknown
 	DTS int64 `json:"dts"`
+	// Time is the presentation time of the frame in seconds
+	Time float64 `json:"time"`
+	// KeyFrame indicates whether decoding can start at the frame (IDR)
+	KeyFrame bool `json:"key_frame"`
+}
+
+// FrameHash is the checksum of a decoded frame, as written by the FFmpeg framehash muxer.
+type FrameHash struct {
+	StreamIndex int     // Index of the stream in the file
+	Frame       int     // Frame number within the stream
+	PTS         int64   // Presentation timestamp in stream time base units
+	Time        float64 // Presentation time in seconds
+	Duration    int64   // Frame duration in stream time base units
+	Size        int     // Size in bytes of the decoded frame
+	Hash        string  // Checksum of the decoded frame
+}
+
+// FrameHashAnalyzer computes the checksum of every decoded frame of a file.
+// It wraps the FFmpeg framehash muxer, decoding video to raw pictures and audio to 16-bit PCM.
+type FrameHashAnalyzer struct {
+	FFmpegPath string // Path to the FFmpeg executable
+	Algorithm  string // Hash algorithm of the framehash muxer, such as md5 or sha256
+}
+
+// FrameHashComparison is the result of comparing the decoded frames of two files.
+type FrameHashComparison struct {
+	Identical bool                   // Whether every stream decodes to the same frames
+	Streams   []StreamHashComparison // Compared stream pairs, unmatched streams included
 }
 
 // FrameQP represents the QP (Quantization Parameter) data for a single video frame.
@@ -234,6 +735,14 @@ type FrameQP struct {
 	CodecType string `json:"codec_type,omitempty"`
 }
 
+// FrameTypeSize holds the sizes of the frames of a frame type in a compared file.
+type FrameTypeSize struct {
+	Frames      int     // Number of frames
+	TotalBits   int64   // Sum of the frame sizes in bits
+	AverageSize float64 // Average frame size in bits
+	Ratio       float64 // Average size relative to the same frame type in the reference, 0 when unknown
+}
+
 // GeneralInfo provides general metadata about a media container.
 // It includes details like format, duration, and file size that apply to the container as a whole.
 type GeneralInfo struct {
@@ -247,6 +756,240 @@ type GeneralInfo struct {
 	Tags        map[string]string // Metadata tags
 }
 
+// IntegrityAnalyzer checks whether the streams of a file decode without errors.
+// It decodes every stream in full with the FFmpeg error detection flags enabled.
+type IntegrityAnalyzer struct {
+	FFmpegPath          string  // Path to the FFmpeg executable
+	TruncationTolerance float64 // Largest gap in seconds between declared and decoded duration
+}
+
+// IntegrityEvent is an error reported while decoding a file. Times are taken from the FFmpeg
+// progress output and are accurate to about half a second.
+type IntegrityEvent struct {
+	StreamIndex int     // Index of the decoded stream, -1 for container errors
+	Time        float64 // Decoding position in seconds when the error was reported
+	Type        string  // Event type, one of the Integrity constants
+	Source      string  // FFmpeg component reporting the error, such as the decoder or demuxer name
+	Message     string  // Error message as printed by FFmpeg
+}
+
+// IntegrityReport collects the results of the integrity check of a file.
+type IntegrityReport struct {
+	Streams []IntegrityStream // Checked streams
+	Events  []IntegrityEvent  // Errors in the order they were reported
+}
+
+// IntegrityStream is the integrity check result of a single stream.
+type IntegrityStream struct {
+	StreamIndex      int     // Stream index
+	Type             string  // Stream type (video, audio)
+	Format           string  // Codec name, used to tell decoder errors from container errors
+	DeclaredDuration float64 // Duration declared by the container in seconds, 0 when unknown
+	DecodedDuration  float64 // Position in seconds of the end of the decoded data
+	Errors           int     // Number of errors reported while decoding the stream
+	Truncated        bool    // Whether the data ends before the declared duration
+	Failed           bool    // Whether FFmpeg stopped before reaching the end of the stream
+}
+
+// KeyframeAlignment is the distance between a chapter start or a scene cut and the nearest
+// I/IDR frame. Positive offsets mean the keyframe comes after the marker.
+type KeyframeAlignment struct {
+	Index         int     // Chapter ID or scene number
+	Title         string  // Chapter title, empty for scene cuts
+	Time          float64 // Time of the marker in seconds
+	Frame         int     // Frame at the marker in presentation order
+	KeyframeFrame int     // Nearest I/IDR frame, -1 when the stream has no keyframe
+	KeyframeTime  float64 // Presentation time of the nearest I/IDR frame in seconds
+	IDR           bool    // Whether the nearest keyframe is an IDR frame
+	OffsetFrames  int     // Distance to the keyframe in frames
+	OffsetMs      float64 // Distance to the keyframe in milliseconds
+	Aligned       bool    // Whether the marker lands on the keyframe
+}
+
+// KeyframeReport checks whether chapter starts and scene cuts land on keyframes.
+// Frames from the bitrate analyzer are added as they are measured.
+type KeyframeReport struct {
+	FrameRate float64             // Frames per second used to convert times to frame counts
+	Chapters  []KeyframeAlignment // Alignment of every chapter start
+	SceneCuts []KeyframeAlignment // Alignment of every scene cut
+
+	keyframes []keyframePosition // I/IDR frames in presentation order
+}
+
+// LoudnessAnalyzer measures audio loudness with the FFmpeg ebur128 filter.
+// Measurements are compared with a delivery target such as EBU R128 or ATSC A/85.
+type LoudnessAnalyzer struct {
+	FFmpegPath string         // Path to the FFmpeg executable
+	Target     LoudnessTarget // Target the measurements are checked against
+}
+
+// LoudnessReport contains the loudness measurements of an audio stream.
+// Loudness values are in LUFS, the loudness range in LU and the true peak in dBTP.
+type LoudnessReport struct {
+	StreamIndex         int              // Index of the analyzed stream
+	Target              LoudnessTarget   // Target used for the checks
+	IntegratedLoudness  float64          // Integrated loudness over the whole stream
+	IntegratedThreshold float64          // Relative gating threshold of the integrated loudness
+	LoudnessRange       float64          // Loudness range (LRA)
+	LoudnessRangeLow    float64          // Low end of the loudness range
+	LoudnessRangeHigh   float64          // High end of the loudness range
+	TruePeak            float64          // Maximum true peak
+	IntegratedPass      bool             // Integrated loudness is within the target tolerance
+	TruePeakPass        bool             // True peak does not exceed the target maximum
+	Passed              bool             // All checks passed
+	Samples             []LoudnessSample // Momentary and short-term series
+}
+
+// LoudnessSample is a point of the momentary and short-term loudness series.
+type LoudnessSample struct {
+	Time      float64 // Time in seconds
+	Momentary float64 // Momentary loudness (400 ms window) in LUFS
+	ShortTerm float64 // Short-term loudness (3 s window) in LUFS
+}
+
+// LoudnessTarget describes a loudness delivery specification.
+type LoudnessTarget struct {
+	Name               string  // Human readable name of the specification
+	IntegratedLoudness float64 // Target integrated loudness in LUFS
+	Tolerance          float64 // Allowed deviation from the target in LU
+	MaxTruePeak        float64 // Maximum true peak in dBTP
+}
+
+// Manifest is a local HLS or DASH package analyzed as a unit.
+// Segment sizes are measured when the manifest is inspected; the IDR frames of every
+// rendition are added from a bitrate analysis and checked with CheckAlignment.
+type Manifest struct {
+	Path           string      // Path of the playlist or MPD
+	Format         string      // ManifestHLS or ManifestDASH
+	TargetDuration float64     // Declared largest (HLS) or nominal (DASH) segment duration in seconds, 0 when not declared
+	Renditions     []Rendition // Renditions in manifest order
+	Issues         []string    // Human-readable findings of the checks
+}
+
+// ManifestInspector reads local HLS playlists and DASH manifests without invoking FFmpeg.
+// It resolves the renditions and segments on disk and measures the segment sizes.
+type ManifestInspector struct{}
+
+// ManifestSegment is a media segment (or the initialization segment) of a rendition.
+type ManifestSegment struct {
+	URI           string  // URI as written in the manifest
+	Path          string  // Resolved local path, empty for remote URIs
+	Start         float64 // Start time within the rendition in seconds
+	Duration      float64 // Declared duration in seconds
+	Offset        int64   // Byte offset of the segment within its file
+	Length        int64   // Byte length of the segment, -1 for the whole file
+	Size          int64   // Measured size in bytes, -1 when the file is missing
+	StartsWithIDR bool    // Whether an IDR frame starts the segment, set by CheckAlignment
+}
+
+// MatroskaAttachment describes a file attached to a Matroska segment.
+type MatroskaAttachment struct {
+	FileName    string // Attached file name
+	MimeType    string // MIME type
+	Description string // Optional description
+	Size        int64  // Attached data size in bytes
+	SHA256      string // Hex SHA-256 checksum of the attached data
+}
+
+// MatroskaCueGap represents a stretch of the timeline without any cue point.
+type MatroskaCueGap struct {
+	Start float64 // Gap start in seconds
+	End   float64 // Gap end in seconds
+}
+
+// MatroskaInspector reads the EBML structure of Matroska and WebM files without invoking FFmpeg.
+// It reports segment information, cue coverage, statistics tags and attachments.
+type MatroskaInspector struct{}
+
+// MatroskaStructure contains the result of a Matroska EBML walk.
+// It exposes the segment level metadata together with the checks derived from it.
+type MatroskaStructure struct {
+	DocType             string               // EBML document type (matroska, webm)
+	DocTypeVersion      uint64               // EBML document type version
+	Title               string               // Segment title
+	MuxingApp           string               // Library that muxed the segment
+	WritingApp          string               // Application that wrote the segment
+	TimestampScale      uint64               // Nanoseconds per timestamp tick
+	Duration            float64              // Segment duration in seconds
+	Tracks              []MatroskaTrack      // Track entries in declaration order
+	Attachments         []MatroskaAttachment // Attached files
+	CuePointCount       int                  // Number of cue points
+	FirstCueTime        float64              // Time of the first cue point in seconds
+	LastCueTime         float64              // Time of the last cue point in seconds
+	CueGaps             []MatroskaCueGap     // Stretches without cue points above the gap threshold
+	ClusterCount        int                  // Number of clusters in the segment
+	IndexedClusterCount int                  // Number of clusters referenced by at least one cue point
+	Issues              []string             // Human-readable findings of the structure checks
+}
+
+// MatroskaTrack describes a track entry of a Matroska segment.
+type MatroskaTrack struct {
+	Number          uint64            // Track number used by blocks
+	UID             uint64            // Track UID used by tags
+	Type            string            // Track type (video, audio, subtitle, ...)
+	CodecID         string            // Matroska codec identifier (V_MPEG4/ISO/AVC, A_AAC, ...)
+	Name            string            // Track name
+	Language        string            // Track language
+	Compression     string            // Content compression (header stripping, zlib, ...), empty when none
+	StatisticsTags  map[string]string // Statistics tags (BPS, NUMBER_OF_FRAMES, DURATION, ...)
+	StaleStatistics bool              // Whether the statistics tags disagree with the file
+}
+
+// MetadataDifference compares a container or stream property across files.
+type MetadataDifference struct {
+	Field   string   // Name of the property
+	Values  []string // Value of every file, empty when unknown
+	Differs bool     // Whether the files have different values
+}
+
+// MP4Box represents a single box (atom) of an ISO Base Media File Format file.
+// Container boxes such as moov or trak carry their nested boxes in Children.
+type MP4Box struct {
+	Type     string   // Four-character box type
+	Offset   int64    // Absolute offset of the box header in the file
+	Size     int64    // Total box size in bytes, header included
+	Children []MP4Box // Nested boxes for container box types
+}
+
+// MP4EditList summarizes the edit list (elst) of a single track.
+// It expresses how the edit list moves the track start on the presentation timeline.
+type MP4EditList struct {
+	EntryCount int     // Number of entries in the edit list
+	EmptyEdit  float64 // Initial empty edit (presentation delay) in seconds
+	MediaStart float64 // Media time skipped by the first non-empty edit in seconds
+	StartShift float64 // Net start shift (EmptyEdit - MediaStart) in seconds
+}
+
+// MP4Inspector walks the box hierarchy of MP4 and MOV files without invoking FFmpeg.
+// It reports the layout details that ffprobe hides, such as box order and edit lists.
+type MP4Inspector struct{}
+
+// MP4Structure contains the result of an ISO-BMFF box walk.
+// It exposes the box hierarchy together with the delivery checks derived from it.
+type MP4Structure struct {
+	MajorBrand       string     // Major brand declared in the ftyp box
+	MinorVersion     uint32     // Minor version declared in the ftyp box
+	CompatibleBrands []string   // Compatible brands declared in the ftyp box
+	Boxes            []MP4Box   // Top-level boxes in file order
+	Tracks           []MP4Track // Tracks found in the moov box
+	MoovOffset       int64      // Offset of the moov box, -1 when missing
+	MdatOffset       int64      // Offset of the first mdat box, -1 when missing
+	FastStart        bool       // Whether moov precedes the first mdat
+	Fragmented       bool       // Whether the file uses a fragmented layout
+	FragmentCount    int        // Number of moof boxes
+	HasSegmentIndex  bool       // Whether a sidx box is present
+	Issues           []string   // Human-readable findings of the delivery checks
+}
+
+// MP4Track describes a track (trak box) found while walking an MP4 file.
+type MP4Track struct {
+	TrackID            uint32       // Track identifier from tkhd
+	HandlerType        string       // Handler type from hdlr (vide, soun, subt, ...)
+	Timescale          uint32       // Media timescale from mdhd
+	SampleDescriptions int          // Number of entries in stsd
+	EditList           *MP4EditList // Edit list summary, nil when the track has none
+}
+
 // OtherStream represents any stream type in a media file that doesn't fit into standard categories.
 // It provides a way to access information about specialized or uncommon stream types.
 type OtherStream struct {
@@ -397,6 +1140,172 @@ type QualityReport struct {
 	QPReportSummary *QPReportSummary `json:"qp_report_summary,omitempty"`
 }
 
+// Query selects stored analyses by the values of spec fields, such as
+// "codec=hevc and avg_bitrate>8M". A field of a file with several streams matches when any of
+// its streams matches.
+type Query struct {
+	Text    string             // Query as written
+	clauses [][]queryCondition // Clauses joined by or, each a list of conditions joined by and
+}
+
+// Rendition is a variant (HLS) or representation (DASH) of a manifest with its checks.
+type Rendition struct {
+	ID                       string               // Playlist URI (HLS) or representation ID (DASH)
+	Type                     string               // RenditionVideo or RenditionAudio
+	Bandwidth                int64                // Declared peak bandwidth in bits per second, 0 when not declared
+	AverageBandwidth         int64                // Declared average bandwidth in bits per second, 0 when not declared
+	Width                    int                  // Declared width in pixels
+	Height                   int                  // Declared height in pixels
+	Codecs                   string               // Declared codecs
+	InitSegment              *ManifestSegment     // Initialization segment, nil when the segments are self-contained
+	Segments                 []ManifestSegment    // Media segments in playback order
+	MissingSegments          int                  // Number of segments not found on disk
+	MeasuredAverageBandwidth float64              // Total segment size over total duration in bits per second
+	MeasuredPeakBandwidth    float64              // Highest segment bitrate in bits per second
+	SegmentDurations         SegmentDurationStats // Statistics of the declared segment durations
+	Info                     *ContainerInfo       // Probed container information, nil until probed
+	IDRTimes                 []float64            // Times of the IDR frames from the first frame in seconds
+	SegmentsWithoutIDR       int                  // Number of segments not starting with an IDR frame
+	MisalignedIDRs           int                  // Segment start IDR frames of the reference rendition missing in this one
+
+	firstTime float64 // Presentation time of the first added frame
+	frames    int     // Number of added frames
+}
+
+// ResultStore keeps the analyses of files in an embedded bbolt database, keyed by the SHA-256 of
+// the file, so a library can be tracked over time without analyzing it again. Only one process
+// can open a store at a time.
+type ResultStore struct {
+	path string    // Path of the database file
+	db   *bbolt.DB // Open database
+}
+
+// ScanTypeAnalyzer detects whether a video stream is progressive, interlaced or telecined.
+// It runs the FFmpeg idet filter and classifies the stream in fixed length segments.
+type ScanTypeAnalyzer struct {
+	FFmpegPath      string  // Path to the FFmpeg executable
+	SegmentDuration float64 // Length in seconds of the classified segments
+}
+
+// ScanTypeCounts holds the idet frame classifications of a part of a video stream.
+type ScanTypeCounts struct {
+	Frames         int // Number of analyzed frames
+	Progressive    int // Frames detected as progressive
+	TFF            int // Frames detected as interlaced, top field first
+	BFF            int // Frames detected as interlaced, bottom field first
+	Undetermined   int // Frames idet could not classify
+	RepeatedTop    int // Frames repeating the top field of the previous frame
+	RepeatedBottom int // Frames repeating the bottom field of the previous frame
+}
+
+// ScanTypeReport contains the detected scan type of a video stream.
+type ScanTypeReport struct {
+	StreamIndex int               // Index of the analyzed stream
+	ScanType    string            // Detected scan type, one of the ScanType constants
+	Confidence  float64           // Share of frames (0-1) supporting the detected scan type
+	Counts      ScanTypeCounts    // Frame classifications of the whole stream
+	Segments    []ScanTypeSegment // Consecutive parts of the stream with the same scan type
+}
+
+// ScanTypeSegment is a part of a video stream with a single detected scan type.
+type ScanTypeSegment struct {
+	Start      float64        // Start time in seconds
+	End        float64        // End time in seconds
+	ScanType   string         // Detected scan type, one of the ScanType constants
+	Confidence float64        // Share of frames (0-1) supporting the detected scan type
+	Counts     ScanTypeCounts // Frame classifications of the segment
+}
+
+// Scene is a shot of a video stream between two scene cuts.
+type Scene struct {
+	Index          int            // Scene number (starting from 0)
+	StartFrame     int            // First frame of the scene in presentation order
+	EndFrame       int            // First frame after the scene
+	Start          float64        // Start time in seconds
+	End            float64        // End time in seconds
+	CutScore       float64        // scdet score (0-100) of the cut starting the scene, 0 for the first scene
+	Frames         int            // Number of frames measured by the bitrate analysis
+	TotalBits      int64          // Sum of the frame sizes in bits
+	AverageBitrate float64        // Average bitrate in bits per second
+	PeakBitrate    float64        // Highest bitrate over one second of frames in bits per second
+	AverageQP      float64        // Average QP, valid when QPFrames > 0
+	QPFrames       int            // Number of frames with a known QP
+	FrameTypes     map[string]int // Number of frames by frame type (I, P, B, ...)
+	KeyframeAtCut  bool           // Whether the first frame of the scene is an I-frame
+	Starved        bool           // Whether the scene gets much less bitrate than the stream average
+}
+
+// SceneAnalyzer splits a video stream into scenes.
+// It runs the FFmpeg scdet filter to find the scene cuts.
+type SceneAnalyzer struct {
+	FFmpegPath string  // Path to the FFmpeg executable
+	Threshold  float64 // scdet score (0-100) at which a frame starts a new scene
+}
+
+// SceneReport contains the scenes of a video stream with their statistics.
+// Frames from the bitrate and quality analyzers are added as they are measured.
+type SceneReport struct {
+	StreamIndex    int     // Index of the analyzed stream
+	FrameRate      float64 // Frames per second used to convert frame counts to durations
+	Threshold      float64 // scdet score (0-100) used to find the cuts
+	AverageBitrate float64 // Average bitrate of the stream in bits per second, set by Finish
+	Scenes         []Scene // Scenes in presentation order
+
+	window    []int64 // Frame sizes of the last second of the current scene
+	windowSum int64   // Sum of the frame sizes in window
+	current   int     // Scene of the last added frame
+}
+
+// SegmentDurationStats summarizes the segment durations of a rendition.
+type SegmentDurationStats struct {
+	Count  int     // Number of segments
+	Min    float64 // Shortest segment in seconds
+	Max    float64 // Longest segment in seconds
+	Mean   float64 // Average segment duration in seconds
+	StdDev float64 // Standard deviation of the segment durations in seconds
+}
+
+// Spec is a delivery specification: rules a file must satisfy, loaded from a YAML or JSON file
+// or shipped as a built-in profile. A spec naming a profile overrides the rules of the profile.
+type Spec struct {
+	Name        string     `json:"name" yaml:"name"`                                   // Name of the specification
+	Version     string     `json:"version,omitempty" yaml:"version,omitempty"`         // Version of the specification
+	Description string     `json:"description,omitempty" yaml:"description,omitempty"` // Free text description
+	Profile     string     `json:"profile,omitempty" yaml:"profile,omitempty"`         // Built-in profile the rules override, as name or name@version
+	Rules       []SpecRule `json:"rules" yaml:"rules"`                                 // Rules in report order
+}
+
+// SpecResult holds the outcome of every rule of a Spec for a file.
+type SpecResult struct {
+	Spec   *Spec            // Evaluated specification
+	Rules  []SpecRuleResult // Outcome of every rule, in the order of the spec
+	Passed bool             // Whether every rule passed
+}
+
+// SpecRule checks a single field of a file. Strings are compared case-insensitively; numbers
+// must differ by less than 0.01% unless a tolerance is given. Min and Max are inclusive.
+type SpecRule struct {
+	Name        string   `json:"name,omitempty" yaml:"name,omitempty"`               // Name shown in the reports, the field when empty
+	Field       string   `json:"field" yaml:"field"`                                 // Checked field, such as video.codec or audio.integrated_loudness
+	Equals      any      `json:"equals,omitempty" yaml:"equals,omitempty"`           // Expected value
+	OneOf       []any    `json:"one_of,omitempty" yaml:"one_of,omitempty"`           // Accepted values
+	Min         *float64 `json:"min,omitempty" yaml:"min,omitempty"`                 // Smallest accepted value
+	Max         *float64 `json:"max,omitempty" yaml:"max,omitempty"`                 // Largest accepted value
+	Tolerance   float64  `json:"tolerance,omitempty" yaml:"tolerance,omitempty"`     // Accepted deviation of numbers from Equals or OneOf
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"` // Reason for the threshold, shown in the reports
+	Disabled    bool     `json:"disabled,omitempty" yaml:"disabled,omitempty"`       // Removes the rule of the same field from the profile
+	Source      string   `json:"-" yaml:"-"`                                         // Profile the rule comes from, as name@version, empty for own rules
+}
+
+// SpecRuleResult holds the outcome of a SpecRule.
+type SpecRuleResult struct {
+	Rule    SpecRule // Evaluated rule
+	Name    string   // Name of the rule
+	Passed  bool     // Whether every checked stream satisfies the rule
+	Actual  []string // Values found, prefixed with their stream when the field applies to several streams
+	Message string   // Explanation of the failure, empty when the rule passed
+}
+
 // SSIMMetrics contains Structural Similarity Index measurements.
 // SSIM evaluates the perceived quality difference between two images.
 type SSIMMetrics struct {
@@ -413,14 +1322,188 @@ type SSIMMetrics struct {
 	Average float64 `json:"average"`
 }
 
+// StoredRun is an analysis of a file kept in a ResultStore.
+type StoredRun struct {
+	Hash     string         `json:"hash"`     // SHA-256 of the file
+	Path     string         `json:"path"`     // Absolute path of the file when analyzed
+	Size     int64          `json:"size"`     // Size of the file in bytes
+	Analyzed time.Time      `json:"analyzed"` // Time of the analysis
+	Version  string         `json:"version"`  // Version of framehound that ran the analysis
+	Info     *ContainerInfo `json:"info"`     // Analysis results
+	Summary  StoredSummary  `json:"summary"`  // Summary statistics
+	Frames   int            `json:"frames"`   // Number of frames of the stored per-frame series, 0 when not stored
+}
+
+// StoredSummary holds the summary statistics of a stored analysis.
+type StoredSummary struct {
+	Duration       float64  `json:"duration"`                  // Duration of the container in seconds
+	VideoCodec     string   `json:"video_codec,omitempty"`     // Codec of the primary video stream
+	Resolution     string   `json:"resolution,omitempty"`      // Resolution of the primary video stream
+	FrameRate      float64  `json:"frame_rate,omitempty"`      // Frame rate of the primary video stream
+	FrameCount     int      `json:"frame_count,omitempty"`     // Number of measured video frames, 0 when not measured
+	AverageBitrate float64  `json:"average_bitrate,omitempty"` // Measured average video bitrate in bits per second
+	PeakBitrate    float64  `json:"peak_bitrate,omitempty"`    // Measured peak video bitrate over one second in bits per second
+	AudioCodecs    []string `json:"audio_codecs,omitempty"`    // Codecs of the audio streams
+	Loudness       *float64 `json:"loudness,omitempty"`        // Integrated loudness of the first audio stream in LUFS, nil when not measured or silent
+	Healthy        *bool    `json:"healthy,omitempty"`         // Outcome of the integrity check, nil when not checked
+}
+
+// StreamDisposition holds the disposition flags of a stream as reported by ffprobe.
+// Players use them to pick the default tracks and to label accessibility tracks.
+type StreamDisposition struct {
+	Default         bool // Track is selected by default
+	Forced          bool // Track must be displayed (typically foreign dialogue subtitles)
+	HearingImpaired bool // Track is intended for the hearing impaired (SDH)
+	VisualImpaired  bool // Track is intended for the visually impaired (audio description)
+	Commentary      bool // Track contains commentary
+	Original        bool // Track is in the original language
+	AttachedPic     bool // Stream is an attached picture such as cover art
+}
+
+// StreamHash holds the checksums of the decoded frames of a stream.
+type StreamHash struct {
+	StreamIndex int     // Stream index
+	Type        string  // Stream type (video, audio)
+	TimeBase    float64 // Length in seconds of a time base unit of the frame timestamps
+	Frames      int     // Number of decoded frames
+	ContentHash string  // SHA-256 of the frame checksums in decoding order
+}
+
+// StreamHashComparison compares the frames of two streams of the same type.
+// A stream index of -1 marks a stream missing from one of the files.
+type StreamHashComparison struct {
+	Type            string  // Stream type (video, audio)
+	StreamIndexA    int     // Stream index in the first file
+	StreamIndexB    int     // Stream index in the second file
+	FramesA         int     // Number of frames in the first file
+	FramesB         int     // Number of frames in the second file
+	Identical       bool    // Whether both streams decode to the same frames
+	FirstDifference int     // Number of the first differing frame, -1 when identical
+	TimeA           float64 // Presentation time of the first differing frame in the first file
+	TimeB           float64 // Presentation time of the first differing frame in the second file
+	HashA           string  // Checksum of the first differing frame in the first file, empty when missing
+	HashB           string  // Checksum of the first differing frame in the second file, empty when missing
+}
+
+// SubtitleAnalyzer extracts subtitle events from a media file and checks their timing.
+// Text subtitles are converted to SRT by FFmpeg; bitmap subtitles are analyzed by packet timing only.
+type SubtitleAnalyzer struct {
+	FFmpegPath        string  // Path to the FFmpeg executable
+	FFprobePath       string  // Path to the FFprobe executable
+	MaxCharsPerSecond float64 // Reading speed above which a cue is reported
+}
+
+// SubtitleEvent is a single cue of a subtitle stream.
+type SubtitleEvent struct {
+	Number         int     // Sequential cue number starting from 1
+	Start          float64 // Start time in seconds
+	End            float64 // End time in seconds
+	Text           string  // Cue text without markup, empty for bitmap subtitles
+	Characters     int     // Number of visible characters
+	CharsPerSecond float64 // Reading speed, 0 for bitmap subtitles
+	Overlapping    bool    // Cue starts before the previous cue ends
+	TooFast        bool    // Reading speed exceeds the configured limit
+	BeyondVideoEnd bool    // Cue ends after the end of the video
+}
+
+// SubtitleReport summarizes the events of a subtitle stream.
+type SubtitleReport struct {
+	StreamIndex            int             // Index of the analyzed stream
+	Bitmap                 bool            // Whether the stream is a bitmap format analyzed by timing only
+	EventCount             int             // Number of cues
+	FirstCueTime           float64         // Start of the first cue in seconds
+	LastCueTime            float64         // End of the last cue in seconds
+	OverlapCount           int             // Number of cues overlapping the previous one
+	ReadingSpeedViolations int             // Number of cues above the reading speed limit
+	BeyondVideoCount       int             // Number of cues ending after the video
+	Events                 []SubtitleEvent // All cues in presentation order
+}
+
 // SubtitleStream contains information about a subtitle stream in a media file.
 // It provides access to properties like format, language, and title.
 type SubtitleStream struct {
-	Index      int    // Stream index
-	Format     string // Subtitle codec name
-	FormatFull string // Full codec name
-	Language   string // Language code
-	Title      string // Stream title
+	Index       int               // Stream index
+	Format      string            // Subtitle codec name
+	FormatFull  string            // Full codec name
+	Duration    float64           // Duration in seconds, 0 when not declared
+	StartTime   float64           // Start time in seconds
+	Language    string            // Language code
+	Title       string            // Stream title
+	Disposition StreamDisposition // Disposition flags
+	Tags        map[string]string // Raw stream tags
+	Analysis    *SubtitleReport   // Event analysis, nil until a SubtitleAnalyzer has run
+}
+
+// SyncAnalyzer compares the timing of audio and subtitle streams with the primary video stream.
+// The metadata check uses the probed start times, durations and edit lists; the optional
+// content check correlates audio onsets with video scene cuts.
+type SyncAnalyzer struct {
+	FFmpegPath string  // Path to the FFmpeg executable
+	Threshold  float64 // Largest offset in seconds accepted between a stream and the video
+}
+
+// SyncReport contains the result of an A/V sync check.
+type SyncReport struct {
+	VideoStreamIndex int                // Index of the reference video stream
+	VideoStartTime   float64            // Start time of the video stream in seconds
+	VideoDuration    float64            // Duration of the video stream in seconds
+	Threshold        float64            // Threshold in seconds used for the check
+	ContentChecked   bool               // Whether the content check has run
+	SceneCuts        int                // Number of scene cuts found by the content check
+	Streams          []SyncStreamOffset // Audio and subtitle streams compared with the video
+	Passed           bool               // Whether every stream is within the threshold
+}
+
+// SyncStreamOffset describes the timing of a stream relative to the primary video stream.
+// Positive offsets mean that the stream plays late compared to the video.
+type SyncStreamOffset struct {
+	StreamIndex    int     // Index of the compared stream
+	Type           string  // Stream type (audio, subtitle)
+	StartTime      float64 // Start time of the stream in seconds
+	StartDelta     float64 // Stream start minus video start in seconds
+	Duration       float64 // Duration of the stream in seconds, 0 when unknown
+	DurationDelta  float64 // Stream duration minus video duration in seconds, 0 when unknown
+	EditListDelta  float64 // Edit list start shift relative to the video track in seconds (MP4/MOV only)
+	ContentOffset  float64 // Offset measured from the content in seconds, valid when ContentMatches > 0
+	ContentMatches int     // Number of audio onsets matching a scene cut at the measured offset
+	OutOfSync      bool    // Whether any offset exceeds the threshold
+}
+
+// TimestampAnomaly is a frame whose timestamps break the expected order or cadence.
+type TimestampAnomaly struct {
+	FrameNumber int     // Frame number in presentation order
+	Time        float64 // Presentation time of the frame in seconds
+	Type        string  // Anomaly type, one of the Timestamp constants
+	Severity    string  // SeverityError or SeverityWarning
+	PTS         int64   // Presentation timestamp of the frame, NoTimestamp when unknown
+	DTS         int64   // Decoding timestamp of the frame, NoTimestamp when unknown
+	PreviousPTS int64   // Presentation timestamp of the previous frame, NoTimestamp when unknown
+	PreviousDTS int64   // Decoding timestamp of the previous frame, NoTimestamp when unknown
+	Delta       float64 // Distance in seconds from the previous frame for gaps and jumps, 0 otherwise
+}
+
+// TimestampReport checks the timestamps of the frames of a video stream.
+// It observes the frames of a BitrateAnalyzer, so no separate pass is needed.
+type TimestampReport struct {
+	FrameRate float64            // Frame rate used for the expected frame duration, 0 disables the gap check
+	Frames    int                // Number of frames checked
+	Anomalies []TimestampAnomaly // Anomalies in presentation order
+	previous  FrameBitrateInfo   // Last frame added
+}
+
+// VideoEvent is a timestamped interval found by a BlackFreezeAnalyzer.
+type VideoEvent struct {
+	Type  string  // Event type, one of the VideoEvent constants
+	Start float64 // Start time in seconds
+	End   float64 // End time in seconds
+	Value float64 // Highest ratio of black pixels in percent for blank frames, 0 otherwise
+}
+
+// VideoEventReport collects the events found in a video stream.
+type VideoEventReport struct {
+	StreamIndex int          // Index of the analyzed stream
+	IgnoreEdges bool         // Whether leading and trailing black were ignored
+	Events      []VideoEvent // Events in the order they were found
 }
 
 // VideoInfo contains basic information about a video file.
@@ -448,24 +1531,35 @@ type VideoInfo struct {
 // VideoStream encapsulates detailed information about a video stream in a media file.
 // It exposes comprehensive properties including format, dimensions, frame rate, and more.
 type VideoStream struct {
-	Index              int     // Stream index
-	Format             string  // Video codec name
-	FormatFull         string  // Full codec name
-	FormatProfile      string  // Codec profile
-	Width              int     // Frame width in pixels
-	Height             int     // Frame height in pixels
-	DisplayAspectRatio float64 // Display aspect ratio
-	PixelAspectRatio   float64 // Pixel aspect ratio
-	FrameRate          float64 // Frames per second
-	FrameRateMode      string  // Frame rate mode (CFR, VFR)
-	BitRate            int64   // Bit rate in bits per second
-	BitDepth           int     // Bit depth
-	Duration           float64 // Duration in seconds
-	ColorSpace         string  // Color space
-	ScanType           string  // Scan type (progressive, interlaced)
-	HasBFrames         bool    // Whether the stream has B-frames
-	Language           string  // Language code
-	Title              string  // Stream title
+	Index              int               // Stream index
+	Format             string            // Video codec name
+	FormatFull         string            // Full codec name
+	FormatProfile      string            // Codec profile
+	Level              float64           // Codec level, such as 4.1, 0 when unknown
+	Width              int               // Frame width in pixels
+	Height             int               // Frame height in pixels
+	DisplayAspectRatio float64           // Display aspect ratio
+	PixelAspectRatio   float64           // Pixel aspect ratio
+	FrameRate          float64           // Frames per second
+	FrameRateMode      string            // Frame rate mode (CFR, VFR)
+	BitRate            int64             // Bit rate in bits per second
+	BitDepth           int               // Bit depth
+	Duration           float64           // Duration in seconds
+	StartTime          float64           // Start time in seconds
+	ColorSpace         string            // Color space
+	ScanType           string            // Scan type, the container field order until a ScanTypeAnalyzer has run
+	ScanTypeConfidence float64           // Confidence (0-1) of a detected scan type, 0 when declared by the container
+	FieldOrder         string            // Field order declared by the container
+	HasBFrames         bool              // Whether the stream has B-frames
+	Language           string            // Language code
+	Title              string            // Stream title
+	Disposition        StreamDisposition // Disposition flags
+	Tags               map[string]string // Raw stream tags
+	Events             *VideoEventReport // Black and freeze events, nil until a BlackFreezeAnalyzer has run
+	ScanAnalysis       *ScanTypeReport   // Scan type detection, nil until a ScanTypeAnalyzer has run
+	Crop               *CropReport       // Active picture area, nil until a CropAnalyzer has run
+	Artifacts          *ArtifactSummary  // Artifact scores, nil until an ArtifactAnalyzer has run
+	Cadence            *CadenceReport    // Duplicate and dropped frames, nil until a CadenceAnalyzer has run
 }
 
 // VMAFMetrics contains Video Multi-method Assessment Fusion measurements.
@@ -480,3 +1574,21 @@ type VMAFMetrics struct {
 	// Max is the maximum VMAF score
 	Max float64 `json:"max"`
 }
+
+// WatchEntry is a file of a watched directory waiting for, or going through, analysis.
+type WatchEntry struct {
+	Path     string    `json:"path"`            // Absolute path of the file
+	Size     int64     `json:"size"`            // Size of the file when last seen
+	ModTime  time.Time `json:"mod_time"`        // Modification time of the file when last seen
+	Changed  time.Time `json:"changed"`         // When the size or the modification time last changed
+	Status   string    `json:"status"`          // Status, one of the WatchStatus constants
+	Attempts int       `json:"attempts"`        // Number of analyses started, including interrupted ones
+	Error    string    `json:"error,omitempty"` // Error of the last interrupted analysis
+}
+
+// WatchQueue tracks the files of a watched directory from their arrival to their analysis.
+// It is saved after every change so a restarted watcher resumes where it stopped.
+type WatchQueue struct {
+	Version int                    `json:"version"` // Format version of the queue
+	Entries map[string]*WatchEntry `json:"entries"` // Entries by absolute path
+}
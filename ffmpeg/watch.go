@@ -0,0 +1,158 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// It offers capabilities for analyzing video files, extracting metadata, and
+// processing frame-level information such as bitrates, quality parameters, and
+// quality metrics including QP values, PSNR, SSIM, and VMAF.
+package ffmpeg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// Public functions (alphabetical)
+
+// LoadWatchQueue reads a watch queue. A missing queue, or a queue written in an older format,
+// yields an empty queue. Files whose analysis was interrupted are queued again.
+func LoadWatchQueue(path string) (*WatchQueue, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewWatchQueue(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading watch queue: %w", err)
+	}
+
+	queue := NewWatchQueue()
+	if err := json.Unmarshal(data, queue); err != nil {
+		return nil, fmt.Errorf("error parsing watch queue: %w", err)
+	}
+	if queue.Version != watchQueueVersion || queue.Entries == nil {
+		return NewWatchQueue(), nil
+	}
+
+	for _, entry := range queue.Entries {
+		if entry.Status == WatchStatusRunning {
+			entry.Status = WatchStatusQueued
+			entry.Error = "analysis interrupted"
+		}
+	}
+	return queue, nil
+}
+
+// NewWatchQueue creates an empty watch queue.
+func NewWatchQueue() *WatchQueue {
+	return &WatchQueue{
+		Version: watchQueueVersion,
+		Entries: make(map[string]*WatchEntry),
+	}
+}
+
+// Public methods (alphabetical)
+
+// Fail marks a file that could not be handled, such as a file that could not be moved after its
+// analysis, so it is not analyzed again until it changes.
+func (q *WatchQueue) Fail(path string, reason string) {
+	if entry, found := q.Entries[path]; found {
+		entry.Status = WatchStatusFailed
+		entry.Error = reason
+	}
+}
+
+// Next returns the queued file that became ready first and marks it as running, or nil when
+// no file is queued.
+func (q *WatchQueue) Next() *WatchEntry {
+	var next *WatchEntry
+	for _, entry := range q.Entries {
+		if entry.Status != WatchStatusQueued {
+			continue
+		}
+		if next == nil || entry.Changed.Before(next.Changed) ||
+			(entry.Changed.Equal(next.Changed) && entry.Path < next.Path) {
+			next = entry
+		}
+	}
+	if next != nil {
+		next.Status = WatchStatusRunning
+		next.Attempts++
+	}
+	return next
+}
+
+// Ready queues the pending files whose size and modification time have not changed for the
+// settle duration and that are not locked by their writer. It returns the newly queued files
+// sorted by path.
+func (q *WatchQueue) Ready(now time.Time, settle time.Duration, locked func(path string) bool) []*WatchEntry {
+	var ready []*WatchEntry
+	for _, entry := range q.Entries {
+		if entry.Status != WatchStatusPending || now.Sub(entry.Changed) < settle {
+			continue
+		}
+		if locked != nil && locked(entry.Path) {
+			continue
+		}
+		entry.Status = WatchStatusQueued
+		ready = append(ready, entry)
+	}
+	sort.Slice(ready, func(i, j int) bool { return ready[i].Path < ready[j].Path })
+	return ready
+}
+
+// Remove drops a file from the queue once it has been handled.
+func (q *WatchQueue) Remove(path string) {
+	delete(q.Entries, path)
+}
+
+// Save writes the queue as JSON. The queue is written to a temporary file first so an
+// interrupted save does not lose the previous queue.
+func (q *WatchQueue) Save(path string) error {
+	data, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding watch queue: %w", err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("error writing watch queue: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("error replacing watch queue: %w", err)
+	}
+	return nil
+}
+
+// Update records the files found in the watched directory, by absolute path. New files are
+// pending; a queued or failed file whose size or modification time changed is pending again, as
+// it is still being written or was replaced. Files that disappeared are dropped, unless they are
+// being analyzed.
+func (q *WatchQueue) Update(files map[string]os.FileInfo, now time.Time) {
+	for path, entry := range q.Entries {
+		if _, found := files[path]; !found && entry.Status != WatchStatusRunning {
+			delete(q.Entries, path)
+		}
+	}
+
+	for path, fileInfo := range files {
+		entry, found := q.Entries[path]
+		if !found {
+			q.Entries[path] = &WatchEntry{
+				Path:    path,
+				Size:    fileInfo.Size(),
+				ModTime: fileInfo.ModTime(),
+				Changed: now,
+				Status:  WatchStatusPending,
+			}
+			continue
+		}
+		if entry.Status == WatchStatusRunning {
+			continue
+		}
+		if entry.Size != fileInfo.Size() || !entry.ModTime.Equal(fileInfo.ModTime()) {
+			entry.Size, entry.ModTime, entry.Changed = fileInfo.Size(), fileInfo.ModTime(), now
+			entry.Status = WatchStatusPending
+		}
+	}
+}
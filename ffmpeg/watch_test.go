@@ -0,0 +1,149 @@
+// Package ffmpeg provides functionality for detecting and working with FFmpeg.
+// This file contains tests for the watch queue.
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// WatchQueueTestSuite defines the test suite for WatchQueue.
+type WatchQueueTestSuite struct {
+	suite.Suite
+	tempDir string    // Temporary directory for test files
+	start   time.Time // Time of the first scan
+}
+
+// SetupTest creates a temporary directory.
+func (s *WatchQueueTestSuite) SetupTest() {
+	tempDir, err := os.MkdirTemp("", "watch-test")
+	require.NoError(s.T(), err)
+	s.tempDir = tempDir
+	s.start = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+}
+
+// TearDownTest removes the temporary directory.
+func (s *WatchQueueTestSuite) TearDownTest() {
+	os.RemoveAll(s.tempDir)
+}
+
+// write creates a file of the given size and returns its path and information.
+func (s *WatchQueueTestSuite) write(name string, size int) (string, os.FileInfo) {
+	path := filepath.Join(s.tempDir, name)
+	require.NoError(s.T(), os.WriteFile(path, make([]byte, size), 0644))
+	fileInfo, err := os.Stat(path)
+	require.NoError(s.T(), err)
+	return path, fileInfo
+}
+
+// TestSettle verifies that files are queued once unchanged for the settle duration and unlocked.
+func (s *WatchQueueTestSuite) TestSettle() {
+	queue := NewWatchQueue()
+	moviePath, movieInfo := s.write("movie.mkv", 100)
+	trailerPath, trailerInfo := s.write("trailer.mp4", 10)
+	queue.Update(map[string]os.FileInfo{moviePath: movieInfo, trailerPath: trailerInfo}, s.start)
+	assert.Empty(s.T(), queue.Ready(s.start.Add(10*time.Second), 30*time.Second, nil))
+
+	// The movie is still being written
+	_, movieInfo = s.write("movie.mkv", 200)
+	queue.Update(map[string]os.FileInfo{moviePath: movieInfo, trailerPath: trailerInfo}, s.start.Add(20*time.Second))
+	locked := func(path string) bool { return false }
+	ready := queue.Ready(s.start.Add(30*time.Second), 30*time.Second, locked)
+	require.Len(s.T(), ready, 1)
+	assert.Equal(s.T(), trailerPath, ready[0].Path)
+	assert.Equal(s.T(), WatchStatusPending, queue.Entries[moviePath].Status)
+
+	// The movie is complete but its writer still holds a lock
+	locked = func(path string) bool { return path == moviePath }
+	assert.Empty(s.T(), queue.Ready(s.start.Add(60*time.Second), 30*time.Second, locked))
+	ready = queue.Ready(s.start.Add(60*time.Second), 30*time.Second, nil)
+	require.Len(s.T(), ready, 1)
+
+	// The trailer became ready first
+	next := queue.Next()
+	require.NotNil(s.T(), next)
+	assert.Equal(s.T(), trailerPath, next.Path)
+	assert.Equal(s.T(), WatchStatusRunning, next.Status)
+	assert.Equal(s.T(), 1, next.Attempts)
+	assert.Equal(s.T(), moviePath, queue.Next().Path)
+	assert.Nil(s.T(), queue.Next())
+
+	// Running files are kept even when they disappear
+	queue.Update(map[string]os.FileInfo{}, s.start.Add(90*time.Second))
+	assert.Len(s.T(), queue.Entries, 2)
+	queue.Remove(trailerPath)
+	assert.Len(s.T(), queue.Entries, 1)
+}
+
+// TestFail verifies that a failed file is skipped until it changes.
+func (s *WatchQueueTestSuite) TestFail() {
+	queue := NewWatchQueue()
+	moviePath, movieInfo := s.write("movie.mkv", 100)
+	queue.Update(map[string]os.FileInfo{moviePath: movieInfo}, s.start)
+	queue.Ready(s.start.Add(time.Minute), 30*time.Second, nil)
+	require.NotNil(s.T(), queue.Next())
+	queue.Fail(moviePath, "permission denied")
+
+	queuePath := filepath.Join(s.tempDir, "queue.json")
+	require.NoError(s.T(), queue.Save(queuePath))
+	queue, err := LoadWatchQueue(queuePath)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), WatchStatusFailed, queue.Entries[moviePath].Status)
+	assert.Equal(s.T(), "permission denied", queue.Entries[moviePath].Error)
+
+	queue.Update(map[string]os.FileInfo{moviePath: movieInfo}, s.start.Add(2*time.Minute))
+	assert.Empty(s.T(), queue.Ready(s.start.Add(5*time.Minute), 30*time.Second, nil))
+	assert.Nil(s.T(), queue.Next())
+
+	// A replaced file is analyzed again
+	_, movieInfo = s.write("movie.mkv", 200)
+	queue.Update(map[string]os.FileInfo{moviePath: movieInfo}, s.start.Add(6*time.Minute))
+	assert.Equal(s.T(), WatchStatusPending, queue.Entries[moviePath].Status)
+}
+
+// TestPersistence verifies that a saved queue resumes interrupted analyses.
+func (s *WatchQueueTestSuite) TestPersistence() {
+	queue := NewWatchQueue()
+	moviePath, movieInfo := s.write("movie.mkv", 100)
+	trailerPath, trailerInfo := s.write("trailer.mp4", 10)
+	queue.Update(map[string]os.FileInfo{moviePath: movieInfo, trailerPath: trailerInfo}, s.start)
+	queue.Ready(s.start.Add(time.Minute), 30*time.Second, nil)
+	running := queue.Next()
+	require.NotNil(s.T(), running)
+
+	queuePath := filepath.Join(s.tempDir, "queue.json")
+	require.NoError(s.T(), queue.Save(queuePath))
+	loaded, err := LoadWatchQueue(queuePath)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), loaded.Entries, 2)
+	resumed := loaded.Entries[running.Path]
+	assert.Equal(s.T(), WatchStatusQueued, resumed.Status)
+	assert.Equal(s.T(), 1, resumed.Attempts)
+	assert.Equal(s.T(), "analysis interrupted", resumed.Error)
+	assert.True(s.T(), resumed.ModTime.Equal(running.ModTime))
+
+	// An unchanged file keeps its place in the queue
+	loaded.Update(map[string]os.FileInfo{moviePath: movieInfo, trailerPath: trailerInfo}, s.start.Add(2*time.Minute))
+	assert.Equal(s.T(), WatchStatusQueued, loaded.Entries[moviePath].Status)
+	assert.Equal(s.T(), 2, loaded.Next().Attempts)
+
+	empty, err := LoadWatchQueue(filepath.Join(s.tempDir, "missing.json"))
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), empty.Entries)
+
+	require.NoError(s.T(), os.WriteFile(queuePath, []byte(`{"version": 0, "entries": {}}`), 0644))
+	old, err := LoadWatchQueue(queuePath)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), watchQueueVersion, old.Version)
+}
+
+// TestWatchQueueSuite runs the WatchQueue test suite.
+func TestWatchQueueSuite(t *testing.T) {
+	suite.Run(t, new(WatchQueueTestSuite))
+}
@@ -3,15 +3,29 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
 	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"html"
+	"io"
+	"log"
 	"math"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"text/tabwriter"
 	"time"
 
@@ -20,16 +34,70 @@ import (
 	"github.com/schollz/progressbar/v3"
 	"github.com/torre76/framehound/ffmpeg"
 	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
 )
 
 // Private constants (alphabetical)
-// None currently defined
+
+// fingerprintIndexName is the name of the fingerprint index the dedupe command keeps in the scanned directory.
+const fingerprintIndexName = ".framehound-index.json"
+
+// jobDefaultMaxUpload is the largest file in bytes the serve command accepts as an upload.
+const jobDefaultMaxUpload = 20 << 30
+
+// jobDefaultRetention is how long the serve command keeps a finished job before removing it.
+const jobDefaultRetention = time.Hour
+
+// storeEnvVar is the environment variable naming the result store of the commands.
+const storeEnvVar = "FRAMEHOUND_STORE"
+
+// watchMaxAttempts is the number of interrupted analyses after which the watch command gives up on a file.
+const watchMaxAttempts = 3
+
+// watchQueueName is the name of the queue the watch command keeps in the watched directory.
+const watchQueueName = ".framehound-queue.json"
+
+// Statuses of the analysis jobs of the serve command.
+const (
+	// jobStatusDone marks a job whose results are ready.
+	jobStatusDone = "done"
+
+	// jobStatusFailed marks a job stopped by an error.
+	jobStatusFailed = "failed"
+
+	// jobStatusQueued marks a job waiting for a worker.
+	jobStatusQueued = "queued"
+
+	// jobStatusRunning marks a job being analyzed.
+	jobStatusRunning = "running"
+)
 
 // Public constants (alphabetical)
 // None currently defined
 
 // Private variables (alphabetical)
-// None currently defined
+
+// comparisonColors are the line colors of the files in the comparison graphs, reused in order
+// for more files.
+var comparisonColors = []string{"#3399FF", "#FF9900", "#33CC66", "#CC3366", "#9966FF", "#666666"}
+
+// timestampAnomalyTypes lists the timestamp anomaly types in the order the reports count them.
+var timestampAnomalyTypes = []string{
+	ffmpeg.TimestampNonMonotonicDTS,
+	ffmpeg.TimestampDuplicatePTS,
+	ffmpeg.TimestampPTSBeforeDTS,
+	ffmpeg.TimestampJump,
+	ffmpeg.TimestampGap,
+	ffmpeg.TimestampWraparound,
+	ffmpeg.TimestampMissingPTS,
+}
+
+// videoExtensions lists the file extensions the dedupe command fingerprints.
+var videoExtensions = map[string]bool{
+	".3gp": true, ".avi": true, ".flv": true, ".m2ts": true, ".m4v": true, ".mkv": true,
+	".mov": true, ".mp4": true, ".mpeg": true, ".mpg": true, ".mts": true, ".ogv": true,
+	".ts": true, ".vob": true, ".webm": true, ".wmv": true,
+}
 
 // Public variables (alphabetical)
 
@@ -46,6 +114,142 @@ var Commit = "unknown"
 // go build -ldflags="-X 'github.com/torre76/framehound.Version=v1.0.0'"
 var Version = "Development Version"
 
+// Private types (alphabetical)
+
+// analysisJob is an analysis queued by the serve command. Its fields are protected by the
+// mutex of the jobServer running it.
+type analysisJob struct {
+	ID       string     `json:"id"`                 // Random identifier
+	Path     string     `json:"path"`               // Absolute path of the analyzed file
+	Uploaded bool       `json:"uploaded"`           // Whether the file was uploaded to the server
+	Status   string     `json:"status"`             // jobStatusQueued, jobStatusRunning, jobStatusDone or jobStatusFailed
+	Stage    string     `json:"stage,omitempty"`    // Current pass of a running job (probe, bitrate, qp, reports)
+	Progress float64    `json:"progress"`           // Completion between 0 and 1, estimated from the frame count
+	Frames   int        `json:"frames"`             // Number of frames measured so far
+	Warnings []string   `json:"warnings,omitempty"` // Analyses that were skipped
+	Error    string     `json:"error,omitempty"`    // Error of a failed job
+	Created  time.Time  `json:"created"`            // Time the job was queued
+	Started  *time.Time `json:"started,omitempty"`  // Time a worker picked the job up
+	Finished *time.Time `json:"finished,omitempty"` // Time the job completed or failed
+
+	info      *ffmpeg.ContainerInfo     // Analysis results, set once the file is probed
+	frames    []ffmpeg.FrameBitrateInfo // Measured frames in presentation order
+	qp        []jobQP                   // Measured frame QPs
+	outputDir string                    // Directory of the reports of the job
+	changed   chan struct{}             // Closed and replaced on every update to wake the event streams
+}
+
+// analysisOptions selects the analyses of a file. The default command fills them from its flags
+// and the watch command reads them from an analysis profile, a YAML or JSON file with the same
+// keys as the flags.
+type analysisOptions struct {
+	ShowFrames      bool    `json:"show_frames" yaml:"show_frames"`             // Show frame count information
+	Integrity       bool    `json:"integrity" yaml:"integrity"`                 // Decode every stream with error detection
+	FrameHash       bool    `json:"framehash" yaml:"framehash"`                 // Checksum every decoded frame
+	Scenes          bool    `json:"scenes" yaml:"scenes"`                       // Split the video into scenes
+	SceneThreshold  float64 `json:"scene_threshold" yaml:"scene_threshold"`     // scdet score starting a new scene, 0 for the default
+	ScanType        bool    `json:"scan_type" yaml:"scan_type"`                 // Detect the scan type
+	Crop            bool    `json:"crop" yaml:"crop"`                           // Detect the active picture area
+	Cadence         bool    `json:"cadence" yaml:"cadence"`                     // Find duplicate and dropped frames
+	BlackFreeze     bool    `json:"black_freeze" yaml:"black_freeze"`           // Detect black and frozen video
+	BlackDuration   float64 `json:"black_duration" yaml:"black_duration"`       // Minimum black segment in seconds, 0 for the default
+	BlackThreshold  float64 `json:"black_threshold" yaml:"black_threshold"`     // Ratio of black pixels of a black frame, 0 for the default
+	FreezeDuration  float64 `json:"freeze_duration" yaml:"freeze_duration"`     // Minimum frozen segment in seconds, 0 for the default
+	FreezeNoise     float64 `json:"freeze_noise" yaml:"freeze_noise"`           // Noise tolerance of frozen video in dB, 0 for the default
+	IgnoreEdgeBlack bool    `json:"ignore_edge_black" yaml:"ignore_edge_black"` // Ignore black at the start and the end
+	Artifacts       bool    `json:"artifacts" yaml:"artifacts"`                 // Score blocking, blur and banding
+	AudioQC         bool    `json:"audio_qc" yaml:"audio_qc"`                   // Detect audio defects
+	Loudness        string  `json:"loudness" yaml:"loudness"`                   // Loudness target, empty to skip the measurement
+	SyncThreshold   float64 `json:"sync_threshold" yaml:"sync_threshold"`       // Largest A/V offset in milliseconds, 0 for the default
+	SyncContent     bool    `json:"sync_content" yaml:"sync_content"`           // Measure A/V sync from the content
+	Spec            string  `json:"spec" yaml:"spec"`                           // Delivery spec file to check the file against
+	Profile         string  `json:"profile" yaml:"profile"`                     // Built-in delivery profile to check the file against
+	Store           string  `json:"store" yaml:"store"`                         // Result store to record the analysis in, empty to skip it
+	StoreFrames     bool    `json:"store_frames" yaml:"store_frames"`           // Record the per-frame series in the result store
+
+	spec *ffmpeg.Spec // Delivery spec resolved from Spec and Profile, nil when none is set
+}
+
+// bitrateSummary holds totals measured while writing the bitrate report.
+// They are used to cross-check values declared by the container.
+type bitrateSummary struct {
+	FrameCount int64 // Number of video frames analyzed
+	TotalBits  int64 // Sum of all frame sizes in bits
+}
+
+// jobQP is the QP of a frame measured by a job, sent to the event streams.
+type jobQP struct {
+	FrameNumber int     `json:"frame_number"` // Frame number in presentation order
+	QP          float64 `json:"qp"`           // Average quantization parameter of the frame
+}
+
+// jobServer runs the analysis jobs of the serve command and serves their status and results over HTTP.
+// Jobs wait in a bounded queue for a fixed number of workers.
+type jobServer struct {
+	ffmpegInfo *ffmpeg.FFmpegInfo                                // FFmpeg used by the analyses
+	prober     *ffmpeg.Prober                                    // Prober used by the analyses
+	dir        string                                            // Directory of the uploaded files and of the job reports
+	retention  time.Duration                                     // Time a finished job is kept before prune removes it
+	maxUpload  int64                                             // Largest upload in bytes
+	roots      []string                                          // Directories whose files can be queued by path, none to accept uploads only
+	queue      chan *analysisJob                                 // Jobs waiting for a worker
+	analyze    func(ctx context.Context, job *analysisJob) error // Analysis run by the workers, runJob unless replaced
+	mutex      sync.Mutex                                        // Protects the jobs and their fields
+	jobs       map[string]*analysisJob                           // Jobs by identifier
+	order      []*analysisJob                                    // Jobs in the order they were queued
+}
+
+// junitFailure is the failure of a JUnit test case.
+type junitFailure struct {
+	Message string `xml:"message,attr"` // Short description of the failure
+	Type    string `xml:"type,attr"`    // Kind of failure
+	Text    string `xml:",chardata"`    // Details of the failure
+}
+
+// junitProperties holds the properties of a JUnit test suite.
+type junitProperties struct {
+	Properties []junitProperty `xml:"property"` // Properties of the suite
+}
+
+// junitProperty is a property of a JUnit test suite.
+type junitProperty struct {
+	Name  string `xml:"name,attr"`  // Name of the property
+	Value string `xml:"value,attr"` // Value of the property
+}
+
+// junitTestCase is a spec rule reported as a JUnit test case.
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`            // Name of the rule
+	ClassName string        `xml:"classname,attr"`       // Name of the checked file
+	Failure   *junitFailure `xml:"failure,omitempty"`    // Failure, nil when the rule passed
+	SystemOut string        `xml:"system-out,omitempty"` // Details of a passed rule
+}
+
+// junitTestSuite is a delivery spec reported as a JUnit test suite.
+type junitTestSuite struct {
+	Name       string           `xml:"name,attr"`            // Name of the spec
+	Tests      int              `xml:"tests,attr"`           // Number of rules
+	Failures   int              `xml:"failures,attr"`        // Number of failed rules
+	Properties *junitProperties `xml:"properties,omitempty"` // Version and profile of the spec, nil when unknown
+	TestCases  []junitTestCase  `xml:"testcase"`             // Rules in the order of the spec
+}
+
+// junitTestSuites is the root element of a JUnit XML report.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`     // Name of the tool
+	Tests    int              `xml:"tests,attr"`    // Number of rules of all suites
+	Failures int              `xml:"failures,attr"` // Number of failed rules of all suites
+	Suites   []junitTestSuite `xml:"testsuite"`     // Checked specs
+}
+
+// watchDirs holds the output directories of the watch command.
+type watchDirs struct {
+	output string // Root of the report tree
+	done   string // Directory of the analyzed files
+	failed string // Directory of the files whose analysis failed
+}
+
 // Private functions (alphabetical)
 
 // formatWithThousandSeparators formats an integer with thousand separators.
@@ -74,6 +278,362 @@ func formatWithThousandSeparators(n int64) string {
 	return sign + result.String()
 }
 
+// formatYesNo renders a boolean as "Yes" or "No" for the text reports.
+func formatYesNo(value bool) string {
+	if value {
+		return "Yes"
+	}
+	return "No"
+}
+
+// formatDisposition lists the disposition flags of a stream, or "none" when no flag is set.
+func formatDisposition(disposition ffmpeg.StreamDisposition) string {
+	flags := disposition.Flags()
+	if len(flags) == 0 {
+		return "none"
+	}
+	return strings.Join(flags, ", ")
+}
+
+// formatActiveImage describes the active picture area of a video stream with its aspect ratio and black bars.
+func formatActiveImage(report *ffmpeg.CropReport) string {
+	return fmt.Sprintf("%dx%d (%.2f:1, %s)", report.Crop.Width, report.Crop.Height, report.AspectRatio, report.Boxing)
+}
+
+// formatArtifactScore describes the average and the worst artifact score of a video stream.
+func formatArtifactScore(summary *ffmpeg.ArtifactSummary) string {
+	return fmt.Sprintf("%.1f average (%s), %.1f minimum at frame %d",
+		summary.AverageScore, summary.QualityLevel, summary.MinScore, summary.WorstFrame)
+}
+
+// formatAudioQC summarizes the defects of an audio stream by type.
+func formatAudioQC(report *ffmpeg.AudioQCReport) string {
+	if len(report.Events) == 0 {
+		return "no defects found"
+	}
+
+	var parts []string
+	for _, eventType := range []string{ffmpeg.AudioQCClipping, ffmpeg.AudioQCSilence, ffmpeg.AudioQCSilentChannel,
+		ffmpeg.AudioQCPhase, ffmpeg.AudioQCDCOffset, ffmpeg.AudioQCDropout} {
+		if count := report.Count(eventType); count > 0 {
+			parts = append(parts, fmt.Sprintf("%s (%d)", strings.ReplaceAll(eventType, "_", " "), count))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatCadence summarizes the unique frame rate with the duplicate and dropped frames.
+func formatCadence(report *ffmpeg.CadenceReport) string {
+	return fmt.Sprintf("%.3f fps (%d duplicate frames in %d runs, %d dropped frames)",
+		report.UniqueFrameRate, report.Duplicates, report.DuplicateRuns(), report.Dropped)
+}
+
+// formatCadenceSegment describes the unique frame rate of a segment with its duplicate and dropped frames.
+func formatCadenceSegment(segment ffmpeg.CadenceSegment) string {
+	return fmt.Sprintf("%s - %s\t%.3f fps unique (%d duplicates, %d dropped)", formatTimestamp(segment.Start),
+		formatTimestamp(segment.End), segment.UniqueFrameRate, segment.Duplicates, segment.Dropped)
+}
+
+// formatBBCodeCount colors a problem count green when it is zero and red otherwise.
+func formatBBCodeCount(count int) string {
+	if count == 0 {
+		return "[color=#00CC00]0[/color]"
+	}
+	return fmt.Sprintf("[color=#FF0000]%d[/color]", count)
+}
+
+// formatBBCodeIntegrity renders an integrity verdict as a green HEALTHY or a red DAMAGED.
+func formatBBCodeIntegrity(healthy bool) string {
+	if healthy {
+		return "[color=#00CC00]HEALTHY[/color]"
+	}
+	return "[color=#FF0000]DAMAGED[/color]"
+}
+
+// formatBBCodePassFail renders a check result as a green PASS or a red FAIL.
+func formatBBCodePassFail(passed bool) string {
+	if passed {
+		return "[color=#00CC00]PASS[/color]"
+	}
+	return "[color=#FF0000]FAIL[/color]"
+}
+
+// formatBBCodeVideoEvents colors a video event summary green when there are no events and red otherwise.
+func formatBBCodeVideoEvents(report *ffmpeg.VideoEventReport, eventType string) string {
+	if report.Count(eventType) == 0 {
+		return "[color=#00CC00]none[/color]"
+	}
+	return fmt.Sprintf("[color=#FF0000]%s[/color]", formatVideoEvents(report, eventType))
+}
+
+// formatCropSegment describes a segment of the crop detection.
+func formatCropSegment(segment ffmpeg.CropSegment) string {
+	return fmt.Sprintf("%s - %s\t%dx%d (%.2f:1, %d samples)", formatTimestamp(segment.Start), formatTimestamp(segment.End),
+		segment.Crop.Width, segment.Crop.Height, segment.AspectRatio, segment.Samples)
+}
+
+// formatIntegrity renders an integrity verdict as HEALTHY or DAMAGED.
+func formatIntegrity(healthy bool) string {
+	if healthy {
+		return "HEALTHY"
+	}
+	return "DAMAGED"
+}
+
+// formatIntegrityEvent describes an error found by the integrity check.
+func formatIntegrityEvent(event ffmpeg.IntegrityEvent) string {
+	location := "container"
+	if event.StreamIndex >= 0 {
+		location = fmt.Sprintf("stream #%d", event.StreamIndex)
+	}
+	return fmt.Sprintf("%s	%s, %s: %s", formatTimestamp(event.Time), strings.ReplaceAll(event.Type, "_", " "), location, event.Message)
+}
+
+// formatIntegrityStream describes how far a stream decoded and how it ended.
+func formatIntegrityStream(stream ffmpeg.IntegrityStream) string {
+	status := "complete"
+	switch {
+	case stream.Failed:
+		status = "decoding failed"
+	case stream.Truncated:
+		status = "truncated"
+	}
+	decoded := formatTimestamp(stream.DecodedDuration)
+	if stream.DeclaredDuration > 0 {
+		decoded += " of " + formatTimestamp(stream.DeclaredDuration)
+	}
+	return fmt.Sprintf("%s, %d errors, %s", decoded, stream.Errors, status)
+}
+
+// formatComparedFile describes the measurements of a compared file, relative to the reference
+// for the other files.
+func formatComparedFile(file ffmpeg.ComparedFile, reference ffmpeg.ComparedFile, isReference bool) string {
+	description := fmt.Sprintf("%.2f Kbps", file.AverageBitrate/1000)
+	if !isReference && reference.TotalBits > 0 {
+		description += fmt.Sprintf(" (%.1f%% of the reference)", float64(file.TotalBits)*100/float64(reference.TotalBits))
+	}
+	if file.QPFrames > 0 {
+		description += fmt.Sprintf(", QP %.2f", file.AverageQP)
+	}
+	return description + fmt.Sprintf(", %d frames, %s", file.Frames, formatTimestamp(file.Duration))
+}
+
+// formatFingerprintFile describes a fingerprinted file with the properties used to pick the best copy.
+func formatFingerprintFile(fingerprint *ffmpeg.Fingerprint) string {
+	description := fmt.Sprintf("%s %dx%d, %.2f Kbps", fingerprint.Format, fingerprint.Width, fingerprint.Height,
+		float64(fingerprint.BitRate)/1000)
+	if fingerprint.AverageQP > 0 {
+		description += fmt.Sprintf(", QP %.2f", fingerprint.AverageQP)
+	}
+	return description + fmt.Sprintf(", %s, %d scenes", formatTimestamp(fingerprint.Duration), len(fingerprint.Scenes))
+}
+
+// formatHashComparison describes the result of comparing the frames of two streams.
+func formatHashComparison(comparison ffmpeg.StreamHashComparison) string {
+	switch {
+	case comparison.StreamIndexA < 0:
+		return fmt.Sprintf("%s stream #%d only in the second file", comparison.Type, comparison.StreamIndexB)
+	case comparison.StreamIndexB < 0:
+		return fmt.Sprintf("%s stream #%d only in the first file", comparison.Type, comparison.StreamIndexA)
+	}
+
+	streams := fmt.Sprintf("%s stream #%d / #%d", comparison.Type, comparison.StreamIndexA, comparison.StreamIndexB)
+	if comparison.Identical {
+		return fmt.Sprintf("%s: %d frames identical", streams, comparison.FramesA)
+	}
+	if comparison.HashA == "" || comparison.HashB == "" {
+		return fmt.Sprintf("%s: frame counts differ (%d / %d), first %d frames identical",
+			streams, comparison.FramesA, comparison.FramesB, comparison.FirstDifference)
+	}
+	return fmt.Sprintf("%s: first difference at frame %d (%s / %s)", streams, comparison.FirstDifference,
+		formatTimestamp(comparison.TimeA), formatTimestamp(comparison.TimeB))
+}
+
+// formatKeyframeAlignment describes the nearest keyframe of a chapter start or scene cut.
+func formatKeyframeAlignment(alignment ffmpeg.KeyframeAlignment) string {
+	if alignment.KeyframeFrame < 0 {
+		return fmt.Sprintf("%s, no keyframe", formatTimestamp(alignment.Time))
+	}
+	keyframeType := "I-frame"
+	if alignment.IDR {
+		keyframeType = "IDR"
+	}
+	return fmt.Sprintf("%s, %s at %s (%+d frames, %+.0f ms)", formatTimestamp(alignment.Time), keyframeType,
+		formatTimestamp(alignment.KeyframeTime), alignment.OffsetFrames, alignment.OffsetMs)
+}
+
+// formatMatroskaCues describes the cue point coverage of a Matroska segment.
+func formatMatroskaCues(structure *ffmpeg.MatroskaStructure) string {
+	if structure.CuePointCount == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d (%s - %s), %d of %d clusters indexed",
+		structure.CuePointCount, formatDuration(structure.FirstCueTime), formatDuration(structure.LastCueTime),
+		structure.IndexedClusterCount, structure.ClusterCount)
+}
+
+// formatMatroskaStatistics lists the statistics tags of a Matroska track in a stable order.
+func formatMatroskaStatistics(track ffmpeg.MatroskaTrack) string {
+	var parts []string
+	for _, name := range []string{"BPS", "NUMBER_OF_FRAMES", "NUMBER_OF_BYTES", "DURATION"} {
+		if value, ok := track.StatisticsTags[name]; ok {
+			parts = append(parts, name+"="+value)
+		}
+	}
+	if track.StaleStatistics {
+		parts = append(parts, "(stale)")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatPassFail renders a check result as PASS or FAIL for the text reports.
+func formatPassFail(passed bool) string {
+	if passed {
+		return "PASS"
+	}
+	return "FAIL"
+}
+
+// formatScene describes a scene with its bitrate relative to the stream average and its QP.
+func formatScene(scene ffmpeg.Scene, averageBitrate float64) string {
+	description := fmt.Sprintf("Scene #%d\t%s - %s, %.2f Kbps", scene.Index,
+		formatTimestamp(scene.Start), formatTimestamp(scene.End), scene.AverageBitrate/1000)
+	if averageBitrate > 0 {
+		description += fmt.Sprintf(" (%.0f%% of average)", scene.AverageBitrate*100/averageBitrate)
+	}
+	if scene.QPFrames > 0 {
+		description += fmt.Sprintf(", QP %.1f", scene.AverageQP)
+	}
+	return description
+}
+
+// formatScanType describes the scan type of a video stream, noting whether it was detected.
+func formatScanType(stream ffmpeg.VideoStream) string {
+	if stream.ScanTypeConfidence == 0 {
+		return stream.ScanType
+	}
+	fieldOrder := stream.FieldOrder
+	if fieldOrder == "" {
+		fieldOrder = "not declared"
+	}
+	return fmt.Sprintf("%s (detected, %.0f%% confidence; container: %s)",
+		stream.ScanType, stream.ScanTypeConfidence*100, fieldOrder)
+}
+
+// formatScanTypeSegment describes a segment of the scan type detection.
+func formatScanTypeSegment(segment ffmpeg.ScanTypeSegment) string {
+	return fmt.Sprintf("%s - %s\t%s (%.0f%%, %d frames)", formatTimestamp(segment.Start), formatTimestamp(segment.End),
+		segment.ScanType, segment.Confidence*100, segment.Counts.Frames)
+}
+
+// formatStoredSummary describes the primary video stream, the measured bitrate and the audio codecs of a stored analysis.
+func formatStoredSummary(summary ffmpeg.StoredSummary) string {
+	parts := []string{}
+	if summary.VideoCodec != "" {
+		parts = append(parts, fmt.Sprintf("%s %s @ %.3f fps", summary.VideoCodec, summary.Resolution, summary.FrameRate))
+	}
+	if summary.AverageBitrate > 0 {
+		parts = append(parts, fmt.Sprintf("%.2f Kbps average, %.2f Kbps peak", summary.AverageBitrate/1000, summary.PeakBitrate/1000))
+	}
+	if len(summary.AudioCodecs) > 0 {
+		parts = append(parts, strings.Join(summary.AudioCodecs, ", "))
+	}
+	if len(parts) == 0 {
+		return "no video or audio"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatSubtitleEvents describes the event count and cue range of a subtitle analysis.
+func formatSubtitleEvents(report *ffmpeg.SubtitleReport) string {
+	if report.EventCount == 0 {
+		return "none"
+	}
+
+	events := fmt.Sprintf("%d (%s - %s)", report.EventCount,
+		formatDuration(report.FirstCueTime), formatDuration(report.LastCueTime))
+	if report.Bitmap {
+		events += ", bitmap format analyzed by timing only"
+	}
+	return events
+}
+
+// formatSubtitleStreamName names a subtitle stream by language and title for the analysis summary.
+func formatSubtitleStreamName(stream ffmpeg.SubtitleStream) string {
+	parts := []string{stream.Format}
+	if stream.Language != "" {
+		parts = append(parts, stream.Language)
+	}
+	if stream.Title != "" {
+		parts = append(parts, stream.Title)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatSyncContentOffset describes the content-based offset of a stream.
+func formatSyncContentOffset(stream ffmpeg.SyncStreamOffset) string {
+	if stream.ContentMatches == 0 {
+		return "not measured (too few onsets matching scene cuts)"
+	}
+	return fmt.Sprintf("%s (%d matching onsets)", formatSyncOffset(stream.ContentOffset), stream.ContentMatches)
+}
+
+// formatSyncOffset formats an offset in seconds as signed milliseconds.
+func formatSyncOffset(seconds float64) string {
+	return fmt.Sprintf("%+.0f ms", seconds*1000)
+}
+
+// formatSyncStreamName returns a label for a stream compared by the sync check.
+func formatSyncStreamName(stream ffmpeg.SyncStreamOffset) string {
+	if stream.Type == "subtitle" {
+		return fmt.Sprintf("Subtitle stream index %d", stream.StreamIndex)
+	}
+	return fmt.Sprintf("Audio stream index %d", stream.StreamIndex)
+}
+
+// formatTimestamp formats a time in seconds as HH:MM:SS.mmm.
+func formatTimestamp(seconds float64) string {
+	milliseconds := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", milliseconds/3600000, milliseconds/60000%60, milliseconds/1000%60, milliseconds%1000)
+}
+
+// formatTimestampAnomaly describes a timestamp anomaly with the distance from the previous frame
+// for gaps and jumps.
+func formatTimestampAnomaly(anomaly ffmpeg.TimestampAnomaly) string {
+	description := fmt.Sprintf("%s, %s (%s)", formatTimestamp(anomaly.Time), strings.ReplaceAll(anomaly.Type, "_", " "), anomaly.Severity)
+	if anomaly.Delta != 0 {
+		description += fmt.Sprintf(", %+.3f s", anomaly.Delta)
+	}
+	return description
+}
+
+// formatTimestampValue formats a timestamp in time base units, empty when unknown.
+func formatTimestampValue(value int64) string {
+	if value == ffmpeg.NoTimestamp {
+		return ""
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+// formatVideoEvents summarizes the events of one type with their total duration.
+func formatVideoEvents(report *ffmpeg.VideoEventReport, eventType string) string {
+	count := report.Count(eventType)
+	if count == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d (%.2f s)", count, report.TotalDuration(eventType))
+}
+
+// hasSubtitleAnalysis reports whether any subtitle stream carries an event analysis.
+func hasSubtitleAnalysis(streams []ffmpeg.SubtitleStream) bool {
+	for _, stream := range streams {
+		if stream.Analysis != nil {
+			return true
+		}
+	}
+	return false
+}
+
 // printSimpleContainerSummary prints a simplified summary of the container information.
 // It displays the file name and counts of video, audio, and subtitle streams
 // with proper pluralization.
@@ -179,6 +739,15 @@ func getFrameRate(info *ffmpeg.ContainerInfo) float64 {
 	return frameRate
 }
 
+// getVideoDuration returns the duration of the first video stream in seconds.
+// It falls back to the container duration when the stream does not declare one.
+func getVideoDuration(info *ffmpeg.ContainerInfo) float64 {
+	if len(info.VideoStreams) > 0 && info.VideoStreams[0].Duration > 0 {
+		return info.VideoStreams[0].Duration
+	}
+	return info.General.DurationF
+}
+
 func versionPrinter(_ *cli.Context) {
 	summaryStyle := color.New(color.FgCyan, color.Bold)
 	valueStyle := color.New(color.Bold)
@@ -324,6 +893,127 @@ func writeMediaInfoContainerSection(w *tabwriter.Writer, info *ffmpeg.ContainerI
 			}
 		}
 	}
+
+	// Write ISO-BMFF and Matroska structure checks if available
+	writeMediaInfoMP4Structure(w, info.MP4Structure)
+	writeMediaInfoMatroskaStructure(w, info.MatroskaStructure)
+	fmt.Fprintln(w)
+}
+
+// writeMediaInfoMatroskaStructure writes the EBML structure checks of Matroska/WebM containers
+func writeMediaInfoMatroskaStructure(w *tabwriter.Writer, structure *ffmpeg.MatroskaStructure) {
+	if structure == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "\nStructure:")
+	fmt.Fprintf(w, "  DocType:\t%s (version %d)\n", structure.DocType, structure.DocTypeVersion)
+	if structure.MuxingApp != "" {
+		fmt.Fprintf(w, "  Muxing App:\t%s\n", structure.MuxingApp)
+	}
+	if structure.WritingApp != "" {
+		fmt.Fprintf(w, "  Writing App:\t%s\n", structure.WritingApp)
+	}
+	fmt.Fprintf(w, "  Cue Points:\t%s\n", formatMatroskaCues(structure))
+	for _, gap := range structure.CueGaps {
+		fmt.Fprintf(w, "  Cue Gap:\t%s - %s\n", formatDuration(gap.Start), formatDuration(gap.End))
+	}
+
+	for _, track := range structure.Tracks {
+		if track.Compression != "" {
+			fmt.Fprintf(w, "  Compression (track %d, %s):\t%s\n", track.Number, track.Type, track.Compression)
+		}
+		if len(track.StatisticsTags) > 0 {
+			fmt.Fprintf(w, "  Statistics (track %d, %s):\t%s\n", track.Number, track.Type, formatMatroskaStatistics(track))
+		}
+	}
+
+	if len(structure.Issues) == 0 {
+		fmt.Fprintln(w, "  Checks:\tall passed")
+		return
+	}
+	for _, issue := range structure.Issues {
+		fmt.Fprintf(w, "  Warning:\t%s\n", issue)
+	}
+}
+
+// writeMediaInfoMP4Structure writes the ISO-BMFF layout checks of MP4/MOV containers
+func writeMediaInfoMP4Structure(w *tabwriter.Writer, structure *ffmpeg.MP4Structure) {
+	if structure == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "\nStructure:")
+	fmt.Fprintf(w, "  Brands:\t%s (%s)\n", strings.TrimSpace(structure.MajorBrand), strings.Join(structure.CompatibleBrands, ", "))
+	fmt.Fprintf(w, "  Faststart:\t%s\n", formatYesNo(structure.FastStart))
+	fmt.Fprintf(w, "  Fragmented:\t%s\n", formatYesNo(structure.Fragmented))
+
+	for _, track := range structure.Tracks {
+		if track.EditList != nil {
+			fmt.Fprintf(w, "  Edit List (track %d, %s):\t%d entries, start shift %+.3f seconds\n",
+				track.TrackID, track.HandlerType, track.EditList.EntryCount, track.EditList.StartShift)
+		}
+	}
+
+	if len(structure.Issues) == 0 {
+		fmt.Fprintln(w, "  Checks:\tall passed")
+		return
+	}
+	for _, issue := range structure.Issues {
+		fmt.Fprintf(w, "  Warning:\t%s\n", issue)
+	}
+}
+
+// writeMediaInfoIntegrity writes the decode integrity verdict and the first errors found
+func writeMediaInfoIntegrity(w *tabwriter.Writer, report *ffmpeg.IntegrityReport) {
+	if report == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w, "INTEGRITY")
+	fmt.Fprintln(w, "===========================================")
+
+	fmt.Fprintf(w, "\nVerdict:\t%s\n", formatIntegrity(report.Healthy()))
+	fmt.Fprintf(w, "Decode Errors:\t%d\n", report.Count(ffmpeg.IntegrityDecodeError))
+	fmt.Fprintf(w, "Concealments:\t%d\n", report.Count(ffmpeg.IntegrityConcealment))
+	fmt.Fprintf(w, "Missing References:\t%d\n", report.Count(ffmpeg.IntegrityMissingReference))
+	fmt.Fprintf(w, "Truncations:\t%d\n", report.Count(ffmpeg.IntegrityTruncated))
+	fmt.Fprintf(w, "Container Errors:\t%d\n", report.Count(ffmpeg.IntegrityContainerError))
+
+	for _, stream := range report.Streams {
+		fmt.Fprintf(w, "  Stream #%d (%s, %s):\t%s\n", stream.StreamIndex, stream.Type, stream.Format, formatIntegrityStream(stream))
+	}
+
+	// Only the first errors are listed, integrity.csv has all of them
+	const listed = 10
+	if len(report.Events) > 0 {
+		fmt.Fprintln(w, "\nFirst Errors:")
+		for _, event := range report.Events[:min(listed, len(report.Events))] {
+			fmt.Fprintf(w, "  %s\n", formatIntegrityEvent(event))
+		}
+		if len(report.Events) > listed {
+			fmt.Fprintf(w, "  ... and %d more\n", len(report.Events)-listed)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// writeMediaInfoChecksums writes the file checksum and the content hashes of the decoded streams
+func writeMediaInfoChecksums(w *tabwriter.Writer, checksums *ffmpeg.FileHash) {
+	if checksums == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w, "CHECKSUMS")
+	fmt.Fprintln(w, "===========================================")
+
+	fmt.Fprintf(w, "\nSHA-256:\t%s\n", checksums.SHA256)
+	fmt.Fprintf(w, "Content Hash:\t%s\n", checksums.ContentHash)
+	for _, stream := range checksums.Streams {
+		fmt.Fprintf(w, "  Stream #%d (%s):\t%d frames, %s\n", stream.StreamIndex, stream.Type, stream.Frames, stream.ContentHash)
+	}
 	fmt.Fprintln(w)
 }
 
@@ -362,7 +1052,21 @@ func writeMediaInfoVideoStreams(w *tabwriter.Writer, streams []ffmpeg.VideoStrea
 		if stream.DisplayAspectRatio > 0 {
 			fmt.Fprintf(w, "  Aspect Ratio:\t%.3f\n", stream.DisplayAspectRatio)
 		}
+		if stream.Crop != nil {
+			fmt.Fprintf(w, "  Active Image:\t%s\n", formatActiveImage(stream.Crop))
+			fmt.Fprintf(w, "  Recommended Crop:\t%s\n", stream.Crop.Crop.Filter())
+			if len(stream.Crop.Segments) > 1 {
+				fmt.Fprintln(w, "  Crop Segments:")
+				for _, segment := range stream.Crop.Segments {
+					fmt.Fprintf(w, "    %s\n", formatCropSegment(segment))
+				}
+			}
+		}
 		fmt.Fprintf(w, "  Frame Rate:\t%.3f fps\n", stream.FrameRate)
+		if stream.Cadence != nil {
+			fmt.Fprintf(w, "  Unique Frame Rate:\t%s\n", formatCadence(stream.Cadence))
+			writeMediaInfoCadenceSegments(w, stream.Cadence)
+		}
 
 		// Handle bitrate display with calculation if missing
 		videoBitrate := stream.BitRate
@@ -385,16 +1089,59 @@ func writeMediaInfoVideoStreams(w *tabwriter.Writer, streams []ffmpeg.VideoStrea
 		}
 
 		if stream.ScanType != "" {
-			fmt.Fprintf(w, "  Scan Type:\t%s\n", stream.ScanType)
+			fmt.Fprintf(w, "  Scan Type:\t%s\n", formatScanType(stream))
+		}
+
+		if stream.ScanAnalysis != nil && len(stream.ScanAnalysis.Segments) > 1 {
+			fmt.Fprintln(w, "  Scan Segments:")
+			for _, segment := range stream.ScanAnalysis.Segments {
+				fmt.Fprintf(w, "    %s\n", formatScanTypeSegment(segment))
+			}
 		}
 
 		if stream.Language != "" {
 			fmt.Fprintf(w, "  Language:\t%s\n", stream.Language)
 		}
+
+		fmt.Fprintf(w, "  Disposition:\t%s\n", formatDisposition(stream.Disposition))
+
+		if stream.Events != nil {
+			fmt.Fprintf(w, "  Black Segments:\t%s\n", formatVideoEvents(stream.Events, ffmpeg.VideoEventBlack))
+			fmt.Fprintf(w, "  Blank Frames:\t%s\n", formatVideoEvents(stream.Events, ffmpeg.VideoEventBlankFrames))
+			fmt.Fprintf(w, "  Frozen Segments:\t%s\n", formatVideoEvents(stream.Events, ffmpeg.VideoEventFreeze))
+			if stream.Events.IgnoreEdges {
+				fmt.Fprintln(w, "  Leading/Trailing Black:\tignored")
+			}
+		}
+
+		if stream.Artifacts != nil {
+			fmt.Fprintf(w, "  Artifact Score:\t%s\n", formatArtifactScore(stream.Artifacts))
+			fmt.Fprintf(w, "  Blockiness:\t%.2f average, %.2f max\n", stream.Artifacts.AverageBlockiness, stream.Artifacts.MaxBlockiness)
+			fmt.Fprintf(w, "  Blur:\t%.2f average, %.2f max\n", stream.Artifacts.AverageBlur, stream.Artifacts.MaxBlur)
+			fmt.Fprintf(w, "  Banding:\t%.2f%% average, %.2f%% max\n", stream.Artifacts.AverageBanding, stream.Artifacts.MaxBanding)
+		}
 	}
 	fmt.Fprintln(w)
 }
 
+// writeMediaInfoCadenceSegments writes the segments of a video stream with duplicate or dropped frames
+func writeMediaInfoCadenceSegments(w *tabwriter.Writer, report *ffmpeg.CadenceReport) {
+	segments := report.IrregularSegments()
+	if len(segments) == 0 {
+		return
+	}
+
+	// Only the first segments are listed, cadence.csv has all of them
+	const listed = 10
+	fmt.Fprintln(w, "  Cadence Segments:")
+	for _, segment := range segments[:min(listed, len(segments))] {
+		fmt.Fprintf(w, "    %s\n", formatCadenceSegment(segment))
+	}
+	if len(segments) > listed {
+		fmt.Fprintf(w, "    ... and %d more\n", len(segments)-listed)
+	}
+}
+
 // writeMediaInfoAudioStreams writes audio stream information
 func writeMediaInfoAudioStreams(w *tabwriter.Writer, streams []ffmpeg.AudioStream) {
 	if len(streams) == 0 {
@@ -428,6 +1175,21 @@ func writeMediaInfoAudioStreams(w *tabwriter.Writer, streams []ffmpeg.AudioStrea
 		if stream.Language != "" {
 			fmt.Fprintf(w, "  Language:\t%s\n", stream.Language)
 		}
+
+		fmt.Fprintf(w, "  Disposition:\t%s\n", formatDisposition(stream.Disposition))
+
+		if loudness := stream.Loudness; loudness != nil {
+			fmt.Fprintf(w, "  Loudness:\t%.1f LUFS (%s target %.1f ±%.1f LU: %s)\n",
+				loudness.IntegratedLoudness, loudness.Target.Name, loudness.Target.IntegratedLoudness,
+				loudness.Target.Tolerance, formatPassFail(loudness.IntegratedPass))
+			fmt.Fprintf(w, "  Loudness Range:\t%.1f LU\n", loudness.LoudnessRange)
+			fmt.Fprintf(w, "  True Peak:\t%.1f dBTP (max %.1f dBTP: %s)\n",
+				loudness.TruePeak, loudness.Target.MaxTruePeak, formatPassFail(loudness.TruePeakPass))
+		}
+
+		if stream.QC != nil {
+			fmt.Fprintf(w, "  Audio QC:\t%s\n", formatAudioQC(stream.QC))
+		}
 	}
 	fmt.Fprintln(w)
 }
@@ -453,27 +1215,177 @@ func writeMediaInfoSubtitleStreams(w *tabwriter.Writer, streams []ffmpeg.Subtitl
 		if stream.Language != "" {
 			fmt.Fprintf(w, "  Language:\t%s\n", stream.Language)
 		}
+
+		fmt.Fprintf(w, "  Disposition:\t%s\n", formatDisposition(stream.Disposition))
 	}
 	fmt.Fprintln(w)
 }
 
-// writeMediaInfoChapters writes chapter information
-func writeMediaInfoChapters(w *tabwriter.Writer, chapters []ffmpeg.ChapterStream) {
-	if len(chapters) == 0 {
+// writeMediaInfoSubtitleAnalysis writes the event analysis summary of the subtitle streams
+func writeMediaInfoSubtitleAnalysis(w *tabwriter.Writer, streams []ffmpeg.SubtitleStream) {
+	if !hasSubtitleAnalysis(streams) {
 		return
 	}
 
 	fmt.Fprintln(w, "===========================================")
-	fmt.Fprintln(w, "CHAPTERS")
+	fmt.Fprintln(w, "SUBTITLE ANALYSIS")
 	fmt.Fprintln(w, "===========================================")
 
-	for _, chapter := range chapters {
-		fmt.Fprintf(w, "\nChapter #%d:\n", chapter.ID)
-		if chapter.Title != "" {
-			fmt.Fprintf(w, "  Title:\t%s\n", chapter.Title)
+	for i, stream := range streams {
+		report := stream.Analysis
+		if report == nil {
+			continue
 		}
 
-		// Format start and end times
+		fmt.Fprintf(w, "\nStream #%d (%s):\n", i, formatSubtitleStreamName(stream))
+		fmt.Fprintf(w, "  Events:\t%s\n", formatSubtitleEvents(report))
+		if report.EventCount == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "  Overlapping Cues:\t%d\n", report.OverlapCount)
+		if !report.Bitmap {
+			fmt.Fprintf(w, "  Reading Speed Violations:\t%d\n", report.ReadingSpeedViolations)
+		}
+		fmt.Fprintf(w, "  Cues Beyond Video End:\t%d\n", report.BeyondVideoCount)
+	}
+	fmt.Fprintln(w)
+}
+
+// writeMediaInfoSync writes the A/V sync check of the audio and subtitle streams
+func writeMediaInfoSync(w *tabwriter.Writer, report *ffmpeg.SyncReport) {
+	if report == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w, "SYNC CHECK")
+	fmt.Fprintln(w, "===========================================")
+
+	fmt.Fprintf(w, "\nReference:\tVideo stream index %d (start %.3f s, duration %s)\n",
+		report.VideoStreamIndex, report.VideoStartTime, formatDuration(report.VideoDuration))
+	fmt.Fprintf(w, "Threshold:\t%.0f ms\n", report.Threshold*1000)
+	if report.ContentChecked {
+		fmt.Fprintf(w, "Scene Cuts:\t%d\n", report.SceneCuts)
+	}
+	fmt.Fprintf(w, "Result:\t%s\n", formatPassFail(report.Passed))
+
+	for _, stream := range report.Streams {
+		fmt.Fprintf(w, "\n%s:\n", formatSyncStreamName(stream))
+		fmt.Fprintf(w, "  Start Offset:\t%s\n", formatSyncOffset(stream.StartDelta))
+		if stream.DurationDelta != 0 {
+			fmt.Fprintf(w, "  Duration Delta:\t%+.3f s\n", stream.DurationDelta)
+		}
+		if stream.EditListDelta != 0 {
+			fmt.Fprintf(w, "  Edit List Offset:\t%s\n", formatSyncOffset(stream.EditListDelta))
+		}
+		if report.ContentChecked && stream.Type == "audio" {
+			fmt.Fprintf(w, "  Content Offset:\t%s\n", formatSyncContentOffset(stream))
+		}
+		fmt.Fprintf(w, "  Status:\t%s\n", formatPassFail(!stream.OutOfSync))
+	}
+	fmt.Fprintln(w)
+}
+
+// writeMediaInfoScenes writes the scene summary and the scenes starved of bits
+func writeMediaInfoScenes(w *tabwriter.Writer, report *ffmpeg.SceneReport) {
+	if report == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w, "SCENES")
+	fmt.Fprintln(w, "===========================================")
+
+	aligned, cuts := report.KeyframesAtCuts()
+	fmt.Fprintf(w, "\nScenes:\t%d (threshold %g)\n", len(report.Scenes), report.Threshold)
+	fmt.Fprintf(w, "Average Scene Duration:\t%s\n", formatDuration(report.AverageDuration()))
+	fmt.Fprintf(w, "Average Bit Rate:\t%.2f Kbps\n", report.AverageBitrate/1000)
+	fmt.Fprintf(w, "Keyframes at Cuts:\t%d of %d\n", aligned, cuts)
+	fmt.Fprintf(w, "Starved Scenes:\t%d\n", report.StarvedScenes())
+
+	for _, scene := range report.Scenes {
+		if scene.Starved {
+			fmt.Fprintf(w, "  %s\n", formatScene(scene, report.AverageBitrate))
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// writeMediaInfoKeyframes writes the keyframe alignment of the chapter starts and scene cuts
+func writeMediaInfoKeyframes(w *tabwriter.Writer, report *ffmpeg.KeyframeReport) {
+	if report == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w, "KEYFRAME ALIGNMENT")
+	fmt.Fprintln(w, "===========================================")
+
+	fmt.Fprintf(w, "\nKeyframes:\t%d\n", report.Keyframes())
+	if len(report.Chapters) > 0 {
+		fmt.Fprintf(w, "Chapters on Keyframes:\t%d of %d\n", len(report.Chapters)-report.MisalignedChapters(), len(report.Chapters))
+		for _, chapter := range report.Chapters {
+			if !chapter.Aligned {
+				fmt.Fprintf(w, "  Chapter #%d\t%s\n", chapter.Index, formatKeyframeAlignment(chapter))
+			}
+		}
+	}
+	if len(report.SceneCuts) > 0 {
+		fmt.Fprintf(w, "Scene Cuts on Keyframes:\t%d of %d\n", len(report.SceneCuts)-report.MisalignedSceneCuts(), len(report.SceneCuts))
+		if worst, found := report.MaxSceneCutOffset(); found {
+			fmt.Fprintf(w, "Largest Scene Cut Offset:\tScene #%d, %s\n", worst.Index, formatKeyframeAlignment(worst))
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// writeMediaInfoTimestamps writes the timestamp anomalies of the primary video stream
+func writeMediaInfoTimestamps(w *tabwriter.Writer, report *ffmpeg.TimestampReport) {
+	if report == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w, "TIMESTAMPS")
+	fmt.Fprintln(w, "===========================================")
+
+	fmt.Fprintf(w, "\nFrames Checked:\t%d\n", report.Frames)
+	fmt.Fprintf(w, "Errors:\t%d\n", report.CountSeverity(ffmpeg.SeverityError))
+	fmt.Fprintf(w, "Warnings:\t%d\n", report.CountSeverity(ffmpeg.SeverityWarning))
+	for _, anomalyType := range timestampAnomalyTypes {
+		if count := report.Count(anomalyType); count > 0 {
+			fmt.Fprintf(w, "  %s:\t%d\n", strings.ReplaceAll(anomalyType, "_", " "), count)
+		}
+	}
+
+	// Only the first anomalies are listed, timestamps.csv has all of them
+	const listed = 10
+	for _, anomaly := range report.Anomalies[:min(listed, len(report.Anomalies))] {
+		fmt.Fprintf(w, "  Frame #%d\t%s\n", anomaly.FrameNumber, formatTimestampAnomaly(anomaly))
+	}
+	if len(report.Anomalies) > listed {
+		fmt.Fprintf(w, "  ... and %d more\n", len(report.Anomalies)-listed)
+	}
+	fmt.Fprintln(w)
+}
+
+// writeMediaInfoChapters writes chapter information
+func writeMediaInfoChapters(w *tabwriter.Writer, chapters []ffmpeg.ChapterStream) {
+	if len(chapters) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w, "CHAPTERS")
+	fmt.Fprintln(w, "===========================================")
+
+	for _, chapter := range chapters {
+		fmt.Fprintf(w, "\nChapter #%d:\n", chapter.ID)
+		if chapter.Title != "" {
+			fmt.Fprintf(w, "  Title:\t%s\n", chapter.Title)
+		}
+
+		// Format start and end times
 		var startTimeStr, endTimeStr string
 		if chapter.StartTime == float64(int(chapter.StartTime)) {
 			startTimeStr = fmt.Sprintf("%d seconds", int(chapter.StartTime))
@@ -525,6 +1437,10 @@ func writeMediaInfoAttachments(w *tabwriter.Writer, attachments []ffmpeg.Attachm
 		if attachment.MimeType != "" {
 			fmt.Fprintf(w, "  MIME Type:\t%s\n", attachment.MimeType)
 		}
+		if attachment.SHA256 != "" {
+			fmt.Fprintf(w, "  Size:\t%s\n", formatHumanReadableSize(int(attachment.Size)))
+			fmt.Fprintf(w, "  SHA-256:\t%s\n", attachment.SHA256)
+		}
 	}
 	fmt.Fprintln(w)
 }
@@ -537,140 +1453,574 @@ func writeMediaInfoFooter(w *tabwriter.Writer) {
 	fmt.Fprintln(w, "===========================================")
 }
 
-// saveMediaInfoText saves detailed container information to a text file in the specified directory.
-// It includes comprehensive information about the container and all streams.
-func saveMediaInfoText(info *ffmpeg.ContainerInfo, outputDir string, prober *ffmpeg.Prober) error {
-	// Create the output directory if it doesn't exist
-	err := os.MkdirAll(outputDir, 0755)
-	if err != nil {
-		return fmt.Errorf("error creating output directory: %w", err)
+// writeDiffReport writes the comparison of several encodes: the metadata of every file with the
+// differing properties marked, the average bitrate and QP, the frame sizes by frame type, the
+// winner of every scene and the verdict.
+func writeDiffReport(w *tabwriter.Writer, comparison *ffmpeg.EncodeComparison) {
+	files := comparison.Files
+	if len(files) == 0 {
+		return
 	}
+	reference := files[0]
 
-	// Define output file path
-	outputPath := filepath.Join(outputDir, "mediainfo.txt")
-
-	// Create the file
-	file, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("error creating mediainfo file: %w", err)
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w, "ENCODE COMPARISON")
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w)
+	for i, file := range files {
+		role := ""
+		if i == 0 {
+			role = " (reference)"
+		}
+		fmt.Fprintf(w, "[%d]:\t%s%s\n", i+1, filepath.Base(file.Path), role)
 	}
-	defer file.Close()
+	fmt.Fprintln(w)
 
-	// Initialize tabwriter for better formatting
-	w := tabwriter.NewWriter(file, 0, 0, 2, ' ', tabwriter.StripEscape)
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w, "METADATA")
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w)
+	for _, difference := range comparison.Metadata {
+		marker := " "
+		if difference.Differs {
+			marker = "*"
+		}
+		values := make([]string, len(difference.Values))
+		for i, value := range difference.Values {
+			if value == "" {
+				value = "-"
+			}
+			values[i] = fmt.Sprintf("[%d] %s", i+1, value)
+		}
+		fmt.Fprintf(w, "%s %s:\t%s\n", marker, difference.Field, strings.Join(values, "\t"))
+	}
+	fmt.Fprintln(w)
 
-	// Get the container title
-	containerTitle := prober.GetContainerTitle(info)
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w, "BITRATE AND QP")
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w)
+	for i, file := range files {
+		fmt.Fprintf(w, "[%d]:\t%s\n", i+1, formatComparedFile(file, reference, i == 0))
+	}
 
-	// Get the filename from the Tags map
-	fileName := ""
-	if info.General.Tags != nil {
-		if path, ok := info.General.Tags["file_path"]; ok {
-			fileName = filepath.Base(path)
+	// Frame types in the usual order, then any other type the files have
+	frameTypes := []string{"I", "P", "B"}
+	for _, file := range files {
+		for frameType := range file.FrameTypes {
+			if !slices.Contains(frameTypes, frameType) {
+				frameTypes = append(frameTypes, frameType)
+			}
+		}
+	}
+	fmt.Fprintln(w, "\nAverage Frame Size:")
+	for _, frameType := range frameTypes {
+		for i, file := range files {
+			size, found := file.FrameTypes[frameType]
+			if !found {
+				continue
+			}
+			description := fmt.Sprintf("%s (%d frames)", formatHumanReadableSize(int(size.AverageSize/8)), size.Frames)
+			if i > 0 && size.Ratio > 0 {
+				description += fmt.Sprintf(", %.2fx the reference", size.Ratio)
+			}
+			fmt.Fprintf(w, "  %s-Frames [%d]:\t%s\n", frameType, i+1, description)
 		}
 	}
+	fmt.Fprintln(w)
 
-	// Count streams
-	videoCount := len(info.VideoStreams)
-	audioCount := len(info.AudioStreams)
-	subtitleCount := len(info.SubtitleStreams)
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w, "SCENES")
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w)
+	criterion := "none, QPs are not comparable (different codecs or no QP), bitrates shown relative to the reference"
+	if comparison.QPComparable {
+		criterion = "lowest QP"
+	}
+	fmt.Fprintf(w, "Winner Criterion:\t%s\n", criterion)
+	for _, scene := range comparison.Scenes {
+		values := make([]string, len(files))
+		for i := range files {
+			values[i] = fmt.Sprintf("[%d] %.2f Kbps", i+1, scene.Bitrate[i]/1000)
+			if scene.AverageQP[i] > 0 {
+				values[i] += fmt.Sprintf(" QP %.2f", scene.AverageQP[i])
+			}
+			if !comparison.QPComparable && i > 0 && scene.Bitrate[0] > 0 {
+				values[i] += fmt.Sprintf(" (%.2fx)", scene.Bitrate[i]/scene.Bitrate[0])
+			}
+		}
+		winner := "-"
+		if scene.Winner >= 0 {
+			winner = fmt.Sprintf("[%d]", scene.Winner+1)
+		}
+		fmt.Fprintf(w, "  Scene #%d %s - %s:\t%s\twinner %s\n", scene.Index,
+			formatTimestamp(scene.Start), formatTimestamp(scene.End), strings.Join(values, "\t"), winner)
+	}
+	fmt.Fprintln(w)
 
-	// Write the different sections of the report
-	writeMediaInfoHeader(w, containerTitle, fileName, videoCount, audioCount, subtitleCount)
-	writeMediaInfoBasicData(w, info)
-	writeMediaInfoContainerSection(w, info)
-	writeMediaInfoVideoStreams(w, info.VideoStreams, info)
-	writeMediaInfoAudioStreams(w, info.AudioStreams)
-	writeMediaInfoSubtitleStreams(w, info.SubtitleStreams)
-	writeMediaInfoChapters(w, info.ChapterStreams)
-	writeMediaInfoAttachments(w, info.AttachmentStreams)
-	writeMediaInfoFooter(w)
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w, "VERDICT")
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w)
+	won := make([]string, len(files))
+	for i, file := range files {
+		won[i] = fmt.Sprintf("[%d] %d", i+1, file.ScenesWon)
+	}
+	fmt.Fprintf(w, "Scenes Won:\t%s\n", strings.Join(won, ", "))
+	if !comparison.QPComparable {
+		fmt.Fprintln(w, "Verdict:\tNo winner, bitrate alone does not measure quality; compare the scene bitrates")
+	} else if comparison.Winner < 0 {
+		fmt.Fprintln(w, "Verdict:\tNo winner, the files have no comparable frames")
+	} else {
+		winner := files[comparison.Winner]
+		verdict := fmt.Sprintf("[%d] %s wins %d of %d scenes", comparison.Winner+1, filepath.Base(winner.Path),
+			winner.ScenesWon, len(comparison.Scenes))
+		if comparison.Winner > 0 && reference.TotalBits > 0 {
+			verdict += fmt.Sprintf(" at %.1f%% of the size of the reference", float64(winner.TotalBits)*100/float64(reference.TotalBits))
+		}
+		fmt.Fprintf(w, "Verdict:\t%s\n", verdict)
+	}
+	fmt.Fprintln(w)
+}
 
-	// Flush buffered data to ensure it's written to the file
-	if err := w.Flush(); err != nil {
-		return fmt.Errorf("error flushing output: %w", err)
+// writeDedupeReport writes the clusters of files with matching content, the content every
+// matching pair shares and the best copy of every cluster. Paths are shown relative to the
+// scanned directory.
+func writeDedupeReport(w *tabwriter.Writer, clusters []ffmpeg.FingerprintCluster, files int, dir string) {
+	relative := func(path string) string {
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			return rel
+		}
+		return path
 	}
 
-	// Print confirmation message with proper styling
-	successStyle := color.New(color.FgGreen)
-	successStyle.Printf("✅ Media information saved to %s\n", outputPath)
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w, "DUPLICATE CONTENT")
+	fmt.Fprintln(w, "===========================================")
 
-	return nil
+	fmt.Fprintf(w, "\nFiles:\t%d\n", files)
+	fmt.Fprintf(w, "Clusters:\t%d\n", len(clusters))
+
+	for i, cluster := range clusters {
+		fmt.Fprintf(w, "\nCluster #%d:\t%d files\n", i+1, len(cluster.Files))
+		for _, file := range cluster.Files {
+			marker := " "
+			if file == cluster.Best {
+				marker = "*"
+			}
+			fmt.Fprintf(w, "  %s %s\t%s\n", marker, relative(file.Path), formatFingerprintFile(file))
+		}
+		fmt.Fprintln(w, "  Overlaps:")
+		for _, match := range cluster.Matches {
+			fmt.Fprintf(w, "    %s %s - %s\t%s %s - %s (%d scenes)\n",
+				relative(match.PathA), formatTimestamp(match.StartA), formatTimestamp(match.EndA),
+				relative(match.PathB), formatTimestamp(match.StartB), formatTimestamp(match.EndB), match.Scenes)
+		}
+		fmt.Fprintf(w, "  Best Copy:\t%s\n", relative(cluster.Best.Path))
+	}
+	fmt.Fprintln(w)
 }
 
-// formatHumanReadableSize formats a size in bytes to a human-readable format
-func formatHumanReadableSize(bytes int) string {
-	const (
-		_          = iota
-		KB float64 = 1 << (10 * iota)
-		MB
-		GB
-		TB
-	)
+// writeHistoryReport writes the stored analyses of a file, oldest first, with the summary
+// statistics of each.
+func writeHistoryReport(w *tabwriter.Writer, runs []*ffmpeg.StoredRun, fileName string) {
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w, "ANALYSIS HISTORY")
+	fmt.Fprintln(w, "===========================================")
 
-	if bytes < 1000 {
-		return fmt.Sprintf("%d bytes", bytes)
-	} else if bytes < 1000*int(KB) {
-		return fmt.Sprintf("%.2f KB", float64(bytes)/KB)
-	} else if bytes < 1000*int(MB) {
-		return fmt.Sprintf("%.2f MB", float64(bytes)/MB)
-	} else if bytes < 1000*int(GB) {
-		return fmt.Sprintf("%.2f GB", float64(bytes)/GB)
+	fmt.Fprintf(w, "\nFile:\t%s\n", fileName)
+	if len(runs) > 0 {
+		fmt.Fprintf(w, "SHA-256:\t%s\n", runs[0].Hash)
+		fmt.Fprintf(w, "Size:\t%s\n", formatHumanReadableSize(int(runs[0].Size)))
 	}
-	return fmt.Sprintf("%.2f TB", float64(bytes)/TB)
+	fmt.Fprintf(w, "Analyses:\t%d\n", len(runs))
+
+	for i, run := range runs {
+		summary := run.Summary
+		fmt.Fprintf(w, "\nAnalysis #%d:\t%s\n", i+1, run.Analyzed.Format(time.RFC1123))
+		fmt.Fprintf(w, "  Path:\t%s\n", run.Path)
+		if run.Version != "" {
+			fmt.Fprintf(w, "  FrameHound Version:\t%s\n", run.Version)
+		}
+		fmt.Fprintf(w, "  Duration:\t%s\n", formatDuration(summary.Duration))
+		if summary.VideoCodec != "" {
+			fmt.Fprintf(w, "  Video:\t%s %s @ %.3f fps\n", summary.VideoCodec, summary.Resolution, summary.FrameRate)
+		}
+		if summary.FrameCount > 0 {
+			fmt.Fprintf(w, "  Frames:\t%s\n", formatWithThousandSeparators(int64(summary.FrameCount)))
+			fmt.Fprintf(w, "  Average Bit Rate:\t%.2f Kbps\n", summary.AverageBitrate/1000)
+			fmt.Fprintf(w, "  Peak Bit Rate:\t%.2f Kbps\n", summary.PeakBitrate/1000)
+		}
+		if len(summary.AudioCodecs) > 0 {
+			fmt.Fprintf(w, "  Audio:\t%s\n", strings.Join(summary.AudioCodecs, ", "))
+		}
+		if summary.Loudness != nil {
+			fmt.Fprintf(w, "  Integrated Loudness:\t%.1f LUFS\n", *summary.Loudness)
+		}
+		if summary.Healthy != nil {
+			fmt.Fprintf(w, "  Integrity:\t%s\n", formatIntegrity(*summary.Healthy))
+		}
+		if run.Frames > 0 {
+			fmt.Fprintf(w, "  Stored Frames:\t%s\n", formatWithThousandSeparators(int64(run.Frames)))
+		}
+	}
+	fmt.Fprintln(w)
 }
 
-// Public functions (alphabetical)
-
-// analyzeCommand implements the default command which analyzes a video file.
-// It reads the video file, extracts container information, and outputs the results.
-func analyzeCommand(c *cli.Context) error {
-	valueStyle := color.New(color.Bold)
-	regularStyle := color.New(color.Reset)
-	successStyle := color.New(color.FgGreen)
-	errorStyle := color.New(color.FgRed)
+// writeQueryReport writes the stored analyses matching a query, out of the searched ones.
+func writeQueryReport(w *tabwriter.Writer, query *ffmpeg.Query, matches []*ffmpeg.StoredRun, searched int) {
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w, "QUERY RESULTS")
+	fmt.Fprintln(w, "===========================================")
 
-	// Get the file path from the first argument
-	if c.NArg() < 1 {
-		// Display a more user-friendly message and usage information
-		errorStyle.Printf("❌ Error: missing required argument: VIDEO_FILE\n\n")
-		regularStyle.Printf("Usage: %s [options] VIDEO_FILE\n", c.App.Name)
-		regularStyle.Printf("Run '%s --help' for more information.\n", c.App.Name)
-		return fmt.Errorf("missing required argument: VIDEO_FILE")
-	}
-	filePath := c.Args().Get(0)
-	outputDir := c.String("dir")
+	fmt.Fprintf(w, "\nQuery:\t%s\n", query.Text)
+	fmt.Fprintf(w, "Matches:\t%d of %d analyses\n", len(matches), searched)
 
-	// Convert to absolute path
-	absPath, err := filepath.Abs(filePath)
-	if err != nil {
-		return fmt.Errorf("error resolving absolute path: %w", err)
+	if len(matches) > 0 {
+		fmt.Fprintln(w)
 	}
-
-	// Create FFmpegInfo instance and detect FFmpeg
-	ffmpegInfo, err := ffmpeg.DetectFFmpeg()
-	if err != nil {
-		return fmt.Errorf("failed to detect FFmpeg: %w", err)
+	for _, run := range matches {
+		fmt.Fprintf(w, "%s\t%s\n", run.Path, formatStoredSummary(run.Summary))
 	}
+	fmt.Fprintln(w)
+}
 
-	// Print FFmpeg info
-	valueStyle.Printf("🔧 Using FFmpeg at %s\n", ffmpegInfo.Path)
-	valueStyle.Printf("🔖 FFmpeg version: %s\n", ffmpegInfo.Version)
+// writeCheckReport writes the outcome of every rule of a spec with its threshold and the reason
+// for it, so the report documents the profile the file was checked against.
+func writeCheckReport(w *tabwriter.Writer, result *ffmpeg.SpecResult, fileName string) {
+	spec := result.Spec
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w, "DELIVERY SPEC CHECK")
+	fmt.Fprintln(w, "===========================================")
 
-	// Create a prober
-	prober, err := ffmpeg.NewProber(ffmpegInfo)
-	if err != nil {
-		return fmt.Errorf("failed to create prober: %w", err)
+	fmt.Fprintf(w, "\nFile:\t%s\n", fileName)
+	fmt.Fprintf(w, "Spec:\t%s\n", spec.Name)
+	if spec.Version != "" {
+		fmt.Fprintf(w, "Version:\t%s\n", spec.Version)
 	}
-
-	// Get file info
-	containerInfo, err := prober.GetExtendedContainerInfo(absPath)
-	if err != nil {
-		return fmt.Errorf("failed to analyze file: %w", err)
+	if spec.Profile != "" {
+		fmt.Fprintf(w, "Profile:\t%s\n", spec.Profile)
 	}
+	if spec.Description != "" {
+		fmt.Fprintf(w, "Description:\t%s\n", spec.Description)
+	}
+	verdict := formatPassFail(result.Passed)
+	if !result.Passed {
+		verdict += fmt.Sprintf(" (%d of %d rules failed)", result.Failures(), len(result.Rules))
+	}
+	fmt.Fprintf(w, "Result:\t%s\n", verdict)
 
-	printSimpleContainerSummary(containerInfo, prober)
+	fmt.Fprintln(w, "\n===========================================")
+	fmt.Fprintln(w, "RULES")
+	fmt.Fprintln(w, "===========================================")
 
+	for i, rule := range result.Rules {
+		fmt.Fprintf(w, "\nRule #%d:\t%s\n", i+1, rule.Name)
+		if rule.Name != rule.Rule.Field {
+			fmt.Fprintf(w, "  Field:\t%s\n", rule.Rule.Field)
+		}
+		fmt.Fprintf(w, "  Threshold:\t%s\n", rule.Rule.Expectation())
+		if rule.Rule.Description != "" {
+			fmt.Fprintf(w, "  Reason:\t%s\n", rule.Rule.Description)
+		}
+		if spec.Profile != "" {
+			origin := rule.Rule.Source
+			if origin == "" {
+				origin = "override"
+			}
+			fmt.Fprintf(w, "  Origin:\t%s\n", origin)
+		}
+		fmt.Fprintf(w, "  Actual:\t%s\n", strings.Join(rule.Actual, ", "))
+		outcome := formatPassFail(rule.Passed)
+		if !rule.Passed {
+			outcome += " (" + rule.Message + ")"
+		}
+		fmt.Fprintf(w, "  Result:\t%s\n", outcome)
+	}
+	fmt.Fprintln(w)
+}
+
+// writeJUnitReport writes the outcome of every rule of a spec as a JUnit XML report, so CI
+// pipelines can show the rules as test cases. Failed rules carry the failure message.
+func writeJUnitReport(w io.Writer, result *ffmpeg.SpecResult, fileName string) error {
+	suite := junitTestSuite{Name: result.Spec.Name, Tests: len(result.Rules), Failures: result.Failures()}
+	properties := &junitProperties{}
+	for _, property := range []junitProperty{
+		{Name: "version", Value: result.Spec.Version},
+		{Name: "profile", Value: result.Spec.Profile},
+		{Name: "description", Value: result.Spec.Description},
+	} {
+		if property.Value != "" {
+			properties.Properties = append(properties.Properties, property)
+		}
+	}
+	if len(properties.Properties) > 0 {
+		suite.Properties = properties
+	}
+	for _, rule := range result.Rules {
+		testCase := junitTestCase{Name: rule.Name, ClassName: fileName}
+		details := fmt.Sprintf("Field: %s\nExpected: %s\nActual: %s",
+			rule.Rule.Field, rule.Rule.Expectation(), strings.Join(rule.Actual, ", "))
+		if rule.Rule.Description != "" {
+			details += "\nReason: " + rule.Rule.Description
+		}
+		if rule.Passed {
+			testCase.SystemOut = details
+		} else {
+			testCase.Failure = &junitFailure{Message: rule.Message, Type: "SpecViolation", Text: details}
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	report := junitTestSuites{Name: "framehound", Tests: suite.Tests, Failures: suite.Failures, Suites: []junitTestSuite{suite}}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("error writing JUnit report: %w", err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("error encoding JUnit report: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("error writing JUnit report: %w", err)
+	}
+	return nil
+}
+
+// newJobServer creates a job server keeping its files in dir, with a queue of queueSize jobs.
+// The workers are started separately with work.
+func newJobServer(ffmpegInfo *ffmpeg.FFmpegInfo, prober *ffmpeg.Prober, dir string, queueSize int) *jobServer {
+	server := &jobServer{
+		ffmpegInfo: ffmpegInfo,
+		prober:     prober,
+		dir:        dir,
+		retention:  jobDefaultRetention,
+		maxUpload:  jobDefaultMaxUpload,
+		queue:      make(chan *analysisJob, queueSize),
+		jobs:       make(map[string]*analysisJob),
+	}
+	server.analyze = server.runJob
+	return server
+}
+
+// writeJobCSV writes the size, type, time and QP of every frame measured by a job, or stored
+// with an analysis. The QP column is empty for frames without a QP.
+func writeJobCSV(w io.Writer, frames []ffmpeg.FrameBitrateInfo, qp []jobQP) error {
+	frameQP := make(map[int]float64, len(qp))
+	for _, frame := range qp {
+		frameQP[frame.FrameNumber] = frame.QP
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"frame_number", "frame_type", "bitrate", "time", "qp"}); err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
+	}
+	for _, frame := range frames {
+		record := []string{
+			strconv.Itoa(frame.FrameNumber),
+			frame.FrameType,
+			strconv.FormatInt(frame.Bitrate, 10),
+			strconv.FormatFloat(frame.Time, 'f', 6, 64),
+			"",
+		}
+		if value, ok := frameQP[frame.FrameNumber]; ok {
+			record[4] = strconv.FormatFloat(value, 'f', 2, 64)
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("error writing CSV record: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing frames CSV: %w", err)
+	}
+	return nil
+}
+
+// writeJSONResponse writes a value as a JSON response with the given status code.
+func writeJSONResponse(w http.ResponseWriter, status int, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error encoding response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(data, '\n'))
+}
+
+// writeJSONError writes an error message as a JSON response with the given status code.
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSONResponse(w, status, map[string]string{"error": message})
+}
+
+// writeServerEvent writes a value as a server-sent event of the given type.
+func writeServerEvent(w io.Writer, event string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("error encoding %s event: %w", event, err)
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+// writeLadderReport writes the declared and measured values of every rendition of a manifest
+// followed by the findings of the checks
+func writeLadderReport(w *tabwriter.Writer, manifest *ffmpeg.Manifest) {
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w, "ADAPTIVE BITRATE LADDER")
+	fmt.Fprintln(w, "===========================================")
+
+	fmt.Fprintf(w, "\nManifest:\t%s (%s)\n", filepath.Base(manifest.Path), manifest.Format)
+	fmt.Fprintf(w, "Renditions:\t%d\n", len(manifest.Renditions))
+	if manifest.TargetDuration > 0 {
+		fmt.Fprintf(w, "Target Duration:\t%g s\n", manifest.TargetDuration)
+	}
+
+	for i, rendition := range manifest.Renditions {
+		fmt.Fprintf(w, "\nRendition #%d:\t%s\n", i+1, rendition.ID)
+		fmt.Fprintf(w, "  Type:\t%s\n", rendition.Type)
+		width, height := rendition.Width, rendition.Height
+		if rendition.Info != nil && len(rendition.Info.VideoStreams) > 0 {
+			width, height = rendition.Info.VideoStreams[0].Width, rendition.Info.VideoStreams[0].Height
+		}
+		if width > 0 && height > 0 {
+			fmt.Fprintf(w, "  Resolution:\t%dx%d\n", width, height)
+		}
+		if rendition.Codecs != "" {
+			fmt.Fprintf(w, "  Codecs:\t%s\n", rendition.Codecs)
+		}
+
+		declared := "not declared"
+		if rendition.Bandwidth > 0 {
+			declared = fmt.Sprintf("%.2f Kbps peak", float64(rendition.Bandwidth)/1000)
+			if rendition.AverageBandwidth > 0 {
+				declared += fmt.Sprintf(", %.2f Kbps average", float64(rendition.AverageBandwidth)/1000)
+			}
+		}
+		fmt.Fprintf(w, "  Declared Bandwidth:\t%s\n", declared)
+		fmt.Fprintf(w, "  Measured Bandwidth:\t%.2f Kbps peak, %.2f Kbps average\n",
+			rendition.MeasuredPeakBandwidth/1000, rendition.MeasuredAverageBandwidth/1000)
+
+		segments := strconv.Itoa(len(rendition.Segments))
+		if rendition.MissingSegments > 0 {
+			segments += fmt.Sprintf(" (%d missing)", rendition.MissingSegments)
+		}
+		fmt.Fprintf(w, "  Segments:\t%s\n", segments)
+		if stats := rendition.SegmentDurations; stats.Count > 0 {
+			fmt.Fprintf(w, "  Segment Duration:\t%.3f s average, %.3f - %.3f s, %.3f s std. dev.\n",
+				stats.Mean, stats.Min, stats.Max, stats.StdDev)
+		}
+		if len(rendition.IDRTimes) > 0 && rendition.MissingSegments > 0 {
+			fmt.Fprintf(w, "  IDR Alignment:\tnot checked (segments missing)\n")
+		} else if len(rendition.IDRTimes) > 0 {
+			fmt.Fprintf(w, "  Segments Starting with IDR:\t%d of %d\n", len(rendition.Segments)-rendition.SegmentsWithoutIDR, len(rendition.Segments))
+			alignment := formatPassFail(rendition.MisalignedIDRs == 0)
+			if rendition.MisalignedIDRs > 0 {
+				alignment += fmt.Sprintf(" (%d IDR frames missing)", rendition.MisalignedIDRs)
+			}
+			fmt.Fprintf(w, "  IDR Alignment:\t%s\n", alignment)
+		}
+	}
+
+	fmt.Fprintln(w, "\n===========================================")
+	fmt.Fprintln(w, "ISSUES")
+	fmt.Fprintln(w, "===========================================")
+	fmt.Fprintln(w)
+	if len(manifest.Issues) == 0 {
+		fmt.Fprintln(w, "None")
+	}
+	for _, issue := range manifest.Issues {
+		fmt.Fprintf(w, "- %s\n", issue)
+	}
+	fmt.Fprintln(w)
+}
+
+// saveMediaInfoText saves detailed container information to a text file in the specified directory.
+// It includes comprehensive information about the container and all streams.
+func saveMediaInfoText(info *ffmpeg.ContainerInfo, outputDir string, prober *ffmpeg.Prober) error {
+	// Create the output directory if it doesn't exist
+	err := os.MkdirAll(outputDir, 0755)
+	if err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+
+	// Define output file path
+	outputPath := filepath.Join(outputDir, "mediainfo.txt")
+
+	// Create the file
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating mediainfo file: %w", err)
+	}
+	defer file.Close()
+
+	// Initialize tabwriter for better formatting
+	w := tabwriter.NewWriter(file, 0, 0, 2, ' ', tabwriter.StripEscape)
+
+	// Get the container title
+	containerTitle := prober.GetContainerTitle(info)
+
+	// Get the filename from the Tags map
+	fileName := ""
+	if info.General.Tags != nil {
+		if path, ok := info.General.Tags["file_path"]; ok {
+			fileName = filepath.Base(path)
+		}
+	}
+
+	// Count streams
+	videoCount := len(info.VideoStreams)
+	audioCount := len(info.AudioStreams)
+	subtitleCount := len(info.SubtitleStreams)
+
+	// Write the different sections of the report
+	writeMediaInfoHeader(w, containerTitle, fileName, videoCount, audioCount, subtitleCount)
+	writeMediaInfoBasicData(w, info)
+	writeMediaInfoContainerSection(w, info)
+	writeMediaInfoIntegrity(w, info.Integrity)
+	writeMediaInfoChecksums(w, info.Checksums)
+	writeMediaInfoVideoStreams(w, info.VideoStreams, info)
+	writeMediaInfoAudioStreams(w, info.AudioStreams)
+	writeMediaInfoSubtitleStreams(w, info.SubtitleStreams)
+	writeMediaInfoSubtitleAnalysis(w, info.SubtitleStreams)
+	writeMediaInfoSync(w, info.Sync)
+	writeMediaInfoScenes(w, info.Scenes)
+	writeMediaInfoKeyframes(w, info.Keyframes)
+	writeMediaInfoTimestamps(w, info.Timestamps)
+	writeMediaInfoChapters(w, info.ChapterStreams)
+	writeMediaInfoAttachments(w, info.AttachmentStreams)
+	writeMediaInfoFooter(w)
+
+	// Flush buffered data to ensure it's written to the file
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error flushing output: %w", err)
+	}
+
+	// Print confirmation message with proper styling
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Media information saved to %s\n", outputPath)
+
+	return nil
+}
+
+// saveBoxTree saves the ISO-BMFF box hierarchy of an MP4/MOV container to a text file.
+func saveBoxTree(structure *ffmpeg.MP4Structure, outputDir string) error {
+	outputPath := filepath.Join(outputDir, "boxes.txt")
+
+	if err := os.WriteFile(outputPath, []byte(structure.String()), 0644); err != nil {
+		return fmt.Errorf("error writing box tree file: %w", err)
+	}
+
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Box hierarchy saved to %s\n", outputPath)
+
+	return nil
+}
+
+// prepareOutputDir replaces the output directory with an empty one.
+func prepareOutputDir(outputDir string) error {
 	// Delete the output directory if it exists
 	if _, err := os.Stat(outputDir); err == nil {
 		if err := os.RemoveAll(outputDir); err != nil {
@@ -678,353 +2028,3449 @@ func analyzeCommand(c *cli.Context) error {
 		}
 	}
 
-	// Create output directory
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("error creating output directory: %w", err)
+	// Create output directory
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+	return nil
+}
+
+// formatHumanReadableSize formats a size in bytes to a human-readable format
+func formatHumanReadableSize(bytes int) string {
+	const (
+		_          = iota
+		KB float64 = 1 << (10 * iota)
+		MB
+		GB
+		TB
+	)
+
+	if bytes < 1000 {
+		return fmt.Sprintf("%d bytes", bytes)
+	} else if bytes < 1000*int(KB) {
+		return fmt.Sprintf("%.2f KB", float64(bytes)/KB)
+	} else if bytes < 1000*int(MB) {
+		return fmt.Sprintf("%.2f MB", float64(bytes)/MB)
+	} else if bytes < 1000*int(GB) {
+		return fmt.Sprintf("%.2f GB", float64(bytes)/GB)
+	}
+	return fmt.Sprintf("%.2f TB", float64(bytes)/TB)
+}
+
+// Public functions (alphabetical)
+
+// analyzeCommand implements the default command which analyzes a video file.
+// It reads the video file, extracts container information, and outputs the results.
+func analyzeCommand(c *cli.Context) error {
+	valueStyle := color.New(color.Bold)
+	regularStyle := color.New(color.Reset)
+	successStyle := color.New(color.FgGreen)
+	errorStyle := color.New(color.FgRed)
+
+	// Get the file path from the first argument
+	if c.NArg() < 1 {
+		// Display a more user-friendly message and usage information
+		errorStyle.Printf("❌ Error: missing required argument: VIDEO_FILE\n\n")
+		regularStyle.Printf("Usage: %s [options] VIDEO_FILE\n", c.App.Name)
+		regularStyle.Printf("Run '%s --help' for more information.\n", c.App.Name)
+		return fmt.Errorf("missing required argument: VIDEO_FILE")
+	}
+	filePath := c.Args().Get(0)
+	outputDir := c.String("dir")
+
+	options := &analysisOptions{
+		ShowFrames:      c.Bool("show-frames"),
+		Integrity:       c.Bool("integrity"),
+		FrameHash:       c.Bool("framehash"),
+		Scenes:          c.Bool("scenes"),
+		SceneThreshold:  c.Float64("scene-threshold"),
+		ScanType:        c.Bool("scan-type"),
+		Crop:            c.Bool("crop"),
+		Cadence:         c.Bool("cadence"),
+		BlackFreeze:     c.Bool("black-freeze"),
+		BlackDuration:   c.Float64("black-duration"),
+		BlackThreshold:  c.Float64("black-threshold"),
+		FreezeDuration:  c.Float64("freeze-duration"),
+		FreezeNoise:     c.Float64("freeze-noise"),
+		IgnoreEdgeBlack: c.Bool("ignore-edge-black"),
+		Artifacts:       c.Bool("artifacts"),
+		AudioQC:         c.Bool("audio-qc"),
+		Loudness:        c.String("loudness"),
+		SyncThreshold:   c.Float64("sync-threshold"),
+		SyncContent:     c.Bool("sync-content"),
+		Store:           c.String("store"),
+		StoreFrames:     c.Bool("store-frames"),
+	}
+
+	// Validate the options before starting the analysis
+	if err := resolveAnalysisOptions(options); err != nil {
+		return err
+	}
+
+	// Convert to absolute path
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return fmt.Errorf("error resolving absolute path: %w", err)
+	}
+
+	// Create FFmpegInfo instance and detect FFmpeg
+	ffmpegInfo, err := ffmpeg.DetectFFmpeg()
+	if err != nil {
+		return fmt.Errorf("failed to detect FFmpeg: %w", err)
+	}
+
+	// Print FFmpeg info
+	valueStyle.Printf("🔧 Using FFmpeg at %s\n", ffmpegInfo.Path)
+	valueStyle.Printf("🔖 FFmpeg version: %s\n", ffmpegInfo.Version)
+
+	// Create a prober
+	prober, err := ffmpeg.NewProber(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create prober: %w", err)
+	}
+
+	// HLS and DASH packages are analyzed rendition by rendition
+	if ffmpeg.IsManifest(absPath) {
+		return analyzeManifest(absPath, outputDir, ffmpegInfo, prober)
+	}
+
+	if err := analyzeFile(absPath, outputDir, ffmpegInfo, prober, options); err != nil {
+		return err
+	}
+
+	successStyle.Printf("\n✅ Analysis complete! All reports saved to %s\n", outputDir)
+
+	return nil
+}
+
+// analyzeFile runs the analyses selected by the options on a video file and saves the reports
+// to the output directory, which is emptied first. When the options name a result store, the
+// analysis is recorded in it. When the options name a delivery spec or profile, the file is also
+// checked against it and an error is returned when a rule fails.
+func analyzeFile(absPath string, outputDir string, ffmpegInfo *ffmpeg.FFmpegInfo, prober *ffmpeg.Prober, options *analysisOptions) error {
+	infoStyle := color.New(color.FgCyan)
+	successStyle := color.New(color.FgGreen)
+	errorStyle := color.New(color.FgRed)
+
+	var loudnessTarget *ffmpeg.LoudnessTarget
+	if options.Loudness != "" {
+		target, err := ffmpeg.LookupLoudnessTarget(options.Loudness)
+		if err != nil {
+			return err
+		}
+		loudnessTarget = &target
+	}
+
+	// The spec rules judge the loudness measurements, whatever the target
+	if options.spec != nil && options.spec.Requires(ffmpeg.SpecAnalysisLoudness) && loudnessTarget == nil {
+		target, err := ffmpeg.LookupLoudnessTarget("ebu-r128")
+		if err != nil {
+			return err
+		}
+		loudnessTarget = &target
+	}
+
+	// Get file info
+	containerInfo, err := prober.GetExtendedContainerInfo(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to analyze file: %w", err)
+	}
+
+	printSimpleContainerSummary(containerInfo, prober)
+
+	if err := prepareOutputDir(outputDir); err != nil {
+		return err
+	}
+
+	// Save the ISO-BMFF box hierarchy for MP4/MOV containers
+	if containerInfo.MP4Structure != nil {
+		if err := saveBoxTree(containerInfo.MP4Structure, outputDir); err != nil {
+			return fmt.Errorf("error saving box tree: %w", err)
+		}
+	}
+
+	// Decode every stream first when requested, so damage is known before the other analyses
+	if options.Integrity {
+		if err := saveIntegrityCSV(absPath, outputDir, ffmpegInfo, containerInfo); err != nil {
+			return fmt.Errorf("error saving integrity CSV: %w", err)
+		}
+	}
+
+	// Hash every decoded frame when requested
+	if options.FrameHash {
+		if err := saveFrameHashCSV(absPath, outputDir, ffmpegInfo, containerInfo); err != nil {
+			return fmt.Errorf("error saving framehash CSV: %w", err)
+		}
+	}
+
+	// Create a bitrate analyzer
+	bitrateAnalyzer, err := ffmpeg.NewBitrateAnalyzer(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create bitrate analyzer: %w", err)
+	}
+
+	// Find the scene cuts first, so the bitrate analysis can fill in the scene statistics
+	var frameObservers []func(ffmpeg.FrameBitrateInfo)
+	if options.Scenes && len(containerInfo.VideoStreams) > 0 {
+		sceneAnalyzer, err := ffmpeg.NewSceneAnalyzer(ffmpegInfo)
+		if err != nil {
+			return fmt.Errorf("failed to create scene analyzer: %w", err)
+		}
+		if options.SceneThreshold != 0 {
+			sceneAnalyzer.Threshold = options.SceneThreshold
+		}
+		detectScenes(absPath, sceneAnalyzer, containerInfo)
+		if containerInfo.Scenes != nil {
+			frameObservers = append(frameObservers, containerInfo.Scenes.AddFrame)
+		}
+	}
+
+	// Record the keyframes during the bitrate analysis to check chapters and scene cuts against them
+	var keyframes *ffmpeg.KeyframeReport
+	if len(containerInfo.VideoStreams) > 0 && (len(containerInfo.ChapterStreams) > 0 || containerInfo.Scenes != nil) {
+		keyframes = ffmpeg.NewKeyframeReport(containerInfo.VideoStreams[0].FrameRate)
+		frameObservers = append(frameObservers, keyframes.AddFrame)
+	}
+
+	// Measure the peak bitrate and the frame rate mode for the delivery spec and the result store
+	var bitrateStats *ffmpeg.BitrateStats
+	if (options.spec != nil || options.Store != "") && len(containerInfo.VideoStreams) > 0 {
+		bitrateStats = ffmpeg.NewBitrateStats(getFrameRate(containerInfo))
+		frameObservers = append(frameObservers, bitrateStats.AddFrame)
+	}
+
+	// Keep the frames for the result store when requested
+	var frames []ffmpeg.FrameBitrateInfo
+	if options.Store != "" && options.StoreFrames {
+		frameObservers = append(frameObservers, func(frame ffmpeg.FrameBitrateInfo) {
+			frames = append(frames, frame)
+		})
+	}
+
+	// Check the timestamps of the frames during the bitrate analysis
+	var timestamps *ffmpeg.TimestampReport
+	if len(containerInfo.VideoStreams) > 0 {
+		timestamps = ffmpeg.NewTimestampReport(containerInfo.VideoStreams[0].FrameRate)
+		frameObservers = append(frameObservers, timestamps.AddFrame)
+	}
+
+	// Generate bitrate CSV report before the media info reports, so the measured
+	// totals can be checked against the values declared by the container
+	summary, err := saveBitrateCSV(absPath, outputDir, bitrateAnalyzer, options.ShowFrames, frameObservers...)
+	if err != nil {
+		return fmt.Errorf("error saving bitrate CSV: %w", err)
+	}
+
+	if bitrateStats != nil {
+		bitrateStats.Finish()
+		containerInfo.Bitrate = bitrateStats
+	}
+
+	// Complete the scene statistics with the QP of every frame
+	if containerInfo.Scenes != nil {
+		if err := saveScenesCSV(absPath, outputDir, containerInfo.Scenes); err != nil {
+			return fmt.Errorf("error saving scenes CSV: %w", err)
+		}
+	}
+
+	// Check that chapter starts and scene cuts land on keyframes
+	if keyframes != nil {
+		keyframes.Check(containerInfo.ChapterStreams, containerInfo.Scenes)
+		containerInfo.Keyframes = keyframes
+		if err := saveKeyframesCSV(outputDir, keyframes); err != nil {
+			return fmt.Errorf("error saving keyframes CSV: %w", err)
+		}
+	}
+
+	if timestamps != nil {
+		containerInfo.Timestamps = timestamps
+		if err := saveTimestampsCSV(outputDir, timestamps); err != nil {
+			return fmt.Errorf("error saving timestamps CSV: %w", err)
+		}
+	}
+
+	// Compare Matroska statistics tags with the measured video stream
+	if containerInfo.MatroskaStructure != nil {
+		containerInfo.MatroskaStructure.CrossCheckStatistics(summary.FrameCount, summary.TotalBits, getVideoDuration(containerInfo))
+	}
+
+	// Analyze subtitle events, the summary is added to the media info reports
+	if len(containerInfo.SubtitleStreams) > 0 {
+		if err := saveSubtitleCSV(absPath, outputDir, ffmpegInfo, containerInfo); err != nil {
+			return fmt.Errorf("error saving subtitle CSV: %w", err)
+		}
+	}
+
+	// Detect the scan type of the video streams when requested
+	if options.ScanType && len(containerInfo.VideoStreams) > 0 {
+		if err := detectScanType(absPath, ffmpegInfo, containerInfo); err != nil {
+			return fmt.Errorf("error detecting scan type: %w", err)
+		}
+	}
+
+	// Detect the active picture area of the video streams when requested
+	if options.Crop && len(containerInfo.VideoStreams) > 0 {
+		if err := detectCrop(absPath, ffmpegInfo, containerInfo); err != nil {
+			return fmt.Errorf("error detecting crop: %w", err)
+		}
+	}
+
+	// Find duplicate and dropped frames when requested
+	if options.Cadence && len(containerInfo.VideoStreams) > 0 {
+		if err := saveCadenceCSV(absPath, outputDir, ffmpegInfo, containerInfo); err != nil {
+			return fmt.Errorf("error saving cadence CSV: %w", err)
+		}
+	}
+
+	// Detect black and frozen video when requested
+	if options.BlackFreeze && len(containerInfo.VideoStreams) > 0 {
+		analyzer, err := ffmpeg.NewBlackFreezeAnalyzer(ffmpegInfo)
+		if err != nil {
+			return fmt.Errorf("failed to create black and freeze analyzer: %w", err)
+		}
+		if options.BlackDuration != 0 {
+			analyzer.BlackMinDuration = options.BlackDuration
+		}
+		if options.BlackThreshold != 0 {
+			analyzer.PictureThreshold = options.BlackThreshold
+		}
+		if options.FreezeDuration != 0 {
+			analyzer.FreezeMinDuration = options.FreezeDuration
+		}
+		if options.FreezeNoise != 0 {
+			analyzer.FreezeNoise = options.FreezeNoise
+		}
+		analyzer.IgnoreEdges = options.IgnoreEdgeBlack
+
+		if err := saveEventsCSV(absPath, outputDir, analyzer, containerInfo); err != nil {
+			return fmt.Errorf("error saving events CSV: %w", err)
+		}
+	}
+
+	// Score blocking, blur and banding artifacts when requested
+	if options.Artifacts && len(containerInfo.VideoStreams) > 0 {
+		if err := saveArtifactsCSV(absPath, outputDir, ffmpegInfo, containerInfo); err != nil {
+			return fmt.Errorf("error saving artifacts CSV: %w", err)
+		}
+	}
+
+	// Compare audio and subtitle timing with the video stream
+	syncThreshold := options.SyncThreshold
+	if syncThreshold == 0 {
+		syncThreshold = ffmpeg.DefaultSyncThreshold * 1000
+	}
+	if len(containerInfo.VideoStreams) > 0 && len(containerInfo.AudioStreams)+len(containerInfo.SubtitleStreams) > 0 {
+		if err := checkSync(absPath, ffmpegInfo, containerInfo, syncThreshold/1000, options.SyncContent); err != nil {
+			return fmt.Errorf("error checking A/V sync: %w", err)
+		}
+	}
+
+	// Measure loudness of the audio streams when a target was requested
+	if loudnessTarget != nil && len(containerInfo.AudioStreams) > 0 {
+		if err := saveLoudnessCSV(absPath, outputDir, ffmpegInfo, containerInfo, *loudnessTarget); err != nil {
+			return fmt.Errorf("error saving loudness CSV: %w", err)
+		}
+	}
+
+	// Detect audio defects when requested
+	if options.AudioQC && len(containerInfo.AudioStreams) > 0 {
+		if err := saveAudioQCCSV(absPath, outputDir, ffmpegInfo, containerInfo); err != nil {
+			return fmt.Errorf("error saving audio QC CSV: %w", err)
+		}
+	}
+
+	// Save detailed media information to a text file in the output directory
+	if err := saveMediaInfoText(containerInfo, outputDir, prober); err != nil {
+		return fmt.Errorf("error saving media info: %w", err)
+	}
+
+	// Save BBCode formatted media information
+	if err := saveMediaInfoBBCode(containerInfo, outputDir, prober); err != nil {
+		return fmt.Errorf("error saving BBCode media info: %w", err)
+	}
+
+	// Record the analysis in the result store, before the spec check so failed files are recorded too
+	if options.Store != "" {
+		if err := saveStoredRun(absPath, containerInfo, frames, options.Store); err != nil {
+			return err
+		}
+	}
+
+	// Check the file against the delivery spec of the options
+	if options.spec != nil {
+		result := options.spec.Evaluate(containerInfo)
+		infoStyle.Printf("📋 Checking %s against %s\n", filepath.Base(absPath), options.spec.Name)
+		if err := saveCheckReport(result, absPath, outputDir); err != nil {
+			return fmt.Errorf("error saving check report: %w", err)
+		}
+		if err := saveJUnitReport(result, absPath, filepath.Join(outputDir, "junit.xml")); err != nil {
+			return fmt.Errorf("error saving JUnit report: %w", err)
+		}
+		if !result.Passed {
+			errorStyle.Printf("❌ %s: %d of %d rules failed\n", options.spec.Name, result.Failures(), len(result.Rules))
+			return fmt.Errorf("%d of %d rules of %s failed", result.Failures(), len(result.Rules), options.spec.Name)
+		}
+		successStyle.Printf("✅ %s complies with %s\n", filepath.Base(absPath), options.spec.Name)
+	}
+
+	return nil
+}
+
+// analyzeManifest analyzes a local HLS or DASH package as a unit. The segments of every
+// rendition are concatenated into a temporary file that is probed and measured with the
+// bitrate analyzer, then the IDR frames are checked across renditions.
+func analyzeManifest(manifestPath string, outputDir string, ffmpegInfo *ffmpeg.FFmpegInfo, prober *ffmpeg.Prober) error {
+	infoStyle := color.New(color.FgCyan)
+	warningStyle := color.New(color.FgYellow)
+	successStyle := color.New(color.FgGreen)
+
+	manifest, err := ffmpeg.NewManifestInspector().Inspect(manifestPath)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+	infoStyle.Printf("📺 %s manifest with %d renditions\n", manifest.Format, len(manifest.Renditions))
+
+	if err := prepareOutputDir(outputDir); err != nil {
+		return err
+	}
+
+	bitrateAnalyzer, err := ffmpeg.NewBitrateAnalyzer(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create bitrate analyzer: %w", err)
+	}
+
+	for i := range manifest.Renditions {
+		rendition := &manifest.Renditions[i]
+		if rendition.MissingSegments == len(rendition.Segments) {
+			warningStyle.Printf("⚠️ Skipping rendition %s: no segment found on disk\n", rendition.ID)
+			continue
+		}
+		infoStyle.Printf("🔍 Analyzing rendition %s (%d segments)\n", rendition.ID, len(rendition.Segments))
+		if err := probeRendition(rendition, prober, bitrateAnalyzer); err != nil {
+			warningStyle.Printf("⚠️ Skipping rendition %s: %v\n", rendition.ID, err)
+		}
+	}
+	manifest.CheckAlignment()
+
+	if err := saveLadderCSV(manifest, outputDir); err != nil {
+		return fmt.Errorf("error saving ladder CSV: %w", err)
+	}
+	if err := saveLadderReport(manifest, outputDir); err != nil {
+		return fmt.Errorf("error saving ladder report: %w", err)
+	}
+
+	successStyle.Printf("\n✅ Analysis complete! All reports saved to %s\n", outputDir)
+	return nil
+}
+
+// probeRendition concatenates the segments of a rendition into a temporary file, probes it
+// and adds the frames of its video stream to the rendition.
+func probeRendition(rendition *ffmpeg.Rendition, prober *ffmpeg.Prober, analyzer *ffmpeg.BitrateAnalyzer) error {
+	// Segments following an initialization segment are fragmented MP4, others keep their own format
+	extension := ".mp4"
+	if rendition.InitSegment == nil && len(rendition.Segments) > 0 {
+		extension = filepath.Ext(rendition.Segments[0].Path)
+	}
+	file, err := os.CreateTemp("", "framehound-rendition-*"+extension)
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = rendition.WriteTo(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error concatenating segments: %w", err)
+	}
+
+	info, err := prober.GetExtendedContainerInfo(file.Name())
+	if err != nil {
+		return fmt.Errorf("error probing segments: %w", err)
+	}
+	rendition.Info = info
+	if len(info.VideoStreams) == 0 {
+		rendition.Type = ffmpeg.RenditionAudio
+		return nil
+	}
+	rendition.Type = ffmpeg.RenditionVideo
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	resultCh := make(chan ffmpeg.FrameBitrateInfo, 100)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- analyzer.Analyze(ctx, file.Name(), resultCh)
+		close(resultCh)
+	}()
+	for frame := range resultCh {
+		rendition.AddFrame(frame)
+	}
+	if err := <-errCh; err != nil {
+		return fmt.Errorf("error measuring frames: %w", err)
+	}
+	return nil
+}
+
+// checkCommand checks a file against the rules of a delivery spec or a built-in profile, prints
+// the outcome of every rule and writes a text report and a JUnit report for CI pipelines. A spec
+// given with a profile overrides its rules. The frames and the loudness are only measured when a
+// rule needs them. It fails when any rule fails.
+func checkCommand(c *cli.Context) error {
+	infoStyle := color.New(color.FgCyan)
+	successStyle := color.New(color.FgGreen)
+	warningStyle := color.New(color.FgYellow)
+	errorStyle := color.New(color.FgRed)
+
+	if c.NArg() != 1 || (c.String("spec") == "" && c.String("profile") == "") {
+		errorStyle.Printf("❌ Error: expected a spec or a profile and a file to check\n\n")
+		fmt.Printf("Usage: %s check [--profile NAME[@VERSION]] [--spec SPEC_FILE] VIDEO_FILE\n", c.App.Name)
+		return fmt.Errorf("expected a spec or a profile and a file to check")
+	}
+
+	spec, err := loadCheckSpec(c.String("spec"), c.String("profile"))
+	if err != nil {
+		return err
+	}
+	filePath, err := filepath.Abs(c.Args().First())
+	if err != nil {
+		return fmt.Errorf("error resolving absolute path: %w", err)
+	}
+
+	ffmpegInfo, err := ffmpeg.DetectFFmpeg()
+	if err != nil {
+		return fmt.Errorf("failed to detect FFmpeg: %w", err)
+	}
+	prober, err := ffmpeg.NewProber(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create prober: %w", err)
+	}
+	info, err := prober.GetExtendedContainerInfo(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to analyze file: %w", err)
+	}
+
+	if spec.Requires(ffmpeg.SpecAnalysisBitrate) && len(info.VideoStreams) > 0 {
+		bitrateAnalyzer, err := ffmpeg.NewBitrateAnalyzer(ffmpegInfo)
+		if err != nil {
+			return fmt.Errorf("failed to create bitrate analyzer: %w", err)
+		}
+		infoStyle.Printf("📊 Measuring the frames of %s\n", filepath.Base(filePath))
+		if err := measureBitrateStats(filePath, bitrateAnalyzer, info); err != nil {
+			return err
+		}
+	}
+
+	if spec.Requires(ffmpeg.SpecAnalysisLoudness) {
+		// The measurements do not depend on the target, the spec rules judge them
+		target, err := ffmpeg.LookupLoudnessTarget("ebu-r128")
+		if err != nil {
+			return err
+		}
+		loudnessAnalyzer, err := ffmpeg.NewLoudnessAnalyzer(ffmpegInfo, target)
+		if err != nil {
+			return fmt.Errorf("failed to create loudness analyzer: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+		for i := range info.AudioStreams {
+			infoStyle.Printf("🔊 Measuring loudness of audio stream #%d\n", i)
+			report, err := loudnessAnalyzer.Analyze(ctx, filePath, info.AudioStreams[i])
+			if err != nil {
+				warningStyle.Printf("⚠️ Skipping audio stream #%d: %v\n", i, err)
+				continue
+			}
+			info.AudioStreams[i].Loudness = report
+		}
+	}
+
+	result := spec.Evaluate(info)
+	against := spec.Name
+	if spec.Profile != "" {
+		against += " (" + spec.Profile + ")"
+	}
+	infoStyle.Printf("📋 Checking %s against %s\n", filepath.Base(filePath), against)
+	for _, rule := range result.Rules {
+		if rule.Passed {
+			successStyle.Printf("✅ %s: %s\n", rule.Name, strings.Join(rule.Actual, ", "))
+		} else {
+			errorStyle.Printf("❌ %s: %s\n", rule.Name, rule.Message)
+		}
+	}
+
+	outputDir := c.String("dir")
+	if err := prepareOutputDir(outputDir); err != nil {
+		return err
+	}
+	if err := saveCheckReport(result, filePath, outputDir); err != nil {
+		return fmt.Errorf("error saving check report: %w", err)
+	}
+	junitPath := c.String("junit")
+	if junitPath == "" {
+		junitPath = filepath.Join(outputDir, "junit.xml")
+	}
+	if err := saveJUnitReport(result, filePath, junitPath); err != nil {
+		return fmt.Errorf("error saving JUnit report: %w", err)
+	}
+	if !result.Passed {
+		return fmt.Errorf("%d of %d rules of %s failed", result.Failures(), len(result.Rules), spec.Name)
+	}
+	successStyle.Printf("✅ %s complies with %s\n", filepath.Base(filePath), spec.Name)
+	return nil
+}
+
+// diffCommand compares several encodes of the same source. Every file is measured frame by
+// frame, the files are aligned on their presentation times and compared over the scenes of the
+// first file, the reference. The report, the aligned series and the graphs are saved to the
+// output directory.
+func diffCommand(c *cli.Context) error {
+	infoStyle := color.New(color.FgCyan)
+	warningStyle := color.New(color.FgYellow)
+	errorStyle := color.New(color.FgRed)
+
+	if c.NArg() < 2 {
+		errorStyle.Printf("❌ Error: expected at least two files to compare\n\n")
+		fmt.Printf("Usage: %s diff FILE_A FILE_B [FILE_C...]\n", c.App.Name)
+		return fmt.Errorf("expected at least two files to compare")
+	}
+
+	ffmpegInfo, err := ffmpeg.DetectFFmpeg()
+	if err != nil {
+		return fmt.Errorf("failed to detect FFmpeg: %w", err)
+	}
+	prober, err := ffmpeg.NewProber(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create prober: %w", err)
+	}
+	bitrateAnalyzer, err := ffmpeg.NewBitrateAnalyzer(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create bitrate analyzer: %w", err)
+	}
+
+	paths := make([]string, c.NArg())
+	infos := make([]*ffmpeg.ContainerInfo, c.NArg())
+	for i := range paths {
+		if paths[i], err = filepath.Abs(c.Args().Get(i)); err != nil {
+			return fmt.Errorf("error resolving absolute path: %w", err)
+		}
+		if infos[i], err = prober.GetExtendedContainerInfo(paths[i]); err != nil {
+			return fmt.Errorf("failed to analyze %s: %w", paths[i], err)
+		}
+		if len(infos[i].VideoStreams) == 0 {
+			return fmt.Errorf("%s has no video stream", paths[i])
+		}
+	}
+
+	outputDir := c.String("dir")
+	if err := prepareOutputDir(outputDir); err != nil {
+		return err
+	}
+
+	comparison := ffmpeg.NewEncodeComparison(paths, infos)
+	for i, path := range paths {
+		infoStyle.Printf("📊 Measuring the frames of %s\n", filepath.Base(path))
+		if err := measureComparedFile(comparison, i, path, bitrateAnalyzer); err != nil {
+			return err
+		}
+	}
+
+	// The files are compared over the scenes of the reference
+	sceneAnalyzer, err := ffmpeg.NewSceneAnalyzer(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create scene analyzer: %w", err)
+	}
+	detectScenes(paths[0], sceneAnalyzer, infos[0])
+	if infos[0].Scenes != nil {
+		comparison.SetScenes(infos[0].Scenes.Scenes)
+	} else {
+		warningStyle.Println("⚠️ Comparing 10 second segments instead of scenes")
+	}
+	comparison.Finish()
+
+	if err := saveDiffReport(comparison, outputDir); err != nil {
+		return fmt.Errorf("error saving diff report: %w", err)
+	}
+	if err := saveDiffCSV(comparison, outputDir); err != nil {
+		return fmt.Errorf("error saving diff CSV: %w", err)
+	}
+	return saveDiffGraphs(comparison, outputDir)
+}
+
+// dedupeCommand fingerprints every video file of a directory and lists the clusters of files
+// with matching content, whatever their codec, resolution or trims. Fingerprints are kept in
+// an index so only new and changed files are decoded on the next run.
+func dedupeCommand(c *cli.Context) error {
+	infoStyle := color.New(color.FgCyan)
+	warningStyle := color.New(color.FgYellow)
+	successStyle := color.New(color.FgGreen)
+	errorStyle := color.New(color.FgRed)
+
+	if c.NArg() != 1 {
+		errorStyle.Printf("❌ Error: expected a directory to scan\n\n")
+		fmt.Printf("Usage: %s dedupe DIR\n", c.App.Name)
+		return fmt.Errorf("expected a directory to scan")
+	}
+	dir, err := filepath.Abs(c.Args().First())
+	if err != nil {
+		return fmt.Errorf("error resolving absolute path: %w", err)
+	}
+
+	ffmpegInfo, err := ffmpeg.DetectFFmpeg()
+	if err != nil {
+		return fmt.Errorf("failed to detect FFmpeg: %w", err)
+	}
+	prober, err := ffmpeg.NewProber(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create prober: %w", err)
+	}
+	analyzer, err := ffmpeg.NewFingerprintAnalyzer(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create fingerprint analyzer: %w", err)
+	}
+
+	indexPath := c.String("index")
+	if indexPath == "" {
+		indexPath = filepath.Join(dir, fingerprintIndexName)
+	}
+	index, err := ffmpeg.LoadFingerprintIndex(indexPath)
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	err = filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && videoExtensions[strings.ToLower(filepath.Ext(path))] {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error scanning directory: %w", err)
+	}
+
+	// Files that disappeared since the last run are dropped from the index
+	updated := ffmpeg.NewFingerprintIndex()
+	var fingerprints []*ffmpeg.Fingerprint
+	for _, path := range paths {
+		fileInfo, err := os.Stat(path)
+		if err != nil {
+			warningStyle.Printf("⚠️ Skipping %s: %v\n", path, err)
+			continue
+		}
+		fingerprint := index.Lookup(path, fileInfo)
+		if fingerprint == nil {
+			infoStyle.Printf("🧬 Fingerprinting %s\n", filepath.Base(path))
+			if fingerprint, err = fingerprintFile(path, prober, analyzer, c.Bool("qp")); err != nil {
+				warningStyle.Printf("⚠️ Skipping %s: %v\n", path, err)
+				continue
+			}
+		}
+		updated.Set(fingerprint)
+		fingerprints = append(fingerprints, fingerprint)
+
+		// Save as we go so an interrupted scan keeps its progress
+		if err := updated.Save(indexPath); err != nil {
+			return err
+		}
+	}
+	if err := updated.Save(indexPath); err != nil {
+		return err
+	}
+
+	clusters := ffmpeg.ClusterFingerprints(fingerprints)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeDedupeReport(w, clusters, len(fingerprints), dir)
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error writing dedupe report: %w", err)
+	}
+
+	successStyle.Printf("✅ Fingerprint index saved to %s\n", indexPath)
+	return nil
+}
+
+// historyCommand lists the stored analyses of a file. The file is looked up by content when it
+// exists, so renamed and moved copies share their history, and by its last analyzed path
+// otherwise. The per-frame series of the latest analysis can be exported as CSV.
+func historyCommand(c *cli.Context) error {
+	warningStyle := color.New(color.FgYellow)
+	successStyle := color.New(color.FgGreen)
+	errorStyle := color.New(color.FgRed)
+
+	if c.NArg() != 1 {
+		errorStyle.Printf("❌ Error: expected a file\n\n")
+		fmt.Printf("Usage: %s history [options] FILE\n", c.App.Name)
+		return fmt.Errorf("expected a file")
+	}
+	absPath, err := filepath.Abs(c.Args().First())
+	if err != nil {
+		return fmt.Errorf("error resolving absolute path: %w", err)
+	}
+
+	store, err := openExistingResultStore(c.String("store"))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var hash string
+	if _, statErr := os.Stat(absPath); statErr == nil {
+		hash, err = ffmpeg.FileSHA256(absPath)
+	} else {
+		hash, err = store.LookupPath(absPath)
+	}
+	if err != nil {
+		return err
+	}
+	runs, err := store.History(hash)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		return fmt.Errorf("no analysis of %s in the result store", absPath)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeHistoryReport(w, runs, absPath)
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error writing history report: %w", err)
+	}
+
+	framesPath := c.String("frames")
+	if framesPath == "" {
+		return nil
+	}
+	frames, err := store.Frames(runs[len(runs)-1])
+	if err != nil {
+		return err
+	}
+	if len(frames) == 0 {
+		warningStyle.Printf("⚠️ The latest analysis has no stored frames, analyze the file with --store-frames\n")
+		return nil
+	}
+	file, err := os.Create(framesPath)
+	if err != nil {
+		return fmt.Errorf("error creating frames CSV: %w", err)
+	}
+	defer file.Close()
+	if err := writeJobCSV(file, frames, nil); err != nil {
+		return err
+	}
+	successStyle.Printf("✅ Frames of the latest analysis saved to %s\n", framesPath)
+	return nil
+}
+
+// queryCommand lists the stored files whose latest analysis matches a query, or every matching
+// analysis with --all. The query is made of the arguments joined by spaces, so it can be quoted
+// or not.
+func queryCommand(c *cli.Context) error {
+	errorStyle := color.New(color.FgRed)
+
+	if c.NArg() < 1 {
+		errorStyle.Printf("❌ Error: expected a query\n\n")
+		fmt.Printf("Usage: %s query [options] QUERY\n", c.App.Name)
+		fmt.Printf("Fields: %s, or any field of the check command\n", strings.Join(ffmpeg.QueryFieldNames(), ", "))
+		return fmt.Errorf("expected a query")
+	}
+	query, err := ffmpeg.ParseQuery(strings.Join(c.Args().Slice(), " "))
+	if err != nil {
+		return err
+	}
+
+	store, err := openExistingResultStore(c.String("store"))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	runs, err := store.Latest()
+	if err != nil {
+		return err
+	}
+	if c.Bool("all") {
+		var history []*ffmpeg.StoredRun
+		for _, latest := range runs {
+			fileRuns, err := store.History(latest.Hash)
+			if err != nil {
+				return err
+			}
+			history = append(history, fileRuns...)
+		}
+		runs = history
+	}
+
+	var matches []*ffmpeg.StoredRun
+	for _, run := range runs {
+		if query.Match(run.Info) {
+			matches = append(matches, run)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeQueryReport(w, query, matches, len(runs))
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error writing query report: %w", err)
+	}
+	return nil
+}
+
+// serveCommand runs framehound as an HTTP service. Clients queue analyses by path or by
+// upload, poll their status and progress, stream the measured frames as server-sent events
+// and fetch the results as JSON, CSV, text or BBCode. A bounded queue feeds a fixed number of
+// workers; a full queue rejects new jobs with 503 Service Unavailable.
+func serveCommand(c *cli.Context) error {
+	infoStyle := color.New(color.FgCyan)
+
+	workers, queueSize := c.Int("workers"), c.Int("queue")
+	if workers < 1 || queueSize < 1 {
+		return fmt.Errorf("workers and queue size must be at least 1")
+	}
+
+	ffmpegInfo, err := ffmpeg.DetectFFmpeg()
+	if err != nil {
+		return fmt.Errorf("failed to detect FFmpeg: %w", err)
+	}
+	prober, err := ffmpeg.NewProber(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create prober: %w", err)
+	}
+
+	dir, err := filepath.Abs(c.String("dir"))
+	if err != nil {
+		return fmt.Errorf("error resolving absolute path: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating job directory: %w", err)
+	}
+
+	// The quality analyzers log every frame, keep the console readable
+	log.SetOutput(io.Discard)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := newJobServer(ffmpegInfo, prober, dir, queueSize)
+	server.retention = c.Duration("retention")
+	server.maxUpload = c.Int64("max-upload") << 20
+	for _, root := range c.StringSlice("root") {
+		resolved, err := filepath.Abs(root)
+		if err == nil {
+			resolved, err = filepath.EvalSymlinks(resolved)
+		}
+		if err != nil {
+			return fmt.Errorf("error resolving root directory: %w", err)
+		}
+		if fileInfo, err := os.Stat(resolved); err != nil || !fileInfo.IsDir() {
+			return fmt.Errorf("%s is not a directory", root)
+		}
+		server.roots = append(server.roots, resolved)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.work(ctx)
+		}()
+	}
+
+	// Request contexts end with the server, so the event streams close on shutdown
+	httpServer := &http.Server{
+		Addr:              c.String("listen"),
+		Handler:           server.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	// Finished jobs are removed once their retention time is over
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				server.prune(now)
+			}
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	infoStyle.Printf("🌐 Serving the HTTP API on %s with %d workers, jobs in %s\n", httpServer.Addr, workers, dir)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error serving HTTP API: %w", err)
+	}
+	wg.Wait()
+	return nil
+}
+
+// verifyCommand checks that two files decode to identical frames. The files may use different
+// containers: only the decoded pictures and samples are compared, stream by stream, and the
+// first differing frame of every stream is reported. An error is returned when the files differ.
+func verifyCommand(c *cli.Context) error {
+	infoStyle := color.New(color.FgCyan)
+	successStyle := color.New(color.FgGreen)
+	errorStyle := color.New(color.FgRed)
+
+	if c.NArg() != 2 {
+		errorStyle.Printf("❌ Error: expected two files to compare\n\n")
+		fmt.Printf("Usage: %s verify FILE_A FILE_B\n", c.App.Name)
+		return fmt.Errorf("expected two files to compare")
+	}
+
+	ffmpegInfo, err := ffmpeg.DetectFFmpeg()
+	if err != nil {
+		return fmt.Errorf("failed to detect FFmpeg: %w", err)
+	}
+	prober, err := ffmpeg.NewProber(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create prober: %w", err)
+	}
+	analyzer, err := ffmpeg.NewFrameHashAnalyzer(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create framehash analyzer: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	var hashes [2]*ffmpeg.FileHash
+	var frames [2][]ffmpeg.FrameHash
+	for i := range hashes {
+		path, err := filepath.Abs(c.Args().Get(i))
+		if err != nil {
+			return fmt.Errorf("error resolving absolute path: %w", err)
+		}
+		info, err := prober.GetExtendedContainerInfo(path)
+		if err != nil {
+			return fmt.Errorf("failed to analyze file: %w", err)
+		}
+		infoStyle.Printf("🔏 Computing frame checksums of %s\n", filepath.Base(path))
+
+		frameCh := make(chan ffmpeg.FrameHash, 64)
+		errCh := make(chan error, 1)
+		go func() {
+			var analyzeErr error
+			hashes[i], analyzeErr = analyzer.Analyze(ctx, path, info, frameCh)
+			errCh <- analyzeErr
+		}()
+		for frame := range frameCh {
+			frames[i] = append(frames[i], frame)
+		}
+		if err := <-errCh; err != nil {
+			return fmt.Errorf("error hashing %s: %w", path, err)
+		}
+	}
+
+	if hashes[0].SHA256 == hashes[1].SHA256 {
+		successStyle.Printf("✅ Files are byte-identical (SHA-256 %s)\n", hashes[0].SHA256)
+		return nil
+	}
+
+	comparison := ffmpeg.CompareFrameHashes(hashes[0], hashes[1], frames[0], frames[1])
+	for _, stream := range comparison.Streams {
+		if stream.Identical {
+			successStyle.Printf("✅ %s\n", formatHashComparison(stream))
+		} else {
+			errorStyle.Printf("❌ %s\n", formatHashComparison(stream))
+		}
+	}
+	if !comparison.Identical {
+		return fmt.Errorf("files decode to different content")
+	}
+	successStyle.Printf("✅ Files decode to identical content (content hash %s)\n", hashes[0].ContentHash)
+	return nil
+}
+
+// watchCommand watches a directory for new video files. A file is analyzed once its size and
+// modification time have not changed for the settle duration and its writer released it, with
+// the analyses of an analysis profile. The reports go to the output tree and the file is moved to
+// the done or the failed directory. The queue is saved in the watched directory, so a restarted
+// watcher resumes interrupted analyses.
+func watchCommand(c *cli.Context) error {
+	infoStyle := color.New(color.FgCyan)
+	warningStyle := color.New(color.FgYellow)
+	errorStyle := color.New(color.FgRed)
+
+	if c.NArg() != 1 {
+		errorStyle.Printf("❌ Error: expected a directory to watch\n\n")
+		fmt.Printf("Usage: %s watch [options] DIR\n", c.App.Name)
+		return fmt.Errorf("expected a directory to watch")
+	}
+	dir, err := filepath.Abs(c.Args().First())
+	if err != nil {
+		return fmt.Errorf("error resolving absolute path: %w", err)
+	}
+	if fileInfo, err := os.Stat(dir); err != nil || !fileInfo.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if c.Duration("interval") <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+
+	options := &analysisOptions{}
+	if path := c.String("analysis"); path != "" {
+		if options, err = loadAnalysisOptions(path); err != nil {
+			return err
+		}
+	}
+	if store := c.String("store"); store != "" {
+		options.Store = store
+	}
+	if err := resolveAnalysisOptions(options); err != nil {
+		return err
+	}
+
+	dirs := watchDirs{
+		output: c.String("output"),
+		done:   c.String("done"),
+		failed: c.String("failed"),
+	}
+	if dirs.done == "" {
+		dirs.done = filepath.Join(dir, "done")
+	}
+	if dirs.failed == "" {
+		dirs.failed = filepath.Join(dir, "failed")
+	}
+	for _, target := range []*string{&dirs.output, &dirs.done, &dirs.failed} {
+		if *target, err = filepath.Abs(*target); err != nil {
+			return fmt.Errorf("error resolving absolute path: %w", err)
+		}
+		if err := os.MkdirAll(*target, 0755); err != nil {
+			return fmt.Errorf("error creating directory: %w", err)
+		}
+	}
+
+	ffmpegInfo, err := ffmpeg.DetectFFmpeg()
+	if err != nil {
+		return fmt.Errorf("failed to detect FFmpeg: %w", err)
+	}
+	prober, err := ffmpeg.NewProber(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create prober: %w", err)
+	}
+
+	queuePath := filepath.Join(dir, watchQueueName)
+	queue, err := ffmpeg.LoadWatchQueue(queuePath)
+	if err != nil {
+		return err
+	}
+
+	// The first interrupt lets the current analysis finish, the second one stops at once
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+		warningStyle.Printf("\n⚠️ Stopping after the current file, interrupt again to stop now\n")
+	}()
+
+	infoStyle.Printf("👀 Watching %s, reports saved to %s\n", dir, dirs.output)
+	ticker := time.NewTicker(c.Duration("interval"))
+	defer ticker.Stop()
+	for {
+		files, err := scanWatchDir(dir, dirs.output, dirs.done, dirs.failed)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		queue.Update(files, now)
+		for _, entry := range queue.Ready(now, c.Duration("settle"), watchFileLocked) {
+			infoStyle.Printf("📥 %s is ready for analysis\n", entry.Path)
+		}
+
+		// The running file is saved first, so an interrupted analysis counts as an attempt
+		entry := queue.Next()
+		if err := queue.Save(queuePath); err != nil {
+			return err
+		}
+		if entry != nil {
+			// A file that cannot be moved stays failed in the queue, the watcher keeps going
+			if err := processWatchedFile(entry, dir, dirs, ffmpegInfo, prober, options); err != nil {
+				errorStyle.Printf("❌ %s: %v, it is skipped until it changes\n", entry.Path, err)
+				queue.Fail(entry.Path, err.Error())
+			} else {
+				queue.Remove(entry.Path)
+			}
+			if err := queue.Save(queuePath); err != nil {
+				return err
+			}
+			if ctx.Err() == nil {
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// main is the entry point of the application.
+// It parses command-line arguments, validates input, and starts the analysis.
+func main() {
+	// Override the default version printer
+	cli.VersionPrinter = versionPrinter
+
+	// Create a new CLI app
+	app := &cli.App{
+		Name:  "framehound",
+		Usage: "A tool for analyzing video frame bitrates",
+		Description: "FrameHound analyzes video files to extract frame-by-frame bitrate information " +
+			"and provides detailed metadata about media containers. Local HLS playlists (.m3u8) and " +
+			"DASH manifests (.mpd) are analyzed rendition by rendition.",
+		Authors: []*cli.Author{
+			{
+				Name: "Gian Luca Dalla Torre",
+			},
+		},
+		Commands: []*cli.Command{
+			{
+				Name:      "check",
+				Usage:     "Check a file against a built-in delivery profile or the rules of a YAML or JSON spec",
+				ArgsUsage: "VIDEO_FILE",
+				Action:    checkCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "spec",
+						Usage: "Delivery spec file (YAML, or JSON with a .json extension), overriding the profile when both are given",
+					},
+					&cli.StringFlag{
+						Name:  "profile",
+						Usage: "Built-in profile as NAME or NAME@VERSION (" + strings.Join(ffmpeg.SpecProfileNames(), ", ") + ")",
+					},
+					&cli.StringFlag{
+						Name:    "dir",
+						Aliases: []string{"d"},
+						Usage:   "Directory where to output the check report",
+						Value:   filepath.Join(".", "reports"),
+					},
+					&cli.StringFlag{
+						Name:  "junit",
+						Usage: "JUnit XML report of the rules (default DIR/junit.xml)",
+					},
+				},
+			},
+			{
+				Name:      "dedupe",
+				Usage:     "Find files with the same content, whatever their codec, resolution or trims",
+				ArgsUsage: "DIR",
+				Action:    dedupeCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "index",
+						Usage: "Fingerprint index file (default DIR/" + fingerprintIndexName + ")",
+					},
+					&cli.BoolFlag{
+						Name:  "qp",
+						Usage: "Measure the average QP of every new file to pick the best copy (decodes every frame)",
+					},
+				},
+			},
+			{
+				Name:      "diff",
+				Usage:     "Compare encodes of the same source side by side, aligned on their timestamps",
+				ArgsUsage: "FILE_A FILE_B [FILE_C...]",
+				Action:    diffCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "dir",
+						Aliases: []string{"d"},
+						Usage:   "Directory where to output the results of the comparison",
+						Value:   filepath.Join(".", "reports"),
+					},
+				},
+			},
+			{
+				Name:      "history",
+				Usage:     "List the stored analyses of a file, found by content even when renamed or moved",
+				ArgsUsage: "FILE",
+				Action:    historyCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "store",
+						Usage:   "Result store to read",
+						EnvVars: []string{storeEnvVar},
+					},
+					&cli.StringFlag{
+						Name:  "frames",
+						Usage: "Save the per-frame series of the latest analysis to a CSV file",
+					},
+				},
+			},
+			{
+				Name:      "query",
+				Usage:     "Find the stored files matching conditions such as \"codec=hevc and avg_bitrate>8M\"",
+				ArgsUsage: "QUERY",
+				Action:    queryCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "store",
+						Usage:   "Result store to read",
+						EnvVars: []string{storeEnvVar},
+					},
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "Match every stored analysis instead of the latest analysis of each file",
+					},
+				},
+			},
+			{
+				Name:   "serve",
+				Usage:  "Run an HTTP API queueing analyses and streaming their per-frame results",
+				Action: serveCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "listen",
+						Usage: "Address to listen on, use :8080 to accept connections from other hosts",
+						Value: "127.0.0.1:8080",
+					},
+					&cli.StringSliceFlag{
+						Name:  "root",
+						Usage: "Directory whose files can be queued by path, repeatable; without one only uploads are accepted",
+					},
+					&cli.Int64Flag{
+						Name:  "max-upload",
+						Usage: "Largest upload in megabytes",
+						Value: jobDefaultMaxUpload >> 20,
+					},
+					&cli.IntFlag{
+						Name:  "workers",
+						Usage: "Number of jobs analyzed at the same time",
+						Value: 2,
+					},
+					&cli.IntFlag{
+						Name:  "queue",
+						Usage: "Number of jobs waiting before new jobs are rejected",
+						Value: 16,
+					},
+					&cli.StringFlag{
+						Name:    "dir",
+						Aliases: []string{"d"},
+						Usage:   "Directory where to keep uploads and job reports",
+						Value:   filepath.Join(".", "jobs"),
+					},
+					&cli.DurationFlag{
+						Name:  "retention",
+						Usage: "Time a finished job and its reports are kept before they are removed",
+						Value: jobDefaultRetention,
+					},
+				},
+			},
+			{
+				Name:      "watch",
+				Usage:     "Analyze the video files dropped into a directory once they are fully written",
+				ArgsUsage: "DIR",
+				Action:    watchCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "analysis",
+						Usage: "Analysis profile, a YAML or JSON file with the analyses to run and an optional delivery spec or profile",
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Root of the report tree, with a directory per analyzed file",
+						Value:   filepath.Join(".", "reports"),
+					},
+					&cli.StringFlag{
+						Name:  "done",
+						Usage: "Directory where analyzed files are moved (default DIR/done)",
+					},
+					&cli.StringFlag{
+						Name:  "failed",
+						Usage: "Directory where files whose analysis failed are moved (default DIR/failed)",
+					},
+					&cli.DurationFlag{
+						Name:  "settle",
+						Usage: "Time a file must stay unchanged before it is analyzed",
+						Value: 30 * time.Second,
+					},
+					&cli.DurationFlag{
+						Name:  "interval",
+						Usage: "Time between two scans of the directory",
+						Value: 5 * time.Second,
+					},
+					&cli.StringFlag{
+						Name:    "store",
+						Usage:   "Result store to record the analyses in, overriding the analysis profile",
+						EnvVars: []string{storeEnvVar},
+					},
+				},
+			},
+			{
+				Name:      "verify",
+				Usage:     "Check that two files decode to identical frames, whatever their containers",
+				ArgsUsage: "FILE_A FILE_B",
+				Action:    verifyCommand,
+			},
+		},
+		Version:   Version,
+		Action:    analyzeCommand,
+		ArgsUsage: "VIDEO_FILE",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "dir",
+				Aliases: []string{"d"},
+				Usage:   "Directory where to output the results of analysis",
+				Value:   filepath.Join(".", "reports"),
+			},
+			&cli.BoolFlag{
+				Name:  "show-frames",
+				Usage: "Show frame count information for debugging purposes",
+			},
+			&cli.BoolFlag{
+				Name:  "integrity",
+				Usage: "Decode every stream with error detection and report corrupt or truncated data",
+			},
+			&cli.BoolFlag{
+				Name:  "framehash",
+				Usage: "Checksum every decoded frame and compute container independent content hashes",
+			},
+			&cli.BoolFlag{
+				Name:  "scan-type",
+				Usage: "Detect progressive, interlaced and telecined video with the idet filter",
+			},
+			&cli.BoolFlag{
+				Name:  "crop",
+				Usage: "Detect the active picture area, letterboxing and pillarboxing with the cropdetect filter",
+			},
+			&cli.BoolFlag{
+				Name:  "scenes",
+				Usage: "Split the video into scenes with the scdet filter and save per-scene statistics",
+			},
+			&cli.Float64Flag{
+				Name:  "scene-threshold",
+				Usage: "scdet score (0-100) at which a frame starts a new scene (default 10)",
+			},
+			&cli.BoolFlag{
+				Name:  "cadence",
+				Usage: "Find duplicate and dropped frames and measure the unique frame rate",
+			},
+			&cli.BoolFlag{
+				Name:  "black-freeze",
+				Usage: "Detect black segments, black flashes and frozen video",
+			},
+			&cli.Float64Flag{
+				Name:  "black-duration",
+				Usage: "Minimum duration in seconds of a black segment (default 1)",
+			},
+			&cli.Float64Flag{
+				Name:  "black-threshold",
+				Usage: "Ratio of black pixels a frame needs to count as black (default 0.98)",
+			},
+			&cli.Float64Flag{
+				Name:  "freeze-duration",
+				Usage: "Minimum duration in seconds of a frozen segment (default 2)",
+			},
+			&cli.Float64Flag{
+				Name:  "freeze-noise",
+				Usage: "Noise tolerance in dB between frames of a frozen segment (default -60)",
+			},
+			&cli.BoolFlag{
+				Name:  "ignore-edge-black",
+				Usage: "Ignore black at the start and at the end of the video",
+			},
+			&cli.BoolFlag{
+				Name:  "artifacts",
+				Usage: "Score blocking, blur and banding artifacts of every frame without a reference",
+			},
+			&cli.BoolFlag{
+				Name:  "audio-qc",
+				Usage: "Detect audio defects (clipping, silence, silent channels, phase, DC offset, dropouts)",
+			},
+			&cli.StringFlag{
+				Name: "loudness",
+				Usage: "Measure audio loudness against a target (" +
+					strings.Join(ffmpeg.LoudnessTargetNames(), ", ") + ")",
+			},
+			&cli.Float64Flag{
+				Name:  "sync-threshold",
+				Usage: "Largest A/V offset in milliseconds accepted by the sync check",
+				Value: ffmpeg.DefaultSyncThreshold * 1000,
+			},
+			&cli.BoolFlag{
+				Name:  "sync-content",
+				Usage: "Measure A/V sync from the content by matching audio onsets with scene cuts",
+			},
+			&cli.StringFlag{
+				Name:    "store",
+				Usage:   "Result store to record the analysis in, keyed by the SHA-256 of the file",
+				EnvVars: []string{storeEnvVar},
+			},
+			&cli.BoolFlag{
+				Name:  "store-frames",
+				Usage: "Also record the per-frame series in the result store",
+			},
+		},
+	}
+
+	// Run the application
+	if err := app.Run(os.Args); err != nil {
+		errorStyle := color.New(color.FgRed)
+		errorStyle.Fprintf(os.Stderr, "⚠️ Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// loadAnalysisOptions reads an analysis profile, YAML or JSON with a .json extension. Relative
+// spec and store paths are resolved from the directory of the profile.
+func loadAnalysisOptions(path string) (*analysisOptions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading analysis profile: %w", err)
+	}
+
+	options := &analysisOptions{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(options)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(options)
+	}
+	// An empty profile runs the default analysis
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error parsing analysis profile %s: %w", path, err)
+	}
+
+	if options.Spec != "" && !filepath.IsAbs(options.Spec) {
+		options.Spec = filepath.Join(filepath.Dir(path), options.Spec)
+	}
+	if options.Store != "" && !filepath.IsAbs(options.Store) {
+		options.Store = filepath.Join(filepath.Dir(path), options.Store)
+	}
+	return options, nil
+}
+
+// resolveAnalysisOptions checks the loudness target of the options and loads their delivery spec.
+func resolveAnalysisOptions(options *analysisOptions) error {
+	if options.Loudness != "" {
+		if _, err := ffmpeg.LookupLoudnessTarget(options.Loudness); err != nil {
+			return err
+		}
+	}
+	if options.Spec == "" && options.Profile == "" {
+		return nil
+	}
+
+	spec, err := loadCheckSpec(options.Spec, options.Profile)
+	if err != nil {
+		return err
+	}
+	options.spec = spec
+	return nil
+}
+
+// loadCheckSpec returns the spec of the check command: the spec file, the built-in profile, or
+// the profile overridden by the spec file. A spec file naming its own profile cannot be combined
+// with another one.
+func loadCheckSpec(specPath string, profileName string) (*ffmpeg.Spec, error) {
+	var spec *ffmpeg.Spec
+	if specPath != "" {
+		var err error
+		if spec, err = ffmpeg.LoadSpec(specPath); err != nil {
+			return nil, err
+		}
+	}
+	if profileName == "" {
+		return spec, nil
+	}
+
+	profile, err := ffmpeg.LookupSpecProfile(profileName)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case spec == nil:
+		return profile, nil
+	case spec.Profile != "":
+		return nil, fmt.Errorf("spec %s already overrides profile %s", specPath, spec.Profile)
+	}
+	return ffmpeg.MergeSpecs(profile, spec), nil
+}
+
+// openExistingResultStore opens the result store of the history and query commands, which have
+// nothing to read from a store that does not exist yet.
+func openExistingResultStore(storePath string) (*ffmpeg.ResultStore, error) {
+	if storePath == "" {
+		return nil, fmt.Errorf("no result store given, set --store or %s", storeEnvVar)
+	}
+	if _, err := os.Stat(storePath); err != nil {
+		return nil, fmt.Errorf("result store %s not found: %w", storePath, err)
+	}
+	return ffmpeg.OpenResultStore(storePath)
+}
+
+// saveStoredRun records the analysis of a file in a result store, keyed by the SHA-256 of the
+// file so the history follows the file when it is renamed or moved. The store is only kept open
+// while saving, so other commands can read it meanwhile.
+func saveStoredRun(absPath string, info *ffmpeg.ContainerInfo, frames []ffmpeg.FrameBitrateInfo, storePath string) error {
+	infoStyle := color.New(color.FgCyan)
+
+	fileInfo, err := os.Stat(absPath)
+	if err != nil {
+		return fmt.Errorf("error reading file information: %w", err)
+	}
+	hash, err := ffmpeg.FileSHA256(absPath)
+	if err != nil {
+		return fmt.Errorf("error hashing file for the result store: %w", err)
+	}
+
+	store, err := ffmpeg.OpenResultStore(storePath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	run := ffmpeg.NewStoredRun(absPath, hash, fileInfo.Size(), info, time.Now())
+	run.Version = Version
+	if err := store.Save(run, frames); err != nil {
+		return err
+	}
+	infoStyle.Printf("💾 Analysis recorded in %s\n", storePath)
+	return nil
+}
+
+// moveWatchedFile moves a file of a watched directory to the same relative path under destDir,
+// adding the current time to its name when the destination exists. Files are copied when they
+// cannot be renamed, such as across file systems. It returns the new path.
+func moveWatchedFile(path string, dir string, destDir string) (string, error) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return "", fmt.Errorf("error resolving relative path: %w", err)
+	}
+	target := filepath.Join(destDir, rel)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", fmt.Errorf("error creating directory: %w", err)
+	}
+	if _, err := os.Stat(target); err == nil {
+		ext := filepath.Ext(target)
+		target = strings.TrimSuffix(target, ext) + time.Now().Format("-20060102-150405") + ext
+	}
+
+	if err := os.Rename(path, target); err != nil {
+		if copyErr := copyFile(path, target); copyErr != nil {
+			return "", fmt.Errorf("error moving %s: %w", path, err)
+		}
+		if err := os.Remove(path); err != nil {
+			return "", fmt.Errorf("error removing %s: %w", path, err)
+		}
+	}
+	return target, nil
+}
+
+// copyFile copies a file with its permissions, removing a partial copy on failure.
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fileInfo, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fileInfo.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
+
+// processWatchedFile analyzes a file of a watched directory into OUTPUT/NAME, where NAME is its
+// path relative to the watched directory, then moves it to the done or the failed directory.
+// Files whose analysis was interrupted too many times, such as files crashing FFmpeg, are failed
+// without another attempt. The error of a failed analysis is saved to error.txt in the report
+// directory. An error is returned only when the file cannot be moved.
+func processWatchedFile(entry *ffmpeg.WatchEntry, dir string, dirs watchDirs, ffmpegInfo *ffmpeg.FFmpegInfo,
+	prober *ffmpeg.Prober, options *analysisOptions) error {
+	successStyle := color.New(color.FgGreen)
+	errorStyle := color.New(color.FgRed)
+
+	rel, err := filepath.Rel(dir, entry.Path)
+	if err != nil {
+		return fmt.Errorf("error resolving relative path: %w", err)
+	}
+	reportDir := filepath.Join(dirs.output, rel)
+
+	var analysisErr error
+	if entry.Attempts > watchMaxAttempts {
+		analysisErr = fmt.Errorf("analysis interrupted %d times", entry.Attempts-1)
+	} else {
+		color.New(color.FgCyan).Printf("🔍 Analyzing %s (attempt %d)\n", rel, entry.Attempts)
+		analysisErr = analyzeFile(entry.Path, reportDir, ffmpegInfo, prober, options)
+	}
+
+	if analysisErr != nil {
+		errorStyle.Printf("❌ %s failed: %v\n", rel, analysisErr)
+		if err := os.MkdirAll(reportDir, 0755); err != nil {
+			return fmt.Errorf("error creating report directory: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(reportDir, "error.txt"), []byte(analysisErr.Error()+"\n"), 0644); err != nil {
+			return fmt.Errorf("error saving error report: %w", err)
+		}
+		target, err := moveWatchedFile(entry.Path, dir, dirs.failed)
+		if err != nil {
+			return err
+		}
+		errorStyle.Printf("❌ %s moved to %s\n", rel, target)
+		return nil
+	}
+
+	target, err := moveWatchedFile(entry.Path, dir, dirs.done)
+	if err != nil {
+		return err
+	}
+	successStyle.Printf("✅ %s analyzed, reports saved to %s, file moved to %s\n", rel, reportDir, target)
+	return nil
+}
+
+// scanWatchDir lists the video files of a watched directory by absolute path. Hidden files and
+// directories are skipped, as well as the excluded directories, such as the done and failed
+// directories when they are inside the watched one.
+func scanWatchDir(dir string, excluded ...string) (map[string]os.FileInfo, error) {
+	files := make(map[string]os.FileInfo)
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			if slices.Contains(excluded, path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || !videoExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		fileInfo, err := entry.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		files[path] = fileInfo
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+// watchFileLocked reports whether the writer of a file still holds it: a NAME.lock or NAME.lck
+// file sits next to it, or the file cannot be opened, as on Windows while it is being copied.
+func watchFileLocked(path string) bool {
+	for _, suffix := range []string{".lock", ".lck"} {
+		if _, err := os.Stat(path + suffix); err == nil {
+			return true
+		}
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return true
+	}
+	file.Close()
+	return false
+}
+
+// measureBitrateStats measures the peak bitrate and the frame rate mode of the first video stream
+// and attaches them to the analysis of the file.
+func measureBitrateStats(filePath string, analyzer *ffmpeg.BitrateAnalyzer, info *ffmpeg.ContainerInfo) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	stats := ffmpeg.NewBitrateStats(getFrameRate(info))
+	frameCh := make(chan ffmpeg.FrameBitrateInfo, 100)
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(frameCh)
+		errCh <- analyzer.Analyze(ctx, filePath, frameCh)
+	}()
+	for frame := range frameCh {
+		stats.AddFrame(frame)
+	}
+	if err := <-errCh; err != nil {
+		return fmt.Errorf("error measuring %s: %w", filePath, err)
+	}
+
+	stats.Finish()
+	info.Bitrate = stats
+	return nil
+}
+
+// measureComparedFile adds the size, type and time of every frame of a file to a comparison,
+// then the QP of every frame when the codec supports it.
+func measureComparedFile(comparison *ffmpeg.EncodeComparison, index int, filePath string, analyzer *ffmpeg.BitrateAnalyzer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	frameCh := make(chan ffmpeg.FrameBitrateInfo, 100)
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(frameCh)
+		errCh <- analyzer.Analyze(ctx, filePath, frameCh)
+	}()
+	for frame := range frameCh {
+		comparison.AddFrame(index, frame)
+	}
+	if err := <-errCh; err != nil {
+		return fmt.Errorf("error measuring %s: %w", filePath, err)
+	}
+
+	warningStyle := color.New(color.FgYellow)
+	qualityAnalyzer, err := ffmpeg.NewQualityAnalyzer(filePath)
+	if err != nil {
+		warningStyle.Printf("⚠️ Skipping QP: %v\n", err)
+		return nil
+	}
+
+	// The quality analyzers log every frame, keep the console readable
+	logWriter := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(logWriter)
+
+	qualityCh := make(chan ffmpeg.QualityFrame, 100)
+	go func() {
+		errCh <- qualityAnalyzer.Analyze(context.Background(), filePath, qualityCh)
+	}()
+	for frame := range qualityCh {
+		comparison.AddQualityFrame(index, frame)
+	}
+	if err := <-errCh; err != nil {
+		warningStyle.Printf("⚠️ Skipping QP: %v\n", err)
+	}
+	return nil
+}
+
+// fingerprintFile probes a file and computes its fingerprint. With measureQP, the average QP of
+// the video is measured too, which decodes the whole video.
+func fingerprintFile(filePath string, prober *ffmpeg.Prober, analyzer *ffmpeg.FingerprintAnalyzer, measureQP bool) (*ffmpeg.Fingerprint, error) {
+	info, err := prober.GetExtendedContainerInfo(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze file: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	fingerprint, err := analyzer.Analyze(ctx, filePath, info)
+	if err != nil {
+		return nil, err
+	}
+	if !measureQP {
+		return fingerprint, nil
+	}
+
+	qualityAnalyzer, err := ffmpeg.NewQualityAnalyzer(filePath)
+	if err != nil {
+		color.New(color.FgYellow).Printf("⚠️ Skipping QP: %v\n", err)
+		return fingerprint, nil
+	}
+
+	// The quality analyzers log every frame, keep the console readable
+	logWriter := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(logWriter)
+
+	qualityCh := make(chan ffmpeg.QualityFrame, 100)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- qualityAnalyzer.Analyze(context.Background(), filePath, qualityCh)
+	}()
+	total, frames := 0.0, 0
+	for frame := range qualityCh {
+		if frame.QP > 0 {
+			total += frame.QP
+			frames++
+		}
+	}
+	if err := <-errCh; err != nil {
+		color.New(color.FgYellow).Printf("⚠️ Skipping QP: %v\n", err)
+	} else if frames > 0 {
+		fingerprint.AverageQP = total / float64(frames)
+	}
+	return fingerprint, nil
+}
+
+// formatETA formats the estimated time of completion in a human-readable format.
+// It returns the formatted string with color formatting if ANSI codes are enabled.
+// Format: "ETA: X hours Y minutes Z seconds" (hours and minutes only displayed when > 0)
+func formatETA(seconds float64) string {
+	// Handle edge cases
+	if seconds < 0 || math.IsInf(seconds, 0) || math.IsNaN(seconds) {
+		return "[cyan][bold]ETA: unknown[reset]"
+	}
+
+	// Convert to hours, minutes, seconds
+	hours := int(seconds) / 3600
+	minutes := (int(seconds) % 3600) / 60
+	secs := int(seconds) % 60
+
+	// Build the human-readable format with conditional parts
+	var parts []string
+	if hours > 0 {
+		if hours == 1 {
+			parts = append(parts, "1 hour")
+		} else {
+			parts = append(parts, fmt.Sprintf("%d hours", hours))
+		}
+	}
+
+	if minutes > 0 {
+		if minutes == 1 {
+			parts = append(parts, "1 minute")
+		} else {
+			parts = append(parts, fmt.Sprintf("%d minutes", minutes))
+		}
+	}
+
+	// Always include seconds
+	if secs == 1 {
+		parts = append(parts, "1 second")
+	} else {
+		parts = append(parts, fmt.Sprintf("%d seconds", secs))
+	}
+
+	// Join the parts into a single string
+	etaText := strings.Join(parts, " ")
+
+	// Return with cyan bold color codes
+	return fmt.Sprintf("[cyan][bold]ETA: %s[reset]", etaText)
+}
+
+// saveBitrateCSV generates a CSV report containing frame-by-frame bitrate information.
+// It creates a csv file with frame number, frame type, and bitrate for each frame.
+// It displays a progress bar during generation to provide user feedback.
+// Every observer is called with each frame, so other analyses can share the pass.
+func saveBitrateCSV(filePath string, outputDir string, analyzer *ffmpeg.BitrateAnalyzer, showFrames bool,
+	observers ...func(ffmpeg.FrameBitrateInfo)) (bitrateSummary, error) {
+	// Set up output file
+	csvFile, writer, err := setupBitrateCSVFile(outputDir)
+	if err != nil {
+		return bitrateSummary{}, err
+	}
+	defer csvFile.Close()
+	defer writer.Flush()
+
+	// Get estimated frame count
+	estimatedFrameCount, err := getEstimatedFrameCount(filePath)
+	if err != nil {
+		return bitrateSummary{}, err
+	}
+
+	// Display detailed frame count information if enabled
+	if showFrames {
+		infoStyle := color.New(color.FgCyan)
+		infoStyle.Printf("🔢 Estimated frame count: %d\n", estimatedFrameCount)
+	}
+
+	// Create a channel for frame info
+	resultCh := make(chan ffmpeg.FrameBitrateInfo, 100)
+
+	// Create a context with timeout (30 minutes should be enough for most files)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	// Display header
+	infoStyle := color.New(color.FgCyan, color.Bold)
+	infoStyle.Printf("\n🔍 BITRATE ANALYSIS\n")
+	infoStyle.Printf("----------------\n\n")
+
+	// Create the progress bar
+	description := "📈 Generating bitrate report"
+
+	// Start time for calculating ETA manually
+	startTime := time.Now()
+
+	// Create the progress bar
+	bar := progressbar.NewOptions64(
+		estimatedFrameCount,
+		progressbar.OptionSetDescription(description),
+		progressbar.OptionShowBytes(false),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionClearOnFinish(),
+		progressbar.OptionSetTheme(progressbar.Theme{
+			Saucer:        "=",
+			SaucerHead:    ">",
+			SaucerPadding: "-",
+			BarStart:      "[",
+			BarEnd:        "]",
+		}),
+		progressbar.OptionSetRenderBlankState(true),
+		progressbar.OptionUseANSICodes(true),
+		progressbar.OptionEnableColorCodes(true),
+		// Explicitly disable default time displays
+		progressbar.OptionSetPredictTime(false),
+		progressbar.OptionShowElapsedTimeOnFinish(),
+		progressbar.OptionSetElapsedTime(false),
+		progressbar.OptionClearOnFinish(),
+		progressbar.OptionOnCompletion(func() {
+			// Clear the line and reset the cursor
+			fmt.Print("\033[2K\r")
+			// Add new line for spacing
+			fmt.Print("\n")
+		}),
+	)
+
+	// Start a goroutine to update the ETA in the description
+	go func() {
+		for !bar.IsFinished() {
+			current := bar.State().CurrentPercent
+			if current > 0 {
+				// Calculate estimated time
+				elapsed := time.Since(startTime).Seconds()
+				totalEstimated := elapsed / float64(current)
+				eta := totalEstimated - elapsed
+
+				// Format the ETA
+				etaFormatted := formatETA(eta)
+
+				// Update the description with a space after the description, then formatted ETA
+				newDesc := fmt.Sprintf("%s - %s -", description, etaFormatted)
+				bar.Describe(newDesc)
+			}
+			time.Sleep(500 * time.Millisecond) // Update twice per second
+		}
+	}()
+
+	// Create a WaitGroup to properly manage goroutine completion
+	var wg sync.WaitGroup
+	var processErr error
+	var summary bitrateSummary
+
+	// Start frame processing in a goroutine, but don't wait for it yet
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		summary, processErr = processFramesForCSV(ctx, resultCh, writer, bar, cancel, observers...)
+	}()
+
+	// Now start the analyzer - it will feed frames into the channel
+	if err := analyzer.Analyze(ctx, filePath, resultCh); err != nil {
+		close(resultCh)
+		return bitrateSummary{}, fmt.Errorf("error analyzing file for CSV generation: %w", err)
+	}
+	close(resultCh)
+
+	// Wait for processing to complete
+	wg.Wait()
+
+	// Check for errors during processing
+	if processErr != nil {
+		return bitrateSummary{}, processErr
+	}
+	actualFrameCount := int(summary.FrameCount)
+
+	// If our estimate was incorrect, adjust the bar to show exactly 100%
+	if actualFrameCount > 0 && actualFrameCount != int(estimatedFrameCount) {
+		// Log the discrepancy in frame count if debug mode is enabled
+		if showFrames {
+			warningStyle := color.New(color.FgYellow)
+			warningStyle.Printf("⚠️ Frame count discrepancy: estimated %d, actual %d (use --integrity to check for damage)\n", estimatedFrameCount, actualFrameCount)
+		}
+	}
+
+	// Make sure bar is completed and closed (this will clear the bar)
+	_ = bar.Finish()
+
+	// Print success message with completion indicator
+	successStyle := color.New(color.FgGreen)
+	completedStyle := color.New(color.FgCyan, color.Bold)
+	completedStyle.Println("📈 Generating bitrate report - Completed!")
+	successStyle.Printf("✅ Bitrate report saved to %s\n", filepath.Join(outputDir, "bitrate.csv"))
+
+	return summary, nil
+}
+
+// processFramesForCSV processes frame information from the channel and writes it to the CSV file.
+// It returns the actual frame count, the total size of all frames and any error that occurred during processing.
+// Every frame is also passed to the observers.
+func processFramesForCSV(ctx context.Context, resultCh chan ffmpeg.FrameBitrateInfo, writer *csv.Writer, bar *progressbar.ProgressBar,
+	cancel context.CancelFunc, observers ...func(ffmpeg.FrameBitrateInfo)) (bitrateSummary, error) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	var processErr error
+	var summary bitrateSummary
+
+	go func() {
+		defer wg.Done()
+		frameCount := 0
+		var totalBits int64
+
+		for {
+			select {
+			case <-ctx.Done():
+				processErr = ctx.Err()
+				return
+			case frame, ok := <-resultCh:
+				if !ok {
+					// Channel closed, we're done
+					summary = bitrateSummary{FrameCount: int64(frameCount), TotalBits: totalBits}
+					return
+				}
+
+				// Update progress bar
+				_ = bar.Add(1)
+
+				for _, observe := range observers {
+					observe(frame)
+				}
+
+				// Convert to CSV record
+				record := []string{
+					strconv.Itoa(frame.FrameNumber),
+					frame.FrameType,
+					strconv.FormatInt(frame.Bitrate, 10),
+				}
+
+				// Write CSV record
+				if err := writer.Write(record); err != nil {
+					processErr = fmt.Errorf("error writing CSV record: %w", err)
+					cancel() // Cancel processing on error
+					return
+				}
+
+				// Flush periodically to ensure data is written to disk
+				if frameCount%1000 == 0 {
+					writer.Flush()
+				}
+				frameCount++
+				totalBits += frame.Bitrate
+			}
+		}
+	}()
+
+	// Wait for processing to complete
+	wg.Wait()
+
+	// Final flush to ensure all data is written
+	writer.Flush()
+
+	return summary, processErr
+}
+
+// setupBitrateCSVFile creates the CSV file and writer for bitrate data.
+func setupBitrateCSVFile(outputDir string) (*os.File, *csv.Writer, error) {
+	// Create the output directory if it doesn't exist
+	err := os.MkdirAll(outputDir, 0755)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating output directory: %w", err)
+	}
+
+	// Define output file path
+	outputPath := filepath.Join(outputDir, "bitrate.csv")
+
+	// Create CSV file
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating bitrate CSV file: %w", err)
+	}
+
+	// Set up CSV writer
+	writer := csv.NewWriter(file)
+
+	// Write CSV header
+	if err := writer.Write([]string{"frame_number", "frame_type", "bitrate"}); err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("error writing CSV header: %w", err)
+	}
+
+	return file, writer, nil
+}
+
+// saveIntegrityCSV decodes every audio and video stream in full with error detection and writes
+// the errors to integrity.csv as they are reported. The report is attached to the container info
+// and the healthy or damaged verdict is printed. Attached pictures such as cover art are skipped.
+func saveIntegrityCSV(filePath string, outputDir string, ffmpegInfo *ffmpeg.FFmpegInfo, info *ffmpeg.ContainerInfo) error {
+	analyzer, err := ffmpeg.NewIntegrityAnalyzer(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create integrity analyzer: %w", err)
+	}
+
+	outputPath := filepath.Join(outputDir, "integrity.csv")
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating integrity CSV file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	header := []string{"stream_index", "time", "type", "source", "message"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	infoStyle := color.New(color.FgCyan)
+	warningStyle := color.New(color.FgYellow)
+	report := ffmpeg.NewIntegrityReport(info)
+	for i := range report.Streams {
+		stream := report.Streams[i]
+		infoStyle.Printf("🩺 Decoding %s stream #%d to check its integrity\n", stream.Type, stream.StreamIndex)
+
+		// Errors are written as soon as FFmpeg reports them
+		eventCh := make(chan ffmpeg.IntegrityEvent, 16)
+		resultCh := make(chan ffmpeg.IntegrityStream, 1)
+		errCh := make(chan error, 1)
+		go func() {
+			result, err := analyzer.Analyze(ctx, filePath, stream, eventCh)
+			resultCh <- result
+			errCh <- err
+		}()
+
+		var writeErr error
+		for event := range eventCh {
+			if writeErr != nil || !report.Add(event) {
+				continue
+			}
+			record := []string{
+				strconv.Itoa(event.StreamIndex),
+				strconv.FormatFloat(event.Time, 'f', 3, 64),
+				event.Type,
+				event.Source,
+				event.Message,
+			}
+			if writeErr = writer.Write(record); writeErr != nil {
+				cancel()
+			}
+		}
+		result, analyzeErr := <-resultCh, <-errCh
+		if writeErr != nil {
+			return fmt.Errorf("error writing CSV record: %w", writeErr)
+		}
+		if analyzeErr != nil {
+			// A stream that could not be checked cannot be reported as healthy
+			warningStyle.Printf("⚠️ Skipping %s stream #%d: %v\n", stream.Type, stream.StreamIndex, analyzeErr)
+			report.Streams[i].Failed = true
+			continue
+		}
+		report.Streams[i] = result
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing integrity CSV: %w", err)
+	}
+	info.Integrity = report
+
+	if report.Healthy() {
+		color.New(color.FgGreen).Println("✅ Integrity check: file is healthy")
+	} else {
+		warningStyle.Printf("⚠️ Integrity check: file is damaged (%d errors)\n", len(report.Events))
+	}
+
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Integrity errors saved to %s\n", outputPath)
+	return nil
+}
+
+// saveFrameHashCSV writes the checksum of every decoded video and audio frame to framehash.csv
+// as frames are decoded. The file and content hashes are attached to the container info so the
+// media info reports can show them.
+func saveFrameHashCSV(filePath string, outputDir string, ffmpegInfo *ffmpeg.FFmpegInfo, info *ffmpeg.ContainerInfo) error {
+	analyzer, err := ffmpeg.NewFrameHashAnalyzer(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create framehash analyzer: %w", err)
+	}
+
+	outputPath := filepath.Join(outputDir, "framehash.csv")
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating framehash CSV file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	header := []string{"stream_index", "frame", "pts", "time", "duration", "size", "hash"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	infoStyle := color.New(color.FgCyan)
+	infoStyle.Println("🔏 Computing frame checksums")
+
+	// Checksums are written as soon as frames are decoded
+	frameCh := make(chan ffmpeg.FrameHash, 64)
+	resultCh := make(chan *ffmpeg.FileHash, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		result, err := analyzer.Analyze(ctx, filePath, info, frameCh)
+		resultCh <- result
+		errCh <- err
+	}()
+
+	var writeErr error
+	for frame := range frameCh {
+		if writeErr != nil {
+			continue
+		}
+		record := []string{
+			strconv.Itoa(frame.StreamIndex),
+			strconv.Itoa(frame.Frame),
+			strconv.FormatInt(frame.PTS, 10),
+			strconv.FormatFloat(frame.Time, 'f', 6, 64),
+			strconv.FormatInt(frame.Duration, 10),
+			strconv.Itoa(frame.Size),
+			frame.Hash,
+		}
+		if writeErr = writer.Write(record); writeErr != nil {
+			cancel()
+		}
+	}
+	result, analyzeErr := <-resultCh, <-errCh
+	if writeErr != nil {
+		return fmt.Errorf("error writing CSV record: %w", writeErr)
+	}
+	if analyzeErr != nil {
+		return analyzeErr
+	}
+	info.Checksums = result
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing framehash CSV: %w", err)
+	}
+
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Frame checksums saved to %s (content hash %s)\n", outputPath, result.ContentHash)
+	return nil
+}
+
+// saveSubtitleCSV analyzes the events of every subtitle stream and writes them to subtitles.csv.
+// The analysis is attached to the subtitle streams so the media info reports can summarize it.
+// A stream that cannot be extracted is reported as a warning and skipped.
+func saveSubtitleCSV(filePath string, outputDir string, ffmpegInfo *ffmpeg.FFmpegInfo, info *ffmpeg.ContainerInfo) error {
+	analyzer, err := ffmpeg.NewSubtitleAnalyzer(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create subtitle analyzer: %w", err)
+	}
+
+	outputPath := filepath.Join(outputDir, "subtitles.csv")
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating subtitle CSV file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	header := []string{"stream_index", "event", "start", "end", "duration", "characters",
+		"chars_per_second", "overlapping", "too_fast", "beyond_video_end", "text"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	warningStyle := color.New(color.FgYellow)
+	videoDuration := getVideoDuration(info)
+	for i := range info.SubtitleStreams {
+		stream := &info.SubtitleStreams[i]
+		report, err := analyzer.Analyze(ctx, filePath, *stream, videoDuration)
+		if err != nil {
+			warningStyle.Printf("⚠️ Skipping subtitle stream #%d: %v\n", stream.Index, err)
+			continue
+		}
+		stream.Analysis = report
+
+		for _, event := range report.Events {
+			record := []string{
+				strconv.Itoa(report.StreamIndex),
+				strconv.Itoa(event.Number),
+				strconv.FormatFloat(event.Start, 'f', 3, 64),
+				strconv.FormatFloat(event.End, 'f', 3, 64),
+				strconv.FormatFloat(event.End-event.Start, 'f', 3, 64),
+				strconv.Itoa(event.Characters),
+				strconv.FormatFloat(event.CharsPerSecond, 'f', 2, 64),
+				strconv.FormatBool(event.Overlapping),
+				strconv.FormatBool(event.TooFast),
+				strconv.FormatBool(event.BeyondVideoEnd),
+				strings.ReplaceAll(event.Text, "\n", " "),
+			}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("error writing CSV record: %w", err)
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing subtitle CSV: %w", err)
+	}
+
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Subtitle report saved to %s\n", outputPath)
+	return nil
+}
+
+// saveLoudnessCSV measures the loudness of every audio stream and writes the momentary and
+// short-term series to loudness.csv. The measurements are attached to the audio streams so the
+// media info reports can show them. A stream that cannot be measured is reported as a warning and skipped.
+func saveLoudnessCSV(filePath string, outputDir string, ffmpegInfo *ffmpeg.FFmpegInfo, info *ffmpeg.ContainerInfo, target ffmpeg.LoudnessTarget) error {
+	analyzer, err := ffmpeg.NewLoudnessAnalyzer(ffmpegInfo, target)
+	if err != nil {
+		return fmt.Errorf("failed to create loudness analyzer: %w", err)
+	}
+
+	outputPath := filepath.Join(outputDir, "loudness.csv")
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating loudness CSV file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	if err := writer.Write([]string{"stream_index", "time", "momentary", "short_term"}); err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	infoStyle := color.New(color.FgCyan)
+	warningStyle := color.New(color.FgYellow)
+	for i := range info.AudioStreams {
+		stream := &info.AudioStreams[i]
+		infoStyle.Printf("🔊 Measuring loudness of audio stream #%d\n", i)
+
+		report, err := analyzer.Analyze(ctx, filePath, *stream)
+		if err != nil {
+			warningStyle.Printf("⚠️ Skipping audio stream #%d: %v\n", i, err)
+			continue
+		}
+		stream.Loudness = report
+
+		for _, sample := range report.Samples {
+			record := []string{
+				strconv.Itoa(report.StreamIndex),
+				strconv.FormatFloat(sample.Time, 'f', 1, 64),
+				strconv.FormatFloat(sample.Momentary, 'f', 1, 64),
+				strconv.FormatFloat(sample.ShortTerm, 'f', 1, 64),
+			}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("error writing CSV record: %w", err)
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing loudness CSV: %w", err)
+	}
+
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Loudness report saved to %s\n", outputPath)
+	return nil
+}
+
+// saveAudioQCCSV detects defects in every audio stream and writes them to audio_qc.csv.
+// The reports are attached to the audio streams so the media info reports can summarize them.
+// A stream that cannot be analyzed is reported as a warning and skipped.
+func saveAudioQCCSV(filePath string, outputDir string, ffmpegInfo *ffmpeg.FFmpegInfo, info *ffmpeg.ContainerInfo) error {
+	analyzer, err := ffmpeg.NewAudioQCAnalyzer(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create audio QC analyzer: %w", err)
+	}
+
+	outputPath := filepath.Join(outputDir, "audio_qc.csv")
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating audio QC CSV file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	header := []string{"stream_index", "type", "channel", "start", "end", "value", "description"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	infoStyle := color.New(color.FgCyan)
+	warningStyle := color.New(color.FgYellow)
+	for i := range info.AudioStreams {
+		stream := &info.AudioStreams[i]
+		infoStyle.Printf("🎧 Checking audio stream #%d for defects\n", i)
+
+		report, err := analyzer.Analyze(ctx, filePath, *stream)
+		if err != nil {
+			warningStyle.Printf("⚠️ Skipping audio stream #%d: %v\n", i, err)
+			continue
+		}
+		stream.QC = report
+
+		for _, event := range report.Events {
+			record := []string{
+				strconv.Itoa(report.StreamIndex),
+				event.Type,
+				strconv.Itoa(event.Channel),
+				strconv.FormatFloat(event.Start, 'f', 3, 64),
+				strconv.FormatFloat(event.End, 'f', 3, 64),
+				strconv.FormatFloat(event.Value, 'f', 4, 64),
+				event.Description,
+			}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("error writing CSV record: %w", err)
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing audio QC CSV: %w", err)
+	}
+
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Audio QC report saved to %s\n", outputPath)
+	return nil
+}
+
+// saveCadenceCSV finds the duplicate and dropped frames of every video stream and writes them
+// to cadence.csv, every segment followed by the runs starting in it. The reports are attached
+// to the video streams so the media info reports can show them. Attached pictures such as cover art are skipped.
+func saveCadenceCSV(filePath string, outputDir string, ffmpegInfo *ffmpeg.FFmpegInfo, info *ffmpeg.ContainerInfo) error {
+	analyzer, err := ffmpeg.NewCadenceAnalyzer(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create cadence analyzer: %w", err)
+	}
+
+	outputPath := filepath.Join(outputDir, "cadence.csv")
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating cadence CSV file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	header := []string{"stream_index", "type", "start", "end", "frames", "duplicates", "dropped", "unique_fps"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	infoStyle := color.New(color.FgCyan)
+	warningStyle := color.New(color.FgYellow)
+	for i := range info.VideoStreams {
+		stream := &info.VideoStreams[i]
+		if stream.Disposition.AttachedPic {
+			continue
+		}
+		infoStyle.Printf("🎞️ Looking for duplicate and dropped frames in stream #%d\n", i)
+
+		report, err := analyzer.Analyze(ctx, filePath, *stream)
+		if err != nil {
+			warningStyle.Printf("⚠️ Skipping video stream #%d: %v\n", i, err)
+			continue
+		}
+		stream.Cadence = report
+
+		run := 0
+		for _, segment := range report.Segments {
+			records := [][]string{{
+				strconv.Itoa(stream.Index),
+				"segment",
+				strconv.FormatFloat(segment.Start, 'f', 3, 64),
+				strconv.FormatFloat(segment.End, 'f', 3, 64),
+				strconv.Itoa(segment.Frames),
+				strconv.Itoa(segment.Duplicates),
+				strconv.Itoa(segment.Dropped),
+				strconv.FormatFloat(segment.UniqueFrameRate, 'f', 3, 64),
+			}}
+			for ; run < len(report.Runs) && report.Runs[run].Start < segment.End; run++ {
+				duplicates, dropped := report.Runs[run].Frames, 0
+				if report.Runs[run].Type == ffmpeg.CadenceDropped {
+					duplicates, dropped = 0, report.Runs[run].Frames
+				}
+				records = append(records, []string{
+					strconv.Itoa(stream.Index),
+					report.Runs[run].Type,
+					strconv.FormatFloat(report.Runs[run].Start, 'f', 3, 64),
+					strconv.FormatFloat(report.Runs[run].End, 'f', 3, 64),
+					strconv.Itoa(report.Runs[run].Frames),
+					strconv.Itoa(duplicates),
+					strconv.Itoa(dropped),
+					"",
+				})
+			}
+			if err := writer.WriteAll(records); err != nil {
+				return fmt.Errorf("error writing CSV record: %w", err)
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing cadence CSV: %w", err)
+	}
+
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Duplicate and dropped frames saved to %s\n", outputPath)
+	return nil
+}
+
+// saveEventsCSV detects black segments, blank frames and frozen video in every video stream
+// and writes them to events.csv as they are found. The reports are attached to the video streams
+// so the media info reports can summarize them. Attached pictures such as cover art are skipped.
+func saveEventsCSV(filePath string, outputDir string, analyzer *ffmpeg.BlackFreezeAnalyzer, info *ffmpeg.ContainerInfo) error {
+	outputPath := filepath.Join(outputDir, "events.csv")
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating events CSV file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	header := []string{"stream_index", "type", "start", "end", "duration", "value"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	infoStyle := color.New(color.FgCyan)
+	warningStyle := color.New(color.FgYellow)
+	for i := range info.VideoStreams {
+		stream := &info.VideoStreams[i]
+		if stream.Disposition.AttachedPic {
+			continue
+		}
+		infoStyle.Printf("⬛ Looking for black and frozen video in stream #%d\n", i)
+
+		// Events are written as soon as the analyzer completes them
+		eventCh := make(chan ffmpeg.VideoEvent, 16)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- analyzer.Analyze(ctx, filePath, *stream, eventCh)
+		}()
+
+		report := &ffmpeg.VideoEventReport{StreamIndex: stream.Index, IgnoreEdges: analyzer.IgnoreEdges}
+		var writeErr error
+		for event := range eventCh {
+			if writeErr != nil {
+				continue
+			}
+			report.Events = append(report.Events, event)
+			record := []string{
+				strconv.Itoa(stream.Index),
+				event.Type,
+				strconv.FormatFloat(event.Start, 'f', 3, 64),
+				strconv.FormatFloat(event.End, 'f', 3, 64),
+				strconv.FormatFloat(event.End-event.Start, 'f', 3, 64),
+				strconv.FormatFloat(event.Value, 'f', 2, 64),
+			}
+			if writeErr = writer.Write(record); writeErr != nil {
+				cancel()
+			}
+		}
+		analyzeErr := <-errCh
+		if writeErr != nil {
+			return fmt.Errorf("error writing CSV record: %w", writeErr)
+		}
+		if analyzeErr != nil {
+			warningStyle.Printf("⚠️ Skipping video stream #%d: %v\n", i, analyzeErr)
+			continue
+		}
+		stream.Events = report
 	}
 
-	// Save detailed media information to a text file in the output directory
-	if err := saveMediaInfoText(containerInfo, outputDir, prober); err != nil {
-		return fmt.Errorf("error saving media info: %w", err)
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing events CSV: %w", err)
 	}
 
-	// Save BBCode formatted media information
-	if err := saveMediaInfoBBCode(containerInfo, outputDir, prober); err != nil {
-		return fmt.Errorf("error saving BBCode media info: %w", err)
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Black and freeze events saved to %s\n", outputPath)
+	return nil
+}
+
+// saveArtifactsCSV scores the blocking, blur and banding artifacts of every frame of every video
+// stream and writes them to artifacts.csv as frames are decoded. The summaries are attached to the
+// video streams so the media info reports can show them. Attached pictures such as cover art are skipped.
+func saveArtifactsCSV(filePath string, outputDir string, ffmpegInfo *ffmpeg.FFmpegInfo, info *ffmpeg.ContainerInfo) error {
+	analyzer, err := ffmpeg.NewArtifactAnalyzer(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create artifact analyzer: %w", err)
 	}
 
-	// Create a bitrate analyzer
-	bitrateAnalyzer, err := ffmpeg.NewBitrateAnalyzer(ffmpegInfo)
+	outputPath := filepath.Join(outputDir, "artifacts.csv")
+	file, err := os.Create(outputPath)
 	if err != nil {
-		return fmt.Errorf("failed to create bitrate analyzer: %w", err)
+		return fmt.Errorf("error creating artifacts CSV file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	header := []string{"stream_index", "frame", "time", "blockiness", "blur", "banding", "score", "quality_level"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
 	}
 
-	// Generate bitrate CSV report
-	if err := saveBitrateCSV(absPath, outputDir, bitrateAnalyzer, c.Bool("show-frames")); err != nil {
-		return fmt.Errorf("error saving bitrate CSV: %w", err)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	infoStyle := color.New(color.FgCyan)
+	warningStyle := color.New(color.FgYellow)
+	for i := range info.VideoStreams {
+		stream := &info.VideoStreams[i]
+		if stream.Disposition.AttachedPic {
+			continue
+		}
+		infoStyle.Printf("🔬 Scoring visual artifacts of video stream #%d\n", i)
+
+		// Frames are written as soon as the analyzer scores them
+		frameCh := make(chan ffmpeg.ArtifactFrame, 64)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- analyzer.Analyze(ctx, filePath, *stream, frameCh)
+		}()
+
+		var frames []ffmpeg.ArtifactFrame
+		var writeErr error
+		for frame := range frameCh {
+			if writeErr != nil {
+				continue
+			}
+			frames = append(frames, frame)
+			record := []string{
+				strconv.Itoa(stream.Index),
+				strconv.Itoa(frame.FrameNumber),
+				strconv.FormatFloat(frame.Time, 'f', 3, 64),
+				strconv.FormatFloat(frame.Blockiness, 'f', 3, 64),
+				strconv.FormatFloat(frame.Blur, 'f', 3, 64),
+				strconv.FormatFloat(frame.Banding, 'f', 3, 64),
+				strconv.FormatFloat(frame.Score, 'f', 2, 64),
+				frame.QualityLevel.String(),
+			}
+			if writeErr = writer.Write(record); writeErr != nil {
+				cancel()
+			}
+		}
+		analyzeErr := <-errCh
+		if writeErr != nil {
+			return fmt.Errorf("error writing CSV record: %w", writeErr)
+		}
+		if analyzeErr != nil {
+			warningStyle.Printf("⚠️ Skipping video stream #%d: %v\n", i, analyzeErr)
+			continue
+		}
+		stream.Artifacts = ffmpeg.SummarizeArtifacts(frames)
 	}
 
-	successStyle.Printf("\n✅ Analysis complete! All reports saved to %s\n", outputDir)
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing artifacts CSV: %w", err)
+	}
 
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Artifact scores saved to %s\n", outputPath)
 	return nil
 }
 
-// main is the entry point of the application.
-// It parses command-line arguments, validates input, and starts the analysis.
-func main() {
-	// Override the default version printer
-	cli.VersionPrinter = versionPrinter
+// saveScenesCSV completes the scene statistics with the QP of every frame and writes one line
+// per scene to scenes.csv. Without QP support for the codec, the QP columns are left empty.
+func saveScenesCSV(filePath string, outputDir string, report *ffmpeg.SceneReport) error {
+	infoStyle := color.New(color.FgCyan)
+	warningStyle := color.New(color.FgYellow)
 
-	// Create a new CLI app
-	app := &cli.App{
-		Name:  "framehound",
-		Usage: "A tool for analyzing video frame bitrates",
-		Description: "FrameHound analyzes video files to extract frame-by-frame bitrate information " +
-			"and provides detailed metadata about media containers.",
-		Authors: []*cli.Author{
-			{
-				Name: "Gian Luca Dalla Torre",
-			},
-		},
-		Version:   Version,
-		Action:    analyzeCommand,
-		ArgsUsage: "VIDEO_FILE",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:    "dir",
-				Aliases: []string{"d"},
-				Usage:   "Directory where to output the results of analysis",
-				Value:   filepath.Join(".", "reports"),
-			},
-			&cli.BoolFlag{
-				Name:  "show-frames",
-				Usage: "Show frame count information for debugging purposes",
-			},
-		},
+	infoStyle.Println("🎬 Measuring the QP of every scene")
+	if analyzer, err := ffmpeg.NewQualityAnalyzer(filePath); err != nil {
+		warningStyle.Printf("⚠️ Skipping scene QP: %v\n", err)
+	} else {
+		// The quality analyzers log every frame, keep the console readable
+		logWriter := log.Writer()
+		log.SetOutput(io.Discard)
+
+		qualityCh := make(chan ffmpeg.QualityFrame, 100)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- analyzer.Analyze(context.Background(), filePath, qualityCh)
+		}()
+		for frame := range qualityCh {
+			report.AddQualityFrame(frame)
+		}
+		analyzeErr := <-errCh
+
+		log.SetOutput(logWriter)
+		if analyzeErr != nil {
+			warningStyle.Printf("⚠️ Skipping scene QP: %v\n", analyzeErr)
+		}
 	}
+	report.Finish()
 
-	// Run the application
-	if err := app.Run(os.Args); err != nil {
-		errorStyle := color.New(color.FgRed)
-		errorStyle.Fprintf(os.Stderr, "⚠️ Error: %v\n", err)
-		os.Exit(1)
+	outputPath := filepath.Join(outputDir, "scenes.csv")
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating scenes CSV file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	header := []string{"scene", "start_frame", "end_frame", "start", "end", "duration", "cut_score", "frames",
+		"avg_bitrate", "peak_bitrate", "avg_qp", "i_frames", "p_frames", "b_frames", "keyframe_at_cut", "starved"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
+	}
+
+	for _, scene := range report.Scenes {
+		averageQP := ""
+		if scene.QPFrames > 0 {
+			averageQP = strconv.FormatFloat(scene.AverageQP, 'f', 2, 64)
+		}
+		record := []string{
+			strconv.Itoa(scene.Index),
+			strconv.Itoa(scene.StartFrame),
+			strconv.Itoa(scene.EndFrame),
+			strconv.FormatFloat(scene.Start, 'f', 3, 64),
+			strconv.FormatFloat(scene.End, 'f', 3, 64),
+			strconv.FormatFloat(scene.End-scene.Start, 'f', 3, 64),
+			strconv.FormatFloat(scene.CutScore, 'f', 2, 64),
+			strconv.Itoa(scene.Frames),
+			strconv.FormatFloat(scene.AverageBitrate, 'f', 0, 64),
+			strconv.FormatFloat(scene.PeakBitrate, 'f', 0, 64),
+			averageQP,
+			strconv.Itoa(scene.FrameTypes["I"]),
+			strconv.Itoa(scene.FrameTypes["P"]),
+			strconv.Itoa(scene.FrameTypes["B"]),
+			strconv.FormatBool(scene.KeyframeAtCut),
+			strconv.FormatBool(scene.Starved),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("error writing CSV record: %w", err)
+		}
 	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing scenes CSV: %w", err)
+	}
+
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Scene statistics saved to %s\n", outputPath)
+	return nil
 }
 
-// formatETA formats the estimated time of completion in a human-readable format.
-// It returns the formatted string with color formatting if ANSI codes are enabled.
-// Format: "ETA: X hours Y minutes Z seconds" (hours and minutes only displayed when > 0)
-func formatETA(seconds float64) string {
-	// Handle edge cases
-	if seconds < 0 || math.IsInf(seconds, 0) || math.IsNaN(seconds) {
-		return "[cyan][bold]ETA: unknown[reset]"
+// saveKeyframesCSV writes the nearest keyframe of every chapter start and scene cut to keyframes.csv.
+func saveKeyframesCSV(outputDir string, report *ffmpeg.KeyframeReport) error {
+	outputPath := filepath.Join(outputDir, "keyframes.csv")
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating keyframes CSV file: %w", err)
 	}
+	defer file.Close()
 
-	// Convert to hours, minutes, seconds
-	hours := int(seconds) / 3600
-	minutes := (int(seconds) % 3600) / 60
-	secs := int(seconds) % 60
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
 
-	// Build the human-readable format with conditional parts
-	var parts []string
-	if hours > 0 {
-		if hours == 1 {
-			parts = append(parts, "1 hour")
-		} else {
-			parts = append(parts, fmt.Sprintf("%d hours", hours))
-		}
+	header := []string{"marker", "index", "title", "time", "frame", "keyframe_frame", "keyframe_time", "idr",
+		"offset_frames", "offset_ms", "aligned"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
 	}
 
-	if minutes > 0 {
-		if minutes == 1 {
-			parts = append(parts, "1 minute")
-		} else {
-			parts = append(parts, fmt.Sprintf("%d minutes", minutes))
+	write := func(marker string, alignments []ffmpeg.KeyframeAlignment) error {
+		for _, alignment := range alignments {
+			record := []string{
+				marker,
+				strconv.Itoa(alignment.Index),
+				alignment.Title,
+				strconv.FormatFloat(alignment.Time, 'f', 3, 64),
+				strconv.Itoa(alignment.Frame),
+				strconv.Itoa(alignment.KeyframeFrame),
+				strconv.FormatFloat(alignment.KeyframeTime, 'f', 3, 64),
+				strconv.FormatBool(alignment.IDR),
+				strconv.Itoa(alignment.OffsetFrames),
+				strconv.FormatFloat(alignment.OffsetMs, 'f', 0, 64),
+				strconv.FormatBool(alignment.Aligned),
+			}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("error writing CSV record: %w", err)
+			}
 		}
+		return nil
 	}
-
-	// Always include seconds
-	if secs == 1 {
-		parts = append(parts, "1 second")
-	} else {
-		parts = append(parts, fmt.Sprintf("%d seconds", secs))
+	if err := write("chapter", report.Chapters); err != nil {
+		return err
+	}
+	if err := write("scene_cut", report.SceneCuts); err != nil {
+		return err
 	}
 
-	// Join the parts into a single string
-	etaText := strings.Join(parts, " ")
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing keyframes CSV: %w", err)
+	}
 
-	// Return with cyan bold color codes
-	return fmt.Sprintf("[cyan][bold]ETA: %s[reset]", etaText)
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Keyframe alignment saved to %s (%d misaligned chapters, %d misaligned scene cuts)\n",
+		outputPath, report.MisalignedChapters(), report.MisalignedSceneCuts())
+	return nil
 }
 
-// saveBitrateCSV generates a CSV report containing frame-by-frame bitrate information.
-// It creates a csv file with frame number, frame type, and bitrate for each frame.
-// It displays a progress bar during generation to provide user feedback.
-func saveBitrateCSV(filePath string, outputDir string, analyzer *ffmpeg.BitrateAnalyzer, showFrames bool) error {
-	// Set up output file
-	csvFile, writer, err := setupBitrateCSVFile(outputDir)
+// saveTimestampsCSV writes the timestamp anomalies of the primary video stream to timestamps.csv.
+func saveTimestampsCSV(outputDir string, report *ffmpeg.TimestampReport) error {
+	outputPath := filepath.Join(outputDir, "timestamps.csv")
+	file, err := os.Create(outputPath)
 	if err != nil {
-		return err
+		return fmt.Errorf("error creating timestamps CSV file: %w", err)
 	}
-	defer csvFile.Close()
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
-	// Get estimated frame count
-	estimatedFrameCount, err := getEstimatedFrameCount(filePath)
-	if err != nil {
-		return err
+	header := []string{"frame", "time", "type", "severity", "pts", "dts", "previous_pts", "previous_dts", "delta"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
 	}
 
-	// Display detailed frame count information if enabled
-	if showFrames {
-		infoStyle := color.New(color.FgCyan)
-		infoStyle.Printf("🔢 Estimated frame count: %d\n", estimatedFrameCount)
+	for _, anomaly := range report.Anomalies {
+		record := []string{
+			strconv.Itoa(anomaly.FrameNumber),
+			strconv.FormatFloat(anomaly.Time, 'f', 3, 64),
+			anomaly.Type,
+			anomaly.Severity,
+			formatTimestampValue(anomaly.PTS),
+			formatTimestampValue(anomaly.DTS),
+			formatTimestampValue(anomaly.PreviousPTS),
+			formatTimestampValue(anomaly.PreviousDTS),
+			strconv.FormatFloat(anomaly.Delta, 'f', 3, 64),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("error writing CSV record: %w", err)
+		}
 	}
 
-	// Create a channel for frame info
-	resultCh := make(chan ffmpeg.FrameBitrateInfo, 100)
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing timestamps CSV: %w", err)
+	}
 
-	// Create a context with timeout (30 minutes should be enough for most files)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
-	defer cancel()
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Timestamp anomalies saved to %s (%d errors, %d warnings)\n",
+		outputPath, report.CountSeverity(ffmpeg.SeverityError), report.CountSeverity(ffmpeg.SeverityWarning))
+	return nil
+}
 
-	// Display header
-	infoStyle := color.New(color.FgCyan, color.Bold)
-	infoStyle.Printf("\n🔍 BITRATE ANALYSIS\n")
-	infoStyle.Printf("----------------\n\n")
+// saveLadderCSV writes one line per rendition of a manifest to ladder.csv and one line per
+// segment to segments.csv.
+func saveLadderCSV(manifest *ffmpeg.Manifest, outputDir string) error {
+	write := func(name string, header []string, records [][]string) error {
+		file, err := os.Create(filepath.Join(outputDir, name))
+		if err != nil {
+			return fmt.Errorf("error creating %s: %w", name, err)
+		}
+		defer file.Close()
 
-	// Create the progress bar
-	description := "📈 Generating bitrate report"
+		writer := csv.NewWriter(file)
+		if err := writer.Write(header); err != nil {
+			return fmt.Errorf("error writing CSV header: %w", err)
+		}
+		if err := writer.WriteAll(records); err != nil {
+			return fmt.Errorf("error writing %s: %w", name, err)
+		}
+		return nil
+	}
 
-	// Start time for calculating ETA manually
-	startTime := time.Now()
+	var renditions, segments [][]string
+	for _, rendition := range manifest.Renditions {
+		stats := rendition.SegmentDurations
+		renditions = append(renditions, []string{
+			rendition.ID,
+			rendition.Type,
+			strconv.Itoa(rendition.Width),
+			strconv.Itoa(rendition.Height),
+			rendition.Codecs,
+			strconv.FormatInt(rendition.Bandwidth, 10),
+			strconv.FormatInt(rendition.AverageBandwidth, 10),
+			strconv.FormatFloat(rendition.MeasuredPeakBandwidth, 'f', 0, 64),
+			strconv.FormatFloat(rendition.MeasuredAverageBandwidth, 'f', 0, 64),
+			strconv.Itoa(len(rendition.Segments)),
+			strconv.Itoa(rendition.MissingSegments),
+			strconv.FormatFloat(stats.Mean, 'f', 3, 64),
+			strconv.FormatFloat(stats.Min, 'f', 3, 64),
+			strconv.FormatFloat(stats.Max, 'f', 3, 64),
+			strconv.FormatFloat(stats.StdDev, 'f', 3, 64),
+			strconv.Itoa(rendition.SegmentsWithoutIDR),
+			strconv.Itoa(rendition.MisalignedIDRs),
+		})
+
+		for i, segment := range rendition.Segments {
+			bitrate := ""
+			if segment.Size >= 0 && segment.Duration > 0 {
+				bitrate = strconv.FormatFloat(float64(segment.Size*8)/segment.Duration, 'f', 0, 64)
+			}
+			startsWithIDR := ""
+			if len(rendition.IDRTimes) > 0 && rendition.MissingSegments == 0 {
+				startsWithIDR = strconv.FormatBool(segment.StartsWithIDR)
+			}
+			segments = append(segments, []string{
+				rendition.ID,
+				strconv.Itoa(i),
+				segment.URI,
+				strconv.FormatFloat(segment.Start, 'f', 3, 64),
+				strconv.FormatFloat(segment.Duration, 'f', 3, 64),
+				strconv.FormatInt(segment.Size, 10),
+				bitrate,
+				startsWithIDR,
+			})
+		}
+	}
 
-	// Create the progress bar
-	bar := progressbar.NewOptions64(
-		estimatedFrameCount,
-		progressbar.OptionSetDescription(description),
-		progressbar.OptionShowBytes(false),
-		progressbar.OptionFullWidth(),
-		progressbar.OptionClearOnFinish(),
-		progressbar.OptionSetTheme(progressbar.Theme{
-			Saucer:        "=",
-			SaucerHead:    ">",
-			SaucerPadding: "-",
-			BarStart:      "[",
-			BarEnd:        "]",
-		}),
-		progressbar.OptionSetRenderBlankState(true),
-		progressbar.OptionUseANSICodes(true),
-		progressbar.OptionEnableColorCodes(true),
-		// Explicitly disable default time displays
-		progressbar.OptionSetPredictTime(false),
-		progressbar.OptionShowElapsedTimeOnFinish(),
-		progressbar.OptionSetElapsedTime(false),
-		progressbar.OptionClearOnFinish(),
-		progressbar.OptionOnCompletion(func() {
-			// Clear the line and reset the cursor
-			fmt.Print("\033[2K\r")
-			// Add new line for spacing
-			fmt.Print("\n")
-		}),
-	)
+	if err := write("ladder.csv", []string{"rendition", "type", "width", "height", "codecs", "declared_bandwidth",
+		"declared_average_bandwidth", "measured_peak_bandwidth", "measured_average_bandwidth", "segments", "missing_segments",
+		"segment_duration_mean", "segment_duration_min", "segment_duration_max", "segment_duration_stddev",
+		"segments_without_idr", "misaligned_idrs"}, renditions); err != nil {
+		return err
+	}
+	if err := write("segments.csv", []string{"rendition", "segment", "uri", "start", "duration", "size", "bitrate",
+		"starts_with_idr"}, segments); err != nil {
+		return err
+	}
+
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Rendition and segment statistics saved to %s\n", outputDir)
+	return nil
+}
+
+// saveLadderReport writes the ladder report of a manifest to ladder.txt.
+func saveLadderReport(manifest *ffmpeg.Manifest, outputDir string) error {
+	outputPath := filepath.Join(outputDir, "ladder.txt")
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating ladder report file: %w", err)
+	}
+	defer file.Close()
+
+	w := tabwriter.NewWriter(file, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeLadderReport(w, manifest)
+	writeMediaInfoFooter(w)
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error writing ladder report: %w", err)
+	}
+
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Ladder report saved to %s (%d issues)\n", outputPath, len(manifest.Issues))
+	return nil
+}
 
-	// Start a goroutine to update the ETA in the description
-	go func() {
-		for !bar.IsFinished() {
-			current := bar.State().CurrentPercent
-			if current > 0 {
-				// Calculate estimated time
-				elapsed := time.Since(startTime).Seconds()
-				totalEstimated := elapsed / float64(current)
-				eta := totalEstimated - elapsed
+// saveDiffCSV writes the aligned bitrate and QP series of the compared files to diff.csv,
+// one line per time bin. Unknown QPs are left empty.
+func saveDiffCSV(comparison *ffmpeg.EncodeComparison, outputDir string) error {
+	outputPath := filepath.Join(outputDir, "diff.csv")
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating diff CSV file: %w", err)
+	}
+	defer file.Close()
 
-				// Format the ETA
-				etaFormatted := formatETA(eta)
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
 
-				// Update the description with a space after the description, then formatted ETA
-				newDesc := fmt.Sprintf("%s - %s -", description, etaFormatted)
-				bar.Describe(newDesc)
+	header := []string{"time"}
+	for i := range comparison.Files {
+		header = append(header, fmt.Sprintf("bitrate_%d", i+1))
+	}
+	for i := range comparison.Files {
+		header = append(header, fmt.Sprintf("qp_%d", i+1))
+	}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("error writing CSV header: %w", err)
+	}
+
+	for _, bin := range comparison.Bins {
+		record := []string{strconv.FormatFloat(bin.Start, 'f', 3, 64)}
+		for _, bitrate := range bin.Bitrate {
+			record = append(record, strconv.FormatFloat(bitrate, 'f', 0, 64))
+		}
+		for _, qp := range bin.AverageQP {
+			value := ""
+			if qp > 0 {
+				value = strconv.FormatFloat(qp, 'f', 2, 64)
 			}
-			time.Sleep(500 * time.Millisecond) // Update twice per second
+			record = append(record, value)
 		}
-	}()
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("error writing CSV record: %w", err)
+		}
+	}
 
-	// Create a WaitGroup to properly manage goroutine completion
-	var wg sync.WaitGroup
-	var processErr error
-	var actualFrameCount int
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing diff CSV: %w", err)
+	}
 
-	// Start frame processing in a goroutine, but don't wait for it yet
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		actualFrameCount, processErr = processFramesForCSV(ctx, resultCh, writer, bar, cancel)
-	}()
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Aligned bitrate and QP series saved to %s\n", outputPath)
+	return nil
+}
 
-	// Now start the analyzer - it will feed frames into the channel
-	if err := analyzer.Analyze(ctx, filePath, resultCh); err != nil {
-		close(resultCh)
-		return fmt.Errorf("error analyzing file for CSV generation: %w", err)
+// saveDiffGraphs draws the bitrate and QP series of the compared files overlaid on the same
+// time axis, to diff_bitrate.svg and diff_qp.svg. The QP graph is skipped when no file has QPs.
+func saveDiffGraphs(comparison *ffmpeg.EncodeComparison, outputDir string) error {
+	graphs := []struct {
+		name  string
+		title string
+		value func(bin ffmpeg.ComparisonBin, file int) float64
+	}{
+		{"diff_bitrate.svg", "Bitrate (Kbps)", func(bin ffmpeg.ComparisonBin, file int) float64 {
+			return bin.Bitrate[file] / 1000
+		}},
+		{"diff_qp.svg", "Average QP", func(bin ffmpeg.ComparisonBin, file int) float64 {
+			if bin.AverageQP[file] <= 0 {
+				return math.NaN()
+			}
+			return bin.AverageQP[file]
+		}},
 	}
-	close(resultCh)
 
-	// Wait for processing to complete
-	wg.Wait()
+	successStyle := color.New(color.FgGreen)
+	for _, graph := range graphs {
+		names := make([]string, len(comparison.Files))
+		series := make([][]float64, len(comparison.Files))
+		known := false
+		for i, file := range comparison.Files {
+			names[i] = fmt.Sprintf("[%d] %s", i+1, filepath.Base(file.Path))
+			for _, bin := range comparison.Bins {
+				value := graph.value(bin, i)
+				known = known || !math.IsNaN(value)
+				series[i] = append(series[i], value)
+			}
+		}
+		if !known {
+			continue
+		}
 
-	// Check for errors during processing
-	if processErr != nil {
-		return processErr
+		outputPath := filepath.Join(outputDir, graph.name)
+		file, err := os.Create(outputPath)
+		if err != nil {
+			return fmt.Errorf("error creating %s: %w", graph.name, err)
+		}
+		writeComparisonSVG(file, graph.title, comparison.BinDuration, names, series)
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("error writing %s: %w", graph.name, err)
+		}
+		successStyle.Printf("✅ %s graph saved to %s\n", graph.title, outputPath)
 	}
+	return nil
+}
 
-	// If our estimate was incorrect, adjust the bar to show exactly 100%
-	if actualFrameCount > 0 && actualFrameCount != int(estimatedFrameCount) {
-		// Log the discrepancy in frame count if debug mode is enabled
-		if showFrames {
-			warningStyle := color.New(color.FgYellow)
-			warningStyle.Printf("⚠️ Frame count discrepancy: estimated %d, actual %d\n", estimatedFrameCount, actualFrameCount)
-		}
+// saveDiffReport writes the comparison report to diff.txt.
+func saveDiffReport(comparison *ffmpeg.EncodeComparison, outputDir string) error {
+	outputPath := filepath.Join(outputDir, "diff.txt")
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating diff report file: %w", err)
 	}
+	defer file.Close()
 
-	// Make sure bar is completed and closed (this will clear the bar)
-	_ = bar.Finish()
+	w := tabwriter.NewWriter(file, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeDiffReport(w, comparison)
+	writeMediaInfoFooter(w)
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error writing diff report: %w", err)
+	}
 
-	// Print success message with completion indicator
 	successStyle := color.New(color.FgGreen)
-	completedStyle := color.New(color.FgCyan, color.Bold)
-	completedStyle.Println("📈 Generating bitrate report - Completed!")
-	successStyle.Printf("✅ Bitrate report saved to %s\n", filepath.Join(outputDir, "bitrate.csv"))
-
+	successStyle.Printf("✅ Comparison report saved to %s\n", outputPath)
 	return nil
 }
 
-// processFramesForCSV processes frame information from the channel and writes it to the CSV file.
-// It returns the actual frame count and any error that occurred during processing.
-func processFramesForCSV(ctx context.Context, resultCh chan ffmpeg.FrameBitrateInfo, writer *csv.Writer, bar *progressbar.ProgressBar, cancel context.CancelFunc) (int, error) {
-	var wg sync.WaitGroup
-	wg.Add(1)
-
-	var processErr error
-	actualFrameCount := 0
+// saveCheckReport writes the outcome of a spec check to check.txt.
+func saveCheckReport(result *ffmpeg.SpecResult, filePath string, outputDir string) error {
+	outputPath := filepath.Join(outputDir, "check.txt")
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating check report file: %w", err)
+	}
+	defer file.Close()
 
-	go func() {
-		defer wg.Done()
-		frameCount := 0
+	w := tabwriter.NewWriter(file, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeCheckReport(w, result, filepath.Base(filePath))
+	writeMediaInfoFooter(w)
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error writing check report: %w", err)
+	}
 
-		for {
-			select {
-			case <-ctx.Done():
-				processErr = ctx.Err()
-				return
-			case frame, ok := <-resultCh:
-				if !ok {
-					// Channel closed, we're done
-					actualFrameCount = frameCount
-					return
-				}
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Check report saved to %s\n", outputPath)
+	return nil
+}
 
-				// Update progress bar
-				_ = bar.Add(1)
+// saveJUnitReport writes the outcome of a spec check to a JUnit XML file, creating its directory.
+func saveJUnitReport(result *ffmpeg.SpecResult, filePath string, outputPath string) error {
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("error creating JUnit report directory: %w", err)
+	}
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error creating JUnit report file: %w", err)
+	}
+	defer file.Close()
 
-				// Convert to CSV record
-				record := []string{
-					strconv.Itoa(frame.FrameNumber),
-					frame.FrameType,
-					strconv.FormatInt(frame.Bitrate, 10),
-				}
+	if err := writeJUnitReport(file, result, filepath.Base(filePath)); err != nil {
+		return err
+	}
 
-				// Write CSV record
-				if err := writer.Write(record); err != nil {
-					processErr = fmt.Errorf("error writing CSV record: %w", err)
-					cancel() // Cancel processing on error
-					return
-				}
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ JUnit report saved to %s\n", outputPath)
+	return nil
+}
 
-				// Flush periodically to ensure data is written to disk
-				if frameCount%1000 == 0 {
-					writer.Flush()
-				}
-				frameCount++
+// writeComparisonSVG draws series sampled every step seconds as overlaid lines, with a legend,
+// the time on the horizontal axis and the values on the vertical axis. NaN values break the lines.
+func writeComparisonSVG(w io.Writer, title string, step float64, names []string, series [][]float64) {
+	const width, height = 1200.0, 400.0
+	const left, right, top, bottom = 70.0, 20.0, 40.0, 40.0
+	plotWidth, plotHeight := width-left-right, height-top-bottom
+
+	points, highest := 0, 0.0
+	for _, values := range series {
+		points = max(points, len(values))
+		for _, value := range values {
+			if !math.IsNaN(value) {
+				highest = math.Max(highest, value)
 			}
 		}
-	}()
+	}
+	if highest <= 0 {
+		highest = 1
+	}
+	duration := math.Max(float64(points)*step, step)
+	x := func(t float64) float64 { return left + t/duration*plotWidth }
+	y := func(value float64) float64 { return top + plotHeight - value/highest*plotHeight }
+
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%.0f\" height=\"%.0f\" font-family=\"sans-serif\" font-size=\"12\">\n", width, height)
+	fmt.Fprintf(w, "<rect width=\"%.0f\" height=\"%.0f\" fill=\"white\"/>\n", width, height)
+	fmt.Fprintf(w, "<text x=\"%.0f\" y=\"20\" font-size=\"14\" font-weight=\"bold\">%s</text>\n", left, html.EscapeString(title))
+
+	// Horizontal grid lines with their values, and the time at both ends of the axis
+	for i := 0; i <= 4; i++ {
+		value := highest * float64(i) / 4
+		fmt.Fprintf(w, "<line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\" stroke=\"#DDDDDD\"/>\n", left, y(value), width-right, y(value))
+		fmt.Fprintf(w, "<text x=\"%.1f\" y=\"%.1f\" text-anchor=\"end\">%.1f</text>\n", left-6, y(value)+4, value)
+	}
+	fmt.Fprintf(w, "<text x=\"%.1f\" y=\"%.1f\">%s</text>\n", left, height-bottom+18, formatTimestamp(0))
+	fmt.Fprintf(w, "<text x=\"%.1f\" y=\"%.1f\" text-anchor=\"end\">%s</text>\n", width-right, height-bottom+18, formatTimestamp(duration))
+
+	for i, values := range series {
+		stroke := comparisonColors[i%len(comparisonColors)]
+		var line []string
+		flush := func() {
+			if len(line) > 1 {
+				fmt.Fprintf(w, "<polyline fill=\"none\" stroke=\"%s\" stroke-width=\"1.5\" points=\"%s\"/>\n", stroke, strings.Join(line, " "))
+			}
+			line = line[:0]
+		}
+		for j, value := range values {
+			if math.IsNaN(value) {
+				flush()
+				continue
+			}
+			line = append(line, fmt.Sprintf("%.1f,%.1f", x((float64(j)+0.5)*step), y(value)))
+		}
+		flush()
 
-	// Wait for processing to complete
-	wg.Wait()
+		fmt.Fprintf(w, "<rect x=\"%.1f\" y=\"%.1f\" width=\"12\" height=\"12\" fill=\"%s\"/>\n", width-right-300, top+float64(i)*18, stroke)
+		fmt.Fprintf(w, "<text x=\"%.1f\" y=\"%.1f\">%s</text>\n", width-right-282, top+float64(i)*18+10, html.EscapeString(names[i]))
+	}
+	fmt.Fprintln(w, "</svg>")
+}
 
-	// Final flush to ensure all data is written
-	writer.Flush()
+// checkSync compares the start times, durations and edit lists of the audio and subtitle
+// streams with the primary video stream and attaches the result to the container information.
+// With content set, the offset of every audio stream is also measured from the content;
+// a failure of the content check is reported as a warning.
+func checkSync(filePath string, ffmpegInfo *ffmpeg.FFmpegInfo, info *ffmpeg.ContainerInfo, threshold float64, content bool) error {
+	analyzer, err := ffmpeg.NewSyncAnalyzer(ffmpegInfo, threshold)
+	if err != nil {
+		return fmt.Errorf("failed to create sync analyzer: %w", err)
+	}
+
+	warningStyle := color.New(color.FgYellow)
+	report, err := analyzer.Check(info)
+	if err != nil {
+		warningStyle.Printf("⚠️ Skipping A/V sync check: %v\n", err)
+		return nil
+	}
+	info.Sync = report
+
+	if content {
+		infoStyle := color.New(color.FgCyan)
+		infoStyle.Println("🎬 Matching audio onsets with scene cuts")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+
+		if err := analyzer.AnalyzeContent(ctx, filePath, report); err != nil {
+			warningStyle.Printf("⚠️ Skipping content-based sync check: %v\n", err)
+		}
+	}
 
-	return actualFrameCount, processErr
+	if report.Passed {
+		successStyle := color.New(color.FgGreen)
+		successStyle.Println("✅ A/V sync within threshold")
+	} else {
+		warningStyle.Println("⚠️ A/V sync offsets above threshold, see media info report")
+	}
+	return nil
 }
 
-// setupBitrateCSVFile creates the CSV file and writer for bitrate data.
-func setupBitrateCSVFile(outputDir string) (*os.File, *csv.Writer, error) {
-	// Create the output directory if it doesn't exist
-	err := os.MkdirAll(outputDir, 0755)
+// detectCrop samples the keyframes of every video stream with the cropdetect filter and
+// stores the active picture area on the stream.
+// A stream that cannot be analyzed is reported as a warning and skipped.
+func detectCrop(filePath string, ffmpegInfo *ffmpeg.FFmpegInfo, info *ffmpeg.ContainerInfo) error {
+	analyzer, err := ffmpeg.NewCropAnalyzer(ffmpegInfo)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error creating output directory: %w", err)
+		return fmt.Errorf("failed to create crop analyzer: %w", err)
 	}
 
-	// Define output file path
-	outputPath := filepath.Join(outputDir, "bitrate.csv")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
 
-	// Create CSV file
-	file, err := os.Create(outputPath)
+	infoStyle := color.New(color.FgCyan)
+	warningStyle := color.New(color.FgYellow)
+	successStyle := color.New(color.FgGreen)
+	for i := range info.VideoStreams {
+		stream := &info.VideoStreams[i]
+		if stream.Disposition.AttachedPic {
+			continue
+		}
+		infoStyle.Printf("🔍 Detecting active picture area of video stream #%d\n", i)
+
+		report, err := analyzer.Analyze(ctx, filePath, *stream)
+		if err != nil {
+			warningStyle.Printf("⚠️ Skipping video stream #%d: %v\n", i, err)
+			continue
+		}
+		stream.Crop = report
+		successStyle.Printf("✅ Video stream #%d active image is %s\n", i, formatActiveImage(report))
+	}
+	return nil
+}
+
+// detectScenes finds the scene cuts of the video stream measured by the bitrate analysis and
+// attaches the scenes to the container information. A failure is reported as a warning.
+func detectScenes(filePath string, analyzer *ffmpeg.SceneAnalyzer, info *ffmpeg.ContainerInfo) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	infoStyle := color.New(color.FgCyan)
+	warningStyle := color.New(color.FgYellow)
+
+	// The bitrate analysis measures the first video stream
+	stream := info.VideoStreams[0]
+	if stream.Disposition.AttachedPic {
+		warningStyle.Println("⚠️ Skipping scene detection: the first video stream is an attached picture")
+		return
+	}
+	infoStyle.Println("🎬 Detecting scene cuts of video stream #0")
+
+	report, err := analyzer.Analyze(ctx, filePath, stream)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error creating bitrate CSV file: %w", err)
+		warningStyle.Printf("⚠️ Skipping scene detection: %v\n", err)
+		return
 	}
+	info.Scenes = report
 
-	// Set up CSV writer
-	writer := csv.NewWriter(file)
+	successStyle := color.New(color.FgGreen)
+	successStyle.Printf("✅ Found %d scenes\n", len(report.Scenes))
+}
 
-	// Write CSV header
-	if err := writer.Write([]string{"frame_number", "frame_type", "bitrate"}); err != nil {
-		file.Close()
-		return nil, nil, fmt.Errorf("error writing CSV header: %w", err)
+// detectScanType classifies every video stream with the idet filter and replaces the
+// scan type declared by the container with the detected one.
+// A stream that cannot be analyzed is reported as a warning and skipped.
+func detectScanType(filePath string, ffmpegInfo *ffmpeg.FFmpegInfo, info *ffmpeg.ContainerInfo) error {
+	analyzer, err := ffmpeg.NewScanTypeAnalyzer(ffmpegInfo)
+	if err != nil {
+		return fmt.Errorf("failed to create scan type analyzer: %w", err)
 	}
 
-	return file, writer, nil
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	infoStyle := color.New(color.FgCyan)
+	warningStyle := color.New(color.FgYellow)
+	successStyle := color.New(color.FgGreen)
+	for i := range info.VideoStreams {
+		stream := &info.VideoStreams[i]
+		if stream.Disposition.AttachedPic {
+			continue
+		}
+		infoStyle.Printf("🔍 Detecting scan type of video stream #%d\n", i)
+
+		report, err := analyzer.Analyze(ctx, filePath, *stream)
+		if err != nil {
+			warningStyle.Printf("⚠️ Skipping video stream #%d: %v\n", i, err)
+			continue
+		}
+		report.Apply(stream)
+		successStyle.Printf("✅ Video stream #%d is %s (%.0f%% confidence)\n", i, report.ScanType, report.Confidence*100)
+	}
+	return nil
 }
 
 // getEstimatedFrameCount calculates the estimated frame count for a video file
@@ -1161,6 +5607,128 @@ func writeBBCodeMediaInfoContainerSection(w *tabwriter.Writer, info *ffmpeg.Cont
 			}
 		}
 	}
+
+	// Write ISO-BMFF and Matroska structure checks if available
+	writeBBCodeMediaInfoMP4Structure(w, info.MP4Structure)
+	writeBBCodeMediaInfoMatroskaStructure(w, info.MatroskaStructure)
+	fmt.Fprintln(w)
+}
+
+// writeBBCodeMediaInfoMatroskaStructure writes the EBML structure checks with BBCode
+func writeBBCodeMediaInfoMatroskaStructure(w *tabwriter.Writer, structure *ffmpeg.MatroskaStructure) {
+	if structure == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "\n[b]Structure:[/b]")
+	fmt.Fprintf(w, "  [b]DocType:[/b]\t[color=#FF9900]%s[/color] (version %d)\n", structure.DocType, structure.DocTypeVersion)
+	if structure.MuxingApp != "" {
+		fmt.Fprintf(w, "  [b]Muxing App:[/b]\t[color=#FF9900]%s[/color]\n", structure.MuxingApp)
+	}
+	if structure.WritingApp != "" {
+		fmt.Fprintf(w, "  [b]Writing App:[/b]\t[color=#FF9900]%s[/color]\n", structure.WritingApp)
+	}
+	fmt.Fprintf(w, "  [b]Cue Points:[/b]\t[color=#FF9900]%s[/color]\n", formatMatroskaCues(structure))
+	for _, gap := range structure.CueGaps {
+		fmt.Fprintf(w, "  [b]Cue Gap:[/b]\t[color=#FF9900]%s - %s[/color]\n", formatDuration(gap.Start), formatDuration(gap.End))
+	}
+
+	for _, track := range structure.Tracks {
+		if track.Compression != "" {
+			fmt.Fprintf(w, "  [b]Compression (track %d, %s):[/b]\t[color=#FF9900]%s[/color]\n", track.Number, track.Type, track.Compression)
+		}
+		if len(track.StatisticsTags) > 0 {
+			fmt.Fprintf(w, "  [b]Statistics (track %d, %s):[/b]\t[color=#FF9900]%s[/color]\n", track.Number, track.Type, formatMatroskaStatistics(track))
+		}
+	}
+
+	if len(structure.Issues) == 0 {
+		fmt.Fprintln(w, "  [b]Checks:[/b]\t[color=#00CC00]all passed[/color]")
+		return
+	}
+	for _, issue := range structure.Issues {
+		fmt.Fprintf(w, "  [b]Warning:[/b]\t[color=#FF0000]%s[/color]\n", issue)
+	}
+}
+
+// writeBBCodeMediaInfoMP4Structure writes the ISO-BMFF layout checks with BBCode
+func writeBBCodeMediaInfoMP4Structure(w *tabwriter.Writer, structure *ffmpeg.MP4Structure) {
+	if structure == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "\n[b]Structure:[/b]")
+	fmt.Fprintf(w, "  [b]Brands:[/b]\t[color=#FF9900]%s[/color] (%s)\n", strings.TrimSpace(structure.MajorBrand), strings.Join(structure.CompatibleBrands, ", "))
+	fmt.Fprintf(w, "  [b]Faststart:[/b]\t[color=#FF9900]%s[/color]\n", formatYesNo(structure.FastStart))
+	fmt.Fprintf(w, "  [b]Fragmented:[/b]\t[color=#FF9900]%s[/color]\n", formatYesNo(structure.Fragmented))
+
+	for _, track := range structure.Tracks {
+		if track.EditList != nil {
+			fmt.Fprintf(w, "  [b]Edit List (track %d, %s):[/b]\t[color=#FF9900]%d entries, start shift %+.3f seconds[/color]\n",
+				track.TrackID, track.HandlerType, track.EditList.EntryCount, track.EditList.StartShift)
+		}
+	}
+
+	if len(structure.Issues) == 0 {
+		fmt.Fprintln(w, "  [b]Checks:[/b]\t[color=#00CC00]all passed[/color]")
+		return
+	}
+	for _, issue := range structure.Issues {
+		fmt.Fprintf(w, "  [b]Warning:[/b]\t[color=#FF0000]%s[/color]\n", issue)
+	}
+}
+
+// writeBBCodeMediaInfoIntegrity writes the decode integrity verdict and the first errors found with BBCode
+func writeBBCodeMediaInfoIntegrity(w *tabwriter.Writer, report *ffmpeg.IntegrityReport) {
+	if report == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "[b][size=16][color=#3399FF]===========================================[/color][/size][/b]")
+	fmt.Fprintln(w, "[b][color=#3399FF]🩺 [size=100]INTEGRITY[/size][/color][/b]")
+	fmt.Fprintln(w, "[b][size=16][color=#3399FF]===========================================[/color][/size][/b]")
+
+	fmt.Fprintf(w, "\n[b]Verdict:[/b]\t%s\n", formatBBCodeIntegrity(report.Healthy()))
+	fmt.Fprintf(w, "[b]Decode Errors:[/b]\t%s\n", formatBBCodeCount(report.Count(ffmpeg.IntegrityDecodeError)))
+	fmt.Fprintf(w, "[b]Concealments:[/b]\t%s\n", formatBBCodeCount(report.Count(ffmpeg.IntegrityConcealment)))
+	fmt.Fprintf(w, "[b]Missing References:[/b]\t%s\n", formatBBCodeCount(report.Count(ffmpeg.IntegrityMissingReference)))
+	fmt.Fprintf(w, "[b]Truncations:[/b]\t%s\n", formatBBCodeCount(report.Count(ffmpeg.IntegrityTruncated)))
+	fmt.Fprintf(w, "[b]Container Errors:[/b]\t%s\n", formatBBCodeCount(report.Count(ffmpeg.IntegrityContainerError)))
+
+	for _, stream := range report.Streams {
+		fmt.Fprintf(w, "  [b]Stream #%d (%s, %s):[/b]\t[color=#FF9900]%s[/color]\n", stream.StreamIndex, stream.Type, stream.Format, formatIntegrityStream(stream))
+	}
+
+	// Only the first errors are listed, integrity.csv has all of them
+	const listed = 10
+	if len(report.Events) > 0 {
+		fmt.Fprintln(w, "\n[b]First Errors:[/b]")
+		for _, event := range report.Events[:min(listed, len(report.Events))] {
+			fmt.Fprintf(w, "  [color=#FF0000]%s[/color]\n", formatIntegrityEvent(event))
+		}
+		if len(report.Events) > listed {
+			fmt.Fprintf(w, "  ... and %d more\n", len(report.Events)-listed)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// writeBBCodeMediaInfoChecksums writes the file checksum and the content hashes of the decoded streams with BBCode
+func writeBBCodeMediaInfoChecksums(w *tabwriter.Writer, checksums *ffmpeg.FileHash) {
+	if checksums == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "[b][size=16][color=#3399FF]===========================================[/color][/size][/b]")
+	fmt.Fprintln(w, "[b][color=#3399FF]🔏 [size=100]CHECKSUMS[/size][/color][/b]")
+	fmt.Fprintln(w, "[b][size=16][color=#3399FF]===========================================[/color][/size][/b]")
+
+	fmt.Fprintf(w, "\n[b]SHA-256:[/b]\t[color=#FF9900]%s[/color]\n", checksums.SHA256)
+	fmt.Fprintf(w, "[b]Content Hash:[/b]\t[color=#FF9900]%s[/color]\n", checksums.ContentHash)
+	for _, stream := range checksums.Streams {
+		fmt.Fprintf(w, "  [b]Stream #%d (%s):[/b]\t[color=#FF9900]%d frames, %s[/color]\n",
+			stream.StreamIndex, stream.Type, stream.Frames, stream.ContentHash)
+	}
 	fmt.Fprintln(w)
 }
 
@@ -1199,7 +5767,21 @@ func writeBBCodeMediaInfoVideoStreams(w *tabwriter.Writer, streams []ffmpeg.Vide
 		if stream.DisplayAspectRatio > 0 {
 			fmt.Fprintf(w, "  [b]Aspect Ratio:[/b]\t[color=#FF9900]%.3f[/color]\n", stream.DisplayAspectRatio)
 		}
+		if stream.Crop != nil {
+			fmt.Fprintf(w, "  [b]Active Image:[/b]\t[color=#FF9900]%s[/color]\n", formatActiveImage(stream.Crop))
+			fmt.Fprintf(w, "  [b]Recommended Crop:[/b]\t[color=#FF9900]%s[/color]\n", stream.Crop.Crop.Filter())
+			if len(stream.Crop.Segments) > 1 {
+				fmt.Fprintln(w, "  [b]Crop Segments:[/b]")
+				for _, segment := range stream.Crop.Segments {
+					fmt.Fprintf(w, "    [color=#FF9900]%s[/color]\n", formatCropSegment(segment))
+				}
+			}
+		}
 		fmt.Fprintf(w, "  [b]Frame Rate:[/b]\t[color=#FF9900]%.3f fps[/color]\n", stream.FrameRate)
+		if stream.Cadence != nil {
+			fmt.Fprintf(w, "  [b]Unique Frame Rate:[/b]\t[color=#FF9900]%s[/color]\n", formatCadence(stream.Cadence))
+			writeBBCodeMediaInfoCadenceSegments(w, stream.Cadence)
+		}
 
 		// Handle bitrate display with calculation if missing
 		videoBitrate := stream.BitRate
@@ -1221,16 +5803,62 @@ func writeBBCodeMediaInfoVideoStreams(w *tabwriter.Writer, streams []ffmpeg.Vide
 		}
 
 		if stream.ScanType != "" {
-			fmt.Fprintf(w, "  [b]Scan Type:[/b]\t[color=#FF9900]%s[/color]\n", stream.ScanType)
+			fmt.Fprintf(w, "  [b]Scan Type:[/b]\t[color=#FF9900]%s[/color]\n", formatScanType(stream))
+		}
+
+		if stream.ScanAnalysis != nil && len(stream.ScanAnalysis.Segments) > 1 {
+			fmt.Fprintln(w, "  [b]Scan Segments:[/b]")
+			for _, segment := range stream.ScanAnalysis.Segments {
+				fmt.Fprintf(w, "    [color=#FF9900]%s[/color]\n", formatScanTypeSegment(segment))
+			}
 		}
 
 		if stream.Language != "" {
 			fmt.Fprintf(w, "  [b]Language:[/b]\t[color=#FF9900]%s[/color]\n", stream.Language)
 		}
+
+		fmt.Fprintf(w, "  [b]Disposition:[/b]\t[color=#FF9900]%s[/color]\n", formatDisposition(stream.Disposition))
+
+		if stream.Events != nil {
+			fmt.Fprintf(w, "  [b]Black Segments:[/b]\t%s\n", formatBBCodeVideoEvents(stream.Events, ffmpeg.VideoEventBlack))
+			fmt.Fprintf(w, "  [b]Blank Frames:[/b]\t%s\n", formatBBCodeVideoEvents(stream.Events, ffmpeg.VideoEventBlankFrames))
+			fmt.Fprintf(w, "  [b]Frozen Segments:[/b]\t%s\n", formatBBCodeVideoEvents(stream.Events, ffmpeg.VideoEventFreeze))
+			if stream.Events.IgnoreEdges {
+				fmt.Fprintln(w, "  [b]Leading/Trailing Black:[/b]\t[color=#FF9900]ignored[/color]")
+			}
+		}
+
+		if stream.Artifacts != nil {
+			fmt.Fprintf(w, "  [b]Artifact Score:[/b]\t[color=#FF9900]%s[/color]\n", formatArtifactScore(stream.Artifacts))
+			fmt.Fprintf(w, "  [b]Blockiness:[/b]\t[color=#FF9900]%.2f average, %.2f max[/color]\n",
+				stream.Artifacts.AverageBlockiness, stream.Artifacts.MaxBlockiness)
+			fmt.Fprintf(w, "  [b]Blur:[/b]\t[color=#FF9900]%.2f average, %.2f max[/color]\n",
+				stream.Artifacts.AverageBlur, stream.Artifacts.MaxBlur)
+			fmt.Fprintf(w, "  [b]Banding:[/b]\t[color=#FF9900]%.2f%% average, %.2f%% max[/color]\n",
+				stream.Artifacts.AverageBanding, stream.Artifacts.MaxBanding)
+		}
 	}
 	fmt.Fprintln(w)
 }
 
+// writeBBCodeMediaInfoCadenceSegments writes the segments of a video stream with duplicate or dropped frames with BBCode
+func writeBBCodeMediaInfoCadenceSegments(w *tabwriter.Writer, report *ffmpeg.CadenceReport) {
+	segments := report.IrregularSegments()
+	if len(segments) == 0 {
+		return
+	}
+
+	// Only the first segments are listed, cadence.csv has all of them
+	const listed = 10
+	fmt.Fprintln(w, "  [b]Cadence Segments:[/b]")
+	for _, segment := range segments[:min(listed, len(segments))] {
+		fmt.Fprintf(w, "    [color=#FF0000]%s[/color]\n", formatCadenceSegment(segment))
+	}
+	if len(segments) > listed {
+		fmt.Fprintf(w, "    ... and %d more\n", len(segments)-listed)
+	}
+}
+
 // writeBBCodeMediaInfoAudioStreams writes audio stream information with BBCode
 func writeBBCodeMediaInfoAudioStreams(w *tabwriter.Writer, streams []ffmpeg.AudioStream) {
 	if len(streams) == 0 {
@@ -1264,6 +5892,25 @@ func writeBBCodeMediaInfoAudioStreams(w *tabwriter.Writer, streams []ffmpeg.Audi
 		if stream.Language != "" {
 			fmt.Fprintf(w, "  [b]Language:[/b]\t[color=#FF9900]%s[/color]\n", stream.Language)
 		}
+
+		fmt.Fprintf(w, "  [b]Disposition:[/b]\t[color=#FF9900]%s[/color]\n", formatDisposition(stream.Disposition))
+
+		if loudness := stream.Loudness; loudness != nil {
+			fmt.Fprintf(w, "  [b]Loudness:[/b]\t[color=#FF9900]%.1f LUFS[/color] (%s target %.1f ±%.1f LU: %s)\n",
+				loudness.IntegratedLoudness, loudness.Target.Name, loudness.Target.IntegratedLoudness,
+				loudness.Target.Tolerance, formatBBCodePassFail(loudness.IntegratedPass))
+			fmt.Fprintf(w, "  [b]Loudness Range:[/b]\t[color=#FF9900]%.1f LU[/color]\n", loudness.LoudnessRange)
+			fmt.Fprintf(w, "  [b]True Peak:[/b]\t[color=#FF9900]%.1f dBTP[/color] (max %.1f dBTP: %s)\n",
+				loudness.TruePeak, loudness.Target.MaxTruePeak, formatBBCodePassFail(loudness.TruePeakPass))
+		}
+
+		if stream.QC != nil {
+			qcColor := "#00CC00"
+			if len(stream.QC.Events) > 0 {
+				qcColor = "#FF0000"
+			}
+			fmt.Fprintf(w, "  [b]Audio QC:[/b]\t[color=%s]%s[/color]\n", qcColor, formatAudioQC(stream.QC))
+		}
 	}
 	fmt.Fprintln(w)
 }
@@ -1289,6 +5936,160 @@ func writeBBCodeMediaInfoSubtitleStreams(w *tabwriter.Writer, streams []ffmpeg.S
 		if stream.Language != "" {
 			fmt.Fprintf(w, "  [b]Language:[/b]\t[color=#FF9900]%s[/color]\n", stream.Language)
 		}
+
+		fmt.Fprintf(w, "  [b]Disposition:[/b]\t[color=#FF9900]%s[/color]\n", formatDisposition(stream.Disposition))
+	}
+	fmt.Fprintln(w)
+}
+
+// writeBBCodeMediaInfoSubtitleAnalysis writes the event analysis summary of the subtitle streams with BBCode
+func writeBBCodeMediaInfoSubtitleAnalysis(w *tabwriter.Writer, streams []ffmpeg.SubtitleStream) {
+	if !hasSubtitleAnalysis(streams) {
+		return
+	}
+
+	fmt.Fprintln(w, "[b][size=16][color=#3399FF]===========================================[/color][/size][/b]")
+	fmt.Fprintln(w, "[b][color=#3399FF]🔎 [size=100]SUBTITLE ANALYSIS[/size][/color][/b]")
+	fmt.Fprintln(w, "[b][size=16][color=#3399FF]===========================================[/color][/size][/b]")
+
+	for i, stream := range streams {
+		report := stream.Analysis
+		if report == nil {
+			continue
+		}
+
+		fmt.Fprintf(w, "\n[b][color=#3399FF]Stream #%d (%s):[/color][/b]\n", i, formatSubtitleStreamName(stream))
+		fmt.Fprintf(w, "  [b]Events:[/b]\t[color=#FF9900]%s[/color]\n", formatSubtitleEvents(report))
+		if report.EventCount == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "  [b]Overlapping Cues:[/b]\t%s\n", formatBBCodeCount(report.OverlapCount))
+		if !report.Bitmap {
+			fmt.Fprintf(w, "  [b]Reading Speed Violations:[/b]\t%s\n", formatBBCodeCount(report.ReadingSpeedViolations))
+		}
+		fmt.Fprintf(w, "  [b]Cues Beyond Video End:[/b]\t%s\n", formatBBCodeCount(report.BeyondVideoCount))
+	}
+	fmt.Fprintln(w)
+}
+
+// writeBBCodeMediaInfoScenes writes the scene summary and the scenes starved of bits with BBCode
+func writeBBCodeMediaInfoScenes(w *tabwriter.Writer, report *ffmpeg.SceneReport) {
+	if report == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "[b][size=16][color=#3399FF]===========================================[/color][/size][/b]")
+	fmt.Fprintln(w, "[b][color=#3399FF]🎬 [size=100]SCENES[/size][/color][/b]")
+	fmt.Fprintln(w, "[b][size=16][color=#3399FF]===========================================[/color][/size][/b]")
+
+	aligned, cuts := report.KeyframesAtCuts()
+	fmt.Fprintf(w, "\n[b]Scenes:[/b]\t[color=#FF9900]%d (threshold %g)[/color]\n", len(report.Scenes), report.Threshold)
+	fmt.Fprintf(w, "[b]Average Scene Duration:[/b]\t[color=#FF9900]%s[/color]\n", formatDuration(report.AverageDuration()))
+	fmt.Fprintf(w, "[b]Average Bit Rate:[/b]\t[color=#FF9900]%.2f Kbps[/color]\n", report.AverageBitrate/1000)
+	alignedColor := "#00CC00"
+	if aligned < cuts {
+		alignedColor = "#FF0000"
+	}
+	fmt.Fprintf(w, "[b]Keyframes at Cuts:[/b]\t[color=%s]%d of %d[/color]\n", alignedColor, aligned, cuts)
+	fmt.Fprintf(w, "[b]Starved Scenes:[/b]\t%s\n", formatBBCodeCount(report.StarvedScenes()))
+
+	for _, scene := range report.Scenes {
+		if scene.Starved {
+			fmt.Fprintf(w, "  [color=#FF0000]%s[/color]\n", formatScene(scene, report.AverageBitrate))
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// writeBBCodeMediaInfoKeyframes writes the keyframe alignment of the chapter starts and scene cuts with BBCode
+func writeBBCodeMediaInfoKeyframes(w *tabwriter.Writer, report *ffmpeg.KeyframeReport) {
+	if report == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "[b][size=16][color=#3399FF]===========================================[/color][/size][/b]")
+	fmt.Fprintln(w, "[b][color=#3399FF]🔑 [size=100]KEYFRAME ALIGNMENT[/size][/color][/b]")
+	fmt.Fprintln(w, "[b][size=16][color=#3399FF]===========================================[/color][/size][/b]")
+
+	fmt.Fprintf(w, "\n[b]Keyframes:[/b]\t[color=#FF9900]%d[/color]\n", report.Keyframes())
+	if len(report.Chapters) > 0 {
+		fmt.Fprintf(w, "[b]Misaligned Chapters:[/b]\t%s\n", formatBBCodeCount(report.MisalignedChapters()))
+		for _, chapter := range report.Chapters {
+			if !chapter.Aligned {
+				fmt.Fprintf(w, "  [color=#FF0000]Chapter #%d\t%s[/color]\n", chapter.Index, formatKeyframeAlignment(chapter))
+			}
+		}
+	}
+	if len(report.SceneCuts) > 0 {
+		fmt.Fprintf(w, "[b]Misaligned Scene Cuts:[/b]\t%s\n", formatBBCodeCount(report.MisalignedSceneCuts()))
+		if worst, found := report.MaxSceneCutOffset(); found {
+			fmt.Fprintf(w, "[b]Largest Scene Cut Offset:[/b]\t[color=#FF0000]Scene #%d, %s[/color]\n", worst.Index, formatKeyframeAlignment(worst))
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// writeBBCodeMediaInfoTimestamps writes the timestamp anomalies of the primary video stream with BBCode
+func writeBBCodeMediaInfoTimestamps(w *tabwriter.Writer, report *ffmpeg.TimestampReport) {
+	if report == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "[b][size=16][color=#3399FF]===========================================[/color][/size][/b]")
+	fmt.Fprintln(w, "[b][color=#3399FF]⏱️ [size=100]TIMESTAMPS[/size][/color][/b]")
+	fmt.Fprintln(w, "[b][size=16][color=#3399FF]===========================================[/color][/size][/b]")
+
+	fmt.Fprintf(w, "\n[b]Frames Checked:[/b]\t[color=#FF9900]%d[/color]\n", report.Frames)
+	fmt.Fprintf(w, "[b]Errors:[/b]\t%s\n", formatBBCodeCount(report.CountSeverity(ffmpeg.SeverityError)))
+	fmt.Fprintf(w, "[b]Warnings:[/b]\t%s\n", formatBBCodeCount(report.CountSeverity(ffmpeg.SeverityWarning)))
+	for _, anomalyType := range timestampAnomalyTypes {
+		if count := report.Count(anomalyType); count > 0 {
+			fmt.Fprintf(w, "  [b]%s:[/b]\t[color=#FF0000]%d[/color]\n", strings.ReplaceAll(anomalyType, "_", " "), count)
+		}
+	}
+
+	// Only the first anomalies are listed, timestamps.csv has all of them
+	const listed = 10
+	for _, anomaly := range report.Anomalies[:min(listed, len(report.Anomalies))] {
+		fmt.Fprintf(w, "  [color=#FF0000]Frame #%d\t%s[/color]\n", anomaly.FrameNumber, formatTimestampAnomaly(anomaly))
+	}
+	if len(report.Anomalies) > listed {
+		fmt.Fprintf(w, "  ... and %d more\n", len(report.Anomalies)-listed)
+	}
+	fmt.Fprintln(w)
+}
+
+// writeBBCodeMediaInfoSync writes the A/V sync check of the audio and subtitle streams with BBCode
+func writeBBCodeMediaInfoSync(w *tabwriter.Writer, report *ffmpeg.SyncReport) {
+	if report == nil {
+		return
+	}
+
+	fmt.Fprintln(w, "[b][size=16][color=#3399FF]===========================================[/color][/size][/b]")
+	fmt.Fprintln(w, "[b][color=#3399FF]⏱️ [size=100]SYNC CHECK[/size][/color][/b]")
+	fmt.Fprintln(w, "[b][size=16][color=#3399FF]===========================================[/color][/size][/b]")
+
+	fmt.Fprintf(w, "\n[b]Reference:[/b]\t[color=#FF9900]Video stream index %d[/color] (start %.3f s, duration %s)\n",
+		report.VideoStreamIndex, report.VideoStartTime, formatDuration(report.VideoDuration))
+	fmt.Fprintf(w, "[b]Threshold:[/b]\t[color=#FF9900]%.0f ms[/color]\n", report.Threshold*1000)
+	if report.ContentChecked {
+		fmt.Fprintf(w, "[b]Scene Cuts:[/b]\t[color=#FF9900]%d[/color]\n", report.SceneCuts)
+	}
+	fmt.Fprintf(w, "[b]Result:[/b]\t%s\n", formatBBCodePassFail(report.Passed))
+
+	for _, stream := range report.Streams {
+		fmt.Fprintf(w, "\n[b][color=#3399FF]%s:[/color][/b]\n", formatSyncStreamName(stream))
+		fmt.Fprintf(w, "  [b]Start Offset:[/b]\t[color=#FF9900]%s[/color]\n", formatSyncOffset(stream.StartDelta))
+		if stream.DurationDelta != 0 {
+			fmt.Fprintf(w, "  [b]Duration Delta:[/b]\t[color=#FF9900]%+.3f s[/color]\n", stream.DurationDelta)
+		}
+		if stream.EditListDelta != 0 {
+			fmt.Fprintf(w, "  [b]Edit List Offset:[/b]\t[color=#FF9900]%s[/color]\n", formatSyncOffset(stream.EditListDelta))
+		}
+		if report.ContentChecked && stream.Type == "audio" {
+			fmt.Fprintf(w, "  [b]Content Offset:[/b]\t[color=#FF9900]%s[/color]\n", formatSyncContentOffset(stream))
+		}
+		fmt.Fprintf(w, "  [b]Status:[/b]\t%s\n", formatBBCodePassFail(!stream.OutOfSync))
 	}
 	fmt.Fprintln(w)
 }
@@ -1361,6 +6162,10 @@ func writeBBCodeMediaInfoAttachments(w *tabwriter.Writer, attachments []ffmpeg.A
 		if attachment.MimeType != "" {
 			fmt.Fprintf(w, "  [b]MIME Type:[/b]\t[color=#FF9900]%s[/color]\n", attachment.MimeType)
 		}
+		if attachment.SHA256 != "" {
+			fmt.Fprintf(w, "  [b]Size:[/b]\t[color=#FF9900]%s[/color]\n", formatHumanReadableSize(int(attachment.Size)))
+			fmt.Fprintf(w, "  [b]SHA-256:[/b]\t[color=#FF9900]%s[/color]\n", attachment.SHA256)
+		}
 	}
 	fmt.Fprintln(w)
 }
@@ -1418,9 +6223,16 @@ func saveMediaInfoBBCode(info *ffmpeg.ContainerInfo, outputDir string, prober *f
 	writeBBCodeMediaInfoHeader(w, containerTitle, fileName, videoCount, audioCount, subtitleCount)
 	writeBBCodeMediaInfoBasicData(w, info)
 	writeBBCodeMediaInfoContainerSection(w, info)
+	writeBBCodeMediaInfoIntegrity(w, info.Integrity)
+	writeBBCodeMediaInfoChecksums(w, info.Checksums)
 	writeBBCodeMediaInfoVideoStreams(w, info.VideoStreams, info)
 	writeBBCodeMediaInfoAudioStreams(w, info.AudioStreams)
 	writeBBCodeMediaInfoSubtitleStreams(w, info.SubtitleStreams)
+	writeBBCodeMediaInfoSubtitleAnalysis(w, info.SubtitleStreams)
+	writeBBCodeMediaInfoSync(w, info.Sync)
+	writeBBCodeMediaInfoScenes(w, info.Scenes)
+	writeBBCodeMediaInfoKeyframes(w, info.Keyframes)
+	writeBBCodeMediaInfoTimestamps(w, info.Timestamps)
 	writeBBCodeMediaInfoChapters(w, info.ChapterStreams)
 	writeBBCodeMediaInfoAttachments(w, info.AttachmentStreams)
 	writeBBCodeMediaInfoFooter(w)
@@ -1436,3 +6248,502 @@ func saveMediaInfoBBCode(info *ffmpeg.ContainerInfo, outputDir string, prober *f
 
 	return nil
 }
+
+// Private methods (alphabetical)
+
+// enqueue creates a job for a file and queues it. It fails when the queue is full.
+func (s *jobServer) enqueue(path string, uploaded bool) (*analysisJob, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("error creating job identifier: %w", err)
+	}
+	job := &analysisJob{
+		ID:       hex.EncodeToString(id),
+		Path:     path,
+		Uploaded: uploaded,
+		Status:   jobStatusQueued,
+		Created:  time.Now(),
+		changed:  make(chan struct{}),
+	}
+	job.outputDir = filepath.Join(s.dir, job.ID)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	select {
+	case s.queue <- job:
+	default:
+		return nil, fmt.Errorf("job queue is full (%d jobs waiting)", cap(s.queue))
+	}
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job)
+	return job, nil
+}
+
+// handleCreate queues a job for a file on the server, given as {"path": "..."}, or for a file
+// uploaded as the "file" field of a multipart form. Files given by path must be inside one of
+// the root directories of the server, and uploads larger than its limit are rejected.
+func (s *jobServer) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var path string
+	uploaded := strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data")
+	if uploaded {
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxUpload)
+		var err error
+		if path, err = s.receiveUpload(r); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("upload exceeds %d bytes", tooLarge.Limit))
+				return
+			}
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	} else {
+		var request struct {
+			Path string `json:"path"`
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Path == "" {
+			writeJSONError(w, http.StatusBadRequest, `expected {"path": "..."} or a multipart upload`)
+			return
+		}
+		var status int
+		var err error
+		if path, status, err = s.resolvePath(request.Path); err != nil {
+			writeJSONError(w, status, err.Error())
+			return
+		}
+	}
+
+	job, err := s.enqueue(path, uploaded)
+	if err != nil {
+		if uploaded {
+			os.Remove(path)
+		}
+		w.Header().Set("Retry-After", "60")
+		writeJSONError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	color.New(color.FgCyan).Printf("📥 Job %s queued for %s\n", job.ID, path)
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	writeJSONResponse(w, http.StatusAccepted, s.snapshot(job))
+}
+
+// handleDelete removes a finished job with its reports. Queued and running jobs cannot be removed.
+func (s *jobServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	job := s.lookup(w, r)
+	if job == nil {
+		return
+	}
+	s.mutex.Lock()
+	if job.Finished == nil {
+		s.mutex.Unlock()
+		writeJSONError(w, http.StatusConflict, "job is "+job.Status)
+		return
+	}
+	delete(s.jobs, job.ID)
+	s.order = slices.DeleteFunc(s.order, func(other *analysisJob) bool { return other == job })
+	s.mutex.Unlock()
+
+	s.removeFiles(job)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleEvents streams the frames measured by a job as server-sent events: "frame" events with
+// the size and time of every frame, "qp" events with the QP of every frame and "status" events
+// when the status of the job changes. Frames measured before the client connected are sent first.
+// The stream ends with the final status of the job.
+func (s *jobServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	job := s.lookup(w, r)
+	if job == nil {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	sentFrames, sentQP, sentStatus := 0, 0, ""
+	for {
+		// Frames are only appended, so the slices stay valid after unlocking
+		s.mutex.Lock()
+		frames, qp := job.frames[sentFrames:], job.qp[sentQP:]
+		status := *job
+		changed := job.changed
+		s.mutex.Unlock()
+
+		for _, frame := range frames {
+			if err := writeServerEvent(w, "frame", frame); err != nil {
+				return
+			}
+		}
+		for _, frame := range qp {
+			if err := writeServerEvent(w, "qp", frame); err != nil {
+				return
+			}
+		}
+		sentFrames, sentQP = sentFrames+len(frames), sentQP+len(qp)
+
+		if status.Status != sentStatus {
+			if err := writeServerEvent(w, "status", status); err != nil {
+				return
+			}
+			sentStatus = status.Status
+		}
+		flusher.Flush()
+
+		if status.Status == jobStatusDone || status.Status == jobStatusFailed {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleList lists every job in the order they were queued.
+func (s *jobServer) handleList(w http.ResponseWriter, _ *http.Request) {
+	s.mutex.Lock()
+	jobs := make([]analysisJob, len(s.order))
+	for i, job := range s.order {
+		jobs[i] = *job
+	}
+	s.mutex.Unlock()
+	writeJSONResponse(w, http.StatusOK, jobs)
+}
+
+// handleResult serves the results of a completed job in the format given by the format query
+// parameter: json (default) for the job and the analysis, csv for the frames, text or bbcode for
+// the media info reports.
+func (s *jobServer) handleResult(w http.ResponseWriter, r *http.Request) {
+	job := s.lookup(w, r)
+	if job == nil {
+		return
+	}
+	status := s.snapshot(job)
+	switch status.Status {
+	case jobStatusDone:
+	case jobStatusFailed:
+		writeJSONError(w, http.StatusConflict, "job failed: "+status.Error)
+		return
+	default:
+		writeJSONError(w, http.StatusConflict, "job is "+status.Status)
+		return
+	}
+
+	// A completed job does not change anymore
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		writeJSONResponse(w, http.StatusOK, struct {
+			Job  analysisJob           `json:"job"`
+			Info *ffmpeg.ContainerInfo `json:"info"`
+		}{status, job.info})
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="bitrate.csv"`)
+		if err := writeJobCSV(w, job.frames, job.qp); err != nil {
+			color.New(color.FgYellow).Printf("⚠️ Error sending the CSV of job %s: %v\n", job.ID, err)
+		}
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		http.ServeFile(w, r, filepath.Join(job.outputDir, "mediainfo.txt"))
+	case "bbcode":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		http.ServeFile(w, r, filepath.Join(job.outputDir, "mediainfo.bbcode.txt"))
+	default:
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("unknown format %q (available: json, csv, text, bbcode)", format))
+	}
+}
+
+// handleStatus serves the status and progress of a job.
+func (s *jobServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if job := s.lookup(w, r); job != nil {
+		writeJSONResponse(w, http.StatusOK, s.snapshot(job))
+	}
+}
+
+// handler returns the routes of the HTTP API.
+func (s *jobServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.handleCreate)
+	mux.HandleFunc("GET /jobs", s.handleList)
+	mux.HandleFunc("GET /jobs/{id}", s.handleStatus)
+	mux.HandleFunc("DELETE /jobs/{id}", s.handleDelete)
+	mux.HandleFunc("GET /jobs/{id}/events", s.handleEvents)
+	mux.HandleFunc("GET /jobs/{id}/result", s.handleResult)
+	return mux
+}
+
+// lookup returns the job named by the request path, or writes a 404 response and returns nil.
+func (s *jobServer) lookup(w http.ResponseWriter, r *http.Request) *analysisJob {
+	s.mutex.Lock()
+	job := s.jobs[r.PathValue("id")]
+	s.mutex.Unlock()
+	if job == nil {
+		writeJSONError(w, http.StatusNotFound, "unknown job")
+	}
+	return job
+}
+
+// prune removes the jobs finished for longer than the retention time, with their reports, so a
+// long running server does not keep every job in memory and on disk.
+func (s *jobServer) prune(now time.Time) {
+	var expired []*analysisJob
+	s.mutex.Lock()
+	s.order = slices.DeleteFunc(s.order, func(job *analysisJob) bool {
+		if job.Finished == nil || now.Sub(*job.Finished) < s.retention {
+			return false
+		}
+		delete(s.jobs, job.ID)
+		expired = append(expired, job)
+		return true
+	})
+	s.mutex.Unlock()
+
+	for _, job := range expired {
+		s.removeFiles(job)
+	}
+}
+
+// receiveUpload saves the "file" field of a multipart form in the uploads directory and returns its path.
+// The file is streamed to disk, so uploads are not limited by memory.
+func (s *jobServer) receiveUpload(r *http.Request) (string, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return "", fmt.Errorf("error reading upload: %w", err)
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("missing file field")
+		}
+		if err != nil {
+			return "", fmt.Errorf("error reading upload: %w", err)
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		uploadDir := filepath.Join(s.dir, "uploads")
+		if err := os.MkdirAll(uploadDir, 0755); err != nil {
+			return "", fmt.Errorf("error creating upload directory: %w", err)
+		}
+		name := strings.ReplaceAll(filepath.Base(part.FileName()), "*", "_")
+		if name == "." || name == string(filepath.Separator) {
+			name = "upload"
+		}
+		file, err := os.CreateTemp(uploadDir, "*-"+name)
+		if err != nil {
+			return "", fmt.Errorf("error creating upload file: %w", err)
+		}
+		_, err = io.Copy(file, part)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(file.Name())
+			return "", fmt.Errorf("error saving upload: %w", err)
+		}
+		return file.Name(), nil
+	}
+}
+
+// removeFiles deletes the reports of a job and its uploaded file.
+func (s *jobServer) removeFiles(job *analysisJob) {
+	if err := os.RemoveAll(job.outputDir); err != nil {
+		color.New(color.FgYellow).Printf("⚠️ Error removing the reports of job %s: %v\n", job.ID, err)
+	}
+	if job.Uploaded {
+		if err := os.Remove(job.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			color.New(color.FgYellow).Printf("⚠️ Error removing the upload of job %s: %v\n", job.ID, err)
+		}
+	}
+}
+
+// resolvePath returns the file named by a job request with its symbolic links resolved, or the
+// HTTP status and the error rejecting it. The path, and the file it links to, must be inside a
+// root directory, so clients cannot read any file of the server.
+func (s *jobServer) resolvePath(requested string) (string, int, error) {
+	inRoot := func(path string) bool {
+		for _, root := range s.roots {
+			rel, err := filepath.Rel(root, path)
+			if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(s.roots) == 0 {
+		return "", http.StatusForbidden, fmt.Errorf("analyses by path are disabled, start the server with --root")
+	}
+	absPath, err := filepath.Abs(requested)
+	if err != nil {
+		return "", http.StatusBadRequest, err
+	}
+	if !inRoot(absPath) {
+		return "", http.StatusForbidden, fmt.Errorf("%s is outside the root directories", requested)
+	}
+	resolved, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		return "", http.StatusBadRequest, fmt.Errorf("%s is not a readable file", requested)
+	}
+	if !inRoot(resolved) {
+		return "", http.StatusForbidden, fmt.Errorf("%s links outside the root directories", requested)
+	}
+	if fileInfo, err := os.Stat(resolved); err != nil || !fileInfo.Mode().IsRegular() {
+		return "", http.StatusBadRequest, fmt.Errorf("%s is not a readable file", requested)
+	}
+	return resolved, 0, nil
+}
+
+// runJob probes the file of a job, measures the size of every frame, then the QP of every frame
+// when the codec supports it, and writes the media info reports. The bitrate pass counts for the
+// first half of the progress when the QP pass follows. When the context ends during the QP pass,
+// its decode is killed, the pass is abandoned with a warning and the reports are written with the
+// QPs measured so far.
+func (s *jobServer) runJob(ctx context.Context, job *analysisJob) error {
+	info, err := s.prober.GetExtendedContainerInfo(job.Path)
+	if err != nil {
+		return fmt.Errorf("failed to analyze file: %w", err)
+	}
+	s.update(job, func() {
+		job.info = info
+		job.Stage = "bitrate"
+	})
+
+	qualityAnalyzer, qualityErr := ffmpeg.NewQualityAnalyzer(job.Path)
+	bitrateShare := 1.0
+	if qualityErr == nil {
+		bitrateShare = 0.5
+	}
+	estimatedFrames := getVideoDuration(info) * getFrameRate(info)
+
+	if len(info.VideoStreams) > 0 {
+		bitrateAnalyzer, err := ffmpeg.NewBitrateAnalyzer(s.ffmpegInfo)
+		if err != nil {
+			return fmt.Errorf("failed to create bitrate analyzer: %w", err)
+		}
+
+		stats := ffmpeg.NewBitrateStats(getFrameRate(info))
+		frameCh := make(chan ffmpeg.FrameBitrateInfo, 100)
+		errCh := make(chan error, 1)
+		go func() {
+			defer close(frameCh)
+			errCh <- bitrateAnalyzer.Analyze(ctx, job.Path, frameCh)
+		}()
+		for frame := range frameCh {
+			stats.AddFrame(frame)
+			s.update(job, func() {
+				job.frames = append(job.frames, frame)
+				job.Frames = len(job.frames)
+				if estimatedFrames > 0 {
+					job.Progress = math.Min(float64(job.Frames)/estimatedFrames, 1) * bitrateShare
+				}
+			})
+		}
+		if err := <-errCh; err != nil {
+			return fmt.Errorf("error measuring frames: %w", err)
+		}
+		stats.Finish()
+		s.update(job, func() { info.Bitrate = stats })
+	}
+
+	if qualityErr != nil {
+		s.update(job, func() { job.Warnings = append(job.Warnings, "QP not measured: "+qualityErr.Error()) })
+	} else if len(info.VideoStreams) > 0 {
+		s.update(job, func() { job.Stage = "qp" })
+		qualityCh := make(chan ffmpeg.QualityFrame, 100)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- qualityAnalyzer.Analyze(ctx, job.Path, qualityCh)
+		}()
+		for frame := range qualityCh {
+			s.update(job, func() {
+				job.qp = append(job.qp, jobQP{FrameNumber: frame.FrameNumber, QP: frame.QP})
+				if job.Frames > 0 {
+					job.Progress = bitrateShare + (1-bitrateShare)*math.Min(float64(len(job.qp))/float64(job.Frames), 1)
+				}
+			})
+		}
+		// Ending the context kills the decode, the pass is then reported as abandoned
+		if err := <-errCh; ctx.Err() != nil {
+			s.update(job, func() { job.Warnings = append(job.Warnings, "QP pass abandoned: "+ctx.Err().Error()) })
+		} else if err != nil {
+			s.update(job, func() { job.Warnings = append(job.Warnings, "QP not measured: "+err.Error()) })
+		}
+	}
+
+	s.update(job, func() { job.Stage = "reports" })
+	if err := saveMediaInfoText(info, job.outputDir, s.prober); err != nil {
+		return fmt.Errorf("error saving text report: %w", err)
+	}
+	if err := saveMediaInfoBBCode(info, job.outputDir, s.prober); err != nil {
+		return fmt.Errorf("error saving BBCode report: %w", err)
+	}
+	return nil
+}
+
+// snapshot returns a copy of the status of a job.
+func (s *jobServer) snapshot(job *analysisJob) analysisJob {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return *job
+}
+
+// update applies a change to a job under the server lock and wakes its event streams.
+func (s *jobServer) update(job *analysisJob, change func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	change()
+	close(job.changed)
+	job.changed = make(chan struct{})
+}
+
+// work runs queued jobs one at a time until the context ends. Each job gets 30 minutes. The
+// upload of a job is deleted once the job finishes.
+func (s *jobServer) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-s.queue:
+			s.update(job, func() {
+				now := time.Now()
+				job.Status, job.Stage, job.Started = jobStatusRunning, "probe", &now
+			})
+
+			jobCtx, cancel := context.WithTimeout(ctx, 30*time.Minute)
+			err := s.analyze(jobCtx, job)
+			cancel()
+
+			// The results are kept in memory and in the reports, the upload is no longer needed
+			if job.Uploaded {
+				if removeErr := os.Remove(job.Path); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+					color.New(color.FgYellow).Printf("⚠️ Error removing the upload of job %s: %v\n", job.ID, removeErr)
+				}
+			}
+
+			s.update(job, func() {
+				now := time.Now()
+				job.Stage, job.Finished = "", &now
+				if err != nil {
+					job.Status, job.Error = jobStatusFailed, err.Error()
+				} else {
+					job.Status, job.Progress = jobStatusDone, 1
+				}
+			})
+			if err != nil {
+				color.New(color.FgRed).Printf("❌ Job %s failed: %v\n", job.ID, err)
+			} else {
+				color.New(color.FgGreen).Printf("✅ Job %s done\n", job.ID)
+			}
+		}
+	}
+}
@@ -1,13 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"text/tabwriter"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/stretchr/testify/assert"
@@ -95,13 +105,15 @@ func (s *MainTestSuite) SetupTest() {
 				SamplingRate:  48000,
 				BitRate:       192000,
 				Language:      "eng",
+				Disposition:   ffmpeg.StreamDisposition{Default: true},
 			},
 		},
 		SubtitleStreams: []ffmpeg.SubtitleStream{
 			{
-				Format:   "SRT",
-				Language: "eng",
-				Title:    "English",
+				Format:      "SRT",
+				Language:    "eng",
+				Title:       "English",
+				Disposition: ffmpeg.StreamDisposition{Forced: true, HearingImpaired: true},
 			},
 		},
 	}
@@ -574,6 +586,1086 @@ func (s *MainTestSuite) TestWriteBBCodeMediaInfoSections() {
 	assert.Contains(s.T(), footerOutput, "[align=right][color=#666666]")
 }
 
+// TestWriteMediaInfoMP4Structure tests that ISO-BMFF checks are written to the container section.
+func (s *MainTestSuite) TestWriteMediaInfoMP4Structure() {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+
+	// Container without structure information writes nothing extra
+	writeMediaInfoContainerSection(w, s.testContainerInfo)
+	w.Flush()
+	assert.NotContains(s.T(), sb.String(), "Structure:")
+
+	// Container with a non-faststart layout and an audio edit list
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	info := *s.testContainerInfo
+	info.MP4Structure = &ffmpeg.MP4Structure{
+		MajorBrand:       "isom",
+		CompatibleBrands: []string{"isom", "mp41"},
+		Tracks: []ffmpeg.MP4Track{
+			{TrackID: 2, HandlerType: "soun", EditList: &ffmpeg.MP4EditList{EntryCount: 2, StartShift: 0.2}},
+		},
+		Issues: []string{"moov box is located after mdat (not faststart)"},
+	}
+	writeMediaInfoContainerSection(w, &info)
+	w.Flush()
+
+	output := sb.String()
+	assert.Contains(s.T(), output, "Structure:")
+	assert.Contains(s.T(), output, "isom (isom, mp41)")
+	assert.Contains(s.T(), output, "Faststart:")
+	assert.Contains(s.T(), output, "2 entries, start shift +0.200 seconds")
+	assert.Contains(s.T(), output, "moov box is located after mdat (not faststart)")
+}
+
+// TestWriteMediaInfoMatroskaStructure tests that Matroska checks and attachment checksums are reported.
+func (s *MainTestSuite) TestWriteMediaInfoMatroskaStructure() {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+
+	info := *s.testContainerInfo
+	info.MatroskaStructure = &ffmpeg.MatroskaStructure{
+		DocType:             "matroska",
+		DocTypeVersion:      4,
+		WritingApp:          "mkvmerge v70.0.0",
+		CuePointCount:       2,
+		FirstCueTime:        0,
+		LastCueTime:         20,
+		ClusterCount:        3,
+		IndexedClusterCount: 2,
+		CueGaps:             []ffmpeg.MatroskaCueGap{{Start: 2, End: 20}},
+		Tracks: []ffmpeg.MatroskaTrack{{
+			Number:          1,
+			Type:            "video",
+			Compression:     "header stripping",
+			StatisticsTags:  map[string]string{"BPS": "8000", "NUMBER_OF_FRAMES": "250"},
+			StaleStatistics: true,
+		}},
+		Issues: []string{"track 1 statistics tags are stale: NUMBER_OF_FRAMES=250 (measured 200)"},
+	}
+	writeMediaInfoContainerSection(w, &info)
+	writeMediaInfoAttachments(w, []ffmpeg.AttachmentStream{{FileName: "font.ttf", Size: 2048, SHA256: "abc123"}})
+	w.Flush()
+
+	output := sb.String()
+	assert.Contains(s.T(), output, "matroska (version 4)")
+	assert.Contains(s.T(), output, "mkvmerge v70.0.0")
+	assert.Contains(s.T(), output, "2 of 3 clusters indexed")
+	assert.Contains(s.T(), output, "2 seconds - 20 seconds")
+	assert.Contains(s.T(), output, "header stripping")
+	assert.Contains(s.T(), output, "BPS=8000, NUMBER_OF_FRAMES=250, (stale)")
+	assert.Contains(s.T(), output, "statistics tags are stale")
+	assert.Contains(s.T(), output, "abc123")
+}
+
+// TestWriteMediaInfoDisposition tests that stream disposition flags are shown in both report formats.
+func (s *MainTestSuite) TestWriteMediaInfoDisposition() {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+
+	writeMediaInfoVideoStreams(w, s.testContainerInfo.VideoStreams, s.testContainerInfo)
+	writeMediaInfoAudioStreams(w, s.testContainerInfo.AudioStreams)
+	writeMediaInfoSubtitleStreams(w, s.testContainerInfo.SubtitleStreams)
+	w.Flush()
+
+	output := sb.String()
+	assert.Regexp(s.T(), `Disposition:\s+none`, output)
+	assert.Regexp(s.T(), `Disposition:\s+default`, output)
+	assert.Regexp(s.T(), `Disposition:\s+forced, hearing impaired`, output)
+
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeBBCodeMediaInfoAudioStreams(w, s.testContainerInfo.AudioStreams)
+	writeBBCodeMediaInfoSubtitleStreams(w, s.testContainerInfo.SubtitleStreams)
+	w.Flush()
+
+	output = sb.String()
+	assert.Contains(s.T(), output, "[color=#FF9900]default[/color]")
+	assert.Contains(s.T(), output, "[color=#FF9900]forced, hearing impaired[/color]")
+}
+
+// TestWriteMediaInfoSubtitleAnalysis tests the subtitle analysis summary in both report formats.
+func (s *MainTestSuite) TestWriteMediaInfoSubtitleAnalysis() {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+
+	// Streams without analysis write nothing
+	writeMediaInfoSubtitleAnalysis(w, s.testContainerInfo.SubtitleStreams)
+	w.Flush()
+	assert.Empty(s.T(), sb.String())
+
+	streams := []ffmpeg.SubtitleStream{
+		{
+			Format:   "subrip",
+			Language: "eng",
+			Analysis: &ffmpeg.SubtitleReport{
+				EventCount:             120,
+				FirstCueTime:           2.5,
+				LastCueTime:            118,
+				OverlapCount:           3,
+				ReadingSpeedViolations: 7,
+			},
+		},
+		{
+			Format:   "hdmv_pgs_subtitle",
+			Analysis: &ffmpeg.SubtitleReport{Bitmap: true, EventCount: 10, FirstCueTime: 1, LastCueTime: 50, BeyondVideoCount: 1},
+		},
+	}
+
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeMediaInfoSubtitleAnalysis(w, streams)
+	w.Flush()
+
+	output := sb.String()
+	assert.Contains(s.T(), output, "SUBTITLE ANALYSIS")
+	assert.Contains(s.T(), output, "Stream #0 (subrip, eng):")
+	assert.Contains(s.T(), output, "120 (2.500 seconds - 1 minute and 58 seconds)")
+	assert.Regexp(s.T(), `Overlapping Cues:\s+3`, output)
+	assert.Regexp(s.T(), `Reading Speed Violations:\s+7`, output)
+	assert.Contains(s.T(), output, "bitmap format analyzed by timing only")
+	assert.Regexp(s.T(), `Cues Beyond Video End:\s+1`, output)
+
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeBBCodeMediaInfoSubtitleAnalysis(w, streams)
+	w.Flush()
+
+	output = sb.String()
+	assert.Contains(s.T(), output, "[size=100]SUBTITLE ANALYSIS")
+	assert.Contains(s.T(), output, "[color=#FF0000]7[/color]")
+	assert.Contains(s.T(), output, "[color=#00CC00]0[/color]")
+}
+
+// TestGetVideoDuration tests the video duration fallback to the container duration.
+func (s *MainTestSuite) TestGetVideoDuration() {
+	assert.Equal(s.T(), 120.5, getVideoDuration(s.testContainerInfo))
+
+	info := *s.testContainerInfo
+	info.VideoStreams = []ffmpeg.VideoStream{{Duration: 60}}
+	assert.Equal(s.T(), 60.0, getVideoDuration(&info))
+}
+
+// TestWriteMediaInfoLoudness tests that loudness measurements and their checks are reported.
+func (s *MainTestSuite) TestWriteMediaInfoLoudness() {
+	target, err := ffmpeg.LookupLoudnessTarget("ebu-r128")
+	require.NoError(s.T(), err)
+
+	streams := []ffmpeg.AudioStream{{
+		Format:   "AAC",
+		Channels: 2,
+		Loudness: &ffmpeg.LoudnessReport{
+			Target:             target,
+			IntegratedLoudness: -22.8,
+			LoudnessRange:      5.3,
+			TruePeak:           -0.4,
+			IntegratedPass:     true,
+		},
+	}}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeMediaInfoAudioStreams(w, streams)
+	w.Flush()
+
+	output := sb.String()
+	assert.Contains(s.T(), output, "-22.8 LUFS (EBU R128 target -23.0 ±0.5 LU: PASS)")
+	assert.Regexp(s.T(), `Loudness Range:\s+5.3 LU`, output)
+	assert.Contains(s.T(), output, "-0.4 dBTP (max -1.0 dBTP: FAIL)")
+
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeBBCodeMediaInfoAudioStreams(w, streams)
+	w.Flush()
+
+	output = sb.String()
+	assert.Contains(s.T(), output, "[color=#00CC00]PASS[/color]")
+	assert.Contains(s.T(), output, "[color=#FF0000]FAIL[/color]")
+}
+
+// TestWriteMediaInfoAudioQC tests the audio defect summary in both report formats.
+func (s *MainTestSuite) TestWriteMediaInfoAudioQC() {
+	streams := []ffmpeg.AudioStream{
+		{Format: "AAC", QC: &ffmpeg.AudioQCReport{Events: []ffmpeg.AudioQCEvent{
+			{Type: ffmpeg.AudioQCClipping, Channel: 1},
+			{Type: ffmpeg.AudioQCClipping, Channel: 2},
+			{Type: ffmpeg.AudioQCSilentChannel, Channel: 2},
+		}}},
+		{Format: "AC3", QC: &ffmpeg.AudioQCReport{}},
+	}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeMediaInfoAudioStreams(w, streams)
+	w.Flush()
+
+	output := sb.String()
+	assert.Contains(s.T(), output, "clipping (2), silent channel (1)")
+	assert.Contains(s.T(), output, "no defects found")
+
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeBBCodeMediaInfoAudioStreams(w, streams)
+	w.Flush()
+
+	output = sb.String()
+	assert.Contains(s.T(), output, "[color=#FF0000]clipping (2), silent channel (1)[/color]")
+	assert.Contains(s.T(), output, "[color=#00CC00]no defects found[/color]")
+}
+
+// TestWriteMediaInfoSync tests the A/V sync section in both report formats.
+func (s *MainTestSuite) TestWriteMediaInfoSync() {
+	report := &ffmpeg.SyncReport{
+		VideoStreamIndex: 0,
+		VideoDuration:    60,
+		Threshold:        0.04,
+		ContentChecked:   true,
+		SceneCuts:        14,
+		Streams: []ffmpeg.SyncStreamOffset{
+			{StreamIndex: 1, Type: "audio", StartDelta: 0.2, EditListDelta: 0.021, ContentOffset: 0.198, ContentMatches: 9, OutOfSync: true},
+			{StreamIndex: 2, Type: "subtitle", StartDelta: 12.5},
+		},
+	}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeMediaInfoSync(w, report)
+	w.Flush()
+
+	output := sb.String()
+	assert.Contains(s.T(), output, "SYNC CHECK")
+	assert.Regexp(s.T(), `Threshold:\s+40 ms`, output)
+	assert.Regexp(s.T(), `Result:\s+FAIL`, output)
+	assert.Regexp(s.T(), `Start Offset:\s+\+200 ms`, output)
+	assert.Regexp(s.T(), `Edit List Offset:\s+\+21 ms`, output)
+	assert.Regexp(s.T(), `Content Offset:\s+\+198 ms \(9 matching onsets\)`, output)
+	assert.Contains(s.T(), output, "Subtitle stream index 2:")
+
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeBBCodeMediaInfoSync(w, report)
+	w.Flush()
+
+	output = sb.String()
+	assert.Contains(s.T(), output, "[size=100]SYNC CHECK[/size]")
+	assert.Contains(s.T(), output, "[color=#FF9900]+200 ms[/color]")
+
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeMediaInfoSync(w, nil)
+	w.Flush()
+	assert.Empty(s.T(), sb.String())
+}
+
+// TestWriteMediaInfoVideoEvents tests the black and freeze summary of the video streams.
+func (s *MainTestSuite) TestWriteMediaInfoVideoEvents() {
+	streams := []ffmpeg.VideoStream{{
+		Format: "h264",
+		Events: &ffmpeg.VideoEventReport{IgnoreEdges: true, Events: []ffmpeg.VideoEvent{
+			{Type: ffmpeg.VideoEventBlack, Start: 10, End: 11.5},
+			{Type: ffmpeg.VideoEventBlack, Start: 20, End: 21},
+			{Type: ffmpeg.VideoEventFreeze, Start: 30, End: 32.25},
+		}},
+	}}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeMediaInfoVideoStreams(w, streams, s.testContainerInfo)
+	w.Flush()
+
+	output := sb.String()
+	assert.Regexp(s.T(), `Black Segments:\s+2 \(2\.50 s\)`, output)
+	assert.Regexp(s.T(), `Blank Frames:\s+none`, output)
+	assert.Regexp(s.T(), `Frozen Segments:\s+1 \(2\.25 s\)`, output)
+	assert.Regexp(s.T(), `Leading/Trailing Black:\s+ignored`, output)
+
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeBBCodeMediaInfoVideoStreams(w, streams, s.testContainerInfo)
+	w.Flush()
+
+	output = sb.String()
+	assert.Contains(s.T(), output, "[color=#FF0000]2 (2.50 s)[/color]")
+	assert.Contains(s.T(), output, "[color=#00CC00]none[/color]")
+}
+
+// TestWriteMediaInfoScanType tests the detected scan type and its segments in the video section.
+func (s *MainTestSuite) TestWriteMediaInfoScanType() {
+	stream := ffmpeg.VideoStream{Format: "mpeg2video", ScanType: "progressive", FieldOrder: "progressive"}
+	report := &ffmpeg.ScanTypeReport{
+		ScanType:   ffmpeg.ScanTypeInterlacedTFF,
+		Confidence: 0.93,
+		Segments: []ffmpeg.ScanTypeSegment{
+			{Start: 0, End: 20, ScanType: ffmpeg.ScanTypeProgressive, Confidence: 1, Counts: ffmpeg.ScanTypeCounts{Frames: 500}},
+			{Start: 20, End: 3725.5, ScanType: ffmpeg.ScanTypeInterlacedTFF, Confidence: 0.95, Counts: ffmpeg.ScanTypeCounts{Frames: 92637}},
+		},
+	}
+	report.Apply(&stream)
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeMediaInfoVideoStreams(w, []ffmpeg.VideoStream{stream}, s.testContainerInfo)
+	w.Flush()
+
+	output := sb.String()
+	assert.Regexp(s.T(), `Scan Type:\s+interlaced \(TFF\) \(detected, 93% confidence; container: progressive\)`, output)
+	assert.Contains(s.T(), output, "Scan Segments:")
+	assert.Regexp(s.T(), `00:00:20\.000 - 01:02:05\.500\s+interlaced \(TFF\) \(95%, 92637 frames\)`, output)
+
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeBBCodeMediaInfoVideoStreams(w, []ffmpeg.VideoStream{stream}, s.testContainerInfo)
+	w.Flush()
+	assert.Contains(s.T(), sb.String(), "[b]Scan Segments:[/b]")
+}
+
+// TestWriteMediaInfoCrop tests the coded and active resolution and the crop segments in the video section.
+func (s *MainTestSuite) TestWriteMediaInfoCrop() {
+	stream := ffmpeg.VideoStream{Format: "h264", Width: 1920, Height: 1080, DisplayAspectRatio: 16.0 / 9.0}
+	stream.Crop = &ffmpeg.CropReport{
+		CodedWidth:  1920,
+		CodedHeight: 1080,
+		Crop:        ffmpeg.CropRect{Y: 140, Width: 1920, Height: 800},
+		AspectRatio: 2.4,
+		Boxing:      ffmpeg.BoxingLetterbox,
+		Segments: []ffmpeg.CropSegment{
+			{Start: 0, End: 600, Crop: ffmpeg.CropRect{Y: 140, Width: 1920, Height: 800}, AspectRatio: 2.4, Samples: 120},
+			{Start: 600, End: 900, Crop: ffmpeg.CropRect{Y: 140, Width: 1920, Height: 800}, AspectRatio: 2.4, Samples: 60},
+		},
+	}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeMediaInfoVideoStreams(w, []ffmpeg.VideoStream{stream}, s.testContainerInfo)
+	w.Flush()
+
+	output := sb.String()
+	assert.Regexp(s.T(), `Resolution:\s+1920x1080 pixels`, output)
+	assert.Regexp(s.T(), `Active Image:\s+1920x800 \(2\.40:1, letterbox\)`, output)
+	assert.Regexp(s.T(), `Recommended Crop:\s+crop=1920:800:0:140`, output)
+	assert.Regexp(s.T(), `00:10:00\.000 - 00:15:00\.000\s+1920x800 \(2\.40:1, 60 samples\)`, output)
+
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeBBCodeMediaInfoVideoStreams(w, []ffmpeg.VideoStream{stream}, s.testContainerInfo)
+	w.Flush()
+	assert.Contains(s.T(), sb.String(), "[b]Active Image:[/b]")
+}
+
+// TestWriteMediaInfoArtifacts tests the artifact summary in the video section.
+func (s *MainTestSuite) TestWriteMediaInfoArtifacts() {
+	stream := ffmpeg.VideoStream{Format: "h264", Width: 1280, Height: 720}
+	stream.Artifacts = ffmpeg.SummarizeArtifacts([]ffmpeg.ArtifactFrame{
+		{FrameNumber: 0, Blockiness: 1, Blur: 3, Banding: 0.1, Score: 90},
+		{FrameNumber: 1, Blockiness: 5, Blur: 4, Banding: 0.5, Score: 70},
+	})
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeMediaInfoVideoStreams(w, []ffmpeg.VideoStream{stream}, s.testContainerInfo)
+	w.Flush()
+
+	output := sb.String()
+	assert.Regexp(s.T(), `Artifact Score:\s+80\.0 average \(High\), 70\.0 minimum at frame 1`, output)
+	assert.Regexp(s.T(), `Blockiness:\s+3\.00 average, 5\.00 max`, output)
+	assert.Regexp(s.T(), `Banding:\s+0\.30% average, 0\.50% max`, output)
+
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeBBCodeMediaInfoVideoStreams(w, []ffmpeg.VideoStream{stream}, s.testContainerInfo)
+	w.Flush()
+	assert.Contains(s.T(), sb.String(), "[b]Artifact Score:[/b]")
+}
+
+// TestWriteMediaInfoCadence tests the unique frame rate and the listing of irregular segments.
+func (s *MainTestSuite) TestWriteMediaInfoCadence() {
+	stream := ffmpeg.VideoStream{Format: "h264", Width: 1280, Height: 720, FrameRate: 25}
+	stream.Cadence = &ffmpeg.CadenceReport{
+		FrameRate: 25, Frames: 497, Duplicates: 5, Dropped: 3, UniqueFrameRate: 24.6,
+		Runs: []ffmpeg.CadenceRun{
+			{Type: ffmpeg.CadenceDuplicate, Start: 2, End: 2.2, Frames: 5},
+			{Type: ffmpeg.CadenceDropped, Start: 12, End: 12.12, Frames: 3},
+		},
+		Segments: []ffmpeg.CadenceSegment{
+			{Start: 0, End: 10, Frames: 250, Duplicates: 5, UniqueFrameRate: 24.5},
+			{Start: 10, End: 20, Frames: 247, Dropped: 3, UniqueFrameRate: 24.7},
+			{Start: 20, End: 30, Frames: 250, UniqueFrameRate: 25},
+		},
+	}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeMediaInfoVideoStreams(w, []ffmpeg.VideoStream{stream}, s.testContainerInfo)
+	w.Flush()
+
+	output := sb.String()
+	assert.Regexp(s.T(), `Unique Frame Rate:\s+24\.600 fps \(5 duplicate frames in 1 runs, 3 dropped frames\)`, output)
+	assert.Regexp(s.T(), `00:00:00\.000 - 00:00:10\.000\s+24\.500 fps unique \(5 duplicates, 0 dropped\)`, output)
+	assert.Regexp(s.T(), `00:00:10\.000 - 00:00:20\.000\s+24\.700 fps unique \(0 duplicates, 3 dropped\)`, output)
+	assert.NotContains(s.T(), output, "00:00:20.000 - 00:00:30.000")
+
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeBBCodeMediaInfoVideoStreams(w, []ffmpeg.VideoStream{stream}, s.testContainerInfo)
+	w.Flush()
+	assert.Contains(s.T(), sb.String(), "[b]Cadence Segments:[/b]")
+}
+
+// TestWriteMediaInfoScenes tests the scene summary and the listing of starved scenes.
+func (s *MainTestSuite) TestWriteMediaInfoScenes() {
+	report := &ffmpeg.SceneReport{
+		Threshold:      10,
+		AverageBitrate: 4000000,
+		Scenes: []ffmpeg.Scene{
+			{Index: 0, Start: 0, End: 30, Frames: 750, AverageBitrate: 5000000},
+			{Index: 1, Start: 30, End: 45, Frames: 375, AverageBitrate: 6000000, KeyframeAtCut: true},
+			{Index: 2, Start: 45, End: 60, Frames: 375, AverageBitrate: 1000000, AverageQP: 31.25, QPFrames: 375, Starved: true},
+		},
+	}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeMediaInfoScenes(w, report)
+	w.Flush()
+
+	output := sb.String()
+	assert.Regexp(s.T(), `Scenes:\s+3 \(threshold 10\)`, output)
+	assert.Regexp(s.T(), `Average Scene Duration:\s+20 seconds`, output)
+	assert.Regexp(s.T(), `Keyframes at Cuts:\s+1 of 2`, output)
+	assert.Regexp(s.T(), `Starved Scenes:\s+1`, output)
+	assert.Regexp(s.T(), `Scene #2\s+00:00:45\.000 - 00:01:00\.000, 1000\.00 Kbps \(25% of average\), QP 31\.2`, output)
+
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeBBCodeMediaInfoScenes(w, report)
+	w.Flush()
+	assert.Contains(s.T(), sb.String(), "[color=#FF0000]1 of 2[/color]")
+}
+
+// TestWriteMediaInfoKeyframes tests the listing of chapters and scene cuts off their keyframes.
+func (s *MainTestSuite) TestWriteMediaInfoKeyframes() {
+	report := ffmpeg.NewKeyframeReport(25)
+	report.AddFrame(ffmpeg.FrameBitrateInfo{FrameNumber: 0, FrameType: "I", KeyFrame: true})
+	report.AddFrame(ffmpeg.FrameBitrateInfo{FrameNumber: 250, FrameType: "I", KeyFrame: true, Time: 10})
+	report.Check([]ffmpeg.ChapterStream{{ID: 1, StartTime: 0}, {ID: 2, StartTime: 9.6}}, nil)
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeMediaInfoKeyframes(w, report)
+	w.Flush()
+
+	output := sb.String()
+	assert.Regexp(s.T(), `Keyframes:\s+2`, output)
+	assert.Regexp(s.T(), `Chapters on Keyframes:\s+1 of 2`, output)
+	assert.Regexp(s.T(), `Chapter #2\s+00:00:09\.600, IDR at 00:00:10\.000 \(\+10 frames, \+400 ms\)`, output)
+	assert.NotContains(s.T(), output, "Scene Cuts on Keyframes")
+
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeBBCodeMediaInfoKeyframes(w, report)
+	w.Flush()
+	assert.Contains(s.T(), sb.String(), "[color=#FF0000]1[/color]")
+}
+
+// TestWriteMediaInfoIntegrity tests the integrity verdict, the stream results and the error list.
+func (s *MainTestSuite) TestWriteMediaInfoIntegrity() {
+	report := &ffmpeg.IntegrityReport{
+		Streams: []ffmpeg.IntegrityStream{
+			{StreamIndex: 0, Type: "video", Format: "h264", DeclaredDuration: 60, DecodedDuration: 42.5, Errors: 2, Truncated: true},
+			{StreamIndex: 1, Type: "audio", Format: "aac", DeclaredDuration: 60, DecodedDuration: 60},
+		},
+	}
+	report.Add(ffmpeg.IntegrityEvent{StreamIndex: 0, Time: 12.5, Type: ffmpeg.IntegrityConcealment, Source: "h264",
+		Message: "concealing 812 DC, 812 AC, 812 MV errors in P frame"})
+	report.Add(ffmpeg.IntegrityEvent{StreamIndex: -1, Time: 42.5, Type: ffmpeg.IntegrityContainerError, Message: "Read error"})
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeMediaInfoIntegrity(w, report)
+	w.Flush()
+
+	output := sb.String()
+	assert.Regexp(s.T(), `Verdict:\s+DAMAGED`, output)
+	assert.Regexp(s.T(), `Concealments:\s+1`, output)
+	assert.Regexp(s.T(), `Stream #0 \(video, h264\):\s+00:00:42\.500 of 00:01:00\.000, 2 errors, truncated`, output)
+	assert.Regexp(s.T(), `Stream #1 \(audio, aac\):\s+00:01:00\.000 of 00:01:00\.000, 0 errors, complete`, output)
+	assert.Regexp(s.T(), `00:00:12\.500\s+concealment, stream #0: concealing`, output)
+	assert.Contains(s.T(), output, "container error, container: Read error")
+
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeBBCodeMediaInfoIntegrity(w, &ffmpeg.IntegrityReport{Streams: report.Streams[1:]})
+	w.Flush()
+	assert.Contains(s.T(), sb.String(), "[color=#00CC00]HEALTHY[/color]")
+}
+
+// TestWriteMediaInfoTimestamps tests the anomaly counts and the listed frames.
+func (s *MainTestSuite) TestWriteMediaInfoTimestamps() {
+	report := ffmpeg.NewTimestampReport(25)
+	for i, n := range []int{0, 1, 1, 2, 4} {
+		report.AddFrame(ffmpeg.FrameBitrateInfo{FrameNumber: i, PTS: int64(n) * 3600, DTS: int64(n-1) * 3600, Time: float64(n) / 25})
+	}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeMediaInfoTimestamps(w, report)
+	w.Flush()
+
+	output := sb.String()
+	assert.Regexp(s.T(), `Frames Checked:\s+5`, output)
+	assert.Regexp(s.T(), `Errors:\s+2`, output)
+	assert.Regexp(s.T(), `Warnings:\s+1`, output)
+	assert.Regexp(s.T(), `duplicate pts:\s+1`, output)
+	assert.NotContains(s.T(), output, "wraparound")
+	assert.Regexp(s.T(), `Frame #4\s+00:00:00\.160, gap \(warning\), \+0\.080 s`, output)
+
+	assert.Empty(s.T(), formatTimestampValue(ffmpeg.NoTimestamp))
+	assert.Equal(s.T(), "3600", formatTimestampValue(3600))
+}
+
+// TestWriteMediaInfoChecksums tests the checksum section and the verify result lines.
+func (s *MainTestSuite) TestWriteMediaInfoChecksums() {
+	checksums := &ffmpeg.FileHash{
+		SHA256:      "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		ContentHash: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		Streams: []ffmpeg.StreamHash{
+			{StreamIndex: 0, Type: "video", Frames: 1440, ContentHash: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"},
+		},
+	}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeMediaInfoChecksums(w, checksums)
+	writeMediaInfoChecksums(w, nil)
+	w.Flush()
+
+	output := sb.String()
+	assert.Equal(s.T(), 1, strings.Count(output, "CHECKSUMS"))
+	assert.Regexp(s.T(), `SHA-256:\s+ba7816bf`, output)
+	assert.Regexp(s.T(), `Stream #0 \(video\):\s+1440 frames, 9f86d081`, output)
+
+	assert.Equal(s.T(), "video stream #0 / #0: 1440 frames identical",
+		formatHashComparison(ffmpeg.StreamHashComparison{Type: "video", FramesA: 1440, FramesB: 1440, Identical: true, FirstDifference: -1}))
+	assert.Equal(s.T(), "video stream #0 / #1: first difference at frame 250 (00:00:10.000 / 00:00:10.000)",
+		formatHashComparison(ffmpeg.StreamHashComparison{Type: "video", StreamIndexB: 1, FramesA: 1440, FramesB: 1440,
+			FirstDifference: 250, TimeA: 10, TimeB: 10, HashA: "a", HashB: "b"}))
+	assert.Equal(s.T(), "audio stream #1 only in the first file",
+		formatHashComparison(ffmpeg.StreamHashComparison{Type: "audio", StreamIndexA: 1, StreamIndexB: -1}))
+}
+
+// TestWriteDiffReport tests the metadata diff, the frame type ratios, the scene winners and the verdict.
+func (s *MainTestSuite) TestWriteDiffReport() {
+	infos := []*ffmpeg.ContainerInfo{
+		{VideoStreams: []ffmpeg.VideoStream{{Format: "h264", Width: 1920, Height: 1080, FrameRate: 25}}},
+		{VideoStreams: []ffmpeg.VideoStream{{Format: "h264", Width: 1280, Height: 720, FrameRate: 25}}},
+	}
+	comparison := ffmpeg.NewEncodeComparison([]string{"/encodes/crf18.mkv", "/encodes/crf22.mkv"}, infos)
+	for file := range infos {
+		for n := 0; n < 250; n++ {
+			frameType := "P"
+			if n%50 == 0 {
+				frameType = "I"
+			}
+			comparison.AddFrame(file, ffmpeg.FrameBitrateInfo{FrameNumber: n, FrameType: frameType,
+				Bitrate: 80000 / int64(file+1), Time: float64(n) / 25})
+			comparison.AddQualityFrame(file, ffmpeg.QualityFrame{FrameNumber: n, QP: 18 + 4*float64(file)})
+		}
+	}
+	comparison.Finish()
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeDiffReport(w, comparison)
+	w.Flush()
+
+	output := sb.String()
+	assert.Regexp(s.T(), `\[1\]:\s+crf18\.mkv \(reference\)`, output)
+	assert.Regexp(s.T(), `\* Resolution:\s+\[1\] 1920x1080\s+\[2\] 1280x720`, output)
+	assert.Regexp(s.T(), `  Video Codec:\s+\[1\] h264\s+\[2\] h264`, output)
+	assert.Regexp(s.T(), `\[2\]:\s+1000\.00 Kbps \(50\.0% of the reference\), QP 22\.00, 250 frames, 00:00:10\.000`, output)
+	assert.Regexp(s.T(), `P-Frames \[2\]:\s+.* \(245 frames\), 0\.50x the reference`, output)
+	assert.Regexp(s.T(), `Winner Criterion:\s+lowest QP`, output)
+	assert.Regexp(s.T(), `Scene #0 00:00:00\.000 - 00:00:10\.000:\s+\[1\] 2000\.00 Kbps QP 18\.00\s+\[2\] 1000\.00 Kbps QP 22\.00\s+winner \[1\]`, output)
+	assert.Regexp(s.T(), `Verdict:\s+\[1\] crf18\.mkv wins 1 of 1 scenes`, output)
+
+	// Without comparable QPs, the scenes show bitrate ratios and no file wins
+	infos[1].VideoStreams[0].Format = "hevc"
+	comparison = ffmpeg.NewEncodeComparison([]string{"/encodes/crf18.mkv", "/encodes/x265.mkv"}, infos)
+	for file := range infos {
+		for n := 0; n < 250; n++ {
+			comparison.AddFrame(file, ffmpeg.FrameBitrateInfo{FrameNumber: n, FrameType: "P",
+				Bitrate: 80000 / int64(file+1), Time: float64(n) / 25})
+		}
+	}
+	comparison.Finish()
+
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeDiffReport(w, comparison)
+	w.Flush()
+
+	output = sb.String()
+	assert.Regexp(s.T(), `Winner Criterion:\s+none, QPs are not comparable`, output)
+	assert.Regexp(s.T(), `\[2\] 1000\.00 Kbps \(0\.50x\)\s+winner -`, output)
+	assert.Regexp(s.T(), `Verdict:\s+No winner, bitrate alone does not measure quality`, output)
+}
+
+// TestWriteComparisonSVG tests that unknown values break the lines of the graph.
+func (s *MainTestSuite) TestWriteComparisonSVG() {
+	var sb strings.Builder
+	writeComparisonSVG(&sb, "Average QP", 1, []string{"[1] a.mkv", "[2] b&c.mkv"},
+		[][]float64{{20, 21, math.NaN(), 22, 23}, {24, 25, 26, 27, 28}})
+
+	output := sb.String()
+	assert.True(s.T(), strings.HasPrefix(output, "<svg "))
+	assert.Equal(s.T(), 3, strings.Count(output, "<polyline"))
+	assert.Contains(s.T(), output, "[2] b&amp;c.mkv")
+	assert.Contains(s.T(), output, "00:00:05.000")
+}
+
+// TestWriteDedupeReport tests the listing of clusters, overlaps and best copies.
+func (s *MainTestSuite) TestWriteDedupeReport() {
+	best := &ffmpeg.Fingerprint{Path: "/library/movie.mkv", Format: "hevc", Width: 3840, Height: 2160,
+		BitRate: 20000000, AverageQP: 21.5, Duration: 5400, Scenes: make([]ffmpeg.FingerprintScene, 900)}
+	reencode := &ffmpeg.Fingerprint{Path: "/library/old/movie.avi", Format: "mpeg4", Width: 720, Height: 400,
+		BitRate: 1200000, Duration: 5340, Scenes: make([]ffmpeg.FingerprintScene, 880)}
+	clusters := []ffmpeg.FingerprintCluster{{
+		Files: []*ffmpeg.Fingerprint{best, reencode},
+		Matches: []ffmpeg.FingerprintMatch{{PathA: best.Path, PathB: reencode.Path, Scenes: 870,
+			Offset: -60, StartA: 60, EndA: 5400, StartB: 0, EndB: 5340}},
+		Best: best,
+	}}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeDedupeReport(w, clusters, 5, "/library")
+	w.Flush()
+
+	output := sb.String()
+	assert.Regexp(s.T(), `Files:\s+5`, output)
+	assert.Regexp(s.T(), `Cluster #1:\s+2 files`, output)
+	assert.Regexp(s.T(), `\* movie\.mkv\s+hevc 3840x2160, 20000\.00 Kbps, QP 21\.50, 01:30:00\.000, 900 scenes`, output)
+	assert.Regexp(s.T(), `  old/movie\.avi\s+mpeg4 720x400, 1200\.00 Kbps, 01:29:00\.000, 880 scenes`, output)
+	assert.Regexp(s.T(), `movie\.mkv 00:01:00\.000 - 01:30:00\.000\s+old/movie\.avi 00:00:00\.000 - 01:29:00\.000 \(870 scenes\)`, output)
+	assert.Regexp(s.T(), `Best Copy:\s+movie\.mkv`, output)
+}
+
+// TestWriteCheckReport tests that the report documents the thresholds of a profile and the overrides.
+func (s *MainTestSuite) TestWriteCheckReport() {
+	profile, err := ffmpeg.LookupSpecProfile("broadcast-hd@1")
+	require.NoError(s.T(), err)
+	spec := ffmpeg.MergeSpecs(profile, &ffmpeg.Spec{Rules: []ffmpeg.SpecRule{{Field: "video.codec", Equals: "hevc"}}})
+	info := &ffmpeg.ContainerInfo{VideoStreams: []ffmpeg.VideoStream{{Format: "h264", FormatProfile: "High", Level: 4.1}}}
+	result := spec.Evaluate(info)
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeCheckReport(w, result, "movie.ts")
+	w.Flush()
+
+	output := sb.String()
+	assert.Regexp(s.T(), `Spec:\s+Generic broadcast HD`, output)
+	assert.Regexp(s.T(), `Version:\s+1`, output)
+	assert.Regexp(s.T(), `Profile:\s+broadcast-hd@1`, output)
+	assert.Regexp(s.T(), `Result:\s+FAIL \(\d+ of 12 rules failed\)`, output)
+	assert.Regexp(s.T(), `Rule #1:\s+video\.codec\n\s+Threshold:\s+hevc\n\s+Origin:\s+override\n\s+Actual:\s+h264\n\s+Result:\s+FAIL \(video stream #0: expected hevc, got h264\)`, output)
+	assert.Regexp(s.T(), `Rule #3:\s+video\.level\n\s+Threshold:\s+≤ 4\.1\n\s+Origin:\s+broadcast-hd@1\n\s+Actual:\s+4\.1\n\s+Result:\s+PASS`, output)
+	assert.Regexp(s.T(), `Reason:\s+EBU R128 target of -23 LUFS`, output)
+
+	var junit strings.Builder
+	require.NoError(s.T(), writeJUnitReport(&junit, result, "movie.ts"))
+	assert.Contains(s.T(), junit.String(), `<property name="profile" value="broadcast-hd@1"></property>`)
+}
+
+// TestWriteJUnitReport tests that the rules of a spec become JUnit test cases with failures.
+func (s *MainTestSuite) TestWriteJUnitReport() {
+	maxLevel := 4.1
+	spec := &ffmpeg.Spec{Name: "Broadcast HD", Rules: []ffmpeg.SpecRule{
+		{Field: "video.codec", Equals: "h264"},
+		{Name: "Level", Field: "video.level", Max: &maxLevel},
+	}}
+	info := &ffmpeg.ContainerInfo{VideoStreams: []ffmpeg.VideoStream{{Format: "h264", Level: 5.1}}}
+	result := spec.Evaluate(info)
+
+	var sb strings.Builder
+	require.NoError(s.T(), writeJUnitReport(&sb, result, "movie.ts"))
+
+	output := sb.String()
+	assert.True(s.T(), strings.HasPrefix(output, xml.Header))
+	assert.Contains(s.T(), output, `<testsuites name="framehound" tests="2" failures="1">`)
+	assert.Contains(s.T(), output, `<testsuite name="Broadcast HD" tests="2" failures="1">`)
+	assert.Contains(s.T(), output, `<testcase name="video.codec" classname="movie.ts">`)
+	assert.Contains(s.T(), output, `<failure message="video stream #0: expected ≤ 4.1, got 5.1" type="SpecViolation">`)
+	assert.Contains(s.T(), output, "Actual: 5.1</failure>")
+	assert.NotContains(s.T(), output, "<properties>")
+
+	var parsed junitTestSuites
+	require.NoError(s.T(), xml.Unmarshal([]byte(output), &parsed))
+	require.Len(s.T(), parsed.Suites, 1)
+	assert.Nil(s.T(), parsed.Suites[0].TestCases[0].Failure)
+	assert.NotNil(s.T(), parsed.Suites[0].TestCases[1].Failure)
+}
+
+// TestJobServer tests queueing jobs by path and by upload, the status, the event stream and the
+// results of the HTTP API, with a stub analysis so no FFmpeg is required.
+func (s *MainTestSuite) TestJobServer() {
+	jobDir := filepath.Join(s.tempDir, "jobs")
+	root, err := filepath.EvalSymlinks(s.tempDir)
+	require.NoError(s.T(), err)
+	server := newJobServer(nil, s.prober, jobDir, 1)
+	server.roots = []string{root}
+	release := make(chan struct{})
+	server.analyze = func(_ context.Context, job *analysisJob) error {
+		<-release
+		for n := 0; n < 3; n++ {
+			frame := ffmpeg.FrameBitrateInfo{FrameNumber: n, FrameType: "P", Bitrate: 1000, Time: float64(n) / 25}
+			server.update(job, func() {
+				job.frames = append(job.frames, frame)
+				job.Frames = len(job.frames)
+				job.qp = append(job.qp, jobQP{FrameNumber: n, QP: 21.5})
+			})
+		}
+		if strings.HasSuffix(job.Path, "broken.ts") {
+			return fmt.Errorf("corrupt file")
+		}
+		return nil
+	}
+	httpServer := httptest.NewServer(server.handler())
+	defer httpServer.Close()
+
+	post := func(path string) *http.Response {
+		response, err := http.Post(httpServer.URL+"/jobs", "application/json", strings.NewReader(fmt.Sprintf(`{"path": %q}`, path)))
+		require.NoError(s.T(), err)
+		response.Body.Close()
+		return response
+	}
+	assert.Equal(s.T(), http.StatusBadRequest, post(filepath.Join(s.tempDir, "missing.ts")).StatusCode)
+	assert.Equal(s.T(), http.StatusForbidden, post(filepath.Join(s.tempDir, "..", "movie.ts")).StatusCode)
+	outside, err := os.MkdirTemp("", "outside")
+	require.NoError(s.T(), err)
+	defer os.RemoveAll(outside)
+	require.NoError(s.T(), os.WriteFile(filepath.Join(outside, "secret.ts"), []byte("secret"), 0644))
+	require.NoError(s.T(), os.Symlink(filepath.Join(outside, "secret.ts"), filepath.Join(s.tempDir, "link.ts")))
+	assert.Equal(s.T(), http.StatusForbidden, post(filepath.Join(s.tempDir, "link.ts")).StatusCode)
+
+	videoPath := filepath.Join(s.tempDir, "movie.ts")
+	require.NoError(s.T(), os.WriteFile(videoPath, []byte("video"), 0644))
+	response := post(videoPath)
+	require.Equal(s.T(), http.StatusAccepted, response.StatusCode)
+	location := response.Header.Get("Location")
+	require.True(s.T(), strings.HasPrefix(location, "/jobs/"))
+
+	// The queue holds one job and no worker runs yet
+	assert.Equal(s.T(), http.StatusServiceUnavailable, post(videoPath).StatusCode)
+
+	response, err = http.Get(httpServer.URL + location + "/result")
+	require.NoError(s.T(), err)
+	response.Body.Close()
+	assert.Equal(s.T(), http.StatusConflict, response.StatusCode)
+
+	// The worker prints the outcome of every job, wait for it before the suite restores the colors
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		server.work(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	close(release)
+
+	// The event stream ends when the job is done
+	response, err = http.Get(httpServer.URL + location + "/events")
+	require.NoError(s.T(), err)
+	events, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "text/event-stream", response.Header.Get("Content-Type"))
+	assert.Equal(s.T(), 3, strings.Count(string(events), "event: frame\n"))
+	assert.Equal(s.T(), 3, strings.Count(string(events), "event: qp\n"))
+	assert.Contains(s.T(), string(events), `"status":"done","progress":1,"frames":3`)
+
+	response, err = http.Get(httpServer.URL + location)
+	require.NoError(s.T(), err)
+	var job analysisJob
+	require.NoError(s.T(), json.NewDecoder(response.Body).Decode(&job))
+	response.Body.Close()
+	assert.Equal(s.T(), jobStatusDone, job.Status)
+	assert.Equal(s.T(), 3, job.Frames)
+	assert.NotNil(s.T(), job.Finished)
+
+	response, err = http.Get(httpServer.URL + location + "/result?format=csv")
+	require.NoError(s.T(), err)
+	csvData, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "frame_number,frame_type,bitrate,time,qp\n0,P,1000,0.000000,21.50\n", string(csvData)[:64])
+
+	response, err = http.Get(httpServer.URL + location + "/result?format=xml")
+	require.NoError(s.T(), err)
+	response.Body.Close()
+	assert.Equal(s.T(), http.StatusBadRequest, response.StatusCode)
+
+	// An upload is saved in the job directory and a failed job reports its error
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "broken.ts")
+	require.NoError(s.T(), err)
+	_, err = part.Write([]byte("video"))
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), form.Close())
+	response, err = http.Post(httpServer.URL+"/jobs", form.FormDataContentType(), &body)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), json.NewDecoder(response.Body).Decode(&job))
+	response.Body.Close()
+	require.Equal(s.T(), http.StatusAccepted, response.StatusCode)
+	assert.True(s.T(), job.Uploaded)
+	assert.True(s.T(), strings.HasPrefix(job.Path, filepath.Join(jobDir, "uploads")))
+
+	response, err = http.Get(httpServer.URL + "/jobs/" + job.ID + "/events")
+	require.NoError(s.T(), err)
+	_, err = io.ReadAll(response.Body)
+	response.Body.Close()
+	require.NoError(s.T(), err)
+
+	response, err = http.Get(httpServer.URL + "/jobs/" + job.ID + "/result")
+	require.NoError(s.T(), err)
+	response.Body.Close()
+	assert.Equal(s.T(), http.StatusConflict, response.StatusCode)
+
+	response, err = http.Get(httpServer.URL + "/jobs")
+	require.NoError(s.T(), err)
+	var jobs []analysisJob
+	require.NoError(s.T(), json.NewDecoder(response.Body).Decode(&jobs))
+	response.Body.Close()
+	require.Len(s.T(), jobs, 2)
+	assert.Equal(s.T(), jobStatusFailed, jobs[1].Status)
+	assert.Equal(s.T(), "corrupt file", jobs[1].Error)
+	assert.NoFileExists(s.T(), jobs[1].Path)
+
+	// A finished job can be deleted with its reports, the others expire after the retention time
+	require.NoError(s.T(), os.MkdirAll(filepath.Join(jobDir, jobs[0].ID), 0755))
+	request, err := http.NewRequest(http.MethodDelete, httpServer.URL+location, nil)
+	require.NoError(s.T(), err)
+	response, err = http.DefaultClient.Do(request)
+	require.NoError(s.T(), err)
+	response.Body.Close()
+	assert.Equal(s.T(), http.StatusNoContent, response.StatusCode)
+	assert.NoDirExists(s.T(), filepath.Join(jobDir, jobs[0].ID))
+	response, err = http.Get(httpServer.URL + location)
+	require.NoError(s.T(), err)
+	response.Body.Close()
+	assert.Equal(s.T(), http.StatusNotFound, response.StatusCode)
+
+	server.prune(time.Now())
+	assert.Len(s.T(), server.order, 1)
+	server.prune(time.Now().Add(jobDefaultRetention))
+	assert.Empty(s.T(), server.order)
+	assert.Empty(s.T(), server.jobs)
+
+	// Without root directories only uploads are accepted, up to the upload limit
+	limited := newJobServer(nil, s.prober, jobDir, 1)
+	limited.maxUpload = 16
+	recorder := httptest.NewRecorder()
+	limited.handleCreate(recorder, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(fmt.Sprintf(`{"path": %q}`, videoPath))))
+	assert.Equal(s.T(), http.StatusForbidden, recorder.Code)
+	body.Reset()
+	form = multipart.NewWriter(&body)
+	part, err = form.CreateFormFile("file", "large.ts")
+	require.NoError(s.T(), err)
+	_, err = part.Write(make([]byte, 1024))
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), form.Close())
+	request = httptest.NewRequest(http.MethodPost, "/jobs", &body)
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	recorder = httptest.NewRecorder()
+	limited.handleCreate(recorder, request)
+	assert.Equal(s.T(), http.StatusRequestEntityTooLarge, recorder.Code)
+	uploads, err := os.ReadDir(filepath.Join(jobDir, "uploads"))
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), uploads)
+}
+
+// TestLoadAnalysisOptions tests reading analysis profiles and resolving their delivery spec.
+func (s *MainTestSuite) TestLoadAnalysisOptions() {
+	profileDir := filepath.Join(s.tempDir, "profiles")
+	require.NoError(s.T(), os.MkdirAll(profileDir, 0755))
+	require.NoError(s.T(), os.WriteFile(filepath.Join(profileDir, "house.yaml"),
+		[]byte("rules:\n  - field: video.level\n    max: 4.2\n"), 0644))
+
+	path := filepath.Join(profileDir, "ingest.yaml")
+	require.NoError(s.T(), os.WriteFile(path, []byte(`integrity: true
+black_freeze: true
+freeze_duration: 5
+loudness: ebu-r128
+profile: broadcast-hd@1
+spec: house.yaml
+`), 0644))
+	options, err := loadAnalysisOptions(path)
+	require.NoError(s.T(), err)
+	assert.True(s.T(), options.Integrity)
+	assert.True(s.T(), options.BlackFreeze)
+	assert.Equal(s.T(), 5.0, options.FreezeDuration)
+	assert.Equal(s.T(), filepath.Join(profileDir, "house.yaml"), options.Spec)
+
+	require.NoError(s.T(), resolveAnalysisOptions(options))
+	require.NotNil(s.T(), options.spec)
+	assert.Equal(s.T(), "broadcast-hd@1", options.spec.Profile)
+	for _, rule := range options.spec.Rules {
+		if rule.Field == "video.level" {
+			assert.Equal(s.T(), 4.2, *rule.Max)
+		}
+	}
+
+	jsonPath := filepath.Join(profileDir, "quick.json")
+	require.NoError(s.T(), os.WriteFile(jsonPath, []byte(`{"scenes": true, "scene_threshold": 12}`), 0644))
+	options, err = loadAnalysisOptions(jsonPath)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 12.0, options.SceneThreshold)
+	require.NoError(s.T(), resolveAnalysisOptions(options))
+	assert.Nil(s.T(), options.spec)
+
+	emptyPath := filepath.Join(profileDir, "empty.yaml")
+	require.NoError(s.T(), os.WriteFile(emptyPath, nil, 0644))
+	options, err = loadAnalysisOptions(emptyPath)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), analysisOptions{}, *options)
+
+	require.NoError(s.T(), os.WriteFile(path, []byte("blackfreeze: true\n"), 0644))
+	_, err = loadAnalysisOptions(path)
+	assert.ErrorContains(s.T(), err, "field blackfreeze not found")
+
+	assert.Error(s.T(), resolveAnalysisOptions(&analysisOptions{Loudness: "cinema"}))
+}
+
+// TestWatchDir tests finding the files of a watched directory, the lock files of their writers
+// and moving them once analyzed.
+func (s *MainTestSuite) TestWatchDir() {
+	dir := filepath.Join(s.tempDir, "ingest")
+	doneDir := filepath.Join(dir, "done")
+	for _, name := range []string{"show/episode1.mkv", "show/episode2.mp4", "notes.txt", ".hidden.mkv",
+		".partial/movie.mkv", "done/old.mkv", "movie.ts"} {
+		path := filepath.Join(dir, name)
+		require.NoError(s.T(), os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(s.T(), os.WriteFile(path, []byte(name), 0644))
+	}
+
+	files, err := scanWatchDir(dir, doneDir)
+	require.NoError(s.T(), err)
+	var found []string
+	for path := range files {
+		rel, err := filepath.Rel(dir, path)
+		require.NoError(s.T(), err)
+		found = append(found, filepath.ToSlash(rel))
+	}
+	assert.ElementsMatch(s.T(), []string{"show/episode1.mkv", "show/episode2.mp4", "movie.ts"}, found)
+
+	episode := filepath.Join(dir, "show", "episode1.mkv")
+	assert.False(s.T(), watchFileLocked(episode))
+	require.NoError(s.T(), os.WriteFile(episode+".lock", nil, 0644))
+	assert.True(s.T(), watchFileLocked(episode))
+	assert.True(s.T(), watchFileLocked(filepath.Join(dir, "missing.mkv")))
+
+	target, err := moveWatchedFile(episode, dir, doneDir)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), filepath.Join(doneDir, "show", "episode1.mkv"), target)
+	assert.NoFileExists(s.T(), episode)
+
+	// A file with the same name keeps the previous one
+	require.NoError(s.T(), os.WriteFile(episode, []byte("again"), 0644))
+	target, err = moveWatchedFile(episode, dir, doneDir)
+	require.NoError(s.T(), err)
+	assert.Regexp(s.T(), `episode1-\d{8}-\d{6}\.mkv$`, target)
+	data, err := os.ReadFile(target)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "again", string(data))
+	assert.FileExists(s.T(), filepath.Join(doneDir, "show", "episode1.mkv"))
+}
+
+// TestWriteHistoryAndQueryReports tests the stored analyses of a file and the files matching a query.
+func (s *MainTestSuite) TestWriteHistoryAndQueryReports() {
+	info := &ffmpeg.ContainerInfo{
+		General:      ffmpeg.GeneralInfo{DurationF: 5400},
+		VideoStreams: []ffmpeg.VideoStream{{Format: "hevc", Width: 3840, Height: 2160, FrameRate: 24}},
+		AudioStreams: []ffmpeg.AudioStream{{Format: "eac3"}},
+		Bitrate:      &ffmpeg.BitrateStats{Frames: 129600, AverageBitrate: 12500000, PeakBitrate: 30000000},
+	}
+	analyzed := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	first := ffmpeg.NewStoredRun("/media/movie.mkv", "abc123", 8000000000, info, analyzed)
+	second := ffmpeg.NewStoredRun("/archive/movie.mkv", "abc123", 8000000000, info, analyzed.Add(24*time.Hour))
+	second.Version, second.Frames = "1.2.0", 129600
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeHistoryReport(w, []*ffmpeg.StoredRun{first, second}, "/archive/movie.mkv")
+	w.Flush()
+
+	output := sb.String()
+	assert.Regexp(s.T(), `SHA-256:\s+abc123`, output)
+	assert.Regexp(s.T(), `Analyses:\s+2`, output)
+	assert.Regexp(s.T(), `Analysis #1:\s+Fri, 01 Mar 2024 12:00:00 UTC`, output)
+	assert.Regexp(s.T(), `Path:\s+/media/movie\.mkv`, output)
+	assert.Regexp(s.T(), `Video:\s+hevc 3840x2160 @ 24\.000 fps`, output)
+	assert.Regexp(s.T(), `Average Bit Rate:\s+12500\.00 Kbps`, output)
+	assert.Regexp(s.T(), `FrameHound Version:\s+1\.2\.0`, output)
+	assert.Regexp(s.T(), `Stored Frames:\s+129,600`, output)
+	assert.Equal(s.T(), 1, strings.Count(output, "Stored Frames"))
+	assert.NotContains(s.T(), output, "Integrity")
+
+	query, err := ffmpeg.ParseQuery("codec=hevc and avg_bitrate>8M")
+	require.NoError(s.T(), err)
+	sb.Reset()
+	w = tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeQueryReport(w, query, []*ffmpeg.StoredRun{second}, 3)
+	w.Flush()
+
+	output = sb.String()
+	assert.Regexp(s.T(), `Query:\s+codec=hevc and avg_bitrate>8M`, output)
+	assert.Regexp(s.T(), `Matches:\s+1 of 3 analyses`, output)
+	assert.Regexp(s.T(), `/archive/movie\.mkv\s+hevc 3840x2160 @ 24\.000 fps, 12500\.00 Kbps average, 30000\.00 Kbps peak, eac3`, output)
+}
+
+// TestWriteLadderReport tests the rendition details and the issue list of a manifest.
+func (s *MainTestSuite) TestWriteLadderReport() {
+	manifest := &ffmpeg.Manifest{
+		Path:           "/packages/movie/master.m3u8",
+		Format:         ffmpeg.ManifestHLS,
+		TargetDuration: 6,
+		Renditions: []ffmpeg.Rendition{
+			{
+				ID: "1080p/index.m3u8", Type: ffmpeg.RenditionVideo, Width: 1920, Height: 1080,
+				Bandwidth: 6000000, AverageBandwidth: 4500000,
+				MeasuredPeakBandwidth: 5800000, MeasuredAverageBandwidth: 4400000,
+				Segments:         make([]ffmpeg.ManifestSegment, 10),
+				SegmentDurations: ffmpeg.SegmentDurationStats{Count: 10, Min: 4, Max: 6, Mean: 5.8, StdDev: 0.6},
+				IDRTimes:         []float64{0, 6}, SegmentsWithoutIDR: 1, MisalignedIDRs: 2,
+			},
+			{ID: "audio/index.m3u8", Type: ffmpeg.RenditionAudio, Segments: make([]ffmpeg.ManifestSegment, 10), MissingSegments: 1},
+		},
+		Issues: []string{"rendition 1080p/index.m3u8: 1 segments do not start with an IDR frame"},
+	}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', tabwriter.StripEscape)
+	writeLadderReport(w, manifest)
+	w.Flush()
+
+	output := sb.String()
+	assert.Regexp(s.T(), `Manifest:\s+master\.m3u8 \(HLS\)`, output)
+	assert.Regexp(s.T(), `Target Duration:\s+6 s`, output)
+	assert.Regexp(s.T(), `Declared Bandwidth:\s+6000\.00 Kbps peak, 4500\.00 Kbps average`, output)
+	assert.Regexp(s.T(), `Measured Bandwidth:\s+5800\.00 Kbps peak, 4400\.00 Kbps average`, output)
+	assert.Regexp(s.T(), `Segment Duration:\s+5\.800 s average, 4\.000 - 6\.000 s, 0\.600 s std\. dev\.`, output)
+	assert.Regexp(s.T(), `Segments Starting with IDR:\s+9 of 10`, output)
+	assert.Regexp(s.T(), `IDR Alignment:\s+FAIL \(2 IDR frames missing\)`, output)
+	assert.Regexp(s.T(), `Declared Bandwidth:\s+not declared`, output)
+	assert.Regexp(s.T(), `Segments:\s+10 \(1 missing\)`, output)
+	assert.Contains(s.T(), output, "- rendition 1080p/index.m3u8: 1 segments do not start with an IDR frame")
+}
+
 // TestMainTestSuite runs the test suite.
 func TestMainTestSuite(t *testing.T) {
 	suite.Run(t, new(MainTestSuite))